@@ -0,0 +1,101 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// collect reads the compared contents of the application and
+// its application dependencies
+func collect(source Source) (*contents, error) {
+	application, err := source.Apps.GetApp(source.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := &contents{
+		locator:  application.Package,
+		manifest: application.Manifest,
+		images:   make(map[string]string),
+		charts:   make(map[string]string),
+	}
+	locators := append([]loc.Locator{application.Package}, application.Manifest.Dependencies.GetApps()...)
+	for _, locator := range locators {
+		err := utils.WithTempDir(func(dir string) error {
+			err := pack.Unpack(source.Packages, locator, dir, nil)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			return trace.Wrap(result.addDir(dir))
+		}, "diff")
+		if err != nil {
+			if trace.IsNotFound(err) {
+				// e.g. packages omitted from delta upgrade tarballs
+				log.Warnf("Package %v not found, skipping its images and charts.", locator)
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+	}
+	return result, nil
+}
+
+// addDir adds the charts and images found in the unpacked application
+// package directory
+func (r *contents) addDir(dir string) error {
+	err := filepath.Walk(filepath.Join(dir, defaults.ResourcesDir), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return trace.ConvertSystemError(err)
+		}
+		if fi.IsDir() || fi.Name() != constants.HelmChartFile {
+			return nil
+		}
+		chart, err := chartutil.LoadChartfile(path)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.charts[chart.Name] = chart.Version
+		return nil
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	images, err := docker.ListRegistryImages(filepath.Join(dir, defaults.RegistryDir))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, image := range images {
+		r.images[fmt.Sprintf("%v:%v", image.Repository, image.Tag)] = image.Digest.String()
+	}
+	return nil
+}
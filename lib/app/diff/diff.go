@@ -0,0 +1,405 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diff compares two versions of an application: their manifests,
+// package dependencies, vendored container images and Helm charts.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+)
+
+// Source identifies the application to compare
+type Source struct {
+	// Apps is the application service with the application
+	Apps app.Applications
+	// Packages is the package service with the application packages
+	Packages pack.PackageService
+	// Package is the application package locator
+	Package loc.Locator
+}
+
+// Diff describes differences between two versions of an application
+type Diff struct {
+	// Old is the locator of the old application
+	Old loc.Locator `json:"old"`
+	// New is the locator of the new application
+	New loc.Locator `json:"new"`
+	// Manifest lists changed manifest fields other than node profiles,
+	// hooks and dependencies
+	Manifest []Change `json:"manifest,omitempty"`
+	// NodeProfiles lists changed node profiles
+	NodeProfiles []Change `json:"nodeProfiles,omitempty"`
+	// Hooks lists changed application hooks
+	Hooks []Change `json:"hooks,omitempty"`
+	// Packages lists changed package and application dependencies
+	Packages []Change `json:"packages,omitempty"`
+	// Images lists changed container images
+	Images []Change `json:"images,omitempty"`
+	// Charts lists changed Helm charts
+	Charts []Change `json:"charts,omitempty"`
+}
+
+// Change describes a single difference
+type Change struct {
+	// Type is the change type
+	Type ChangeType `json:"type"`
+	// Name identifies the changed item, e.g. manifest field path,
+	// package name or image reference
+	Name string `json:"name"`
+	// Old is the old value, if any
+	Old string `json:"old,omitempty"`
+	// New is the new value, if any
+	New string `json:"new,omitempty"`
+	// Fields lists changed fields of the item
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ChangeType is the type of a change
+type ChangeType string
+
+const (
+	// ChangeAdded means the item was added in the new version
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved means the item was removed in the new version
+	ChangeRemoved ChangeType = "removed"
+	// ChangeModified means the item was changed in the new version
+	ChangeModified ChangeType = "changed"
+)
+
+// Compare returns differences between the old and the new application
+func Compare(old, new Source) (*Diff, error) {
+	oldContents, err := collect(old)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read %v", old.Package)
+	}
+	newContents, err := collect(new)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read %v", new.Package)
+	}
+	return compareContents(*oldContents, *newContents)
+}
+
+// IsEmpty returns true if there are no differences
+func (d Diff) IsEmpty() bool {
+	return len(d.Manifest)+len(d.NodeProfiles)+len(d.Hooks)+
+		len(d.Packages)+len(d.Images)+len(d.Charts) == 0
+}
+
+// WriteJSON writes the diff to the provided writer in JSON format
+func (d Diff) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(d, "", "    ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return trace.Wrap(err)
+}
+
+// WriteText writes the diff to the provided writer in human-readable format
+func (d Diff) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Comparing %v with %v\n", d.Old, d.New)
+	if d.IsEmpty() {
+		_, err := fmt.Fprintln(w, "No differences found")
+		return trace.Wrap(err)
+	}
+	sections := []struct {
+		title   string
+		changes []Change
+	}{
+		{"Manifest", d.Manifest},
+		{"Node profiles", d.NodeProfiles},
+		{"Hooks", d.Hooks},
+		{"Packages", d.Packages},
+		{"Images", d.Images},
+		{"Charts", d.Charts},
+	}
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%v:\n", section.title)
+		for _, change := range section.changes {
+			fmt.Fprintf(w, "  %v\n", change)
+		}
+	}
+	return nil
+}
+
+// String returns a single-line description of the change
+func (c Change) String() string {
+	var details string
+	switch c.Type {
+	case ChangeAdded:
+		details = c.New
+	case ChangeRemoved:
+		details = c.Old
+	case ChangeModified:
+		if c.Old != "" || c.New != "" {
+			details = fmt.Sprintf("%v -> %v", c.Old, c.New)
+		}
+	}
+	line := fmt.Sprintf("%v %v", c.Type.symbol(), c.Name)
+	if details != "" {
+		line = fmt.Sprintf("%v: %v", line, details)
+	}
+	if len(c.Fields) != 0 {
+		line = fmt.Sprintf("%v (%v)", line, strings.Join(c.Fields, ", "))
+	}
+	return line
+}
+
+func (t ChangeType) symbol() string {
+	switch t {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// contents describes the application contents that are compared
+type contents struct {
+	// locator is the application package locator
+	locator loc.Locator
+	// manifest is the application manifest
+	manifest schema.Manifest
+	// images maps image references to their digests
+	images map[string]string
+	// charts maps Helm chart names to their versions
+	charts map[string]string
+}
+
+func compareContents(old, new contents) (*Diff, error) {
+	diff := &Diff{Old: old.locator, New: new.locator}
+	oldFields, err := manifestFields(old.manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	newFields, err := manifestFields(new.manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	diff.Manifest = compareValues("", oldFields, newFields)
+	diff.NodeProfiles, err = compareNodeProfiles(old.manifest.NodeProfiles, new.manifest.NodeProfiles)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	diff.Hooks, err = compareHooks(old.manifest.Hooks, new.manifest.Hooks)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	diff.Packages = compareSets(dependencyVersions(old.manifest), dependencyVersions(new.manifest))
+	diff.Images = compareSets(old.images, new.images)
+	diff.Charts = compareSets(old.charts, new.charts)
+	return diff, nil
+}
+
+// manifestFields returns the manifest as a generic map without
+// the sections that are compared separately
+func manifestFields(manifest schema.Manifest) (map[string]interface{}, error) {
+	fields, err := toMap(manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, section := range []string{"nodeProfiles", "hooks", "dependencies"} {
+		delete(fields, section)
+	}
+	return fields, nil
+}
+
+func compareNodeProfiles(old, new schema.NodeProfiles) ([]Change, error) {
+	oldProfiles := make(map[string]interface{})
+	for _, profile := range old {
+		oldProfiles[profile.Name] = profile
+	}
+	newProfiles := make(map[string]interface{})
+	for _, profile := range new {
+		newProfiles[profile.Name] = profile
+	}
+	return compareItems(oldProfiles, newProfiles)
+}
+
+func compareHooks(old, new *schema.Hooks) ([]Change, error) {
+	oldHooks, err := toMap(old)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	newHooks, err := toMap(new)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return compareItems(oldHooks, newHooks)
+}
+
+// compareItems compares named items and reports changed fields of the modified ones
+func compareItems(old, new map[string]interface{}) (changes []Change, err error) {
+	for _, name := range unionKeys(old, new) {
+		oldItem, inOld := old[name]
+		newItem, inNew := new[name]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Type: ChangeAdded, Name: name})
+		case !inNew:
+			changes = append(changes, Change{Type: ChangeRemoved, Name: name})
+		default:
+			oldFields, err := toMap(oldItem)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			newFields, err := toMap(newItem)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			var fields []string
+			for _, change := range compareValues("", oldFields, newFields) {
+				fields = append(fields, change.Name)
+			}
+			if len(fields) != 0 {
+				changes = append(changes, Change{Type: ChangeModified, Name: name, Fields: fields})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// compareValues returns changes between two generic JSON values.
+// Objects are compared recursively, other values are compared as a whole
+func compareValues(path string, old, new interface{}) (changes []Change) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		for _, key := range unionKeys(oldMap, newMap) {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			oldValue, inOld := oldMap[key]
+			newValue, inNew := newMap[key]
+			switch {
+			case !inOld:
+				changes = append(changes, Change{Type: ChangeAdded, Name: keyPath, New: formatValue(newValue)})
+			case !inNew:
+				changes = append(changes, Change{Type: ChangeRemoved, Name: keyPath, Old: formatValue(oldValue)})
+			default:
+				changes = append(changes, compareValues(keyPath, oldValue, newValue)...)
+			}
+		}
+		return changes
+	}
+	if reflect.DeepEqual(old, new) {
+		return nil
+	}
+	return []Change{{Type: ChangeModified, Name: path, Old: formatValue(old), New: formatValue(new)}}
+}
+
+// compareSets compares two sets of named values, e.g. package versions
+func compareSets(old, new map[string]string) (changes []Change) {
+	for _, name := range unionStringKeys(old, new) {
+		oldValue, inOld := old[name]
+		newValue, inNew := new[name]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Type: ChangeAdded, Name: name, New: newValue})
+		case !inNew:
+			changes = append(changes, Change{Type: ChangeRemoved, Name: name, Old: oldValue})
+		case oldValue != newValue:
+			changes = append(changes, Change{Type: ChangeModified, Name: name, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+// dependencyVersions returns versions of all package and application
+// dependencies of the manifest keyed by repository and name
+func dependencyVersions(manifest schema.Manifest) map[string]string {
+	versions := make(map[string]string)
+	locators := append(manifest.AllPackageDependencies(), manifest.Dependencies.GetApps()...)
+	if base := manifest.Base(); base != nil {
+		locators = append(locators, *base)
+	}
+	for _, locator := range locators {
+		versions[fmt.Sprintf("%v/%v", locator.Repository, locator.Name)] = locator.Version
+	}
+	return versions
+}
+
+// toMap converts the value into a generic map using its JSON representation
+func toMap(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := make(map[string]interface{})
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return result, nil
+}
+
+// formatValue formats the generic JSON value for output
+func formatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func unionKeys(a, b map[string]interface{}) (keys []string) {
+	seen := make(map[string]struct{})
+	for _, m := range []map[string]interface{}{a, b} {
+		for key := range m {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func unionStringKeys(a, b map[string]string) (keys []string) {
+	seen := make(map[string]struct{})
+	for _, m := range []map[string]string{a, b} {
+		for key := range m {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"gopkg.in/check.v1"
+)
+
+func TestDiff(t *testing.T) { check.TestingT(t) }
+
+type DiffSuite struct{}
+
+var _ = check.Suite(&DiffSuite{})
+
+func (s *DiffSuite) TestComparesContents(c *check.C) {
+	old := contents{
+		locator: loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		manifest: parseManifest(c, `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app
+  resourceVersion: 1.0.0
+dependencies:
+  packages:
+  - gravitational.io/planet:5.0.0
+  - gravitational.io/teleport:3.0.0
+  apps:
+  - gravitational.io/dns-app:0.1.0
+nodeProfiles:
+- name: node
+  requirements:
+    cpu:
+      min: 2
+- name: worker
+hooks:
+  install:
+    job: install-v1
+  uninstall:
+    job: uninstall
+`),
+		images: map[string]string{
+			"nginx:1.16": "sha256:aaa",
+			"redis:5":    "sha256:bbb",
+		},
+		charts: map[string]string{"app": "1.0.0"},
+	}
+	new := contents{
+		locator: loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		manifest: parseManifest(c, `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app
+  resourceVersion: 2.0.0
+releaseNotes: fixes
+dependencies:
+  packages:
+  - gravitational.io/planet:5.0.1
+  - gravitational.io/teleport:3.0.0
+  apps:
+  - gravitational.io/dns-app:0.1.0
+  - gravitational.io/monitoring-app:1.0.0
+nodeProfiles:
+- name: node
+  requirements:
+    cpu:
+      min: 4
+- name: db
+hooks:
+  install:
+    job: install-v2
+  uninstall:
+    job: uninstall
+`),
+		images: map[string]string{
+			"nginx:1.17": "sha256:ccc",
+			"redis:5":    "sha256:ddd",
+		},
+		charts: map[string]string{"app": "2.0.0", "db": "0.1.0"},
+	}
+
+	diff, err := compareContents(old, new)
+	c.Assert(err, check.IsNil)
+	c.Assert(diff.Manifest, check.DeepEquals, []Change{
+		{Type: ChangeModified, Name: "metadata.resourceVersion", Old: "1.0.0", New: "2.0.0"},
+		{Type: ChangeAdded, Name: "releaseNotes", New: "fixes"},
+	})
+	c.Assert(diff.NodeProfiles, check.DeepEquals, []Change{
+		{Type: ChangeAdded, Name: "db"},
+		{Type: ChangeModified, Name: "node", Fields: []string{"requirements.cpu.min"}},
+		{Type: ChangeRemoved, Name: "worker"},
+	})
+	c.Assert(diff.Hooks, check.DeepEquals, []Change{
+		{Type: ChangeModified, Name: "install", Fields: []string{"job"}},
+	})
+	c.Assert(diff.Packages, check.DeepEquals, []Change{
+		{Type: ChangeAdded, Name: "gravitational.io/monitoring-app", New: "1.0.0"},
+		{Type: ChangeModified, Name: "gravitational.io/planet", Old: "5.0.0", New: "5.0.1"},
+	})
+	c.Assert(diff.Images, check.DeepEquals, []Change{
+		{Type: ChangeRemoved, Name: "nginx:1.16", Old: "sha256:aaa"},
+		{Type: ChangeAdded, Name: "nginx:1.17", New: "sha256:ccc"},
+		{Type: ChangeModified, Name: "redis:5", Old: "sha256:bbb", New: "sha256:ddd"},
+	})
+	c.Assert(diff.Charts, check.DeepEquals, []Change{
+		{Type: ChangeModified, Name: "app", Old: "1.0.0", New: "2.0.0"},
+		{Type: ChangeAdded, Name: "db", New: "0.1.0"},
+	})
+
+	var buf bytes.Buffer
+	c.Assert(diff.WriteText(&buf), check.IsNil)
+	c.Assert(buf.String(), check.Matches, `(?s)Comparing gravitational.io/app:1.0.0 with gravitational.io/app:2.0.0
+.*Node profiles:
+  \+ db
+  ~ node \(requirements.cpu.min\)
+  - worker
+.*  ~ gravitational.io/planet: 5.0.0 -> 5.0.1
+.*`)
+}
+
+func (s *DiffSuite) TestReportsNoDifferences(c *check.C) {
+	app := contents{
+		locator:  loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		manifest: parseManifest(c, "apiVersion: bundle.gravitational.io/v2\nkind: Bundle\nmetadata:\n  name: app\n  resourceVersion: 1.0.0\n"),
+	}
+	diff, err := compareContents(app, app)
+	c.Assert(err, check.IsNil)
+	c.Assert(diff.IsEmpty(), check.Equals, true)
+	var buf bytes.Buffer
+	c.Assert(diff.WriteText(&buf), check.IsNil)
+	c.Assert(buf.String(), check.Matches, "(?s).*No differences found\n")
+}
+
+func parseManifest(c *check.C, data string) schema.Manifest {
+	manifest, err := schema.ParseManifestYAMLNoValidate([]byte(data))
+	c.Assert(err, check.IsNil)
+	return *manifest
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	digest "github.com/opencontainers/go-digest"
+	log "github.com/sirupsen/logrus"
+)
+
+// ImageCache is a persistent content-addressed cache of images exported
+// into registry directories.
+//
+// Images are keyed by their docker image ID. Blobs are stored using the
+// registry blob layout and shared between images, and are hard-linked into
+// the target registry directory when possible.
+type ImageCache struct {
+	// Dir is the cache directory
+	Dir string
+}
+
+// NewImageCache returns a new image cache in the specified directory
+func NewImageCache(dir string) (*ImageCache, error) {
+	err := os.MkdirAll(filepath.Join(dir, imageCacheIndexDir), defaults.SharedDirMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return &ImageCache{Dir: dir}, nil
+}
+
+// Export makes the cached image with the specified ID available in the
+// registry with the specified root directory under the provided repository and tag.
+// Returns false if the image is not in the cache
+func (c *ImageCache) Export(id, root, repository, tag string) (bool, error) {
+	entryPath := c.entryPath(id)
+	data, err := ioutil.ReadFile(entryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, trace.ConvertSystemError(err)
+	}
+	dgst, err := digest.Parse(strings.TrimSpace(string(data)))
+	if err != nil {
+		log.Warnf("Invalid image cache entry %v: %v.", entryPath, err)
+		return false, nil
+	}
+	manifest, err := readManifest(c.Dir, dgst)
+	if err != nil {
+		log.Warnf("Failed to read cached manifest of %v: %v.", id, trace.DebugReport(err))
+		return false, nil
+	}
+	image := RegistryImage{
+		Repository: repository,
+		Tag:        tag,
+		Digest:     dgst,
+		Manifest:   *manifest,
+	}
+	for _, blob := range append([]digest.Digest{dgst}, image.Blobs()...) {
+		err := linkBlob(RegistryBlobPath(c.Dir, blob), RegistryBlobPath(root, blob))
+		if err != nil {
+			if trace.IsNotFound(err) {
+				log.Warnf("Cached blob %v of %v is missing.", blob, id)
+				return false, nil
+			}
+			return false, trace.Wrap(err)
+		}
+	}
+	if err := LinkRegistryImage(root, image); err != nil {
+		return false, trace.Wrap(err)
+	}
+	// mark the entry as recently used
+	now := time.Now()
+	if err := os.Chtimes(entryPath, now, now); err != nil {
+		return false, trace.ConvertSystemError(err)
+	}
+	return true, nil
+}
+
+// Import adds the image with the specified ID exported to the registry with
+// the specified root directory under the provided repository and tag to the cache
+func (c *ImageCache) Import(id, root, repository, tag string) error {
+	image, err := ReadRegistryImage(root, repository, tag)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, blob := range append([]digest.Digest{image.Digest}, image.Blobs()...) {
+		err := linkBlob(RegistryBlobPath(root, blob), RegistryBlobPath(c.Dir, blob))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(writeAtomic(c.entryPath(id), []byte(image.Digest)))
+}
+
+// Prune removes images that have not been used for longer than maxAge
+// and blobs no longer referenced by any cached image.
+// Returns the number of bytes freed
+func (c *ImageCache) Prune(maxAge time.Duration) (freed int64, err error) {
+	entries, err := ioutil.ReadDir(filepath.Join(c.Dir, imageCacheIndexDir))
+	if err != nil {
+		return 0, trace.ConvertSystemError(err)
+	}
+	referenced := map[digest.Digest]struct{}{}
+	for _, entry := range entries {
+		path := filepath.Join(c.Dir, imageCacheIndexDir, entry.Name())
+		if time.Since(entry.ModTime()) > maxAge {
+			log.Debugf("Pruning cached image %v.", entry.Name())
+			if err := os.Remove(path); err != nil {
+				return 0, trace.ConvertSystemError(err)
+			}
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return 0, trace.ConvertSystemError(err)
+		}
+		dgst, err := digest.Parse(strings.TrimSpace(string(data)))
+		if err != nil {
+			continue
+		}
+		manifest, err := readManifest(c.Dir, dgst)
+		if err != nil {
+			continue
+		}
+		image := RegistryImage{Digest: dgst, Manifest: *manifest}
+		for _, blob := range append([]digest.Digest{dgst}, image.Blobs()...) {
+			referenced[blob] = struct{}{}
+		}
+	}
+	blobsDir := filepath.Join(c.Dir, registryBlobsDir)
+	err = filepath.Walk(blobsDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return trace.ConvertSystemError(err)
+		}
+		if fi.IsDir() || fi.Name() != "data" {
+			return nil
+		}
+		hexDir := filepath.Dir(path)
+		algorithm := filepath.Base(filepath.Dir(filepath.Dir(hexDir)))
+		blob := digest.NewDigestFromHex(algorithm, filepath.Base(hexDir))
+		if _, ok := referenced[blob]; ok {
+			return nil
+		}
+		if err := os.RemoveAll(hexDir); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		freed += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	return freed, nil
+}
+
+// entryPath returns the path of the index entry of the image with the specified ID
+func (c *ImageCache) entryPath(id string) string {
+	return filepath.Join(c.Dir, imageCacheIndexDir, strings.Replace(id, ":", "-", -1))
+}
+
+// linkBlob hard-links the blob at src to dst falling back to copying
+// if hard links are not supported. Does nothing if dst already exists
+func linkBlob(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(src); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	tmp, err := tempPath(dst)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.Link(src, tmp); err != nil {
+		if err := utils.CopyFile(tmp, src); err != nil {
+			os.Remove(tmp)
+			return trace.Wrap(err)
+		}
+	}
+	// rename is atomic so concurrent exports of images that share
+	// blobs always observe complete files
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// writeAtomic writes data to the file at the specified path atomically
+func writeAtomic(path string, data []byte) error {
+	tmp, err := tempPath(path)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := ioutil.WriteFile(tmp, data, defaults.SharedReadMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+func tempPath(path string) (string, error) {
+	suffix, err := teleutils.CryptoRandomHex(4)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return path + ".tmp-" + suffix, nil
+}
+
+// imageCacheIndexDir is the directory with image entries inside the cache directory
+const imageCacheIndexDir = "images"
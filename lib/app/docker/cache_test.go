@@ -0,0 +1,103 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	. "gopkg.in/check.v1"
+)
+
+type ImageCacheSuite struct{}
+
+var _ = Suite(&ImageCacheSuite{})
+
+func (_ *ImageCacheSuite) TestImportsAndExportsImages(c *C) {
+	cache, err := NewImageCache(c.MkDir())
+	c.Assert(err, IsNil)
+
+	source := c.MkDir()
+	layer := writeRegistryBlob(c, source, "layer")
+	writeRegistryBlob(c, source, "example/web")
+	writeRegistryImage(c, source, "example/web", "1.0", layer)
+
+	target := c.MkDir()
+	cached, err := cache.Export("sha256:abc", target, "example/web", "1.0")
+	c.Assert(err, IsNil)
+	c.Assert(cached, Equals, false)
+
+	c.Assert(cache.Import("sha256:abc", source, "example/web", "1.0"), IsNil)
+	cached, err = cache.Export("sha256:abc", target, "example/web", "1.0")
+	c.Assert(err, IsNil)
+	c.Assert(cached, Equals, true)
+
+	images, err := ListRegistryImages(target)
+	c.Assert(err, IsNil)
+	c.Assert(images, HasLen, 1)
+	c.Assert(images[0].Repository, Equals, "example/web")
+	for _, blob := range images[0].Blobs() {
+		_, err := os.Stat(RegistryBlobPath(target, blob))
+		c.Assert(err, IsNil)
+		_, err = os.Stat(filepath.Join(target, registryRepositoriesDir, "example/web",
+			"_layers", "sha256", blob.Hex(), "link"))
+		c.Assert(err, IsNil)
+	}
+}
+
+func (_ *ImageCacheSuite) TestPrunesUnusedImages(c *C) {
+	cache, err := NewImageCache(c.MkDir())
+	c.Assert(err, IsNil)
+
+	source := c.MkDir()
+	shared := writeRegistryBlob(c, source, "shared")
+	old := writeRegistryBlob(c, source, "old")
+	writeRegistryBlob(c, source, "example/old")
+	writeRegistryBlob(c, source, "example/new")
+	writeRegistryImage(c, source, "example/old", "1.0", shared, old)
+	writeRegistryImage(c, source, "example/new", "1.0", shared)
+	c.Assert(cache.Import("sha256:old", source, "example/old", "1.0"), IsNil)
+	c.Assert(cache.Import("sha256:new", source, "example/new", "1.0"), IsNil)
+
+	lastUsed := time.Now().Add(-2 * time.Hour)
+	c.Assert(os.Chtimes(cache.entryPath("sha256:old"), lastUsed, lastUsed), IsNil)
+
+	freed, err := cache.Prune(time.Hour)
+	c.Assert(err, IsNil)
+	c.Assert(freed > 0, Equals, true)
+
+	_, err = os.Stat(RegistryBlobPath(cache.Dir, shared))
+	c.Assert(err, IsNil)
+	_, err = os.Stat(RegistryBlobPath(cache.Dir, old))
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	cached, err := cache.Export("sha256:old", c.MkDir(), "example/old", "1.0")
+	c.Assert(err, IsNil)
+	c.Assert(cached, Equals, false)
+	cached, err = cache.Export("sha256:new", c.MkDir(), "example/new", "1.0")
+	c.Assert(err, IsNil)
+	c.Assert(cached, Equals, true)
+}
+
+// writeRegistryBlob writes the blob with the specified contents to the registry
+func writeRegistryBlob(c *C, root, data string) digest.Digest {
+	dgst := digest.FromString(data)
+	writeRegistryFile(c, RegistryBlobPath(root, dgst), []byte(data))
+	return dgst
+}
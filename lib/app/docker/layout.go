@@ -0,0 +1,212 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/gravitational/trace"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// RegistryImage describes an image stored in the directory of
+// a registry with filesystem storage, e.g. the registry vendored
+// into an application package
+type RegistryImage struct {
+	// Repository is the image repository, e.g. gravitational/debian-tall
+	Repository string
+	// Tag is the image tag
+	Tag string
+	// Digest is the digest of the image manifest
+	Digest digest.Digest
+	// Manifest is the image manifest
+	Manifest schema2.Manifest
+}
+
+// ListRegistryImages returns all tagged images stored in the registry
+// with the specified root directory, sorted by repository and tag.
+// Returns an empty list if the directory does not contain a registry
+func ListRegistryImages(root string) (images []RegistryImage, err error) {
+	repositories := filepath.Join(root, registryRepositoriesDir)
+	err = filepath.Walk(repositories, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return trace.ConvertSystemError(err)
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+		switch fi.Name() {
+		case "_layers", "_uploads":
+			return filepath.SkipDir
+		case "_manifests":
+		default:
+			return nil
+		}
+		repository, err := filepath.Rel(repositories, filepath.Dir(path))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		tagged, err := repositoryImages(root, filepath.ToSlash(repository), path)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		images = append(images, tagged...)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Repository != images[j].Repository {
+			return images[i].Repository < images[j].Repository
+		}
+		return images[i].Tag < images[j].Tag
+	})
+	return images, nil
+}
+
+// RegistryBlobPath returns the path of the blob with the specified digest
+// in the registry with the specified root directory
+func RegistryBlobPath(root string, dgst digest.Digest) string {
+	hex := dgst.Hex()
+	prefix := hex
+	if len(prefix) > 2 {
+		prefix = prefix[:2]
+	}
+	return filepath.Join(root, registryBlobsDir, string(dgst.Algorithm()), prefix, hex, "data")
+}
+
+// ReadRegistryImage returns the image with the specified repository and tag
+// from the registry with the specified root directory
+func ReadRegistryImage(root, repository, tag string) (*RegistryImage, error) {
+	link, err := ioutil.ReadFile(filepath.Join(root, registryRepositoriesDir, repository,
+		"_manifests", "tags", tag, "current", "link"))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	dgst, err := digest.Parse(strings.TrimSpace(string(link)))
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid manifest link of %v:%v", repository, tag)
+	}
+	manifest, err := readManifest(root, dgst)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read manifest of %v:%v", repository, tag)
+	}
+	return &RegistryImage{
+		Repository: repository,
+		Tag:        tag,
+		Digest:     dgst,
+		Manifest:   *manifest,
+	}, nil
+}
+
+// LinkRegistryImage makes the image available in the registry with the
+// specified root directory under the image repository and tag.
+// The manifest, config and layer blobs of the image must already be present in the registry
+func LinkRegistryImage(root string, image RegistryImage) error {
+	repositoryDir := filepath.Join(root, registryRepositoriesDir, image.Repository)
+	links := []string{
+		filepath.Join(repositoryDir, "_manifests", "revisions", string(image.Digest.Algorithm()), image.Digest.Hex()),
+		filepath.Join(repositoryDir, "_manifests", "tags", image.Tag, "current"),
+		filepath.Join(repositoryDir, "_manifests", "tags", image.Tag, "index", string(image.Digest.Algorithm()), image.Digest.Hex()),
+	}
+	if err := writeLinks(links, image.Digest); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, blob := range image.Blobs() {
+		link := filepath.Join(repositoryDir, "_layers", string(blob.Algorithm()), blob.Hex())
+		if err := writeLinks([]string{link}, blob); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// Blobs returns digests of the config and layer blobs of the image
+func (r RegistryImage) Blobs() (blobs []digest.Digest) {
+	if r.Manifest.Config.Digest != "" {
+		blobs = append(blobs, r.Manifest.Config.Digest)
+	}
+	for _, layer := range r.Manifest.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	return blobs
+}
+
+func writeLinks(dirs []string, dgst digest.Digest) error {
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, defaults.SharedDirMask); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		err := ioutil.WriteFile(filepath.Join(dir, "link"), []byte(dgst), defaults.SharedReadMask)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	return nil
+}
+
+// repositoryImages returns all tagged images of the registry repository
+// with the specified manifests directory
+func repositoryImages(root, repository, manifestsDir string) (images []RegistryImage, err error) {
+	tags, err := ioutil.ReadDir(filepath.Join(manifestsDir, "tags"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	for _, tag := range tags {
+		image, err := ReadRegistryImage(root, repository, tag.Name())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		images = append(images, *image)
+	}
+	return images, nil
+}
+
+// readManifest reads the image manifest with the specified digest from the registry
+func readManifest(root string, dgst digest.Digest) (*schema2.Manifest, error) {
+	data, err := ioutil.ReadFile(RegistryBlobPath(root, dgst))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var manifest schema2.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &manifest, nil
+}
+
+var (
+	// registryRepositoriesDir is the directory with repositories inside the registry directory
+	registryRepositoriesDir = filepath.Join("docker", "registry", "v2", "repositories")
+	// registryBlobsDir is the directory with blobs inside the registry directory
+	registryBlobsDir = filepath.Join("docker", "registry", "v2", "blobs")
+)
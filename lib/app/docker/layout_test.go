@@ -0,0 +1,85 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	digest "github.com/opencontainers/go-digest"
+	. "gopkg.in/check.v1"
+)
+
+type LayoutSuite struct{}
+
+var _ = Suite(&LayoutSuite{})
+
+func (_ *LayoutSuite) TestListsRegistryImages(c *C) {
+	root := c.MkDir()
+	layer := digest.FromString("layer")
+	writeRegistryImage(c, root, "example/web", "1.0", layer)
+	writeRegistryImage(c, root, "alpine", "3.9", layer)
+	// layer links and uploads are not images
+	writeRegistryFile(c, filepath.Join(root, registryRepositoriesDir, "alpine",
+		"_layers", "sha256", layer.Hex(), "link"), []byte(layer))
+
+	images, err := ListRegistryImages(root)
+	c.Assert(err, IsNil)
+	c.Assert(images, HasLen, 2)
+	c.Assert(images[0].Repository, Equals, "alpine")
+	c.Assert(images[0].Tag, Equals, "3.9")
+	c.Assert(images[1].Repository, Equals, "example/web")
+	c.Assert(images[1].Tag, Equals, "1.0")
+	c.Assert(images[1].Manifest.Layers, HasLen, 1)
+	c.Assert(images[1].Manifest.Layers[0].Digest, Equals, layer)
+}
+
+func (_ *LayoutSuite) TestListsMissingRegistry(c *C) {
+	images, err := ListRegistryImages(filepath.Join(c.MkDir(), "registry"))
+	c.Assert(err, IsNil)
+	c.Assert(images, HasLen, 0)
+}
+
+func writeRegistryImage(c *C, root, repository, tag string, layers ...digest.Digest) {
+	manifest := schema2.Manifest{
+		Config: distribution.Descriptor{
+			MediaType: schema2.MediaTypeImageConfig,
+			Digest:    digest.FromString(repository),
+		},
+	}
+	for _, layer := range layers {
+		manifest.Layers = append(manifest.Layers, distribution.Descriptor{
+			MediaType: schema2.MediaTypeLayer,
+			Digest:    layer,
+		})
+	}
+	data, err := json.Marshal(manifest)
+	c.Assert(err, IsNil)
+	dgst := digest.FromBytes(data)
+	writeRegistryFile(c, RegistryBlobPath(root, dgst), data)
+	writeRegistryFile(c, filepath.Join(root, registryRepositoriesDir, repository,
+		"_manifests", "tags", tag, "current", "link"), []byte(dgst))
+}
+
+func writeRegistryFile(c *C, path string, data []byte) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(path, data, 0644), IsNil)
+}
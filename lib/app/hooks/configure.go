@@ -98,6 +98,16 @@ func configureMetadata(job *batchv1.Job, p Params) error {
 				job.Spec.Template.Spec.Containers[i].Env,
 				v1.EnvVar{Name: name, Value: value})
 		}
+		if p.TrustedCA {
+			for _, name := range CABundleEnvs {
+				if _, ok := p.Env[name]; ok {
+					continue
+				}
+				job.Spec.Template.Spec.Containers[i].Env = append(
+					job.Spec.Template.Spec.Containers[i].Env,
+					v1.EnvVar{Name: name, Value: CABundlePath})
+			}
+		}
 		job.Spec.Template.Spec.Containers[i].Env = append(
 			job.Spec.Template.Spec.Containers[i].Env,
 			v1.EnvVar{
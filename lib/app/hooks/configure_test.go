@@ -24,6 +24,7 @@ import (
 	"github.com/gravitational/rigging"
 	"gopkg.in/check.v1"
 	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -48,3 +49,22 @@ func (s *ConfigureSuite) TestConfigureMetadata(c *check.C) {
 	c.Assert(*job.Spec.ActiveDeadlineSeconds, check.Equals, int64(deadline.Seconds()))
 	c.Assert(job.Spec.Template.Spec.SecurityContext, check.DeepEquals, defaults.HookSecurityContext())
 }
+
+func (s *ConfigureSuite) TestConfiguresTrustedCA(c *check.C) {
+	job := &batchv1.Job{}
+	job.Spec.Template.Spec.Containers = []v1.Container{{Name: "hook"}}
+	err := configureMetadata(job, Params{
+		Env:       map[string]string{"SSL_CERT_FILE": "/custom.pem"},
+		TrustedCA: true,
+	})
+	c.Assert(err, check.IsNil)
+
+	env := make(map[string]string)
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	c.Assert(env["SSL_CERT_FILE"], check.Equals, "/custom.pem")
+	c.Assert(env["CURL_CA_BUNDLE"], check.Equals, CABundlePath)
+	c.Assert(env["REQUESTS_CA_BUNDLE"], check.Equals, CABundlePath)
+	c.Assert(env["NODE_EXTRA_CA_CERTS"], check.Equals, CABundlePath)
+}
@@ -67,6 +67,10 @@ const (
 	// VolumeCerts is the name of the volume with host's certificates
 	VolumeCerts = "certs"
 
+	// CABundlePath is the path to the system certificate bundle inside hook containers.
+	// It includes the cluster's trusted certificate authorities
+	CABundlePath = "/etc/ssl/certs/ca-certificates.crt"
+
 	// VolumeGravity is the name of the volume with local gravity state
 	VolumeGravity = "gravity"
 
@@ -84,3 +88,12 @@ const (
 
 // InitContainerImage is the image for the init container
 var InitContainerImage = fmt.Sprintf("%v/gravitational/debian-tall:0.0.1", constants.DockerRegistry)
+
+// CABundleEnvs lists environment variables commonly used by tools and
+// language runtimes to locate the certificate authority bundle
+var CABundleEnvs = []string{
+	"SSL_CERT_FILE",
+	"CURL_CA_BUNDLE",
+	"REQUESTS_CA_BUNDLE",
+	"NODE_EXTRA_CA_CERTS",
+}
@@ -83,6 +83,10 @@ type Params struct {
 	// ServiceUser specifies the service user which overrides the default
 	// security context for the job's Pod
 	ServiceUser storage.OSUser
+	// TrustedCA indicates that the cluster has custom trusted certificate
+	// authorities installed, in which case hook containers are configured
+	// to use the system certificate bundle that includes them
+	TrustedCA bool
 }
 
 // JobRef is a reference to a hook job
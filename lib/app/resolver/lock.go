@@ -0,0 +1,107 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+)
+
+// LockFile records the versions the application dependencies have been resolved to
+type LockFile struct {
+	// Dependencies lists the resolved dependencies
+	Dependencies []LockedDependency `json:"dependencies"`
+}
+
+// LockedDependency is a dependency resolved to an exact version
+type LockedDependency struct {
+	// Name is the dependency name in the repository/name format
+	Name string `json:"name"`
+	// Version is the resolved version
+	Version string `json:"version"`
+	// Requirements lists the requirements the version has been resolved from
+	Requirements []string `json:"requirements,omitempty"`
+	// Source describes where the version has been found
+	Source string `json:"source,omitempty"`
+}
+
+// Version returns the locked version of the specified application
+func (r LockFile) Version(repository, name string) (version string, ok bool) {
+	key := repository + "/" + name
+	for _, dep := range r.Dependencies {
+		if dep.Name == key {
+			return dep.Version, true
+		}
+	}
+	return "", false
+}
+
+// Locator returns the locator of the specified application with the locked version
+func (r LockFile) Locator(repository, name string) (*loc.Locator, error) {
+	version, ok := r.Version(repository, name)
+	if !ok {
+		return nil, trace.NotFound("%v/%v is not locked", repository, name)
+	}
+	return loc.NewLocator(repository, name, version)
+}
+
+// Write writes the lock file to the specified path
+func (r LockFile) Write(path string) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ioutil.WriteFile(path, data, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// ReadLockFile reads the lock file at the specified path
+func ReadLockFile(path string) (*LockFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, trace.NotFound("lock file %v not found", path)
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	var lock LockFile
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, trace.BadParameter("failed to parse lock file %v: %v", path, err)
+	}
+	return &lock, nil
+}
+
+func newLockedDependency(locator loc.Locator, requirements []Requirement, source string) LockedDependency {
+	dep := LockedDependency{
+		Name:    locator.Repository + "/" + locator.Name,
+		Version: locator.Version,
+		Source:  source,
+	}
+	for _, req := range requirements {
+		dep.Requirements = append(dep.Requirements, req.String())
+	}
+	return dep
+}
@@ -0,0 +1,377 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/loc"
+
+	"github.com/Masterminds/semver"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Requirement is a constraint on the version of an application dependency
+type Requirement struct {
+	// Repository is the dependency repository
+	Repository string
+	// Name is the dependency name
+	Name string
+	// Constraint is the semver range or the exact version of the dependency
+	Constraint string
+	// RequiredBy describes where the requirement comes from, e.g. the
+	// manifest file or the locator of the application that depends on it
+	RequiredBy string
+}
+
+// String formats the requirement as "constraint (required by ...)"
+func (r Requirement) String() string {
+	return fmt.Sprintf("%v (required by %v)", r.Constraint, r.RequiredBy)
+}
+
+// key returns the repository/name key of the required application
+func (r Requirement) key() string {
+	return fmt.Sprintf("%v/%v", r.Repository, r.Name)
+}
+
+// Source lists application versions available in a repository
+type Source interface {
+	// Versions returns all versions of the specified application in the source
+	Versions(repository, name string) ([]string, error)
+	// String describes the source
+	String() string
+}
+
+// DependenciesFunc returns the requirements of the specified application,
+// or nil if the application manifest is not available
+type DependenciesFunc func(loc.Locator) ([]Requirement, error)
+
+// Config is the dependency resolver configuration
+type Config struct {
+	// Sources lists the sources of available versions in the order of preference
+	Sources []Source
+	// Locked is the optional lock file of the previous build. Locked versions are
+	// preferred over the latest ones as long as they satisfy the requirements
+	Locked *LockFile
+	// Dependencies optionally returns the requirements of the resolved
+	// applications to resolve the transitive dependencies
+	Dependencies DependenciesFunc
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if len(c.Sources) == 0 {
+		return trace.BadParameter("at least one source is required")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "resolver")
+	}
+	return nil
+}
+
+// Resolve selects the versions of the required applications that satisfy
+// all requirements, including the ones of the transitive dependencies,
+// and returns them as a lock file. The highest satisfying version is
+// selected unless a satisfying version is locked.
+//
+// Returns an aggregate of ConflictError if no version of an application
+// satisfies all of its requirements
+func Resolve(config Config, requirements []Requirement) (*LockFile, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	r := &resolver{
+		Config:     config,
+		available:  make(map[string][]candidate),
+		transitive: make(map[loc.Locator][]Requirement),
+	}
+	return r.resolve(requirements)
+}
+
+type resolver struct {
+	Config
+	// available caches available versions by application key
+	available map[string][]candidate
+	// transitive caches the requirements of the resolved applications
+	transitive map[loc.Locator][]Requirement
+}
+
+// candidate is an available version of an application
+type candidate struct {
+	version *semver.Version
+	// source describes where the version is available, empty
+	// for exact versions not found in any source
+	source string
+}
+
+func (r *resolver) resolve(direct []Requirement) (*LockFile, error) {
+	resolved := make(map[string]loc.Locator)
+	for iteration := 0; ; iteration++ {
+		if iteration > maxIterations {
+			return nil, trace.LimitExceeded("dependency resolution did not converge "+
+				"after %v iterations", maxIterations)
+		}
+		requirements := append([]Requirement{}, direct...)
+		for _, locator := range sortedLocators(resolved) {
+			requirements = append(requirements, r.transitive[locator]...)
+		}
+		keys, grouped := group(requirements)
+		var errors []error
+		lock := &LockFile{}
+		next := make(map[string]loc.Locator)
+		for _, key := range keys {
+			reqs := grouped[key]
+			selected, err := r.pick(reqs)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			locator := loc.Locator{
+				Repository: reqs[0].Repository,
+				Name:       reqs[0].Name,
+				Version:    selected.version.Original(),
+			}
+			next[key] = locator
+			lock.Dependencies = append(lock.Dependencies, newLockedDependency(locator, reqs, selected.source))
+		}
+		if len(errors) != 0 {
+			return nil, trace.NewAggregate(errors...)
+		}
+		expanded, err := r.expand(next)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if !expanded && sameLocators(resolved, next) {
+			return lock, nil
+		}
+		resolved = next
+	}
+}
+
+// expand fetches the requirements of the resolved applications not seen before.
+// Returns true if new requirements have been found
+func (r *resolver) expand(resolved map[string]loc.Locator) (expanded bool, err error) {
+	if r.Dependencies == nil {
+		return false, nil
+	}
+	for _, locator := range sortedLocators(resolved) {
+		if _, ok := r.transitive[locator]; ok {
+			continue
+		}
+		requirements, err := r.Dependencies(locator)
+		if err != nil {
+			return false, trace.Wrap(err)
+		}
+		if requirements == nil {
+			requirements = []Requirement{}
+		}
+		r.transitive[locator] = requirements
+		expanded = expanded || len(requirements) != 0
+	}
+	return expanded, nil
+}
+
+// pick selects the version of the application that satisfies all requirements
+func (r *resolver) pick(requirements []Requirement) (*candidate, error) {
+	first := requirements[0]
+	candidates, err := r.versions(first.Repository, first.Name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var constraints []*semver.Constraints
+	for _, req := range requirements {
+		constraint, err := semver.NewConstraint(req.Constraint)
+		if err != nil {
+			return nil, trace.BadParameter("invalid version constraint %q for %v: %v",
+				req.Constraint, first.key(), err)
+		}
+		constraints = append(constraints, constraint)
+		// exact versions are accepted even if not found in any source
+		// to preserve the behavior of the dependencies without constraints
+		if version, err := semver.NewVersion(req.Constraint); err == nil && !hasVersion(candidates, version) {
+			candidates = append(candidates, candidate{version: version})
+		}
+	}
+	var satisfying []candidate
+	for _, c := range candidates {
+		if satisfiesAll(c.version, constraints) {
+			satisfying = append(satisfying, c)
+		}
+	}
+	if len(satisfying) == 0 {
+		return nil, &ConflictError{
+			Name:         first.key(),
+			Requirements: requirements,
+			Available:    versionStrings(candidates),
+		}
+	}
+	sort.Slice(satisfying, func(i, j int) bool {
+		return satisfying[i].version.GreaterThan(satisfying[j].version)
+	})
+	if r.Locked != nil {
+		if locked, ok := r.Locked.Version(first.Repository, first.Name); ok {
+			for _, c := range satisfying {
+				if c.version.Original() == locked {
+					r.Debugf("Using locked version %v of %v.", locked, first.key())
+					return &c, nil
+				}
+			}
+		}
+	}
+	return &satisfying[0], nil
+}
+
+// versions returns the available versions of the application from all sources
+func (r *resolver) versions(repository, name string) ([]candidate, error) {
+	key := fmt.Sprintf("%v/%v", repository, name)
+	if candidates, ok := r.available[key]; ok {
+		return append([]candidate{}, candidates...), nil
+	}
+	var candidates []candidate
+	for _, source := range r.Sources {
+		versions, err := source.Versions(repository, name)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err, "failed to list versions of %v in %v", key, source)
+		}
+		for _, v := range versions {
+			version, err := semver.NewVersion(v)
+			if err != nil {
+				r.Debugf("Skipping version %q of %v in %v: %v.", v, key, source, err)
+				continue
+			}
+			if !hasVersion(candidates, version) {
+				candidates = append(candidates, candidate{version: version, source: source.String()})
+			}
+		}
+	}
+	r.available[key] = candidates
+	return append([]candidate{}, candidates...), nil
+}
+
+// ConflictError is returned when no available version of an application
+// satisfies all of its requirements
+type ConflictError struct {
+	// Name is the application name in the repository/name format
+	Name string
+	// Requirements lists the conflicting requirements
+	Requirements []Requirement
+	// Available lists the available versions
+	Available []string
+}
+
+// Error formats the conflict with all requirements and available versions
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "no version of %v satisfies all requirements:", e.Name)
+	for _, req := range e.Requirements {
+		fmt.Fprintf(&b, "\n  %v", req)
+	}
+	if len(e.Available) == 0 {
+		fmt.Fprintf(&b, "\nno versions are available")
+	} else {
+		fmt.Fprintf(&b, "\navailable versions: %v", strings.Join(e.Available, ", "))
+	}
+	return b.String()
+}
+
+// IsConflictError returns true if the error is a dependency conflict
+func IsConflictError(err error) bool {
+	if aggregate, ok := trace.Unwrap(err).(trace.Aggregate); ok {
+		for _, err := range aggregate.Errors() {
+			if IsConflictError(err) {
+				return true
+			}
+		}
+		return false
+	}
+	_, ok := trace.Unwrap(err).(*ConflictError)
+	return ok
+}
+
+// group groups the requirements by application preserving the order
+func group(requirements []Requirement) (keys []string, grouped map[string][]Requirement) {
+	grouped = make(map[string][]Requirement)
+	for _, req := range requirements {
+		key := req.key()
+		if _, ok := grouped[key]; !ok {
+			keys = append(keys, key)
+		}
+		grouped[key] = append(grouped[key], req)
+	}
+	return keys, grouped
+}
+
+func satisfiesAll(version *semver.Version, constraints []*semver.Constraints) bool {
+	for _, constraint := range constraints {
+		if !constraint.Check(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasVersion(candidates []candidate, version *semver.Version) bool {
+	for _, c := range candidates {
+		if c.version.Equal(version) {
+			return true
+		}
+	}
+	return false
+}
+
+func versionStrings(candidates []candidate) (versions []string) {
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].version.LessThan(candidates[j].version)
+	})
+	for _, c := range candidates {
+		versions = append(versions, c.version.Original())
+	}
+	return versions
+}
+
+func sortedLocators(locators map[string]loc.Locator) (result []loc.Locator) {
+	for _, locator := range locators {
+		result = append(result, locator)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].String() < result[j].String()
+	})
+	return result
+}
+
+func sameLocators(a, b map[string]loc.Locator) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, locator := range a {
+		if b[key] != locator {
+			return false
+		}
+	}
+	return true
+}
+
+// maxIterations limits the number of resolution passes
+const maxIterations = 100
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/loc"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestResolver(t *testing.T) { check.TestingT(t) }
+
+type ResolverSuite struct{}
+
+var _ = check.Suite(&ResolverSuite{})
+
+func (s *ResolverSuite) TestPicksHighestSatisfyingVersion(c *check.C) {
+	lock, err := Resolve(Config{
+		Sources: []Source{
+			fakeSource{"example.com/db": {"1.0.0", "1.2.0", "1.3.1", "2.0.0"}},
+		},
+	}, []Requirement{
+		{Repository: "example.com", Name: "db", Constraint: "^1.2.0", RequiredBy: "app.yaml"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(versions(lock), check.DeepEquals, map[string]string{"example.com/db": "1.3.1"})
+	c.Assert(lock.Dependencies[0].Source, check.Equals, "fake")
+}
+
+func (s *ResolverSuite) TestPrefersLockedVersion(c *check.C) {
+	lock, err := Resolve(Config{
+		Sources: []Source{
+			fakeSource{"example.com/db": {"1.2.0", "1.3.1"}},
+		},
+		Locked: &LockFile{Dependencies: []LockedDependency{
+			{Name: "example.com/db", Version: "1.2.0"},
+		}},
+	}, []Requirement{
+		{Repository: "example.com", Name: "db", Constraint: "^1.2.0", RequiredBy: "app.yaml"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(versions(lock), check.DeepEquals, map[string]string{"example.com/db": "1.2.0"})
+}
+
+func (s *ResolverSuite) TestResolvesTransitiveDependencies(c *check.C) {
+	lock, err := Resolve(Config{
+		Sources: []Source{
+			fakeSource{
+				"example.com/api": {"1.0.0", "1.1.0"},
+				"example.com/db":  {"1.2.0", "1.3.1", "1.4.0"},
+			},
+		},
+		Dependencies: func(locator loc.Locator) ([]Requirement, error) {
+			if locator.Name != "api" {
+				return nil, nil
+			}
+			return []Requirement{
+				{Repository: "example.com", Name: "db", Constraint: "<1.4.0", RequiredBy: locator.String()},
+			}, nil
+		},
+	}, []Requirement{
+		{Repository: "example.com", Name: "api", Constraint: "~1.1", RequiredBy: "app.yaml"},
+		{Repository: "example.com", Name: "db", Constraint: ">=1.2", RequiredBy: "app.yaml"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(versions(lock), check.DeepEquals, map[string]string{
+		"example.com/api": "1.1.0",
+		"example.com/db":  "1.3.1",
+	})
+}
+
+func (s *ResolverSuite) TestReportsConflicts(c *check.C) {
+	_, err := Resolve(Config{
+		Sources: []Source{
+			fakeSource{"example.com/db": {"1.2.0", "2.0.0"}},
+		},
+	}, []Requirement{
+		{Repository: "example.com", Name: "db", Constraint: "^1.2.0", RequiredBy: "app.yaml"},
+		{Repository: "example.com", Name: "db", Constraint: "2.0.0", RequiredBy: "example.com/api:1.0.0"},
+	})
+	c.Assert(err, check.NotNil)
+	c.Assert(IsConflictError(err), check.Equals, true)
+	c.Assert(trace.UserMessage(err), check.Matches, `(?s).*\^1.2.0 \(required by app.yaml\).*2.0.0 \(required by example.com/api:1.0.0\).*available versions: 1.2.0, 2.0.0.*`)
+}
+
+func (s *ResolverSuite) TestWritesAndReadsLockFile(c *check.C) {
+	path := filepath.Join(c.MkDir(), "app.lock")
+	lock := LockFile{Dependencies: []LockedDependency{
+		{Name: "example.com/db", Version: "1.2.0", Requirements: []string{"^1.2.0 (required by app.yaml)"}},
+	}}
+	c.Assert(lock.Write(path), check.IsNil)
+	read, err := ReadLockFile(path)
+	c.Assert(err, check.IsNil)
+	c.Assert(*read, check.DeepEquals, lock)
+	locator, err := read.Locator("example.com", "db")
+	c.Assert(err, check.IsNil)
+	c.Assert(locator.String(), check.Equals, "example.com/db:1.2.0")
+}
+
+type fakeSource map[string][]string
+
+func (r fakeSource) Versions(repository, name string) ([]string, error) {
+	return r[repository+"/"+name], nil
+}
+
+func (r fakeSource) String() string {
+	return "fake"
+}
+
+func versions(lock *LockFile) map[string]string {
+	result := make(map[string]string)
+	for _, dep := range lock.Dependencies {
+		result[dep.Name] = dep.Version
+	}
+	return result
+}
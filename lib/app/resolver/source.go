@@ -0,0 +1,93 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/hub"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"github.com/gravitational/trace"
+)
+
+// NewPackageSource returns a source of the application versions available
+// in the specified package service, e.g. the local package cache or an Ops Center
+func NewPackageSource(packages pack.PackageService, description string) Source {
+	return &packageSource{
+		packages:    packages,
+		description: description,
+	}
+}
+
+type packageSource struct {
+	packages    pack.PackageService
+	description string
+}
+
+// Versions returns all versions of the specified application in the package service
+func (r *packageSource) Versions(repository, name string) (versions []string, err error) {
+	envelopes, err := r.packages.GetPackages(repository)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, envelope := range envelopes {
+		if envelope.Locator.Name == name {
+			versions = append(versions, envelope.Locator.Version)
+		}
+	}
+	return versions, nil
+}
+
+// String describes the source
+func (r *packageSource) String() string {
+	return r.description
+}
+
+// NewHubSource returns a source of the application versions published in the hub
+func NewHubSource(hub hub.Hub) Source {
+	return &hubSource{hub: hub}
+}
+
+type hubSource struct {
+	hub  hub.Hub
+	apps []hub.App
+}
+
+// Versions returns all versions of the specified application in the hub.
+// The hub only serves applications from the system repository
+func (r *hubSource) Versions(repository, name string) (versions []string, err error) {
+	if repository != defaults.SystemAccountOrg {
+		return nil, nil
+	}
+	if r.apps == nil {
+		r.apps, err = r.hub.List(true)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	for _, app := range r.apps {
+		if app.Name == name {
+			versions = append(versions, app.Version)
+		}
+	}
+	return versions, nil
+}
+
+// String describes the source
+func (r *hubSource) String() string {
+	return "hub"
+}
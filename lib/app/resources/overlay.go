@@ -0,0 +1,249 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/schema"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// Overlay describes modifications to the application manifest and
+// resource files that produce a variant of the application, e.g. for
+// a specific environment:
+//
+//	manifest:
+//	  merge:
+//	    installer:
+//	      flavors:
+//	        default: large
+//	resources:
+//	  - file: resources/app.yaml
+//	    kind: Deployment
+//	    name: web
+//	    merge:
+//	      spec:
+//	        replicas: 3
+type Overlay struct {
+	// Name is the overlay name
+	Name string `json:"-"`
+	// Manifest patches the application manifest
+	Manifest *Patch `json:"manifest,omitempty"`
+	// Resources patches Kubernetes resources in the application resource files
+	Resources []ResourcePatch `json:"resources,omitempty"`
+}
+
+// Patch describes modifications of a single document.
+// The merge patch is applied before the JSON patch
+type Patch struct {
+	// Merge is the strategic merge patch
+	Merge json.RawMessage `json:"merge,omitempty"`
+	// JSON is the list of RFC 6902 JSON patch operations
+	JSON json.RawMessage `json:"json,omitempty"`
+}
+
+// ResourcePatch modifies Kubernetes resources in a resource file
+type ResourcePatch struct {
+	// Patch is the modification applied to the matching resources
+	Patch
+	// File is the path to the resource file relative to the manifest directory
+	File string `json:"file"`
+	// Kind optionally limits the patch to resources of this kind
+	Kind string `json:"kind,omitempty"`
+	// Name optionally limits the patch to resources with this name
+	Name string `json:"name,omitempty"`
+	// Namespace optionally limits the patch to resources in this namespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// LoadOverlay reads the overlay specified either with the path to the overlay
+// file or with the name of the overlay in the overlays directory next to the
+// application manifest in manifestDir
+func LoadOverlay(manifestDir, overlay string) (*Overlay, error) {
+	path := overlay
+	name := strings.TrimSuffix(filepath.Base(overlay), filepath.Ext(overlay))
+	if !strings.ContainsRune(overlay, filepath.Separator) && filepath.Ext(overlay) == "" {
+		path = filepath.Join(manifestDir, defaults.OverlaysDir, overlay+".yaml")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, trace.NotFound("overlay %q not found at %v", overlay, path)
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	var result Overlay
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, trace.BadParameter("failed to parse overlay %v: %v", path, err)
+	}
+	result.Name = name
+	for _, patch := range result.Resources {
+		if patch.File == "" {
+			return nil, trace.BadParameter("overlay %v: resource patch is missing file", path)
+		}
+		if filepath.IsAbs(patch.File) || strings.HasPrefix(filepath.Clean(patch.File), "..") {
+			return nil, trace.BadParameter("overlay %v: resource file %v must be relative "+
+				"to the manifest directory", path, patch.File)
+		}
+	}
+	return &result, nil
+}
+
+// ApplyManifest applies the overlay to the application manifest given as YAML
+// and returns the resulting manifest
+func (r Overlay) ApplyManifest(data []byte) ([]byte, error) {
+	if r.Manifest == nil {
+		return data, nil
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	jsonData, err = r.Manifest.apply(jsonData, schema.Manifest{})
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to apply overlay %q to manifest", r.Name)
+	}
+	return yaml.JSONToYAML(jsonData)
+}
+
+// ApplyResources applies the overlay to the resource files in dir
+// which is a copy of the manifest directory
+func (r Overlay) ApplyResources(dir string) error {
+	for _, patch := range r.Resources {
+		path := filepath.Join(dir, patch.File)
+		file, err := NewResourceFile(path)
+		if err != nil {
+			return trace.Wrap(err, "failed to read resource file %v", patch.File)
+		}
+		var matched int
+		for i, object := range file.Objects {
+			patched, err := patch.applyObject(object)
+			if err != nil {
+				return trace.Wrap(err, "failed to apply overlay %q to %v", r.Name, patch.File)
+			}
+			if patched != nil {
+				file.Objects[i] = patched
+				matched++
+			}
+		}
+		if matched == 0 {
+			return trace.NotFound("overlay %q: no resources in %v match %v",
+				r.Name, patch.File, patch.selector())
+		}
+		log.Debugf("Overlay %q patched %v resource(s) in %v.", r.Name, matched, patch.File)
+		if err := (ResourceFiles{*file}).Write(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// applyObject returns the patched object or nil if the object does not match the patch
+func (r ResourcePatch) applyObject(object runtime.Object) (runtime.Object, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if (r.Kind != "" && r.Kind != header.Kind) ||
+		(r.Name != "" && r.Name != header.Metadata.Name) ||
+		(r.Namespace != "" && r.Namespace != header.Metadata.Namespace) {
+		return nil, nil
+	}
+	var dataStruct interface{}
+	if _, ok := object.(*Unknown); !ok {
+		dataStruct = object
+	}
+	data, err = r.apply(data, dataStruct)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	resource, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(resource.Objects) != 1 {
+		return nil, trace.BadParameter("expected a single resource after patch, got %v",
+			len(resource.Objects))
+	}
+	return resource.Objects[0], nil
+}
+
+// selector describes the resources the patch applies to
+func (r ResourcePatch) selector() string {
+	var selector []string
+	for _, s := range []struct{ name, value string }{
+		{"kind", r.Kind}, {"name", r.Name}, {"namespace", r.Namespace},
+	} {
+		if s.value != "" {
+			selector = append(selector, s.name+"="+s.value)
+		}
+	}
+	if len(selector) == 0 {
+		return "any resource"
+	}
+	return strings.Join(selector, ",")
+}
+
+// apply applies the patch to the JSON document. If dataStruct is set, it is used
+// to look up the strategic merge metadata, otherwise a JSON merge patch is applied
+func (r Patch) apply(data []byte, dataStruct interface{}) (result []byte, err error) {
+	result = data
+	if len(r.Merge) != 0 {
+		if dataStruct != nil {
+			result, err = strategicpatch.StrategicMergePatch(result, r.Merge, dataStruct)
+		} else {
+			result, err = jsonpatch.MergePatch(result, r.Merge)
+		}
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to apply merge patch")
+		}
+	}
+	if len(r.JSON) != 0 {
+		patch, err := jsonpatch.DecodePatch(r.JSON)
+		if err != nil {
+			return nil, trace.Wrap(err, "invalid JSON patch")
+		}
+		result, err = patch.Apply(result)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to apply JSON patch")
+		}
+	}
+	return result, nil
+}
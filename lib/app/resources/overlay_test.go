@@ -0,0 +1,186 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+)
+
+type OverlaySuite struct {
+	dir string
+}
+
+var _ = Suite(&OverlaySuite{})
+
+func (s *OverlaySuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(s.dir, defaults.OverlaysDir), defaults.SharedDirMask), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(s.dir, "resources"), defaults.SharedDirMask), IsNil)
+	s.write(c, filepath.Join(defaults.OverlaysDir, "prod.yaml"), prodOverlay)
+	s.write(c, "resources/app.yaml", overlayResources)
+}
+
+func (s *OverlaySuite) TestAppliesManifestOverlay(c *C) {
+	overlay, err := LoadOverlay(s.dir, "prod")
+	c.Assert(err, IsNil)
+	c.Assert(overlay.Name, Equals, "prod")
+
+	data, err := overlay.ApplyManifest([]byte(overlayManifest))
+	c.Assert(err, IsNil)
+	manifest, err := schema.ParseManifestYAMLNoValidate(data)
+	c.Assert(err, IsNil)
+	c.Assert(manifest.Installer.Flavors.Default, Equals, "large")
+	c.Assert(manifest.FlavorNames(), DeepEquals, []string{"small", "large"})
+	c.Assert(manifest.NodeProfiles[0].Requirements.CPU.Min, Equals, 8)
+}
+
+func (s *OverlaySuite) TestAppliesResourceOverlay(c *C) {
+	overlay, err := LoadOverlay(s.dir, filepath.Join(s.dir, defaults.OverlaysDir, "prod.yaml"))
+	c.Assert(err, IsNil)
+
+	c.Assert(overlay.ApplyResources(s.dir), IsNil)
+
+	file, err := NewResourceFile(filepath.Join(s.dir, "resources/app.yaml"))
+	c.Assert(err, IsNil)
+	c.Assert(file.Objects, HasLen, 2)
+	deployment, ok := file.Objects[0].(*appsv1.Deployment)
+	c.Assert(ok, Equals, true)
+	c.Assert(*deployment.Spec.Replicas, Equals, int32(3))
+	// strategic merge patch merges containers by name
+	c.Assert(deployment.Spec.Template.Spec.Containers, HasLen, 1)
+	c.Assert(deployment.Spec.Template.Spec.Containers[0].Image, Equals, "web:2.0.0")
+	c.Assert(deployment.Spec.Template.Spec.Containers[0].Name, Equals, "web")
+	service, ok := file.Objects[1].(*v1.Service)
+	c.Assert(ok, Equals, true)
+	c.Assert(service.Spec.Type, Equals, v1.ServiceTypeNodePort)
+}
+
+func (s *OverlaySuite) TestFailsOnUnmatchedResources(c *C) {
+	s.write(c, filepath.Join(defaults.OverlaysDir, "typo.yaml"), `resources:
+  - file: resources/app.yaml
+    kind: Deployment
+    name: wbe
+    merge:
+      spec:
+        replicas: 3
+`)
+	overlay, err := LoadOverlay(s.dir, "typo")
+	c.Assert(err, IsNil)
+	err = overlay.ApplyResources(s.dir)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *OverlaySuite) TestRejectsFilesOutsideManifestDir(c *C) {
+	s.write(c, filepath.Join(defaults.OverlaysDir, "bad.yaml"), `resources:
+  - file: ../app.yaml
+`)
+	_, err := LoadOverlay(s.dir, "bad")
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
+
+func (s *OverlaySuite) write(c *C, path, data string) {
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, path), []byte(data), defaults.SharedReadMask), IsNil)
+}
+
+const overlayManifest = `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app
+  resourceVersion: 0.0.1
+nodeProfiles:
+  - name: node
+    requirements:
+      cpu:
+        min: 2
+installer:
+  flavors:
+    default: small
+    items:
+      - name: small
+        nodes:
+          - profile: node
+            count: 1
+`
+
+const prodOverlay = `manifest:
+  merge:
+    installer:
+      flavors:
+        default: large
+  json:
+    - op: add
+      path: /installer/flavors/items/-
+      value:
+        name: large
+        nodes:
+          - profile: node
+            count: 3
+    - op: replace
+      path: /nodeProfiles/0/requirements/cpu/min
+      value: 8
+resources:
+  - file: resources/app.yaml
+    kind: Deployment
+    name: web
+    merge:
+      spec:
+        replicas: 3
+        template:
+          spec:
+            containers:
+              - name: web
+                image: web:2.0.0
+  - file: resources/app.yaml
+    kind: Service
+    json:
+      - op: add
+        path: /spec/type
+        value: NodePort
+`
+
+const overlayResources = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: web:1.0.0
+          ports:
+            - containerPort: 8080
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - port: 80
+`
@@ -44,8 +44,10 @@ import (
 	fileutils "github.com/gravitational/gravity/lib/utils"
 
 	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/rigging"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -302,6 +304,14 @@ func (r *applications) StartAppHook(ctx context.Context, req appservice.HookRunR
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	trustedCA, err := hasTrustedCA(client)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = addProxyEnv(client, req.Env)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	params := hooks.Params{
 		Hook:               hook,
 		Locator:            req.Application,
@@ -316,6 +326,7 @@ func (r *applications) StartAppHook(ctx context.Context, req appservice.HookRunR
 		AgentPassword:      creds.Password,
 		GravityPackage:     req.GravityPackage,
 		ServiceUser:        req.ServiceUser,
+		TrustedCA:          trustedCA,
 	}
 
 	ref, err := runner.Start(ctx, params)
@@ -943,3 +954,41 @@ type applications struct {
 	sync.Mutex
 	Config
 }
+
+// hasTrustedCA returns true if the cluster has custom trusted certificate
+// authorities configured
+func hasTrustedCA(client *kubernetes.Clientset) (bool, error) {
+	_, err := client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).
+		Get(defaults.TrustedCAConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return false, nil
+		}
+		return false, trace.Wrap(err)
+	}
+	return true, nil
+}
+
+// addProxyEnv adds the cluster proxy environment to env unless
+// the hook request overrides it
+func addProxyEnv(client *kubernetes.Clientset, env map[string]string) error {
+	configMap, err := client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).
+		Get(defaults.ProxyConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	for name, value := range configMap.Data {
+		if name == constants.ResourceSpecKey {
+			continue
+		}
+		if _, ok := env[name]; !ok {
+			env[name] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,597 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/encryptedpack"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeltaManifest describes the contents omitted from a delta upgrade
+// tarball that have to be restored from the packages of the cluster
+// before the application can be imported
+type DeltaManifest struct {
+	// BasePackages lists packages omitted from the tarball
+	// because they are the same in the base version
+	BasePackages []DeltaPackage `json:"basePackages"`
+	// Apps lists application packages with registry blobs omitted
+	// from the tarball
+	Apps []DeltaApp `json:"apps,omitempty"`
+}
+
+// DeltaPackage identifies a package of the base version
+type DeltaPackage struct {
+	// Locator is the package locator
+	Locator loc.Locator `json:"locator"`
+	// SHA512 is the package checksum
+	SHA512 string `json:"sha512"`
+	// App indicates whether this is an application package
+	App bool `json:"app,omitempty"`
+}
+
+// DeltaApp describes an application package with omitted registry blobs
+type DeltaApp struct {
+	// Package is the application package locator
+	Package loc.Locator `json:"package"`
+	// Sources lists base application packages with the omitted blobs
+	Sources []DeltaSource `json:"sources"`
+}
+
+// DeltaSource lists registry blobs omitted from an application package
+// that are contained in the specified base package
+type DeltaSource struct {
+	// DeltaPackage is the base package with the blobs
+	DeltaPackage
+	// Blobs lists paths of the omitted blob files inside the package
+	Blobs []string `json:"blobs"`
+}
+
+// DeltaRequest describes a request to convert the packages of an installer
+// into a delta against the packages of an older installer
+type DeltaRequest struct {
+	// Packages is the package service of the new installer.
+	// Its packages are replaced with their delta versions
+	Packages pack.PackageService
+	// Base is the package service of the installer to generate the delta against
+	Base pack.PackageService
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the request and sets defaults
+func (r *DeltaRequest) CheckAndSetDefaults() error {
+	if r.Packages == nil {
+		return trace.BadParameter("missing Packages")
+	}
+	if r.Base == nil {
+		return trace.BadParameter("missing Base")
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "delta")
+	}
+	return nil
+}
+
+// CreateDelta removes packages that are the same in the base installer
+// from the installer package service and strips registry blobs present
+// in the base application packages from the application packages.
+// Returns the manifest describing the omitted contents
+func CreateDelta(req DeltaRequest) (*DeltaManifest, error) {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// index registry blobs of all base application packages
+	baseBlobs := make(map[string]pack.PackageEnvelope)
+	err := pack.ForeachPackage(req.Base, func(envelope pack.PackageEnvelope) error {
+		if envelope.Type == "" || envelope.Encrypted {
+			return nil
+		}
+		blobs, err := packageBlobs(req.Base, envelope.Locator)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, blob := range blobs {
+			baseBlobs[blob] = envelope
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var envelopes []pack.PackageEnvelope
+	err = pack.ForeachPackage(req.Packages, func(envelope pack.PackageEnvelope) error {
+		envelopes = append(envelopes, envelope)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var manifest DeltaManifest
+	for _, envelope := range envelopes {
+		base, err := req.Base.ReadPackageEnvelope(envelope.Locator)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		if base != nil && base.SHA512 == envelope.SHA512 {
+			req.Infof("Omitting package %v present in the base version.", envelope.Locator)
+			if err := req.Packages.DeletePackage(envelope.Locator); err != nil {
+				return nil, trace.Wrap(err)
+			}
+			manifest.BasePackages = append(manifest.BasePackages, newDeltaPackage(envelope))
+			continue
+		}
+		if envelope.Type == "" || envelope.Encrypted {
+			continue
+		}
+		sources := make(map[loc.Locator]*DeltaSource)
+		var order []loc.Locator
+		app := DeltaApp{Package: envelope.Locator}
+		err = rewritePackage(req.Packages, envelope, func(header *tar.Header) bool {
+			name := tarEntryName(header.Name)
+			base, ok := baseBlobs[name]
+			if !ok {
+				return false
+			}
+			source, ok := sources[base.Locator]
+			if !ok {
+				source = &DeltaSource{DeltaPackage: newDeltaPackage(base)}
+				sources[base.Locator] = source
+				order = append(order, base.Locator)
+			}
+			source.Blobs = append(source.Blobs, name)
+			return true
+		}, func(w *tar.Writer) error {
+			if len(order) == 0 {
+				return nil
+			}
+			for _, locator := range order {
+				app.Sources = append(app.Sources, *sources[locator])
+			}
+			// record the omitted blobs in the package itself so the
+			// application can be restored when imported on its own
+			return trace.Wrap(writeDeltaManifestEntry(w, DeltaManifest{Apps: []DeltaApp{app}}))
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if len(order) == 0 {
+			continue
+		}
+		for _, source := range app.Sources {
+			req.Infof("Omitting %v blobs of %v present in %v.",
+				len(source.Blobs), envelope.Locator, source.Locator)
+		}
+		manifest.Apps = append(manifest.Apps, app)
+	}
+	return &manifest, nil
+}
+
+// ReconstituteRequest describes a request to restore the full application
+// from a delta upgrade tarball
+type ReconstituteRequest struct {
+	// Manifest describes the contents omitted from the tarball
+	Manifest DeltaManifest
+	// Packages is the package service of the delta tarball
+	Packages pack.PackageService
+	// ClusterPackages is the package service of the cluster
+	// with the packages of the base version
+	ClusterPackages pack.PackageService
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the request and sets defaults
+func (r *ReconstituteRequest) CheckAndSetDefaults() error {
+	if r.Packages == nil {
+		return trace.BadParameter("missing Packages")
+	}
+	if _, ok := r.Packages.(*encryptedpack.EncryptedPack); ok {
+		return trace.BadParameter("delta upgrade can not be restored into an encrypted package service")
+	}
+	if r.ClusterPackages == nil {
+		return trace.BadParameter("missing ClusterPackages")
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "delta")
+	}
+	return nil
+}
+
+// ReconstituteTarball restores the contents omitted from the delta upgrade
+// tarball unpacked in the specified directory using the packages already
+// present in the cluster. Returns false for tarballs that are not deltas.
+// The packages of the tarball have to be accessed without decryption
+func ReconstituteTarball(dir string, packages, clusterPackages pack.PackageService) (restored bool, err error) {
+	manifest, err := ReadDeltaManifest(dir)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return false, nil
+		}
+		return false, trace.Wrap(err)
+	}
+	err = Reconstitute(ReconstituteRequest{
+		Manifest:        *manifest,
+		Packages:        packages,
+		ClusterPackages: clusterPackages,
+	})
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	return true, nil
+}
+
+// Reconstitute restores the contents omitted from a delta upgrade tarball
+// using the packages already present in the cluster so the tarball
+// package service contains the complete application
+func Reconstitute(req ReconstituteRequest) error {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, base := range req.Manifest.BasePackages {
+		envelope, err := checkBasePackage(req.ClusterPackages, base)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		// regular packages are pulled only if missing in the cluster
+		// so only application packages have to be present locally
+		if !base.App {
+			continue
+		}
+		err = copyPackage(req.ClusterPackages, req.Packages, *envelope)
+		if err != nil && !trace.IsAlreadyExists(err) {
+			return trace.Wrap(err)
+		}
+	}
+	for _, app := range req.Manifest.Apps {
+		req.Infof("Restoring omitted blobs of %v.", app.Package)
+		envelope, err := req.Packages.ReadPackageEnvelope(app.Package)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if envelope.Encrypted {
+			return trace.BadParameter("package %v is encrypted and can not be restored", app.Package)
+		}
+		for _, source := range app.Sources {
+			if _, err := checkBasePackage(req.ClusterPackages, source.DeltaPackage); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		err = rewritePackage(req.Packages, *envelope, isDeltaManifestEntry, func(w *tar.Writer) error {
+			for _, source := range app.Sources {
+				if err := copyBlobs(req.ClusterPackages, source, w); err != nil {
+					return trace.Wrap(err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// restoreDeltaBlobs restores the registry blobs omitted from the delta
+// application package unpacked in the specified directory using the
+// packages of the cluster. It is a no-op for complete application packages
+func restoreDeltaBlobs(dir string, packages pack.PackageService) error {
+	manifest, err := ReadDeltaManifest(dir)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	for _, app := range manifest.Apps {
+		log.Infof("Restoring omitted blobs of %v.", app.Package)
+		for _, source := range app.Sources {
+			if _, err := checkBasePackage(packages, source.DeltaPackage); err != nil {
+				return trace.Wrap(err)
+			}
+			if err := extractBlobs(packages, source, dir); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+	}
+	return trace.ConvertSystemError(os.Remove(filepath.Join(dir, DeltaManifestFile)))
+}
+
+// ReadDeltaManifest reads the delta manifest from the tarball
+// unpacked in the specified directory.
+// Returns trace.NotFound if this is not a delta tarball
+func ReadDeltaManifest(dir string) (*DeltaManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, DeltaManifestFile))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var manifest DeltaManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, trace.Wrap(err, "failed to parse delta manifest")
+	}
+	return &manifest, nil
+}
+
+// WriteDeltaManifest writes the delta manifest into the tarball
+// directory specified with dir
+func WriteDeltaManifest(dir string, manifest DeltaManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, DeltaManifestFile), data, defaults.SharedReadMask)
+	return trace.ConvertSystemError(err)
+}
+
+// checkBasePackage makes sure the base package is present in the cluster
+func checkBasePackage(packages pack.PackageService, base DeltaPackage) (*pack.PackageEnvelope, error) {
+	envelope, err := packages.ReadPackageEnvelope(base.Locator)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("package %v required by the delta upgrade "+
+				"is not present in the cluster, use the full installer instead", base.Locator)
+		}
+		return nil, trace.Wrap(err)
+	}
+	if envelope.Encrypted {
+		return nil, trace.BadParameter("package %v required by the delta upgrade "+
+			"is encrypted, use the full installer instead", base.Locator)
+	}
+	if envelope.SHA512 != base.SHA512 {
+		return nil, trace.BadParameter("package %v in the cluster does not match "+
+			"the one the delta upgrade was built against, use the full installer instead",
+			base.Locator)
+	}
+	return envelope, nil
+}
+
+// copyPackage copies the package with the specified envelope between package services
+func copyPackage(src, dst pack.PackageService, envelope pack.PackageEnvelope) error {
+	_, reader, err := src.ReadPackage(envelope.Locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	err = dst.UpsertRepository(envelope.Locator.Repository, time.Time{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = dst.CreatePackage(envelope.Locator, reader, packageOptions(envelope)...)
+	return trace.Wrap(err)
+}
+
+// copyBlobs copies the blobs of the specified base package into the tarball writer
+func copyBlobs(packages pack.PackageService, source DeltaSource, w *tar.Writer) error {
+	return trace.Wrap(copyBlobsWith(packages, source, func(header *tar.Header, r io.Reader) error {
+		if err := w.WriteHeader(header); err != nil {
+			return trace.Wrap(err)
+		}
+		_, err := io.Copy(w, r)
+		return trace.Wrap(err)
+	}))
+}
+
+// copyBlobsWith invokes the handler for each blob of the specified base package
+func copyBlobsWith(packages pack.PackageService, source DeltaSource, handler func(*tar.Header, io.Reader) error) error {
+	blobs := make(map[string]struct{}, len(source.Blobs))
+	for _, blob := range source.Blobs {
+		if !isRegistryBlob(blob) {
+			return trace.BadParameter("invalid registry blob path %q", blob)
+		}
+		blobs[blob] = struct{}{}
+	}
+	err := readPackageTarball(packages, source.Locator, func(header *tar.Header, r io.Reader) error {
+		name := tarEntryName(header.Name)
+		if _, ok := blobs[name]; !ok {
+			return nil
+		}
+		delete(blobs, name)
+		return trace.Wrap(handler(header, r))
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(blobs) != 0 {
+		return trace.NotFound("package %v is missing %v blobs required by the delta upgrade",
+			source.Locator, len(blobs))
+	}
+	return nil
+}
+
+// extractBlobs extracts the blobs of the specified base package into the directory
+func extractBlobs(packages pack.PackageService, source DeltaSource, dir string) error {
+	return trace.Wrap(copyBlobsWith(packages, source, func(header *tar.Header, r io.Reader) error {
+		path := filepath.Join(dir, filepath.FromSlash(tarEntryName(header.Name)))
+		if err := os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaults.SharedReadMask)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		defer f.Close()
+		_, err = io.Copy(f, r)
+		return trace.ConvertSystemError(err)
+	}))
+}
+
+// packageBlobs returns paths of all registry blob files in the specified package
+func packageBlobs(packages pack.PackageService, locator loc.Locator) (blobs []string, err error) {
+	err = readPackageTarball(packages, locator, func(header *tar.Header, _ io.Reader) error {
+		if name := tarEntryName(header.Name); isRegistryBlob(name) {
+			blobs = append(blobs, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return blobs, nil
+}
+
+// readPackageTarball invokes the handler for each entry of the specified package tarball
+func readPackageTarball(packages pack.PackageService, locator loc.Locator, handler func(*tar.Header, io.Reader) error) error {
+	_, reader, err := packages.ReadPackage(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	stream, err := dockerarchive.DecompressStream(reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer stream.Close()
+	tarball := tar.NewReader(stream)
+	for {
+		header, err := tarball.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := handler(header, tarball); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+}
+
+// rewritePackage replaces the package with the specified envelope with the
+// version where entries for which skip returns true are omitted and
+// entries written by appendFn are added.
+// Either of skip or appendFn can be nil
+func rewritePackage(packages pack.PackageService, envelope pack.PackageEnvelope,
+	skip func(*tar.Header) bool, appendFn func(*tar.Writer) error) error {
+	f, err := ioutil.TempFile("", "delta")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	gz := gzip.NewWriter(f)
+	w := tar.NewWriter(gz)
+	err = readPackageTarball(packages, envelope.Locator, func(header *tar.Header, r io.Reader) error {
+		if skip != nil && skip(header) {
+			return nil
+		}
+		if err := w.WriteHeader(header); err != nil {
+			return trace.Wrap(err)
+		}
+		_, err := io.Copy(w, r)
+		return trace.Wrap(err)
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if appendFn != nil {
+		if err := appendFn(w); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := gz.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	_, err = packages.UpsertPackage(envelope.Locator, f, packageOptions(envelope)...)
+	return trace.Wrap(err)
+}
+
+// packageOptions returns options to recreate the package with the specified envelope
+func packageOptions(envelope pack.PackageEnvelope) []pack.PackageOption {
+	options := []pack.PackageOption{
+		pack.WithLabels(envelope.RuntimeLabels),
+		pack.WithHidden(envelope.Hidden),
+		pack.WithCreatedBy(envelope.CreatedBy),
+	}
+	if envelope.Type != "" {
+		options = append(options, pack.WithManifest(envelope.Type, envelope.Manifest))
+	}
+	return options
+}
+
+func newDeltaPackage(envelope pack.PackageEnvelope) DeltaPackage {
+	return DeltaPackage{
+		Locator: envelope.Locator,
+		SHA512:  envelope.SHA512,
+		App:     envelope.Type != "",
+	}
+}
+
+// isRegistryBlob returns true if the package tarball entry with the
+// specified name is a data file of a vendored registry blob
+func isRegistryBlob(name string) bool {
+	return name == tarEntryName(name) && strings.HasPrefix(name, defaults.RegistryDir+"/") &&
+		strings.Contains(name, "/docker/registry/v2/blobs/") &&
+		strings.HasSuffix(name, "/data")
+}
+
+// writeDeltaManifestEntry writes the delta manifest into the package tarball
+func writeDeltaManifestEntry(w *tar.Writer, manifest DeltaManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = w.WriteHeader(&tar.Header{
+		Name:     DeltaManifestFile,
+		Mode:     defaults.SharedReadMask,
+		Size:     int64(len(data)),
+		Typeflag: tar.TypeReg,
+		ModTime:  time.Now().UTC(),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = w.Write(data)
+	return trace.Wrap(err)
+}
+
+// isDeltaManifestEntry returns true if the package tarball entry
+// is the delta manifest
+func isDeltaManifestEntry(header *tar.Header) bool {
+	return tarEntryName(header.Name) == DeltaManifestFile
+}
+
+// tarEntryName returns the normalized name of the tarball entry
+func tarEntryName(name string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(name)), "./")
+}
+
+// DeltaManifestFile is the name of the file with the delta manifest
+// inside a delta upgrade tarball
+const DeltaManifestFile = "delta.json"
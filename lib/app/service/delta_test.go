@@ -0,0 +1,232 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/encryptedpack"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type DeltaSuite struct{}
+
+var _ = Suite(&DeltaSuite{})
+
+func (s *DeltaSuite) TestCreatesAndReconstitutesDelta(c *C) {
+	_, basePack, _ := setupServices(c)
+	_, newPack, _ := setupServices(c)
+	for _, packages := range []pack.PackageService{basePack, newPack} {
+		c.Assert(packages.UpsertRepository("example.com", time.Time{}), IsNil)
+		createDeltaTestPackage(c, packages, "example.com/planet:1.0.0", "", map[string]string{
+			"rootfs/bin/planet": "planet",
+		})
+	}
+	createDeltaTestPackage(c, basePack, "example.com/app:1.0.0", "user", map[string]string{
+		"resources/app.yaml": "version: 1.0.0",
+		blobPath("aa11"):     "shared layer",
+		blobPath("bb22"):     "old layer",
+	})
+	createDeltaTestPackage(c, newPack, "example.com/app:2.0.0", "user", map[string]string{
+		"resources/app.yaml": "version: 2.0.0",
+		blobPath("aa11"):     "shared layer",
+		blobPath("cc33"):     "new layer",
+	})
+
+	manifest, err := CreateDelta(DeltaRequest{Packages: newPack, Base: basePack})
+	c.Assert(err, IsNil)
+	c.Assert(manifest.BasePackages, HasLen, 1)
+	c.Assert(manifest.BasePackages[0].Locator, Equals, loc.MustParseLocator("example.com/planet:1.0.0"))
+	c.Assert(manifest.Apps, HasLen, 1)
+	c.Assert(manifest.Apps[0].Package, Equals, loc.MustParseLocator("example.com/app:2.0.0"))
+	c.Assert(manifest.Apps[0].Sources, HasLen, 1)
+	c.Assert(manifest.Apps[0].Sources[0].Locator, Equals, loc.MustParseLocator("example.com/app:1.0.0"))
+	c.Assert(manifest.Apps[0].Sources[0].Blobs, DeepEquals, []string{blobPath("aa11")})
+
+	_, err = newPack.ReadPackageEnvelope(loc.MustParseLocator("example.com/planet:1.0.0"))
+	c.Assert(trace.IsNotFound(err), Equals, true)
+	c.Assert(deltaTestPackageFiles(c, newPack, "example.com/app:2.0.0"), DeepEquals, []string{
+		DeltaManifestFile, blobPath("cc33"), "resources/app.yaml",
+	})
+	envelope, err := newPack.ReadPackageEnvelope(loc.MustParseLocator("example.com/app:2.0.0"))
+	c.Assert(err, IsNil)
+	c.Assert(envelope.Type, Equals, "user")
+
+	err = Reconstitute(ReconstituteRequest{
+		Manifest:        *manifest,
+		Packages:        newPack,
+		ClusterPackages: basePack,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(deltaTestPackageFiles(c, newPack, "example.com/app:2.0.0"), DeepEquals, []string{
+		blobPath("aa11"), blobPath("cc33"), "resources/app.yaml",
+	})
+}
+
+func (s *DeltaSuite) TestRestoresDeltaApplicationOnImport(c *C) {
+	_, basePack, _ := setupServices(c)
+	_, newPack, _ := setupServices(c)
+	for _, packages := range []pack.PackageService{basePack, newPack} {
+		c.Assert(packages.UpsertRepository("example.com", time.Time{}), IsNil)
+	}
+	createDeltaTestPackage(c, basePack, "example.com/app:1.0.0", "user", map[string]string{
+		"resources/app.yaml": "version: 1.0.0",
+		blobPath("aa11"):     "shared layer",
+	})
+	createDeltaTestPackage(c, newPack, "example.com/app:2.0.0", "user", map[string]string{
+		"resources/app.yaml": "version: 2.0.0",
+		blobPath("aa11"):     "shared layer",
+		blobPath("cc33"):     "new layer",
+	})
+	_, err := CreateDelta(DeltaRequest{Packages: newPack, Base: basePack})
+	c.Assert(err, IsNil)
+
+	dir := c.MkDir()
+	err = readPackageTarball(newPack, loc.MustParseLocator("example.com/app:2.0.0"), func(header *tar.Header, r io.Reader) error {
+		path := filepath.Join(dir, header.Name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+		data, err := ioutil.ReadAll(r)
+		c.Assert(err, IsNil)
+		return ioutil.WriteFile(path, data, 0644)
+	})
+	c.Assert(err, IsNil)
+
+	c.Assert(restoreDeltaBlobs(dir, basePack), IsNil)
+	data, err := ioutil.ReadFile(filepath.Join(dir, blobPath("aa11")))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "shared layer")
+	_, err = os.Stat(filepath.Join(dir, DeltaManifestFile))
+	c.Assert(os.IsNotExist(err), Equals, true, Commentf("Delta manifest should be removed."))
+
+	// complete application packages are imported as is
+	c.Assert(restoreDeltaBlobs(dir, basePack), IsNil)
+}
+
+func (s *DeltaSuite) TestReconstituteRejectsEncryptedPackages(c *C) {
+	_, clusterPack, _ := setupServices(c)
+	_, tarballPack, _ := setupServices(c)
+	for _, packages := range []pack.PackageService{clusterPack, tarballPack} {
+		c.Assert(packages.UpsertRepository("example.com", time.Time{}), IsNil)
+	}
+
+	err := Reconstitute(ReconstituteRequest{
+		Packages:        encryptedpack.New(tarballPack, "secret"),
+		ClusterPackages: clusterPack,
+	})
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+
+	envelope := createDeltaTestPackage(c, clusterPack, "example.com/app:1.0.0", "user", map[string]string{
+		"resources/app.yaml": "version: 1.0.0",
+	})
+	_, err = clusterPack.UpsertPackage(envelope.Locator, strings.NewReader("encrypted"),
+		pack.WithManifest("user", []byte("manifest")), pack.WithEncrypted(true))
+	c.Assert(err, IsNil)
+	envelope, err = clusterPack.ReadPackageEnvelope(envelope.Locator)
+	c.Assert(err, IsNil)
+	err = Reconstitute(ReconstituteRequest{
+		Manifest: DeltaManifest{BasePackages: []DeltaPackage{{
+			Locator: envelope.Locator,
+			SHA512:  envelope.SHA512,
+			App:     true,
+		}}},
+		Packages:        tarballPack,
+		ClusterPackages: clusterPack,
+	})
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+	_, err = tarballPack.ReadPackageEnvelope(envelope.Locator)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("Encrypted package should not be copied."))
+}
+
+func (s *DeltaSuite) TestReconstituteRequiresBasePackages(c *C) {
+	_, clusterPack, _ := setupServices(c)
+	_, tarballPack, _ := setupServices(c)
+	c.Assert(clusterPack.UpsertRepository("example.com", time.Time{}), IsNil)
+	envelope := createDeltaTestPackage(c, clusterPack, "example.com/planet:1.0.0", "", map[string]string{
+		"rootfs/bin/planet": "planet",
+	})
+
+	err := Reconstitute(ReconstituteRequest{
+		Manifest: DeltaManifest{BasePackages: []DeltaPackage{{
+			Locator: loc.MustParseLocator("example.com/teleport:1.0.0"),
+		}}},
+		Packages:        tarballPack,
+		ClusterPackages: clusterPack,
+	})
+	c.Assert(trace.IsNotFound(err), Equals, true)
+
+	err = Reconstitute(ReconstituteRequest{
+		Manifest: DeltaManifest{BasePackages: []DeltaPackage{{
+			Locator: envelope.Locator,
+			SHA512:  "mismatch",
+		}}},
+		Packages:        tarballPack,
+		ClusterPackages: clusterPack,
+	})
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
+
+func blobPath(hex string) string {
+	return "registry/docker/registry/v2/blobs/sha256/" + hex[:2] + "/" + hex + "/data"
+}
+
+func createDeltaTestPackage(c *C, packages pack.PackageService, locator, packageType string, files map[string]string) *pack.PackageEnvelope {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for name, data := range files {
+		c.Assert(w.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}), IsNil)
+		_, err := w.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	c.Assert(gz.Close(), IsNil)
+	var options []pack.PackageOption
+	if packageType != "" {
+		options = append(options, pack.WithManifest(packageType, []byte("manifest")))
+	}
+	envelope, err := packages.CreatePackage(loc.MustParseLocator(locator), &buf, options...)
+	c.Assert(err, IsNil)
+	return envelope
+}
+
+func deltaTestPackageFiles(c *C, packages pack.PackageService, locator string) (files []string) {
+	err := readPackageTarball(packages, loc.MustParseLocator(locator), func(header *tar.Header, _ io.Reader) error {
+		files = append(files, header.Name)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	sort.Strings(files)
+	return files
+}
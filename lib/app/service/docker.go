@@ -36,8 +36,8 @@ import (
 // exportLayers exports the layers of the specified set of images into
 // the specified local directory
 func exportLayers(ctx context.Context, dir string, images []string, dockerClient docker.DockerInterface, log log.FieldLogger,
-	parallel int, progress utils.Progress) error {
-	layerExporter, err := newLayerExporter(dir, dockerClient, log, progress)
+	parallel int, progress utils.Progress, cache *docker.ImageCache) error {
+	layerExporter, err := newLayerExporter(dir, dockerClient, log, progress, cache)
 	if err != nil {
 		return trace.Wrap(err, "failed to create layer export")
 	}
@@ -55,7 +55,7 @@ func exportLayers(ctx context.Context, dir string, images []string, dockerClient
 }
 
 // newLayerExporter creates an instance of layer exporter
-func newLayerExporter(exportDir string, client docker.DockerInterface, log log.FieldLogger, progress utils.Progress) (*layerExporter, error) {
+func newLayerExporter(exportDir string, client docker.DockerInterface, log log.FieldLogger, progress utils.Progress, cache *docker.ImageCache) (*layerExporter, error) {
 	outputDir := filepath.Join(exportDir, defaults.RegistryDir)
 	config := docker.BasicConfiguration("127.0.0.1:0", outputDir)
 	registry, err := docker.NewRegistry(config)
@@ -70,6 +70,8 @@ func newLayerExporter(exportDir string, client docker.DockerInterface, log log.F
 		dockerClient:     client,
 		registry:         registry,
 		progressReporter: progress,
+		outputDir:        outputDir,
+		cache:            cache,
 	}, nil
 }
 
@@ -81,6 +83,10 @@ type layerExporter struct {
 	dockerClient     docker.DockerInterface
 	registry         *docker.Registry
 	progressReporter utils.Progress
+	// outputDir is the registry directory
+	outputDir string
+	// cache is the optional cache of previously exported images
+	cache *docker.ImageCache
 }
 
 // push pushes the list of specified images into the temporary local registry
@@ -106,6 +112,22 @@ func (r *layerExporter) pushImage(image string) func() error {
 		if err != nil {
 			return trace.Wrap(err)
 		}
+		var imageID string
+		if r.cache != nil {
+			info, err := r.dockerClient.InspectImage(image)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			imageID = info.ID
+			cached, err := r.cache.Export(imageID, r.outputDir, parsed.Repository, imageTag(*parsed))
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			if cached {
+				r.progressReporter.PrintSubStep("Reused cached image %v", image)
+				return nil
+			}
+		}
 		if err = r.tagCmd(image, parsed.Repository, parsed.Tag); err != nil {
 			return trace.Wrap(err)
 		}
@@ -114,6 +136,12 @@ func (r *layerExporter) pushImage(image string) func() error {
 			return trace.Wrap(err)
 		}
 		r.progressReporter.PrintSubStep("Vendored image %v", image)
+		if r.cache != nil {
+			err := r.cache.Import(imageID, r.outputDir, parsed.Repository, imageTag(*parsed))
+			if err != nil {
+				r.Warnf("Failed to cache image %v: %v.", image, trace.DebugReport(err))
+			}
+		}
 		if err = r.removeTagCmd(parsed.Repository, parsed.Tag); err != nil {
 			r.Warnf("Failed to remove %v.", image)
 		}
@@ -121,6 +149,14 @@ func (r *layerExporter) pushImage(image string) func() error {
 	}
 }
 
+// imageTag returns the tag of the image, defaulting to latest
+func imageTag(image loc.DockerImage) string {
+	if image.Tag == "" {
+		return latestTag
+	}
+	return image.Tag
+}
+
 func (r *layerExporter) tagCmd(image, repository, tag string) error {
 	opts := dockerapi.TagImageOptions{
 		Repo:  fmt.Sprintf("%v/%v", r.registry.Addr(), repository),
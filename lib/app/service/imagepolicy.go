@@ -0,0 +1,456 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/ghodss/yaml"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// ImagePolicy defines the rules container images referenced by application
+// resources have to satisfy in order to be vendored.
+// The rules apply to images in resource files, Helm charts and runtime
+// images, including the default container image shipped with the application
+type ImagePolicy struct {
+	// AllowedRegistries lists registries, optionally with a repository
+	// prefix (e.g. quay.io/example), images can be vendored from.
+	// If empty, all registries not explicitly denied are allowed
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+	// DeniedRegistries lists registries, optionally with a repository
+	// prefix, images can not be vendored from
+	DeniedRegistries []string `json:"deniedRegistries,omitempty"`
+	// DeniedTags lists image tags that are not allowed, e.g. latest.
+	// Images without a tag are assumed to have the latest tag
+	DeniedTags []string `json:"deniedTags,omitempty"`
+	// RequireDigest requires all images to be referenced by digest
+	RequireDigest bool `json:"requireDigest,omitempty"`
+	// RegistryRewrites maps registry prefixes to replacement prefixes.
+	// Rewrites are applied to the images in resource files, chart values
+	// and templates and to the runtime images before the rules are enforced
+	RegistryRewrites []RegistryRewrite `json:"registryRewrites,omitempty"`
+	// PinDigests replaces tags of vendored images with their digests
+	// in the application resources and records the digest of every
+	// vendored image in the application manifest
+	PinDigests bool `json:"pinDigests,omitempty"`
+}
+
+// RegistryRewrite replaces the registry prefix of an image
+type RegistryRewrite struct {
+	// From is the registry prefix to replace, e.g. docker.io
+	From string `json:"from"`
+	// To is the replacement prefix, e.g. registry.example.com/dockerhub
+	To string `json:"to"`
+}
+
+// ReadImagePolicy reads the image policy from the specified YAML file
+func ReadImagePolicy(path string) (*ImagePolicy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	policy, err := ParseImagePolicy(data)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse image policy %v", path)
+	}
+	return policy, nil
+}
+
+// ParseImagePolicy parses the image policy from the provided YAML data
+func ParseImagePolicy(data []byte) (*ImagePolicy, error) {
+	var policy ImagePolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, rewrite := range policy.RegistryRewrites {
+		if rewrite.From == "" || rewrite.To == "" {
+			return nil, trace.BadParameter("registry rewrite requires both from and to: %+v", rewrite)
+		}
+	}
+	return &policy, nil
+}
+
+// Rewrite returns the image with its registry prefix replaced according
+// to the first matching registry rewrite
+func (p ImagePolicy) Rewrite(image string) string {
+	parsed, err := loc.ParseDockerImage(image)
+	if err != nil {
+		log.Warningf("Failed to rewrite %v: %v.", image, trace.DebugReport(err))
+		return image
+	}
+	name := imageName(*parsed)
+	for _, rewrite := range p.RegistryRewrites {
+		if !hasPathPrefix(name, rewrite.From) {
+			continue
+		}
+		rewritten, err := loc.ParseDockerImage(strings.TrimSuffix(rewrite.To, "/") +
+			strings.TrimPrefix(name, strings.TrimSuffix(rewrite.From, "/")))
+		if err != nil {
+			log.Warningf("Failed to rewrite %v: %v.", image, trace.DebugReport(err))
+			return image
+		}
+		rewritten.Tag = parsed.Tag
+		log.Infof("Image %v rewritten to %v.", image, rewritten.String())
+		return rewritten.String()
+	}
+	return image
+}
+
+// rewriteRuntimeImages rewrites the runtime base images in the application
+// manifest according to the registry rewrites
+func (p ImagePolicy) rewriteRuntimeImages(m *schema.Manifest) error {
+	if m.SystemOptions != nil && m.SystemOptions.BaseImage != "" {
+		m.SystemOptions.BaseImage = p.Rewrite(m.SystemOptions.BaseImage)
+	}
+	for _, profile := range m.NodeProfiles {
+		if profile.SystemOptions != nil && profile.SystemOptions.BaseImage != "" {
+			profile.SystemOptions.BaseImage = p.Rewrite(profile.SystemOptions.BaseImage)
+		}
+	}
+	return nil
+}
+
+// rewriteCharts rewrites the images in values and templates of the specified
+// charts according to the registry rewrites and returns the re-rendered charts.
+// Returns an error if an image could not be rewritten, e.g. because the chart
+// composes the image reference in a way that does not allow a rewrite
+func (p ImagePolicy) rewriteCharts(charts resources.ResourceFiles) (resources.ResourceFiles, error) {
+	rewritten, remaining, err := rewriteChartImages(charts, p.Rewrite, true)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(remaining) != 0 {
+		return nil, trace.BadParameter("failed to rewrite chart images, "+
+			"reference them in chart values or templates by their full names:\n%v",
+			strings.Join(remaining, "\n"))
+	}
+	return rewritten, nil
+}
+
+// Check verifies that all specified images satisfy the policy.
+// Returns an error listing all violations
+func (p ImagePolicy) Check(images []string) error {
+	var violations []string
+	for _, image := range teleutils.Deduplicate(images) {
+		if err := p.checkImage(image); err != nil {
+			violations = append(violations, fmt.Sprintf("%v: %v", image, err))
+		}
+	}
+	if len(violations) != 0 {
+		sort.Strings(violations)
+		return trace.BadParameter("images violate the image policy:\n%v",
+			strings.Join(violations, "\n"))
+	}
+	return nil
+}
+
+func (p ImagePolicy) checkImage(image string) error {
+	parsed, err := loc.ParseDockerImage(image)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	name := imageName(*parsed)
+	for _, denied := range p.DeniedRegistries {
+		if hasPathPrefix(name, denied) {
+			return trace.BadParameter("registry %v is denied", denied)
+		}
+	}
+	if len(p.AllowedRegistries) != 0 && !matchesAnyPrefix(name, p.AllowedRegistries) {
+		return trace.BadParameter("registry is not in the list of allowed registries")
+	}
+	digest := isDigest(parsed.Tag)
+	if p.RequireDigest && !digest {
+		return trace.BadParameter("image is not referenced by digest")
+	}
+	tag := parsed.Tag
+	if tag == "" {
+		tag = latestTag
+	}
+	if !digest && utils.StringInSlice(p.DeniedTags, tag) {
+		return trace.BadParameter("tag %v is denied", tag)
+	}
+	return nil
+}
+
+// imageDigests returns digests of the specified images from the local docker daemon
+func imageDigests(client docker.DockerInterface, images []string) (digests []schema.ImageDigest, err error) {
+	for _, image := range teleutils.Deduplicate(images) {
+		info, err := client.InspectImage(image)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to inspect %v", image)
+		}
+		digests = append(digests, schema.ImageDigest{
+			Image:  image,
+			Digest: repoDigest(image, info.RepoDigests),
+			ID:     info.ID,
+		})
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return digests[i].Image < digests[j].Image
+	})
+	return digests, nil
+}
+
+// repoDigest returns the digest of the image from the list of
+// repository digests reported by docker
+func repoDigest(image string, repoDigests []string) string {
+	parsed, err := loc.ParseDockerImage(image)
+	if err != nil {
+		return ""
+	}
+	for _, repoDigest := range repoDigests {
+		candidate, err := loc.ParseDockerImage(repoDigest)
+		if err != nil {
+			continue
+		}
+		if imageName(*candidate) == imageName(*parsed) {
+			return candidate.Tag
+		}
+	}
+	return ""
+}
+
+// pinImageDigests replaces the tags of the images in the specified resources
+// with the digests of the images vendored into the registry directory
+func pinImageDigests(registryDir string, resourceFiles resources.ResourceFiles, charts resources.ResourceFiles) error {
+	pin, err := makePinImageFunc(registryDir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := resourceFiles.RewriteImages(pin); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := resourceFiles.Write(); err != nil {
+		return trace.Wrap(err)
+	}
+	_, remaining, err := rewriteChartImages(charts, pin, false)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, image := range remaining {
+		log.Warnf("Failed to pin chart image %v to digest.", image)
+	}
+	return nil
+}
+
+// makePinImageFunc returns an image rewrite function that replaces the tag
+// of an image with the digest of the image vendored into the registry directory
+func makePinImageFunc(registryDir string) (func(string) string, error) {
+	images, err := docker.ListRegistryImages(registryDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	digests := make(map[string]string, len(images))
+	for _, image := range images {
+		digests[fmt.Sprintf("%v:%v", image.Repository, image.Tag)] = image.Digest.String()
+	}
+	return func(image string) string {
+		parsed, err := loc.ParseDockerImage(image)
+		if err != nil || isDigest(parsed.Tag) {
+			return image
+		}
+		digest, ok := digests[fmt.Sprintf("%v:%v", parsed.Repository, imageTag(*parsed))]
+		if !ok {
+			return image
+		}
+		parsed.Tag = digest
+		return parsed.String()
+	}, nil
+}
+
+// makeRewriteImageDigestsFunc returns a manifest rewrite function that
+// records the specified image digests
+func makeRewriteImageDigestsFunc(digests []schema.ImageDigest) func(*schema.Manifest) error {
+	return func(m *schema.Manifest) error {
+		m.ImageDigests = digests
+		return nil
+	}
+}
+
+// imageName returns the fully-qualified name of the image without tag,
+// with the default registry filled in
+func imageName(image loc.DockerImage) string {
+	registry := image.Registry
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	return fmt.Sprintf("%v/%v", registry, image.Repository)
+}
+
+func matchesAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if hasPathPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix returns true if the name starts with the specified
+// prefix on the path boundary
+func hasPathPrefix(name, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return name == prefix || strings.HasPrefix(name, prefix+"/")
+}
+
+func isDigest(tag string) bool {
+	return strings.HasPrefix(tag, "sha256:")
+}
+
+const (
+	// defaultRegistry is the registry of images specified without one
+	defaultRegistry = "docker.io"
+	// latestTag is the tag of images specified without one
+	latestTag = "latest"
+)
+
+// rewriteChartImages rewrites the images of the specified charts in chart
+// values and templates and returns the re-rendered charts along with the
+// images that could not be rewritten.
+// If rewriteNames is set, image names referenced without a tag are rewritten
+// as well, e.g. when a chart composes an image from repository and tag values
+func rewriteChartImages(charts resources.ResourceFiles, rewrite func(string) string, rewriteNames bool) (result resources.ResourceFiles, remaining []string, err error) {
+	for _, chart := range charts {
+		images, err := chart.Images()
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		var replacements []imageReplacement
+		for _, image := range images.Images {
+			rewritten := rewrite(image)
+			if rewritten == image {
+				continue
+			}
+			replacements = append(replacements, imageReplacement{from: image, to: rewritten})
+			from, to := imageRefName(image), imageRefName(rewritten)
+			if rewriteNames && from != to && strings.Contains(from, "/") {
+				replacements = append(replacements, imageReplacement{from: from, to: to})
+			}
+		}
+		if len(replacements) == 0 {
+			result = append(result, chart)
+			continue
+		}
+		if err := replaceChartImages(chart.Path(), replacements); err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		resource, err := resourceFromChart(chart.Path())
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		rendered := resources.NewResourceFileObject(chart.Path(), *resource)
+		result = append(result, rendered)
+		images, err = rendered.Images()
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		for _, image := range images.Images {
+			if rewrite(image) != image {
+				remaining = append(remaining, fmt.Sprintf("%v in chart %v", image, chart.Path()))
+			}
+		}
+	}
+	return result, remaining, nil
+}
+
+// replaceChartImages replaces the image references in values and
+// templates of the chart in the specified directory
+func replaceChartImages(chartDir string, replacements []imageReplacement) error {
+	// replace longer references first so image names do not
+	// match inside the full references
+	sort.Slice(replacements, func(i, j int) bool {
+		return len(replacements[i].from) > len(replacements[j].from)
+	})
+	// references prefixed with a template expression, e.g. the registry
+	// value, are not replaced as the prefix is not part of the image name
+	var exprs []*regexp.Regexp
+	for _, replacement := range replacements {
+		exprs = append(exprs, regexp.MustCompile(fmt.Sprintf(`(?m)(^|[\s"'=,(\[])%v($|[\s"'@:,)\]{}])`,
+			regexp.QuoteMeta(replacement.from))))
+	}
+	return filepath.Walk(chartDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if fi.IsDir() || !isChartTemplate(chartDir, path) {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		replaced := data
+		for i, expr := range exprs {
+			replaced = expr.ReplaceAll(replaced, []byte("${1}"+replacements[i].to+"${2}"))
+		}
+		if bytes.Equal(replaced, data) {
+			return nil
+		}
+		log.Infof("Rewrote images in chart file %v.", path)
+		return trace.ConvertSystemError(ioutil.WriteFile(path, replaced, fi.Mode()))
+	})
+}
+
+// isChartTemplate returns true if the specified file is a values file
+// or a template of the chart in chartDir or of one of its subcharts
+func isChartTemplate(chartDir, path string) bool {
+	if filepath.Base(path) == chartValuesFile {
+		return true
+	}
+	rel, err := filepath.Rel(chartDir, path)
+	if err != nil {
+		return false
+	}
+	return utils.StringInSlice(strings.Split(filepath.ToSlash(rel), "/"), chartTemplatesDir)
+}
+
+// imageRefName returns the image reference without the tag or digest
+func imageRefName(image string) string {
+	name, _ := loc.ParseRepositoryTag(image)
+	return name
+}
+
+// imageReplacement replaces an image reference in chart files
+type imageReplacement struct {
+	// from is the image reference to replace
+	from string
+	// to is the replacement reference
+	to string
+}
+
+const (
+	// chartValuesFile is the name of the chart values file
+	chartValuesFile = "values.yaml"
+	// chartTemplatesDir is the name of the chart templates directory
+	chartTemplatesDir = "templates"
+)
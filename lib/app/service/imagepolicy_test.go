@@ -0,0 +1,256 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/gravitational/trace"
+	digest "github.com/opencontainers/go-digest"
+	. "gopkg.in/check.v1"
+)
+
+type ImagePolicySuite struct{}
+
+var _ = Suite(&ImagePolicySuite{})
+
+func (s *ImagePolicySuite) TestParsesPolicy(c *C) {
+	policy, err := ParseImagePolicy([]byte(`
+allowedRegistries: [quay.io/example, registry.example.com]
+deniedTags: [latest]
+requireDigest: true
+registryRewrites:
+- from: docker.io
+  to: registry.example.com/dockerhub
+pinDigests: true
+`))
+	c.Assert(err, IsNil)
+	c.Assert(*policy, DeepEquals, ImagePolicy{
+		AllowedRegistries: []string{"quay.io/example", "registry.example.com"},
+		DeniedTags:        []string{"latest"},
+		RequireDigest:     true,
+		RegistryRewrites: []RegistryRewrite{
+			{From: "docker.io", To: "registry.example.com/dockerhub"},
+		},
+		PinDigests: true,
+	})
+
+	_, err = ParseImagePolicy([]byte(`
+registryRewrites:
+- from: docker.io
+`))
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
+
+func (s *ImagePolicySuite) TestRewritesImages(c *C) {
+	policy := ImagePolicy{
+		RegistryRewrites: []RegistryRewrite{
+			{From: "docker.io", To: "registry.example.com/dockerhub"},
+			{From: "quay.io/example/", To: "registry.example.com/quay"},
+		},
+	}
+	var testCases = []struct {
+		image    string
+		expected string
+	}{
+		{image: "nginx:1.15", expected: "registry.example.com/dockerhub/nginx:1.15"},
+		{image: "docker.io/library/redis", expected: "registry.example.com/dockerhub/library/redis"},
+		{image: "quay.io/example/app@sha256:0123", expected: "registry.example.com/quay/app@sha256:0123"},
+		{image: "quay.io/examples/app:1.0", expected: "quay.io/examples/app:1.0"},
+		{image: "gcr.io/project/app:1.0", expected: "gcr.io/project/app:1.0"},
+	}
+	for _, tc := range testCases {
+		c.Assert(policy.Rewrite(tc.image), Equals, tc.expected, Commentf(tc.image))
+	}
+}
+
+func (s *ImagePolicySuite) TestChecksImages(c *C) {
+	policy := ImagePolicy{
+		AllowedRegistries: []string{"quay.io/example", "docker.io"},
+		DeniedRegistries:  []string{"docker.io/untrusted"},
+		DeniedTags:        []string{"latest"},
+	}
+	c.Assert(policy.Check([]string{
+		"quay.io/example/app:1.0",
+		"nginx:1.15",
+		"quay.io/example/app@sha256:0123",
+	}), IsNil)
+
+	var testCases = []string{
+		"quay.io/other/app:1.0",
+		"untrusted/app:1.0",
+		"nginx",
+		"nginx:latest",
+	}
+	for _, image := range testCases {
+		c.Assert(trace.IsBadParameter(policy.Check([]string{image})), Equals, true, Commentf(image))
+	}
+
+	policy = ImagePolicy{RequireDigest: true}
+	c.Assert(policy.Check([]string{"nginx@sha256:0123"}), IsNil)
+	c.Assert(policy.Check([]string{"nginx:1.15"}), NotNil)
+}
+
+func (s *ImagePolicySuite) TestFindsRepoDigest(c *C) {
+	repoDigests := []string{
+		"nginx@sha256:1111",
+		"registry.example.com/nginx@sha256:2222",
+	}
+	c.Assert(repoDigest("nginx:1.15", repoDigests), Equals, "sha256:1111")
+	c.Assert(repoDigest("registry.example.com/nginx:1.15", repoDigests), Equals, "sha256:2222")
+	c.Assert(repoDigest("quay.io/nginx:1.15", repoDigests), Equals, "")
+}
+
+func (s *ImagePolicySuite) TestRewritesRuntimeImages(c *C) {
+	policy := ImagePolicy{
+		RegistryRewrites: []RegistryRewrite{
+			{From: "quay.io/gravitational", To: "registry.example.com/gravitational"},
+		},
+	}
+	manifest := schema.Manifest{
+		SystemOptions: &schema.SystemOptions{BaseImage: "quay.io/gravitational/planet:0.0.1"},
+		NodeProfiles: schema.NodeProfiles{
+			{Name: "worker", SystemOptions: &schema.SystemOptions{BaseImage: "quay.io/gravitational/planet:0.0.2"}},
+			{Name: "db"},
+		},
+	}
+	c.Assert(policy.rewriteRuntimeImages(&manifest), IsNil)
+	c.Assert(manifest.RuntimeImages(), DeepEquals, []string{
+		"registry.example.com/gravitational/planet:0.0.1",
+		"registry.example.com/gravitational/planet:0.0.2",
+	})
+}
+
+func (s *ImagePolicySuite) TestReplacesImagesInChartValuesAndTemplates(c *C) {
+	chartDir := c.MkDir()
+	writeFiles(c, chartDir, map[string]string{
+		"Chart.yaml": "name: app\nversion: 0.0.1\n",
+		"values.yaml": `
+image:
+  repository: quay.io/example/app
+  tag: "1.0"
+sidecar: quay.io/example/app-sidecar:1.0
+`,
+		"templates/deployment.yaml": `
+containers:
+- name: app
+  image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+- name: nginx
+  image: nginx:1.15
+- name: web
+  image: {{ .Values.registry }}nginx:1.15
+`,
+		"charts/db/values.yaml": "image: quay.io/example/app:1.0\n",
+		"README.md":             "image: nginx:1.15\n",
+	})
+	err := replaceChartImages(chartDir, []imageReplacement{
+		{from: "quay.io/example/app", to: "registry.example.com/quay/app"},
+		{from: "quay.io/example/app:1.0", to: "registry.example.com/quay/app:1.0"},
+		{from: "nginx:1.15", to: "registry.example.com/dockerhub/nginx:1.15"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(readFiles(c, chartDir, "values.yaml", "templates/deployment.yaml", "charts/db/values.yaml", "README.md"),
+		DeepEquals, map[string]string{
+			"values.yaml": `
+image:
+  repository: registry.example.com/quay/app
+  tag: "1.0"
+sidecar: quay.io/example/app-sidecar:1.0
+`,
+			"templates/deployment.yaml": `
+containers:
+- name: app
+  image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+- name: nginx
+  image: registry.example.com/dockerhub/nginx:1.15
+- name: web
+  image: {{ .Values.registry }}nginx:1.15
+`,
+			"charts/db/values.yaml": "image: registry.example.com/quay/app:1.0\n",
+			"README.md":             "image: nginx:1.15\n",
+		})
+}
+
+func (s *ImagePolicySuite) TestPinsImagesToVendoredDigests(c *C) {
+	registryDir := filepath.Join(c.MkDir(), "registry")
+	appDigest := writeRegistryImage(c, registryDir, "example/app", "1.0")
+	nginxDigest := writeRegistryImage(c, registryDir, "nginx", "latest")
+	pin, err := makePinImageFunc(registryDir)
+	c.Assert(err, IsNil)
+	var testCases = []struct {
+		image    string
+		expected string
+	}{
+		{
+			image:    "leader.telekube.local:5000/example/app:1.0",
+			expected: "leader.telekube.local:5000/example/app@" + appDigest.String(),
+		},
+		{image: "example/app:1.0", expected: "example/app@" + appDigest.String()},
+		{image: "nginx", expected: "nginx@" + nginxDigest.String()},
+		{image: "example/app:2.0", expected: "example/app:2.0"},
+		{image: "other:1.0", expected: "other:1.0"},
+		{image: "nginx@sha256:0123", expected: "nginx@sha256:0123"},
+	}
+	for _, tc := range testCases {
+		c.Assert(pin(tc.image), Equals, tc.expected, Commentf(tc.image))
+	}
+}
+
+func writeFiles(c *C, dir string, files map[string]string) {
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(data), 0644), IsNil)
+	}
+}
+
+func readFiles(c *C, dir string, names ...string) map[string]string {
+	files := make(map[string]string)
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		c.Assert(err, IsNil)
+		files[name] = string(data)
+	}
+	return files
+}
+
+func writeRegistryImage(c *C, root, repository, tag string) digest.Digest {
+	data, err := json.Marshal(schema2.Manifest{
+		Config: distribution.Descriptor{
+			MediaType: schema2.MediaTypeImageConfig,
+			Digest:    digest.FromString(repository),
+		},
+	})
+	c.Assert(err, IsNil)
+	dgst := digest.FromBytes(data)
+	blobPath, err := filepath.Rel(root, docker.RegistryBlobPath(root, dgst))
+	c.Assert(err, IsNil)
+	writeFiles(c, root, map[string]string{
+		blobPath: string(data),
+		filepath.Join("docker", "registry", "v2", "repositories", repository,
+			"_manifests", "tags", tag, "current", "link"): string(dgst),
+	})
+	return dgst
+}
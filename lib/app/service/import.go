@@ -57,6 +57,12 @@ func (r *applications) importAppWithContext(ctx *operationContext, request *app.
 		return trace.Wrap(err)
 	}
 
+	// delta application packages reference registry blobs
+	// of the application versions already present in the cluster
+	if err := restoreDeltaBlobs(unpackedDir, r.Packages); err != nil {
+		return trace.Wrap(err)
+	}
+
 	archiveOptions := &archive.TarOptions{
 		Compression:     archive.Gzip,
 		ExcludePatterns: request.ExcludePatterns,
@@ -118,6 +118,10 @@ type VendorRequest struct {
 	SetImages []loc.DockerImage
 	// SetDeps is a list of app dependencies to rewrite to new versions
 	SetDeps []loc.Locator
+	// ImagePolicy is an optional policy the vendored images have to satisfy
+	ImagePolicy *ImagePolicy
+	// ImageCache is an optional cache of previously exported images
+	ImageCache *docker.ImageCache
 	// VendorRuntime specifies whether to translate runtime images into packages.
 	// The vendoring of the runtime package is a multi-step process which also requires
 	// access to the package store used for building the final application installer
@@ -165,6 +169,9 @@ func (v *vendorer) VendorTarball(ctx context.Context, tarball io.ReadCloser, req
 //
 // It will detect and import missing docker images and rewrite image references in all resource files
 // to point to a fixed docker registry address.
+//
+// The resource files and charts in unpackedDir are rewritten in place, including the image policy
+// rewrites and digest pinning, so unpackedDir is expected to be a copy of the application layout.
 func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req VendorRequest) error {
 	if req.ProgressReporter == nil {
 		req.ProgressReporter = utils.NewNopProgress()
@@ -205,6 +212,21 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 		return trace.Wrap(err)
 	}
 
+	if req.ImagePolicy != nil {
+		err = resourceFiles.RewriteImages(req.ImagePolicy.Rewrite)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = resourceFiles.RewriteManifest(req.ImagePolicy.rewriteRuntimeImages)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		chartResources, err = req.ImagePolicy.rewriteCharts(chartResources)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
 	images, err := resourceFiles.Images()
 	if err != nil {
 		return trace.Wrap(err)
@@ -226,6 +248,19 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 
 	images = append(images, chartImages...)
 
+	if req.ImagePolicy != nil {
+		var baseImages []string
+		err = resourceFiles.RewriteManifest(fetchRuntimeImages(&baseImages))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		policyImages := append(images, baseImages...)
+		policyImages = append(policyImages, defaults.ContainerImage)
+		if err := req.ImagePolicy.Check(policyImages); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
 	// Now that we have all referenced images in our local registry, and can find them without
 	// a registry prefix, rewrite our resource files to vendor the images.
 	if err = resourceFiles.RewriteImages(v.imageService.Wrap); err != nil {
@@ -279,6 +314,23 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 		return trace.Wrap(err)
 	}
 
+	if req.ImagePolicy != nil && req.ImagePolicy.PinDigests {
+		var pinImages []string
+		for _, image := range images {
+			if !strings.HasPrefix(image, v.registryURL) {
+				pinImages = append(pinImages, image)
+			}
+		}
+		digests, err := imageDigests(v.dockerClient, pinImages)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = resourceFiles.RewriteManifest(makeRewriteImageDigestsFunc(digests))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
 	if req.VendorRuntime {
 		err = resourceFiles.RewriteManifest(v.translateRuntimeImages)
 		if err != nil {
@@ -290,6 +342,23 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 		return trace.Wrap(err)
 	}
 
+	if err = v.exportImages(ctx, unpackedDir, resourceFiles, chartImages, req); err != nil {
+		return trace.Wrap(err)
+	}
+
+	if req.ImagePolicy != nil && req.ImagePolicy.PinDigests {
+		err = pinImageDigests(filepath.Join(unpackedDir, defaults.RegistryDir), resourceFiles, chartResources)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+// exportImages exports the images referenced by the specified resources
+// into the registry directory of the application unless it is already present
+func (v *vendorer) exportImages(ctx context.Context, unpackedDir string, resourceFiles resources.ResourceFiles, chartImages []string, req VendorRequest) error {
 	if ok, _ := utils.IsDirectory(filepath.Join(unpackedDir, defaults.RegistryDir)); ok {
 		log.Debug("Registry layers are present.")
 		return nil
@@ -297,7 +366,7 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 
 	// if the application package does not contain the dump of docker images of the referenced
 	// containers, pull all the necessary images, then export those images to disk
-	images, err = resourceFiles.Images()
+	images, err := resourceFiles.Images()
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -308,11 +377,11 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 	}
 
 	log.Infof("No registry layers found, will pull and export images %q.", images)
-	if err = v.pullAndExportImages(ctx, teleutils.Deduplicate(images), unpackedDir, req.Parallel, req.ProgressReporter); err != nil {
+	if err = v.pullAndExportImages(ctx, teleutils.Deduplicate(images), unpackedDir, req); err != nil {
 		return trace.Wrap(err)
 	}
 
-	if err = v.pullAndExportImages(ctx, teleutils.Deduplicate(chartImages), unpackedDir, req.Parallel, req.ProgressReporter); err != nil {
+	if err = v.pullAndExportImages(ctx, teleutils.Deduplicate(chartImages), unpackedDir, req); err != nil {
 		return trace.Wrap(err)
 	}
 
@@ -353,7 +422,7 @@ func printResourceStatus(resourceFile resources.ResourceFile, manifestPath strin
 // pullAndExportImages pulls the docker images of all referenced container images (if not yet
 // present locally), pushes them into an instance of a private docker registry and then
 // dumps the contents of this private registry into the specified directory
-func (v *vendorer) pullAndExportImages(ctx context.Context, images []string, exportDir string, parallel int, progress utils.Progress) error {
+func (v *vendorer) pullAndExportImages(ctx context.Context, images []string, exportDir string, req VendorRequest) error {
 	resourcesDir := filepath.Join(exportDir, "resources")
 	if err := os.MkdirAll(resourcesDir, defaults.PrivateDirMask); err != nil {
 		return trace.Wrap(trace.ConvertSystemError(err),
@@ -372,7 +441,7 @@ func (v *vendorer) pullAndExportImages(ctx context.Context, images []string, exp
 	}
 
 	if err := exportLayers(ctx, exportDir, images, v.dockerClient,
-		log.WithField("export-directory", exportDir), parallel, progress); err != nil {
+		log.WithField("export-directory", exportDir), req.Parallel, req.ProgressReporter, req.ImageCache); err != nil {
 		return trace.Wrap(err)
 	}
 	return nil
@@ -54,3 +54,10 @@ type Objects interface {
 	// GetBLOBEnvelope returns BLOB envelope
 	GetBLOBEnvelope(hash string) (*Envelope, error)
 }
+
+// Quarantiner is implemented by BLOB storages that can set aside
+// corrupt BLOBs for later inspection instead of deleting them
+type Quarantiner interface {
+	// QuarantineBLOB moves the BLOB identified by hash out of the storage
+	QuarantineBLOB(hash string) error
+}
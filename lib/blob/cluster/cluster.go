@@ -18,6 +18,7 @@ package cluster
 
 import (
 	"io"
+	"math/rand"
 	"sort"
 	"time"
 
@@ -63,6 +64,14 @@ type Config struct {
 	// GracePeriod is a period for GC not to delete undetected files
 	// to prevent accidental deletion. Defaults to 1 hour
 	GracePeriod time.Duration
+	// MinReplicas is the minimum amount of active peers that should
+	// store each object. Under-replicated objects are repaired by fetching
+	// them to this peer
+	MinReplicas int
+	// ScrubPeriod defines the period between integrity checks of local objects
+	ScrubPeriod time.Duration
+	// RepairPeriod defines the period between under-replication checks
+	RepairPeriod time.Duration
 }
 
 // New returns cluster BLOB storage that takes care of replication
@@ -99,6 +108,15 @@ func New(config Config) (blob.Objects, error) {
 	if config.GracePeriod == 0 {
 		config.GracePeriod = defaults.GracePeriod
 	}
+	if config.MinReplicas < 1 {
+		config.MinReplicas = defaults.MinReplicas
+	}
+	if config.ScrubPeriod == 0 {
+		config.ScrubPeriod = defaults.BLOBScrubPeriod
+	}
+	if config.RepairPeriod == 0 {
+		config.RepairPeriod = defaults.BLOBRepairPeriod
+	}
 
 	close, cancelFn := context.WithCancel(context.TODO())
 
@@ -110,9 +128,13 @@ func New(config Config) (blob.Objects, error) {
 
 	c := &cluster{Config: config, close: close, cancelFn: cancelFn, Entry: entry}
 	if !c.TestMode {
-		go c.periodically("heartbeat", c.heartbeat)
-		go c.periodically("purgeDeleted", c.purgeDeletedObjects)
-		go c.periodically("fetchNew", c.fetchNewObjects)
+		go c.periodically("heartbeat", defaults.HeartbeatPeriod, 0, c.heartbeat)
+		go c.periodically("purgeDeleted", defaults.HeartbeatPeriod, 0, c.purgeDeletedObjects)
+		go c.periodically("fetchNew", defaults.HeartbeatPeriod, 0, c.fetchNewObjects)
+		// Integrity checks are expensive so they do not run on startup and
+		// are spread out in time to avoid all peers scrubbing at once
+		go c.periodically("scrub", c.ScrubPeriod, withJitter(c.ScrubPeriod), c.scrubObjects)
+		go c.periodically("repair", c.RepairPeriod, withJitter(c.RepairPeriod), c.repairObjects)
 	}
 
 	return c, nil
@@ -145,8 +167,17 @@ type resultTuple struct {
 	peer     storage.Peer
 }
 
-func (c *cluster) periodically(name string, fn func() error) {
-	ticker := time.NewTicker(defaults.HeartbeatPeriod)
+// periodically runs fn every period after the initial delay
+func (c *cluster) periodically(name string, period, delay time.Duration, fn func() error) {
+	if delay > 0 {
+		select {
+		case <-c.close.Done():
+			c.Infof("Returning, cluster is closing.")
+			return
+		case <-c.Clock.After(delay):
+		}
+	}
+	ticker := time.NewTicker(period)
 	defer ticker.Stop()
 	if err := fn(); err != nil {
 		c.Errorf("Periodic %v failed: %v.", name, err)
@@ -272,6 +303,15 @@ func (c *cluster) fetchObject(hash string) error {
 			errors = append(errors, err)
 			continue
 		}
+		if envelope.SHA512 != hash {
+			// the peer copy is corrupt, do not keep it and try other peers
+			c.Errorf("Object %v fetched from %v has hash %v.", hash, p, envelope.SHA512)
+			if err := c.Local.DeleteBLOB(envelope.SHA512); err != nil {
+				c.Warnf("Failed to delete %v: %v.", envelope.SHA512, err)
+			}
+			errors = append(errors, trace.CompareFailed("%v returned corrupt copy of %v", p, hash))
+			continue
+		}
 		c.Infof("Successfully fetched %v from %v.", envelope, p)
 		err = c.Backend.UpsertObjectPeers(hash, []string{c.ID}, 0)
 		if err != nil {
@@ -420,7 +460,7 @@ func (c *cluster) getPeers(ids []string) ([]storage.Peer, error) {
 	if len(in) == 0 {
 		in = []storage.Peer{c.localPeer()}
 	}
-	missedWindow := time.Duration(c.MissedHeartbeats) * c.HeartbeatPeriod
+	missedWindow := c.missedWindow()
 	out := make([]storage.Peer, 0, len(in))
 	for _, p := range in {
 		// Skip non-local peer
@@ -430,7 +470,7 @@ func (c *cluster) getPeers(ids []string) ([]storage.Peer, error) {
 				continue
 			}
 			// if it's last heartbeat is older than the acceptance time frame
-			if c.Clock.Now().UTC().Sub(p.LastHeartbeat) > missedWindow {
+			if !isActive(p, c.Clock.Now().UTC(), missedWindow) {
 				c.Infof("Excluding %v, missed heartbeat window %v, last heartbeat: %v.", p.ID, missedWindow, p.LastHeartbeat)
 				continue
 			}
@@ -441,6 +481,17 @@ func (c *cluster) getPeers(ids []string) ([]storage.Peer, error) {
 	return out, nil
 }
 
+// missedWindow returns the time frame a peer should heartbeat within
+// to be considered active
+func (c *cluster) missedWindow() time.Duration {
+	return time.Duration(c.MissedHeartbeats) * c.HeartbeatPeriod
+}
+
+// isActive returns true if the peer has heartbeated within the missed window
+func isActive(p storage.Peer, now time.Time, missedWindow time.Duration) bool {
+	return now.Sub(p.LastHeartbeat) <= missedWindow
+}
+
 // peerSorter makes sure local peer always goes first
 // and guarantees deterministic peer order
 type peerSorter struct {
@@ -466,3 +517,12 @@ func (s *peerSorter) Less(i, j int) bool {
 	}
 	return s.P[i].ID < s.P[j].ID
 }
+
+// withJitter returns the specified duration extended by
+// a random jitter of up to a tenth of its value
+func withJitter(d time.Duration) time.Duration {
+	if jitter := int64(d / 10); jitter > 0 {
+		return d + time.Duration(rand.Int63n(jitter))
+	}
+	return d
+}
@@ -42,6 +42,7 @@ import (
 	"github.com/gravitational/roundtrip"
 	"github.com/gravitational/trace"
 	"github.com/jonboulle/clockwork"
+	"golang.org/x/net/context"
 	. "gopkg.in/check.v1"
 )
 
@@ -118,6 +119,42 @@ func (s *ClusterSinglePeer) TestBLOBList(c *C) {
 	s.suite.BLOBList(c)
 }
 
+func (s *ClusterSinglePeer) TestPeriodicRunsAfterDelay(c *C) {
+	clock := clockwork.NewFakeClock()
+	close, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cluster := &cluster{
+		Entry:  log.WithField("test", "periodic"),
+		Config: Config{Clock: clock},
+		close:  close,
+	}
+	runs := make(chan struct{}, 1)
+	go cluster.periodically("test", time.Hour, time.Minute, func() error {
+		runs <- struct{}{}
+		return nil
+	})
+	clock.BlockUntil(1)
+	select {
+	case <-runs:
+		c.Fatal("Function should not run before the initial delay.")
+	default:
+	}
+	clock.Advance(time.Minute)
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		c.Fatal("Function has not run after the initial delay.")
+	}
+}
+
+func (s *ClusterSinglePeer) TestJitterExtendsPeriod(c *C) {
+	for i := 0; i < 10; i++ {
+		delay := withJitter(time.Hour)
+		c.Assert(delay >= time.Hour && delay < time.Hour+6*time.Minute, Equals, true,
+			Commentf("unexpected delay %v", delay))
+	}
+}
+
 const peersCount = 3
 
 type ClusterMultiPeers struct {
@@ -210,6 +247,14 @@ func (s *ClusterMultiPeers) TestCleanup(c *C) {
 	s.clusterSuite.Cleanup(c)
 }
 
+func (s *ClusterMultiPeers) TestScrub(c *C) {
+	s.clusterSuite.Scrub(c)
+}
+
+func (s *ClusterMultiPeers) TestRepair(c *C) {
+	s.clusterSuite.Repair(c)
+}
+
 type RPCSuite struct {
 	suite        suite.BLOBSuite
 	clusterSuite clusterSuite
@@ -339,6 +384,14 @@ func (s *RPCSuite) TestCleanup(c *C) {
 	s.clusterSuite.Cleanup(c)
 }
 
+func (s *RPCSuite) TestScrub(c *C) {
+	s.clusterSuite.Scrub(c)
+}
+
+func (s *RPCSuite) TestRepair(c *C) {
+	s.clusterSuite.Repair(c)
+}
+
 type clusterSuite struct {
 	objects []*cluster
 	clients []blob.Objects
@@ -402,3 +455,80 @@ func (s *clusterSuite) Cleanup(c *C) {
 		c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
 	}
 }
+
+func (s *clusterSuite) Scrub(c *C) {
+	peer1 := s.objects[0]
+
+	data := []byte("hello, there, cluster!")
+
+	envelope, err := s.clients[0].WriteBLOB(bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+
+	// corrupt the local copy on disk
+	f, err := peer1.Local.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+	path := f.(*os.File).Name()
+	c.Assert(f.Close(), IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte("hello, there, corrupt!"), 0644), IsNil)
+	c.Assert(trace.IsCompareFailed(verifyObject(peer1.Local, envelope.SHA512)), Equals, true)
+
+	c.Assert(peer1.scrubObjects(), IsNil)
+
+	// corrupt copy has been quarantined and replaced with the intact copy from another peer
+	quarantined, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), "..", "..", "quarantine", envelope.SHA512))
+	c.Assert(err, IsNil)
+	c.Assert(string(quarantined), Equals, "hello, there, corrupt!")
+	c.Assert(verifyObject(peer1.Local, envelope.SHA512), IsNil)
+	ids, err := peer1.Backend.GetObjectPeers(envelope.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []string{"0", "1"})
+}
+
+func (s *clusterSuite) Repair(c *C) {
+	peer3 := s.objects[2]
+
+	data := []byte("hello, there, cluster!")
+
+	envelope, err := s.clients[0].WriteBLOB(bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+
+	status, err := GetStatus(StatusConfig{
+		Backend:          peer3.Backend,
+		MinReplicas:      peersCount,
+		HeartbeatPeriod:  heartbeatPeriod,
+		MissedHeartbeats: missedHeartbeats,
+		Clock:            s.clock,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(status.Objects, DeepEquals, []ObjectStatus{{
+		Hash:        envelope.SHA512,
+		Peers:       []string{"0", "1"},
+		ActivePeers: []string{"0", "1"},
+		Health:      ReplicaUnderReplicated,
+	}})
+
+	peer3.MinReplicas = peersCount
+	c.Assert(peer3.repairObjects(), IsNil)
+	c.Assert(verifyObject(peer3.Local, envelope.SHA512), IsNil)
+
+	status, err = GetStatus(StatusConfig{
+		Backend:          peer3.Backend,
+		MinReplicas:      peersCount,
+		HeartbeatPeriod:  heartbeatPeriod,
+		MissedHeartbeats: missedHeartbeats,
+		Clock:            s.clock,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(status.IsHealthy(), Equals, true, Commentf("%#v", status))
+
+	// all peers have missed their heartbeats
+	s.clock.Advance(time.Minute)
+	status, err = GetStatus(StatusConfig{
+		Backend:          peer3.Backend,
+		HeartbeatPeriod:  heartbeatPeriod,
+		MissedHeartbeats: missedHeartbeats,
+		Clock:            s.clock,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(status.Objects[0].Health, Equals, ReplicaUnavailable)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// scrubObjects re-hashes all local objects and compares them to their envelopes.
+// Corrupt objects are quarantined and re-fetched from other peers
+func (c *cluster) scrubObjects() error {
+	hashes, err := c.Local.GetBLOBs()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, hash := range hashes {
+		err := verifyObject(c.Local, hash)
+		if err == nil {
+			continue
+		}
+		if !trace.IsCompareFailed(err) {
+			c.Warnf("Failed to verify object %v: %v.", hash, trace.DebugReport(err))
+			continue
+		}
+		c.Errorf("Found corrupt object: %v.", err)
+		if err := c.quarantineObject(hash); err != nil {
+			c.Errorf("Failed to quarantine object %v: %v.", hash, trace.DebugReport(err))
+			continue
+		}
+		if err := c.fetchObject(hash); err != nil {
+			c.Warningf("Failed to fetch object(%v) %v.", hash, trace.DebugReport(err))
+		}
+	}
+	return nil
+}
+
+// repairObjects makes sure that objects stored on fewer active peers than
+// required are replicated to this peer
+func (c *cluster) repairObjects() error {
+	objects, err := c.Backend.GetObjects()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	peers, err := c.getPeers(nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, hash := range objects {
+		ids, err := c.Backend.GetObjectPeers(hash)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return trace.Wrap(err)
+		}
+		replicas := activeReplicas(ids, peers)
+		if len(replicas) >= c.MinReplicas || utils.StringInSlice(replicas, c.ID) {
+			continue
+		}
+		c.Warnf("Object %v is under-replicated: %v of %v replicas.",
+			hash, len(replicas), c.MinReplicas)
+		if err := c.repairObject(hash); err != nil {
+			c.Warningf("Failed to repair object(%v) %v.", hash, trace.DebugReport(err))
+		}
+	}
+	return nil
+}
+
+// repairObject registers the intact local copy of the object with this peer
+// or fetches the object from other peers if there is none
+func (c *cluster) repairObject(hash string) error {
+	err := verifyObject(c.Local, hash)
+	if err == nil {
+		return trace.Wrap(c.Backend.UpsertObjectPeers(hash, []string{c.ID}, 0))
+	}
+	if !trace.IsNotFound(err) {
+		if err := c.quarantineObject(hash); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(c.fetchObject(hash))
+}
+
+// quarantineObject removes the local copy of the object from service.
+// If the local storage supports quarantine, the copy is kept for inspection
+func (c *cluster) quarantineObject(hash string) error {
+	err := c.Backend.DeleteObjectPeers(hash, []string{c.ID})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if quarantiner, ok := c.Local.(blob.Quarantiner); ok {
+		return trace.Wrap(quarantiner.QuarantineBLOB(hash))
+	}
+	return trace.Wrap(c.Local.DeleteBLOB(hash))
+}
+
+// verifyObject computes the hash of the object contents and compares
+// it to the object envelope
+func verifyObject(objects blob.Objects, hash string) error {
+	envelope, err := objects.GetBLOBEnvelope(hash)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	f, err := objects.OpenBLOB(hash)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer f.Close()
+	hasher := sha512.New()
+	size, err := io.Copy(hasher, f)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	actual := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+	if actual != envelope.SHA512 || size != envelope.SizeBytes {
+		return trace.CompareFailed("object %v has hash %v and size %v, expected size %v",
+			hash, actual, size, envelope.SizeBytes)
+	}
+	return nil
+}
+
+// activeReplicas returns IDs of active peers from the specified list
+func activeReplicas(ids []string, active []storage.Peer) (replicas []string) {
+	for _, p := range active {
+		if utils.StringInSlice(ids, p.ID) {
+			replicas = append(replicas, p.ID)
+		}
+	}
+	return replicas
+}
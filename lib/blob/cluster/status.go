@@ -0,0 +1,177 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// StatusConfig is the configuration of the replication status query
+type StatusConfig struct {
+	// Backend is a discovery and metadata backend
+	Backend storage.Backend
+	// MinReplicas is the minimum amount of active peers that should
+	// store each object
+	MinReplicas int
+	// HeartbeatPeriod defines the period between heartbeats
+	HeartbeatPeriod time.Duration
+	// MissedHeartbeats is how many heartbeats the peer
+	// should miss before it is considered inactive
+	MissedHeartbeats int
+	// Clock is clock interface, used in tests
+	Clock clockwork.Clock
+}
+
+func (c *StatusConfig) checkAndSetDefaults() error {
+	if c.Backend == nil {
+		return trace.BadParameter("missing parameter Backend")
+	}
+	if c.MinReplicas < 1 {
+		c.MinReplicas = defaults.MinReplicas
+	}
+	if c.HeartbeatPeriod == 0 {
+		c.HeartbeatPeriod = defaults.HeartbeatPeriod
+	}
+	if c.MissedHeartbeats == 0 {
+		c.MissedHeartbeats = defaults.MissedHeartbeats
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	return nil
+}
+
+// Status describes the replication state of the cluster BLOB storage
+type Status struct {
+	// MinReplicas is the minimum amount of active peers that should
+	// store each object
+	MinReplicas int `json:"min_replicas"`
+	// Peers lists the storage peers
+	Peers []PeerStatus `json:"peers"`
+	// Objects lists the replica health of each object
+	Objects []ObjectStatus `json:"objects"`
+}
+
+// PeerStatus describes a single storage peer
+type PeerStatus struct {
+	// ID is the peer ID
+	ID string `json:"id"`
+	// AdvertiseAddr is the peer advertise address
+	AdvertiseAddr string `json:"advertise_addr"`
+	// LastHeartbeat is the time of the last peer heartbeat
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	// Active is whether the peer has heartbeated recently
+	Active bool `json:"active"`
+}
+
+// ObjectStatus describes the replica health of a single object
+type ObjectStatus struct {
+	// Hash is the object hash
+	Hash string `json:"hash"`
+	// Peers lists IDs of all peers that have the object
+	Peers []string `json:"peers"`
+	// ActivePeers lists IDs of active peers that have the object
+	ActivePeers []string `json:"active_peers"`
+	// Health is the replica health of the object
+	Health ReplicaHealth `json:"health"`
+}
+
+// ReplicaHealth describes the replica health of an object
+type ReplicaHealth string
+
+const (
+	// ReplicaHealthy means the object is stored on enough active peers
+	ReplicaHealthy ReplicaHealth = "healthy"
+	// ReplicaUnderReplicated means the object is stored on fewer active
+	// peers than required
+	ReplicaUnderReplicated ReplicaHealth = "under-replicated"
+	// ReplicaUnavailable means no active peer stores the object
+	ReplicaUnavailable ReplicaHealth = "unavailable"
+)
+
+// IsHealthy returns true if all objects are sufficiently replicated
+func (s Status) IsHealthy() bool {
+	for _, object := range s.Objects {
+		if object.Health != ReplicaHealthy {
+			return false
+		}
+	}
+	return true
+}
+
+// GetStatus returns the replication status of objects in the cluster BLOB storage
+func GetStatus(config StatusConfig) (*Status, error) {
+	if err := config.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	peers, err := config.Backend.GetPeers()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	now := config.Clock.Now().UTC()
+	missedWindow := time.Duration(config.MissedHeartbeats) * config.HeartbeatPeriod
+	status := Status{MinReplicas: config.MinReplicas}
+	var active []storage.Peer
+	for _, p := range peers {
+		peerStatus := PeerStatus{
+			ID:            p.ID,
+			AdvertiseAddr: p.AdvertiseAddr,
+			LastHeartbeat: p.LastHeartbeat,
+			Active:        isActive(p, now, missedWindow),
+		}
+		if peerStatus.Active {
+			active = append(active, p)
+		}
+		status.Peers = append(status.Peers, peerStatus)
+	}
+	sort.Slice(status.Peers, func(i, j int) bool {
+		return status.Peers[i].ID < status.Peers[j].ID
+	})
+	hashes, err := config.Backend.GetObjects()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, hash := range hashes {
+		ids, err := config.Backend.GetObjectPeers(hash)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		sort.Strings(ids)
+		object := ObjectStatus{
+			Hash:        hash,
+			Peers:       ids,
+			ActivePeers: activeReplicas(ids, active),
+		}
+		switch {
+		case len(object.ActivePeers) == 0:
+			object.Health = ReplicaUnavailable
+		case len(object.ActivePeers) < config.MinReplicas:
+			object.Health = ReplicaUnderReplicated
+		default:
+			object.Health = ReplicaHealthy
+		}
+		status.Objects = append(status.Objects, object)
+	}
+	return &status, nil
+}
@@ -22,8 +22,10 @@ import (
 	"io/ioutil"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/sbom"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -42,6 +44,14 @@ func Build(ctx context.Context, builder *Builder) error {
 	locator := builder.Locator()
 	if builder.OutPath == "" {
 		builder.OutPath = fmt.Sprintf("%v-%v.tar", locator.Name, locator.Version)
+		if len(builder.overlays) != 0 {
+			var names []string
+			for _, overlay := range builder.overlays {
+				names = append(names, overlay.Name)
+			}
+			builder.OutPath = fmt.Sprintf("%v-%v-%v.tar", locator.Name, locator.Version,
+				strings.Join(names, "-"))
+		}
 		if _, err := os.Stat(builder.OutPath); err == nil && !builder.Overwrite {
 			return trace.BadParameter("tarball %v already exists, please remove "+
 				"it first or provide '--force' flag to overwrite it", builder.OutPath)
@@ -60,6 +70,11 @@ func Build(ctx context.Context, builder *Builder) error {
 			builder.Manifest.Kind)
 	}
 
+	err = builder.ResolveDependencies()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	switch builder.Manifest.Kind {
 	case schema.KindBundle, schema.KindCluster:
 		builder.NextStep("Selecting application runtime")
@@ -90,7 +105,7 @@ func Build(ctx context.Context, builder *Builder) error {
 	defer stream.Close()
 
 	builder.NextStep("Creating application")
-	application, err := builder.CreateApplication(stream)
+	application, err := builder.CreateApplicationFromDir(vendorDir, stream)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -101,6 +116,14 @@ func Build(ctx context.Context, builder *Builder) error {
 		return trace.Wrap(err)
 	}
 	defer installer.Close()
+	if builder.DeltaFrom != "" {
+		delta, err := builder.GenerateDelta(installer)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		defer delta.Close()
+		installer = delta
+	}
 
 	builder.NextStep("Saving the snapshot as %v", builder.OutPath)
 	err = builder.WriteInstaller(installer)
@@ -108,6 +131,17 @@ func Build(ctx context.Context, builder *Builder) error {
 		return trace.Wrap(err)
 	}
 
+	sbomPath := sbom.FileName(builder.OutPath)
+	builder.NextStep("Saving the software bill of materials as %v", sbomPath)
+	err = builder.WriteSBOM(*application, sbomPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	if err := builder.PruneCache(); err != nil {
+		builder.Warnf("Failed to prune build cache: %v.", trace.DebugReport(err))
+	}
+
 	return nil
 }
 
@@ -135,7 +169,7 @@ func checkBuildEnv() error {
 
 const (
 	// clusterBuildSteps is a number of steps when building a cluster image.
-	clusterBuildSteps = 6
+	clusterBuildSteps = 7
 	// appBuildSteps is a number of steps when building an app image.
-	appBuildSteps = 4
+	appBuildSteps = 5
 )
@@ -28,6 +28,8 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/resolver"
+	"github.com/gravitational/gravity/lib/app/resources"
 	"github.com/gravitational/gravity/lib/app/service"
 	blobfs "github.com/gravitational/gravity/lib/blob/fs"
 	"github.com/gravitational/gravity/lib/constants"
@@ -37,14 +39,17 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/layerpack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/sbom"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/lib/vulnscan"
 	"k8s.io/helm/pkg/chartutil"
 
 	"github.com/coreos/go-semver/semver"
 	"github.com/docker/docker/pkg/archive"
+	"github.com/dustin/go-humanize"
 	"github.com/ghodss/yaml"
 	"github.com/gravitational/trace"
 	"github.com/gravitational/version"
@@ -65,6 +70,10 @@ type Config struct {
 	manifestDir string
 	// manifestFilename is the name of the manifest file
 	manifestFilename string
+	// Overlays lists the overlays applied to the manifest and resource files,
+	// either as names of files in the overlays directory next to the manifest
+	// or as paths to overlay files
+	Overlays []string
 	// OutPath holds the path to the installer tarball to be output
 	OutPath string
 	// Overwrite indicates whether or not to overwrite an existing installer file
@@ -75,6 +84,19 @@ type Config struct {
 	SkipVersionCheck bool
 	// VendorReq combines vendoring options
 	VendorReq service.VendorRequest
+	// DeltaFrom is the optional path to the installer of the previous version.
+	// If specified, the build produces a delta upgrade tarball against it
+	DeltaFrom string
+	// CacheDir is the optional directory of the persistent build cache
+	CacheDir string
+	// CacheMaxAge is the time after which unused entries are pruned from the build cache
+	CacheMaxAge time.Duration
+	// VulnDatabase is the optional vulnerability database the vendored
+	// images are scanned against
+	VulnDatabase *vulnscan.Database
+	// VulnThreshold is the minimum severity of found vulnerabilities
+	// that fails the build
+	VulnThreshold vulnscan.Severity
 	// Generator is used to generate installer
 	Generator Generator
 	// NewSyncer is used to initialize package cache syncer for the builder
@@ -112,6 +134,17 @@ func (c *Config) CheckAndSetDefaults() error {
 				defaults.ManifestFileName)
 		}
 	}
+	if c.DeltaFrom != "" {
+		if _, err := os.Stat(c.DeltaFrom); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	if c.VulnThreshold == "" {
+		c.VulnThreshold = vulnscan.Severity(defaults.VulnSeverityThreshold)
+	}
+	if c.CacheMaxAge == 0 {
+		c.CacheMaxAge = defaults.BuildCacheMaxAge
+	}
 	if c.VendorReq.Parallel == 0 {
 		c.VendorReq.Parallel = runtime.NumCPU()
 	}
@@ -144,7 +177,12 @@ func New(config Config) (*Builder, error) {
 		return nil, trace.ConvertSystemError(err)
 	}
 	var manifest *schema.Manifest
+	var manifestBytes []byte
+	var overlays []resources.Overlay
 	if fi.IsDir() {
+		if len(config.Overlays) != 0 {
+			return nil, trace.BadParameter("overlays are not supported for Helm charts")
+		}
 		// If this is a Helm chart directory, extract the chart metadata
 		// and generate a basic application manifest.
 		fi, err := os.Stat(filepath.Join(config.ManifestPath, constants.HelmChartFile))
@@ -163,10 +201,21 @@ func New(config Config) (*Builder, error) {
 			return nil, trace.Wrap(err)
 		}
 	} else {
-		manifestBytes, err := ioutil.ReadFile(config.ManifestPath)
+		manifestBytes, err = ioutil.ReadFile(config.ManifestPath)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
+		for _, name := range config.Overlays {
+			overlay, err := resources.LoadOverlay(config.manifestDir, name)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			manifestBytes, err = overlay.ApplyManifest(manifestBytes)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			overlays = append(overlays, *overlay)
+		}
 		manifest, err = schema.ParseManifestYAMLNoValidate(manifestBytes)
 		if err != nil {
 			logrus.Errorf(trace.DebugReport(err))
@@ -175,14 +224,24 @@ func New(config Config) (*Builder, error) {
 		}
 	}
 	b := &Builder{
-		Config:   config,
-		Manifest: *manifest,
+		Config:       config,
+		Manifest:     *manifest,
+		manifestData: manifestBytes,
+		overlays:     overlays,
 	}
 	err = b.initServices()
 	if err != nil {
 		b.Close()
 		return nil, trace.Wrap(err)
 	}
+	if config.CacheDir != "" {
+		b.cache, err = NewCache(config.CacheDir)
+		if err != nil {
+			b.Close()
+			return nil, trace.Wrap(err)
+		}
+		b.VendorReq.ImageCache = b.cache.Images
+	}
 	return b, nil
 }
 
@@ -206,6 +265,15 @@ type Builder struct {
 	Apps app.Applications
 	// syncer is used to sync local package cache with repository
 	syncer Syncer
+	// cache is the optional persistent build cache
+	cache *Cache
+	// manifestData is the manifest file contents with overlays applied
+	manifestData []byte
+	// overlays lists the overlays applied to the resource files
+	overlays []resources.Overlay
+	// lock records the versions of the dependencies specified with
+	// version constraints
+	lock *resolver.LockFile
 }
 
 // Locator returns locator of the application that's being built
@@ -282,13 +350,43 @@ func (b *Builder) SyncPackageCache(runtimeVersion *semver.Version) error {
 }
 
 // Vendor vendors the application images in the provided directory and
-// returns the compressed data stream with the application data
+// returns the compressed data stream with the application data.
+//
+// The application layout is copied into dir first so the image policy
+// and other rewrites do not modify the source files
 func (b *Builder) Vendor(ctx context.Context, dir string) (io.ReadCloser, error) {
 	err := utils.CopyDirContents(b.manifestDir, filepath.Join(dir, defaults.ResourcesDir))
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 	manifestPath := filepath.Join(dir, defaults.ResourcesDir, "app.yaml")
+	if b.manifestFilename != "" && (len(b.overlays) != 0 || b.lock != nil) {
+		err = ioutil.WriteFile(manifestPath, b.manifestData, defaults.SharedReadMask)
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
+	if b.lock != nil {
+		err = b.lock.Write(filepath.Join(dir, defaults.ResourcesDir, defaults.LockFileName))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if len(b.overlays) != 0 {
+		// The overlays have been applied to the variant being built
+		// so they are not shipped with the application
+		err = os.RemoveAll(filepath.Join(dir, defaults.ResourcesDir, defaults.OverlaysDir))
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		for _, overlay := range b.overlays {
+			b.PrintSubStep("Applying overlay %v", overlay.Name)
+			err = overlay.ApplyResources(filepath.Join(dir, defaults.ResourcesDir))
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+		}
+	}
 	// If manifest filename is empty, it means it was auto-generated
 	// out of a Helm chart so write the generated manifest to the
 	// vendor directory as well.
@@ -317,9 +415,37 @@ func (b *Builder) Vendor(ctx context.Context, dir string) (io.ReadCloser, error)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	if b.VulnDatabase != nil {
+		err = b.scanImages(filepath.Join(dir, defaults.RegistryDir))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
 	return archive.Tar(dir, archive.Uncompressed)
 }
 
+// scanImages scans the vendored images in the specified registry directory
+// for vulnerabilities and writes the report next to the installer tarball.
+// Returns an error if vulnerabilities at or above the configured threshold are found
+func (b *Builder) scanImages(registryDir string) error {
+	b.PrintSubStep("Scanning images for vulnerabilities")
+	report, err := vulnscan.Scan(registryDir, *b.VulnDatabase)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	reportPath := vulnscan.ReportFileName(b.OutPath)
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if err := report.Write(f); err != nil {
+		return trace.Wrap(err)
+	}
+	b.PrintSubStep("Vulnerability scan: %v, report saved as %v", report, reportPath)
+	return trace.Wrap(report.Check(b.VulnThreshold))
+}
+
 // CreateApplication creates a Gravity application from the provided
 // data in the local database
 func (b *Builder) CreateApplication(data io.ReadCloser) (*app.Application, error) {
@@ -347,6 +473,50 @@ func (b *Builder) CreateApplication(data io.ReadCloser) (*app.Application, error
 	return b.Apps.GetImportedApplication(*op)
 }
 
+// CreateApplicationFromDir creates a Gravity application from the data
+// vendored into the specified directory, reusing the package from the
+// build cache if the vendored data has not changed since it was cached
+func (b *Builder) CreateApplicationFromDir(dir string, data io.ReadCloser) (*app.Application, error) {
+	if b.cache == nil {
+		return b.CreateApplication(data)
+	}
+	locator := b.Locator()
+	key, err := b.cache.PackageKey(locator, dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	application, err := b.cache.CreateApp(b.Apps, locator, key)
+	if err == nil {
+		b.PrintSubStep("Reused cached application package %v", locator)
+		return application, nil
+	}
+	if !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	application, err = b.CreateApplication(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := b.cache.StoreApp(b.Packages, application.Package, key); err != nil {
+		b.Warnf("Failed to cache application package %v: %v.", application.Package, trace.DebugReport(err))
+	}
+	return application, nil
+}
+
+// PruneCache removes entries of the build cache that have not been
+// used for longer than the configured maximum age
+func (b *Builder) PruneCache() error {
+	if b.cache == nil {
+		return nil
+	}
+	freed, err := b.cache.Prune(b.CacheMaxAge)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	b.Infof("Pruned %v from the build cache in %v.", humanize.Bytes(uint64(freed)), b.CacheDir)
+	return nil
+}
+
 // GenerateInstaller generates an installer tarball for the specified
 // application and returns its data as a stream
 func (b *Builder) GenerateInstaller(application app.Application) (io.ReadCloser, error) {
@@ -363,6 +533,26 @@ func (b *Builder) WriteInstaller(data io.ReadCloser) error {
 	return trace.Wrap(err)
 }
 
+// WriteSBOM writes the software bill of materials of the specified
+// application to the file at the provided path
+func (b *Builder) WriteSBOM(application app.Application, path string) error {
+	bom, err := sbom.Generate(sbom.Config{
+		Apps:        b.Apps,
+		Packages:    b.Packages,
+		Application: application,
+		Tool:        constants.TelePackage,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	return trace.Wrap(bom.Write(f))
+}
+
 // initServices initializes the builder backend, package and apps services
 func (b *Builder) initServices() (err error) {
 	b.Env, err = b.makeBuildEnv()
@@ -0,0 +1,235 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/docker"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Cache is the persistent build cache shared between builds.
+//
+// Vendored images are cached by their image ID, and application packages
+// are cached by the hash of the vendored resource tree so unchanged
+// images and packages are reused by subsequent builds
+type Cache struct {
+	// Dir is the cache directory
+	Dir string
+	// Images is the cache of vendored images
+	Images *docker.ImageCache
+}
+
+// NewCache returns a new build cache in the specified directory
+func NewCache(dir string) (*Cache, error) {
+	err := os.MkdirAll(filepath.Join(dir, cachePackagesDir), defaults.SharedDirMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	images, err := docker.NewImageCache(filepath.Join(dir, cacheImagesDir))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Cache{
+		Dir:    dir,
+		Images: images,
+	}, nil
+}
+
+// PackageKey returns the cache key of the application package with the
+// specified locator vendored into the provided directory
+func (c *Cache) PackageKey(locator loc.Locator, dir string) (string, error) {
+	hash, err := treeHash(dir)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	sum := sha256.Sum256([]byte(locator.String() + "\n" + hash))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CreateApp creates the application with the specified locator from the
+// package cached under the provided key.
+// Returns trace.NotFound if the package is not in the cache
+func (c *Cache) CreateApp(apps app.Applications, locator loc.Locator, key string) (*app.Application, error) {
+	entryDir := filepath.Join(c.Dir, cachePackagesDir, key)
+	manifest, err := ioutil.ReadFile(filepath.Join(entryDir, cacheManifestFile))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	f, err := os.Open(filepath.Join(entryDir, cacheDataFile))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	application, err := apps.CreateAppWithManifest(locator, manifest, f, nil)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// mark the entry as recently used
+	now := time.Now()
+	if err := os.Chtimes(entryDir, now, now); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return application, nil
+}
+
+// StoreApp adds the application package with the specified locator
+// to the cache under the provided key
+func (c *Cache) StoreApp(packages pack.PackageService, locator loc.Locator, key string) error {
+	envelope, reader, err := packages.ReadPackage(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	tempDir, err := ioutil.TempDir(filepath.Join(c.Dir, cachePackagesDir), ".tmp-")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(tempDir)
+	f, err := os.Create(filepath.Join(tempDir, cacheDataFile))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, reader); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := f.Close(); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(tempDir, cacheManifestFile), envelope.Manifest, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	entryDir := filepath.Join(c.Dir, cachePackagesDir, key)
+	if err := os.RemoveAll(entryDir); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.ConvertSystemError(os.Rename(tempDir, entryDir))
+}
+
+// Prune removes cached images and packages that have not been used
+// for longer than maxAge. Returns the number of bytes freed
+func (c *Cache) Prune(maxAge time.Duration) (freed int64, err error) {
+	freed, err = c.Images.Prune(maxAge)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	packagesDir := filepath.Join(c.Dir, cachePackagesDir)
+	entries, err := ioutil.ReadDir(packagesDir)
+	if err != nil {
+		return 0, trace.ConvertSystemError(err)
+	}
+	for _, entry := range entries {
+		if time.Since(entry.ModTime()) <= maxAge {
+			continue
+		}
+		entryDir := filepath.Join(packagesDir, entry.Name())
+		size, err := dirSize(entryDir)
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		logrus.Debugf("Pruning cached package %v.", entry.Name())
+		if err := os.RemoveAll(entryDir); err != nil {
+			return 0, trace.ConvertSystemError(err)
+		}
+		freed += size
+	}
+	return freed, nil
+}
+
+// treeHash returns the hash of the directory tree computed over the
+// relative paths, modes and contents of all files.
+// Registry blobs are content-addressed so only their paths are hashed
+func treeHash(dir string) (string, error) {
+	hash := sha256.New()
+	// filepath.Walk visits files in lexical order so the hash is stable
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		rel = filepath.ToSlash(rel)
+		fmt.Fprintf(hash, "%v %v\n", rel, fi.Mode())
+		if !fi.Mode().IsRegular() || isRegistryBlob(rel) {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		defer f.Close()
+		_, err = io.Copy(hash, f)
+		return trace.ConvertSystemError(err)
+	})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isRegistryBlob returns true if the file with the specified relative
+// path is a blob of the vendored registry
+func isRegistryBlob(path string) bool {
+	return strings.HasPrefix(path, defaults.RegistryDir+"/") &&
+		strings.Contains(path, "/docker/registry/v2/blobs/")
+}
+
+func dirSize(dir string) (size int64, err error) {
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, trace.Wrap(err)
+}
+
+const (
+	// cacheImagesDir is the directory with cached images inside the cache directory
+	cacheImagesDir = "images"
+	// cachePackagesDir is the directory with cached application packages inside the cache directory
+	cachePackagesDir = "packages"
+	// cacheDataFile is the name of the file with the cached package data
+	cacheDataFile = "data"
+	// cacheManifestFile is the name of the file with the cached package manifest
+	cacheManifestFile = "manifest"
+)
@@ -0,0 +1,123 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/archive"
+	blobfs "github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// GenerateDelta converts the provided installer tarball into a delta
+// upgrade tarball that only contains packages and registry blobs not
+// present in the installer specified with DeltaFrom
+func (b *Builder) GenerateDelta(installer io.Reader) (delta io.ReadCloser, err error) {
+	b.PrintSubStep("Generating delta against %v", b.DeltaFrom)
+	baseDir, err := archive.Unpack(b.DeltaFrom)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer os.RemoveAll(baseDir)
+	dir, err := ioutil.TempDir("", "delta")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	if err := archive.Extract(installer, dir); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := createDelta(dir, baseDir); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(archive.CompressDirectory(dir, writer))
+	}()
+	return &utils.CleanupReadCloser{
+		ReadCloser: reader,
+		Cleanup: func() {
+			if err := os.RemoveAll(dir); err != nil {
+				b.Warnf("Failed to delete %v: %v.", dir, trace.DebugReport(err))
+			}
+		},
+	}, nil
+}
+
+// createDelta converts the installer unpacked into dir into a delta
+// against the installer unpacked into baseDir
+func createDelta(dir, baseDir string) error {
+	backend, packages, err := openInstallerPackages(dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer backend.Close()
+	baseBackend, basePackages, err := openInstallerPackages(baseDir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer baseBackend.Close()
+	manifest, err := service.CreateDelta(service.DeltaRequest{
+		Packages: packages,
+		Base:     basePackages,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(service.WriteDeltaManifest(dir, *manifest))
+}
+
+// openInstallerPackages returns the package service of the installer unpacked into dir
+func openInstallerPackages(dir string) (storage.Backend, pack.PackageService, error) {
+	backend, err := keyval.NewBolt(keyval.BoltConfig{
+		Path: filepath.Join(dir, defaults.GravityDBFile),
+	})
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	objects, err := blobfs.New(filepath.Join(dir, defaults.PackagesDir))
+	if err != nil {
+		backend.Close()
+		return nil, nil, trace.Wrap(err)
+	}
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		UnpackedDir: filepath.Join(dir, defaults.PackagesDir, defaults.UnpackedDir),
+		Objects:     objects,
+	})
+	if err != nil {
+		backend.Close()
+		return nil, nil, trace.Wrap(err)
+	}
+	return backend, packages, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/resolver"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+)
+
+// ResolveDependencies resolves the application dependencies specified with
+// version constraints to exact versions available in the local package cache
+// and the package repository.
+//
+// Versions from the lock file next to the manifest are preferred as long as
+// they satisfy the constraints. The resolved versions are recorded in the lock
+// file shipped with the application
+func (b *Builder) ResolveDependencies() error {
+	if !b.Manifest.Dependencies.HasConstraints() {
+		return nil
+	}
+	b.PrintSubStep("Resolving application dependencies")
+	sources := []resolver.Source{resolver.NewPackageSource(b.Env.Packages, "local cache")}
+	if syncer, ok := b.syncer.(dependencySource); ok {
+		sources = append(sources, syncer.DependencySource())
+	}
+	var requirements []resolver.Requirement
+	for _, dep := range b.Manifest.Dependencies.Apps {
+		constraint := dep.Constraint
+		if constraint == "" {
+			constraint = dep.Locator.Version
+		}
+		requirements = append(requirements, resolver.Requirement{
+			Repository: dep.Locator.Repository,
+			Name:       dep.Locator.Name,
+			Constraint: constraint,
+			RequiredBy: defaults.ManifestFileName,
+		})
+	}
+	locked, err := resolver.ReadLockFile(filepath.Join(b.manifestDir, defaults.LockFileName))
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	lock, err := resolver.Resolve(resolver.Config{
+		Sources:      sources,
+		Locked:       locked,
+		Dependencies: b.appDependencies,
+		FieldLogger:  b.FieldLogger,
+	}, requirements)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for i, dep := range b.Manifest.Dependencies.Apps {
+		if dep.Constraint == "" {
+			continue
+		}
+		locator, err := lock.Locator(dep.Locator.Repository, dep.Locator.Name)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		b.PrintSubStep("Resolved %v/%v %v to %v", dep.Locator.Repository,
+			dep.Locator.Name, dep.Constraint, locator.Version)
+		b.Manifest.Dependencies.Apps[i] = schema.Dependency{Locator: *locator}
+	}
+	if b.manifestData != nil {
+		b.manifestData, err = rewriteDependencies(b.manifestData, b.Manifest.Dependencies)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	b.lock = lock
+	return nil
+}
+
+// dependencySource is implemented by syncers that can list the application
+// versions available in their repository
+type dependencySource interface {
+	// DependencySource returns the source of available application versions
+	DependencySource() resolver.Source
+}
+
+// appDependencies returns the requirements of the specified application
+// dependency if its manifest is available in the local cache or the
+// package repository
+func (b *Builder) appDependencies(locator loc.Locator) ([]resolver.Requirement, error) {
+	services := []app.Applications{b.Apps}
+	if syncer, ok := b.syncer.(*packSyncer); ok {
+		services = append(services, syncer.apps)
+	}
+	for _, apps := range services {
+		application, err := apps.GetApp(locator)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		var requirements []resolver.Requirement
+		for _, dep := range application.Manifest.Dependencies.Apps {
+			constraint := dep.Constraint
+			if constraint == "" {
+				constraint = dep.Locator.Version
+			}
+			requirements = append(requirements, resolver.Requirement{
+				Repository: dep.Locator.Repository,
+				Name:       dep.Locator.Name,
+				Constraint: constraint,
+				RequiredBy: locator.String(),
+			})
+		}
+		return requirements, nil
+	}
+	b.Debugf("Manifest of %v is not available, skipping its dependencies.", locator)
+	return nil, nil
+}
+
+// rewriteDependencies replaces the dependencies in the manifest given as YAML
+func rewriteDependencies(manifest []byte, dependencies schema.Dependencies) ([]byte, error) {
+	jsonData, err := yaml.YAMLToJSON(manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(jsonData, &object); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	object["dependencies"] = dependencies
+	jsonData, err = json.Marshal(object)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return yaml.JSONToYAML(jsonData)
+}
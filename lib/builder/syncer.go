@@ -21,6 +21,7 @@ import (
 	"os"
 
 	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/resolver"
 	"github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/defaults"
@@ -152,6 +153,11 @@ func (s *s3Syncer) Sync(builder *Builder, runtimeVersion *semver.Version) error
 	}, builder.Manifest)
 }
 
+// DependencySource returns the source of application versions published in the hub
+func (s *s3Syncer) DependencySource() resolver.Source {
+	return resolver.NewHubSource(s.hub)
+}
+
 // packSyncer synchronizes local package cache with pack/apps services
 type packSyncer struct {
 	pack pack.PackageService
@@ -184,6 +190,11 @@ func (s *packSyncer) SelectRuntime(builder *Builder) (*semver.Version, error) {
 	return runtime.SemVer()
 }
 
+// DependencySource returns the source of application versions available in the package service
+func (s *packSyncer) DependencySource() resolver.Source {
+	return resolver.NewPackageSource(s.pack, s.repo)
+}
+
 // Sync pulls dependencies from the package/app service not available locally
 func (s *packSyncer) Sync(builder *Builder, runtimeVersion *semver.Version) error {
 	cacheApps, err := builder.Env.AppServiceLocal(localenv.AppConfig{})
@@ -29,23 +29,39 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// Fix takes a list of failed probes and attempts to fix some of them
-func Fix(ctx context.Context, probes []*agentpb.Probe, progress utils.Progress) (fixed, unfixed []*agentpb.Probe) {
+// Config defines the auto-fix configuration
+type Config struct {
+	// Progress is used to report information about auto-fixed problems
+	utils.Progress
+	// DryRun when set only prints the actions that would be taken
+	// to fix the problems without executing them
+	DryRun bool
+}
+
+// Fix takes a list of failed probes and attempts to fix some of them.
+// In dry-run mode, the probes are reported unfixed
+func Fix(ctx context.Context, probes []*agentpb.Probe, config Config) (fixed, unfixed []*agentpb.Probe) {
+	if config.Progress == nil {
+		config.Progress = utils.NewNopProgress()
+	}
 	// reorder the probes so "kernel module" ones go before "sysctl parameter"
 	// ones because some kernel parameters cannot be set unless a certain
 	// module is loaded, so they have to be fixed in order
-	sort.Slice(probes, func(i, j int) bool {
-		return probes[i].Checker == monitoring.KernelModuleCheckerID
+	sort.SliceStable(probes, func(i, j int) bool {
+		return fixOrder(probes[i].Checker) < fixOrder(probes[j].Checker)
 	})
+	fixer := &fixer{Config: config}
 	for _, probe := range probes {
 		// we should only have gotten failed probes here but in case we got
 		// something else, skip it
 		if probe.Status != agentpb.Probe_Failed {
 			continue
 		}
-		if err := fixProbe(ctx, probe, progress); err != nil {
+		if err := fixer.fixProbe(ctx, probe); err != nil {
 			logrus.Debugf("Failed to auto-fix probe %#v: %v", *probe, err)
 			unfixed = append(unfixed, probe)
+		} else if config.DryRun {
+			unfixed = append(unfixed, probe)
 		} else {
 			fixed = append(fixed, probe)
 		}
@@ -59,10 +75,9 @@ func GetFixable(probes []*agentpb.Probe) (failed, fixable []*agentpb.Probe) {
 		// we should only have gotten failed probes here but in case we got
 		// something else, skip it
 		if probe.Status == agentpb.Probe_Failed {
-			switch probe.Checker {
-			case monitoring.KernelModuleCheckerID, monitoring.IPForwardCheckerID, monitoring.NetfilterCheckerID, monitoring.MountsCheckerID:
+			if IsFixable(probe.Checker) {
 				fixable = append(fixable, probe)
-			default:
+			} else {
 				failed = append(failed, probe)
 			}
 		}
@@ -70,8 +85,33 @@ func GetFixable(probes []*agentpb.Probe) (failed, fixable []*agentpb.Probe) {
 	return failed, fixable
 }
 
+// IsFixable returns true if the failed probes of the specified checker
+// can be attempted to auto-fix
+func IsFixable(checker string) bool {
+	switch checker {
+	case monitoring.KernelModuleCheckerID, monitoring.IPForwardCheckerID, monitoring.NetfilterCheckerID, monitoring.MountsCheckerID,
+		SwapCheckerID, TransparentHugepagesCheckerID, FirewallCheckerID:
+		return true
+	default:
+		return false
+	}
+}
+
+// fixOrder returns the relative order in which the probes of the specified
+// checker are fixed
+func fixOrder(checker string) int {
+	switch checker {
+	case monitoring.KernelModuleCheckerID:
+		return 0
+	case monitoring.IPForwardCheckerID, monitoring.NetfilterCheckerID, monitoring.MountsCheckerID:
+		return 1
+	default:
+		return 2
+	}
+}
+
 // fixProbe attempts to fix the provided failed probe
-func fixProbe(ctx context.Context, probe *agentpb.Probe, progress utils.Progress) error {
+func (r *fixer) fixProbe(ctx context.Context, probe *agentpb.Probe) error {
 	switch probe.Checker {
 	case monitoring.KernelModuleCheckerID:
 		var data monitoring.KernelModuleCheckerData
@@ -81,7 +121,7 @@ func fixProbe(ctx context.Context, probe *agentpb.Probe, progress utils.Progress
 		if data.Module.Name == "" {
 			return trace.BadParameter("empty probe data: %#v", data)
 		}
-		if err := enableKernelModule(ctx, data.Module.Name, data.Module.Names, progress); err != nil {
+		if err := r.enableKernelModule(ctx, data.Module.Name, data.Module.Names); err != nil {
 			return trace.Wrap(err)
 		}
 	case monitoring.IPForwardCheckerID, monitoring.NetfilterCheckerID, monitoring.MountsCheckerID:
@@ -92,7 +132,32 @@ func fixProbe(ctx context.Context, probe *agentpb.Probe, progress utils.Progress
 		if data.ParameterName == "" || data.ParameterValue == "" {
 			return trace.BadParameter("empty probe data: %#v", data)
 		}
-		if err := setSysctlParameter(ctx, data.ParameterName, data.ParameterValue, progress); err != nil {
+		if probe.Checker == monitoring.NetfilterCheckerID {
+			// bridge netfilter parameters only exist with the module loaded
+			if err := r.enableKernelModule(ctx, "br_netfilter", []string{"bridge"}); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		if err := r.setSysctlParameter(ctx, data.ParameterName, data.ParameterValue); err != nil {
+			return trace.Wrap(err)
+		}
+	case SwapCheckerID:
+		if err := r.disableSwap(ctx); err != nil {
+			return trace.Wrap(err)
+		}
+	case TransparentHugepagesCheckerID:
+		if err := r.disableTransparentHugepages(ctx); err != nil {
+			return trace.Wrap(err)
+		}
+	case FirewallCheckerID:
+		var data FirewallCheckerData
+		if err := json.Unmarshal(probe.CheckerData, &data); err != nil {
+			return trace.Wrap(err)
+		}
+		if data.Firewall == "" || len(data.Ports) == 0 {
+			return trace.BadParameter("empty probe data: %#v", data)
+		}
+		if err := r.allowPorts(ctx, data.Firewall, data.Zone, data.Ports); err != nil {
 			return trace.Wrap(err)
 		}
 	default:
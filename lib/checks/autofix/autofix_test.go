@@ -0,0 +1,183 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autofix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+	. "gopkg.in/check.v1"
+)
+
+func TestAutofix(t *testing.T) { TestingT(t) }
+
+type AutofixSuite struct{}
+
+var _ = Suite(&AutofixSuite{})
+
+func (s *AutofixSuite) TestParsesSwaps(c *C) {
+	devices, err := parseSwaps(strings.NewReader(
+		`Filename				Type		Size	Used	Priority
+/dev/dm-1                               partition	2097148	0	-2
+/swapfile                               file		1048572	0	-3
+`))
+	c.Assert(err, IsNil)
+	c.Assert(devices, DeepEquals, []string{"/dev/dm-1", "/swapfile"})
+
+	devices, err = parseSwaps(strings.NewReader("Filename\tType\tSize\tUsed\tPriority\n"))
+	c.Assert(err, IsNil)
+	c.Assert(devices, HasLen, 0)
+}
+
+func (s *AutofixSuite) TestParsesSelectedMode(c *C) {
+	c.Assert(parseSelectedMode("[always] madvise never\n"), Equals, "always")
+	c.Assert(parseSelectedMode("always [madvise] never\n"), Equals, "madvise")
+}
+
+func (s *AutofixSuite) TestFindsPortsBlockedByFirewall(c *C) {
+	required := []PortRange{
+		{Protocol: "tcp", From: 6443, To: 6443},
+		{Protocol: "tcp", From: 30000, To: 32767},
+		{Protocol: "udp", From: 8472, To: 8472},
+	}
+
+	allowed, err := parseFirewalldPorts("6443/tcp 30000-32767/tcp\n")
+	c.Assert(err, IsNil)
+	c.Assert(missingPorts(required, allowed), DeepEquals, []PortRange{
+		{Protocol: "udp", From: 8472, To: 8472},
+	})
+
+	active, allowed := parseUFWStatus(strings.NewReader(`Status: active
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW       Anywhere
+30000:32767/tcp            ALLOW       Anywhere
+8472                       ALLOW       Anywhere
+`))
+	c.Assert(active, Equals, true)
+	c.Assert(missingPorts(required, allowed), DeepEquals, []PortRange{
+		{Protocol: "tcp", From: 6443, To: 6443},
+	})
+
+	active, _ = parseUFWStatus(strings.NewReader("Status: inactive\n"))
+	c.Assert(active, Equals, false)
+}
+
+func (s *AutofixSuite) TestParsesFirewalldZones(c *C) {
+	zones := parseFirewalldActiveZones(`docker
+  interfaces: docker0
+internal
+  sources: 10.0.0.0/8
+public
+  interfaces: eth0 eth1
+`)
+	c.Assert(zones, DeepEquals, []string{"docker", "public"})
+
+	info := parseFirewalldInfo(`public (active)
+  target: default
+  icmp-block-inversion: no
+  interfaces: eth0 eth1
+  sources:
+  services: ssh kube-apiserver
+  ports: 30000-32767/tcp
+  protocols:
+  rich rules:
+`)
+	c.Assert(info[firewalldTarget], Equals, "default")
+	c.Assert(info[firewalldServices], Equals, "ssh kube-apiserver")
+	c.Assert(info[firewalldPorts], Equals, "30000-32767/tcp")
+
+	service := parseFirewalldInfo(`kube-apiserver
+  ports: 6443/tcp 8472/udp
+  protocols:
+  source-ports:
+`)
+	ports, err := parseFirewalldPorts(service[firewalldPorts])
+	c.Assert(err, IsNil)
+	c.Assert(ports, DeepEquals, []PortRange{
+		{Protocol: "tcp", From: 6443, To: 6443},
+		{Protocol: "udp", From: 8472, To: 8472},
+	})
+}
+
+func (s *AutofixSuite) TestCommentsOutSwapInFstab(c *C) {
+	out, changed := commentOutSwap([]byte(`/dev/mapper/root /     xfs  defaults 0 0
+/dev/mapper/swap swap  swap defaults 0 0
+#/swapfile       none  swap sw       0 0
+`))
+	c.Assert(changed, Equals, true)
+	c.Assert(string(out), Equals, `/dev/mapper/root /     xfs  defaults 0 0
+#/dev/mapper/swap swap  swap defaults 0 0
+#/swapfile       none  swap sw       0 0
+`)
+}
+
+func (s *AutofixSuite) TestDryRunOnlyPrintsActions(c *C) {
+	progress := &recordingProgress{NopProgress: utils.NewNopProgress()}
+	probes := []*agentpb.Probe{
+		newProbe(c, FirewallCheckerID, FirewallCheckerData{
+			Firewall: FirewallFirewalld,
+			Zone:     "public",
+			Ports:    []PortRange{{Protocol: "tcp", From: 30000, To: 32767}},
+		}),
+		newProbe(c, monitoring.NetfilterCheckerID, monitoring.SysctlCheckerData{
+			ParameterName:  "net.bridge.bridge-nf-call-iptables",
+			ParameterValue: "1",
+		}),
+		newProbe(c, SwapCheckerID, SwapCheckerData{Devices: []string{"/swapfile"}}),
+	}
+	fixed, unfixed := Fix(context.TODO(), probes, Config{Progress: progress, DryRun: true})
+	c.Assert(fixed, HasLen, 0)
+	c.Assert(unfixed, HasLen, 3)
+	c.Assert(progress.messages, DeepEquals, []string{
+		"Would run: modprobe br_netfilter",
+		`Would add "br_netfilter" to /etc/modules-load.d/gravity.conf`,
+		"Would run: sysctl -w net.bridge.bridge-nf-call-iptables=1",
+		`Would add "net.bridge.bridge-nf-call-iptables=1" to /etc/sysctl.d/50-gravity.conf`,
+		"Would run: firewall-cmd --add-port=30000-32767/tcp --zone=public",
+		"Would run: firewall-cmd --add-port=30000-32767/tcp --zone=public --permanent",
+		"Would run: swapoff -a",
+		"Would comment out swap entries in /etc/fstab",
+	})
+}
+
+func newProbe(c *C, checker string, data interface{}) *agentpb.Probe {
+	bytes, err := json.Marshal(data)
+	c.Assert(err, IsNil)
+	return &agentpb.Probe{
+		Checker:     checker,
+		Status:      agentpb.Probe_Failed,
+		CheckerData: bytes,
+	}
+}
+
+type recordingProgress struct {
+	*utils.NopProgress
+	messages []string
+}
+
+func (r *recordingProgress) PrintInfo(message string, args ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(message, args...))
+}
@@ -0,0 +1,531 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autofix
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/satellite/agent/health"
+	pb "github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+	"github.com/gravitational/trace"
+)
+
+const (
+	// SwapCheckerID is the ID of the checker of enabled swap
+	SwapCheckerID = "swap"
+	// TransparentHugepagesCheckerID is the ID of the checker of transparent hugepages
+	TransparentHugepagesCheckerID = "transparent-hugepages"
+	// FirewallCheckerID is the ID of the checker of host firewall rules
+	FirewallCheckerID = "firewall"
+)
+
+const (
+	// FirewallFirewalld names the firewalld host firewall
+	FirewallFirewalld = "firewalld"
+	// FirewallUFW names the ufw host firewall
+	FirewallUFW = "ufw"
+)
+
+// SwapCheckerData gets attached to the swap check probes
+type SwapCheckerData struct {
+	// Devices lists the active swap devices
+	Devices []string `json:"devices"`
+}
+
+// TransparentHugepagesCheckerData gets attached to the transparent hugepages check probes
+type TransparentHugepagesCheckerData struct {
+	// Mode is the current transparent hugepages mode
+	Mode string `json:"mode"`
+}
+
+// FirewallCheckerData gets attached to the host firewall check probes
+type FirewallCheckerData struct {
+	// Firewall names the active host firewall: firewalld or ufw
+	Firewall string `json:"firewall"`
+	// Zone is the firewalld zone that does not allow the ports.
+	// Empty for ufw
+	Zone string `json:"zone,omitempty"`
+	// Ports lists the required port ranges the firewall does not allow
+	Ports []PortRange `json:"ports"`
+}
+
+// PortRange describes a range of ports
+type PortRange struct {
+	// Protocol is the port protocol: tcp or udp
+	Protocol string `json:"protocol"`
+	// From is the first port in the range
+	From uint64 `json:"from"`
+	// To is the last port in the range
+	To uint64 `json:"to"`
+}
+
+// String returns the range in the firewalld notation, e.g. 30000-32767/tcp
+func (r PortRange) String() string {
+	if r.From == r.To {
+		return fmt.Sprintf("%v/%v", r.From, r.Protocol)
+	}
+	return fmt.Sprintf("%v-%v/%v", r.From, r.To, r.Protocol)
+}
+
+// NewSwapChecker returns a checker that verifies that swap is disabled.
+// The failed probes are reported with the specified severity
+func NewSwapChecker(severity pb.Probe_Severity) health.Checker {
+	return &swapChecker{severity: severity}
+}
+
+type swapChecker struct {
+	severity pb.Probe_Severity
+}
+
+// Name returns name of checker
+func (c *swapChecker) Name() string {
+	return SwapCheckerID
+}
+
+// Check verifies that there are no active swap devices
+func (c *swapChecker) Check(ctx context.Context, reporter health.Reporter) {
+	data, err := ioutil.ReadFile(defaults.SwapsPath)
+	if err != nil {
+		if !trace.IsNotFound(trace.ConvertSystemError(err)) {
+			reporter.Add(monitoring.NewProbeFromErr(SwapCheckerID,
+				"failed to query swap devices", trace.ConvertSystemError(err)))
+		}
+		return
+	}
+	devices, err := parseSwaps(bytes.NewReader(data))
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(SwapCheckerID,
+			"failed to query swap devices", err))
+		return
+	}
+	if len(devices) == 0 {
+		reporter.Add(&pb.Probe{Checker: SwapCheckerID, Status: pb.Probe_Running})
+		return
+	}
+	reporter.Add(newFailedProbe(SwapCheckerID, c.severity,
+		"swap should be disabled as kubelet does not support running with swap enabled",
+		fmt.Sprintf("swap is enabled on %v", strings.Join(devices, ", ")),
+		SwapCheckerData{Devices: devices}))
+}
+
+// NewTransparentHugepagesChecker returns a checker that verifies that
+// transparent hugepages are not enabled system-wide.
+// The failed probes are reported with the specified severity
+func NewTransparentHugepagesChecker(severity pb.Probe_Severity) health.Checker {
+	return &transparentHugepagesChecker{severity: severity}
+}
+
+type transparentHugepagesChecker struct {
+	severity pb.Probe_Severity
+}
+
+// Name returns name of checker
+func (c *transparentHugepagesChecker) Name() string {
+	return TransparentHugepagesCheckerID
+}
+
+// Check verifies that transparent hugepages are not set to always
+func (c *transparentHugepagesChecker) Check(ctx context.Context, reporter health.Reporter) {
+	data, err := ioutil.ReadFile(defaults.TransparentHugepagesPath)
+	if err != nil {
+		if !trace.IsNotFound(trace.ConvertSystemError(err)) {
+			reporter.Add(monitoring.NewProbeFromErr(TransparentHugepagesCheckerID,
+				"failed to query transparent hugepages mode", trace.ConvertSystemError(err)))
+		}
+		return
+	}
+	mode := parseSelectedMode(string(data))
+	if mode != transparentHugepagesAlways {
+		reporter.Add(&pb.Probe{Checker: TransparentHugepagesCheckerID, Status: pb.Probe_Running})
+		return
+	}
+	reporter.Add(newFailedProbe(TransparentHugepagesCheckerID, c.severity,
+		"transparent hugepages should be disabled as they cause latency spikes in etcd and databases",
+		fmt.Sprintf("transparent hugepages are set to %v", mode),
+		TransparentHugepagesCheckerData{Mode: mode}))
+}
+
+// NewFirewallChecker returns a checker that verifies that an active host
+// firewall (firewalld or ufw) allows the specified ports.
+// The failed probes are reported with the specified severity
+func NewFirewallChecker(severity pb.Probe_Severity, ports ...monitoring.PortRange) health.Checker {
+	var required []PortRange
+	for _, port := range ports {
+		// ports restricted to a local listen address do not need to be reachable
+		if port.ListenAddr != "" {
+			continue
+		}
+		required = append(required, PortRange{Protocol: port.Protocol, From: port.From, To: port.To})
+	}
+	return &firewallChecker{severity: severity, ports: required}
+}
+
+type firewallChecker struct {
+	severity pb.Probe_Severity
+	ports    []PortRange
+}
+
+// Name returns name of checker
+func (c *firewallChecker) Name() string {
+	return FirewallCheckerID
+}
+
+// Check verifies that the active host firewall does not block the required ports
+// in any of its zones
+func (c *firewallChecker) Check(ctx context.Context, reporter health.Reporter) {
+	firewall, zones, err := queryFirewall(ctx)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(FirewallCheckerID,
+			"failed to query host firewall rules", err))
+		return
+	}
+	var failed bool
+	for _, zone := range zones {
+		if zone.acceptAll {
+			continue
+		}
+		missing := missingPorts(c.ports, zone.allowed)
+		if len(missing) == 0 {
+			continue
+		}
+		failed = true
+		ports := make([]string, 0, len(missing))
+		for _, port := range missing {
+			ports = append(ports, port.String())
+		}
+		name := firewall
+		if zone.name != "" {
+			name = fmt.Sprintf("%v zone %v", firewall, zone.name)
+		}
+		reporter.Add(newFailedProbe(FirewallCheckerID, c.severity,
+			fmt.Sprintf("%v should allow the ports required by the cluster", name),
+			fmt.Sprintf("%v blocks %v", name, strings.Join(ports, ", ")),
+			FirewallCheckerData{Firewall: firewall, Zone: zone.name, Ports: missing}))
+	}
+	if !failed {
+		reporter.Add(&pb.Probe{Checker: FirewallCheckerID, Status: pb.Probe_Running})
+	}
+}
+
+// firewallZone describes the traffic allowed by a host firewall zone
+type firewallZone struct {
+	// name is the firewalld zone name. Empty for ufw
+	name string
+	// acceptAll is set if the zone accepts all traffic
+	acceptAll bool
+	// allowed lists the allowed ports
+	allowed []PortRange
+}
+
+// queryFirewall returns the name of the active host firewall along
+// with the traffic allowed by each of its zones.
+// Returns an empty name if there is no active firewall
+func queryFirewall(ctx context.Context) (firewall string, zones []firewallZone, err error) {
+	if _, err := exec.LookPath("firewall-cmd"); err == nil {
+		out, err := utils.RunCommand(ctx, nil, "firewall-cmd", "--state")
+		if err == nil && strings.TrimSpace(string(out)) == "running" {
+			zones, err := queryFirewalldZones(ctx)
+			if err != nil {
+				return "", nil, trace.Wrap(err)
+			}
+			return FirewallFirewalld, zones, nil
+		}
+	}
+	if _, err := exec.LookPath("ufw"); err == nil {
+		out, err := utils.RunCommand(ctx, nil, "ufw", "status")
+		if err != nil {
+			return "", nil, trace.Wrap(err, "failed to query ufw status: %s", out)
+		}
+		active, allowed := parseUFWStatus(bytes.NewReader(out))
+		if active {
+			return FirewallUFW, []firewallZone{{allowed: allowed}}, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// queryFirewalldZones returns the firewalld zones the network interfaces
+// are bound to along with the ports allowed in each zone either directly
+// or by the enabled services
+func queryFirewalldZones(ctx context.Context) (zones []firewallZone, err error) {
+	out, err := utils.RunCommand(ctx, nil, "firewall-cmd", "--get-active-zones")
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to list firewalld zones: %s", out)
+	}
+	names := parseFirewalldActiveZones(string(out))
+	if len(names) == 0 {
+		// the interfaces not bound to any zone belong to the default zone
+		out, err := utils.RunCommand(ctx, nil, "firewall-cmd", "--get-default-zone")
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to query firewalld default zone: %s", out)
+		}
+		names = []string{strings.TrimSpace(string(out))}
+	}
+	servicePorts := make(map[string][]PortRange)
+	for _, name := range names {
+		out, err := utils.RunCommand(ctx, nil, "firewall-cmd", "--zone="+name, "--list-all")
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to query firewalld zone %v: %s", name, out)
+		}
+		info := parseFirewalldInfo(string(out))
+		zone := firewallZone{
+			name:      name,
+			acceptAll: info[firewalldTarget] == firewalldTargetAccept,
+		}
+		zone.allowed, err = parseFirewalldPorts(info[firewalldPorts])
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, service := range strings.Fields(info[firewalldServices]) {
+			ports, ok := servicePorts[service]
+			if !ok {
+				out, err := utils.RunCommand(ctx, nil, "firewall-cmd", "--info-service="+service)
+				if err != nil {
+					return nil, trace.Wrap(err, "failed to query firewalld service %v: %s", service, out)
+				}
+				ports, err = parseFirewalldPorts(parseFirewalldInfo(string(out))[firewalldPorts])
+				if err != nil {
+					return nil, trace.Wrap(err)
+				}
+				servicePorts[service] = ports
+			}
+			zone.allowed = append(zone.allowed, ports...)
+		}
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
+
+// parseSwaps returns the list of active swap devices from the contents
+// of /proc/swaps
+func parseSwaps(r io.Reader) (devices []string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// skip the header and empty lines
+		if len(fields) == 0 || fields[0] == "Filename" {
+			continue
+		}
+		devices = append(devices, fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return devices, nil
+}
+
+// parseSelectedMode returns the selected value from the sysfs
+// multiple choice file contents, e.g. "always [madvise] never"
+func parseSelectedMode(data string) string {
+	for _, field := range strings.Fields(data) {
+		if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+			return strings.Trim(field, "[]")
+		}
+	}
+	return strings.TrimSpace(data)
+}
+
+// parseFirewalldActiveZones returns the names of the zones with network interfaces
+// from the output of firewall-cmd --get-active-zones, e.g.:
+//
+//	public
+//	  interfaces: eth0
+//	trusted
+//	  sources: 10.0.0.0/8
+//
+// The zones only bound to sources are skipped as they do not apply
+// to the traffic between the cluster nodes unless configured explicitly
+func parseFirewalldActiveZones(data string) (zones []string) {
+	var zone string
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			zone = strings.TrimSpace(line)
+			continue
+		}
+		key, value := splitFirewalldInfoLine(line)
+		if zone != "" && key == firewalldInterfaces && value != "" {
+			zones = append(zones, zone)
+			zone = ""
+		}
+	}
+	return zones
+}
+
+// parseFirewalldInfo parses the key/value attributes from the output
+// of firewall-cmd --list-all or --info-service, e.g.:
+//
+//	public (active)
+//	  target: default
+//	  services: ssh dhcpv6-client
+//	  ports: 6443/tcp
+func parseFirewalldInfo(data string) map[string]string {
+	info := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			continue
+		}
+		key, value := splitFirewalldInfoLine(line)
+		if key != "" {
+			info[key] = value
+		}
+	}
+	return info
+}
+
+func splitFirewalldInfoLine(line string) (key, value string) {
+	parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+// parseFirewalldPorts parses the list of ports in the firewalld notation,
+// e.g. "6443/tcp 30000-32767/tcp"
+func parseFirewalldPorts(data string) (ports []PortRange, err error) {
+	for _, field := range strings.Fields(data) {
+		port, err := parsePortRange(field, "-")
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		ports = append(ports, *port)
+	}
+	return ports, nil
+}
+
+// parseUFWStatus parses the output of ufw status and returns whether
+// the firewall is active along with the list of allowed ports
+func parseUFWStatus(r io.Reader) (active bool, ports []PortRange) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "Status:") {
+			active = strings.TrimSpace(strings.TrimPrefix(line, "Status:")) == "active"
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[1] != "ALLOW" {
+			continue
+		}
+		// rules without the protocol apply to both tcp and udp
+		spec := fields[0]
+		if !strings.Contains(spec, "/") {
+			for _, protocol := range []string{"tcp", "udp"} {
+				if port, err := parsePortRange(spec+"/"+protocol, ":"); err == nil {
+					ports = append(ports, *port)
+				}
+			}
+			continue
+		}
+		if port, err := parsePortRange(spec, ":"); err == nil {
+			ports = append(ports, *port)
+		}
+	}
+	return active, ports
+}
+
+// parsePortRange parses a port range in the format from<sep>to/protocol
+// or port/protocol
+func parsePortRange(spec, sep string) (*PortRange, error) {
+	parts := strings.SplitN(spec, "/", 2)
+	if len(parts) != 2 {
+		return nil, trace.BadParameter("invalid port range %q", spec)
+	}
+	bounds := strings.SplitN(parts[0], sep, 2)
+	from, err := strconv.ParseUint(bounds[0], 10, 16)
+	if err != nil {
+		return nil, trace.BadParameter("invalid port range %q", spec)
+	}
+	to := from
+	if len(bounds) == 2 {
+		to, err = strconv.ParseUint(bounds[1], 10, 16)
+		if err != nil {
+			return nil, trace.BadParameter("invalid port range %q", spec)
+		}
+	}
+	return &PortRange{Protocol: parts[1], From: from, To: to}, nil
+}
+
+// missingPorts returns the required port ranges not fully covered
+// by any of the allowed ranges
+func missingPorts(required, allowed []PortRange) (missing []PortRange) {
+	for _, port := range required {
+		var covered bool
+		for _, rule := range allowed {
+			if rule.Protocol == port.Protocol && rule.From <= port.From && rule.To >= port.To {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			missing = append(missing, port)
+		}
+	}
+	return missing
+}
+
+func newFailedProbe(checker string, severity pb.Probe_Severity, detail, message string, data interface{}) *pb.Probe {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return monitoring.NewProbeFromErr(checker,
+			fmt.Sprintf("failed to marshal %v", data), trace.Wrap(err))
+	}
+	return &pb.Probe{
+		Checker:     checker,
+		Detail:      detail,
+		Error:       message,
+		Status:      pb.Probe_Failed,
+		Severity:    severity,
+		CheckerData: bytes,
+	}
+}
+
+const (
+	// transparentHugepagesAlways is the transparent hugepages mode
+	// that enables them for all memory regions
+	transparentHugepagesAlways = "always"
+	// transparentHugepagesNever is the transparent hugepages mode
+	// that disables them
+	transparentHugepagesNever = "never"
+
+	// firewalldTarget is the firewalld zone attribute with the zone target
+	firewalldTarget = "target"
+	// firewalldTargetAccept is the firewalld zone target that accepts all traffic
+	firewalldTargetAccept = "ACCEPT"
+	// firewalldServices is the firewalld zone attribute with the enabled services
+	firewalldServices = "services"
+	// firewalldPorts is the firewalld zone or service attribute with the ports
+	firewalldPorts = "ports"
+	// firewalldInterfaces is the firewalld zone attribute with the bound interfaces
+	firewalldInterfaces = "interfaces"
+)
@@ -17,8 +17,13 @@ limitations under the License.
 package autofix
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/utils"
@@ -26,26 +31,32 @@ import (
 	"github.com/gravitational/trace"
 )
 
+// fixer executes the actions fixing the failed probes.
+// In dry-run mode, the actions are only printed
+type fixer struct {
+	Config
+}
+
 // enableKernelModule loads the specified kernel module and adds it to the
 // list of modules loaded at boot
-func enableKernelModule(ctx context.Context, name string, altNames []string, progress utils.Progress) error {
-	name, err := modprobe(ctx, name, altNames, progress)
+func (r *fixer) enableKernelModule(ctx context.Context, name string, altNames []string) error {
+	name, err := r.modprobe(ctx, name, altNames)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	progress.PrintInfo("Auto-loaded kernel module: %v", name)
-	if err := utils.EnsureLineInFile(defaults.ModulesPath, name); err != nil && !trace.IsAlreadyExists(err) {
-		progress.PrintWarn(err, "Could not set up kernel module %v to load on boot", name)
+	r.printFixed("Auto-loaded kernel module: %v", name)
+	if err := r.ensureLineInFile(defaults.ModulesPath, name); err != nil {
+		r.PrintWarn(err, "Could not set up kernel module %v to load on boot", name)
 	}
 	return nil
 }
 
 // modprobe loads a kernel module by the provided name or, if that fails, by
 // trying provided alternative names
-func modprobe(ctx context.Context, name string, altNames []string, progress utils.Progress) (string, error) {
+func (r *fixer) modprobe(ctx context.Context, name string, altNames []string) (string, error) {
 	var errors []string
 	for _, n := range append([]string{name}, altNames...) {
-		out, err := utils.RunCommand(ctx, nil, "modprobe", n)
+		out, err := r.run(ctx, "modprobe", n)
 		if err == nil {
 			return n, nil
 		}
@@ -56,14 +67,154 @@ func modprobe(ctx context.Context, name string, altNames []string, progress util
 
 // setSysctlParameter sets the specified kernel parameter and makes sure it
 // persists across reboots
-func setSysctlParameter(ctx context.Context, name, value string, progress utils.Progress) error {
-	out, err := utils.RunCommand(ctx, nil, "sysctl", "-w", fmt.Sprintf("%v=%v", name, value))
+func (r *fixer) setSysctlParameter(ctx context.Context, name, value string) error {
+	out, err := r.run(ctx, "sysctl", "-w", fmt.Sprintf("%v=%v", name, value))
 	if err != nil {
 		return trace.Wrap(err, "failed to set kernel parameter %v=%v: %s", name, value, out)
 	}
-	progress.PrintInfo("Auto-set kernel parameter: %v=%v", name, value)
-	if err := utils.EnsureLineInFile(defaults.SysctlPath, fmt.Sprintf("%v=%v", name, value)); err != nil && !trace.IsAlreadyExists(err) {
-		progress.PrintWarn(err, "Could not set up kernel parameter %v=%v to persist across reboots", name, value)
+	r.printFixed("Auto-set kernel parameter: %v=%v", name, value)
+	if err := r.ensureLineInFile(defaults.SysctlPath, fmt.Sprintf("%v=%v", name, value)); err != nil {
+		r.PrintWarn(err, "Could not set up kernel parameter %v=%v to persist across reboots", name, value)
+	}
+	return nil
+}
+
+// disableSwap turns off all swap devices and comments out the swap
+// entries in the filesystem table so swap stays off after reboot
+func (r *fixer) disableSwap(ctx context.Context) error {
+	out, err := r.run(ctx, "swapoff", "-a")
+	if err != nil {
+		return trace.Wrap(err, "failed to disable swap: %s", out)
+	}
+	r.printFixed("Auto-disabled swap")
+	if r.DryRun {
+		r.PrintInfo("Would comment out swap entries in %v", defaults.FstabPath)
+		return nil
+	}
+	if err := disableSwapInFstab(defaults.FstabPath); err != nil {
+		r.PrintWarn(err, "Could not disable swap in %v", defaults.FstabPath)
+	}
+	return nil
+}
+
+// disableTransparentHugepages disables transparent hugepages and persists
+// the setting across reboots with systemd-tmpfiles
+func (r *fixer) disableTransparentHugepages(ctx context.Context) error {
+	if r.DryRun {
+		r.PrintInfo("Would write %q to %v", transparentHugepagesNever, defaults.TransparentHugepagesPath)
+	} else {
+		err := ioutil.WriteFile(defaults.TransparentHugepagesPath, []byte(transparentHugepagesNever), defaults.SharedReadMask)
+		if err != nil {
+			return trace.Wrap(trace.ConvertSystemError(err), "failed to disable transparent hugepages")
+		}
+	}
+	r.printFixed("Auto-disabled transparent hugepages")
+	line := fmt.Sprintf("w %v - - - - %v", defaults.TransparentHugepagesPath, transparentHugepagesNever)
+	if err := r.ensureLineInFile(defaults.TmpfilesPath, line); err != nil {
+		r.PrintWarn(err, "Could not set up transparent hugepages to stay disabled after reboot")
+	}
+	return nil
+}
+
+// allowPorts adds rules to the specified host firewall to allow the given ports.
+// For firewalld, the ports are allowed in the specified zone both in the runtime
+// and the permanent configuration so the firewall does not need to be reloaded
+func (r *fixer) allowPorts(ctx context.Context, firewall, zone string, ports []PortRange) error {
+	for _, port := range ports {
+		var commands [][]string
+		switch firewall {
+		case FirewallFirewalld:
+			args := []string{"firewall-cmd", fmt.Sprintf("--add-port=%v", port)}
+			if zone != "" {
+				args = append(args, fmt.Sprintf("--zone=%v", zone))
+			}
+			commands = [][]string{args, append(args, "--permanent")}
+		case FirewallUFW:
+			commands = [][]string{{"ufw", "allow", ufwPortRange(port)}}
+		default:
+			return trace.BadParameter("unsupported firewall %q", firewall)
+		}
+		for _, args := range commands {
+			out, err := r.run(ctx, args...)
+			if err != nil {
+				return trace.Wrap(err, "failed to allow %v in %v: %s", port, firewall, out)
+			}
+		}
+		r.printFixed("Auto-allowed %v in %v", port, firewall)
 	}
 	return nil
 }
+
+// run executes the specified command or only prints it in dry-run mode
+func (r *fixer) run(ctx context.Context, args ...string) ([]byte, error) {
+	if r.DryRun {
+		r.PrintInfo("Would run: %v", strings.Join(args, " "))
+		return nil, nil
+	}
+	return utils.RunCommand(ctx, nil, args...)
+}
+
+// ensureLineInFile adds the line to the specified file unless it is already
+// there or only prints the action in dry-run mode
+func (r *fixer) ensureLineInFile(path, line string) error {
+	if r.DryRun {
+		r.PrintInfo("Would add %q to %v", line, path)
+		return nil
+	}
+	err := utils.EnsureLineInFile(path, line)
+	if err != nil && !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// printFixed reports the fixed problem unless in dry-run mode
+func (r *fixer) printFixed(message string, args ...interface{}) {
+	if !r.DryRun {
+		r.PrintInfo(message, args...)
+	}
+}
+
+// disableSwapInFstab comments out the swap entries in the filesystem
+// table at the specified path
+func disableSwapInFstab(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	out, changed := commentOutSwap(data)
+	if !changed {
+		return nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.ConvertSystemError(ioutil.WriteFile(path, out, fi.Mode()))
+}
+
+// commentOutSwap comments out the swap entries in the specified
+// filesystem table contents
+func commentOutSwap(data []byte) (out []byte, changed bool) {
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && !strings.HasPrefix(fields[0], "#") && fields[2] == "swap" {
+			line = "#" + line
+			changed = true
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), changed
+}
+
+// ufwPortRange formats the port range in the ufw notation, e.g. 30000:32767/tcp
+func ufwPortRange(port PortRange) string {
+	if port.From == port.To {
+		return fmt.Sprintf("%v/%v", port.From, port.Protocol)
+	}
+	return fmt.Sprintf("%v:%v/%v", port.From, port.To, port.Protocol)
+}
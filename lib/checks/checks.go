@@ -72,34 +72,44 @@ func ValidateManifest(
 	dockerConfig storage.DockerConfig,
 	stateDir string,
 ) (failedProbes []*agentpb.Probe, err error) {
+	probes, err := CheckManifest(manifest, profile, dockerConfig, stateDir)
+	return probes.GetFailed(), trace.Wrap(err)
+}
+
+// CheckManifest verifies the specified manifest against the host environment.
+// Returns list of all executed health probes.
+func CheckManifest(
+	manifest schema.Manifest,
+	profile schema.NodeProfile,
+	dockerConfig storage.DockerConfig,
+	stateDir string,
+) (probes health.Probes, err error) {
 	var errors []error
-	failed, err := schema.ValidateRequirements(profile.Requirements, stateDir)
+	executed, err := schema.CheckRequirements(profile.Requirements, stateDir)
 	if err != nil {
 		errors = append(errors, trace.Wrap(err,
 			"error validating profile requirements, see syslog for details"))
 	}
-	failedProbes = append(failedProbes, failed...)
+	probes = append(probes, executed...)
 
 	dockerSchema := schema.Docker{StorageDriver: dockerConfig.StorageDriver}
-	failed, err = schema.ValidateDocker(dockerSchema, stateDir)
+	executed, err = schema.CheckDocker(dockerSchema, stateDir)
 	if err != nil {
 		errors = append(errors, trace.Wrap(err,
 			"error validating docker requirements, see syslog for details"))
 	}
-	failedProbes = append(failedProbes, failed...)
+	probes = append(probes, executed...)
 
-	failedProbes = append(failedProbes, schema.ValidateKubelet(profile, manifest)...)
-	return failedProbes, trace.NewAggregate(errors...)
+	probes = append(probes, schema.CheckKubelet(profile, manifest)...)
+	return probes, trace.NewAggregate(errors...)
 }
 
 // RunBasicChecks executes a set of additional health checks.
 // Returns list of failed health probes.
+// The probes that failed with warning severity are not returned
 func RunBasicChecks(ctx context.Context, options *validationpb.ValidateOptions) (failed []*agentpb.Probe) {
-	var reporter health.Probes
-	basicCheckers(options).Check(ctx, &reporter)
-
-	for _, p := range reporter {
-		if p.Status == agentpb.Probe_Failed {
+	for _, p := range runBasicChecks(ctx, options, false) {
+		if p.Status == agentpb.Probe_Failed && p.Severity != agentpb.Probe_Warning {
 			failed = append(failed, p)
 		}
 	}
@@ -107,6 +117,15 @@ func RunBasicChecks(ctx context.Context, options *validationpb.ValidateOptions)
 	return failed
 }
 
+// runBasicChecks executes a set of additional health checks.
+// Returns list of all executed health probes.
+// autoFix specifies whether the auto-fix mode has been requested in which case
+// the problems that can be auto-fixed are reported as critical
+func runBasicChecks(ctx context.Context, options *validationpb.ValidateOptions, autoFix bool) (probes health.Probes) {
+	basicCheckers(options, autoFix).Check(ctx, &probes)
+	return probes
+}
+
 // LocalChecksRequest describes a request to run local pre-flight checks
 type LocalChecksRequest struct {
 	// Context is used for canceling operation
@@ -121,6 +140,13 @@ type LocalChecksRequest struct {
 	Docker storage.DockerConfig
 	// AutoFix when set to true attempts to fix some common problems
 	AutoFix bool
+	// DryRun when set together with AutoFix only prints the actions
+	// that would be taken to fix the problems
+	DryRun bool
+	// ReportPath is the optional path to the file to write the report
+	// of the executed probes to. The report is written in JUnit XML format
+	// if the file has .xml extension and in JSON format otherwise
+	ReportPath string
 	// Progress is used to report information about auto-fixed problems
 	utils.Progress
 }
@@ -141,12 +167,17 @@ func (r *LocalChecksRequest) CheckAndSetDefaults() error {
 
 // LocalChecksResult describes the outcome of local checks execution
 type LocalChecksResult struct {
+	// Probes is a list of all executed probes
+	Probes []*agentpb.Probe
 	// Failed is a list of failed probes
 	Failed []*agentpb.Probe
 	// Fixed is a list of probes that failed but have been auto-fixed
 	Fixed []*agentpb.Probe
 	// Fixable is a list of probes that can be attempted to auto-fix
 	Fixable []*agentpb.Probe
+	// Warnings is a list of probes that failed with warning severity.
+	// Warnings do not fail the checks
+	Warnings []*agentpb.Probe
 }
 
 // GetFailed returns a list of all failed probes
@@ -178,32 +209,53 @@ func ValidateLocal(req LocalChecksRequest) (*LocalChecksResult, error) {
 
 	dockerConfig := DockerConfigFromSchemaValue(req.Manifest.SystemDocker())
 	OverrideDockerConfig(&dockerConfig, req.Docker)
-	failedProbes, err := ValidateManifest(req.Manifest, *profile, dockerConfig, stateDir)
+	probes, err := CheckManifest(req.Manifest, *profile, dockerConfig, stateDir)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	failedProbes = append(failedProbes, RunBasicChecks(req.Context, req.Options)...)
+	probes = append(probes, runBasicChecks(req.Context, req.Options, req.AutoFix)...)
+	failedProbes, warnings := splitWarnings(probes.GetFailed())
 	if len(failedProbes) == 0 {
-		return &LocalChecksResult{}, nil
+		return &LocalChecksResult{Probes: probes, Warnings: warnings}, nil
 	}
 
-	if !req.AutoFix {
+	if !req.AutoFix || req.DryRun {
 		failed, fixable := autofix.GetFixable(failedProbes)
+		if req.AutoFix {
+			autofix.Fix(req.Context, fixable, autofix.Config{Progress: req.Progress, DryRun: true})
+		}
 		return &LocalChecksResult{
-			Failed:  failed,
-			Fixable: fixable,
+			Probes:   probes,
+			Failed:   failed,
+			Fixable:  fixable,
+			Warnings: warnings,
 		}, nil
 	}
 
 	// try to auto-fix some of the issues
-	fixed, unfixed := autofix.Fix(req.Context, failedProbes, req.Progress)
+	fixed, unfixed := autofix.Fix(req.Context, failedProbes, autofix.Config{Progress: req.Progress})
 	return &LocalChecksResult{
-		Failed: unfixed,
-		Fixed:  fixed,
+		Probes:   probes,
+		Failed:   unfixed,
+		Fixed:    fixed,
+		Warnings: warnings,
 	}, nil
 }
 
+// splitWarnings splits the failed probes into the critical failures
+// and the probes that failed with warning severity
+func splitWarnings(probes []*agentpb.Probe) (failed, warnings []*agentpb.Probe) {
+	for _, probe := range probes {
+		if probe.Severity == agentpb.Probe_Warning {
+			warnings = append(warnings, probe)
+		} else {
+			failed = append(failed, probe)
+		}
+	}
+	return failed, warnings
+}
+
 // RunLocalChecks performs all preflight checks for an application that can
 // be run locally on the node
 func RunLocalChecks(req LocalChecksRequest) error {
@@ -215,13 +267,63 @@ func RunLocalChecks(req LocalChecksRequest) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if req.ReportPath != "" {
+		if err := writeReport(req.ReportPath, *result); err != nil {
+			log.Warnf("Failed to write pre-flight checks report: %v.", trace.DebugReport(err))
+		}
+	}
+	if len(result.Warnings) != 0 {
+		req.PrintWarn(nil, "The following pre-flight checks reported warnings, "+
+			"provide --autofix flag to let gravity fix them:\n%v",
+			FormatFailedChecks(result.Warnings))
+	}
 	if len(result.GetFailed()) != 0 {
-		return trace.BadParameter(fmt.Sprintf("The following pre-flight checks failed:\n%v",
-			FormatFailedChecks(result.GetFailed())))
+		var hint string
+		if len(result.Fixable) != 0 {
+			hint = "\nSome of the failed checks can be fixed automatically with --autofix flag."
+		}
+		return trace.BadParameter(fmt.Sprintf("The following pre-flight checks failed:\n%v%v",
+			FormatFailedChecks(result.GetFailed()), hint))
 	}
 	return nil
 }
 
+// RequirementsFromManifest returns the requirements for each node profile
+// in the specified manifest
+func RequirementsFromManifest(manifest schema.Manifest) (map[string]Requirements, error) {
+	result := make(map[string]Requirements)
+	for i, profile := range manifest.NodeProfiles {
+		tcp, udp, err := PortsForProfile(profile)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		maxLatency, err := profile.Requirements.Network.GetMaxLatency()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		maxJitter, err := profile.Requirements.Network.GetMaxJitter()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		req := Requirements{
+			CPU:     &manifest.NodeProfiles[i].Requirements.CPU,
+			RAM:     &manifest.NodeProfiles[i].Requirements.RAM,
+			OS:      profile.Requirements.OS,
+			Volumes: profile.Requirements.Volumes,
+			Network: Network{
+				MinTransferRate: profile.Requirements.Network.MinTransferRate,
+				Ports:           Ports{TCP: tcp, UDP: udp},
+				MaxLatency:      maxLatency,
+				MaxJitter:       maxJitter,
+				MaxPacketLoss:   profile.Requirements.Network.MaxPacketLoss,
+				MinMTU:          profile.Requirements.Network.MinMTU,
+			},
+		}
+		result[profile.Name] = req
+	}
+	return result, nil
+}
+
 // PortsForProfile parses ports ranges from the specified node profile
 func PortsForProfile(profile schema.NodeProfile) (tcp, udp []int, err error) {
 	for _, ports := range profile.Requirements.Network.Ports {
@@ -295,6 +397,9 @@ type checker struct {
 type Features struct {
 	// TestBandwidth specifies whether the bandwidth test is executed
 	TestBandwidth bool
+	// TestNetwork specifies whether the network latency, packet loss
+	// and path MTU test is executed
+	TestNetwork bool
 	// TestDockerDevice specifies if the docker device test should be executed.
 	// Docker device test is only applicable during install.
 	TestDockerDevice bool
@@ -323,6 +428,8 @@ type Remote interface {
 	CheckPorts(context.Context, PingPongGame) (PingPongGameResults, error)
 	// CheckBandwidth executes network bandwidth test
 	CheckBandwidth(context.Context, PingPongGame) (PingPongGameResults, error)
+	// CheckNetwork executes network latency, packet loss and path MTU test
+	CheckNetwork(context.Context, PingPongGame) (PingPongGameResults, error)
 	// Validate validates remote nodes by verifying manifest
 	// requirements and running local tests
 	Validate(ctx context.Context, addr string, manifest schema.Manifest, profileName string) ([]*agentpb.Probe, error)
@@ -349,6 +456,17 @@ type Network struct {
 	MinTransferRate utils.TransferRate
 	// Ports specifies requirements for ports to be available on server
 	Ports Ports
+	// MaxLatency is the maximum allowed average round-trip time
+	// to other servers. Not checked if unspecified
+	MaxLatency time.Duration
+	// MaxJitter is the maximum allowed average round-trip time variation.
+	// Not checked if unspecified
+	MaxJitter time.Duration
+	// MaxPacketLoss is the maximum allowed packet loss, in percent
+	MaxPacketLoss float64
+	// MinMTU is the minimum required path MTU to other servers.
+	// Not checked if unspecified
+	MinMTU int
 }
 
 // Ports describes port requirements for a specific profile
@@ -435,6 +553,13 @@ func (r *checker) Run(ctx context.Context) error {
 		}
 	}
 
+	if r.TestNetwork {
+		err = r.checkNetwork(ctx)
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+
 	return trace.NewAggregate(errors...)
 }
 
@@ -604,6 +729,28 @@ func (r *checker) checkBandwidth(ctx context.Context) error {
 	return nil
 }
 
+// checkNetwork measures latency, jitter, packet loss and path MTU between
+// every pair of servers and makes sure they satisfy the profiles
+func (r *checker) checkNetwork(ctx context.Context) error {
+	if len(r.servers) < 2 {
+		return nil
+	}
+
+	paths, err := RunNetworkTest(ctx, r.remote, r.servers)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var errors []error
+	for _, path := range paths {
+		requirements := r.requirements[path.From.Server.Role]
+		for _, problem := range path.Problems(requirements.Network) {
+			errors = append(errors, trace.BadParameter("%v", problem))
+		}
+	}
+	return trace.NewAggregate(errors...)
+}
+
 // collectTargets returns a list of targets (devices or existing filesystems)
 // for the disk performance test
 func (r *checker) collectTargets(ctx context.Context, server Server, requirements Requirements) ([]diskCheckTarget, error) {
@@ -864,7 +1011,13 @@ func checkSystemPackages(server Server, dockerConfig schema.Docker) error {
 	return nil
 }
 
-func basicCheckers(options *validationpb.ValidateOptions) health.Checker {
+func basicCheckers(options *validationpb.ValidateOptions, autoFix bool) health.Checker {
+	// the problems that can be auto-fixed are only critical
+	// if the auto-fix mode has been explicitly requested
+	severity := agentpb.Probe_Warning
+	if autoFix {
+		severity = agentpb.Probe_Critical
+	}
 	return monitoring.NewCompositeChecker(
 		"local",
 		[]health.Checker{
@@ -874,11 +1027,19 @@ func basicCheckers(options *validationpb.ValidateOptions) health.Checker {
 			monitoring.DefaultProcessChecker(),
 			defaultPortChecker(options),
 			monitoring.DefaultBootConfigParams(),
+			autofix.NewSwapChecker(severity),
+			autofix.NewTransparentHugepagesChecker(severity),
+			autofix.NewFirewallChecker(severity, defaultPortRanges(options)...),
 		},
 	)
 }
 
 func defaultPortChecker(options *validationpb.ValidateOptions) health.Checker {
+	return monitoring.NewPortChecker(defaultPortRanges(options)...)
+}
+
+// defaultPortRanges returns the port ranges required by the cluster
+func defaultPortRanges(options *validationpb.ValidateOptions) []monitoring.PortRange {
 	vxlanPort := uint64(defaults.VxlanPort)
 	if options != nil && options.VxlanPort != 0 {
 		vxlanPort = uint64(options.VxlanPort)
@@ -921,7 +1082,7 @@ func defaultPortChecker(options *validationpb.ValidateOptions) health.Checker {
 		)
 	}
 
-	return monitoring.NewPortChecker(portRanges...)
+	return portRanges
 }
 
 // constructPingPongRequest constructs a regular ping-pong game request
@@ -995,6 +1156,42 @@ func constructBandwidthRequest(servers []Server) (PingPongGame, error) {
 	return game, nil
 }
 
+// constructNetworkRequest constructs a ping-pong game request for the full-mesh
+// network latency, packet loss and path MTU test
+func constructNetworkRequest(servers []Server) (PingPongGame, error) {
+	game := make(PingPongGame, len(servers))
+	for _, server := range servers {
+		var remote []validationpb.Addr
+		for _, other := range servers {
+			if server.AdvertiseIP == other.AdvertiseIP {
+				continue
+			}
+			for _, network := range []string{"tcp", "udp"} {
+				remote = append(remote, validationpb.Addr{
+					Network: network,
+					Addr:    fmt.Sprintf("%v:%v", other.AdvertiseIP, defaults.NetworkTestPort),
+				})
+			}
+		}
+		var listen []validationpb.Addr
+		for _, network := range []string{"tcp", "udp"} {
+			listen = append(listen, validationpb.Addr{
+				Network: network,
+				Addr:    fmt.Sprintf("%v:%v", server.AdvertiseIP, defaults.NetworkTestPort),
+			})
+		}
+		game[server.AdvertiseIP] = PingPongRequest{
+			Duration: defaults.NetworkTestDuration,
+			Listen:   listen,
+			Ping:     remote,
+			Mode:     ModeNetwork,
+			Count:    defaults.NetworkTestProbeCount,
+			MaxMTU:   defaults.NetworkTestMaxMTU,
+		}
+	}
+	return game, nil
+}
+
 func findServer(servers []Server, addr string) (*Server, error) {
 	for _, server := range servers {
 		if server.AdvertiseIP == addr {
@@ -0,0 +1,198 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	pb "github.com/gravitational/gravity/lib/network/validation/proto"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NetworkPath describes the quality of the network path between two servers
+// as measured by the network test
+type NetworkPath struct {
+	// From is the server the path has been probed from
+	From Server
+	// To is the server the path has been probed to
+	To Server
+	// Protocol is the protocol used for probing: tcp or udp
+	Protocol string
+	// Sent is the number of probes sent
+	Sent int
+	// Received is the number of probes replied to
+	Received int
+	// Latency is the average round-trip time
+	Latency time.Duration
+	// Jitter is the average round-trip time variation
+	Jitter time.Duration
+	// MTU is the discovered path MTU
+	MTU int
+	// InterfaceMTU is the MTU of the network interface on the source server
+	InterfaceMTU int
+}
+
+// PacketLoss returns the ratio of lost probes as a percentage
+func (r NetworkPath) PacketLoss() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Sent-r.Received) * 100 / float64(r.Sent)
+}
+
+// OverlayMTU returns the maximum size of the overlay network packet
+// that fits into the path MTU after VXLAN encapsulation
+func (r NetworkPath) OverlayMTU() int {
+	if r.MTU == 0 {
+		return 0
+	}
+	return r.MTU - defaults.VxlanOverhead
+}
+
+// String returns a textual representation of this path
+func (r NetworkPath) String() string {
+	out := fmt.Sprintf("%v -> %v (%v): latency=%v jitter=%v loss=%.1f%%",
+		r.From.GetHostname(), r.To.GetHostname(), r.Protocol,
+		r.Latency, r.Jitter, r.PacketLoss())
+	if r.MTU != 0 {
+		out += fmt.Sprintf(" mtu=%v overlay-mtu=%v", r.MTU, r.OverlayMTU())
+	}
+	return out
+}
+
+// Problems returns the list of network requirements the path does not satisfy
+func (r NetworkPath) Problems(network Network) (problems []string) {
+	path := fmt.Sprintf("%v from %v to %v", r.Protocol, r.From, r.To)
+	if network.MaxLatency != 0 && r.Latency > network.MaxLatency {
+		problems = append(problems, fmt.Sprintf(
+			"%v network latency is %v which is higher than allowed %v",
+			path, r.Latency, network.MaxLatency))
+	}
+	if network.MaxJitter != 0 && r.Jitter > network.MaxJitter {
+		problems = append(problems, fmt.Sprintf(
+			"%v network jitter is %v which is higher than allowed %v",
+			path, r.Jitter, network.MaxJitter))
+	}
+	maxPacketLoss := network.MaxPacketLoss
+	if maxPacketLoss == 0 {
+		maxPacketLoss = defaults.NetworkTestMaxPacketLoss
+	}
+	if r.PacketLoss() > maxPacketLoss {
+		problems = append(problems, fmt.Sprintf(
+			"%v packet loss is %.1f%% which is higher than allowed %.1f%%",
+			path, r.PacketLoss(), maxPacketLoss))
+	}
+	if r.MTU == 0 {
+		return problems
+	}
+	if r.MTU < r.InterfaceMTU {
+		problems = append(problems, fmt.Sprintf(
+			"%v path MTU is %v which is lower than the network interface MTU %v: "+
+				"packets larger than %v bytes, including overlay network packets, "+
+				"will be dropped. Lower the interface MTU or fix the MTU on the network path",
+			path, r.MTU, r.InterfaceMTU, r.MTU))
+	}
+	if network.MinMTU != 0 && r.MTU < network.MinMTU {
+		problems = append(problems, fmt.Sprintf(
+			"%v path MTU is %v which is lower than required %v",
+			path, r.MTU, network.MinMTU))
+	}
+	return problems
+}
+
+// RunNetworkTest executes the full-mesh network test between the specified servers
+// and returns the measured network paths
+func RunNetworkTest(ctx context.Context, remote Remote, servers []Server) ([]NetworkPath, error) {
+	req, err := constructNetworkRequest(servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	log.Infof("Network test request: %v.", req)
+
+	resp, err := remote.CheckNetwork(ctx, req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	log.Infof("Network test response: %v.", resp)
+
+	if len(resp.Failures()) != 0 {
+		return nil, trace.BadParameter("%v", strings.Join(resp.Failures(), ", "))
+	}
+
+	paths := NetworkPaths(resp, servers)
+	for _, path := range paths {
+		log.Infof("Network path %v.", path)
+	}
+	return paths, nil
+}
+
+// NetworkPaths returns the network paths between the specified servers
+// measured by the network test
+func NetworkPaths(results PingPongGameResults, servers []Server) (paths []NetworkPath) {
+	for addr, result := range results {
+		ip, _ := utils.SplitHostPort(addr, "")
+		from, err := findServer(servers, ip)
+		if err != nil {
+			log.Warnf("Skipping network test results from unknown server %v.", addr)
+			continue
+		}
+		for _, probe := range result.NetworkResults {
+			ip, _ := utils.SplitHostPort(probe.Server.Addr, "")
+			to, err := findServer(servers, ip)
+			if err != nil {
+				log.Warnf("Skipping network test results for unknown server %v.", probe.Server.Addr)
+				continue
+			}
+			path := NetworkPath{
+				From:         *from,
+				To:           *to,
+				Protocol:     probe.Server.Network,
+				Sent:         int(probe.Sent),
+				Received:     int(probe.Received),
+				MTU:          int(probe.Mtu),
+				InterfaceMTU: int(probe.InterfaceMtu),
+			}
+			if probe.Latency != nil {
+				path.Latency, _ = pb.DurationFromProto(probe.Latency)
+			}
+			if probe.Jitter != nil {
+				path.Jitter, _ = pb.DurationFromProto(probe.Jitter)
+			}
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].From.AdvertiseIP != paths[j].From.AdvertiseIP {
+			return paths[i].From.AdvertiseIP < paths[j].From.AdvertiseIP
+		}
+		if paths[i].To.AdvertiseIP != paths[j].To.AdvertiseIP {
+			return paths[i].To.AdvertiseIP < paths[j].To.AdvertiseIP
+		}
+		return paths[i].Protocol < paths[j].Protocol
+	})
+	return paths
+}
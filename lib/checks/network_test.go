@@ -0,0 +1,101 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	pb "github.com/gravitational/gravity/lib/network/validation/proto"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gogo/protobuf/types"
+	. "gopkg.in/check.v1"
+)
+
+type NetworkSuite struct{}
+
+var _ = Suite(&NetworkSuite{})
+
+func (s *NetworkSuite) TestConstructsNetworkRequest(c *C) {
+	servers := []Server{newServer("node-1", "10.0.0.1"), newServer("node-2", "10.0.0.2")}
+	req, err := constructNetworkRequest(servers)
+	c.Assert(err, IsNil)
+	c.Assert(req, HasLen, 2)
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2"} {
+		c.Assert(req[addr].Mode, Equals, ModeNetwork)
+		c.Assert(req[addr].Listen, HasLen, 2)
+		c.Assert(req[addr].Ping, HasLen, 2)
+		c.Assert(req[addr].Check(), IsNil)
+	}
+	c.Assert(req["10.0.0.1"].Ping[0].Addr, Equals, "10.0.0.2:4243")
+	c.Assert(req["10.0.0.2"].Ping[0].Addr, Equals, "10.0.0.1:4243")
+}
+
+func (s *NetworkSuite) TestComputesNetworkPaths(c *C) {
+	servers := []Server{newServer("node-1", "10.0.0.1"), newServer("node-2", "10.0.0.2")}
+	results := PingPongGameResults{
+		"10.0.0.1:3012": PingPongResult{
+			NetworkResults: []pb.NetworkResult{
+				{
+					Server:       &pb.Addr{Addr: "10.0.0.2:4243", Network: "udp"},
+					Sent:         10,
+					Received:     8,
+					Latency:      types.DurationProto(2 * time.Millisecond),
+					Jitter:       types.DurationProto(time.Millisecond),
+					Mtu:          1450,
+					InterfaceMtu: 1500,
+				},
+				{
+					Server:   &pb.Addr{Addr: "10.0.0.2:4243", Network: "tcp"},
+					Sent:     10,
+					Received: 10,
+					Latency:  types.DurationProto(time.Millisecond),
+				},
+			},
+		},
+	}
+	paths := NetworkPaths(results, servers)
+	c.Assert(paths, HasLen, 2)
+	c.Assert(paths[0].Protocol, Equals, "tcp")
+	c.Assert(paths[0].PacketLoss(), Equals, 0.0)
+	c.Assert(paths[0].Problems(Network{}), HasLen, 0)
+
+	udp := paths[1]
+	c.Assert(udp.From.AdvertiseIP, Equals, "10.0.0.1")
+	c.Assert(udp.To.AdvertiseIP, Equals, "10.0.0.2")
+	c.Assert(udp.Latency, Equals, 2*time.Millisecond)
+	c.Assert(udp.PacketLoss(), Equals, 20.0)
+	c.Assert(udp.OverlayMTU(), Equals, 1450-defaults.VxlanOverhead)
+	// packet loss exceeds the default and path MTU is below the interface MTU
+	c.Assert(udp.Problems(Network{}), HasLen, 2)
+	c.Assert(udp.Problems(Network{
+		MaxLatency:    time.Millisecond,
+		MaxJitter:     time.Millisecond,
+		MaxPacketLoss: 25,
+		MinMTU:        1500,
+	}), HasLen, 3)
+}
+
+func newServer(hostname, addr string) Server {
+	return Server{
+		Server: storage.Server{AdvertiseIP: addr, Hostname: hostname},
+		ServerInfo: ServerInfo{
+			System: storage.NewSystemInfo(storage.SystemSpecV2{Hostname: hostname}),
+		},
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gravitational/gravity/lib/defaults"
 	pb "github.com/gravitational/gravity/lib/network/validation/proto"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -34,8 +35,14 @@ type PingPongRequest struct {
 	Ping []pb.Addr `json:"ping"`
 	// Duration is the duration of the game
 	Duration time.Duration `json:"duration"`
-	// Mode is the game mode: pingpong or bandwidth
+	// Mode is the game mode: pingpong, bandwidth or network
 	Mode string `json:"mode"`
+	// Count is the number of probes to send to each remote server.
+	// Only used in network mode
+	Count int `json:"count,omitempty"`
+	// MaxMTU is the upper bound for the path MTU discovery.
+	// Only used in network mode
+	MaxMTU int `json:"max_mtu,omitempty"`
 }
 
 const (
@@ -44,11 +51,14 @@ const (
 	ModePingPong = "pingpong"
 	// ModeBandwidth is the mode for testing bandwidth between servers
 	ModeBandwidth = "bandwidth"
+	// ModeNetwork is the mode for measuring latency, packet loss
+	// and path MTU between servers
+	ModeNetwork = "network"
 )
 
 // Checks makes sure the request is correct
 func (r PingPongRequest) Check() error {
-	if !utils.StringInSlice([]string{ModePingPong, ModeBandwidth}, r.Mode) {
+	if !utils.StringInSlice([]string{ModePingPong, ModeBandwidth, ModeNetwork}, r.Mode) {
 		return trace.BadParameter("unsupported mode %q", r.Mode)
 	}
 	if len(r.Listen) < 1 {
@@ -57,7 +67,7 @@ func (r PingPongRequest) Check() error {
 	if len(r.Ping) < 1 {
 		return trace.BadParameter("at least one ping address should be provided: %v", r)
 	}
-	if r.Mode == ModePingPong {
+	if r.Mode == ModePingPong || r.Mode == ModeNetwork {
 		for _, server := range append(r.Listen, r.Ping...) {
 			if !utils.StringInSlice([]string{"tcp", "udp"}, server.Network) {
 				return trace.BadParameter("unsupported protocol %v, supported are: tcp, udp",
@@ -68,6 +78,16 @@ func (r PingPongRequest) Check() error {
 	return nil
 }
 
+// Timeout returns the time to wait for the results of the game.
+// The network test listeners are kept up while they are being probed
+// for up to twice the duration of the game
+func (r PingPongRequest) Timeout() time.Duration {
+	if r.Mode == ModeNetwork {
+		return 2*r.Duration + 2*defaults.NetworkTestIdleTimeout
+	}
+	return 2 * r.Duration
+}
+
 // PortsProto converts this request to protobuf format
 func (r PingPongRequest) PortsProto() *pb.CheckPortsRequest {
 	var listens []*pb.Addr
@@ -99,6 +119,25 @@ func (r PingPongRequest) BandwidthProto() *pb.CheckBandwidthRequest {
 	}
 }
 
+// NetworkProto converts this request to protobuf format
+func (r PingPongRequest) NetworkProto() *pb.CheckNetworkRequest {
+	var listens []*pb.Addr
+	for i := range r.Listen {
+		listens = append(listens, &r.Listen[i])
+	}
+	var pings []*pb.Addr
+	for i := range r.Ping {
+		pings = append(pings, &r.Ping[i])
+	}
+	return &pb.CheckNetworkRequest{
+		Listen:   listens,
+		Ping:     pings,
+		Duration: pb.DurationProto(r.Duration),
+		Count:    int32(r.Count),
+		MaxMtu:   int32(r.MaxMTU),
+	}
+}
+
 // ResultFromPortsProto converts protobuf response to PingPongResult
 func ResultFromPortsProto(resp *pb.CheckPortsResponse, err error) *PingPongResult {
 	result := &PingPongResult{}
@@ -125,6 +164,22 @@ func ResultFromBandwidthProto(resp *pb.CheckBandwidthResponse, err error) *PingP
 	return result
 }
 
+// ResultFromNetworkProto converts protobuf response to PingPongResult
+func ResultFromNetworkProto(resp *pb.CheckNetworkResponse, err error) *PingPongResult {
+	result := &PingPongResult{}
+	if err != nil {
+		result.Code = 1
+		result.Message = err.Error()
+	}
+	for _, listen := range resp.Listen {
+		result.ListenResults = append(result.ListenResults, *listen)
+	}
+	for _, ping := range resp.Ping {
+		result.NetworkResults = append(result.NetworkResults, *ping)
+	}
+	return result
+}
+
 // PingPongResult is a result of a ping-pong game
 type PingPongResult struct {
 	// Code means that the whole operation has succeded
@@ -140,6 +195,9 @@ type PingPongResult struct {
 	PingResults []pb.ServerResult `json:"ping_results"`
 	// BandwidthResult is the result of the bandwidth test
 	BandwidthResult uint64 `json:"bandwidth_result"`
+	// NetworkResults is the result of the network latency, packet loss
+	// and path MTU test for each remote server
+	NetworkResults []pb.NetworkResult `json:"network_results,omitempty"`
 }
 
 // FailureCount returns number of failures in the result
@@ -155,6 +213,11 @@ func (r PingPongResult) FailureCount() int {
 			count += 1
 		}
 	}
+	for _, n := range r.NetworkResults {
+		if n.Code != 0 {
+			count += 1
+		}
+	}
 	return count
 }
 
@@ -187,6 +250,13 @@ func (p *PingPongGameResults) Failures() []string {
 					addr, ping.Server.Addr, ping.Server.Network))
 			}
 		}
+		for _, probe := range result.NetworkResults {
+			if probe.Code != 0 {
+				out = append(out, fmt.Sprintf(
+					"server %v failed to probe %v:%v: %v",
+					addr, probe.Server.Addr, probe.Server.Network, probe.Error))
+			}
+		}
 	}
 	return out
 }
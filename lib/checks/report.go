@@ -0,0 +1,360 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/checks/autofix"
+	"github.com/gravitational/gravity/lib/constants"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+	"github.com/gravitational/trace"
+)
+
+// Report is a machine-readable report of the executed pre-flight checks
+type Report struct {
+	// Created is the time the report has been generated
+	Created time.Time `json:"created"`
+	// Results lists outcomes of individual probes
+	Results []ProbeResult `json:"results"`
+}
+
+// ProbeResult describes the outcome of a single pre-flight probe
+type ProbeResult struct {
+	// Check is the name of the check that executed the probe
+	Check string `json:"check"`
+	// Requirement describes what the probe has verified
+	Requirement string `json:"requirement"`
+	// Node is the name of the node the probe has been executed on
+	Node string `json:"node"`
+	// Status is the probe outcome: passed, failed, warning or fixed
+	Status string `json:"status"`
+	// Severity is the probe severity
+	Severity string `json:"severity,omitempty"`
+	// Observed describes the value observed by the probe
+	Observed string `json:"observed,omitempty"`
+	// Expected describes the value required by the probe
+	Expected string `json:"expected,omitempty"`
+	// Remediation provides guidance on how to fix the failed probe
+	Remediation string `json:"remediation,omitempty"`
+	// AutoFixable specifies whether the failed probe can be fixed with --autofix
+	AutoFixable bool `json:"autofixable"`
+}
+
+// IsFailed returns true if this result describes a failed probe
+func (r ProbeResult) IsFailed() bool {
+	return r.Status == ResultFailed
+}
+
+// IsWarning returns true if this result describes a probe
+// that failed with warning severity
+func (r ProbeResult) IsWarning() bool {
+	return r.Status == ResultWarning
+}
+
+// NewReport returns a report describing the outcome of the local checks
+// executed on the specified node
+func NewReport(node string, result LocalChecksResult) *Report {
+	report := &Report{Created: time.Now().UTC()}
+	report.Add(node, result)
+	return report
+}
+
+// Add adds the outcome of the local checks executed
+// on the specified node to the report
+func (r *Report) Add(node string, result LocalChecksResult) {
+	fixed := make(map[*agentpb.Probe]bool, len(result.Fixed))
+	for _, probe := range result.Fixed {
+		fixed[probe] = true
+	}
+	for _, probe := range result.Probes {
+		status := ResultPassed
+		switch {
+		case fixed[probe]:
+			status = ResultFixed
+		case probe.Status == agentpb.Probe_Failed && probe.Severity == agentpb.Probe_Warning:
+			status = ResultWarning
+		case probe.Status == agentpb.Probe_Failed:
+			status = ResultFailed
+		}
+		r.Results = append(r.Results, newResult(node, *probe, status))
+	}
+}
+
+// AddRemote adds the outcome of the checks executed on the specified
+// remote node to the report. Remote nodes only report the failed probes,
+// so a node without failures is recorded with a single passed result
+func (r *Report) AddRemote(node string, failed []*agentpb.Probe) {
+	if len(failed) == 0 {
+		r.Results = append(r.Results, newResult(node, agentpb.Probe{
+			Checker: remoteChecker,
+			Detail:  "node satisfies the application requirements",
+		}, ResultPassed))
+		return
+	}
+	r.Add(node, LocalChecksResult{Probes: failed, Failed: failed})
+}
+
+// Failed returns the results of the failed probes
+func (r Report) Failed() (failed []ProbeResult) {
+	for _, result := range r.Results {
+		if result.IsFailed() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Write outputs the report in the specified format to w.
+// Supported formats are JSON and JUnit XML
+func (r Report) Write(w io.Writer, format constants.Format) error {
+	switch format {
+	case constants.EncodingJSON:
+		return trace.Wrap(r.WriteJSON(w))
+	case constants.EncodingJUnit:
+		return trace.Wrap(r.WriteJUnit(w))
+	default:
+		return trace.BadParameter("unsupported report format %q, supported are: %v, %v",
+			format, constants.EncodingJSON, constants.EncodingJUnit)
+	}
+}
+
+// WriteJSON outputs the report in JSON format to w
+func (r Report) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return trace.ConvertSystemError(err)
+}
+
+// WriteJUnit outputs the report in JUnit XML format to w.
+// Each probe is reported as a test case named after the requirement
+// and classified by the node and the check
+func (r Report) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      "preflight",
+		Tests:     len(r.Results),
+		Failures:  len(r.Failed()),
+		Timestamp: r.Created.Format("2006-01-02T15:04:05"),
+	}
+	for _, result := range r.Results {
+		testCase := junitTestCase{
+			ClassName: fmt.Sprintf("%v.%v", result.Node, result.Check),
+			Name:      result.Requirement,
+		}
+		if result.IsFailed() {
+			testCase.Failure = &junitFailure{
+				Message:  result.Observed,
+				Type:     result.Severity,
+				Contents: result.details(),
+			}
+		} else {
+			testCase.SystemOut = result.details()
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	data, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = fmt.Fprintf(w, "%v%s\n", xml.Header, data)
+	return trace.ConvertSystemError(err)
+}
+
+// writeReport writes the report of the local checks to the file at the specified path.
+// The report is written in JUnit XML format if the file has .xml extension
+// and in JSON format otherwise
+func writeReport(path string, result LocalChecksResult) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	format := constants.EncodingJSON
+	if filepath.Ext(path) == ".xml" {
+		format = constants.EncodingJUnit
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	return trace.Wrap(NewReport(hostname, result).Write(f, format))
+}
+
+// details formats the result details for JUnit output
+func (r ProbeResult) details() string {
+	var lines []string
+	if r.Observed != "" {
+		lines = append(lines, fmt.Sprintf("Observed: %v", r.Observed))
+	}
+	if r.Expected != "" {
+		lines = append(lines, fmt.Sprintf("Expected: %v", r.Expected))
+	}
+	if r.Status == ResultFixed {
+		lines = append(lines, "Fixed automatically")
+	}
+	if (r.IsFailed() || r.IsWarning()) && r.Remediation != "" {
+		lines = append(lines, fmt.Sprintf("Remediation: %v", r.Remediation))
+	}
+	if (r.IsFailed() || r.IsWarning()) && r.AutoFixable {
+		lines = append(lines, "Can be fixed automatically with --autofix")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newResult(node string, probe agentpb.Probe, status string) ProbeResult {
+	result := ProbeResult{
+		Check:       probe.Checker,
+		Requirement: probe.Detail,
+		Node:        node,
+		Status:      status,
+		Expected:    expectedValue(probe),
+		Remediation: remediations[probe.Checker],
+		AutoFixable: autofix.IsFixable(probe.Checker),
+	}
+	if result.Requirement == "" {
+		result.Requirement = probe.Checker
+	}
+	if severity, err := probe.Severity.MarshalText(); err == nil {
+		result.Severity = string(severity)
+	}
+	if status != ResultPassed {
+		result.Observed = observedValue(probe)
+	}
+	return result
+}
+
+// expectedValue returns the value required by the probe
+// if the probe's checker provides it
+func expectedValue(probe agentpb.Probe) string {
+	if len(probe.CheckerData) == 0 {
+		return ""
+	}
+	switch probe.Checker {
+	case monitoring.KernelModuleCheckerID:
+		var data monitoring.KernelModuleCheckerData
+		if err := json.Unmarshal(probe.CheckerData, &data); err == nil && data.Module.Name != "" {
+			return fmt.Sprintf("kernel module %v loaded", data.Module.Name)
+		}
+	case monitoring.IPForwardCheckerID, monitoring.NetfilterCheckerID, monitoring.MountsCheckerID:
+		var data monitoring.SysctlCheckerData
+		if err := json.Unmarshal(probe.CheckerData, &data); err == nil && data.ParameterName != "" {
+			return fmt.Sprintf("%v = %v", data.ParameterName, data.ParameterValue)
+		}
+	case monitoring.DiskSpaceCheckerID:
+		var data monitoring.HighWatermarkCheckerData
+		if err := json.Unmarshal(probe.CheckerData, &data); err == nil {
+			return fmt.Sprintf("disk usage of %v below %v%%", data.Path, data.HighWatermark)
+		}
+	case autofix.SwapCheckerID:
+		return "swap disabled"
+	case autofix.TransparentHugepagesCheckerID:
+		return "transparent hugepages set to madvise or never"
+	case autofix.FirewallCheckerID:
+		var data autofix.FirewallCheckerData
+		if err := json.Unmarshal(probe.CheckerData, &data); err == nil && data.Firewall != "" {
+			return fmt.Sprintf("%v allows the ports required by the cluster", data.Firewall)
+		}
+	}
+	return ""
+}
+
+// observedValue returns the value observed by the failed probe
+func observedValue(probe agentpb.Probe) string {
+	if probe.Checker == monitoring.DiskSpaceCheckerID && len(probe.CheckerData) != 0 {
+		var data monitoring.HighWatermarkCheckerData
+		if err := json.Unmarshal(probe.CheckerData, &data); err == nil && data.TotalBytes != 0 {
+			return fmt.Sprintf("%v of %v available", humanize.Bytes(data.AvailableBytes),
+				humanize.Bytes(data.TotalBytes))
+		}
+	}
+	return probe.Error
+}
+
+// remediations maps checker names to the guidance on fixing their failed probes
+var remediations = map[string]string{
+	monitoring.KernelModuleCheckerID:      "Load the kernel module with modprobe and add it to /etc/modules-load.d to load it on boot",
+	monitoring.IPForwardCheckerID:         "Enable IP forwarding with 'sysctl -w net.ipv4.ip_forward=1' and persist the setting in /etc/sysctl.d",
+	monitoring.NetfilterCheckerID:         "Load the br_netfilter kernel module and enable 'sysctl -w net.bridge.bridge-nf-call-iptables=1'",
+	monitoring.MountsCheckerID:            "Enable 'sysctl -w fs.may_detach_mounts=1' and persist the setting in /etc/sysctl.d",
+	monitoring.DiskSpaceCheckerID:         "Free up disk space or extend the volume",
+	"cpu-ram":                             "Use a node with more CPU cores or memory as required by the node profile",
+	"os-checker":                          "Use one of the operating system distributions supported by the application",
+	"port-checker":                        "Stop the processes listening on the ports required by the cluster",
+	"process-checker":                     "Stop and disable the conflicting services (e.g. dockerd, kubelet, etcd) on the node",
+	"io-check":                            "Make sure the directory is writable and its disk satisfies the required throughput and capacity",
+	"dtype-check":                         "Format the filesystem hosting the state directory with d_type support (e.g. xfs with ftype=1)",
+	"cgroup-mounts":                       "Mount the required cgroup hierarchies",
+	"boot-config":                         "Rebuild the kernel or switch to one with the required configuration parameters enabled",
+	"script-check":                        "Consult the application documentation on the custom requirement",
+	autofix.SwapCheckerID:                 "Disable swap with 'swapoff -a' and remove the swap entries from /etc/fstab",
+	autofix.TransparentHugepagesCheckerID: "Disable transparent hugepages by writing 'never' to /sys/kernel/mm/transparent_hugepage/enabled and persist the setting in /etc/tmpfiles.d",
+	autofix.FirewallCheckerID:             "Allow the ports required by the cluster in the active firewalld zones or add ufw rules for them",
+}
+
+const (
+	// ResultPassed is the status of a passed probe
+	ResultPassed = "passed"
+	// ResultFailed is the status of a failed probe
+	ResultFailed = "failed"
+	// ResultFixed is the status of a failed probe that has been auto-fixed
+	ResultFixed = "fixed"
+	// ResultWarning is the status of a probe that failed with warning severity.
+	// Warnings are not counted as failures
+	ResultWarning = "warning"
+
+	// remoteChecker is the name of the check that validates remote nodes
+	remoteChecker = "remote-node"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr,omitempty"`
+	Contents string `xml:",chardata"`
+}
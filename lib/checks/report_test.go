@@ -0,0 +1,192 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+
+	"github.com/gravitational/gravity/lib/checks/autofix"
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/constants"
+
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+	. "gopkg.in/check.v1"
+)
+
+type ReportSuite struct{}
+
+var _ = Suite(&ReportSuite{})
+
+func (s *ReportSuite) TestBuildsReport(c *C) {
+	report := NewReport("node-1", s.newResult(c))
+	compare.DeepCompare(c, report.Results, []ProbeResult{
+		{
+			Check:       "cpu-ram",
+			Requirement: "at least 2 CPU cores",
+			Node:        "node-1",
+			Status:      ResultPassed,
+			Severity:    "critical",
+			Remediation: remediations["cpu-ram"],
+		},
+		{
+			Check:       monitoring.IPForwardCheckerID,
+			Requirement: "IP forwarding is enabled",
+			Node:        "node-1",
+			Status:      ResultFailed,
+			Severity:    "critical",
+			Observed:    "ipv4 forwarding is off",
+			Expected:    "net.ipv4.ip_forward = 1",
+			Remediation: remediations[monitoring.IPForwardCheckerID],
+			AutoFixable: true,
+		},
+		{
+			Check:       monitoring.KernelModuleCheckerID,
+			Requirement: "kernel module overlay",
+			Node:        "node-1",
+			Status:      ResultFixed,
+			Severity:    "critical",
+			Observed:    "overlay not loaded",
+			Expected:    "kernel module overlay loaded",
+			Remediation: remediations[monitoring.KernelModuleCheckerID],
+			AutoFixable: true,
+		},
+	})
+	c.Assert(report.Failed(), HasLen, 1)
+}
+
+func (s *ReportSuite) TestBuildsRemoteReport(c *C) {
+	report := &Report{}
+	report.AddRemote("10.0.0.1", nil)
+	report.AddRemote("10.0.0.2", []*agentpb.Probe{{
+		Checker:  monitoring.IPForwardCheckerID,
+		Detail:   "IP forwarding is enabled",
+		Error:    "ipv4 forwarding is off",
+		Status:   agentpb.Probe_Failed,
+		Severity: agentpb.Probe_Critical,
+	}})
+	c.Assert(report.Results, HasLen, 2)
+	c.Assert(report.Results[0].Node, Equals, "10.0.0.1")
+	c.Assert(report.Results[0].Status, Equals, ResultPassed)
+	c.Assert(report.Results[1].Node, Equals, "10.0.0.2")
+	c.Assert(report.Results[1].Status, Equals, ResultFailed)
+	c.Assert(report.Failed(), HasLen, 1)
+}
+
+func (s *ReportSuite) TestWritesJSON(c *C) {
+	report := NewReport("node-1", s.newResult(c))
+	var buf bytes.Buffer
+	c.Assert(report.Write(&buf, constants.EncodingJSON), IsNil)
+	var decoded Report
+	c.Assert(json.Unmarshal(buf.Bytes(), &decoded), IsNil)
+	compare.DeepCompare(c, decoded.Results, report.Results)
+}
+
+func (s *ReportSuite) TestWritesJUnit(c *C) {
+	report := NewReport("node-1", s.newResult(c))
+	var buf bytes.Buffer
+	c.Assert(report.Write(&buf, constants.EncodingJUnit), IsNil)
+	var decoded junitTestSuites
+	c.Assert(xml.Unmarshal(buf.Bytes(), &decoded), IsNil)
+	c.Assert(decoded.Suites, HasLen, 1)
+	suite := decoded.Suites[0]
+	c.Assert(suite.Tests, Equals, 3)
+	c.Assert(suite.Failures, Equals, 1)
+	c.Assert(suite.TestCases[0].Failure, IsNil)
+	failure := suite.TestCases[1].Failure
+	c.Assert(failure, NotNil)
+	c.Assert(suite.TestCases[1].ClassName, Equals, "node-1.ip-forward")
+	c.Assert(failure.Message, Equals, "ipv4 forwarding is off")
+	c.Assert(failure.Contents, Equals, `Observed: ipv4 forwarding is off
+Expected: net.ipv4.ip_forward = 1
+Remediation: `+remediations[monitoring.IPForwardCheckerID]+`
+Can be fixed automatically with --autofix`)
+}
+
+func (s *ReportSuite) TestDoesNotCountWarningsAsFailures(c *C) {
+	warning := &agentpb.Probe{
+		Checker:  autofix.SwapCheckerID,
+		Detail:   "swap should be disabled",
+		Error:    "swap is enabled",
+		Status:   agentpb.Probe_Failed,
+		Severity: agentpb.Probe_Warning,
+	}
+	report := NewReport("node-1", LocalChecksResult{
+		Probes:   []*agentpb.Probe{warning},
+		Warnings: []*agentpb.Probe{warning},
+	})
+	c.Assert(report.Results, HasLen, 1)
+	c.Assert(report.Results[0].Status, Equals, ResultWarning)
+	c.Assert(report.Results[0].Severity, Equals, "warning")
+	c.Assert(report.Failed(), HasLen, 0)
+
+	var buf bytes.Buffer
+	c.Assert(report.Write(&buf, constants.EncodingJUnit), IsNil)
+	var decoded junitTestSuites
+	c.Assert(xml.Unmarshal(buf.Bytes(), &decoded), IsNil)
+	c.Assert(decoded.Suites[0].Failures, Equals, 0)
+	c.Assert(decoded.Suites[0].TestCases[0].Failure, IsNil)
+	c.Assert(decoded.Suites[0].TestCases[0].SystemOut, Equals, `Observed: swap is enabled
+Remediation: `+remediations[autofix.SwapCheckerID]+`
+Can be fixed automatically with --autofix`)
+}
+
+func (s *ReportSuite) TestRejectsUnsupportedFormat(c *C) {
+	report := NewReport("node-1", LocalChecksResult{})
+	c.Assert(report.Write(&bytes.Buffer{}, constants.EncodingYAML), NotNil)
+}
+
+func (s *ReportSuite) newResult(c *C) LocalChecksResult {
+	sysctlData, err := json.Marshal(monitoring.SysctlCheckerData{
+		ParameterName:  "net.ipv4.ip_forward",
+		ParameterValue: "1",
+	})
+	c.Assert(err, IsNil)
+	moduleData, err := json.Marshal(monitoring.KernelModuleCheckerData{
+		Module: monitoring.ModuleRequest{Name: "overlay"},
+	})
+	c.Assert(err, IsNil)
+	passed := &agentpb.Probe{
+		Checker:  "cpu-ram",
+		Detail:   "at least 2 CPU cores",
+		Status:   agentpb.Probe_Running,
+		Severity: agentpb.Probe_Critical,
+	}
+	failed := &agentpb.Probe{
+		Checker:     monitoring.IPForwardCheckerID,
+		Detail:      "IP forwarding is enabled",
+		Error:       "ipv4 forwarding is off",
+		Status:      agentpb.Probe_Failed,
+		Severity:    agentpb.Probe_Critical,
+		CheckerData: sysctlData,
+	}
+	fixed := &agentpb.Probe{
+		Checker:     monitoring.KernelModuleCheckerID,
+		Detail:      "kernel module overlay",
+		Error:       "overlay not loaded",
+		Status:      agentpb.Probe_Failed,
+		Severity:    agentpb.Probe_Critical,
+		CheckerData: moduleData,
+	}
+	return LocalChecksResult{
+		Probes: []*agentpb.Probe{passed, failed, fixed},
+		Failed: []*agentpb.Probe{failed},
+		Fixed:  []*agentpb.Probe{fixed},
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package survey
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/rpc"
+	rpcclient "github.com/gravitational/gravity/lib/rpc/client"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	sshagent "golang.org/x/crypto/ssh/agent"
+	"google.golang.org/grpc/credentials"
+)
+
+// deployAgentRequest describes a temporary agent to deploy on a node
+type deployAgentRequest struct {
+	// Node is the node to deploy the agent on
+	Node Node
+	// GravityPath is the path to the local gravity binary to run the agent with
+	GravityPath string
+	// Secrets is the TLS archive with agent credentials
+	Secrets []byte
+	// Credentials are the client credentials to connect to the agent
+	Credentials credentials.TransportCredentials
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// agent is a temporary RPC agent running on a surveyed node.
+// The agent and all its files are located in a single directory
+// which is removed when the agent is torn down
+type agent struct {
+	node   Node
+	ssh    *ssh.Client
+	dir    string
+	client rpcclient.Client
+	// createdParentDir is set if the agent has created the parent
+	// directory and should remove it on teardown
+	createdParentDir bool
+	logrus.FieldLogger
+}
+
+// deployAgent copies the gravity binary and agent credentials to the node
+// over SSH, starts the RPC agent and connects to it
+func deployAgent(ctx context.Context, req deployAgentRequest) (*agent, error) {
+	sshClient, err := dialSSH(req.Node)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to connect to %v", req.Node)
+	}
+	r := &agent{
+		node:        req.Node,
+		ssh:         sshClient,
+		dir:         path.Join(agentParentDir, fmt.Sprintf("gravity-survey-%v", uuid.New())),
+		FieldLogger: req.WithField("node", req.Node.Addr),
+	}
+	if err := r.start(ctx, req); err != nil {
+		r.teardown()
+		return nil, trace.Wrap(err, "failed to start agent on %v", req.Node)
+	}
+	return r, nil
+}
+
+func (r *agent) start(ctx context.Context, req deployAgentRequest) error {
+	r.Infof("Deploying agent to %v.", r.dir)
+	if err := r.run(ctx, "test -d %v", agentParentDir); err != nil {
+		r.createdParentDir = true
+	}
+	err := r.run(ctx, "mkdir -p -m %o %v", defaults.PrivateDirMask, r.secretsDir())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	gravity, err := os.Open(req.GravityPath)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer gravity.Close()
+	err = r.upload(ctx, gravity, "cat > %[1]v && chmod %[2]o %[1]v",
+		r.gravityPath(), defaults.SharedExecutableMask)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = r.upload(ctx, bytes.NewReader(req.Secrets), "tar -xf - -C %v", r.secretsDir())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// The agent keeps its state and logs in the agent directory
+	// to leave no trace on the node after teardown
+	err = r.run(ctx, "setsid nohup %[1]v/gravity --state-dir=%[1]v/local --system-log-file=%[1]v/agent.log "+
+		"agent run --secrets-dir=%[2]v >/dev/null 2>&1 </dev/null & echo $! > %[1]v/agent.pid",
+		r.dir, r.secretsDir())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, defaults.AgentConnectTimeout)
+	defer cancel()
+	r.client, err = rpcclient.New(connectCtx, rpcclient.Config{
+		ServerAddr:  rpc.AgentAddr(r.node.AdvertiseAddr),
+		Credentials: req.Credentials,
+	})
+	if err != nil {
+		return trace.Wrap(err, "failed to connect to agent on %v", r.node.AdvertiseAddr)
+	}
+	return nil
+}
+
+// teardown stops the agent and removes its directory from the node.
+// The teardown is bound by its own timeout so it runs to completion
+// even if the survey has been cancelled
+func (r *agent) teardown() {
+	if r.client != nil {
+		r.client.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.RPCAgentShutdownTimeout)
+	defer cancel()
+	err := r.run(ctx, "if [ -f %[1]v/agent.pid ]; then kill $(cat %[1]v/agent.pid) || true; fi; rm -rf %[1]v", r.dir)
+	if err != nil {
+		r.WithError(err).Warnf("Failed to clean up agent directory %v.", r.dir)
+	}
+	if r.createdParentDir {
+		// only remove the parent directory if it is empty
+		err = r.run(ctx, "rmdir %v", agentParentDir)
+		if err != nil {
+			r.WithError(err).Warnf("Failed to remove %v.", agentParentDir)
+		}
+	}
+	r.ssh.Close()
+}
+
+// run executes the specified shell command on the node
+func (r *agent) run(ctx context.Context, format string, args ...interface{}) error {
+	return trace.Wrap(utils.SSHRun(ctx, r.ssh, r.FieldLogger, r.command(format, args...),
+		map[string]string{defaults.PathEnv: defaults.PathEnvVal}))
+}
+
+// upload executes the specified shell command on the node
+// feeding it the contents of the reader r on stdin
+func (r *agent) upload(ctx context.Context, reader io.Reader, format string, args ...interface{}) error {
+	session, err := r.ssh.NewSession()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer session.Close()
+	session.Stdin = reader
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	cmd := r.command(format, args...)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- session.Run(cmd)
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return trace.Wrap(err, "%v: %s", cmd, stderr.Bytes())
+		}
+		return nil
+	case <-ctx.Done():
+		session.Signal(ssh.SIGTERM)
+		return trace.Wrap(ctx.Err())
+	}
+}
+
+// command returns the shell command line for the specified command.
+// The command is run with sudo unless the SSH user is root
+func (r *agent) command(format string, args ...interface{}) string {
+	cmd := fmt.Sprintf("sh -c '%v'", fmt.Sprintf(format, args...))
+	if r.node.User != defaults.SSHUser {
+		cmd = fmt.Sprintf("sudo -n %v", cmd)
+	}
+	return cmd
+}
+
+func (r *agent) gravityPath() string {
+	return path.Join(r.dir, "gravity")
+}
+
+func (r *agent) secretsDir() string {
+	return path.Join(r.dir, defaults.SecretsDir)
+}
+
+// dialSSH connects to the node over SSH authenticating either
+// with the node's identity file or with the running SSH agent
+func dialSSH(node Node) (*ssh.Client, error) {
+	auth, err := authMethod(node)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	config := &ssh.ClientConfig{
+		User:            node.User,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback(node),
+		Timeout:         defaults.DialTimeout,
+	}
+	client, err := ssh.Dial("tcp", node.Addr, config)
+	if err != nil {
+		return nil, trace.ConnectionProblem(err, "failed to dial %v", node.Addr)
+	}
+	return client, nil
+}
+
+func authMethod(node Node) (ssh.AuthMethod, error) {
+	if node.IdentityFile != "" {
+		keyBytes, err := ioutil.ReadFile(node.IdentityFile)
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		signer, err := ssh.ParsePrivateKey(keyBytes)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to parse SSH key %v", node.IdentityFile)
+		}
+		return ssh.PublicKeys(signer), nil
+	}
+	socket := os.Getenv(sshAuthSockEnv)
+	if socket == "" {
+		return nil, trace.BadParameter("%v: specify identityFile in the inventory or run an SSH agent", node)
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return ssh.PublicKeysCallback(sshagent.NewClient(conn).Signers), nil
+}
+
+const (
+	// agentParentDir is the directory on the node to create agent directories in.
+	// The gravity state directory is used since temporary directories
+	// are frequently mounted noexec
+	agentParentDir = defaults.GravityDir
+	// sshAuthSockEnv is the environment variable with the SSH agent socket
+	sshAuthSockEnv = "SSH_AUTH_SOCK"
+)
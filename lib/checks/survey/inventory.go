@@ -0,0 +1,215 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package survey
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+)
+
+// Inventory lists the nodes to survey along with the SSH
+// parameters used to reach them
+type Inventory struct {
+	// User is the default SSH user
+	User string `json:"user,omitempty"`
+	// Port is the default SSH port
+	Port int `json:"port,omitempty"`
+	// IdentityFile is the default path to the SSH private key
+	IdentityFile string `json:"identityFile,omitempty"`
+	// KnownHostsFile is the path to the known hosts file used to verify
+	// the host keys of the nodes that do not specify hostKey.
+	// Defaults to ~/.ssh/known_hosts
+	KnownHostsFile string `json:"knownHostsFile,omitempty"`
+	// Nodes lists the nodes to survey
+	Nodes []Node `json:"nodes"`
+}
+
+// Node describes a single node in the inventory
+type Node struct {
+	// Addr is the SSH address of the node as host[:port]
+	Addr string `json:"addr"`
+	// AdvertiseAddr is the address the node will advertise in the cluster.
+	// Defaults to the host part of Addr
+	AdvertiseAddr string `json:"advertiseAddr,omitempty"`
+	// Profile is the node profile from the application manifest
+	Profile string `json:"profile"`
+	// User is the SSH user, overrides the inventory default
+	User string `json:"user,omitempty"`
+	// IdentityFile is the path to the SSH private key,
+	// overrides the inventory default
+	IdentityFile string `json:"identityFile,omitempty"`
+	// HostKey is the expected SHA256 fingerprint of the node's SSH host key.
+	// If unspecified, the host key is verified against the known hosts file
+	HostKey string `json:"hostKey,omitempty"`
+	// KnownHostsFile is the path to the known hosts file,
+	// overrides the inventory default
+	KnownHostsFile string `json:"knownHostsFile,omitempty"`
+}
+
+// String returns a textual representation of this node
+func (r Node) String() string {
+	return fmt.Sprintf("node(addr=%v, profile=%v)", r.Addr, r.Profile)
+}
+
+// ReadInventory reads the inventory from the YAML file at the specified path
+func ReadInventory(path string) (*Inventory, error) {
+	data, err := utils.ReadPath(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	inventory, err := ParseInventory(data)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse inventory %v", path)
+	}
+	return inventory, nil
+}
+
+// ParseInventory parses the inventory from the specified YAML data
+// and sets defaults for the nodes
+func ParseInventory(data []byte) (*Inventory, error) {
+	var inventory Inventory
+	if err := yaml.Unmarshal(data, &inventory); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := inventory.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &inventory, nil
+}
+
+// CheckAndSetDefaults validates the inventory and sets defaults
+// for the unspecified node parameters
+func (r *Inventory) CheckAndSetDefaults() error {
+	if len(r.Nodes) == 0 {
+		return trace.BadParameter("inventory does not list any nodes")
+	}
+	if r.User == "" {
+		r.User = defaults.SSHUser
+	}
+	if r.Port == 0 {
+		r.Port = defaultSSHPort
+	}
+	if r.KnownHostsFile == "" {
+		r.KnownHostsFile = defaultKnownHostsFile
+	}
+	r.KnownHostsFile = expandHome(r.KnownHostsFile)
+	advertiseAddrs := make(map[string]struct{}, len(r.Nodes))
+	for i := range r.Nodes {
+		node := &r.Nodes[i]
+		if node.Addr == "" {
+			return trace.BadParameter("node #%v is missing address", i+1)
+		}
+		if node.Profile == "" {
+			return trace.BadParameter("%v is missing profile", node)
+		}
+		host, port := utils.SplitHostPort(node.Addr, strconv.Itoa(r.Port))
+		node.Addr = net.JoinHostPort(host, port)
+		if node.AdvertiseAddr == "" {
+			node.AdvertiseAddr = host
+		}
+		if net.ParseIP(node.AdvertiseAddr) == nil {
+			return trace.BadParameter("%v advertise address %q is not a valid IP address",
+				node, node.AdvertiseAddr)
+		}
+		if _, exists := advertiseAddrs[node.AdvertiseAddr]; exists {
+			return trace.BadParameter("duplicate advertise address %v", node.AdvertiseAddr)
+		}
+		advertiseAddrs[node.AdvertiseAddr] = struct{}{}
+		if node.User == "" {
+			node.User = r.User
+		}
+		if node.IdentityFile == "" {
+			node.IdentityFile = r.IdentityFile
+		}
+		node.IdentityFile = expandHome(node.IdentityFile)
+		if node.KnownHostsFile == "" {
+			node.KnownHostsFile = r.KnownHostsFile
+		}
+		node.KnownHostsFile = expandHome(node.KnownHostsFile)
+	}
+	return nil
+}
+
+// CheckManifest verifies that the inventory nodes reference profiles from
+// the specified manifest. If flavorName is not empty, the number of nodes
+// of each profile is additionally verified against the flavor
+func (r Inventory) CheckManifest(manifest schema.Manifest, flavorName string) error {
+	counts := make(map[string]int)
+	for _, node := range r.Nodes {
+		if _, err := manifest.NodeProfiles.ByName(node.Profile); err != nil {
+			return trace.NotFound("%v references unknown profile %q", node, node.Profile)
+		}
+		counts[node.Profile]++
+	}
+	if flavorName == "" {
+		return nil
+	}
+	flavor := manifest.FindFlavor(flavorName)
+	if flavor == nil {
+		return trace.NotFound("flavor %q not found in the application manifest", flavorName)
+	}
+	var errors []string
+	for _, node := range flavor.Nodes {
+		if counts[node.Profile] != node.Count {
+			errors = append(errors, fmt.Sprintf("flavor %q requires %v node(s) of profile %q, inventory lists %v",
+				flavor.Name, node.Count, node.Profile, counts[node.Profile]))
+		}
+		delete(counts, node.Profile)
+	}
+	for _, node := range r.Nodes {
+		count, ok := counts[node.Profile]
+		if !ok {
+			continue
+		}
+		errors = append(errors, fmt.Sprintf("flavor %q does not include profile %q, inventory lists %v node(s)",
+			flavor.Name, node.Profile, count))
+		delete(counts, node.Profile)
+	}
+	if len(errors) != 0 {
+		return trace.BadParameter("%v", strings.Join(errors, "\n"))
+	}
+	return nil
+}
+
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+const (
+	// defaultSSHPort is the default SSH port
+	defaultSSHPort = 22
+	// defaultKnownHostsFile is the default path to the known hosts file
+	defaultKnownHostsFile = "~/.ssh/known_hosts"
+)
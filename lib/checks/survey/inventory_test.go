@@ -0,0 +1,163 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package survey
+
+import (
+	"testing"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestSurvey(t *testing.T) { TestingT(t) }
+
+type InventorySuite struct{}
+
+var _ = Suite(&InventorySuite{})
+
+func (s *InventorySuite) TestParsesInventory(c *C) {
+	inventory, err := ParseInventory([]byte(`
+user: centos
+identityFile: /keys/id_rsa
+nodes:
+- addr: 10.0.0.1
+  profile: master
+- addr: 10.0.0.2:2222
+  advertiseAddr: 192.168.1.2
+  profile: worker
+  user: root
+  identityFile: /keys/worker
+  hostKey: SHA256:abc
+`))
+	c.Assert(err, IsNil)
+	knownHosts := expandHome(defaultKnownHostsFile)
+	compare.DeepCompare(c, inventory, &Inventory{
+		User:           "centos",
+		Port:           defaultSSHPort,
+		IdentityFile:   "/keys/id_rsa",
+		KnownHostsFile: knownHosts,
+		Nodes: []Node{
+			{
+				Addr:           "10.0.0.1:22",
+				AdvertiseAddr:  "10.0.0.1",
+				Profile:        "master",
+				User:           "centos",
+				IdentityFile:   "/keys/id_rsa",
+				KnownHostsFile: knownHosts,
+			},
+			{
+				Addr:           "10.0.0.2:2222",
+				AdvertiseAddr:  "192.168.1.2",
+				Profile:        "worker",
+				User:           "root",
+				IdentityFile:   "/keys/worker",
+				HostKey:        "SHA256:abc",
+				KnownHostsFile: knownHosts,
+			},
+		},
+	})
+}
+
+func (s *InventorySuite) TestValidatesInventory(c *C) {
+	var testCases = []struct {
+		inventory string
+		comment   string
+	}{
+		{
+			inventory: `nodes: []`,
+			comment:   "no nodes",
+		},
+		{
+			inventory: `
+nodes:
+- profile: master`,
+			comment: "missing address",
+		},
+		{
+			inventory: `
+nodes:
+- addr: 10.0.0.1`,
+			comment: "missing profile",
+		},
+		{
+			inventory: `
+nodes:
+- addr: node-1.example.com
+  profile: master`,
+			comment: "advertise address is not an IP address",
+		},
+		{
+			inventory: `
+nodes:
+- addr: 10.0.0.1
+  profile: master
+- addr: 10.0.0.1:2222
+  profile: worker`,
+			comment: "duplicate advertise address",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := ParseInventory([]byte(tc.inventory))
+		c.Assert(trace.IsBadParameter(err), Equals, true, Commentf(tc.comment))
+	}
+}
+
+func (s *InventorySuite) TestChecksInventoryAgainstManifest(c *C) {
+	manifest := schema.Manifest{
+		NodeProfiles: schema.NodeProfiles{
+			{Name: "master"},
+			{Name: "worker"},
+		},
+		Installer: &schema.Installer{
+			Flavors: schema.Flavors{
+				Items: []schema.Flavor{
+					{
+						Name: "small",
+						Nodes: []schema.FlavorNode{
+							{Profile: "master", Count: 1},
+							{Profile: "worker", Count: 1},
+						},
+					},
+					{
+						Name: "single",
+						Nodes: []schema.FlavorNode{
+							{Profile: "master", Count: 1},
+						},
+					},
+				},
+			},
+		},
+	}
+	inventory := Inventory{
+		Nodes: []Node{
+			{Addr: "10.0.0.1", Profile: "master"},
+			{Addr: "10.0.0.2", Profile: "worker"},
+		},
+	}
+	c.Assert(inventory.CheckManifest(manifest, ""), IsNil)
+	c.Assert(inventory.CheckManifest(manifest, "small"), IsNil)
+	err := inventory.CheckManifest(manifest, "single")
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+	c.Assert(err, ErrorMatches, `flavor "single" does not include profile "worker", inventory lists 1 node\(s\)`)
+	c.Assert(trace.IsNotFound(inventory.CheckManifest(manifest, "large")), Equals, true)
+
+	inventory.Nodes = append(inventory.Nodes, Node{Addr: "10.0.0.3", Profile: "db"})
+	c.Assert(trace.IsNotFound(inventory.CheckManifest(manifest, "")), Equals, true)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package survey
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ssh"
+)
+
+// knownHosts lists the host keys recorded for a single host
+// in the known hosts file
+type knownHosts struct {
+	// keys lists the trusted host keys
+	keys []ssh.PublicKey
+	// revoked lists the keys marked as revoked
+	revoked []ssh.PublicKey
+}
+
+// readKnownHosts returns the host keys recorded for the specified
+// SSH address (host:port) in the known hosts file at path.
+// Only plain and hashed host names are matched, wildcard patterns
+// and certificate authorities are not supported
+func readKnownHosts(path, addr string) (*knownHosts, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &knownHosts{}, nil
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	host := knownHostsAddr(addr)
+	var hosts knownHosts
+	for len(data) != 0 {
+		marker, patterns, key, _, rest, err := ssh.ParseKnownHosts(data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to parse known hosts file %v", path)
+		}
+		data = rest
+		if !matchesKnownHost(patterns, host) {
+			continue
+		}
+		switch marker {
+		case "":
+			hosts.keys = append(hosts.keys, key)
+		case markerRevoked:
+			hosts.revoked = append(hosts.revoked, key)
+		}
+	}
+	return &hosts, nil
+}
+
+// verify makes sure the specified key is one of the trusted host keys
+func (r knownHosts) verify(addr string, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+	if containsKey(r.revoked, key) {
+		return trace.AccessDenied("host key %v for %v has been revoked", fingerprint, addr)
+	}
+	if len(r.keys) == 0 {
+		return trace.AccessDenied("host key %v for %v is not known: "+
+			"specify hostKey for the node in the inventory or add the node to the known hosts file",
+			fingerprint, addr)
+	}
+	if !containsKey(r.keys, key) {
+		return trace.AccessDenied("host key mismatch for %v: %v does not match the known hosts file",
+			addr, fingerprint)
+	}
+	return nil
+}
+
+// knownHostsAddr formats the SSH address the way it is recorded
+// in the known hosts file: the port is only included if it is not the default
+func knownHostsAddr(addr string) string {
+	host, port := utils.SplitHostPort(addr, "")
+	if port == "" || port == "22" {
+		return host
+	}
+	return "[" + host + "]:" + port
+}
+
+func matchesKnownHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, hashedHostPrefix) {
+			if matchesHashedHost(pattern, host) {
+				return true
+			}
+			continue
+		}
+		if pattern == host {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesHashedHost matches the host against the hashed known hosts
+// entry in the |1|salt|hash format
+func matchesHashedHost(pattern, host string) bool {
+	parts := strings.Split(strings.TrimPrefix(pattern, hashedHostPrefix), "|")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return hmac.Equal(mac.Sum(nil), hash)
+}
+
+func containsKey(keys []ssh.PublicKey, key ssh.PublicKey) bool {
+	for _, k := range keys {
+		if bytes.Equal(k.Marshal(), key.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostKeyCallback verifies the node's host key against the fingerprint
+// from the inventory or, if the inventory does not specify one,
+// against the known hosts file
+func hostKeyCallback(node Node) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if node.HostKey != "" {
+			fingerprint := ssh.FingerprintSHA256(key)
+			if fingerprint != node.HostKey {
+				return trace.AccessDenied("host key mismatch for %v: expected %v, got %v",
+					node.Addr, node.HostKey, fingerprint)
+			}
+			return nil
+		}
+		hosts, err := readKnownHosts(node.KnownHostsFile, node.Addr)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(hosts.verify(node.Addr, key))
+	}
+}
+
+const (
+	// hashedHostPrefix is the prefix of hashed known hosts entries
+	hashedHostPrefix = "|1|"
+	// markerRevoked marks revoked keys in the known hosts file
+	markerRevoked = "revoked"
+)
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package survey
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+	. "gopkg.in/check.v1"
+)
+
+type KnownHostsSuite struct{}
+
+var _ = Suite(&KnownHostsSuite{})
+
+func (s *KnownHostsSuite) TestVerifiesHostKeys(c *C) {
+	key, other := newHostKey(c), newHostKey(c)
+	path := filepath.Join(c.MkDir(), "known_hosts")
+	err := ioutil.WriteFile(path, []byte(fmt.Sprintf(`# comment
+10.0.0.1 %[1]v
+[10.0.0.2]:2222 %[1]v
+%[3]v %[1]v
+node-4,10.0.0.4 %[2]v
+@revoked 10.0.0.5 %[1]v
+10.0.0.5 %[1]v
+`, authorizedKey(key), authorizedKey(other), hashHost("10.0.0.3"))), 0600)
+	c.Assert(err, IsNil)
+
+	var testCases = []struct {
+		node    Node
+		key     ssh.PublicKey
+		err     bool
+		comment string
+	}{
+		{
+			node:    Node{Addr: "10.0.0.1:22", KnownHostsFile: path},
+			key:     key,
+			comment: "plain host name",
+		},
+		{
+			node:    Node{Addr: "10.0.0.2:2222", KnownHostsFile: path},
+			key:     key,
+			comment: "non-default port",
+		},
+		{
+			node:    Node{Addr: "10.0.0.2:22", KnownHostsFile: path},
+			key:     key,
+			err:     true,
+			comment: "host is only known on another port",
+		},
+		{
+			node:    Node{Addr: "10.0.0.3:22", KnownHostsFile: path},
+			key:     key,
+			comment: "hashed host name",
+		},
+		{
+			node:    Node{Addr: "10.0.0.4:22", KnownHostsFile: path},
+			key:     key,
+			err:     true,
+			comment: "host key mismatch",
+		},
+		{
+			node:    Node{Addr: "10.0.0.5:22", KnownHostsFile: path},
+			key:     key,
+			err:     true,
+			comment: "revoked host key",
+		},
+		{
+			node:    Node{Addr: "10.0.0.6:22", KnownHostsFile: path},
+			key:     key,
+			err:     true,
+			comment: "unknown host",
+		},
+		{
+			node:    Node{Addr: "10.0.0.6:22", KnownHostsFile: filepath.Join(c.MkDir(), "missing")},
+			key:     key,
+			err:     true,
+			comment: "missing known hosts file",
+		},
+		{
+			node:    Node{Addr: "10.0.0.6:22", HostKey: ssh.FingerprintSHA256(key), KnownHostsFile: path},
+			key:     key,
+			comment: "fingerprint from the inventory",
+		},
+		{
+			node:    Node{Addr: "10.0.0.1:22", HostKey: ssh.FingerprintSHA256(other), KnownHostsFile: path},
+			key:     key,
+			err:     true,
+			comment: "fingerprint from the inventory takes precedence",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		err := hostKeyCallback(tc.node)(tc.node.Addr, nil, tc.key)
+		if tc.err {
+			c.Assert(trace.IsAccessDenied(err), Equals, true, comment)
+		} else {
+			c.Assert(err, IsNil, comment)
+		}
+	}
+}
+
+func newHostKey(c *C) ssh.PublicKey {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, IsNil)
+	key, err := ssh.NewPublicKey(public)
+	c.Assert(err, IsNil)
+	return key
+}
+
+func authorizedKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+func hashHost(host string) string {
+	salt := make([]byte, sha1.Size)
+	rand.Read(salt)
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return hashedHostPrefix + base64.StdEncoding.EncodeToString(salt) + "|" +
+		base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
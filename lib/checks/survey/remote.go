@@ -0,0 +1,177 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package survey
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/gravitational/gravity/lib/checks"
+	validationpb "github.com/gravitational/gravity/lib/network/validation/proto"
+	"github.com/gravitational/gravity/lib/rpc"
+	rpcclient "github.com/gravitational/gravity/lib/rpc/client"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Exec executes an arbitrary command on the remote node specified with addr.
+// The output is written into out
+func (r *remoteCommands) Exec(ctx context.Context, addr string, command []string, out io.Writer) error {
+	clt, err := r.getClient(addr)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(clt.Command(ctx, r.FieldLogger, out, command...))
+}
+
+// CheckPorts validates the cluster port availability
+func (r *remoteCommands) CheckPorts(ctx context.Context, req checks.PingPongGame) (checks.PingPongGameResults, error) {
+	resp, err := r.pingPong(ctx, req, ports)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp, nil
+}
+
+// CheckBandwidth validates the cluster network bandwidth
+func (r *remoteCommands) CheckBandwidth(ctx context.Context, req checks.PingPongGame) (checks.PingPongGameResults, error) {
+	resp, err := r.pingPong(ctx, req, bandwidth)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp, nil
+}
+
+// CheckNetwork validates the cluster network latency, packet loss and path MTU
+func (r *remoteCommands) CheckNetwork(ctx context.Context, req checks.PingPongGame) (checks.PingPongGameResults, error) {
+	resp, err := r.pingPong(ctx, req, network)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp, nil
+}
+
+// Validate validates the node given with addr against the specified manifest.
+// Returns the list of failed test results.
+func (r *remoteCommands) Validate(ctx context.Context, addr string, manifest schema.Manifest, profileName string) ([]*agentpb.Probe, error) {
+	clt, err := r.getClient(addr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bytes, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	failed, err := clt.Validate(ctx, &validationpb.ValidateRequest{
+		Manifest: bytes,
+		Profile:  profileName,
+		Docker:   &validationpb.Docker{StorageDriver: r.docker.StorageDriver},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if r.report != nil {
+		r.report.AddRemote(addr, failed)
+	}
+	return failed, nil
+}
+
+// remoteCommands executes remote commands and validates remote nodes
+// using the temporary agents deployed on the surveyed nodes.
+// Implements checks.Remote
+type remoteCommands struct {
+	// clients maps advertise address of a node to the client of its agent
+	clients map[string]rpcclient.Client
+	docker  storage.DockerConfig
+	// report optionally collects the outcome of the node validation
+	report *checks.Report
+	logrus.FieldLogger
+}
+
+func (r *remoteCommands) getClient(addr string) (rpcclient.Client, error) {
+	clt, ok := r.clients[addr]
+	if !ok {
+		return nil, trace.NotFound("no agent running on %v", addr)
+	}
+	return clt, nil
+}
+
+func (r *remoteCommands) pingPong(ctx context.Context, game checks.PingPongGame, fn pingpongHandler) (checks.PingPongGameResults, error) {
+	resultsCh := make(chan pingpongResult, len(game))
+	for addr, req := range game {
+		clt, err := r.getClient(addr)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		go fn(ctx, rpc.AgentAddr(addr), clt, req, resultsCh)
+	}
+	results := make(checks.PingPongGameResults, len(game))
+	for _, req := range game {
+		select {
+		case result := <-resultsCh:
+			if result.err != nil {
+				return nil, trace.Wrap(result.err)
+			}
+			results[result.addr] = *result.resp
+		case <-time.After(req.Timeout()):
+			return nil, trace.LimitExceeded("timeout waiting for servers")
+		}
+	}
+	return results, nil
+}
+
+func ports(ctx context.Context, addr string, clt rpcclient.Client, req checks.PingPongRequest, resultsCh chan<- pingpongResult) {
+	resp, err := clt.CheckPorts(ctx, req.PortsProto())
+	if err != nil {
+		resultsCh <- pingpongResult{addr: addr, err: err}
+		return
+	}
+	resultsCh <- pingpongResult{addr: addr, resp: checks.ResultFromPortsProto(resp, nil)}
+}
+
+func bandwidth(ctx context.Context, addr string, clt rpcclient.Client, req checks.PingPongRequest, resultsCh chan<- pingpongResult) {
+	resp, err := clt.CheckBandwidth(ctx, req.BandwidthProto())
+	if err != nil {
+		resultsCh <- pingpongResult{addr: addr, err: err}
+		return
+	}
+	resultsCh <- pingpongResult{addr: addr, resp: checks.ResultFromBandwidthProto(resp, nil)}
+}
+
+func network(ctx context.Context, addr string, clt rpcclient.Client, req checks.PingPongRequest, resultsCh chan<- pingpongResult) {
+	resp, err := clt.CheckNetwork(ctx, req.NetworkProto())
+	if err != nil {
+		resultsCh <- pingpongResult{addr: addr, err: err}
+		return
+	}
+	resultsCh <- pingpongResult{addr: addr, resp: checks.ResultFromNetworkProto(resp, nil)}
+}
+
+type pingpongHandler func(ctx context.Context, addr string, clt rpcclient.Client,
+	req checks.PingPongRequest, resultsCh chan<- pingpongResult)
+
+type pingpongResult struct {
+	addr string
+	resp *checks.PingPongResult
+	err  error
+}
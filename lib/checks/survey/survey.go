@@ -0,0 +1,248 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package survey implements remote pre-flight checks of the nodes listed
+// in an inventory before the cluster is installed.
+//
+// The survey bootstraps temporary RPC agents on the nodes over SSH,
+// runs the multi-node pre-flight checks against the application manifest
+// from the installer tarball and tears the agents down leaving no trace
+// on the nodes.
+package survey
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/checks"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/rpc"
+	rpcclient "github.com/gravitational/gravity/lib/rpc/client"
+	pb "github.com/gravitational/gravity/lib/rpc/proto"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Config defines the configuration of the survey
+type Config struct {
+	// Inventory lists the nodes to survey
+	Inventory Inventory
+	// InstallerPath is the path to the application installer tarball
+	InstallerPath string
+	// Flavor is the optional install flavor to verify the inventory against
+	Flavor string
+	// Report optionally collects the outcome of the checks on every node
+	Report *checks.Report
+	// Emitter outputs the survey progress
+	utils.Emitter
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if err := r.Inventory.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.InstallerPath == "" {
+		return trace.BadParameter("missing installer path")
+	}
+	if r.Emitter == nil {
+		r.Emitter = utils.NopEmitter()
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "survey")
+	}
+	return nil
+}
+
+// RunChecks bootstraps temporary agents on the inventory nodes, runs the
+// multi-node pre-flight checks against the application manifest
+// and removes the agents from the nodes.
+// Returns an aggregate of failed checks
+func RunChecks(ctx context.Context, config Config) error {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	dir, err := ioutil.TempDir("", "survey")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest, err := unpackInstaller(config.InstallerPath, dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := config.Inventory.CheckManifest(*manifest, config.Flavor); err != nil {
+		return trace.Wrap(err)
+	}
+	config.PrintStep("Deploying agents on %v node(s)", len(config.Inventory.Nodes))
+	agents, err := deployAgents(ctx, config, filepath.Join(dir, constants.GravityBin))
+	defer func() {
+		config.PrintStep("Removing agents")
+		for _, agent := range agents {
+			agent.teardown()
+		}
+	}()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	remote := &remoteCommands{
+		clients:     make(map[string]rpcclient.Client, len(agents)),
+		docker:      checks.DockerConfigFromSchemaValue(manifest.SystemDocker()),
+		report:      config.Report,
+		FieldLogger: config.FieldLogger,
+	}
+	servers := make([]checks.Server, 0, len(agents))
+	for _, agent := range agents {
+		info, err := checks.GetServerInfo(ctx, agent.client)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		servers = append(servers, checks.Server{
+			Server: storage.Server{
+				AdvertiseIP: agent.node.AdvertiseAddr,
+				Hostname:    info.GetHostname(),
+				Role:        agent.node.Profile,
+			},
+			ServerInfo: *info,
+		})
+		remote.clients[agent.node.AdvertiseAddr] = agent.client
+	}
+	requirements, err := checks.RequirementsFromManifest(*manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	checker, err := checks.New(remote, servers, *manifest, requirements)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	checker.TestBandwidth = true
+	checker.TestNetwork = true
+	config.PrintStep("Running pre-flight checks")
+	return trace.Wrap(checker.Run(ctx))
+}
+
+// deployAgents deploys agents on all inventory nodes in parallel.
+// Returns the list of successfully deployed agents even if some
+// of the deployments have failed or the context has been cancelled
+// so they can be torn down
+func deployAgents(ctx context.Context, config Config, gravityPath string) ([]*agent, error) {
+	hosts := make([]string, 0, len(config.Inventory.Nodes))
+	for _, node := range config.Inventory.Nodes {
+		hosts = append(hosts, node.AdvertiseAddr)
+	}
+	keys, err := rpc.GenerateAgentCredentials(hosts, defaults.SystemAccountOrg, false)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	creds, err := rpc.ClientCredentialsFromKeyPairs(*keys[pb.Client], *keys[pb.CA])
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	reader, err := utils.CreateTLSArchive(keys)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	secrets, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var (
+		mu     sync.Mutex
+		agents []*agent
+	)
+	errors := make(chan error, len(config.Inventory.Nodes))
+	for _, node := range config.Inventory.Nodes {
+		go func(node Node) {
+			agent, err := deployAgent(ctx, deployAgentRequest{
+				Node:        node,
+				GravityPath: gravityPath,
+				Secrets:     secrets,
+				Credentials: creds,
+				FieldLogger: config.FieldLogger,
+			})
+			if err == nil {
+				mu.Lock()
+				agents = append(agents, agent)
+				mu.Unlock()
+			}
+			errors <- trace.Wrap(err)
+		}(node)
+	}
+	// Wait for all deployments to complete regardless of the context
+	// since a cancelled deployment tears its agent down itself
+	err = utils.CollectErrors(context.Background(), errors)
+	mu.Lock()
+	defer mu.Unlock()
+	return agents, trace.Wrap(err)
+}
+
+// unpackInstaller extracts the application manifest and the gravity binary
+// from the installer tarball into dir and returns the parsed manifest
+func unpackInstaller(path, dir string) (*schema.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	decompressed, err := dockerarchive.DecompressStream(f)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer decompressed.Close()
+	var found []string
+	err = archive.TarGlob(tar.NewReader(decompressed), ".",
+		[]string{defaults.ManifestFileName, constants.GravityBin},
+		func(match string, file io.Reader) error {
+			// only consider files from the installer root
+			if match != filepath.Base(match) {
+				return nil
+			}
+			found = append(found, match)
+			return trace.Wrap(utils.CopyReaderWithPerms(
+				filepath.Join(dir, match), file, defaults.SharedExecutableMask))
+		})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, name := range []string{defaults.ManifestFileName, constants.GravityBin} {
+		if !utils.StringInSlice(found, name) {
+			return nil, trace.NotFound("installer %v does not contain %v", path, name)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, defaults.ManifestFileName))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	manifest, err := schema.ParseManifestYAMLNoValidate(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
@@ -199,6 +199,15 @@ const (
 	// ServiceGroupEnvVar names the environment variable that specifies the service group ID
 	ServiceGroupEnvVar = "GRAVITY_SERVICE_GROUP"
 
+	// HTTPProxyEnvVar names the environment variable that specifies the proxy for HTTP requests
+	HTTPProxyEnvVar = "HTTP_PROXY"
+
+	// HTTPSProxyEnvVar names the environment variable that specifies the proxy for HTTPS requests
+	HTTPSProxyEnvVar = "HTTPS_PROXY"
+
+	// NoProxyEnvVar names the environment variable that lists the hosts that bypass the proxy
+	NoProxyEnvVar = "NO_PROXY"
+
 	// PreflightChecksOffEnvVar is the name of environment variable that can be used to turn off preflight
 	// checks during install or update.
 	// If not empty, turns the preflight checks off
@@ -518,6 +527,9 @@ const (
 	// kubernetes, use a lowercase notation instead to make it backwards-compatible
 	ClusterPrivateKeyMapKey = "privatekey"
 
+	// GravityConfigMap is the name of the ConfigMap with cluster controller configuration
+	GravityConfigMap = "gravity-opscenter"
+
 	// SMTPSecret specifies the name of the Secret with cluster SMTP configuration
 	SMTPSecret = "smtp-configuration-update"
 
@@ -554,6 +566,33 @@ const (
 	// ReportFilterKubernetes defines a report filter to fetch kubernetes diagnostics
 	ReportFilterKubernetes = "kubernetes"
 
+	// ReportFilterHost defines a report filter to fetch the host configuration
+	// and the status of planet services. It is a subset of ReportFilterSystem
+	ReportFilterHost = "host"
+
+	// ReportFilterLogs defines a report filter to fetch system and planet logs.
+	// It is a subset of ReportFilterSystem
+	ReportFilterLogs = "logs"
+
+	// ReportFilterBashHistory defines a report filter to fetch shell histories.
+	// It is a subset of ReportFilterSystem
+	ReportFilterBashHistory = "bash-history"
+
+	// ReportFilterPackages defines a report filter to fetch the list of node packages.
+	// It is a subset of ReportFilterSystem
+	ReportFilterPackages = "packages"
+
+	// ReportFilterCluster defines a report filter to fetch the cluster state
+	// and the output of the application dump hook
+	ReportFilterCluster = "cluster"
+
+	// ReportFilterOperations defines a report filter to fetch cluster operation logs
+	ReportFilterOperations = "operations"
+
+	// ReportFilterApp defines a report filter to run the report collectors
+	// declared in the application manifest
+	ReportFilterApp = "app"
+
 	// RpcAgentUpgradeFunction requests deployed agents to run automatic upgrade operation on leader node
 	RpcAgentUpgradeFunction = "upgrade"
 
@@ -658,6 +697,9 @@ const (
 	HelmLabel = "helm"
 	// AppVersionLabel specifies version of an application in a Helm chart.
 	AppVersionLabel = "app-version"
+
+	// KubernetesVersionLabel is the runtime package label with the Kubernetes version
+	KubernetesVersionLabel = "version-k8s"
 )
 
 var (
@@ -669,12 +711,31 @@ var (
 	EncodingText Format = "text"
 	// EncodingYAML is for the YAML encoding format
 	EncodingYAML Format = "yaml"
+	// EncodingJUnit is for the JUnit XML report format
+	EncodingJUnit Format = "junit"
 	// OutputFormats is a list of recognized output formats for gravity CLI commands
 	OutputFormats = []Format{
 		EncodingText,
 		EncodingJSON,
 		EncodingYAML,
 	}
+
+	// NodeReportFilters lists the report filters collected on each cluster node
+	NodeReportFilters = []string{
+		ReportFilterSystem,
+		ReportFilterHost,
+		ReportFilterLogs,
+		ReportFilterBashHistory,
+		ReportFilterPackages,
+		ReportFilterApp,
+	}
+
+	// ReportFilters lists all recognized cluster report filters
+	ReportFilters = append([]string{
+		ReportFilterKubernetes,
+		ReportFilterCluster,
+		ReportFilterOperations,
+	}, NodeReportFilters...)
 )
 
 var (
@@ -96,6 +96,14 @@ const (
 	// InstallSystemServiceTimeout specifies the maximum time to wait for system install service to complete
 	InstallSystemServiceTimeout = 5 * time.Minute
 
+	// ReleaseHealthTimeout is the maximum time an upgraded application release
+	// has to pass its health gates
+	ReleaseHealthTimeout = "5m"
+
+	// ReleaseHealthCheckInterval specifies how often health gates of an upgraded
+	// application release are evaluated during bake time
+	ReleaseHealthCheckInterval = 10 * time.Second
+
 	// LabelRetryAttempts specifies the maximum number of attempts to label a node
 	LabelRetryAttempts = 10
 
@@ -488,6 +496,14 @@ const (
 	// to prevent accidental deletion
 	GracePeriod = 24 * time.Hour
 
+	// BLOBScrubPeriod specifies how often local BLOBs are re-hashed
+	// to detect corruption
+	BLOBScrubPeriod = 24 * time.Hour
+
+	// BLOBRepairPeriod specifies how often BLOBs are checked for
+	// under-replication
+	BLOBRepairPeriod = time.Minute
+
 	// APIPrefix defines the URL prefix for kubernetes-related queries tunneled from a master node
 	APIPrefix = "/k8s"
 	// APIServerPort defines the port of the kubernetes API server
@@ -513,6 +529,17 @@ const (
 	// LogForwardersConfigMap is the name of the config map that contains log forwarders configuration
 	LogForwardersConfigMap = "log-forwarders"
 
+	// HostConfigConfigMap is the name of the config map that contains cluster host configuration
+	HostConfigConfigMap = "host-config"
+
+	// TrustedCAConfigMap is the name of the config map that contains certificate
+	// authorities trusted by cluster nodes
+	TrustedCAConfigMap = "trusted-ca"
+
+	// ProxyConfigMap is the name of the config map that contains cluster
+	// HTTP proxy configuration
+	ProxyConfigMap = "proxy"
+
 	// GrafanaServiceName is the name of Grafana service
 	GrafanaServiceName = "grafana"
 	// GrafanaServicePort is the port Grafana service is listening on
@@ -531,6 +558,10 @@ const (
 	// to be considered successfull
 	WriteFactor = 1
 
+	// MinReplicas is a default minimum amount of active peers that
+	// should store each object for it to be considered healthy
+	MinReplicas = 1
+
 	// ElectionTerm is a leader election term for multiple gravity instances
 	ElectionTerm = 10 * time.Second
 
@@ -547,6 +578,14 @@ const (
 	// ManifestFileName is the name of the application manifest
 	ManifestFileName = "app.yaml"
 
+	// LockFileName is the name of the file next to the application manifest
+	// with the versions the application dependencies have been resolved to
+	LockFileName = "app.lock"
+
+	// OverlaysDir is the name of the directory next to the application manifest
+	// with the named overlays that produce variants of the application
+	OverlaysDir = "overlays"
+
 	// RegistryDir is the name of the layers directory inside an application tarball
 	RegistryDir = "registry"
 
@@ -560,6 +599,15 @@ const (
 	// SiteStatusCheckInterval is how often local gravity site will invoke app status hook
 	SiteStatusCheckInterval = 1 * time.Minute
 
+	// StatusHistoryInterval is how often local gravity site records the cluster health snapshot
+	StatusHistoryInterval = 5 * time.Minute
+
+	// StatusHistoryRetention is how long the cluster health snapshots are kept
+	StatusHistoryRetention = 24 * time.Hour
+
+	// StatusHistorySince is the default time window of the displayed cluster status history
+	StatusHistorySince = 24 * time.Hour
+
 	// OfflineCheckInterval is how often OpsCenter checks whether its sites are online/offline
 	OfflineCheckInterval = 10 * time.Second
 
@@ -620,6 +668,14 @@ const (
 	// ReportTarball is the name of the gzipped tarball with collected site report information
 	ReportTarball = "report.tar.gz"
 
+	// ReportCollectorTimeout is the default run time limit of an application-defined
+	// report collector
+	ReportCollectorTimeout = time.Minute
+
+	// ReportCollectorMaxSize is the default limit on the size of the data collected
+	// by an application-defined report collector, in bytes
+	ReportCollectorMaxSize = 10 * 1024 * 1024
+
 	// ServiceSubnet is a subnet dedicated to the services in cluster
 	ServiceSubnet = "10.100.0.0/16"
 	// PodSubnet is a subnet dedicated to the pods in the cluster
@@ -662,6 +718,14 @@ const (
 	// VendorPattern is the default app vendor pattern that matches all yaml files
 	VendorPattern = "**/*.yaml"
 
+	// VulnSeverityThreshold is the default minimum severity of vulnerabilities
+	// found in vendored images that fails the build
+	VulnSeverityThreshold = "high"
+
+	// BuildCacheMaxAge is the default time after which unused entries are
+	// pruned from the build cache
+	BuildCacheMaxAge = 30 * 24 * time.Hour
+
 	// LocalDataDir is a default directory where gravity stores its local data
 	LocalDataDir = ".gravity"
 
@@ -774,6 +838,36 @@ const (
 	// second during bandwidth test, which is used in HDR histogram
 	BandwidthMaxSpeedBytes = 100000000000 // 100GB
 
+	// NetworkTestPort is the port for the network latency, packet loss
+	// and path MTU test agents do
+	NetworkTestPort = 4243
+	// NetworkTestDuration is the duration of a network test agents do
+	NetworkTestDuration = 30 * time.Second
+	// NetworkTestProbeCount is the number of probes sent to each server
+	// during the network test
+	NetworkTestProbeCount = 20
+	// NetworkTestProbeInterval is the interval between consecutive network test probes
+	NetworkTestProbeInterval = 50 * time.Millisecond
+	// NetworkTestProbeTimeout is the time to wait for a reply to a network test probe
+	// before considering it lost
+	NetworkTestProbeTimeout = time.Second
+	// NetworkTestMTUProbeAttempts is the number of probes of a given size sent during
+	// the path MTU discovery before the size is considered too large for the path
+	NetworkTestMTUProbeAttempts = 2
+	// NetworkTestMinMTU is the lower bound for the path MTU discovery
+	NetworkTestMinMTU = 576
+	// NetworkTestMaxMTU is the upper bound for the path MTU discovery
+	NetworkTestMaxMTU = 9000
+	// NetworkTestIdleTimeout is the time a network test echo listener is kept up
+	// after the last probe once the test duration has expired
+	NetworkTestIdleTimeout = 5 * time.Second
+	// NetworkTestMaxPacketLoss is the maximum packet loss between servers, in percent,
+	// allowed by the network test if not specified in the manifest
+	NetworkTestMaxPacketLoss = 10.0
+	// VxlanOverhead is the number of bytes the VXLAN encapsulation adds to
+	// the overlay network packets
+	VxlanOverhead = 50
+
 	// Runtime is the name of default runtime application
 	Runtime = "kubernetes"
 
@@ -874,6 +968,10 @@ const (
 	// EndpointsWaitTimeout specifies the timeout for waiting for system service endpoints
 	EndpointsWaitTimeout = 5 * time.Minute
 
+	// NodeHealthWaitTimeout specifies the timeout for waiting for a node
+	// to become healthy after its services have been restarted
+	NodeHealthWaitTimeout = 5 * time.Minute
+
 	// DrainErrorTimeout specifies the timeout for the initial failures of drain operation.
 	// Drain operation might experience transient errors (e.g. api server connect failures)
 	// in which case the timeout defines the maximum time frame to retry such failed attempts.
@@ -962,6 +1060,44 @@ const (
 	ModulesPath = "/etc/modules-load.d/gravity.conf"
 	// SysctlPath is the path to gravity-specific kernel parameters configuration
 	SysctlPath = "/etc/sysctl.d/50-gravity.conf"
+	// TmpfilesPath is the path to gravity-specific systemd-tmpfiles configuration
+	// used to persist sysfs settings across reboots
+	TmpfilesPath = "/etc/tmpfiles.d/gravity.conf"
+	// FstabPath is the path to the static filesystem table
+	FstabPath = "/etc/fstab"
+	// SwapsPath is the path to the list of active swap devices
+	SwapsPath = "/proc/swaps"
+	// TransparentHugepagesPath is the path to the transparent hugepages mode setting
+	TransparentHugepagesPath = "/sys/kernel/mm/transparent_hugepage/enabled"
+
+	// HostConfigModulesPath is the path to the list of kernel modules
+	// from the cluster host configuration loaded at boot
+	HostConfigModulesPath = "/etc/modules-load.d/gravity-hostconfig.conf"
+	// HostConfigSysctlPath is the path to the kernel parameters from the cluster
+	// host configuration. It is ordered after SysctlPath to take precedence
+	HostConfigSysctlPath = "/etc/sysctl.d/60-gravity-hostconfig.conf"
+	// HostConfigDropInFile is the name of the systemd drop-in file with
+	// the resource limits from the cluster host configuration
+	HostConfigDropInFile = "gravity-hostconfig.conf"
+	// TrustedCAFile is the name of the file in the planet share directory
+	// with the certificate authorities trusted by cluster nodes
+	TrustedCAFile = "trusted-ca.pem"
+	// TrustedCAName is the name the trusted certificate authorities are
+	// installed under in the system trust stores
+	TrustedCAName = "gravity-trusted-ca"
+	// PlanetTrustedCADir is the directory with local certificate authorities
+	// inside planet
+	PlanetTrustedCADir = "/usr/local/share/ca-certificates"
+	// ProxyDropInFile is the name of the systemd drop-in file with
+	// the cluster HTTP proxy environment
+	ProxyDropInFile = "gravity-proxy.conf"
+	// ProxyEnvFile is the name of the file in the planet share directory
+	// with the updated planet container environment
+	ProxyEnvFile = "container-environment"
+	// ProcSysPath is the path to the kernel parameters in the proc filesystem
+	ProcSysPath = "/proc/sys"
+	// SysModulePath is the path to the loaded kernel modules in the sys filesystem
+	SysModulePath = "/sys/module"
 
 	// RemoteClusterDialAddr is the "from" address used when dialing remote cluster
 	RemoteClusterDialAddr = "127.0.0.1:3024"
@@ -42,6 +42,9 @@ import (
 	rpcserver "github.com/gravitational/gravity/lib/rpc/server"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/hostconfig"
+	"github.com/gravitational/gravity/lib/system/proxy"
+	"github.com/gravitational/gravity/lib/system/trustedca"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/cenkalti/backoff"
@@ -93,6 +96,8 @@ type PeerConfig struct {
 	Manual bool
 	// OperationID is the ID of existing join operation created via UI
 	OperationID string
+	// AutoFix enables automatic fixing of the failed pre-flight checks
+	AutoFix bool
 }
 
 // CheckAndSetDefaults checks the parameters and autodetects some defaults
@@ -201,6 +206,10 @@ func (p *Peer) dialSite(addr string) (*operationContext, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	err = p.applyHostConfig(operator, *cluster)
+	if err != nil {
+		return nil, utils.Abort(err)
+	}
 	err = p.runLocalChecks(*cluster, *installOp)
 	if err != nil {
 		return nil, utils.Abort(err) // stop retrying on failed checks
@@ -328,6 +337,57 @@ func (p *Peer) checkAndSetServerProfile(app ops.Application) error {
 		"specified node role %q is not defined in the application manifest", p.Role))
 }
 
+// applyHostConfig applies the cluster host configuration for the node profile
+func (p *Peer) applyHostConfig(operator ops.Operator, cluster ops.Site) error {
+	config, err := operator.GetHostConfig(cluster.Key())
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	p.PrintStep("Applying cluster host configuration")
+	err = hostconfig.Apply(p.Context, config.ForProfile(p.Role), utils.NewNopProgress())
+	return trace.Wrap(err, "failed to apply cluster host configuration")
+}
+
+// applyTrustedCA installs the cluster trusted certificate authorities on this node
+func (p *Peer) applyTrustedCA(operator ops.Operator, cluster ops.Site) error {
+	ca, err := operator.GetTrustedCA(cluster.Key())
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	p.sendMessage("Installing trusted certificate authorities")
+	err = trustedca.Apply(p.Context, []byte(ca.GetCertificates()), utils.NewNopProgress())
+	return trace.Wrap(err)
+}
+
+// applyProxy configures the cluster proxy environment on this node
+func (p *Peer) applyProxy(operator ops.Operator, key ops.SiteKey) error {
+	config, err := operator.GetProxy(key)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	// the cluster state now includes this node
+	cluster, err := operator.GetSite(key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env, err := ops.GetProxyEnv(operator, *cluster, config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	p.sendMessage("Configuring proxy environment")
+	err = proxy.Apply(p.Context, env, utils.NewNopProgress())
+	return trace.Wrap(err)
+}
+
 // runLocalChecks makes sure node satisfies system requirements
 func (p *Peer) runLocalChecks(cluster ops.Site, installOperation ops.SiteOperation) error {
 	return checks.RunLocalChecks(checks.LocalChecksRequest{
@@ -340,7 +400,7 @@ func (p *Peer) runLocalChecks(cluster ops.Site, installOperation ops.SiteOperati
 			DnsAddrs:  cluster.DNSConfig.Addrs,
 			DnsPort:   int32(cluster.DNSConfig.Port),
 		},
-		AutoFix: true,
+		AutoFix: p.AutoFix,
 	})
 }
 
@@ -761,6 +821,17 @@ After all phases have completed successfully, complete the operation using "grav
 			p.Errorf("Failed to execute plan: %v.",
 				trace.DebugReport(err))
 		}
+		if fsmErr == nil {
+			// trusted certificate authorities are installed once planet is running
+			if err := p.applyTrustedCA(ctx.Operator, ctx.Cluster); err != nil {
+				p.Warnf("Failed to install trusted certificate authorities: %v.",
+					trace.DebugReport(err))
+			}
+			if err := p.applyProxy(ctx.Operator, ctx.Cluster.Key()); err != nil {
+				p.Warnf("Failed to configure proxy environment: %v.",
+					trace.DebugReport(err))
+			}
+		}
 		err := fsm.Complete(fsmErr)
 		if err != nil {
 			p.Errorf("Failed to complete operation: %v.",
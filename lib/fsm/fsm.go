@@ -259,6 +259,35 @@ func (f *FSM) RollbackPhase(ctx context.Context, p Params) error {
 	return nil
 }
 
+// RollbackPlan rolls back all phases of the plan that have been executed
+// in the reverse order of execution.
+// Phases assigned to other nodes are rolled back using the remote runner.
+// Returns the IDs of the phases that have been rolled back
+func (f *FSM) RollbackPlan(ctx context.Context, progress utils.Progress, force bool) (rolledBack []string, err error) {
+	plan, err := f.GetPlan()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	phases := FlattenPlan(plan)
+	for i := len(phases) - 1; i >= 0; i-- {
+		phase := *phases[i]
+		if phase.HasSubphases() || phase.IsUnstarted() || phase.IsRolledBack() {
+			continue
+		}
+		f.Debugf("Rolling back phase %q.", phase.ID)
+		err := f.rollbackPhaseOnServer(ctx, Params{
+			PhaseID:  phase.ID,
+			Progress: progress,
+			Force:    force,
+		}, phase)
+		if err != nil {
+			return rolledBack, trace.Wrap(err, "failed to rollback phase %q", phase.ID)
+		}
+		rolledBack = append(rolledBack, phase.ID)
+	}
+	return rolledBack, nil
+}
+
 // SetPreExec sets the hook that's called before phase execution
 func (f *FSM) SetPreExec(fn PhaseHookFn) {
 	f.preExecFn = fn
@@ -495,6 +524,56 @@ func (f *FSM) rollbackPhase(ctx context.Context, p Params, phase storage.Operati
 	return nil
 }
 
+// rollbackPhaseOnServer rolls back the specified leaf phase either locally
+// or on the remote server the phase has been executed on
+func (f *FSM) rollbackPhaseOnServer(ctx context.Context, p Params, phase storage.OperationPhase) error {
+	var execServer *storage.Server
+	if phase.Data != nil {
+		if phase.Data.ExecServer != nil {
+			execServer = phase.Data.ExecServer
+		} else {
+			execServer = phase.Data.Server
+		}
+	}
+
+	var err error
+	execWhere := CanRunLocally
+	if execServer != nil {
+		execWhere, err = canExecuteOnServer(ctx, *execServer, f.Runner, f.FieldLogger)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
+	switch execWhere {
+	case CanRunLocally:
+		p.Progress.NextStep("Rolling back %q", phase.ID)
+		return trace.Wrap(f.rollbackPhase(ctx, p, phase))
+
+	case CanRunRemotely:
+		p.Progress.NextStep("Rolling back %q on remote node %v", phase.ID,
+			execServer.Hostname)
+		err = f.Runner.Run(ctx, *execServer, "rollback", "--phase", phase.ID,
+			fmt.Sprintf("--force=%v", p.Force))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		// mark the phase as rolled back in the local database as
+		// the changes might not be synchronized back to us
+		return trace.Wrap(f.ChangePhaseState(ctx, StateChange{
+			Phase: phase.ID,
+			State: storage.OperationPhaseStateRolledBack,
+		}))
+
+	case ShouldRunRemotely:
+		return trace.NotFound("no agent is running on node %v, please rollback phase %q locally on that node",
+			serverName(*execServer), phase.ID)
+
+	default:
+		return trace.BadParameter("unsupported execution location: %v", execWhere)
+	}
+}
+
 // prerequisitesComplete checks if specified phase can be executed in the
 // provided plan
 func (f *FSM) prerequisitesComplete(phaseID string) error {
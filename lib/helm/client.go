@@ -39,8 +39,9 @@ import (
 
 // Client is the Helm client.
 type Client struct {
-	client helm.Interface
-	tunnel *kube.Tunnel
+	client     helm.Interface
+	tunnel     *kube.Tunnel
+	kubeClient kubernetes.Interface
 }
 
 // ClientConfig is the Helm client configuration.
@@ -64,8 +65,9 @@ func NewClient(conf ClientConfig) (*Client, error) {
 		helm.Host(fmt.Sprintf("127.0.0.1:%d", tunnel.Local)),
 	}
 	return &Client{
-		client: helm.NewClient(options...),
-		tunnel: tunnel,
+		client:     helm.NewClient(options...),
+		tunnel:     tunnel,
+		kubeClient: kubeClient,
 	}, nil
 }
 
@@ -171,6 +173,28 @@ func (c *Client) Upgrade(p UpgradeParameters) (*Release, error) {
 	return fromHelm(response.GetRelease()), nil
 }
 
+// Diff renders the upgrade chart with the provided values and returns
+// the changes the upgrade would make to the objects of the release.
+func (c *Client) Diff(p UpgradeParameters) (*Diff, error) {
+	response, err := c.client.ReleaseContent(p.Release)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	release := response.GetRelease()
+	rendered, err := Render(RenderParameters{
+		Path:      p.Path,
+		Values:    p.Values,
+		Set:       p.Set,
+		Name:      release.GetName(),
+		Namespace: release.GetNamespace(),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return DiffManifests(release.GetName(), release.GetNamespace(),
+		release.GetManifest(), string(rendered))
+}
+
 // RollbackParameters defines release rollback parameters.
 type RollbackParameters struct {
 	// Release is a name of the release to rollback.
@@ -0,0 +1,330 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Diff describes the changes an upgrade makes to the objects of a release.
+type Diff struct {
+	// Release is the name of the release.
+	Release string `json:"release"`
+	// Objects lists the objects that are added, removed or changed.
+	Objects []ObjectDiff `json:"objects"`
+}
+
+// IsEmpty returns true if the upgrade does not change any objects.
+func (d Diff) IsEmpty() bool {
+	return len(d.Objects) == 0
+}
+
+// WriteText writes a human-readable representation of the diff to w.
+func (d Diff) WriteText(w io.Writer) error {
+	var buf bytes.Buffer
+	if d.IsEmpty() {
+		fmt.Fprintf(&buf, "Release %v is up-to-date.\n", d.Release)
+	}
+	for _, object := range d.Objects {
+		fmt.Fprintf(&buf, "%v %v\n", object.Action.symbol(), object)
+		for _, field := range object.Fields {
+			switch field.Action {
+			case DiffActionAdded:
+				fmt.Fprintf(&buf, "    + %v: %v\n", field.Path, formatValue(field.New))
+			case DiffActionRemoved:
+				fmt.Fprintf(&buf, "    - %v: %v\n", field.Path, formatValue(field.Old))
+			case DiffActionChanged:
+				fmt.Fprintf(&buf, "    ~ %v: %v -> %v\n", field.Path,
+					formatValue(field.Old), formatValue(field.New))
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return trace.Wrap(err)
+}
+
+// ObjectDiff describes the change to a single Kubernetes object.
+type ObjectDiff struct {
+	// Kind is the object kind.
+	Kind string `json:"kind"`
+	// Namespace is the object namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the object name.
+	Name string `json:"name"`
+	// Action specifies whether the object is added, removed or changed.
+	Action DiffAction `json:"action"`
+	// Fields lists the changed fields of a changed object.
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// String returns a textual representation of the object reference.
+func (d ObjectDiff) String() string {
+	if d.Namespace == "" {
+		return fmt.Sprintf("%v %v", d.Kind, d.Name)
+	}
+	return fmt.Sprintf("%v %v/%v", d.Kind, d.Namespace, d.Name)
+}
+
+// FieldDiff describes the change to a single object field.
+type FieldDiff struct {
+	// Path is the dot-separated path to the field, e.g. spec.replicas.
+	Path string `json:"path"`
+	// Action specifies whether the field is added, removed or changed.
+	Action DiffAction `json:"action"`
+	// Old is the value of the field in the current release.
+	Old interface{} `json:"old,omitempty"`
+	// New is the value of the field after the upgrade.
+	New interface{} `json:"new,omitempty"`
+}
+
+// DiffAction describes the type of change.
+type DiffAction string
+
+const (
+	// DiffActionAdded means the object or field is added.
+	DiffActionAdded DiffAction = "added"
+	// DiffActionRemoved means the object or field is removed.
+	DiffActionRemoved DiffAction = "removed"
+	// DiffActionChanged means the object or field is changed.
+	DiffActionChanged DiffAction = "changed"
+)
+
+func (a DiffAction) symbol() string {
+	switch a {
+	case DiffActionAdded:
+		return "+"
+	case DiffActionRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// DiffManifests computes the structured diff between the current and
+// the new rendered manifests of the release.
+//
+// Objects without a namespace are assumed to belong to the namespace
+// of the release.
+func DiffManifests(release, namespace, current, rendered string) (*Diff, error) {
+	currentObjects, err := parseManifest(current, namespace)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse the current release manifest")
+	}
+	newObjects, err := parseManifest(rendered, namespace)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse the rendered manifest")
+	}
+	diff := Diff{Release: release}
+	for key, object := range newObjects {
+		existing, ok := currentObjects[key]
+		if !ok {
+			diff.Objects = append(diff.Objects, key.diff(DiffActionAdded, nil))
+			continue
+		}
+		var fields []FieldDiff
+		diffValues("", existing, object, &fields)
+		if key.kind == secretKind {
+			redactSecretFields(fields)
+		}
+		if len(fields) != 0 {
+			diff.Objects = append(diff.Objects, key.diff(DiffActionChanged, fields))
+		}
+	}
+	for key := range currentObjects {
+		if _, ok := newObjects[key]; !ok {
+			diff.Objects = append(diff.Objects, key.diff(DiffActionRemoved, nil))
+		}
+	}
+	sort.Slice(diff.Objects, func(i, j int) bool {
+		return diff.Objects[i].String() < diff.Objects[j].String()
+	})
+	return &diff, nil
+}
+
+// diffValues recursively compares the old and new values at the specified path
+// and appends the differences to fields
+func diffValues(path string, old, new interface{}, fields *[]FieldDiff) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		for _, key := range sortedKeys(oldMap, newMap) {
+			oldValue, inOld := oldMap[key]
+			newValue, inNew := newMap[key]
+			fieldPath := joinPath(path, key)
+			switch {
+			case !inOld:
+				*fields = append(*fields, FieldDiff{Path: fieldPath, Action: DiffActionAdded, New: newValue})
+			case !inNew:
+				*fields = append(*fields, FieldDiff{Path: fieldPath, Action: DiffActionRemoved, Old: oldValue})
+			default:
+				diffValues(fieldPath, oldValue, newValue, fields)
+			}
+		}
+		return
+	}
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if oldIsList && newIsList && len(oldList) == len(newList) {
+		for i := range oldList {
+			diffValues(fmt.Sprintf("%v[%v]", path, i), oldList[i], newList[i], fields)
+		}
+		return
+	}
+	if !reflect.DeepEqual(old, new) {
+		*fields = append(*fields, FieldDiff{Path: path, Action: DiffActionChanged, Old: old, New: new})
+	}
+}
+
+// redactSecretFields replaces the values of the Secret data fields
+// so the secret contents are never displayed
+func redactSecretFields(fields []FieldDiff) {
+	for i, field := range fields {
+		if !isSecretDataPath(field.Path) {
+			continue
+		}
+		fields[i].Old = redactValue(field.Old)
+		fields[i].New = redactValue(field.New)
+	}
+}
+
+func isSecretDataPath(path string) bool {
+	for _, field := range []string{"data", "stringData"} {
+		if path == field || strings.HasPrefix(path, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue returns the specified value with all scalar values replaced
+// with a placeholder. The structure of maps is preserved so that the keys
+// that are added or removed are still visible
+func redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			redacted[key] = redactValue(v)
+		}
+		return redacted
+	default:
+		return redactedValue
+	}
+}
+
+// parseManifest decodes all objects from the specified multi-document manifest
+func parseManifest(manifest, namespace string) (map[objectKey]interface{}, error) {
+	objects := make(map[objectKey]interface{})
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), decoderBufferSize)
+	for {
+		var object map[string]interface{}
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if len(object) == 0 {
+			continue
+		}
+		key := newObjectKey(object, namespace)
+		// Status is not part of the desired object state
+		delete(object, "status")
+		objects[key] = object
+	}
+	return objects, nil
+}
+
+func newObjectKey(object map[string]interface{}, namespace string) objectKey {
+	key := objectKey{namespace: namespace}
+	key.kind, _ = object["kind"].(string)
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		key.name, _ = metadata["name"].(string)
+		if ns, ok := metadata["namespace"].(string); ok && ns != "" {
+			key.namespace = ns
+		}
+	}
+	return key
+}
+
+// objectKey uniquely identifies an object within a release
+type objectKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+func (k objectKey) diff(action DiffAction, fields []FieldDiff) ObjectDiff {
+	return ObjectDiff{
+		Kind:      k.kind,
+		Namespace: k.namespace,
+		Name:      k.name,
+		Action:    action,
+		Fields:    fields,
+	}
+}
+
+func sortedKeys(maps ...map[string]interface{}) (keys []string) {
+	seen := make(map[string]bool)
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return fmt.Sprintf("%v.%v", path, key)
+}
+
+func formatValue(value interface{}) string {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(bytes)
+}
+
+// decoderBufferSize is the size of the buffer used to decode manifests
+const decoderBufferSize = 4096
+
+const (
+	// secretKind is the kind of Kubernetes Secret objects
+	secretKind = "Secret"
+	// redactedValue replaces the values of Secret data in the diff
+	redactedValue = "(redacted)"
+)
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/compare"
+
+	"gopkg.in/check.v1"
+)
+
+func TestHelm(t *testing.T) { check.TestingT(t) }
+
+type DiffSuite struct{}
+
+var _ = check.Suite(&DiffSuite{})
+
+func (s *DiffSuite) TestDiffsManifests(c *check.C) {
+	current := `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:1.0.0
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: legacy
+data:
+  key: value
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+`
+	rendered := `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    tier: frontend
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:2.0.0
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+  namespace: kube-system
+`
+	diff, err := DiffManifests("web", "default", current, rendered)
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, diff, &Diff{
+		Release: "web",
+		Objects: []ObjectDiff{
+			{Kind: "ConfigMap", Namespace: "default", Name: "legacy", Action: DiffActionRemoved},
+			{
+				Kind:      "Deployment",
+				Namespace: "default",
+				Name:      "web",
+				Action:    DiffActionChanged,
+				Fields: []FieldDiff{
+					{Path: "metadata.labels", Action: DiffActionAdded, New: map[string]interface{}{"tier": "frontend"}},
+					{Path: "spec.replicas", Action: DiffActionChanged, Old: float64(1), New: float64(2)},
+					{Path: "spec.template.spec.containers[0].image", Action: DiffActionChanged, Old: "web:1.0.0", New: "web:2.0.0"},
+				},
+			},
+			{Kind: "Secret", Namespace: "kube-system", Name: "credentials", Action: DiffActionAdded},
+		},
+	})
+}
+
+func (s *DiffSuite) TestEmptyDiff(c *check.C) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`
+	diff, err := DiffManifests("config", "default", manifest, manifest)
+	c.Assert(err, check.IsNil)
+	c.Assert(diff.IsEmpty(), check.Equals, true)
+}
+
+func (s *DiffSuite) TestRedactsSecretData(c *check.C) {
+	current := `apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+  labels:
+    version: "1"
+data:
+  password: b2xkLXBhc3N3b3Jk
+stringData:
+  token: old-token
+`
+	rendered := `apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+  labels:
+    version: "2"
+data:
+  password: bmV3LXBhc3N3b3Jk
+  username: YWRtaW4=
+`
+	diff, err := DiffManifests("web", "default", current, rendered)
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, diff, &Diff{
+		Release: "web",
+		Objects: []ObjectDiff{
+			{
+				Kind:      "Secret",
+				Namespace: "default",
+				Name:      "credentials",
+				Action:    DiffActionChanged,
+				Fields: []FieldDiff{
+					{Path: "data.password", Action: DiffActionChanged, Old: redactedValue, New: redactedValue},
+					{Path: "data.username", Action: DiffActionAdded, New: redactedValue},
+					{Path: "metadata.labels.version", Action: DiffActionChanged, Old: "1", New: "2"},
+					{Path: "stringData", Action: DiffActionRemoved, Old: map[string]interface{}{"token": redactedValue}},
+				},
+			},
+		},
+	})
+
+	var buf bytes.Buffer
+	c.Assert(diff.WriteText(&buf), check.IsNil)
+	for _, secret := range []string{"b2xkLXBhc3N3b3Jk", "bmV3LXBhc3N3b3Jk", "YWRtaW4=", "old-token"} {
+		c.Assert(strings.Contains(buf.String(), secret), check.Equals, false,
+			check.Commentf("Diff should not reveal %q:\n%s", secret, buf.String()))
+	}
+}
@@ -41,6 +41,10 @@ type RenderParameters struct {
 	Values []string
 	// Set is a list of values set on the CLI.
 	Set []string
+	// Name is an optional release name.
+	Name string
+	// Namespace is an optional release namespace.
+	Namespace string
 }
 
 // RenderHelm renders templates of a provided Helm chart.
@@ -59,7 +63,9 @@ func Render(p RenderParameters) ([]byte, error) {
 	}
 	options := renderutil.Options{
 		ReleaseOptions: chartutil.ReleaseOptions{
-			Time: timeconv.Now(),
+			Name:      p.Name,
+			Namespace: p.Namespace,
+			Time:      timeconv.Now(),
 		},
 	}
 	renderedTemplates, err := renderutil.Render(ch, config, options)
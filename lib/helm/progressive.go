@@ -0,0 +1,331 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HealthGate is a health check an upgraded release has to pass.
+// It returns an error if the release is not healthy.
+type HealthGate func(ctx context.Context) error
+
+// ProgressiveUpgradeParameters defines health-gated release upgrade parameters.
+type ProgressiveUpgradeParameters struct {
+	// UpgradeParameters specifies the release upgrade.
+	UpgradeParameters
+	// Stages lists the rollout stages that precede the full upgrade.
+	// For each stage, the release is upgraded with the stage values set
+	// on top of the upgrade values and has to pass the health gates before
+	// the rollout proceeds to the next stage.
+	Stages []UpgradeStage
+	// HealthGates is a list of additional health checks the release
+	// has to pass after each stage. Workload readiness is always checked.
+	HealthGates []HealthGate
+	// Timeout is the maximum time the release has to become healthy
+	// after each stage.
+	Timeout time.Duration
+	// BakeTime is the time the release has to stay healthy after each
+	// stage once it has become healthy.
+	BakeTime time.Duration
+	// Progress is an optional callback that reports the upgrade progress.
+	Progress func(format string, args ...interface{})
+}
+
+// UpgradeStage describes a single stage of a progressive upgrade.
+type UpgradeStage struct {
+	// Name is the stage name.
+	Name string
+	// Set is a list of values set for this stage in addition
+	// to the upgrade values.
+	Set []string
+}
+
+// ParseUpgradeStage parses the upgrade stage from the specified string
+// in the name:key1=val1,key2=val2 format.
+func ParseUpgradeStage(spec string) (*UpgradeStage, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		return nil, trace.BadParameter("upgrade stage %q has no name, expected name:key=value[,key=value]", spec)
+	}
+	stage := UpgradeStage{Name: name}
+	if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+		stage.Set = []string{strings.TrimSpace(parts[1])}
+	}
+	return &stage, nil
+}
+
+// ProgressiveUpgrade upgrades a release in stages, evaluating health gates
+// after each stage:
+//
+//   - for each configured stage and the final full upgrade, the release is
+//     upgraded and has to pass all health gates within the timeout
+//   - the release is then observed for the bake time and has to keep passing the gates
+//
+// The rollout only proceeds to the next stage if the current stage is healthy.
+// If any of the stages fails, the release is rolled back to the revision
+// it had before the upgrade.
+func (c *Client) ProgressiveUpgrade(ctx context.Context, p ProgressiveUpgradeParameters) (*Release, error) {
+	return progressiveUpgrade(ctx, c, p)
+}
+
+func progressiveUpgrade(ctx context.Context, c releaseUpgrader, p ProgressiveUpgradeParameters) (*Release, error) {
+	if p.Progress == nil {
+		p.Progress = logrus.Infof
+	}
+	previous, err := c.Get(p.Release)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	gates := append([]HealthGate{c.workloadsReady(p.Release)}, p.HealthGates...)
+	stages := make([]UpgradeStage, 0, len(p.Stages)+1)
+	stages = append(stages, p.Stages...)
+	stages = append(stages, UpgradeStage{Name: fullUpgradeStage})
+	var release *Release
+	for i, stage := range stages {
+		p.Progress("Upgrading release %v, stage %v (%v of %v)", p.Release, stage.Name, i+1, len(stages))
+		release, err = c.Upgrade(stage.upgradeParameters(p.UpgradeParameters))
+		if err != nil {
+			// a failed upgrade leaves a failed release revision behind
+			// so the release is rolled back even if the first stage fails
+			return nil, rollbackUnhealthy(c, *previous, p, trace.BadParameter(
+				"release %v failed to upgrade at stage %v: %v", p.Release, stage.Name, trace.Unwrap(err)))
+		}
+		err = waitForStage(ctx, p, stage, gates)
+		if err != nil {
+			return nil, rollbackUnhealthy(c, *previous, p, err)
+		}
+	}
+	return release, nil
+}
+
+// waitForStage waits for the release to pass the health gates after the
+// specified stage and to stay healthy for the bake time
+func waitForStage(ctx context.Context, p ProgressiveUpgradeParameters, stage UpgradeStage, gates []HealthGate) error {
+	check := func() error {
+		return trace.Wrap(evaluateGates(ctx, gates))
+	}
+	p.Progress("Waiting up to %v for release %v to become healthy", p.Timeout, p.Release)
+	err := utils.RetryFor(ctx, p.Timeout, check)
+	if err != nil {
+		return trace.BadParameter("release %v has not become healthy within %v at stage %v: %v",
+			p.Release, p.Timeout, stage.Name, trace.Unwrap(err))
+	}
+	if p.BakeTime > 0 {
+		p.Progress("Observing release %v for %v", p.Release, p.BakeTime)
+		err = bake(ctx, p.BakeTime, defaults.ReleaseHealthCheckInterval, check)
+		if err != nil {
+			return trace.BadParameter("release %v has become unhealthy during bake time at stage %v: %v",
+				p.Release, stage.Name, trace.Unwrap(err))
+		}
+	}
+	return nil
+}
+
+// upgradeParameters returns the release upgrade parameters for this stage
+func (r UpgradeStage) upgradeParameters(p UpgradeParameters) UpgradeParameters {
+	set := make([]string, 0, len(p.Set)+len(r.Set))
+	set = append(set, p.Set...)
+	p.Set = append(set, r.Set...)
+	return p
+}
+
+// rollbackUnhealthy rolls back the release to the previous revision after
+// it has failed the health gates with the specified error
+func rollbackUnhealthy(c releaseUpgrader, previous Release, p ProgressiveUpgradeParameters, healthErr error) error {
+	p.Progress("Rolling back release %v to revision %v", previous.Name, previous.Revision)
+	_, err := c.Rollback(RollbackParameters{
+		Release:  previous.Name,
+		Revision: previous.Revision,
+	})
+	if err != nil {
+		return trace.BadParameter("%v; rollback to revision %v failed: %v",
+			trace.Unwrap(healthErr), previous.Revision, trace.Unwrap(err))
+	}
+	return trace.BadParameter("%v; release has been rolled back to revision %v",
+		trace.Unwrap(healthErr), previous.Revision)
+}
+
+// releaseUpgrader upgrades and rolls back releases
+type releaseUpgrader interface {
+	// Get returns the release with the specified name
+	Get(name string) (*Release, error)
+	// Upgrade upgrades a release
+	Upgrade(UpgradeParameters) (*Release, error)
+	// Rollback rolls back a release to the specified revision
+	Rollback(RollbackParameters) (*Release, error)
+	// workloadsReady returns a health gate that checks the workloads of the release
+	workloadsReady(name string) HealthGate
+}
+
+// bake periodically evaluates the check until the bake time elapses.
+// Returns the first error returned by the check
+func bake(ctx context.Context, bakeTime, interval time.Duration, check func() error) error {
+	timer := time.NewTimer(bakeTime)
+	defer timer.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return trace.Wrap(check())
+		case <-ticker.C:
+			if err := check(); err != nil {
+				return trace.Wrap(err)
+			}
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+	}
+}
+
+// evaluateGates returns the first error returned by the specified health gates
+func evaluateGates(ctx context.Context, gates []HealthGate) error {
+	for _, gate := range gates {
+		if err := gate(ctx); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// workloadsReady returns a health gate that checks that all deployments,
+// statefulsets and daemonsets of the release have been rolled out and are ready
+func (c *Client) workloadsReady(name string) HealthGate {
+	return func(ctx context.Context) error {
+		response, err := c.client.ReleaseContent(name)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		release := response.GetRelease()
+		objects, err := parseManifest(release.GetManifest(), release.GetNamespace())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for key := range objects {
+			if err := checkWorkloadReady(c.kubeClient, key); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		return nil
+	}
+}
+
+func checkWorkloadReady(client kubernetes.Interface, key objectKey) error {
+	switch key.kind {
+	case "Deployment":
+		deployment, err := client.AppsV1().Deployments(key.namespace).Get(key.name, metav1.GetOptions{})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(deploymentReady(*deployment))
+	case "StatefulSet":
+		statefulSet, err := client.AppsV1().StatefulSets(key.namespace).Get(key.name, metav1.GetOptions{})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(statefulSetReady(*statefulSet))
+	case "DaemonSet":
+		daemonSet, err := client.AppsV1().DaemonSets(key.namespace).Get(key.name, metav1.GetOptions{})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(daemonSetReady(*daemonSet))
+	}
+	return nil
+}
+
+func deploymentReady(deployment appsv1.Deployment) error {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	if status.ObservedGeneration < deployment.Generation ||
+		status.UpdatedReplicas != replicas || status.AvailableReplicas != replicas {
+		return trace.BadParameter("deployment %v/%v: %v of %v replicas updated, %v available",
+			deployment.Namespace, deployment.Name, status.UpdatedReplicas, replicas, status.AvailableReplicas)
+	}
+	return nil
+}
+
+// statefulSetReady returns an error if the statefulset has not updated
+// the expected number of replicas or not all of its replicas are ready.
+//
+// With a partitioned rolling update, only the replicas with an ordinal
+// at or above the partition are updated, and with the OnDelete strategy
+// no replicas are updated by the controller, so the statefulset is not
+// expected to converge to the update revision in these cases
+func statefulSetReady(statefulSet appsv1.StatefulSet) error {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	updated := expectedUpdatedReplicas(statefulSet.Spec.UpdateStrategy, replicas)
+	status := statefulSet.Status
+	if status.ObservedGeneration < statefulSet.Generation ||
+		status.ReadyReplicas != replicas || status.UpdatedReplicas < updated {
+		return trace.BadParameter("statefulset %v/%v: %v of %v replicas updated, %v of %v ready",
+			statefulSet.Namespace, statefulSet.Name, status.UpdatedReplicas, updated,
+			status.ReadyReplicas, replicas)
+	}
+	return nil
+}
+
+// expectedUpdatedReplicas returns the number of replicas the statefulset
+// controller updates with the specified update strategy
+func expectedUpdatedReplicas(strategy appsv1.StatefulSetUpdateStrategy, replicas int32) int32 {
+	if strategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return 0
+	}
+	var partition int32
+	if strategy.RollingUpdate != nil && strategy.RollingUpdate.Partition != nil {
+		partition = *strategy.RollingUpdate.Partition
+	}
+	if partition >= replicas {
+		return 0
+	}
+	return replicas - partition
+}
+
+func daemonSetReady(daemonSet appsv1.DaemonSet) error {
+	status := daemonSet.Status
+	if status.ObservedGeneration < daemonSet.Generation ||
+		status.UpdatedNumberScheduled != status.DesiredNumberScheduled ||
+		status.NumberReady != status.DesiredNumberScheduled {
+		return trace.BadParameter("daemonset %v/%v: %v of %v pods updated, %v ready",
+			daemonSet.Namespace, daemonSet.Name, status.UpdatedNumberScheduled,
+			status.DesiredNumberScheduled, status.NumberReady)
+	}
+	return nil
+}
+
+// fullUpgradeStage is the name of the final progressive upgrade stage
+// that upgrades the release with the upgrade values only
+const fullUpgradeStage = "full"
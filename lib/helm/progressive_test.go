@@ -0,0 +1,302 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ProgressiveSuite struct{}
+
+var _ = check.Suite(&ProgressiveSuite{})
+
+func (s *ProgressiveSuite) TestDeploymentReady(c *check.C) {
+	replicas := int32(2)
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  2,
+		},
+	}
+	c.Assert(deploymentReady(deployment), check.NotNil)
+
+	deployment.Status.UpdatedReplicas = 2
+	c.Assert(deploymentReady(deployment), check.IsNil)
+
+	deployment.Generation = 3
+	c.Assert(deploymentReady(deployment), check.NotNil)
+}
+
+func (s *ProgressiveSuite) TestStatefulSetReady(c *check.C) {
+	replicas := int32(3)
+	statefulSet := appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status: appsv1.StatefulSetStatus{
+			ReadyReplicas:   3,
+			UpdatedReplicas: 2,
+			CurrentRevision: "db-1",
+			UpdateRevision:  "db-2",
+		},
+	}
+	c.Assert(statefulSetReady(statefulSet), check.NotNil)
+
+	statefulSet.Status.UpdatedReplicas = 3
+	c.Assert(statefulSetReady(statefulSet), check.IsNil)
+
+	statefulSet.Status.ReadyReplicas = 2
+	c.Assert(statefulSetReady(statefulSet), check.NotNil)
+}
+
+func (s *ProgressiveSuite) TestPartitionedStatefulSetReady(c *check.C) {
+	replicas := int32(3)
+	partition := int32(2)
+	statefulSet := appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+					Partition: &partition,
+				},
+			},
+		},
+		Status: appsv1.StatefulSetStatus{
+			ReadyReplicas:   3,
+			CurrentRevision: "db-1",
+			UpdateRevision:  "db-2",
+		},
+	}
+	c.Assert(statefulSetReady(statefulSet), check.NotNil)
+
+	// the canary replica above the partition has been updated while
+	// the statefulset never converges to the update revision
+	statefulSet.Status.UpdatedReplicas = 1
+	c.Assert(statefulSetReady(statefulSet), check.IsNil)
+
+	statefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+		Type: appsv1.OnDeleteStatefulSetStrategyType,
+	}
+	statefulSet.Status.UpdatedReplicas = 0
+	c.Assert(statefulSetReady(statefulSet), check.IsNil)
+}
+
+func (s *ProgressiveSuite) TestDaemonSetReady(c *check.C) {
+	daemonSet := appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "kube-system"},
+		Status: appsv1.DaemonSetStatus{
+			DesiredNumberScheduled: 3,
+			UpdatedNumberScheduled: 3,
+			NumberReady:            2,
+		},
+	}
+	c.Assert(daemonSetReady(daemonSet), check.NotNil)
+
+	daemonSet.Status.NumberReady = 3
+	c.Assert(daemonSetReady(daemonSet), check.IsNil)
+}
+
+func (s *ProgressiveSuite) TestBakeFailsOnUnhealthyRelease(c *check.C) {
+	var checks int
+	err := bake(context.TODO(), time.Second, 10*time.Millisecond, func() error {
+		checks++
+		if checks == 3 {
+			return trace.BadParameter("unhealthy")
+		}
+		return nil
+	})
+	c.Assert(err, check.ErrorMatches, "unhealthy")
+	c.Assert(checks, check.Equals, 3)
+}
+
+func (s *ProgressiveSuite) TestBakeSucceedsOnHealthyRelease(c *check.C) {
+	var checks int
+	err := bake(context.TODO(), 50*time.Millisecond, 10*time.Millisecond, func() error {
+		checks++
+		return nil
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(checks > 1, check.Equals, true)
+}
+
+func (s *ProgressiveSuite) TestUpgradesReleaseInStages(c *check.C) {
+	upgrader := newTestUpgrader()
+	release, err := progressiveUpgrade(context.TODO(), upgrader, ProgressiveUpgradeParameters{
+		UpgradeParameters: UpgradeParameters{Release: "web", Set: []string{"image.tag=2.0"}},
+		Stages: []UpgradeStage{
+			{Name: "canary", Set: []string{"canary.weight=10"}},
+			{Name: "half", Set: []string{"canary.weight=50"}},
+		},
+		HealthGates: []HealthGate{upgrader.gate("status", nil)},
+		Progress:    c.Logf,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(release.Revision, check.Equals, 4)
+	c.Assert(upgrader.events, check.DeepEquals, []string{
+		"upgrade image.tag=2.0,canary.weight=10", "gate workloads", "gate status",
+		"upgrade image.tag=2.0,canary.weight=50", "gate workloads", "gate status",
+		"upgrade image.tag=2.0", "gate workloads", "gate status",
+	})
+}
+
+func (s *ProgressiveSuite) TestUpgradesReleaseInSingleStageByDefault(c *check.C) {
+	upgrader := newTestUpgrader()
+	_, err := progressiveUpgrade(context.TODO(), upgrader, ProgressiveUpgradeParameters{
+		UpgradeParameters: UpgradeParameters{Release: "web"},
+		Progress:          c.Logf,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(upgrader.events, check.DeepEquals, []string{"upgrade ", "gate workloads"})
+}
+
+func (s *ProgressiveSuite) TestAbortsRolloutOnUnhealthyStage(c *check.C) {
+	upgrader := newTestUpgrader()
+	_, err := progressiveUpgrade(context.TODO(), upgrader, ProgressiveUpgradeParameters{
+		UpgradeParameters: UpgradeParameters{Release: "web"},
+		Stages:            []UpgradeStage{{Name: "canary", Set: []string{"canary.weight=10"}}},
+		HealthGates:       []HealthGate{upgrader.gate("status", trace.BadParameter("canary is failing"))},
+		Progress:          c.Logf,
+	})
+	c.Assert(err, check.ErrorMatches,
+		"release web has not become healthy within 0s at stage canary: canary is failing; "+
+			"release has been rolled back to revision 1")
+	c.Assert(upgrader.events, check.DeepEquals, []string{
+		"upgrade canary.weight=10", "gate workloads", "gate status", "rollback 1",
+	}, check.Commentf("Rollout should not proceed past the unhealthy stage."))
+}
+
+func (s *ProgressiveSuite) TestRollsBackOnFailedStageUpgrade(c *check.C) {
+	upgrader := newTestUpgrader()
+	upgrader.upgradeErrs = map[int]error{2: trace.BadParameter("invalid values")}
+	_, err := progressiveUpgrade(context.TODO(), upgrader, ProgressiveUpgradeParameters{
+		UpgradeParameters: UpgradeParameters{Release: "web"},
+		Stages:            []UpgradeStage{{Name: "canary", Set: []string{"canary.weight=10"}}},
+		Progress:          c.Logf,
+	})
+	c.Assert(err, check.ErrorMatches,
+		"release web failed to upgrade at stage full: invalid values; release has been rolled back to revision 1")
+	c.Assert(upgrader.events, check.DeepEquals, []string{
+		"upgrade canary.weight=10", "gate workloads", "upgrade ", "rollback 1",
+	})
+}
+
+func (s *ProgressiveSuite) TestRollsBackOnFailedFirstStageUpgrade(c *check.C) {
+	upgrader := newTestUpgrader()
+	upgrader.upgradeErrs = map[int]error{1: trace.BadParameter("timed out waiting for the condition")}
+	_, err := progressiveUpgrade(context.TODO(), upgrader, ProgressiveUpgradeParameters{
+		UpgradeParameters: UpgradeParameters{Release: "web"},
+		Stages:            []UpgradeStage{{Name: "canary", Set: []string{"canary.weight=10"}}},
+		Progress:          c.Logf,
+	})
+	c.Assert(err, check.ErrorMatches,
+		"release web failed to upgrade at stage canary: timed out waiting for the condition; "+
+			"release has been rolled back to revision 1")
+	c.Assert(upgrader.events, check.DeepEquals, []string{"upgrade canary.weight=10", "rollback 1"})
+}
+
+func (s *ProgressiveSuite) TestReportsFailedRollback(c *check.C) {
+	upgrader := newTestUpgrader()
+	upgrader.rollbackErr = trace.ConnectionProblem(nil, "tiller is not available")
+	_, err := progressiveUpgrade(context.TODO(), upgrader, ProgressiveUpgradeParameters{
+		UpgradeParameters: UpgradeParameters{Release: "web"},
+		HealthGates:       []HealthGate{upgrader.gate("status", trace.BadParameter("unhealthy"))},
+		Progress:          c.Logf,
+	})
+	c.Assert(err, check.ErrorMatches,
+		"release web has not become healthy within 0s at stage full: unhealthy; "+
+			"rollback to revision 1 failed: tiller is not available")
+}
+
+func (s *ProgressiveSuite) TestParsesUpgradeStage(c *check.C) {
+	stage, err := ParseUpgradeStage("canary:canary.weight=10,replicas=1")
+	c.Assert(err, check.IsNil)
+	c.Assert(*stage, check.DeepEquals, UpgradeStage{
+		Name: "canary",
+		Set:  []string{"canary.weight=10,replicas=1"},
+	})
+
+	stage, err = ParseUpgradeStage("pause")
+	c.Assert(err, check.IsNil)
+	c.Assert(*stage, check.DeepEquals, UpgradeStage{Name: "pause"})
+
+	_, err = ParseUpgradeStage(":replicas=1")
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+}
+
+func newTestUpgrader() *testUpgrader {
+	return &testUpgrader{revision: 1}
+}
+
+// testUpgrader is a release upgrader that records the upgrade events
+type testUpgrader struct {
+	// revision is the current release revision
+	revision int
+	// upgrades counts the release upgrades
+	upgrades int
+	// upgradeErrs maps the upgrade number to the error it fails with
+	upgradeErrs map[int]error
+	// rollbackErr is the rollback outcome
+	rollbackErr error
+	// events lists the recorded events
+	events []string
+}
+
+func (r *testUpgrader) Get(name string) (*Release, error) {
+	return &Release{Name: name, Revision: r.revision}, nil
+}
+
+func (r *testUpgrader) Upgrade(p UpgradeParameters) (*Release, error) {
+	r.upgrades++
+	r.events = append(r.events, "upgrade "+strings.Join(p.Set, ","))
+	if err := r.upgradeErrs[r.upgrades]; err != nil {
+		return nil, err
+	}
+	r.revision++
+	return &Release{Name: p.Release, Revision: r.revision}, nil
+}
+
+func (r *testUpgrader) Rollback(p RollbackParameters) (*Release, error) {
+	r.events = append(r.events, fmt.Sprintf("rollback %v", p.Revision))
+	if r.rollbackErr != nil {
+		return nil, r.rollbackErr
+	}
+	r.revision++
+	return &Release{Name: p.Release, Revision: r.revision}, nil
+}
+
+func (r *testUpgrader) workloadsReady(string) HealthGate {
+	return r.gate("workloads", nil)
+}
+
+func (r *testUpgrader) gate(name string, err error) HealthGate {
+	return func(context.Context) error {
+		r.events = append(r.events, "gate "+name)
+		return err
+	}
+}
@@ -17,9 +17,19 @@ limitations under the License.
 package helm
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"fmt"
+	"io/ioutil"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/helm/pkg/proto/hapi/release"
 )
 
@@ -56,3 +66,109 @@ func fromHelm(release *release.Release) *Release {
 		Description: release.GetInfo().GetDescription(),
 	}
 }
+
+// ReleaseManifest is the rendered manifest of a deployed release
+type ReleaseManifest struct {
+	// Name is the release name
+	Name string
+	// Namespace is the namespace the release is deployed to
+	Namespace string
+	// Chart is the name of the release chart
+	Chart string
+	// Values is the YAML-encoded values the release has been deployed with
+	Values string
+	// Manifest is the rendered release manifest
+	Manifest string
+}
+
+// GetDeployedManifests returns the manifests of all deployed releases
+// read from the Tiller release storage in all namespaces.
+//
+// Both the configmap and the secret storage drivers are inspected.
+// The manifests reflect the API versions the release objects have been
+// created with regardless of the version the API server serves them at
+func GetDeployedManifests(client kubernetes.Interface) (manifests []ReleaseManifest, err error) {
+	options := metav1.ListOptions{
+		LabelSelector: labels.Set{
+			tillerOwnerLabel:  tillerOwner,
+			tillerStatusLabel: release.Status_DEPLOYED.String(),
+		}.String(),
+	}
+	configMaps, err := client.CoreV1().ConfigMaps(metav1.NamespaceAll).List(options)
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	for _, configMap := range configMaps.Items {
+		manifest, err := newReleaseManifest(configMap.Data[tillerReleaseKey])
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to decode release %v/%v",
+				configMap.Namespace, configMap.Name)
+		}
+		manifests = append(manifests, *manifest)
+	}
+	secrets, err := client.CoreV1().Secrets(metav1.NamespaceAll).List(options)
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	for _, secret := range secrets.Items {
+		manifest, err := newReleaseManifest(string(secret.Data[tillerReleaseKey]))
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to decode release %v/%v",
+				secret.Namespace, secret.Name)
+		}
+		manifests = append(manifests, *manifest)
+	}
+	return manifests, nil
+}
+
+func newReleaseManifest(data string) (*ReleaseManifest, error) {
+	rls, err := decodeRelease(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &ReleaseManifest{
+		Name:      rls.GetName(),
+		Namespace: rls.GetNamespace(),
+		Chart:     rls.GetChart().GetMetadata().GetName(),
+		Values:    rls.GetConfig().GetRaw(),
+		Manifest:  rls.GetManifest(),
+	}, nil
+}
+
+// decodeRelease decodes the release in the Tiller storage format:
+// base64-encoded, optionally gzipped protobuf
+func decodeRelease(data string) (*release.Release, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if bytes.HasPrefix(b, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		defer reader.Close()
+		b, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	var rls release.Release
+	if err := proto.Unmarshal(b, &rls); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &rls, nil
+}
+
+const (
+	// tillerOwnerLabel is the label Tiller marks its release storage with
+	tillerOwnerLabel = "OWNER"
+	// tillerOwner is the value of the owner label of Tiller release storage
+	tillerOwner = "TILLER"
+	// tillerStatusLabel is the release status label of Tiller release storage
+	tillerStatusLabel = "STATUS"
+	// tillerReleaseKey is the key of the encoded release in Tiller release storage
+	tillerReleaseKey = "release"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
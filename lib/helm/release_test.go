@@ -0,0 +1,83 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+
+	"github.com/golang/protobuf/proto"
+	"gopkg.in/check.v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+type ReleaseSuite struct{}
+
+var _ = check.Suite(&ReleaseSuite{})
+
+func (s *ReleaseSuite) TestDecodesRelease(c *check.C) {
+	rls := &release.Release{
+		Name:      "web",
+		Namespace: "default",
+		Manifest:  "apiVersion: v1\nkind: Service\n",
+	}
+	data, err := proto.Marshal(rls)
+	c.Assert(err, check.IsNil)
+
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, err = writer.Write(data)
+	c.Assert(err, check.IsNil)
+	c.Assert(writer.Close(), check.IsNil)
+
+	for _, encoded := range []string{
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(gzipped.Bytes()),
+	} {
+		decoded, err := decodeRelease(encoded)
+		c.Assert(err, check.IsNil)
+		c.Assert(decoded.GetName(), check.Equals, "web")
+		c.Assert(decoded.GetNamespace(), check.Equals, "default")
+		c.Assert(decoded.GetManifest(), check.Equals, rls.Manifest)
+	}
+
+	_, err = decodeRelease("not base64!")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *ReleaseSuite) TestReadsReleaseChartAndValues(c *check.C) {
+	data, err := proto.Marshal(&release.Release{
+		Name:      "web",
+		Namespace: "web",
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "nginx", Version: "1.0.0"}},
+		Config:    &chart.Config{Raw: "ingress:\n  enabled: true\n"},
+		Manifest:  "apiVersion: v1\nkind: Service\n",
+	})
+	c.Assert(err, check.IsNil)
+
+	manifest, err := newReleaseManifest(base64.StdEncoding.EncodeToString(data))
+	c.Assert(err, check.IsNil)
+	c.Assert(*manifest, check.DeepEquals, ReleaseManifest{
+		Name:      "web",
+		Namespace: "web",
+		Chart:     "nginx",
+		Values:    "ingress:\n  enabled: true\n",
+		Manifest:  "apiVersion: v1\nkind: Service\n",
+	})
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httplib
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+)
+
+// ProxyConfig defines the HTTP proxy configuration for outbound requests
+type ProxyConfig struct {
+	// HTTPProxy is the proxy URL for HTTP requests
+	HTTPProxy string
+	// HTTPSProxy is the proxy URL for HTTPS requests
+	HTTPSProxy string
+	// NoProxy is the comma-separated list of hosts, domains and CIDR ranges
+	// that are accessed directly
+	NoProxy string
+}
+
+// ProxyConfigFromEnv returns the proxy configuration from the environment.
+// Uppercase variables take precedence
+func ProxyConfigFromEnv() ProxyConfig {
+	return ProxyConfig{
+		HTTPProxy:  getEnvAny(constants.HTTPProxyEnvVar, strings.ToLower(constants.HTTPProxyEnvVar)),
+		HTTPSProxy: getEnvAny(constants.HTTPSProxyEnvVar, strings.ToLower(constants.HTTPSProxyEnvVar)),
+		NoProxy:    getEnvAny(constants.NoProxyEnvVar, strings.ToLower(constants.NoProxyEnvVar)),
+	}
+}
+
+// WithProxy sets up client to send requests via the configured proxy
+func WithProxy(config ProxyConfig) ClientOption {
+	return func(c *http.Client) {
+		c.Transport.(*http.Transport).Proxy = config.ProxyFunc()
+	}
+}
+
+// ProxyFunc returns the function that selects the proxy for a request
+// in the format of http.Transport.Proxy.
+// Unlike http.ProxyFromEnvironment, it is not cached and supports
+// CIDR ranges in NoProxy
+func (r ProxyConfig) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxy := r.HTTPProxy
+		if req.URL.Scheme == "https" {
+			proxy = r.HTTPSProxy
+		}
+		if proxy == "" || !r.useProxy(req.URL) {
+			return nil, nil
+		}
+		if !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+		return url.Parse(proxy)
+	}
+}
+
+// useProxy returns true if the request to the specified URL should
+// be sent via proxy
+func (r ProxyConfig) useProxy(u *url.URL) bool {
+	host, port := u.Hostname(), u.Port()
+	if host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return false
+	}
+	for _, entry := range strings.Split(r.NoProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return false
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && ipNet.Contains(ip) {
+				return false
+			}
+			continue
+		}
+		entryHost, entryPort, err := net.SplitHostPort(entry)
+		if err != nil {
+			entryHost, entryPort = entry, ""
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if matchesDomain(strings.ToLower(host), entryHost) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesDomain returns true if host is either the domain itself
+// or its subdomain
+func matchesDomain(host, domain string) bool {
+	domain = strings.TrimPrefix(domain, ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httplib
+
+import (
+	"net/http"
+	"net/url"
+
+	. "gopkg.in/check.v1"
+)
+
+type ProxySuite struct{}
+
+var _ = Suite(&ProxySuite{})
+
+func (s *ProxySuite) TestSelectsProxy(c *C) {
+	config := ProxyConfig{
+		HTTPProxy:  "proxy.example.com:3128",
+		HTTPSProxy: "https://secure-proxy.example.com:3129",
+		NoProxy:    "10.100.0.0/16, .cluster.local,registry.local,example.org:8080",
+	}
+	var testCases = []struct {
+		url      string
+		expected string
+	}{
+		{url: "http://www.example.com", expected: "http://proxy.example.com:3128"},
+		{url: "https://www.example.com", expected: "https://secure-proxy.example.com:3129"},
+		{url: "https://10.100.0.1:443"},
+		{url: "https://10.101.0.1:443", expected: "https://secure-proxy.example.com:3129"},
+		{url: "https://gravity-site.kube-system.svc.cluster.local"},
+		{url: "https://registry.local:5000"},
+		{url: "http://example.org:8080"},
+		{url: "http://example.org:8081", expected: "http://proxy.example.com:3128"},
+		{url: "http://localhost:3012"},
+		{url: "http://127.0.0.1:3012"},
+	}
+	proxy := config.ProxyFunc()
+	for _, tc := range testCases {
+		u, err := url.Parse(tc.url)
+		c.Assert(err, IsNil)
+		proxyURL, err := proxy(&http.Request{URL: u})
+		c.Assert(err, IsNil)
+		comment := Commentf(tc.url)
+		if tc.expected == "" {
+			c.Assert(proxyURL, IsNil, comment)
+			continue
+		}
+		c.Assert(proxyURL, NotNil, comment)
+		c.Assert(proxyURL.String(), Equals, tc.expected, comment)
+	}
+}
+
+func (s *ProxySuite) TestWildcardDisablesProxy(c *C) {
+	config := ProxyConfig{HTTPProxy: "http://proxy.example.com:3128", NoProxy: "*"}
+	u, err := url.Parse("http://www.example.com")
+	c.Assert(err, IsNil)
+	proxyURL, err := config.ProxyFunc()(&http.Request{URL: u})
+	c.Assert(err, IsNil)
+	c.Assert(proxyURL, IsNil)
+}
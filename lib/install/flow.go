@@ -151,7 +151,8 @@ func (i *Installer) StartCLIInstall() (err error) {
 			DnsAddrs:  i.DNSConfig.Addrs,
 			DnsPort:   int32(i.DNSConfig.Port),
 		},
-		AutoFix: true,
+		AutoFix:    i.AutoFix,
+		ReportPath: i.PreflightReport,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -184,6 +184,11 @@ type Config struct {
 	DNSConfig storage.DNSConfig
 	// Docker specifies docker configuration
 	Docker storage.DockerConfig
+	// PreflightReport is the path to the file to write
+	// the report of the pre-flight checks to
+	PreflightReport string
+	// AutoFix enables automatic fixing of the failed pre-flight checks
+	AutoFix bool
 	// Insecure allows to turn off cert validation
 	Insecure bool
 	// Process is the gravity process running inside the installer
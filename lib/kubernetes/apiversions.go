@@ -0,0 +1,317 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+)
+
+// APIDeprecation describes a Kubernetes API group version of a resource kind
+// that has been deprecated and is eventually removed
+type APIDeprecation struct {
+	// GroupVersion is the deprecated API group version, e.g. extensions/v1beta1
+	GroupVersion string
+	// Kind is the resource kind
+	Kind string
+	// DeprecatedIn is the Kubernetes version the API has been deprecated in
+	DeprecatedIn semver.Version
+	// RemovedIn is the Kubernetes version the API is no longer served in
+	RemovedIn semver.Version
+	// Replacement is the API group version to migrate to.
+	// Empty if the resource kind has no replacement
+	Replacement string
+}
+
+// ObjectRef references a Kubernetes object and the API version it is defined with
+type ObjectRef struct {
+	// APIVersion is the API group version of the object
+	APIVersion string
+	// Kind is the object kind
+	Kind string
+	// Namespace is the object namespace
+	Namespace string
+	// Name is the object name
+	Name string
+	// Source describes where the object has been found
+	Source string
+}
+
+// String returns a textual representation of this object reference
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%v/%v", strings.ToLower(r.Kind), r.Name)
+	}
+	return fmt.Sprintf("%v/%v/%v", r.Namespace, strings.ToLower(r.Kind), r.Name)
+}
+
+// APIFinding describes an object that uses a deprecated or removed API
+type APIFinding struct {
+	// Object references the offending object
+	Object ObjectRef
+	// Deprecation describes the deprecated API
+	Deprecation APIDeprecation
+	// Removed specifies whether the API is no longer served
+	// by the target Kubernetes version
+	Removed bool
+}
+
+// String returns a textual representation of this finding
+func (r APIFinding) String() string {
+	state := "deprecated"
+	if r.Removed {
+		state = "removed"
+	}
+	return fmt.Sprintf("%v uses %v %v API (%v in %v)", r.Object, state,
+		r.Object.APIVersion, state, r.version())
+}
+
+func (r APIFinding) version() semver.Version {
+	if r.Removed {
+		return r.Deprecation.RemovedIn
+	}
+	return r.Deprecation.DeprecatedIn
+}
+
+// APIFindings is a list of API deprecation findings
+type APIFindings []APIFinding
+
+// Removed returns the findings that reference APIs removed from the target Kubernetes version
+func (r APIFindings) Removed() (result APIFindings) {
+	for _, finding := range r {
+		if finding.Removed {
+			result = append(result, finding)
+		}
+	}
+	return result
+}
+
+// Format writes the findings as a table into the specified writer
+func (r APIFindings) Format(w io.Writer) {
+	t := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(t, "Object\tSource\tAPI Version\tStatus\tReplacement\n")
+	fmt.Fprintf(t, "------\t------\t-----------\t------\t-----------\n")
+	for _, finding := range r {
+		status := fmt.Sprintf("deprecated in %v", finding.Deprecation.DeprecatedIn)
+		if finding.Removed {
+			status = fmt.Sprintf("removed in %v", finding.Deprecation.RemovedIn)
+		}
+		replacement := finding.Deprecation.Replacement
+		if replacement == "" {
+			replacement = "none"
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\t%v\n", finding.Object, finding.Object.Source,
+			finding.Object.APIVersion, status, replacement)
+	}
+	t.Flush()
+}
+
+// FindDeprecatedAPIs returns the list of objects that use APIs
+// deprecated or removed in the specified Kubernetes version
+func FindDeprecatedAPIs(objects []ObjectRef, version semver.Version) (findings APIFindings) {
+	version = releaseVersion(version)
+	for _, object := range objects {
+		deprecation, ok := findAPIDeprecation(object.APIVersion, object.Kind)
+		if !ok || version.LessThan(deprecation.DeprecatedIn) {
+			continue
+		}
+		findings = append(findings, APIFinding{
+			Object:      object,
+			Deprecation: *deprecation,
+			Removed:     !version.LessThan(deprecation.RemovedIn),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Removed != findings[j].Removed {
+			return findings[i].Removed
+		}
+		return findings[i].Object.String() < findings[j].Object.String()
+	})
+	return findings
+}
+
+// ListDeprecationCandidates lists the cluster objects of all resource kinds
+// that have deprecated API versions.
+//
+// The API server converts objects to the requested version so the version
+// an object has been created with is determined from the last applied
+// configuration. Objects without one are skipped: objects created by Helm
+// need to be collected from the release manifests instead
+func ListDeprecationCandidates(client kubernetes.Interface) (objects []ObjectRef, err error) {
+	resources, err := discovery.ServerPreferredResources(client.Discovery())
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, trace.Wrap(err)
+		}
+		log.Warnf("Failed to discover some API groups: %v.", err)
+	}
+	kinds := deprecatedKinds()
+	// the same objects can be served from several API groups
+	// (e.g. ingresses are served by both extensions and networking.k8s.io)
+	seen := make(map[ObjectRef]bool)
+	for _, list := range resources {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, resource := range list.APIResources {
+			if !kinds[resource.Kind] || strings.Contains(resource.Name, "/") {
+				continue
+			}
+			refs, err := listAppliedObjects(client, gv, resource.Name, resource.Kind)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			for _, ref := range refs {
+				if !seen[ref] {
+					seen[ref] = true
+					objects = append(objects, ref)
+				}
+			}
+		}
+	}
+	return objects, nil
+}
+
+func listAppliedObjects(client kubernetes.Interface, gv schema.GroupVersion, resource, kind string) (objects []ObjectRef, err error) {
+	path := []string{"/apis", gv.Group, gv.Version, resource}
+	if gv.Group == "" {
+		path = []string{"/api", gv.Version, resource}
+	}
+	data, err := client.Discovery().RESTClient().Get().AbsPath(path...).Do().Raw()
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to list %v", resource)
+	}
+	var list unstructured.UnstructuredList
+	if err := list.UnmarshalJSON(data); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, item := range list.Items {
+		lastApplied, ok := item.GetAnnotations()[v1.LastAppliedConfigAnnotation]
+		if !ok {
+			continue
+		}
+		var applied struct {
+			APIVersion string `json:"apiVersion"`
+		}
+		if err := json.Unmarshal([]byte(lastApplied), &applied); err != nil {
+			log.Warnf("Failed to parse last applied configuration of %v/%v: %v.",
+				item.GetNamespace(), item.GetName(), err)
+			continue
+		}
+		objects = append(objects, ObjectRef{
+			APIVersion: applied.APIVersion,
+			Kind:       kind,
+			Namespace:  item.GetNamespace(),
+			Name:       item.GetName(),
+			Source:     "cluster",
+		})
+	}
+	return objects, nil
+}
+
+func findAPIDeprecation(apiVersion, kind string) (*APIDeprecation, bool) {
+	for _, deprecation := range apiDeprecations {
+		if deprecation.GroupVersion == apiVersion && deprecation.Kind == kind {
+			return &deprecation, true
+		}
+	}
+	return nil, false
+}
+
+func deprecatedKinds() map[string]bool {
+	kinds := make(map[string]bool)
+	for _, deprecation := range apiDeprecations {
+		kinds[deprecation.Kind] = true
+	}
+	return kinds
+}
+
+// releaseVersion returns the release (major.minor) portion of the specified version
+func releaseVersion(version semver.Version) semver.Version {
+	return semver.Version{Major: version.Major, Minor: version.Minor}
+}
+
+func newDeprecation(groupVersion, kind, deprecatedIn, removedIn, replacement string) APIDeprecation {
+	return APIDeprecation{
+		GroupVersion: groupVersion,
+		Kind:         kind,
+		DeprecatedIn: *semver.New(deprecatedIn),
+		RemovedIn:    *semver.New(removedIn),
+		Replacement:  replacement,
+	}
+}
+
+// apiDeprecations lists known deprecated Kubernetes APIs
+var apiDeprecations = []APIDeprecation{
+	newDeprecation("extensions/v1beta1", "DaemonSet", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("extensions/v1beta1", "Deployment", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("extensions/v1beta1", "ReplicaSet", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("extensions/v1beta1", "NetworkPolicy", "1.9.0", "1.16.0", "networking.k8s.io/v1"),
+	newDeprecation("extensions/v1beta1", "PodSecurityPolicy", "1.10.0", "1.16.0", "policy/v1beta1"),
+	newDeprecation("extensions/v1beta1", "Ingress", "1.14.0", "1.22.0", "networking.k8s.io/v1"),
+	newDeprecation("apps/v1beta1", "Deployment", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("apps/v1beta1", "StatefulSet", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("apps/v1beta1", "ControllerRevision", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("apps/v1beta2", "DaemonSet", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("apps/v1beta2", "Deployment", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("apps/v1beta2", "ReplicaSet", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("apps/v1beta2", "StatefulSet", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("apps/v1beta2", "ControllerRevision", "1.9.0", "1.16.0", "apps/v1"),
+	newDeprecation("networking.k8s.io/v1beta1", "Ingress", "1.19.0", "1.22.0", "networking.k8s.io/v1"),
+	newDeprecation("networking.k8s.io/v1beta1", "IngressClass", "1.19.0", "1.22.0", "networking.k8s.io/v1"),
+	newDeprecation("rbac.authorization.k8s.io/v1alpha1", "ClusterRole", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1"),
+	newDeprecation("rbac.authorization.k8s.io/v1alpha1", "ClusterRoleBinding", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1"),
+	newDeprecation("rbac.authorization.k8s.io/v1alpha1", "Role", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1"),
+	newDeprecation("rbac.authorization.k8s.io/v1alpha1", "RoleBinding", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1"),
+	newDeprecation("rbac.authorization.k8s.io/v1beta1", "ClusterRole", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1"),
+	newDeprecation("rbac.authorization.k8s.io/v1beta1", "ClusterRoleBinding", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1"),
+	newDeprecation("rbac.authorization.k8s.io/v1beta1", "Role", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1"),
+	newDeprecation("rbac.authorization.k8s.io/v1beta1", "RoleBinding", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1"),
+	newDeprecation("apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", "1.16.0", "1.22.0", "apiextensions.k8s.io/v1"),
+	newDeprecation("admissionregistration.k8s.io/v1beta1", "MutatingWebhookConfiguration", "1.16.0", "1.22.0", "admissionregistration.k8s.io/v1"),
+	newDeprecation("admissionregistration.k8s.io/v1beta1", "ValidatingWebhookConfiguration", "1.16.0", "1.22.0", "admissionregistration.k8s.io/v1"),
+	newDeprecation("apiregistration.k8s.io/v1beta1", "APIService", "1.19.0", "1.22.0", "apiregistration.k8s.io/v1"),
+	newDeprecation("scheduling.k8s.io/v1beta1", "PriorityClass", "1.14.0", "1.22.0", "scheduling.k8s.io/v1"),
+	newDeprecation("storage.k8s.io/v1beta1", "StorageClass", "1.19.0", "1.22.0", "storage.k8s.io/v1"),
+	newDeprecation("storage.k8s.io/v1beta1", "VolumeAttachment", "1.19.0", "1.22.0", "storage.k8s.io/v1"),
+	newDeprecation("storage.k8s.io/v1beta1", "CSIDriver", "1.19.0", "1.22.0", "storage.k8s.io/v1"),
+	newDeprecation("storage.k8s.io/v1beta1", "CSINode", "1.19.0", "1.22.0", "storage.k8s.io/v1"),
+	newDeprecation("certificates.k8s.io/v1beta1", "CertificateSigningRequest", "1.19.0", "1.22.0", "certificates.k8s.io/v1"),
+	newDeprecation("coordination.k8s.io/v1beta1", "Lease", "1.19.0", "1.22.0", "coordination.k8s.io/v1"),
+	newDeprecation("batch/v1beta1", "CronJob", "1.21.0", "1.25.0", "batch/v1"),
+	newDeprecation("discovery.k8s.io/v1beta1", "EndpointSlice", "1.21.0", "1.25.0", "discovery.k8s.io/v1"),
+	newDeprecation("events.k8s.io/v1beta1", "Event", "1.19.0", "1.25.0", "events.k8s.io/v1"),
+	newDeprecation("policy/v1beta1", "PodDisruptionBudget", "1.21.0", "1.25.0", "policy/v1"),
+	newDeprecation("policy/v1beta1", "PodSecurityPolicy", "1.21.0", "1.25.0", ""),
+	newDeprecation("node.k8s.io/v1beta1", "RuntimeClass", "1.20.0", "1.25.0", "node.k8s.io/v1"),
+	newDeprecation("autoscaling/v2beta1", "HorizontalPodAutoscaler", "1.22.0", "1.25.0", "autoscaling/v2"),
+	newDeprecation("autoscaling/v2beta2", "HorizontalPodAutoscaler", "1.23.0", "1.26.0", "autoscaling/v2"),
+}
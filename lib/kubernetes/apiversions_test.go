@@ -0,0 +1,56 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"github.com/coreos/go-semver/semver"
+	. "gopkg.in/check.v1"
+)
+
+type APIVersionsSuite struct{}
+
+var _ = Suite(&APIVersionsSuite{})
+
+func (s *APIVersionsSuite) TestFindsDeprecatedAPIs(c *C) {
+	deployment := ObjectRef{APIVersion: "extensions/v1beta1", Kind: "Deployment", Namespace: "default", Name: "app"}
+	ingress := ObjectRef{APIVersion: "extensions/v1beta1", Kind: "Ingress", Namespace: "default", Name: "app"}
+	cronJob := ObjectRef{APIVersion: "batch/v1beta1", Kind: "CronJob", Namespace: "default", Name: "backup"}
+	objects := []ObjectRef{
+		deployment,
+		ingress,
+		cronJob,
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "other"},
+	}
+
+	findings := FindDeprecatedAPIs(objects, *semver.New("1.13.5"))
+	c.Assert(findings, HasLen, 1)
+	c.Assert(findings.Removed(), HasLen, 0)
+
+	findings = FindDeprecatedAPIs(objects, *semver.New("1.16.2"))
+	c.Assert(findings, HasLen, 2)
+	c.Assert(findings[0].Object, DeepEquals, deployment)
+	c.Assert(findings[0].Removed, Equals, true)
+	c.Assert(findings[0].Deprecation.Replacement, Equals, "apps/v1")
+	c.Assert(findings[1].Object, DeepEquals, ingress)
+	c.Assert(findings[1].Removed, Equals, false)
+
+	findings = FindDeprecatedAPIs(objects, *semver.New("1.22.0"))
+	c.Assert(findings, HasLen, 3)
+	c.Assert(findings.Removed(), HasLen, 2)
+	c.Assert(findings[2].Object, DeepEquals, cronJob)
+	c.Assert(findings[2].Removed, Equals, false)
+}
@@ -0,0 +1,36 @@
+// +build !linux
+
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/gravitational/trace"
+)
+
+// setDontFragment disables fragmentation of the packets sent on conn
+func setDontFragment(conn syscall.Conn, ipv6 bool) error {
+	return trace.NotImplemented("API is not supported")
+}
+
+// maxSegmentSize returns the maximum segment size of the TCP connection
+func maxSegmentSize(conn *net.TCPConn) (int, error) {
+	return 0, trace.NotImplemented("API is not supported")
+}
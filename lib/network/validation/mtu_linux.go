@@ -0,0 +1,64 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/sys/unix"
+)
+
+// setDontFragment disables fragmentation of the packets sent on conn.
+// The packets are sent with the "don't fragment" bit set regardless of the
+// path MTU cached by the kernel so that each packet probes the actual path
+func setDontFragment(conn syscall.Conn, ipv6 bool) error {
+	level, option, value := unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE
+	if ipv6 {
+		level, option, value = unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_PROBE
+	}
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), level, option, value)
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.ConvertSystemError(sockErr)
+}
+
+// maxSegmentSize returns the maximum segment size of the TCP connection
+// as negotiated with the remote end
+func maxSegmentSize(conn *net.TCPConn) (mss int, err error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		mss, sockErr = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_MAXSEG)
+	})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	return mss, trace.ConvertSystemError(sockErr)
+}
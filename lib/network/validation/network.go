@@ -0,0 +1,596 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	pb "github.com/gravitational/gravity/lib/network/validation/proto"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// CheckNetwork launches a network test that measures the latency, jitter and packet
+// loss on the paths to the servers specified in the request and discovers the
+// path MTU to the servers
+func (r *Server) CheckNetwork(ctx context.Context, req *pb.CheckNetworkRequest) (*pb.CheckNetworkResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	duration, err := pb.DurationFromProto(req.Duration)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	// The echo listeners are kept up for as long as the peers keep probing
+	// them so the probe sequences that outlast the test duration, like the path
+	// MTU discovery, are not cut short, but no longer than twice the duration
+	maxDuration := 2 * duration
+	listenCh := make(chan pb.ServerResult, len(req.Listen))
+	for _, listenServer := range req.Listen {
+		go func(server *pb.Addr) {
+			err := listenEcho(ctx, *server, duration, maxDuration)
+			if err != nil {
+				listenCh <- pb.ServerResult{Code: 1, Error: err.Error(), Server: server}
+			} else {
+				listenCh <- pb.ServerResult{Server: server}
+			}
+		}(listenServer)
+	}
+
+	config := probeConfig{
+		count:   int(req.Count),
+		maxMTU:  int(req.MaxMtu),
+		timeout: defaults.NetworkTestProbeTimeout,
+	}
+	pingCh := make(chan pb.NetworkResult, len(req.Ping))
+	for _, pingServer := range req.Ping {
+		go func(server *pb.Addr) {
+			pingCh <- probe(*server, config, duration)
+		}(pingServer)
+	}
+
+	// allow some extra time for collecting results
+	timeout := time.After(maxDuration + defaults.NetworkTestIdleTimeout)
+
+	response := &pb.CheckNetworkResponse{}
+	for range req.Listen {
+		select {
+		case listen := <-listenCh:
+			response.Listen = append(response.Listen, &listen)
+		case <-timeout:
+			return nil, trace.LimitExceeded("timeout spinning up listen servers")
+		}
+	}
+
+	for range req.Ping {
+		select {
+		case ping := <-pingCh:
+			r.Infof("Network test result for %v: sent=%v received=%v latency=%v jitter=%v mtu=%v.",
+				ping.Server.Address(), ping.Sent, ping.Received, ping.Latency, ping.Jitter, ping.Mtu)
+			response.Ping = append(response.Ping, &ping)
+		case <-timeout:
+			return nil, trace.LimitExceeded("timeout probing servers")
+		}
+	}
+
+	return response, nil
+}
+
+// probeConfig defines the parameters of the network path probe
+type probeConfig struct {
+	// count is the number of latency probes to send
+	count int
+	// maxMTU is the upper bound for the path MTU discovery
+	maxMTU int
+	// timeout is the time to wait for the reply to a single probe
+	timeout time.Duration
+}
+
+// probe measures the quality of the network path to the specified server
+func probe(server pb.Addr, config probeConfig, duration time.Duration) (result pb.NetworkResult) {
+	result.Server = &server
+	var err error
+	if server.Network == "tcp" {
+		err = probeTCP(server.Addr, config, duration, &result)
+	} else {
+		err = probeUDP(server.Addr, config, duration, &result)
+	}
+	if err != nil {
+		result.Code = 1
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// probeTCP sends a series of probes over a TCP connection to the specified echo
+// server and records the round-trip times
+func probeTCP(address string, config probeConfig, duration time.Duration, result *pb.NetworkResult) error {
+	var conn net.Conn
+	// retry a few times because other agents' servers may still be starting up
+	const attempts = 4
+	err := utils.Retry(duration/(2*attempts), attempts, func() (err error) {
+		conn, err = net.DialTimeout("tcp", address, config.timeout)
+		return trace.Wrap(err)
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer conn.Close()
+
+	var stats pathStats
+	buf := make([]byte, probeHeaderSize)
+	for seq := 0; seq < config.count; seq++ {
+		rtt, err := roundTripTCP(conn, uint64(seq), buf, config.timeout)
+		stats.sent++
+		if err != nil {
+			// a timed out stream can no longer be trusted to carry
+			// the replies in order, so count the remaining probes as lost
+			log.Warnf("Failed to probe %v: %v.", address, err)
+			stats.sent = config.count
+			break
+		}
+		stats.add(rtt)
+		time.Sleep(defaults.NetworkTestProbeInterval)
+	}
+	stats.toProto(result)
+
+	if config.maxMTU == 0 {
+		return nil
+	}
+	tcpConn := conn.(*net.TCPConn)
+	localIP := tcpConn.LocalAddr().(*net.TCPAddr).IP
+	ifaceMTU, err := interfaceMTU(localIP)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	result.InterfaceMtu = int32(ifaceMTU)
+	overhead := ipv4HeaderSize + tcpHeaderSize + tcpOptionsSize
+	if tcpConn.RemoteAddr().(*net.TCPAddr).IP.To4() == nil {
+		overhead = ipv6HeaderSize + tcpHeaderSize + tcpOptionsSize
+	}
+	// the probes need to fit into a single segment so the path MTU
+	// is additionally bounded by the maximum segment size negotiated
+	// with the remote end
+	mss, err := maxSegmentSize(tcpConn)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	maxMTU := utils.Min(utils.Min(config.maxMTU, ifaceMTU), mss+overhead)
+	mtu, err := discoverPathMTU(maxMTU, tcpMTUProber(address, overhead, config.timeout))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	result.Mtu = int32(mtu)
+	return nil
+}
+
+// tcpMTUProber returns a function that determines whether an IP packet of the given
+// size gets through to the specified TCP echo server with fragmentation disabled.
+// Every probe uses a new connection since a dropped segment is retransmitted
+// by the kernel and would stall all subsequent probes on the same stream
+func tcpMTUProber(address string, overhead int, timeout time.Duration) func(int) bool {
+	var seq uint64 = 1 << 32
+	return func(mtu int) bool {
+		seq++
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			log.Debugf("Failed to connect to %v: %v.", address, err)
+			return false
+		}
+		defer conn.Close()
+		tcpConn := conn.(*net.TCPConn)
+		ipv6 := tcpConn.RemoteAddr().(*net.TCPAddr).IP.To4() == nil
+		if err := setDontFragment(tcpConn, ipv6); err != nil {
+			log.Warnf("Failed to disable fragmentation: %v.", err)
+			return false
+		}
+		// send the probe as a single segment
+		if err := tcpConn.SetNoDelay(true); err != nil {
+			log.Warnf("Failed to disable Nagle's algorithm: %v.", err)
+			return false
+		}
+		buf := make([]byte, utils.Max(mtu-overhead, probeHeaderSize))
+		_, err = roundTripTCP(conn, seq, buf, timeout)
+		return err == nil
+	}
+}
+
+func roundTripTCP(conn net.Conn, seq uint64, buf []byte, timeout time.Duration) (rtt time.Duration, err error) {
+	binary.BigEndian.PutUint64(buf, seq)
+	start := time.Now()
+	if err := conn.SetDeadline(start.Add(timeout)); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if binary.BigEndian.Uint64(buf) != seq {
+		return 0, trace.BadParameter("unexpected probe reply %v, expected %v",
+			binary.BigEndian.Uint64(buf), seq)
+	}
+	return time.Since(start), nil
+}
+
+// probeUDP sends a series of probes to the specified UDP echo server
+// and records the round-trip times and the number of lost probes.
+// If requested, it also discovers the path MTU to the server
+func probeUDP(address string, config probeConfig, duration time.Duration, result *pb.NetworkResult) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer conn.Close()
+
+	// wait for the remote echo server to come up so the probes
+	// sent before it has started are not counted as lost
+	const attempts = 4
+	err = utils.Retry(duration/(2*attempts), attempts, func() error {
+		_, err := roundTripUDP(conn, 0, probeHeaderSize, config.timeout)
+		return trace.Wrap(err)
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var stats pathStats
+	for seq := 1; seq <= config.count; seq++ {
+		stats.sent++
+		rtt, err := roundTripUDP(conn, uint64(seq), probeHeaderSize, config.timeout)
+		if err == nil {
+			stats.add(rtt)
+		}
+		time.Sleep(defaults.NetworkTestProbeInterval)
+	}
+	stats.toProto(result)
+
+	if config.maxMTU == 0 {
+		return nil
+	}
+	udpConn := conn.(*net.UDPConn)
+	localIP := udpConn.LocalAddr().(*net.UDPAddr).IP
+	ifaceMTU, err := interfaceMTU(localIP)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	result.InterfaceMtu = int32(ifaceMTU)
+	ipv6 := udpConn.RemoteAddr().(*net.UDPAddr).IP.To4() == nil
+	if err := setDontFragment(udpConn, ipv6); err != nil {
+		return trace.Wrap(err)
+	}
+	overhead := ipv4HeaderSize + udpHeaderSize
+	if ipv6 {
+		overhead = ipv6HeaderSize + udpHeaderSize
+	}
+	var seq uint64 = 1 << 32
+	fits := func(mtu int) bool {
+		seq++
+		_, err := roundTripUDP(conn, seq, mtu-overhead, config.timeout)
+		return err == nil
+	}
+	mtu, err := discoverPathMTU(utils.Min(config.maxMTU, ifaceMTU), fits)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	result.Mtu = int32(mtu)
+	return nil
+}
+
+// discoverPathMTU determines the largest IP packet that can be sent on the path
+// without fragmentation by sending probes with the "don't fragment" bit set
+// and looking for the largest probe that is replied to. The probe function
+// sends a single probe of the given IP packet size and reports whether it has
+// been replied to.
+// Unlike the kernel path MTU discovery, this does not rely on ICMP "fragmentation needed"
+// messages which are frequently blocked and result in silently dropped packets
+func discoverPathMTU(maxMTU int, probe func(mtu int) bool) (mtu int, err error) {
+	fits := func(mtu int) bool {
+		for i := 0; i < defaults.NetworkTestMTUProbeAttempts; i++ {
+			if probe(mtu) {
+				return true
+			}
+		}
+		return false
+	}
+	low, high := utils.Min(defaults.NetworkTestMinMTU, maxMTU), maxMTU
+	if !fits(low) {
+		return 0, trace.BadParameter("no reply to %v byte packets with fragmentation disabled", low)
+	}
+	if fits(high) {
+		return high, nil
+	}
+	// invariant: packets of size low get through, packets of size high do not
+	for high-low > 1 {
+		mid := (low + high) / 2
+		if fits(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low, nil
+}
+
+// roundTripUDP sends a probe of the specified size and waits for the echo server
+// to reply with the probe header
+func roundTripUDP(conn net.Conn, seq uint64, size int, timeout time.Duration) (rtt time.Duration, err error) {
+	buf := make([]byte, utils.Max(size, probeHeaderSize))
+	binary.BigEndian.PutUint64(buf, seq)
+	start := time.Now()
+	if err := conn.SetDeadline(start.Add(timeout)); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	reply := make([]byte, probeHeaderSize)
+	for {
+		n, err := conn.Read(reply)
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		// skip late replies to earlier probes
+		if n == probeHeaderSize && binary.BigEndian.Uint64(reply) == seq {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// listenEcho starts an echo server on the provided address.
+// The server runs for at least the specified duration and is kept up
+// while it is being probed, but no longer than maxDuration
+func listenEcho(ctx context.Context, server pb.Addr, duration, maxDuration time.Duration) error {
+	if server.Network == "tcp" {
+		return trace.Wrap(listenEchoTCP(ctx, server.Addr, duration, maxDuration))
+	}
+	return trace.Wrap(listenEchoUDP(ctx, server.Addr, duration, maxDuration))
+}
+
+// listenEchoTCP starts a TCP server that echoes data received
+// on incoming connections back to the sender
+func listenEchoTCP(ctx context.Context, address string, duration, maxDuration time.Duration) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	log.Debugf("Started TCP echo listener: %v.", address)
+	var lifetime echoLifetime
+	go func() {
+		lifetime.wait(ctx, duration, maxDuration)
+		listener.Close()
+	}()
+	deadline := time.Now().Add(maxDuration)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if utils.IsClosedConnectionError(err) {
+				break
+			}
+			return trace.Wrap(err)
+		}
+		lifetime.touch()
+		go func(conn net.Conn) {
+			defer conn.Close()
+			if err := conn.SetDeadline(deadline); err != nil {
+				log.Warnf("Failed to set deadline: %v.", err)
+				return
+			}
+			// the connection is expected to be aborted once the test
+			// duration has expired, so only log the error
+			err := echoTCP(conn, &lifetime)
+			if err != nil && !utils.IsStreamClosedError(err) {
+				log.Debugf("Failed to echo data to %v: %v.", conn.RemoteAddr(), err)
+			}
+		}(conn)
+	}
+	log.Debugf("Stopped TCP echo listener: %v.", address)
+	return nil
+}
+
+// echoTCP writes the data received on the connection back to the sender
+func echoTCP(conn net.Conn, lifetime *echoLifetime) error {
+	buf := make([]byte, maxUDPPacketSize)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			lifetime.touch()
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+}
+
+// listenEchoUDP starts a UDP server that replies to the incoming probes
+// with the probe header
+func listenEchoUDP(ctx context.Context, address string, duration, maxDuration time.Duration) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	log.Debugf("Started UDP echo listener: %v.", address)
+	var lifetime echoLifetime
+	go func() {
+		lifetime.wait(ctx, duration, maxDuration)
+		conn.Close()
+	}()
+	buf := make([]byte, maxUDPPacketSize)
+	for {
+		n, raddr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if utils.IsClosedConnectionError(err) {
+				break
+			}
+			return trace.Wrap(err)
+		}
+		if n < probeHeaderSize {
+			continue
+		}
+		lifetime.touch()
+		// reply with the header only so the reverse path does not
+		// affect the results of the path MTU discovery
+		if _, err := conn.WriteTo(buf[:probeHeaderSize], raddr); err != nil {
+			log.Warnf("Failed to reply to %v: %v.", raddr, err)
+		}
+	}
+	log.Debugf("Stopped UDP echo listener: %v.", address)
+	return nil
+}
+
+// echoLifetime tracks the activity of an echo listener
+// to determine when the listener can be stopped
+type echoLifetime struct {
+	// lastProbe is the time of the last received probe in Unix nanoseconds.
+	// Accessed atomically
+	lastProbe int64
+}
+
+// touch records the receipt of a probe
+func (r *echoLifetime) touch() {
+	atomic.StoreInt64(&r.lastProbe, time.Now().UnixNano())
+}
+
+// wait blocks until the listener should be stopped: either the context
+// is cancelled, maxDuration expires, or the duration has expired and
+// no probes have been received for the idle timeout
+func (r *echoLifetime) wait(ctx context.Context, duration, maxDuration time.Duration) {
+	deadline := time.Now().Add(maxDuration)
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+		now := time.Now()
+		if !now.Before(deadline) {
+			return
+		}
+		idle := now.Sub(time.Unix(0, atomic.LoadInt64(&r.lastProbe)))
+		if idle >= defaults.NetworkTestIdleTimeout {
+			return
+		}
+		next := defaults.NetworkTestIdleTimeout - idle
+		if remaining := deadline.Sub(now); remaining < next {
+			next = remaining
+		}
+		timer.Reset(next)
+	}
+}
+
+// interfaceMTU returns the MTU of the network interface with the specified address
+func interfaceMTU(ip net.IP) (mtu int, err error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface.MTU, nil
+			}
+		}
+	}
+	return 0, trace.NotFound("no network interface with address %v", ip)
+}
+
+// pathStats accumulates the round-trip times of the probes sent on a network path
+type pathStats struct {
+	sent int
+	rtts []time.Duration
+}
+
+func (r *pathStats) add(rtt time.Duration) {
+	r.rtts = append(r.rtts, rtt)
+}
+
+// latency returns the average round-trip time
+func (r pathStats) latency() time.Duration {
+	if len(r.rtts) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, rtt := range r.rtts {
+		total += rtt
+	}
+	return total / time.Duration(len(r.rtts))
+}
+
+// jitter returns the average difference between the round-trip
+// times of consecutive probes
+func (r pathStats) jitter() time.Duration {
+	if len(r.rtts) < 2 {
+		return 0
+	}
+	var total time.Duration
+	for i := 1; i < len(r.rtts); i++ {
+		diff := r.rtts[i] - r.rtts[i-1]
+		if diff < 0 {
+			diff = -diff
+		}
+		total += diff
+	}
+	return total / time.Duration(len(r.rtts)-1)
+}
+
+func (r pathStats) toProto(result *pb.NetworkResult) {
+	result.Sent = int32(r.sent)
+	result.Received = int32(len(r.rtts))
+	result.Latency = pb.DurationProto(r.latency())
+	result.Jitter = pb.DurationProto(r.jitter())
+}
+
+const (
+	// probeHeaderSize is the size of the probe header with the probe sequence number
+	probeHeaderSize = 8
+	// maxUDPPacketSize is the maximum size of the UDP packet
+	maxUDPPacketSize = 65535
+	// ipv4HeaderSize is the size of the IPv4 header without options
+	ipv4HeaderSize = 20
+	// ipv6HeaderSize is the size of the IPv6 header
+	ipv6HeaderSize = 40
+	// udpHeaderSize is the size of the UDP header
+	udpHeaderSize = 8
+	// tcpHeaderSize is the size of the TCP header without options
+	tcpHeaderSize = 20
+	// tcpOptionsSize is the size of the TCP timestamps option
+	// carried by every segment as TCP timestamps are enabled by default
+	tcpOptionsSize = 12
+)
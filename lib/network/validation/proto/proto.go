@@ -55,6 +55,30 @@ func (r CheckBandwidthRequest) Check() error {
 	return nil
 }
 
+// Check makes sure the request is correct
+func (r CheckNetworkRequest) Check() error {
+	if len(r.Listen) == 0 {
+		return trace.BadParameter("at least one listen address should be provided: %v", r)
+	}
+
+	if len(r.Ping) == 0 {
+		return trace.BadParameter("at least one ping address should be provided: %v", r)
+	}
+
+	if r.Count <= 0 {
+		return trace.BadParameter("probe count should be positive: %v", r)
+	}
+
+	for _, server := range append(r.Listen, r.Ping...) {
+		if !utils.StringInSlice([]string{"tcp", "udp"}, server.Network) {
+			return trace.BadParameter("unsupported protocol %v, supported are: tcp, udp",
+				server)
+		}
+	}
+
+	return nil
+}
+
 // Address returns a text representation of this server
 func (r Addr) Address() string {
 	return fmt.Sprintf("%v@%v", r.Network, r.Addr)
@@ -19,6 +19,9 @@
 		ValidateResponse
 		ValidateOptions
 		Docker
+		CheckNetworkRequest
+		CheckNetworkResponse
+		NetworkResult
 */
 package proto
 
@@ -355,6 +358,180 @@ func (m *Docker) GetStorageDriver() string {
 	return ""
 }
 
+// CheckNetworkRequest describes a network latency, packet loss and path MTU test request
+type CheckNetworkRequest struct {
+	// Listen specifies the listen endpoints
+	Listen []*Addr `protobuf:"bytes,1,rep,name=listen" json:"listen,omitempty"`
+	// Ping specifies the ping endpoints
+	Ping []*Addr `protobuf:"bytes,2,rep,name=ping" json:"ping,omitempty"`
+	// Duration specifies the maximum duration for the request
+	Duration *google_protobuf.Duration `protobuf:"bytes,3,opt,name=duration" json:"duration,omitempty"`
+	// Count specifies the number of probes to send to each ping endpoint
+	Count int32 `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	// MaxMtu specifies the upper bound for the path MTU discovery
+	// on ping endpoints. Path MTU is not probed if unspecified
+	MaxMtu int32 `protobuf:"varint,5,opt,name=max_mtu,json=maxMtu,proto3" json:"max_mtu,omitempty"`
+}
+
+func (m *CheckNetworkRequest) Reset()                    { *m = CheckNetworkRequest{} }
+func (m *CheckNetworkRequest) String() string            { return proto1.CompactTextString(m) }
+func (*CheckNetworkRequest) ProtoMessage()               {}
+func (*CheckNetworkRequest) Descriptor() ([]byte, []int) { return fileDescriptorValidation, []int{10} }
+
+func (m *CheckNetworkRequest) GetListen() []*Addr {
+	if m != nil {
+		return m.Listen
+	}
+	return nil
+}
+
+func (m *CheckNetworkRequest) GetPing() []*Addr {
+	if m != nil {
+		return m.Ping
+	}
+	return nil
+}
+
+func (m *CheckNetworkRequest) GetDuration() *google_protobuf.Duration {
+	if m != nil {
+		return m.Duration
+	}
+	return nil
+}
+
+func (m *CheckNetworkRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *CheckNetworkRequest) GetMaxMtu() int32 {
+	if m != nil {
+		return m.MaxMtu
+	}
+	return 0
+}
+
+// CheckNetworkResponse describes the results of a network latency, packet loss
+// and path MTU test
+type CheckNetworkResponse struct {
+	// Listen describes the listen test results
+	Listen []*ServerResult `protobuf:"bytes,1,rep,name=listen" json:"listen,omitempty"`
+	// Ping describes the ping test results
+	Ping []*NetworkResult `protobuf:"bytes,2,rep,name=ping" json:"ping,omitempty"`
+}
+
+func (m *CheckNetworkResponse) Reset()                    { *m = CheckNetworkResponse{} }
+func (m *CheckNetworkResponse) String() string            { return proto1.CompactTextString(m) }
+func (*CheckNetworkResponse) ProtoMessage()               {}
+func (*CheckNetworkResponse) Descriptor() ([]byte, []int) { return fileDescriptorValidation, []int{11} }
+
+func (m *CheckNetworkResponse) GetListen() []*ServerResult {
+	if m != nil {
+		return m.Listen
+	}
+	return nil
+}
+
+func (m *CheckNetworkResponse) GetPing() []*NetworkResult {
+	if m != nil {
+		return m.Ping
+	}
+	return nil
+}
+
+// NetworkResult describes the quality of the network path to a server
+type NetworkResult struct {
+	// Code specifies the result, with 0 for success
+	Code int32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	// Error specifies an error message
+	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	// Server specifies which server the result is from
+	Server *Addr `protobuf:"bytes,3,opt,name=server" json:"server,omitempty"`
+	// Sent specifies the number of probes sent to the server
+	Sent int32 `protobuf:"varint,4,opt,name=sent,proto3" json:"sent,omitempty"`
+	// Received specifies the number of probes the server has replied to
+	Received int32 `protobuf:"varint,5,opt,name=received,proto3" json:"received,omitempty"`
+	// Latency specifies the average round-trip time
+	Latency *google_protobuf.Duration `protobuf:"bytes,6,opt,name=latency" json:"latency,omitempty"`
+	// Jitter specifies the average variation of the round-trip time
+	Jitter *google_protobuf.Duration `protobuf:"bytes,7,opt,name=jitter" json:"jitter,omitempty"`
+	// Mtu specifies the discovered path MTU
+	Mtu int32 `protobuf:"varint,8,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	// InterfaceMtu specifies the MTU of the local network interface
+	// used to reach the server
+	InterfaceMtu int32 `protobuf:"varint,9,opt,name=interface_mtu,json=interfaceMtu,proto3" json:"interface_mtu,omitempty"`
+}
+
+func (m *NetworkResult) Reset()                    { *m = NetworkResult{} }
+func (m *NetworkResult) String() string            { return proto1.CompactTextString(m) }
+func (*NetworkResult) ProtoMessage()               {}
+func (*NetworkResult) Descriptor() ([]byte, []int) { return fileDescriptorValidation, []int{12} }
+
+func (m *NetworkResult) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *NetworkResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *NetworkResult) GetServer() *Addr {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+func (m *NetworkResult) GetSent() int32 {
+	if m != nil {
+		return m.Sent
+	}
+	return 0
+}
+
+func (m *NetworkResult) GetReceived() int32 {
+	if m != nil {
+		return m.Received
+	}
+	return 0
+}
+
+func (m *NetworkResult) GetLatency() *google_protobuf.Duration {
+	if m != nil {
+		return m.Latency
+	}
+	return nil
+}
+
+func (m *NetworkResult) GetJitter() *google_protobuf.Duration {
+	if m != nil {
+		return m.Jitter
+	}
+	return nil
+}
+
+func (m *NetworkResult) GetMtu() int32 {
+	if m != nil {
+		return m.Mtu
+	}
+	return 0
+}
+
+func (m *NetworkResult) GetInterfaceMtu() int32 {
+	if m != nil {
+		return m.InterfaceMtu
+	}
+	return 0
+}
+
 func init() {
 	proto1.RegisterType((*CheckPortsRequest)(nil), "proto.CheckPortsRequest")
 	proto1.RegisterType((*CheckPortsResponse)(nil), "proto.CheckPortsResponse")
@@ -366,6 +543,9 @@ func init() {
 	proto1.RegisterType((*ValidateResponse)(nil), "proto.ValidateResponse")
 	proto1.RegisterType((*ValidateOptions)(nil), "proto.ValidateOptions")
 	proto1.RegisterType((*Docker)(nil), "proto.Docker")
+	proto1.RegisterType((*CheckNetworkRequest)(nil), "proto.CheckNetworkRequest")
+	proto1.RegisterType((*CheckNetworkResponse)(nil), "proto.CheckNetworkResponse")
+	proto1.RegisterType((*NetworkResult)(nil), "proto.NetworkResult")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -383,6 +563,8 @@ type ValidationClient interface {
 	CheckPorts(ctx context.Context, in *CheckPortsRequest, opts ...grpc.CallOption) (*CheckPortsResponse, error)
 	// CheckBandwidth executes a bandwidth network test
 	CheckBandwidth(ctx context.Context, in *CheckBandwidthRequest, opts ...grpc.CallOption) (*CheckBandwidthResponse, error)
+	// CheckNetwork executes a network latency, packet loss and path MTU test
+	CheckNetwork(ctx context.Context, in *CheckNetworkRequest, opts ...grpc.CallOption) (*CheckNetworkResponse, error)
 	// Validate validatest this node against the requirements
 	// from a manifest.
 	Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
@@ -414,6 +596,15 @@ func (c *validationClient) CheckBandwidth(ctx context.Context, in *CheckBandwidt
 	return out, nil
 }
 
+func (c *validationClient) CheckNetwork(ctx context.Context, in *CheckNetworkRequest, opts ...grpc.CallOption) (*CheckNetworkResponse, error) {
+	out := new(CheckNetworkResponse)
+	err := grpc.Invoke(ctx, "/proto.Validation/CheckNetwork", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *validationClient) Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
 	out := new(ValidateResponse)
 	err := grpc.Invoke(ctx, "/proto.Validation/Validate", in, out, c.cc, opts...)
@@ -430,6 +621,8 @@ type ValidationServer interface {
 	CheckPorts(context.Context, *CheckPortsRequest) (*CheckPortsResponse, error)
 	// CheckBandwidth executes a bandwidth network test
 	CheckBandwidth(context.Context, *CheckBandwidthRequest) (*CheckBandwidthResponse, error)
+	// CheckNetwork executes a network latency, packet loss and path MTU test
+	CheckNetwork(context.Context, *CheckNetworkRequest) (*CheckNetworkResponse, error)
 	// Validate validatest this node against the requirements
 	// from a manifest.
 	Validate(context.Context, *ValidateRequest) (*ValidateResponse, error)
@@ -475,6 +668,24 @@ func _Validation_CheckBandwidth_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Validation_CheckNetwork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckNetworkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ValidationServer).CheckNetwork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Validation/CheckNetwork",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ValidationServer).CheckNetwork(ctx, req.(*CheckNetworkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Validation_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ValidateRequest)
 	if err := dec(in); err != nil {
@@ -505,6 +716,10 @@ var _Validation_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CheckBandwidth",
 			Handler:    _Validation_CheckBandwidth_Handler,
 		},
+		{
+			MethodName: "CheckNetwork",
+			Handler:    _Validation_CheckNetwork_Handler,
+		},
 		{
 			MethodName: "Validate",
 			Handler:    _Validation_Validate_Handler,
@@ -907,79 +1122,261 @@ func (m *Docker) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
-func encodeFixed64Validation(dAtA []byte, offset int, v uint64) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	dAtA[offset+4] = uint8(v >> 32)
-	dAtA[offset+5] = uint8(v >> 40)
-	dAtA[offset+6] = uint8(v >> 48)
-	dAtA[offset+7] = uint8(v >> 56)
-	return offset + 8
-}
-func encodeFixed32Validation(dAtA []byte, offset int, v uint32) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	return offset + 4
-}
-func encodeVarintValidation(dAtA []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *CheckNetworkRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return offset + 1
+	return dAtA[:n], nil
 }
-func (m *CheckPortsRequest) Size() (n int) {
+
+func (m *CheckNetworkRequest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
 	if len(m.Listen) > 0 {
-		for _, e := range m.Listen {
-			l = e.Size()
-			n += 1 + l + sovValidation(uint64(l))
+		for _, msg := range m.Listen {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintValidation(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
 		}
 	}
 	if len(m.Ping) > 0 {
-		for _, e := range m.Ping {
-			l = e.Size()
-			n += 1 + l + sovValidation(uint64(l))
+		for _, msg := range m.Ping {
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintValidation(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
 		}
 	}
 	if m.Duration != nil {
-		l = m.Duration.Size()
-		n += 1 + l + sovValidation(uint64(l))
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Duration.Size()))
+		n7, err := m.Duration.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n7
 	}
-	return n
+	if m.Count != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Count))
+	}
+	if m.MaxMtu != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.MaxMtu))
+	}
+	return i, nil
 }
 
-func (m *CheckPortsResponse) Size() (n int) {
+func (m *CheckNetworkResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *CheckNetworkResponse) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
 	if len(m.Listen) > 0 {
-		for _, e := range m.Listen {
-			l = e.Size()
-			n += 1 + l + sovValidation(uint64(l))
+		for _, msg := range m.Listen {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintValidation(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
 		}
 	}
 	if len(m.Ping) > 0 {
-		for _, e := range m.Ping {
-			l = e.Size()
-			n += 1 + l + sovValidation(uint64(l))
+		for _, msg := range m.Ping {
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintValidation(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
 		}
 	}
-	return n
+	return i, nil
 }
 
-func (m *CheckBandwidthRequest) Size() (n int) {
+func (m *NetworkResult) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *NetworkResult) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.Listen != nil {
-		l = m.Listen.Size()
-		n += 1 + l + sovValidation(uint64(l))
+	if m.Code != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Code))
+	}
+	if len(m.Error) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(len(m.Error)))
+		i += copy(dAtA[i:], m.Error)
+	}
+	if m.Server != nil {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Server.Size()))
+		n8, err := m.Server.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n8
+	}
+	if m.Sent != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Sent))
+	}
+	if m.Received != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Received))
+	}
+	if m.Latency != nil {
+		dAtA[i] = 0x32
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Latency.Size()))
+		n9, err := m.Latency.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n9
+	}
+	if m.Jitter != nil {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Jitter.Size()))
+		n10, err := m.Jitter.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n10
+	}
+	if m.Mtu != 0 {
+		dAtA[i] = 0x40
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.Mtu))
+	}
+	if m.InterfaceMtu != 0 {
+		dAtA[i] = 0x48
+		i++
+		i = encodeVarintValidation(dAtA, i, uint64(m.InterfaceMtu))
+	}
+	return i, nil
+}
+func encodeFixed64Validation(dAtA []byte, offset int, v uint64) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	dAtA[offset+4] = uint8(v >> 32)
+	dAtA[offset+5] = uint8(v >> 40)
+	dAtA[offset+6] = uint8(v >> 48)
+	dAtA[offset+7] = uint8(v >> 56)
+	return offset + 8
+}
+func encodeFixed32Validation(dAtA []byte, offset int, v uint32) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	return offset + 4
+}
+func encodeVarintValidation(dAtA []byte, offset int, v uint64) int {
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return offset + 1
+}
+func (m *CheckPortsRequest) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Listen) > 0 {
+		for _, e := range m.Listen {
+			l = e.Size()
+			n += 1 + l + sovValidation(uint64(l))
+		}
+	}
+	if len(m.Ping) > 0 {
+		for _, e := range m.Ping {
+			l = e.Size()
+			n += 1 + l + sovValidation(uint64(l))
+		}
+	}
+	if m.Duration != nil {
+		l = m.Duration.Size()
+		n += 1 + l + sovValidation(uint64(l))
+	}
+	return n
+}
+
+func (m *CheckPortsResponse) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Listen) > 0 {
+		for _, e := range m.Listen {
+			l = e.Size()
+			n += 1 + l + sovValidation(uint64(l))
+		}
+	}
+	if len(m.Ping) > 0 {
+		for _, e := range m.Ping {
+			l = e.Size()
+			n += 1 + l + sovValidation(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *CheckBandwidthRequest) Size() (n int) {
+	var l int
+	_ = l
+	if m.Listen != nil {
+		l = m.Listen.Size()
+		n += 1 + l + sovValidation(uint64(l))
 	}
 	if len(m.Ping) > 0 {
 		for _, e := range m.Ping {
@@ -1099,6 +1496,89 @@ func (m *Docker) Size() (n int) {
 	return n
 }
 
+func (m *CheckNetworkRequest) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Listen) > 0 {
+		for _, e := range m.Listen {
+			l = e.Size()
+			n += 1 + l + sovValidation(uint64(l))
+		}
+	}
+	if len(m.Ping) > 0 {
+		for _, e := range m.Ping {
+			l = e.Size()
+			n += 1 + l + sovValidation(uint64(l))
+		}
+	}
+	if m.Duration != nil {
+		l = m.Duration.Size()
+		n += 1 + l + sovValidation(uint64(l))
+	}
+	if m.Count != 0 {
+		n += 1 + sovValidation(uint64(m.Count))
+	}
+	if m.MaxMtu != 0 {
+		n += 1 + sovValidation(uint64(m.MaxMtu))
+	}
+	return n
+}
+
+func (m *CheckNetworkResponse) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Listen) > 0 {
+		for _, e := range m.Listen {
+			l = e.Size()
+			n += 1 + l + sovValidation(uint64(l))
+		}
+	}
+	if len(m.Ping) > 0 {
+		for _, e := range m.Ping {
+			l = e.Size()
+			n += 1 + l + sovValidation(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *NetworkResult) Size() (n int) {
+	var l int
+	_ = l
+	if m.Code != 0 {
+		n += 1 + sovValidation(uint64(m.Code))
+	}
+	l = len(m.Error)
+	if l > 0 {
+		n += 1 + l + sovValidation(uint64(l))
+	}
+	if m.Server != nil {
+		l = m.Server.Size()
+		n += 1 + l + sovValidation(uint64(l))
+	}
+	if m.Sent != 0 {
+		n += 1 + sovValidation(uint64(m.Sent))
+	}
+	if m.Received != 0 {
+		n += 1 + sovValidation(uint64(m.Received))
+	}
+	if m.Latency != nil {
+		l = m.Latency.Size()
+		n += 1 + l + sovValidation(uint64(l))
+	}
+	if m.Jitter != nil {
+		l = m.Jitter.Size()
+		n += 1 + l + sovValidation(uint64(l))
+	}
+	if m.Mtu != 0 {
+		n += 1 + sovValidation(uint64(m.Mtu))
+	}
+	if m.InterfaceMtu != 0 {
+		n += 1 + sovValidation(uint64(m.InterfaceMtu))
+	}
+	return n
+}
+
 func sovValidation(x uint64) (n int) {
 	for {
 		n++
@@ -2297,6 +2777,574 @@ func (m *Docker) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *CheckNetworkRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowValidation
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: CheckNetworkRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: CheckNetworkRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Listen", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Listen = append(m.Listen, &Addr{})
+			if err := m.Listen[len(m.Listen)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ping", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ping = append(m.Ping, &Addr{})
+			if err := m.Ping[len(m.Ping)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Duration", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Duration == nil {
+				m.Duration = &google_protobuf.Duration{}
+			}
+			if err := m.Duration.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxMtu", wireType)
+			}
+			m.MaxMtu = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxMtu |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipValidation(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthValidation
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *CheckNetworkResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowValidation
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: CheckNetworkResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: CheckNetworkResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Listen", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Listen = append(m.Listen, &ServerResult{})
+			if err := m.Listen[len(m.Listen)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ping", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ping = append(m.Ping, &NetworkResult{})
+			if err := m.Ping[len(m.Ping)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipValidation(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthValidation
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *NetworkResult) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowValidation
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: NetworkResult: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: NetworkResult: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Error", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Error = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Server == nil {
+				m.Server = &Addr{}
+			}
+			if err := m.Server.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sent", wireType)
+			}
+			m.Sent = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Sent |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Received", wireType)
+			}
+			m.Received = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Received |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Latency", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Latency == nil {
+				m.Latency = &google_protobuf.Duration{}
+			}
+			if err := m.Latency.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Jitter", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthValidation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Jitter == nil {
+				m.Jitter = &google_protobuf.Duration{}
+			}
+			if err := m.Jitter.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mtu", wireType)
+			}
+			m.Mtu = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Mtu |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InterfaceMtu", wireType)
+			}
+			m.InterfaceMtu = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowValidation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.InterfaceMtu |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipValidation(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthValidation
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipValidation(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
@@ -2405,44 +3453,55 @@ var (
 func init() { proto1.RegisterFile("validation.proto", fileDescriptorValidation) }
 
 var fileDescriptorValidation = []byte{
-	// 620 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x54, 0xcd, 0x6e, 0x13, 0x31,
-	0x10, 0x66, 0xdb, 0x24, 0xcd, 0x4e, 0x7f, 0x48, 0x5d, 0x28, 0xdb, 0xd0, 0x86, 0x68, 0x51, 0x21,
-	0x52, 0xa5, 0x0d, 0x0a, 0x3f, 0x07, 0x38, 0xb5, 0xe4, 0x8a, 0xa8, 0x8c, 0xd4, 0x1b, 0x8a, 0x36,
-	0xb1, 0x93, 0x2c, 0xd9, 0xda, 0x5b, 0xdb, 0x9b, 0xf2, 0x18, 0x1c, 0x38, 0xf0, 0x48, 0x5c, 0x90,
-	0xb8, 0x73, 0x41, 0xe1, 0x45, 0x90, 0xbd, 0xde, 0xfc, 0x73, 0xe5, 0xb4, 0x9e, 0x99, 0x6f, 0xc6,
-	0xdf, 0x7c, 0xe3, 0x59, 0xa8, 0x8c, 0xc3, 0x38, 0x22, 0xa1, 0x8a, 0x38, 0x0b, 0x12, 0xc1, 0x15,
-	0x47, 0x45, 0xf3, 0xa9, 0xd6, 0x06, 0x9c, 0x0f, 0x62, 0xda, 0x34, 0x56, 0x37, 0xed, 0x37, 0x49,
-	0x2a, 0xe6, 0x60, 0xd5, 0x83, 0x70, 0x40, 0x99, 0x4a, 0xba, 0x4d, 0xf3, 0xcd, 0x9c, 0xfe, 0x17,
-	0x07, 0xf6, 0xdf, 0x0e, 0x69, 0x6f, 0x74, 0xc9, 0x85, 0x92, 0x98, 0xde, 0xa4, 0x54, 0x2a, 0xf4,
-	0x18, 0x4a, 0x71, 0x24, 0x15, 0x65, 0x9e, 0x53, 0xdf, 0x6c, 0x6c, 0xb7, 0xb6, 0x33, 0x74, 0x70,
-	0x4e, 0x88, 0xc0, 0x36, 0x84, 0x1e, 0x41, 0x21, 0x89, 0xd8, 0xc0, 0xdb, 0x58, 0x85, 0x98, 0x00,
-	0x7a, 0x09, 0xe5, 0x9c, 0x82, 0xb7, 0x59, 0x77, 0x1a, 0xdb, 0xad, 0xa3, 0x20, 0xe3, 0x18, 0xe4,
-	0x1c, 0x83, 0xb6, 0x05, 0xe0, 0x29, 0xd4, 0xff, 0x04, 0x68, 0x9e, 0x91, 0x4c, 0x38, 0x93, 0x14,
-	0x9d, 0x2d, 0x51, 0x3a, 0xb0, 0xf7, 0x7d, 0xa0, 0x62, 0x4c, 0x05, 0xa6, 0x32, 0x8d, 0xd5, 0x94,
-	0xda, 0xd3, 0x05, 0x6a, 0x6b, 0xa1, 0x06, 0xe0, 0x7f, 0x75, 0xe0, 0xbe, 0xb9, 0xec, 0x22, 0x64,
-	0xe4, 0x36, 0x22, 0x6a, 0xb8, 0x4e, 0x02, 0xe7, 0x7f, 0x4b, 0xf0, 0x0a, 0x0e, 0x97, 0x59, 0x59,
-	0x19, 0x8e, 0xc1, 0xed, 0xe6, 0x4e, 0xc3, 0xac, 0x80, 0x67, 0x0e, 0xff, 0x23, 0xec, 0xcc, 0x37,
-	0x89, 0x10, 0x14, 0x7a, 0x9c, 0x50, 0x03, 0x2c, 0x62, 0x73, 0x46, 0xf7, 0xa0, 0x48, 0x85, 0xe0,
-	0xc2, 0xdb, 0xa8, 0x3b, 0x0d, 0x17, 0x67, 0x86, 0x6e, 0x57, 0x9a, 0x4c, 0x4b, 0x73, 0xb1, 0xdd,
-	0x2c, 0xe4, 0xbf, 0x80, 0x82, 0xb6, 0x91, 0x07, 0x5b, 0x8c, 0xaa, 0x5b, 0x2e, 0x46, 0xa6, 0xb2,
-	0x8b, 0x73, 0x53, 0x5f, 0x18, 0x12, 0x92, 0xd7, 0x36, 0x67, 0xff, 0x87, 0x03, 0x77, 0xaf, 0xb2,
-	0x37, 0x4b, 0x73, 0x75, 0xab, 0x50, 0xbe, 0x0e, 0x59, 0xd4, 0xa7, 0x52, 0x99, 0x12, 0x3b, 0x78,
-	0x6a, 0xeb, 0xea, 0x89, 0xe0, 0xfd, 0x28, 0xa6, 0xb6, 0x4c, 0x6e, 0xa2, 0x33, 0xd8, 0xef, 0xa7,
-	0x71, 0xdc, 0x11, 0xf4, 0x26, 0x8d, 0x04, 0xbd, 0xa6, 0x4c, 0x49, 0xc3, 0xb7, 0x8c, 0x2b, 0x3a,
-	0x80, 0xe7, 0xfc, 0xe8, 0x19, 0x6c, 0xf1, 0x44, 0xab, 0x29, 0xbd, 0x82, 0x69, 0xe9, 0xd0, 0xb6,
-	0x94, 0x73, 0x79, 0x9f, 0x45, 0x71, 0x0e, 0x43, 0xa7, 0x50, 0x22, 0xbc, 0x37, 0xa2, 0xc2, 0x2b,
-	0x9a, 0x84, 0x5d, 0x9b, 0xd0, 0x36, 0x4e, 0x6c, 0x83, 0xfe, 0x6b, 0xa8, 0xcc, 0xda, 0xb1, 0x63,
-	0x79, 0x02, 0xa5, 0x7e, 0x18, 0xc5, 0x94, 0xd8, 0xd7, 0xb9, 0x17, 0xd8, 0x65, 0x0b, 0x2e, 0x05,
-	0xef, 0x52, 0x6c, 0xa3, 0xfe, 0x70, 0x26, 0x85, 0xbd, 0x1e, 0x9d, 0x00, 0x8c, 0x3f, 0xc7, 0x21,
-	0xeb, 0x24, 0x5c, 0x28, 0x3b, 0x29, 0xd7, 0x78, 0xf4, 0x02, 0xa0, 0x87, 0xe0, 0x12, 0x26, 0x3b,
-	0x5a, 0x49, 0x69, 0xde, 0x99, 0x8b, 0xcb, 0x84, 0x49, 0x3d, 0x07, 0x89, 0x8e, 0x40, 0x9f, 0xb3,
-	0xcc, 0x4d, 0x93, 0xb9, 0x45, 0x98, 0xd4, 0x79, 0x7e, 0x13, 0x4a, 0x19, 0x6f, 0x74, 0x0a, 0x7b,
-	0x52, 0x71, 0x11, 0x0e, 0x68, 0x87, 0x88, 0x48, 0x8f, 0x38, 0x1b, 0xda, 0xae, 0xf5, 0xb6, 0x8d,
-	0xb3, 0xf5, 0xcb, 0x01, 0xb8, 0x9a, 0xfe, 0x5a, 0xd0, 0x39, 0xc0, 0x6c, 0x0b, 0x91, 0x67, 0xa5,
-	0x58, 0xf9, 0x55, 0x54, 0x8f, 0xd6, 0x44, 0xac, 0x28, 0xef, 0x60, 0x6f, 0xf1, 0x15, 0xa3, 0xe3,
-	0x79, 0xf0, 0xf2, 0xca, 0x55, 0x4f, 0xfe, 0x11, 0xb5, 0xe5, 0xde, 0x40, 0x39, 0xd7, 0x0e, 0x2d,
-	0xcf, 0x32, 0x2f, 0xf1, 0x60, 0xc5, 0x9f, 0x25, 0x5f, 0x54, 0xbe, 0x4f, 0x6a, 0xce, 0xcf, 0x49,
-	0xcd, 0xf9, 0x3d, 0xa9, 0x39, 0xdf, 0xfe, 0xd4, 0xee, 0x74, 0x4b, 0x06, 0xf9, 0xfc, 0x6f, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0xa0, 0x27, 0x40, 0x32, 0x50, 0x05, 0x00, 0x00,
+	// 793 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x55, 0xcd, 0x4e, 0x23, 0x47,
+	0x10, 0xce, 0xf8, 0x67, 0x6c, 0x17, 0x36, 0x98, 0x86, 0xc0, 0x60, 0xc0, 0x41, 0x83, 0x48, 0x2c,
+	0x21, 0xd9, 0x09, 0x24, 0x39, 0x24, 0x27, 0x08, 0x52, 0x4e, 0x24, 0xa8, 0x23, 0x71, 0x8b, 0xac,
+	0xb1, 0xbb, 0x6d, 0x06, 0xc6, 0xdd, 0xa6, 0xbb, 0xc7, 0x90, 0xb7, 0xc8, 0x61, 0x0f, 0xfb, 0x00,
+	0x7b, 0xdd, 0x87, 0xd8, 0xdb, 0x5e, 0x56, 0xda, 0x47, 0x58, 0xb1, 0x2f, 0xb2, 0xea, 0x9e, 0x1e,
+	0xff, 0xe1, 0x15, 0xd2, 0x6a, 0xb5, 0xda, 0xd3, 0x74, 0x55, 0x7d, 0x55, 0xfd, 0x55, 0x55, 0x57,
+	0x0d, 0x54, 0x47, 0x41, 0x14, 0x92, 0x40, 0x85, 0x9c, 0x35, 0x87, 0x82, 0x2b, 0x8e, 0xf2, 0xe6,
+	0x53, 0xab, 0xf7, 0x39, 0xef, 0x47, 0xb4, 0x65, 0xa4, 0x4e, 0xdc, 0x6b, 0x91, 0x58, 0x4c, 0xc1,
+	0x6a, 0x6b, 0x41, 0x9f, 0x32, 0x35, 0xec, 0xb4, 0xcc, 0x37, 0x51, 0xfa, 0xff, 0x3b, 0xb0, 0xfa,
+	0xc7, 0x15, 0xed, 0xde, 0x5c, 0x70, 0xa1, 0x24, 0xa6, 0xb7, 0x31, 0x95, 0x0a, 0xed, 0x83, 0x1b,
+	0x85, 0x52, 0x51, 0xe6, 0x39, 0x7b, 0xd9, 0xc6, 0xd2, 0xd1, 0x52, 0x82, 0x6e, 0x9e, 0x10, 0x22,
+	0xb0, 0x35, 0xa1, 0xef, 0x20, 0x37, 0x0c, 0x59, 0xdf, 0xcb, 0x3c, 0x86, 0x18, 0x03, 0xfa, 0x05,
+	0x8a, 0x29, 0x05, 0x2f, 0xbb, 0xe7, 0x34, 0x96, 0x8e, 0xb6, 0x9a, 0x09, 0xc7, 0x66, 0xca, 0xb1,
+	0x79, 0x66, 0x01, 0x78, 0x0c, 0xf5, 0xaf, 0x01, 0x4d, 0x33, 0x92, 0x43, 0xce, 0x24, 0x45, 0x87,
+	0x73, 0x94, 0xd6, 0xec, 0x7d, 0xff, 0x50, 0x31, 0xa2, 0x02, 0x53, 0x19, 0x47, 0x6a, 0x4c, 0xed,
+	0x87, 0x19, 0x6a, 0x0b, 0xa1, 0x06, 0xe0, 0x3f, 0x73, 0xe0, 0x5b, 0x73, 0xd9, 0x69, 0xc0, 0xc8,
+	0x5d, 0x48, 0xd4, 0xd5, 0xa2, 0x12, 0x38, 0x5f, 0xba, 0x04, 0xbf, 0xc2, 0xc6, 0x3c, 0x2b, 0x5b,
+	0x86, 0x1d, 0x28, 0x75, 0x52, 0xa5, 0x61, 0x96, 0xc3, 0x13, 0x85, 0xff, 0x2f, 0x94, 0xa7, 0x93,
+	0x44, 0x08, 0x72, 0x5d, 0x4e, 0xa8, 0x01, 0xe6, 0xb1, 0x39, 0xa3, 0x75, 0xc8, 0x53, 0x21, 0xb8,
+	0xf0, 0x32, 0x7b, 0x4e, 0xa3, 0x84, 0x13, 0x41, 0xa7, 0x2b, 0x8d, 0xa7, 0xa5, 0x39, 0x9b, 0x6e,
+	0x62, 0xf2, 0x7f, 0x86, 0x9c, 0x96, 0x91, 0x07, 0x05, 0x46, 0xd5, 0x1d, 0x17, 0x37, 0x26, 0x72,
+	0x09, 0xa7, 0xa2, 0xbe, 0x30, 0x20, 0x24, 0x8d, 0x6d, 0xce, 0xfe, 0x1b, 0x07, 0x56, 0x2e, 0x93,
+	0x37, 0x4b, 0xd3, 0xea, 0xd6, 0xa0, 0x38, 0x08, 0x58, 0xd8, 0xa3, 0x52, 0x99, 0x10, 0x65, 0x3c,
+	0x96, 0x75, 0xf4, 0xa1, 0xe0, 0xbd, 0x30, 0xa2, 0x36, 0x4c, 0x2a, 0xa2, 0x43, 0x58, 0xed, 0xc5,
+	0x51, 0xd4, 0x16, 0xf4, 0x36, 0x0e, 0x05, 0x1d, 0x50, 0xa6, 0xa4, 0xe1, 0x5b, 0xc4, 0x55, 0x6d,
+	0xc0, 0x53, 0x7a, 0xf4, 0x23, 0x14, 0xf8, 0x50, 0x57, 0x53, 0x7a, 0x39, 0x93, 0xd2, 0x86, 0x4d,
+	0x29, 0xe5, 0xf2, 0x77, 0x62, 0xc5, 0x29, 0x0c, 0x1d, 0x80, 0x4b, 0x78, 0xf7, 0x86, 0x0a, 0x2f,
+	0x6f, 0x1c, 0x2a, 0xd6, 0xe1, 0xcc, 0x28, 0xb1, 0x35, 0xfa, 0xbf, 0x41, 0x75, 0x92, 0x8e, 0x6d,
+	0xcb, 0xf7, 0xe0, 0xf6, 0x82, 0x30, 0xa2, 0xc4, 0xbe, 0xce, 0xe5, 0xa6, 0x1d, 0xb6, 0xe6, 0x85,
+	0xe0, 0x1d, 0x8a, 0xad, 0xd5, 0xbf, 0x82, 0x95, 0xb9, 0xeb, 0xd1, 0x2e, 0xc0, 0xe8, 0x3e, 0x0a,
+	0x58, 0x7b, 0xc8, 0x85, 0xb2, 0x9d, 0x2a, 0x19, 0x8d, 0x1e, 0x00, 0xb4, 0x0d, 0x25, 0xc2, 0x64,
+	0x5b, 0x57, 0x52, 0x9a, 0x77, 0x56, 0xc2, 0x45, 0xc2, 0xa4, 0xee, 0x83, 0x44, 0x5b, 0xa0, 0xcf,
+	0x89, 0x67, 0xd6, 0x78, 0x16, 0x08, 0x93, 0xda, 0xcf, 0x6f, 0x81, 0x9b, 0xf0, 0x46, 0x07, 0xb0,
+	0x2c, 0x15, 0x17, 0x41, 0x9f, 0xb6, 0x89, 0x08, 0x75, 0x8b, 0x93, 0xa6, 0x55, 0xac, 0xf6, 0xcc,
+	0x28, 0xfd, 0x57, 0x0e, 0xac, 0x99, 0x47, 0xf7, 0x57, 0xd2, 0xcb, 0xaf, 0x61, 0x17, 0xe8, 0xc7,
+	0xda, 0xe5, 0x31, 0x53, 0xa6, 0x85, 0x79, 0x9c, 0x08, 0x68, 0x13, 0x0a, 0x83, 0xe0, 0xbe, 0x3d,
+	0x50, 0xb1, 0xe9, 0x54, 0x1e, 0xbb, 0x83, 0xe0, 0xfe, 0x5c, 0xc5, 0xfe, 0x00, 0xd6, 0x67, 0x53,
+	0xf8, 0x94, 0xe5, 0xd1, 0x98, 0xc9, 0x65, 0xdd, 0x42, 0x27, 0x21, 0x27, 0xdb, 0xe3, 0x65, 0x06,
+	0x2a, 0x33, 0xfa, 0xcf, 0x3c, 0x70, 0x3a, 0x9c, 0xa4, 0xe3, 0xec, 0xcd, 0x59, 0x8f, 0x8e, 0xa0,
+	0x5d, 0x1a, 0x8e, 0x28, 0xb1, 0xd9, 0x8f, 0x65, 0x74, 0x0c, 0x85, 0x28, 0x50, 0x94, 0x75, 0xff,
+	0xf3, 0xdc, 0xa7, 0x8a, 0x9c, 0x22, 0xd1, 0x4f, 0xe0, 0x5e, 0x87, 0x4a, 0x51, 0xe1, 0x15, 0x9e,
+	0xf2, 0xb1, 0x40, 0x54, 0x85, 0xac, 0x2e, 0x7e, 0xd1, 0x5c, 0xaf, 0x8f, 0x68, 0x1f, 0x2a, 0x21,
+	0x53, 0x54, 0xf4, 0x82, 0x2e, 0x35, 0x8d, 0x29, 0x19, 0x5b, 0x79, 0xac, 0x3c, 0x57, 0xf1, 0xd1,
+	0x8b, 0x0c, 0xc0, 0xe5, 0xf8, 0xef, 0x85, 0x4e, 0x00, 0x26, 0x8b, 0x1e, 0x79, 0xb6, 0x00, 0x8f,
+	0xfe, 0x46, 0xb5, 0xad, 0x05, 0x16, 0xdb, 0xd8, 0x73, 0x58, 0x9e, 0x5d, 0x94, 0x68, 0x67, 0x1a,
+	0x3c, 0xbf, 0xd5, 0x6b, 0xbb, 0x1f, 0xb1, 0xda, 0x70, 0x7f, 0x42, 0x79, 0xfa, 0xfd, 0xa0, 0xda,
+	0x34, 0x7c, 0x76, 0x2e, 0x6a, 0xdb, 0x0b, 0x6d, 0x36, 0xd0, 0xef, 0x50, 0x4c, 0xe7, 0x1c, 0xcd,
+	0xef, 0x9d, 0x34, 0xc0, 0xe6, 0x23, 0x7d, 0xe2, 0x7c, 0x5a, 0x7d, 0xfd, 0x50, 0x77, 0xde, 0x3e,
+	0xd4, 0x9d, 0x77, 0x0f, 0x75, 0xe7, 0xf9, 0xfb, 0xfa, 0x37, 0x1d, 0xd7, 0x20, 0x8f, 0x3f, 0x0c,
+	0x00, 0x72, 0x30, 0x97, 0xe5, 0xfc, 0x07, 0x00, 0x00,
 }
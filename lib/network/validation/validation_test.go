@@ -17,11 +17,17 @@ limitations under the License.
 package validation
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/gravitational/gravity/lib/defaults"
 	pb "github.com/gravitational/gravity/lib/network/validation/proto"
 
+	"github.com/sirupsen/logrus"
 	"gopkg.in/check.v1"
 )
 
@@ -61,6 +67,85 @@ func (r *ValidationSuite) TestComputesDiff(c *check.C) {
 	}
 }
 
+func (r *ValidationSuite) TestComputesPathStats(c *check.C) {
+	stats := pathStats{
+		sent: 5,
+		rtts: []time.Duration{
+			10 * time.Millisecond,
+			14 * time.Millisecond,
+			12 * time.Millisecond,
+			12 * time.Millisecond,
+		},
+	}
+	c.Assert(stats.latency(), check.Equals, 12*time.Millisecond)
+	c.Assert(stats.jitter(), check.Equals, 2*time.Millisecond)
+
+	var result pb.NetworkResult
+	stats.toProto(&result)
+	c.Assert(result.Sent, check.Equals, int32(5))
+	c.Assert(result.Received, check.Equals, int32(4))
+
+	c.Assert(pathStats{}.latency(), check.Equals, time.Duration(0))
+	c.Assert(pathStats{rtts: []time.Duration{time.Second}}.jitter(), check.Equals, time.Duration(0))
+}
+
+func (r *ValidationSuite) TestChecksNetworkOnLoopback(c *check.C) {
+	addr := fmt.Sprintf("127.0.0.1:%v", freePort(c))
+	servers := []*pb.Addr{
+		{Network: "tcp", Addr: addr},
+		{Network: "udp", Addr: addr},
+	}
+	server := NewServer(logrus.StandardLogger())
+	resp, err := server.CheckNetwork(context.TODO(), &pb.CheckNetworkRequest{
+		Listen:   servers,
+		Ping:     servers,
+		Duration: pb.DurationProto(3 * time.Second),
+		Count:    3,
+		MaxMtu:   1500,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Listen, check.HasLen, 2)
+	for _, listen := range resp.Listen {
+		c.Assert(listen.Code, check.Equals, int32(0), check.Commentf(listen.Error))
+	}
+	c.Assert(resp.Ping, check.HasLen, 2)
+	for _, ping := range resp.Ping {
+		c.Assert(ping.Code, check.Equals, int32(0), check.Commentf(ping.Error))
+		c.Assert(ping.Sent, check.Equals, int32(3))
+		c.Assert(ping.Received, check.Equals, int32(3))
+		c.Assert(ping.Mtu, check.Equals, int32(1500), check.Commentf(ping.Server.Network))
+	}
+}
+
+func (r *ValidationSuite) TestKeepsEchoListenerUpWhileProbed(c *check.C) {
+	// an idle listener stops once the duration expires
+	var lifetime echoLifetime
+	start := time.Now()
+	lifetime.wait(context.TODO(), 10*time.Millisecond, time.Minute)
+	c.Assert(time.Since(start) < defaults.NetworkTestIdleTimeout, check.Equals, true)
+
+	// a listener that is being probed is kept up until the maximum duration
+	lifetime.touch()
+	start = time.Now()
+	lifetime.wait(context.TODO(), 10*time.Millisecond, 200*time.Millisecond)
+	c.Assert(time.Since(start) >= 200*time.Millisecond, check.Equals, true)
+
+	// the listener stops when the test is cancelled
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	lifetime.touch()
+	start = time.Now()
+	lifetime.wait(ctx, time.Minute, time.Minute)
+	c.Assert(time.Since(start) < time.Second, check.Equals, true)
+}
+
+func freePort(c *check.C) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
 func sorted(servers []*pb.Addr) []*pb.Addr {
 	sort.Sort(byIPPort(servers))
 	return servers
@@ -43,7 +43,7 @@ func CheckServers(ctx context.Context, opKey SiteOperationKey,
 		return trace.Wrap(err)
 	}
 	remote := &remoteCommands{key: opKey, AgentService: agentService}
-	requirements, err := requirementsFromManifest(manifest)
+	requirements, err := checks.RequirementsFromManifest(manifest)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -52,6 +52,7 @@ func CheckServers(ctx context.Context, opKey SiteOperationKey,
 		return trace.Wrap(err)
 	}
 	c.TestBandwidth = true
+	c.TestNetwork = true
 	c.TestDockerDevice = true
 	return trace.Wrap(c.Run(ctx))
 }
@@ -93,6 +94,15 @@ func (r *remoteCommands) CheckBandwidth(ctx context.Context, req checks.PingPong
 	return resp, nil
 }
 
+// CheckNetwork validates the cluster network latency, packet loss and path MTU
+func (r *remoteCommands) CheckNetwork(ctx context.Context, req checks.PingPongGame) (checks.PingPongGameResults, error) {
+	resp, err := r.AgentService.CheckNetwork(ctx, r.key, req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp, nil
+}
+
 // Validate validates the node given with addr against the specified manifest.
 // Returns the list of failed test results.
 func (r *remoteCommands) Validate(ctx context.Context, addr string,
@@ -108,28 +118,6 @@ type remoteCommands struct {
 	key SiteOperationKey
 }
 
-func requirementsFromManifest(manifest schema.Manifest) (map[string]checks.Requirements, error) {
-	result := make(map[string]checks.Requirements)
-	for i, profile := range manifest.NodeProfiles {
-		tcp, udp, err := checks.PortsForProfile(profile)
-		if err != nil {
-			return nil, trace.Wrap(err)
-		}
-		req := checks.Requirements{
-			CPU:     &manifest.NodeProfiles[i].Requirements.CPU,
-			RAM:     &manifest.NodeProfiles[i].Requirements.RAM,
-			OS:      profile.Requirements.OS,
-			Volumes: profile.Requirements.Volumes,
-			Network: checks.Network{
-				MinTransferRate: profile.Requirements.Network.MinTransferRate,
-				Ports:           checks.Ports{TCP: tcp, UDP: udp},
-			},
-		}
-		result[profile.Name] = req
-	}
-	return result, nil
-}
-
 func mergeServers(infos checks.ServerInfos, servers []storage.Server) (result []checks.Server, err error) {
 	result = make([]checks.Server, 0, len(servers))
 	for _, server := range servers {
@@ -85,6 +85,8 @@ const (
 	SiteStateUninstalling = "uninstalling"
 	// SiteStateGarbageCollecting is the state of the cluster when it's removing unused resources
 	SiteStateGarbageCollecting = "collecting_garbage"
+	// SiteStateUpdatingProxy is the state of the cluster when it's applying the HTTP proxy environment
+	SiteStateUpdatingProxy = "updating_proxy"
 	// SiteStateDegraded means that the application installed on a deployed site is failing its health check
 	SiteStateDegraded = "degraded"
 	// SiteStateOffline means that OpsCenter cannot connect to remote site
@@ -124,6 +126,10 @@ const (
 	OperationGarbageCollect           = "operation_gc"
 	OperationGarbageCollectInProgress = "gc_in_progress"
 
+	// operation that applies the cluster HTTP proxy environment on nodes
+	OperationUpdateProxy           = "operation_update_proxy"
+	OperationUpdateProxyInProgress = "update_proxy_in_progress"
+
 	// common operation states
 	OperationStateCompleted = "completed"
 	OperationStateFailed    = "failed"
@@ -214,6 +220,7 @@ var (
 		OperationShrink:         SiteStateShrinking,
 		OperationUninstall:      SiteStateUninstalling,
 		OperationGarbageCollect: SiteStateGarbageCollecting,
+		OperationUpdateProxy:    SiteStateUpdatingProxy,
 	}
 
 	// OperationSucceededToClusterState defines states the cluster transitions
@@ -225,6 +232,7 @@ var (
 		OperationShrink:         SiteStateActive,
 		OperationUninstall:      SiteStateNotInstalled,
 		OperationGarbageCollect: SiteStateActive,
+		OperationUpdateProxy:    SiteStateActive,
 	}
 
 	// OperationFailedToClusterState defines states the cluster transitions
@@ -238,5 +246,6 @@ var (
 		OperationShrink:         SiteStateActive,
 		OperationUninstall:      SiteStateFailed,
 		OperationGarbageCollect: SiteStateActive,
+		OperationUpdateProxy:    SiteStateActive,
 	}
 )
@@ -229,6 +229,15 @@ func (o *OperatorACL) GetClusterNodes(key SiteKey) ([]Node, error) {
 	return o.operator.GetClusterNodes(key)
 }
 
+// GetClusterStatusHistory returns the recorded state transitions
+// of the cluster, its nodes and health probes
+func (o *OperatorACL) GetClusterStatusHistory(req ClusterStatusHistoryRequest) ([]StatusTransition, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetClusterStatusHistory(req)
+}
+
 func (o *OperatorACL) ResetUserPassword(req ResetUserPasswordRequest) (string, error) {
 	if err := o.Action(teleservices.KindUser, teleservices.VerbUpdate); err != nil {
 		return "", trace.Wrap(err)
@@ -553,11 +562,11 @@ func (o *OperatorACL) GetSiteOperationCrashReport(key SiteOperationKey) (io.Read
 	return o.operator.GetSiteOperationCrashReport(key)
 }
 
-func (o *OperatorACL) GetSiteReport(key SiteKey) (io.ReadCloser, error) {
-	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+func (o *OperatorACL) GetSiteReport(req SiteReportRequest) (io.ReadCloser, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return o.operator.GetSiteReport(key)
+	return o.operator.GetSiteReport(req)
 }
 
 func (o *OperatorACL) ValidateDomainName(domainName string) error {
@@ -734,6 +743,76 @@ func (o *OperatorACL) DeleteSMTPConfig(key SiteKey) error {
 	return o.operator.DeleteSMTPConfig(key)
 }
 
+func (o *OperatorACL) GetHostConfig(key SiteKey) (storage.HostConfig, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindHostConfig, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetHostConfig(key)
+}
+
+func (o *OperatorACL) UpdateHostConfig(key SiteKey, config storage.HostConfig) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindHostConfig, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpdateHostConfig(key, config)
+}
+
+func (o *OperatorACL) DeleteHostConfig(key SiteKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindHostConfig, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteHostConfig(key)
+}
+
+func (o *OperatorACL) GetTrustedCA(key SiteKey) (storage.TrustedCA, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindTrustedCA, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetTrustedCA(key)
+}
+
+func (o *OperatorACL) UpdateTrustedCA(key SiteKey, ca storage.TrustedCA) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindTrustedCA, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpdateTrustedCA(key, ca)
+}
+
+func (o *OperatorACL) DeleteTrustedCA(key SiteKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindTrustedCA, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteTrustedCA(key)
+}
+
+func (o *OperatorACL) GetProxy(key SiteKey) (storage.Proxy, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProxy, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetProxy(key)
+}
+
+func (o *OperatorACL) UpdateProxy(key SiteKey, proxy storage.Proxy) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProxy, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpdateProxy(key, proxy)
+}
+
+func (o *OperatorACL) DeleteProxy(key SiteKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProxy, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteProxy(key)
+}
+
+func (o *OperatorACL) CreateUpdateProxyOperation(req CreateUpdateProxyOperationRequest) (*SiteOperationKey, error) {
+	if err := o.ClusterAction(req.ClusterName, storage.KindProxy, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.CreateUpdateProxyOperation(req)
+}
+
 func (o *OperatorACL) GetAlerts(key SiteKey) ([]storage.Alert, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindAlert, teleservices.VerbList); err != nil {
 		return nil, trace.Wrap(err)
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -117,6 +118,9 @@ type Operator interface {
 	LogForwarders
 	Monitoring
 	SMTP
+	HostConfig
+	TrustedCA
+	ClusterProxy
 	Endpoints
 	Tokens
 	Certificates
@@ -298,7 +302,7 @@ type Sites interface {
 	CompleteFinalInstallStep(CompleteFinalInstallStepRequest) error
 
 	// GetSiteReport returns a tarball that contains all debugging information gathered for the site
-	GetSiteReport(SiteKey) (io.ReadCloser, error)
+	GetSiteReport(SiteReportRequest) (io.ReadCloser, error)
 
 	// SignTLSKey signs X509 Public Key with X509 certificate authority of this site
 	SignTLSKey(TLSSignRequest) (*TLSSignResponse, error)
@@ -464,6 +468,53 @@ func (r *CompleteFinalInstallStepRequest) CheckAndSetDefaults() error {
 	return nil
 }
 
+// SiteReportRequest is a request to collect the cluster diagnostics report
+type SiteReportRequest struct {
+	// SiteKey identifies the cluster
+	SiteKey
+	// Since limits the collected logs to the specified time window.
+	// Logs are not limited if unspecified
+	Since time.Duration `json:"since,omitempty"`
+	// Namespaces limits Kubernetes diagnostics to the specified namespaces.
+	// All namespaces are collected if unspecified
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Filters limits the report to the specified collectors.
+	// See constants.ReportFilters for the list of collectors.
+	// All diagnostics are collected if unspecified
+	Filters []string `json:"filters,omitempty"`
+	// RedactPatterns lists additional regular expressions matching
+	// the sensitive data to redact from the report
+	RedactPatterns []string `json:"redact_patterns,omitempty"`
+}
+
+// Check validates this request
+func (r SiteReportRequest) Check() error {
+	if err := r.SiteKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Since < 0 {
+		return trace.BadParameter("time window cannot be negative")
+	}
+	for _, filter := range r.Filters {
+		if !utils.StringInSlice(constants.ReportFilters, filter) {
+			return trace.BadParameter("unknown report collector %q, supported are: %v",
+				filter, strings.Join(constants.ReportFilters, ", "))
+		}
+	}
+	for _, pattern := range r.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return trace.BadParameter("invalid redaction pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// HasFilter returns true if the diagnostics of the specified collector
+// should be included in the report
+func (r SiteReportRequest) HasFilter(filter string) bool {
+	return len(r.Filters) == 0 || utils.StringInSlice(r.Filters, filter)
+}
+
 // Certificates contains methods for operating on cluster certificates
 type Certificates interface {
 	// GetClusterCertificate returns the cluster TLS certificate that is
@@ -533,6 +584,105 @@ type Status interface {
 	CheckSiteStatus(key SiteKey) error
 	// GetClusterNodes returns a real-time information about cluster nodes
 	GetClusterNodes(SiteKey) ([]Node, error)
+	// GetClusterStatusHistory returns the recorded state transitions
+	// of the cluster, its nodes and health probes
+	GetClusterStatusHistory(ClusterStatusHistoryRequest) ([]StatusTransition, error)
+}
+
+// ClusterStatusHistoryRequest is a request to retrieve the cluster status history
+type ClusterStatusHistoryRequest struct {
+	// SiteKey identifies the cluster
+	SiteKey
+	// From is the beginning of the time range.
+	// The history is not limited if unspecified
+	From time.Time `json:"from,omitempty"`
+	// To is the end of the time range.
+	// The history is not limited if unspecified
+	To time.Time `json:"to,omitempty"`
+	// Node limits the history to the node with the specified
+	// advertise IP address or hostname
+	Node string `json:"node,omitempty"`
+	// Probe limits the history to the health probe with the specified name
+	Probe string `json:"probe,omitempty"`
+}
+
+// Check validates this request
+func (r ClusterStatusHistoryRequest) Check() error {
+	if err := r.SiteKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.To.Before(r.From) {
+		return trace.BadParameter("end of the time range cannot precede its beginning")
+	}
+	return nil
+}
+
+// Matches returns true if the specified transition
+// satisfies the criteria of this request
+func (r ClusterStatusHistoryRequest) Matches(t StatusTransition) bool {
+	if !r.From.IsZero() && t.Time.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && t.Time.After(r.To) {
+		return false
+	}
+	if r.Node != "" && r.Node != t.Node && r.Node != t.Hostname {
+		return false
+	}
+	if r.Probe != "" && r.Probe != t.Probe {
+		return false
+	}
+	return true
+}
+
+// StatusTransition describes a change of state of the cluster,
+// a cluster node, a health probe on a node or a cluster operation
+type StatusTransition struct {
+	// Time is the time the new state has been recorded
+	Time time.Time `json:"time"`
+	// Node is the advertise IP address of the node.
+	// Empty for cluster and operation transitions
+	Node string `json:"node,omitempty"`
+	// Hostname is the hostname of the node
+	Hostname string `json:"hostname,omitempty"`
+	// Probe is the name of the health probe.
+	// Empty for node transitions
+	Probe string `json:"probe,omitempty"`
+	// OperationID is the ID of the operation for operation transitions
+	OperationID string `json:"operation_id,omitempty"`
+	// From is the previous state. Empty if the object has not been seen before
+	From string `json:"from"`
+	// To is the new state
+	To string `json:"to"`
+	// Detail optionally describes the new state
+	Detail string `json:"detail,omitempty"`
+}
+
+// String returns a textual representation of this transition
+func (t StatusTransition) String() string {
+	var subject string
+	switch {
+	case t.OperationID != "":
+		subject = fmt.Sprintf("operation %v", t.OperationID)
+	case t.Probe != "":
+		subject = fmt.Sprintf("probe %v on node %v", t.Probe, t.nodeName())
+	case t.Node != "":
+		subject = fmt.Sprintf("node %v", t.nodeName())
+	default:
+		subject = "cluster"
+	}
+	from := t.From
+	if from == "" {
+		from = "none"
+	}
+	return fmt.Sprintf("%v: %v -> %v", subject, from, t.To)
+}
+
+func (t StatusTransition) nodeName() string {
+	if t.Hostname != "" {
+		return fmt.Sprintf("%v (%v)", t.Hostname, t.Node)
+	}
+	return t.Node
 }
 
 // Node represents a cluster node information
@@ -929,6 +1079,8 @@ func (s *SiteOperation) String() string {
 		typeS = "uninstall"
 	case OperationGarbageCollect:
 		typeS = "garbage collect"
+	case OperationUpdateProxy:
+		typeS = "update proxy"
 	}
 	return fmt.Sprintf("operation(%v, cluster=%v, state=%s)", typeS, s.SiteDomain, s.State)
 }
@@ -1103,6 +1255,9 @@ type CreateSiteAppUpdateOperationRequest struct {
 	// Manual specifies whether a manual update mode is requested.
 	// Deprecated.
 	Manual bool `json:"manual"`
+	// RollbackPolicy optionally overrides the automatic rollback policy
+	// from the application manifest
+	RollbackPolicy *storage.RollbackPolicy `json:"rollback_policy,omitempty"`
 }
 
 // Check validates this request
@@ -1155,6 +1310,9 @@ type AgentService interface {
 	// CheckBandwidth executes bandwidth network test in agent cluster
 	CheckBandwidth(context.Context, SiteOperationKey, checks.PingPongGame) (checks.PingPongGameResults, error)
 
+	// CheckNetwork executes network latency, packet loss and path MTU test in agent cluster
+	CheckNetwork(context.Context, SiteOperationKey, checks.PingPongGame) (checks.PingPongGameResults, error)
+
 	// StopAgents instructs all remote agents to stop operation
 	// and rejects all consequitive requests to connect for any agent
 	// for this site
@@ -1594,6 +1752,59 @@ type SMTP interface {
 	DeleteSMTPConfig(SiteKey) error
 }
 
+// HostConfig defines the interface to manage cluster host configuration
+type HostConfig interface {
+	// GetHostConfig returns the cluster host configuration
+	GetHostConfig(SiteKey) (storage.HostConfig, error)
+	// UpdateHostConfig updates the cluster host configuration
+	UpdateHostConfig(SiteKey, storage.HostConfig) error
+	// DeleteHostConfig deletes the cluster host configuration
+	DeleteHostConfig(SiteKey) error
+}
+
+// TrustedCA defines the interface to manage certificate authorities trusted by cluster nodes
+type TrustedCA interface {
+	// GetTrustedCA returns the cluster trusted certificate authorities
+	GetTrustedCA(SiteKey) (storage.TrustedCA, error)
+	// UpdateTrustedCA updates the cluster trusted certificate authorities
+	UpdateTrustedCA(SiteKey, storage.TrustedCA) error
+	// DeleteTrustedCA deletes the cluster trusted certificate authorities
+	DeleteTrustedCA(SiteKey) error
+}
+
+// ClusterProxy defines the interface to manage cluster HTTP proxy configuration
+type ClusterProxy interface {
+	// GetProxy returns the cluster HTTP proxy configuration
+	GetProxy(SiteKey) (storage.Proxy, error)
+	// UpdateProxy updates the cluster HTTP proxy configuration
+	UpdateProxy(SiteKey, storage.Proxy) error
+	// DeleteProxy deletes the cluster HTTP proxy configuration
+	DeleteProxy(SiteKey) error
+	// CreateUpdateProxyOperation creates a new operation that applies
+	// the cluster HTTP proxy environment on cluster nodes
+	CreateUpdateProxyOperation(CreateUpdateProxyOperationRequest) (*SiteOperationKey, error)
+}
+
+// Check validates this request
+func (r CreateUpdateProxyOperationRequest) Check() error {
+	if r.AccountID == "" {
+		return trace.BadParameter("missing AccountID")
+	}
+	if r.ClusterName == "" {
+		return trace.BadParameter("missing ClusterName")
+	}
+	return nil
+}
+
+// CreateUpdateProxyOperationRequest is a request to apply
+// the cluster HTTP proxy environment on cluster nodes
+type CreateUpdateProxyOperationRequest struct {
+	// AccountID is id of the account
+	AccountID string `json:"account_id"`
+	// ClusterName is the name of the cluster
+	ClusterName string `json:"cluster_name"`
+}
+
 // Monitoring defines the interface to manage monitoring and metrics
 type Monitoring interface {
 	// GetRetentionPolicies returns a list of retention policies for the site
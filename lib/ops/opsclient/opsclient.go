@@ -357,6 +357,34 @@ func (c *Client) GetClusterNodes(key ops.SiteKey) ([]ops.Node, error) {
 	return nodes, nil
 }
 
+// GetClusterStatusHistory returns the recorded state transitions
+// of the cluster, its nodes and health probes
+func (c *Client) GetClusterStatusHistory(req ops.ClusterStatusHistoryRequest) ([]ops.StatusTransition, error) {
+	query := url.Values{}
+	if !req.From.IsZero() {
+		query.Set("from", req.From.Format(time.RFC3339Nano))
+	}
+	if !req.To.IsZero() {
+		query.Set("to", req.To.Format(time.RFC3339Nano))
+	}
+	if req.Node != "" {
+		query.Set("node", req.Node)
+	}
+	if req.Probe != "" {
+		query.Set("probe", req.Probe)
+	}
+	out, err := c.Get(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "status", "history"), query)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var transitions []ops.StatusTransition
+	err = json.Unmarshal(out.Bytes(), &transitions)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return transitions, nil
+}
+
 func (c *Client) ResetUserPassword(req ops.ResetUserPasswordRequest) (string, error) {
 	out, err := c.PutJSON(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "reset-password"), req)
 	if err != nil {
@@ -646,8 +674,16 @@ func (c *Client) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.ReadC
 	return file.Body(), nil
 }
 
-func (c *Client) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	file, err := c.GetFile(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "report"), url.Values{})
+func (c *Client) GetSiteReport(req ops.SiteReportRequest) (io.ReadCloser, error) {
+	query := url.Values{
+		"namespace": req.Namespaces,
+		"filter":    req.Filters,
+		"redact":    req.RedactPatterns,
+	}
+	if req.Since != 0 {
+		query.Set("since", req.Since.String())
+	}
+	file, err := c.GetFile(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "report"), query)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -1007,6 +1043,138 @@ func (c *Client) DeleteSMTPConfig(key ops.SiteKey) error {
 	return trace.Wrap(err)
 }
 
+// GetHostConfig returns the cluster host configuration
+func (c *Client) GetHostConfig(key ops.SiteKey) (storage.HostConfig, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "hostconfig"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var raw json.RawMessage
+	if err := json.Unmarshal(response.Bytes(), &raw); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	config, err := storage.UnmarshalHostConfig(raw)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return config, nil
+}
+
+// UpdateHostConfig updates the cluster host configuration
+func (c *Client) UpdateHostConfig(key ops.SiteKey, config storage.HostConfig) error {
+	bytes, err := storage.MarshalHostConfig(config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "hostconfig"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteHostConfig deletes the cluster host configuration
+func (c *Client) DeleteHostConfig(key ops.SiteKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "hostconfig"))
+	return trace.Wrap(err)
+}
+
+// GetTrustedCA returns the cluster trusted certificate authorities
+func (c *Client) GetTrustedCA(key ops.SiteKey) (storage.TrustedCA, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "trustedca"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var raw json.RawMessage
+	if err := json.Unmarshal(response.Bytes(), &raw); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	ca, err := storage.UnmarshalTrustedCA(raw)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return ca, nil
+}
+
+// UpdateTrustedCA updates the cluster trusted certificate authorities
+func (c *Client) UpdateTrustedCA(key ops.SiteKey, ca storage.TrustedCA) error {
+	bytes, err := storage.MarshalTrustedCA(ca)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "trustedca"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteTrustedCA deletes the cluster trusted certificate authorities
+func (c *Client) DeleteTrustedCA(key ops.SiteKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "trustedca"))
+	return trace.Wrap(err)
+}
+
+// GetProxy returns the cluster HTTP proxy configuration
+func (c *Client) GetProxy(key ops.SiteKey) (storage.Proxy, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "proxy"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var raw json.RawMessage
+	if err := json.Unmarshal(response.Bytes(), &raw); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	proxy, err := storage.UnmarshalProxy(raw)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return proxy, nil
+}
+
+// UpdateProxy updates the cluster HTTP proxy configuration
+func (c *Client) UpdateProxy(key ops.SiteKey, proxy storage.Proxy) error {
+	bytes, err := storage.MarshalProxy(proxy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "proxy"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteProxy deletes the cluster HTTP proxy configuration
+func (c *Client) DeleteProxy(key ops.SiteKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "proxy"))
+	return trace.Wrap(err)
+}
+
+// CreateUpdateProxyOperation creates a new operation that applies
+// the cluster HTTP proxy environment on cluster nodes
+func (c *Client) CreateUpdateProxyOperation(req ops.CreateUpdateProxyOperationRequest) (*ops.SiteOperationKey, error) {
+	out, err := c.PostJSON(c.Endpoint("accounts", req.AccountID, "sites", req.ClusterName, "operations", "proxy"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var key ops.SiteOperationKey
+	if err := json.Unmarshal(out.Bytes(), &key); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &key, nil
+}
+
 // GetAlerts returns a list of monitoring alerts for the cluster
 func (c *Client) GetAlerts(key ops.SiteKey) ([]storage.Alert, error) {
 	response, err := c.Get(c.Endpoint(
@@ -144,6 +144,7 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 
 	// Status API
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/status", h.needsAuth(h.checkSiteStatus))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/status/history", h.needsAuth(h.getClusterStatusHistory))
 
 	// TODO(klizhetas) refactor this method
 	h.GET("/portal/v1/sites/domain/:domain", h.needsAuth(h.getSiteByDomain))
@@ -224,6 +225,22 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/smtp", h.needsAuth(h.updateSMTPConfig))
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/smtp", h.needsAuth(h.deleteSMTPConfig))
 
+	// host configuration
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/hostconfig", h.needsAuth(h.getHostConfig))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/hostconfig", h.needsAuth(h.updateHostConfig))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/hostconfig", h.needsAuth(h.deleteHostConfig))
+
+	// trusted certificate authorities
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/trustedca", h.needsAuth(h.getTrustedCA))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/trustedca", h.needsAuth(h.updateTrustedCA))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/trustedca", h.needsAuth(h.deleteTrustedCA))
+
+	// cluster HTTP proxy configuration
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/proxy", h.needsAuth(h.getProxy))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/proxy", h.needsAuth(h.updateProxy))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/proxy", h.needsAuth(h.deleteProxy))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/proxy", h.needsAuth(h.createUpdateProxyOperation))
+
 	// monitoring
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/retention", h.needsAuth(h.getRetentionPolicies))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/retention", h.needsAuth(h.updateRetentionPolicy))
@@ -920,10 +937,27 @@ func (h *WebHandler) activateSite(w http.ResponseWriter, r *http.Request, p http
 
 /* getSiteReport returns a tarball with collected information about the site
 
-   GET /portal/v1/accounts/:account_id/sites/:site_domain/report
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/report?since=<duration>&namespace=<namespace>&filter=<collector>&redact=<pattern>
 */
 func (h *WebHandler) getSiteReport(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	report, err := context.Operator.GetSiteReport(siteKey(p))
+	query := r.URL.Query()
+	req := ops.SiteReportRequest{
+		SiteKey:        siteKey(p),
+		Namespaces:     query["namespace"],
+		Filters:        query["filter"],
+		RedactPatterns: query["redact"],
+	}
+	if since := query.Get("since"); since != "" {
+		var err error
+		req.Since, err = time.ParseDuration(since)
+		if err != nil {
+			return trace.BadParameter("invalid time window %q: %v", since, err)
+		}
+	}
+	if err := req.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	report, err := context.Operator.GetSiteReport(req)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -1142,6 +1176,44 @@ func (h *WebHandler) checkSiteStatus(w http.ResponseWriter, r *http.Request, p h
 	return nil
 }
 
+/*  getClusterStatusHistory returns the recorded state transitions of the cluster,
+    its nodes and health probes
+
+    GET /portal/v1/accounts/:account_id/sites/:site_domain/status/history?from=<time>&to=<time>&node=<node>&probe=<probe>
+
+    Success response: []ops.StatusTransition
+*/
+func (h *WebHandler) getClusterStatusHistory(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	query := r.URL.Query()
+	req := ops.ClusterStatusHistoryRequest{
+		SiteKey: siteKey(p),
+		Node:    query.Get("node"),
+		Probe:   query.Get("probe"),
+	}
+	var err error
+	if from := query.Get("from"); from != "" {
+		req.From, err = time.Parse(time.RFC3339Nano, from)
+		if err != nil {
+			return trace.BadParameter("invalid time %q: %v", from, err)
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		req.To, err = time.Parse(time.RFC3339Nano, to)
+		if err != nil {
+			return trace.BadParameter("invalid time %q: %v", to, err)
+		}
+	}
+	if err := req.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	transitions, err := context.Operator.GetClusterStatusHistory(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, transitions)
+	return nil
+}
+
 /*  validateDomainName checks if the specified domain name has already been allocated
 
     GET /portal/v1/domains/:domain
@@ -2060,6 +2132,242 @@ func (h *WebHandler) deleteSMTPConfig(w http.ResponseWriter, r *http.Request, p
 	return nil
 }
 
+/* getHostConfig returns the cluster host configuration
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/hostconfig
+
+   Success Response:
+
+     storage.HostConfig
+*/
+func (h *WebHandler) getHostConfig(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	config, err := context.Operator.GetHostConfig(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, config)
+	return nil
+}
+
+/* updateHostConfig updates the cluster host configuration
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/hostconfig
+
+   Success Response:
+
+     {
+       "message": "host configuration updated"
+     }
+*/
+func (h *WebHandler) updateHostConfig(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+
+	config, err := storage.UnmarshalHostConfig(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = context.Operator.UpdateHostConfig(siteKey(p), config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("host configuration updated"))
+	return nil
+}
+
+/* deleteHostConfig deletes the cluster host configuration
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/hostconfig
+
+   Success Response:
+
+     {
+       "message": "host configuration deleted"
+     }
+*/
+func (h *WebHandler) deleteHostConfig(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteHostConfig(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("host configuration deleted"))
+	return nil
+}
+
+/* getTrustedCA returns the cluster trusted certificate authorities
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/trustedca
+
+   Success Response:
+
+     storage.TrustedCA
+*/
+func (h *WebHandler) getTrustedCA(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	ca, err := context.Operator.GetTrustedCA(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, ca)
+	return nil
+}
+
+/* updateTrustedCA updates the cluster trusted certificate authorities
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/trustedca
+
+   Success Response:
+
+     {
+       "message": "trusted certificate authorities updated"
+     }
+*/
+func (h *WebHandler) updateTrustedCA(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+
+	ca, err := storage.UnmarshalTrustedCA(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = context.Operator.UpdateTrustedCA(siteKey(p), ca)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("trusted certificate authorities updated"))
+	return nil
+}
+
+/* deleteTrustedCA deletes the cluster trusted certificate authorities
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/trustedca
+
+   Success Response:
+
+     {
+       "message": "trusted certificate authorities deleted"
+     }
+*/
+func (h *WebHandler) deleteTrustedCA(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteTrustedCA(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("trusted certificate authorities deleted"))
+	return nil
+}
+
+/* getProxy returns the cluster HTTP proxy configuration
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/proxy
+
+   Success Response:
+
+     storage.Proxy
+*/
+func (h *WebHandler) getProxy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	proxy, err := context.Operator.GetProxy(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, proxy)
+	return nil
+}
+
+/* updateProxy updates the cluster HTTP proxy configuration
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/proxy
+
+   Success Response:
+
+     {
+       "message": "HTTP proxy configuration updated"
+     }
+*/
+func (h *WebHandler) updateProxy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+
+	proxy, err := storage.UnmarshalProxy(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = context.Operator.UpdateProxy(siteKey(p), proxy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("HTTP proxy configuration updated"))
+	return nil
+}
+
+/* deleteProxy deletes the cluster HTTP proxy configuration
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/proxy
+
+   Success Response:
+
+     {
+       "message": "HTTP proxy configuration deleted"
+     }
+*/
+func (h *WebHandler) deleteProxy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteProxy(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("HTTP proxy configuration deleted"))
+	return nil
+}
+
+/* createUpdateProxyOperation creates a new operation that applies the cluster
+   HTTP proxy environment on cluster nodes
+
+   POST	/portal/v1/accounts/:account_id/sites/:site_domain/operations/proxy
+
+   {
+      "account_id": "account id",
+      "cluster_name": "cluster_name",
+   }
+
+
+Success response:
+
+   {
+      "account_id": "account id",
+      "site_id": "cluster_name",
+      "operation_id": "operation id"
+   }
+*/
+func (h *WebHandler) createUpdateProxyOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	d := json.NewDecoder(r.Body)
+	var req ops.CreateUpdateProxyOperationRequest
+	if err := d.Decode(&req); err != nil {
+		return trace.BadParameter(err.Error())
+	}
+
+	key := siteKey(p)
+	req.AccountID = key.AccountID
+	req.ClusterName = key.SiteDomain
+	op, err := context.Operator.CreateUpdateProxyOperation(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, op)
+	return nil
+}
+
 /* getApplicationEndpoints returns application endpoints for a deployed cluster
 
      GET /portal/v1/accounts/:account_id/sites/:site_domain/endpoints
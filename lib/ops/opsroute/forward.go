@@ -172,6 +172,16 @@ func (r *Router) GetClusterNodes(key ops.SiteKey) ([]ops.Node, error) {
 	return client.GetClusterNodes(key)
 }
 
+// GetClusterStatusHistory returns the recorded state transitions
+// of the cluster, its nodes and health probes
+func (r *Router) GetClusterStatusHistory(req ops.ClusterStatusHistoryRequest) ([]ops.StatusTransition, error) {
+	client, err := r.PickClient(req.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetClusterStatusHistory(req)
+}
+
 func (r *Router) ResetUserPassword(req ops.ResetUserPasswordRequest) (string, error) {
 	client, err := r.PickClient(req.SiteDomain)
 	if err != nil {
@@ -404,12 +414,12 @@ func (r *Router) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.ReadC
 	return client.GetSiteOperationCrashReport(key)
 }
 
-func (r *Router) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	client, err := r.PickClient(key.SiteDomain)
+func (r *Router) GetSiteReport(req ops.SiteReportRequest) (io.ReadCloser, error) {
+	client, err := r.PickClient(req.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return client.GetSiteReport(key)
+	return client.GetSiteReport(req)
 }
 
 // ValidateServers runs pre-installation checks
@@ -600,6 +610,97 @@ func (r *Router) DeleteSMTPConfig(key ops.SiteKey) error {
 	return client.DeleteSMTPConfig(key)
 }
 
+// GetHostConfig returns the cluster host configuration
+func (r *Router) GetHostConfig(key ops.SiteKey) (storage.HostConfig, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetHostConfig(key)
+}
+
+// UpdateHostConfig updates the cluster host configuration
+func (r *Router) UpdateHostConfig(key ops.SiteKey, config storage.HostConfig) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpdateHostConfig(key, config)
+}
+
+// DeleteHostConfig deletes the cluster host configuration
+func (r *Router) DeleteHostConfig(key ops.SiteKey) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteHostConfig(key)
+}
+
+// GetTrustedCA returns the cluster trusted certificate authorities
+func (r *Router) GetTrustedCA(key ops.SiteKey) (storage.TrustedCA, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetTrustedCA(key)
+}
+
+// UpdateTrustedCA updates the cluster trusted certificate authorities
+func (r *Router) UpdateTrustedCA(key ops.SiteKey, ca storage.TrustedCA) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpdateTrustedCA(key, ca)
+}
+
+// DeleteTrustedCA deletes the cluster trusted certificate authorities
+func (r *Router) DeleteTrustedCA(key ops.SiteKey) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteTrustedCA(key)
+}
+
+// GetProxy returns the cluster HTTP proxy configuration
+func (r *Router) GetProxy(key ops.SiteKey) (storage.Proxy, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetProxy(key)
+}
+
+// UpdateProxy updates the cluster HTTP proxy configuration
+func (r *Router) UpdateProxy(key ops.SiteKey, proxy storage.Proxy) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpdateProxy(key, proxy)
+}
+
+// DeleteProxy deletes the cluster HTTP proxy configuration
+func (r *Router) DeleteProxy(key ops.SiteKey) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteProxy(key)
+}
+
+// CreateUpdateProxyOperation creates a new operation that applies
+// the cluster HTTP proxy environment on cluster nodes
+func (r *Router) CreateUpdateProxyOperation(req ops.CreateUpdateProxyOperationRequest) (*ops.SiteOperationKey, error) {
+	client, err := r.RemoteClient(req.ClusterName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.CreateUpdateProxyOperation(req)
+}
+
 // GetAlerts returns a list of monitoring alerts
 func (r *Router) GetAlerts(key ops.SiteKey) ([]storage.Alert, error) {
 	client, err := r.RemoteClient(key.SiteDomain)
@@ -226,6 +226,22 @@ func (r *AgentService) CheckBandwidth(ctx context.Context, key ops.SiteOperation
 	return results, nil
 }
 
+// CheckNetwork executes the network latency, packet loss and path MTU test
+// in the agent cluster
+func (r *AgentService) CheckNetwork(ctx context.Context, key ops.SiteOperationKey, game checks.PingPongGame) (checks.PingPongGameResults, error) {
+	group, err := r.peerStore.getOrCreateGroup(key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	results, err := pingPong(ctx, group.AgentGroup, game, network)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return results, nil
+}
+
 // Wait blocks until the specified number of agents have connected for the
 // the given operation. Context can be used for canceling the operation.
 func (r *AgentService) Wait(ctx context.Context, key ops.SiteOperationKey, numAgents int) error {
@@ -585,7 +601,7 @@ func pingPong(ctx context.Context, group rpcserver.AgentGroup, game checks.PingP
 				return nil, trace.Wrap(result.err)
 			}
 			results[result.addr] = *result.resp
-		case <-time.After(2 * req.Duration):
+		case <-time.After(req.Timeout()):
 			return nil, trace.LimitExceeded("timeout waiting for servers")
 		}
 	}
@@ -610,6 +626,15 @@ func bandwidth(ctx context.Context, group rpcserver.AgentGroup, addr string, req
 	resultsCh <- pingpongResult{addr: addr, resp: checks.ResultFromBandwidthProto(resp, nil)}
 }
 
+func network(ctx context.Context, group rpcserver.AgentGroup, addr string, req checks.PingPongRequest, resultsCh chan<- pingpongResult) {
+	resp, err := group.WithContext(ctx, addr).CheckNetwork(ctx, req.NetworkProto())
+	if err != nil {
+		resultsCh <- pingpongResult{addr: addr, err: err}
+		return
+	}
+	resultsCh <- pingpongResult{addr: addr, resp: checks.ResultFromNetworkProto(resp, nil)}
+}
+
 type pingpongHandler func(ctx context.Context, group rpcserver.AgentGroup, addr string, req checks.PingPongRequest, resultsCh chan<- pingpongResult)
 
 type pingpongResult struct {
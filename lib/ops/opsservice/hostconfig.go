@@ -0,0 +1,119 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// GetHostConfig returns the cluster host configuration
+func (o *Operator) GetHostConfig(key ops.SiteKey) (storage.HostConfig, error) {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return GetHostConfig(client.Core().ConfigMaps(defaults.KubeSystemNamespace))
+}
+
+// UpdateHostConfig updates the cluster host configuration
+func (o *Operator) UpdateHostConfig(key ops.SiteKey, config storage.HostConfig) error {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return updateHostConfig(client.Core().ConfigMaps(defaults.KubeSystemNamespace), config)
+}
+
+// DeleteHostConfig deletes the cluster host configuration
+func (o *Operator) DeleteHostConfig(key ops.SiteKey) error {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = rigging.ConvertError(client.Core().ConfigMaps(defaults.KubeSystemNamespace).
+		Delete(defaults.HostConfigConfigMap, nil))
+	if trace.IsNotFound(err) {
+		return trace.NotFound("no host configuration found")
+	}
+	return trace.Wrap(err)
+}
+
+// GetHostConfig returns the host configuration stored in the config map
+func GetHostConfig(client corev1.ConfigMapInterface) (storage.HostConfig, error) {
+	configMap, err := client.Get(defaults.HostConfigConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("no host configuration found")
+		}
+		return nil, trace.Wrap(err)
+	}
+
+	data, ok := configMap.Data[constants.ResourceSpecKey]
+	if !ok {
+		return nil, trace.NotFound("no host configuration found")
+	}
+
+	config, err := storage.UnmarshalHostConfig([]byte(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return config, nil
+}
+
+func updateHostConfig(client corev1.ConfigMapInterface, config storage.HostConfig) error {
+	bytes, err := storage.MarshalHostConfig(config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      defaults.HostConfigConfigMap,
+			Namespace: defaults.KubeSystemNamespace,
+		},
+		Data: map[string]string{
+			constants.ResourceSpecKey: string(bytes),
+		},
+	}
+
+	_, err = client.Create(configMap)
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return nil
+	}
+
+	if !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+
+	_, err = client.Update(configMap)
+	return trace.Wrap(rigging.ConvertError(err))
+}
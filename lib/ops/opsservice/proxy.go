@@ -0,0 +1,173 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// GetProxy returns the cluster HTTP proxy configuration
+func (o *Operator) GetProxy(key ops.SiteKey) (storage.Proxy, error) {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return GetProxy(client.Core().ConfigMaps(defaults.KubeSystemNamespace))
+}
+
+// UpdateProxy updates the cluster HTTP proxy configuration.
+// Besides the resource, the config map stores the resulting proxy
+// environment which is consumed by gravity-site and application hooks
+func (o *Operator) UpdateProxy(key ops.SiteKey, proxy storage.Proxy) error {
+	cluster, err := o.GetSite(key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	env, err := ops.GetProxyEnv(o, *cluster, proxy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return updateProxy(client.Core().ConfigMaps(defaults.KubeSystemNamespace), proxy, env)
+}
+
+// DeleteProxy deletes the cluster HTTP proxy configuration
+func (o *Operator) DeleteProxy(key ops.SiteKey) error {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = rigging.ConvertError(client.Core().ConfigMaps(defaults.KubeSystemNamespace).
+		Delete(defaults.ProxyConfigMap, nil))
+	if trace.IsNotFound(err) {
+		return trace.NotFound("no HTTP proxy configuration found")
+	}
+	return trace.Wrap(err)
+}
+
+// CreateUpdateProxyOperation creates a new operation that applies
+// the cluster HTTP proxy environment on cluster nodes
+func (o *Operator) CreateUpdateProxyOperation(req ops.CreateUpdateProxyOperationRequest) (*ops.SiteOperationKey, error) {
+	err := req.Check()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	cluster, err := o.openSite(ops.SiteKey{AccountID: req.AccountID, SiteDomain: req.ClusterName})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return cluster.createUpdateProxyOperation(req)
+}
+
+// createUpdateProxyOperation creates a new operation that applies
+// the cluster HTTP proxy environment on cluster nodes
+func (s *site) createUpdateProxyOperation(req ops.CreateUpdateProxyOperationRequest) (*ops.SiteOperationKey, error) {
+	op := ops.SiteOperation{
+		ID:         uuid.New(),
+		AccountID:  s.key.AccountID,
+		SiteDomain: s.key.SiteDomain,
+		Type:       ops.OperationUpdateProxy,
+		Created:    s.clock().UtcNow(),
+		Updated:    s.clock().UtcNow(),
+		State:      ops.OperationUpdateProxyInProgress,
+	}
+
+	key, err := s.getOperationGroup().createSiteOperation(op)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return key, nil
+}
+
+// GetProxy returns the HTTP proxy configuration stored in the config map
+func GetProxy(client corev1.ConfigMapInterface) (storage.Proxy, error) {
+	configMap, err := client.Get(defaults.ProxyConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("no HTTP proxy configuration found")
+		}
+		return nil, trace.Wrap(err)
+	}
+
+	data, ok := configMap.Data[constants.ResourceSpecKey]
+	if !ok {
+		return nil, trace.NotFound("no HTTP proxy configuration found")
+	}
+
+	proxy, err := storage.UnmarshalProxy([]byte(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return proxy, nil
+}
+
+func updateProxy(client corev1.ConfigMapInterface, proxy storage.Proxy, env map[string]string) error {
+	bytes, err := storage.MarshalProxy(proxy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	data := map[string]string{
+		constants.ResourceSpecKey: string(bytes),
+	}
+	for name, value := range env {
+		data[name] = value
+	}
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      defaults.ProxyConfigMap,
+			Namespace: defaults.KubeSystemNamespace,
+		},
+		Data: data,
+	}
+
+	_, err = client.Create(configMap)
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return nil
+	}
+
+	if !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+
+	_, err = client.Update(configMap)
+	return trace.Wrap(rigging.ConvertError(err))
+}
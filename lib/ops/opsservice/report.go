@@ -20,12 +20,14 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/archive"
@@ -41,7 +43,7 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func (s *site) getSiteOperationCrashReport(op ops.SiteOperation) (io.ReadCloser, error) {
+func (s *site) getSiteOperationCrashReport(op ops.SiteOperation, req ops.SiteReportRequest) (io.ReadCloser, error) {
 	ctx, err := s.newOperationContext(op)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -63,10 +65,10 @@ func (s *site) getSiteOperationCrashReport(op ops.SiteOperation) (io.ReadCloser,
 	}
 
 	runner := s.agentRunner(ctx)
-	return s.getReport(runner, remoteServers, master)
+	return s.getReport(runner, remoteServers, master, req)
 }
 
-func (s *site) getSiteReport() (io.ReadCloser, error) {
+func (s *site) getSiteReport(req ops.SiteReportRequest) (io.ReadCloser, error) {
 	const noRetry = 1
 	servers, err := s.getTeleportServersWithTimeout(
 		nil,
@@ -93,25 +95,43 @@ func (s *site) getSiteReport() (io.ReadCloser, error) {
 		remoteServers = append(remoteServers, teleportServer)
 	}
 
-	return s.getReport(teleportRunner, remoteServers, master)
+	return s.getReport(teleportRunner, remoteServers, master, req)
 }
 
-func (s *site) getReport(runner remoteRunner, servers []remoteServer, master remoteServer) (io.ReadCloser, error) {
-	dir, err := ioutil.TempDir("", "report")
+func (s *site) getReport(runner remoteRunner, servers []remoteServer, master remoteServer, req ops.SiteReportRequest) (io.ReadCloser, error) {
+	redactor, err := newRedactor(req.RedactPatterns)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	err = runCollectors(*s, dir, runner)
+	dir, err := ioutil.TempDir("", "report")
 	if err != nil {
-		// Intermediate steps in diagnostics collection are not fatal
-		// to collect all possible pieces in best-effort
-		log.Errorf("failed to run cluster collectors: %v", trace.DebugReport(err))
+		return nil, trace.Wrap(err)
+	}
+
+	// Every piece of the report is redacted, including the tarballs
+	// collected from nodes which might run an older version without
+	// redaction support
+	reportWriter := redactor.Writer(report.NewFileWriter(dir))
+	if req.HasFilter(constants.ReportFilterCluster) {
+		err = runCollectors(*s, reportWriter)
+		if err != nil {
+			// Intermediate steps in diagnostics collection are not fatal
+			// to collect all possible pieces in best-effort
+			log.Errorf("failed to run cluster collectors: %v", trace.DebugReport(err))
+		}
 	}
 
-	collectOperationsLogs(*s, dir)
+	if req.HasFilter(constants.ReportFilterOperations) {
+		collectOperationsLogs(*s, reportWriter, req.Since)
+	}
 
-	if len(servers) > 0 {
+	var collectors appReportCollectors
+	if req.HasFilter(constants.ReportFilterApp) {
+		collectors = newAppReportCollectors(s.app.Manifest.ReportCollectors())
+	}
+
+	if len(servers) > 0 && (req.HasFilter(constants.ReportFilterKubernetes) || len(collectors.kubernetes) != 0) {
 		// Use the first master server to collect kubernetes diagnostics
 		server := master
 		if server == nil {
@@ -119,10 +139,15 @@ func (s *site) getReport(runner remoteRunner, servers []remoteServer, master rem
 			log.Warningf("no master servers, collecting kubernetes diagnostics from %v", server)
 		}
 		serverRunner := &serverRunner{server: server, runner: runner}
-		reportWriter := getReportWriterForServer(dir, server)
-		s.collectKubernetesInfo(reportWriter, serverRunner)
+		reportWriter := redactor.Writer(getReportWriterForServer(dir, server))
+		err = s.collectKubernetesInfo(reportWriter, serverRunner, req, collectors.kubernetes)
+		if err != nil {
+			log.Errorf("failed to collect kubernetes diagnostics: %v", trace.DebugReport(err))
+		}
+	}
 
-		err = s.collectDebugInfoFromServers(dir, servers, runner)
+	if filters := nodeReportFilters(req); len(servers) > 0 && len(filters) != 0 {
+		err = s.collectDebugInfoFromServers(dir, servers, runner, redactor, req, filters, collectors.host)
 		if err != nil {
 			log.Errorf("failed to collect diagnostics from some nodes: %v", trace.DebugReport(err))
 		}
@@ -153,15 +178,16 @@ func (s *site) getReport(runner remoteRunner, servers []remoteServer, master rem
 //
 //   <server-name>-<resource>
 //
-func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, runner remoteRunner) error {
+func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, runner remoteRunner,
+	redactor *report.Redactor, req ops.SiteReportRequest, filters []string, collectors []schema.ReportCollector) error {
 	err := s.executeOnServers(context.TODO(), servers, func(c context.Context, server remoteServer) error {
 		log.Debugf("collectDebugInfo for %v", server)
 		r := &serverRunner{
 			server: server,
 			runner: runner,
 		}
-		reportWriter := getReportWriterForServer(dir, server)
-		err := s.collectDebugInfo(reportWriter, r)
+		reportWriter := redactor.Writer(getReportWriterForServer(dir, server))
+		err := s.collectDebugInfo(reportWriter, r, req, filters, collectorsForServer(collectors, server))
 		return trace.Wrap(err)
 	})
 	if err != nil {
@@ -170,37 +196,154 @@ func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, r
 	return nil
 }
 
-func (s *site) collectDebugInfo(reportWriter report.Writer, runner *serverRunner) error {
+func (s *site) collectDebugInfo(reportWriter report.Writer, runner *serverRunner, req ops.SiteReportRequest,
+	filters []string, collectors []schema.ReportCollector) error {
+	args, err := systemReportArgs(req, collectors, filters...)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	w, err := reportWriter("debug-logs.tar")
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer w.Close()
 
-	err = runner.RunStream(w, s.gravityCommand("system", "report",
-		fmt.Sprintf("--filter=%v", constants.ReportFilterSystem), "--compressed")...)
+	err = runner.RunStream(w, s.gravityCommand(args...)...)
 	if err != nil {
 		return trace.Wrap(err, "failed to collect diagnostics")
 	}
 	return nil
 }
 
-func (s *site) collectKubernetesInfo(reportWriter report.Writer, runner *serverRunner) error {
+// collectKubernetesInfo collects kubernetes diagnostics along with the results
+// of the specified application-defined kubectl queries
+func (s *site) collectKubernetesInfo(reportWriter report.Writer, runner *serverRunner, req ops.SiteReportRequest,
+	collectors []schema.ReportCollector) error {
+	filter := constants.ReportFilterKubernetes
+	if !req.HasFilter(constants.ReportFilterKubernetes) {
+		filter = constants.ReportFilterApp
+	}
+	args, err := systemReportArgs(req, collectors, filter)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	w, err := reportWriter("k8s-logs.tar")
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer w.Close()
 
-	err = runner.RunStream(w, s.gravityCommand("system", "report",
-		fmt.Sprintf("--filter=%v", constants.ReportFilterKubernetes), "--compressed")...)
+	err = runner.RunStream(w, s.gravityCommand(args...)...)
 	if err != nil {
 		return trace.Wrap(err, "failed to collect kubernetes diagnostics")
 	}
 	return nil
 }
 
-func runCollectors(site site, dir string, runner remoteRunner) error {
+// systemReportArgs returns the arguments of the node-local report command
+// for the specified request, application collectors and filters.
+// Redaction patterns and collectors are base64-encoded to pass them to the command safely
+func systemReportArgs(req ops.SiteReportRequest, collectors []schema.ReportCollector, filters ...string) ([]string, error) {
+	args := []string{"system", "report", "--compressed"}
+	for _, filter := range filters {
+		args = append(args, fmt.Sprintf("--filter=%v", filter))
+	}
+	if req.Since != 0 {
+		args = append(args, fmt.Sprintf("--since=%v", req.Since))
+	}
+	for _, namespace := range req.Namespaces {
+		args = append(args, fmt.Sprintf("--namespace=%v", namespace))
+	}
+	for _, pattern := range req.RedactPatterns {
+		args = append(args, fmt.Sprintf("--redact-encoded=%v",
+			base64.StdEncoding.EncodeToString([]byte(pattern))))
+	}
+	if len(collectors) != 0 {
+		data, err := json.Marshal(collectors)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		args = append(args, fmt.Sprintf("--app-collectors=%v",
+			base64.StdEncoding.EncodeToString(data)))
+	}
+	return args, nil
+}
+
+// newAppReportCollectors splits the application-defined report collectors
+// into the kubectl queries and the collectors running on each node
+func newAppReportCollectors(collectors []schema.ReportCollector) (result appReportCollectors) {
+	for _, collector := range collectors {
+		if collector.IsKubernetes() {
+			result.kubernetes = append(result.kubernetes, collector)
+		} else {
+			result.host = append(result.host, collector)
+		}
+	}
+	return result
+}
+
+// appReportCollectors groups application-defined report collectors
+type appReportCollectors struct {
+	// kubernetes lists the kubectl queries run once per cluster
+	kubernetes []schema.ReportCollector
+	// host lists the collectors run on each node
+	host []schema.ReportCollector
+}
+
+// collectorsForServer returns the host collectors that apply to the profile
+// of the specified server
+func collectorsForServer(collectors []schema.ReportCollector, server remoteServer) (result []schema.ReportCollector) {
+	profile := serverProfile(server)
+	for _, collector := range collectors {
+		if collector.MatchesProfile(profile) {
+			result = append(result, collector)
+		}
+	}
+	return result
+}
+
+// serverProfile returns the node profile of the specified server
+func serverProfile(server remoteServer) string {
+	switch s := server.(type) {
+	case *teleportServer:
+		return s.getLabel(ops.AppRole)
+	case *ProvisionedServer:
+		return s.Role
+	}
+	return ""
+}
+
+// nodeReportFilters returns the filters of the diagnostics collected
+// on each node for the specified request
+func nodeReportFilters(req ops.SiteReportRequest) (filters []string) {
+	if len(req.Filters) == 0 {
+		return []string{constants.ReportFilterSystem}
+	}
+	for _, filter := range req.Filters {
+		if utils.StringInSlice(constants.NodeReportFilters, filter) {
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
+// newRedactor returns a redactor with the built-in rules and the rules
+// for the specified patterns
+func newRedactor(patterns []string) (*report.Redactor, error) {
+	var rules []report.RedactionRule
+	for _, pattern := range patterns {
+		rule, err := report.NewRedactionRule(pattern)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		rules = append(rules, *rule)
+	}
+	return report.NewRedactor(rules...), nil
+}
+
+func runCollectors(site site, reportWriter report.Writer) error {
 	storageSite, err := site.service.cfg.Backend.GetSite(site.domainName)
 	if err != nil {
 		return trace.Wrap(err)
@@ -210,7 +353,6 @@ func runCollectors(site site, dir string, runner remoteRunner) error {
 		collectSiteInfo(*storageSite),
 		collectDumpHook,
 	}
-	reportWriter := report.NewFileWriter(dir)
 
 	// collect information from all collectors
 	for _, collector := range collectors {
@@ -222,15 +364,18 @@ func runCollectors(site site, dir string, runner remoteRunner) error {
 	return nil
 }
 
-func collectOperationsLogs(site site, dir string) error {
+// collectOperationsLogs collects the logs of cluster operations.
+// If since is specified, only the operations updated within the time window are collected
+func collectOperationsLogs(site site, reportWriter report.Writer, since time.Duration) error {
 	operations, err := site.service.GetSiteOperations(site.key)
 	if err != nil {
 		return trace.Wrap(err, "failed to get cluster operations")
 	}
 
-	reportWriter := report.NewFileWriter(dir)
-
 	for _, op := range operations {
+		if since != 0 && op.Updated.Before(time.Now().Add(-since)) {
+			continue
+		}
 		operation := ops.SiteOperation(op)
 		err = collectOperationLogs(site, operation, reportWriter)
 		if err != nil {
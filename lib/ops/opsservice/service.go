@@ -1055,21 +1055,25 @@ func (o *Operator) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.Rea
 		return nil, trace.Wrap(err)
 	}
 
+	req := ops.SiteReportRequest{SiteKey: key.SiteKey()}
 	switch op.Type {
 	case ops.OperationInstall:
-		return site.getSiteOperationCrashReport(*op)
+		return site.getSiteOperationCrashReport(*op, req)
 	default:
-		return site.getSiteReport()
+		return site.getSiteReport(req)
 	}
 }
 
-func (o *Operator) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	site, err := o.openSite(key)
+func (o *Operator) GetSiteReport(req ops.SiteReportRequest) (io.ReadCloser, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	site, err := o.openSite(req.SiteKey)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	return site.getSiteReport()
+	return site.getSiteReport(req)
 }
 
 func (o *Operator) GetSiteOperationProgress(key ops.SiteOperationKey) (*ops.ProgressEntry, error) {
@@ -1467,12 +1471,14 @@ func (o *Operator) getOperationGroup(key ops.SiteKey) *operationGroup {
 }
 
 // RemoteOpsClient returns remote Ops Center client using the provided trusted
-// cluster token for authentication
+// cluster token for authentication. Requests are sent via the cluster HTTP
+// proxy if one is configured
 func (o *Operator) RemoteOpsClient(cluster teleservices.TrustedCluster) (*opsclient.Client, error) {
 	client, err := opsclient.NewBearerClient(
 		fmt.Sprintf("https://%v", cluster.GetProxyAddress()),
 		cluster.GetToken(),
-		opsclient.HTTPClient(httplib.GetClient(o.cfg.Devmode)))
+		opsclient.HTTPClient(httplib.GetClient(o.cfg.Devmode,
+			httplib.WithProxy(httplib.ProxyConfigFromEnv()))))
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
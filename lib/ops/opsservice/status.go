@@ -79,6 +79,36 @@ func (o *Operator) CheckSiteStatus(key ops.SiteKey) error {
 	return nil
 }
 
+// GetClusterStatusHistory returns the recorded state transitions
+// of the cluster, its nodes and health probes
+func (o *Operator) GetClusterStatusHistory(req ops.ClusterStatusHistoryRequest) ([]ops.StatusTransition, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	snapshots, err := o.backend().GetStatusSnapshots(req.SiteDomain, req.From, req.To)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !req.From.IsZero() {
+		// Transitions are computed relative to the preceding snapshot
+		// so use the last snapshot before the time range as the initial state
+		last, err := o.backend().GetLastStatusSnapshot(req.SiteDomain, req.From)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		if last != nil {
+			snapshots = append([]storage.StatusSnapshot{*last}, snapshots...)
+		}
+	}
+	transitions := []ops.StatusTransition{}
+	for _, transition := range status.Transitions(snapshots) {
+		if req.Matches(transition) {
+			transitions = append(transitions, transition)
+		}
+	}
+	return transitions, nil
+}
+
 // canActivate retursn true if the cluster is disabled b/c of status checks
 func (s *site) canActivate() bool {
 	return s.backendSite.State == ops.SiteStateDegraded &&
@@ -0,0 +1,120 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// GetTrustedCA returns the cluster trusted certificate authorities
+func (o *Operator) GetTrustedCA(key ops.SiteKey) (storage.TrustedCA, error) {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return GetTrustedCA(client.Core().ConfigMaps(defaults.KubeSystemNamespace))
+}
+
+// UpdateTrustedCA updates the cluster trusted certificate authorities
+func (o *Operator) UpdateTrustedCA(key ops.SiteKey, ca storage.TrustedCA) error {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return updateTrustedCA(client.Core().ConfigMaps(defaults.KubeSystemNamespace), ca)
+}
+
+// DeleteTrustedCA deletes the cluster trusted certificate authorities
+func (o *Operator) DeleteTrustedCA(key ops.SiteKey) error {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = rigging.ConvertError(client.Core().ConfigMaps(defaults.KubeSystemNamespace).
+		Delete(defaults.TrustedCAConfigMap, nil))
+	if trace.IsNotFound(err) {
+		return trace.NotFound("no trusted certificate authorities found")
+	}
+	return trace.Wrap(err)
+}
+
+// GetTrustedCA returns the trusted certificate authorities stored in the
+// trusted-ca config map in the kube-system namespace
+func GetTrustedCA(client corev1.ConfigMapInterface) (storage.TrustedCA, error) {
+	configMap, err := client.Get(defaults.TrustedCAConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("no trusted certificate authorities found")
+		}
+		return nil, trace.Wrap(err)
+	}
+
+	data, ok := configMap.Data[constants.ResourceSpecKey]
+	if !ok {
+		return nil, trace.NotFound("no trusted certificate authorities found")
+	}
+
+	ca, err := storage.UnmarshalTrustedCA([]byte(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return ca, nil
+}
+
+func updateTrustedCA(client corev1.ConfigMapInterface, ca storage.TrustedCA) error {
+	bytes, err := storage.MarshalTrustedCA(ca)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      defaults.TrustedCAConfigMap,
+			Namespace: defaults.KubeSystemNamespace,
+		},
+		Data: map[string]string{
+			constants.ResourceSpecKey: string(bytes),
+		},
+	}
+
+	_, err = client.Create(configMap)
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return nil
+	}
+
+	if !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+
+	_, err = client.Update(configMap)
+	return trace.Wrap(rigging.ConvertError(err))
+}
@@ -298,6 +298,11 @@ func (s *site) createUpdateOperation(req ops.CreateSiteAppUpdateOperationRequest
 		return nil, trace.Wrap(err)
 	}
 
+	rollbackPolicy, err := s.getRollbackPolicy(req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	op := ops.SiteOperation{
 		ID:          uuid.New(),
 		AccountID:   s.key.AccountID,
@@ -308,7 +313,8 @@ func (s *site) createUpdateOperation(req ops.CreateSiteAppUpdateOperationRequest
 		State:       ops.OperationStateUpdateInProgress,
 		Provisioner: installOperation.Provisioner,
 		Update: &storage.UpdateOperationState{
-			UpdatePackage: req.App,
+			UpdatePackage:  req.App,
+			RollbackPolicy: rollbackPolicy,
 		},
 	}
 
@@ -364,6 +370,35 @@ func (s *site) validateUpdateOperationRequest(req ops.CreateSiteAppUpdateOperati
 	return s.checkUpdateParameters(newEnvelope, provisioner)
 }
 
+// getRollbackPolicy returns the automatic rollback policy for the update operation.
+// The policy specified in the request takes precedence over the policy
+// defined in the manifest of the update application
+func (s *site) getRollbackPolicy(req ops.CreateSiteAppUpdateOperationRequest) (*storage.RollbackPolicy, error) {
+	if req.RollbackPolicy != nil {
+		return req.RollbackPolicy, nil
+	}
+	updatePackage, err := loc.ParseLocator(req.App)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	updateApp, err := s.service.cfg.Apps.GetApp(*updatePackage)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	policy := updateApp.Manifest.RollbackPolicy()
+	if policy == nil {
+		return nil, nil
+	}
+	gracePeriod, err := policy.GracePeriod()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &storage.RollbackPolicy{
+		OnFailure:              policy.OnFailure,
+		HealthCheckGracePeriod: gracePeriod,
+	}, nil
+}
+
 // checkUpdateParameters checks if update parameters match
 func (s *site) checkUpdateParameters(update *pack.PackageEnvelope, provisioner string) error {
 	if update.Manifest == nil {
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 	"time"
 
@@ -429,6 +430,169 @@ func (r smtpConfigCollection) ToMarshal() interface{} {
 
 type smtpConfigCollection []storage.SMTPConfig
 
+// Resources returns the resources collection in the generic format
+func (c hostConfigCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range c {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+// WriteText serializes collection in human-friendly text format
+func (r hostConfigCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Profile", "Sysctls", "Kernel Modules", "Ulimits"})
+	for _, config := range r {
+		for _, profile := range config.GetProfiles() {
+			var sysctls, ulimits []string
+			for _, name := range profile.SortedSysctls() {
+				sysctls = append(sysctls, fmt.Sprintf("%v=%v", name, profile.Sysctls[name]))
+			}
+			for _, ulimit := range profile.Ulimits {
+				ulimits = append(ulimits, strings.Replace(ulimit.String(), "\n", ", ", -1))
+			}
+			name := profile.Profile
+			if name == "" {
+				name = "*"
+			}
+			fmt.Fprintf(t, "%v\t%v\t%v\t%v\n", name,
+				formatList(sysctls), formatList(profile.KernelModules), formatList(ulimits))
+		}
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r hostConfigCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r hostConfigCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r hostConfigCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+type hostConfigCollection []storage.HostConfig
+
+// Resources returns the resources collection in the generic format
+func (c trustedCACollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range c {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+// WriteText serializes collection in human-friendly text format
+func (r trustedCACollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Subject", "Issuer", "Expires"})
+	for _, ca := range r {
+		certs, err := ca.GetX509Certificates()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, cert := range certs {
+			fmt.Fprintf(t, "%v\t%v\t%v\n", cert.Subject.CommonName, cert.Issuer.CommonName,
+				cert.NotAfter.Format(constants.HumanDateFormat))
+		}
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r trustedCACollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r trustedCACollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r trustedCACollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+type trustedCACollection []storage.TrustedCA
+
+// Resources returns the resources collection in the generic format
+func (c proxyCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range c {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+// WriteText serializes collection in human-friendly text format
+func (r proxyCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"HTTP Proxy", "HTTPS Proxy", "No Proxy"})
+	for _, proxy := range r {
+		fmt.Fprintf(t, "%v\t%v\t%v\n", formatProxyURL(proxy.GetHTTPProxy()),
+			formatProxyURL(proxy.GetHTTPSProxy()), formatList(proxy.GetNoProxy()))
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r proxyCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r proxyCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r proxyCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+type proxyCollection []storage.Proxy
+
+// formatProxyURL returns the proxy URL with the password masked
+func formatProxyURL(proxy string) string {
+	if proxy == "" {
+		return "-"
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
+
 // WriteText serializes collection in human-friendly text format
 func (r alertCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
@@ -17,15 +17,25 @@ limitations under the License.
 package gravity
 
 import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/modules"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/resources"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/hostconfig"
+	"github.com/gravitational/gravity/lib/system/proxy"
+	"github.com/gravitational/gravity/lib/system/trustedca"
+	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/fatih/color"
 	teleservices "github.com/gravitational/teleport/lib/services"
 	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	kubeapi "k8s.io/client-go/kubernetes"
 )
 
 // Resources is a controller that manages cluster local resources
@@ -44,6 +54,13 @@ type Config struct {
 	CurrentUser string
 	// Silent provides methods for printing
 	localenv.Silent
+	// Remote optionally executes commands on cluster nodes.
+	// Required to apply host configuration, trusted certificate authorities
+	// and proxy environment
+	Remote fsm.RemoteRunner
+	// Client is the optional kubernetes client.
+	// Required to drain nodes when the proxy environment is updated
+	Client *kubeapi.Clientset
 }
 
 // Check makes sure the config is valid
@@ -185,6 +202,77 @@ func (r *Resources) Create(req resources.CreateRequest) error {
 			return trace.Wrap(err)
 		}
 		r.Println("Updated cluster SMTP configuration")
+	case storage.KindHostConfig:
+		config, err := storage.UnmarshalHostConfig(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.checkAgents(); err != nil {
+			return trace.Wrap(err)
+		}
+		previous, err := r.Operator.GetHostConfig(r.cluster.Key())
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpdateHostConfig(r.cluster.Key(), config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("Updated cluster host configuration")
+		if err := r.applyHostConfig(config); err != nil {
+			r.restoreHostConfig(previous)
+			return trace.Wrap(err)
+		}
+	case storage.KindTrustedCA:
+		ca, err := storage.UnmarshalTrustedCA(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.checkAgents(); err != nil {
+			return trace.Wrap(err)
+		}
+		previous, err := r.Operator.GetTrustedCA(r.cluster.Key())
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpdateTrustedCA(r.cluster.Key(), ca)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("Updated cluster trusted certificate authorities")
+		if err := r.applyTrustedCA(ca); err != nil {
+			r.restoreTrustedCA(previous)
+			return trace.Wrap(err)
+		}
+	case storage.KindProxy:
+		config, err := storage.UnmarshalProxy(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.checkAgents(); err != nil {
+			return trace.Wrap(err)
+		}
+		previous, err := r.Operator.GetProxy(r.cluster.Key())
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		previousEnv, err := r.getProxyEnv(previous)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		env, err := r.getProxyEnv(config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpdateProxy(r.cluster.Key(), config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("Updated cluster HTTP proxy configuration")
+		if err := r.applyProxy(env, previousEnv); err != nil {
+			r.restoreProxy(previous)
+			return trace.Wrap(err)
+		}
 	case storage.KindAlert:
 		alert, err := storage.UnmarshalAlert(req.Resource.Raw)
 		if err != nil {
@@ -335,6 +423,24 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 			return nil, trace.Wrap(err)
 		}
 		return smtpConfigCollection{config}, nil
+	case storage.KindHostConfig, "hostconfigs":
+		config, err := r.Operator.GetHostConfig(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return hostConfigCollection{config}, nil
+	case storage.KindTrustedCA, "trustedcas":
+		ca, err := r.Operator.GetTrustedCA(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return trustedCACollection{ca}, nil
+	case storage.KindProxy, "proxies":
+		config, err := r.Operator.GetProxy(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return proxyCollection{config}, nil
 	case storage.KindAlert, "alerts":
 		alerts, err := r.Operator.GetAlerts(r.cluster.Key())
 		if err != nil {
@@ -426,6 +532,69 @@ func (r *Resources) Remove(req resources.RemoveRequest) error {
 			return trace.Wrap(err)
 		}
 		r.Println("SMTP configuration has been deleted")
+	case storage.KindHostConfig, "hostconfigs":
+		previous, err := r.Operator.GetHostConfig(r.cluster.Key())
+		if err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		if err := r.checkAgents(); err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.Operator.DeleteHostConfig(r.cluster.Key()); err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("Host configuration has been deleted")
+		// Applying empty configuration removes the persisted settings from nodes.
+		// Kernel parameters keep their current values until the next reboot
+		if err := r.applyHostConfig(storage.NewHostConfig(storage.HostConfigSpecV2{})); err != nil {
+			r.restoreHostConfig(previous)
+			return trace.Wrap(err)
+		}
+	case storage.KindTrustedCA, "trustedcas":
+		previous, err := r.Operator.GetTrustedCA(r.cluster.Key())
+		if err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		if err := r.checkAgents(); err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.Operator.DeleteTrustedCA(r.cluster.Key()); err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("Trusted certificate authorities have been deleted")
+		if err := r.applyTrustedCA(nil); err != nil {
+			r.restoreTrustedCA(previous)
+			return trace.Wrap(err)
+		}
+	case storage.KindProxy, "proxies":
+		previous, err := r.Operator.GetProxy(r.cluster.Key())
+		if err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		if err := r.checkAgents(); err != nil {
+			return trace.Wrap(err)
+		}
+		previousEnv, err := r.getProxyEnv(previous)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.Operator.DeleteProxy(r.cluster.Key()); err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("HTTP proxy configuration has been deleted")
+		if err := r.applyProxy(nil, previousEnv); err != nil {
+			r.restoreProxy(previous)
+			return trace.Wrap(err)
+		}
 	case storage.KindAlert, "alerts":
 		if err := r.Operator.DeleteAlert(r.cluster.Key(), req.Name); err != nil {
 			if trace.IsNotFound(err) && req.Force {
@@ -448,3 +617,155 @@ func (r *Resources) Remove(req resources.RemoveRequest) error {
 	}
 	return nil
 }
+
+// checkAgents makes sure that the commands can be executed on all cluster nodes.
+// It is used to validate agent connectivity before storing the resources
+// that are applied on the nodes
+func (r *Resources) checkAgents() error {
+	if r.Remote == nil {
+		return trace.BadParameter("agents are not available to apply the resource on cluster nodes.\n" +
+			"Deploy the agents with `gravity agent deploy` and re-run this command.")
+	}
+	var errors []error
+	for _, server := range r.cluster.ClusterState.Servers {
+		ctx, cancel := context.WithTimeout(context.TODO(), defaults.AgentConnectTimeout)
+		err := r.Remote.CanExecute(ctx, server)
+		cancel()
+		if err != nil {
+			errors = append(errors, trace.Wrap(err, "agent on %v (%v) is not available",
+				server.Hostname, server.AdvertiseIP))
+		}
+	}
+	if len(errors) != 0 {
+		return trace.Wrap(trace.NewAggregate(errors...),
+			"agents are not available on all cluster nodes.\n"+
+				"Deploy the agents with `gravity agent deploy` and re-run this command.")
+	}
+	return nil
+}
+
+// restoreHostConfig rolls back the cluster host configuration to previous
+// after it has failed to apply. Removes the host configuration if previous is nil
+func (r *Resources) restoreHostConfig(previous storage.HostConfig) {
+	var err error
+	if previous != nil {
+		err = r.Operator.UpdateHostConfig(r.cluster.Key(), previous)
+	} else {
+		previous = storage.NewHostConfig(storage.HostConfigSpecV2{})
+		err = r.Operator.DeleteHostConfig(r.cluster.Key())
+	}
+	if err == nil {
+		err = r.applyHostConfig(previous)
+	}
+	if err != nil {
+		logrus.Warnf("Failed to restore previous host configuration: %v.", trace.DebugReport(err))
+		r.Println("Failed to restore previous host configuration, re-run the command to retry.")
+		return
+	}
+	r.Println("Restored previous host configuration")
+}
+
+// applyHostConfig applies the specified host configuration on all cluster nodes
+func (r *Resources) applyHostConfig(config storage.HostConfig) error {
+	ctx := context.TODO()
+	progress := utils.NewProgress(ctx, "host configuration", -1, bool(r.Silent))
+	defer progress.Stop()
+	err := hostconfig.ApplyCluster(ctx, hostconfig.ClusterConfig{
+		Config:   config,
+		Servers:  r.cluster.ClusterState.Servers,
+		Remote:   r.Remote,
+		Progress: progress,
+	})
+	return trace.Wrap(err)
+}
+
+// restoreTrustedCA rolls back the cluster trusted certificate authorities to
+// previous after they have failed to install. Removes the certificate authorities
+// if previous is nil
+func (r *Resources) restoreTrustedCA(previous storage.TrustedCA) {
+	var err error
+	if previous != nil {
+		err = r.Operator.UpdateTrustedCA(r.cluster.Key(), previous)
+	} else {
+		err = r.Operator.DeleteTrustedCA(r.cluster.Key())
+	}
+	if err == nil {
+		err = r.applyTrustedCA(previous)
+	}
+	if err != nil {
+		logrus.Warnf("Failed to restore previous trusted certificate authorities: %v.", trace.DebugReport(err))
+		r.Println("Failed to restore previous trusted certificate authorities, re-run the command to retry.")
+		return
+	}
+	r.Println("Restored previous trusted certificate authorities")
+}
+
+// applyTrustedCA installs the specified trusted certificate authorities on all
+// cluster nodes or removes them if ca is nil
+func (r *Resources) applyTrustedCA(ca storage.TrustedCA) error {
+	ctx := context.TODO()
+	progress := utils.NewProgress(ctx, "trusted certificate authorities", -1, bool(r.Silent))
+	defer progress.Stop()
+	err := trustedca.ApplyCluster(ctx, trustedca.ClusterConfig{
+		CA:       ca,
+		Servers:  r.cluster.ClusterState.Servers,
+		Remote:   r.Remote,
+		Progress: progress,
+	})
+	return trace.Wrap(err)
+}
+
+// restoreProxy rolls back the cluster HTTP proxy configuration to previous
+// after the proxy environment has failed to apply. The nodes are restored
+// by rolling back the proxy update operation.
+// Removes the proxy configuration if previous is nil
+func (r *Resources) restoreProxy(previous storage.Proxy) {
+	var err error
+	if previous != nil {
+		err = r.Operator.UpdateProxy(r.cluster.Key(), previous)
+	} else {
+		err = r.Operator.DeleteProxy(r.cluster.Key())
+		if trace.IsNotFound(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		logrus.Warnf("Failed to restore previous HTTP proxy configuration: %v.", trace.DebugReport(err))
+		r.Println("Failed to restore previous HTTP proxy configuration, re-run the command to retry.")
+		return
+	}
+	r.Println("Restored previous HTTP proxy configuration")
+}
+
+// getProxyEnv returns the proxy environment for the specified proxy configuration
+// or nothing if config is nil
+func (r *Resources) getProxyEnv(config storage.Proxy) (map[string]string, error) {
+	if config == nil {
+		return nil, nil
+	}
+	env, err := ops.GetProxyEnv(r.Operator, *r.cluster, config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return env, nil
+}
+
+// applyProxy runs the operation that configures the specified proxy environment
+// on all cluster nodes or removes it if env is empty.
+// If the operation fails, it is rolled back to the previous environment
+func (r *Resources) applyProxy(env, previous map[string]string) error {
+	operation, err := proxy.CreateOperation(r.Operator, *r.cluster, env, previous)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx := context.TODO()
+	progress := utils.NewProgress(ctx, "proxy environment", -1, bool(r.Silent))
+	defer progress.Stop()
+	err = proxy.Update(ctx, proxy.Config{
+		Operation: operation,
+		Operator:  r.Operator,
+		Client:    r.Client,
+		Runner:    r.Remote,
+	}, progress)
+	return trace.Wrap(err)
+}
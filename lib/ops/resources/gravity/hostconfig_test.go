@@ -0,0 +1,187 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gravity
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/resources"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/hostconfig"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type HostConfigResourceSuite struct{}
+
+var _ = check.Suite(&HostConfigResourceSuite{})
+
+func (s *HostConfigResourceSuite) TestDoesNotStoreConfigWithoutAgents(c *check.C) {
+	operator := &hostConfigOperator{}
+	remote := &fakeRemote{unavailable: map[string]bool{"10.255.0.2": true}}
+	r := newClusterResources(operator, remote)
+
+	err := r.Create(resources.CreateRequest{Resource: hostConfigResource(c, "262144")})
+	c.Assert(err, check.NotNil)
+	c.Assert(operator.config, check.IsNil)
+	c.Assert(remote.commands, check.HasLen, 0)
+
+	r.Remote = nil
+	err = r.Create(resources.CreateRequest{Resource: hostConfigResource(c, "262144")})
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+	c.Assert(operator.config, check.IsNil)
+}
+
+func (s *HostConfigResourceSuite) TestRestoresPreviousConfigOnFailure(c *check.C) {
+	operator := &hostConfigOperator{}
+	remote := &fakeRemote{}
+	r := newClusterResources(operator, remote)
+	err := r.Create(resources.CreateRequest{Resource: hostConfigResource(c, "262144")})
+	c.Assert(err, check.IsNil)
+	c.Assert(remote.sysctls(c), check.DeepEquals, []string{"10.255.0.1=262144", "10.255.0.2=262144"})
+
+	remote.commands = nil
+	remote.failed = map[string]bool{"10.255.0.2": true}
+	err = r.Create(resources.CreateRequest{Resource: hostConfigResource(c, "524288")})
+	c.Assert(err, check.NotNil)
+	c.Assert(operator.config.ForProfile("").Sysctls["vm.max_map_count"], check.Equals, "262144")
+	// the new configuration is applied on all nodes and then rolled back
+	c.Assert(remote.sysctls(c), check.DeepEquals, []string{
+		"10.255.0.1=524288", "10.255.0.2=524288",
+		"10.255.0.1=262144", "10.255.0.2=262144",
+	})
+}
+
+func (s *HostConfigResourceSuite) TestRemovesConfigFromNodes(c *check.C) {
+	operator := &hostConfigOperator{}
+	remote := &fakeRemote{}
+	r := newClusterResources(operator, remote)
+	err := r.Create(resources.CreateRequest{Resource: hostConfigResource(c, "262144")})
+	c.Assert(err, check.IsNil)
+
+	remote.commands = nil
+	err = r.Remove(resources.RemoveRequest{Kind: storage.KindHostConfig, Name: storage.KindHostConfig})
+	c.Assert(err, check.IsNil)
+	c.Assert(operator.config, check.IsNil)
+	c.Assert(remote.sysctls(c), check.DeepEquals, []string{"10.255.0.1=", "10.255.0.2="})
+
+	err = r.Remove(resources.RemoveRequest{Kind: storage.KindHostConfig, Name: storage.KindHostConfig})
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+	err = r.Remove(resources.RemoveRequest{Kind: storage.KindHostConfig, Name: storage.KindHostConfig, Force: true})
+	c.Assert(err, check.IsNil)
+}
+
+func newClusterResources(operator ops.Operator, remote *fakeRemote) *Resources {
+	return &Resources{
+		Config: Config{
+			Operator: operator,
+			Silent:   localenv.Silent(true),
+			Remote:   remote,
+		},
+		cluster: &ops.Site{
+			Domain: "example.com",
+			ClusterState: storage.ClusterState{
+				Servers: []storage.Server{
+					{Hostname: "node-1", AdvertiseIP: "10.255.0.1"},
+					{Hostname: "node-2", AdvertiseIP: "10.255.0.2"},
+				},
+			},
+		},
+	}
+}
+
+func hostConfigResource(c *check.C, maxMapCount string) teleservices.UnknownResource {
+	return toUnknown(c, storage.NewHostConfig(storage.HostConfigSpecV2{
+		Profiles: []storage.HostConfigProfile{
+			{Sysctls: map[string]string{"vm.max_map_count": maxMapCount}},
+		},
+	}))
+}
+
+// hostConfigOperator keeps the cluster host configuration in memory
+type hostConfigOperator struct {
+	ops.Operator
+	config storage.HostConfig
+}
+
+func (o *hostConfigOperator) GetHostConfig(ops.SiteKey) (storage.HostConfig, error) {
+	if o.config == nil {
+		return nil, trace.NotFound("no host configuration found")
+	}
+	return o.config, nil
+}
+
+func (o *hostConfigOperator) UpdateHostConfig(key ops.SiteKey, config storage.HostConfig) error {
+	o.config = config
+	return nil
+}
+
+func (o *hostConfigOperator) DeleteHostConfig(ops.SiteKey) error {
+	if o.config == nil {
+		return trace.NotFound("no host configuration found")
+	}
+	o.config = nil
+	return nil
+}
+
+// fakeRemote records the commands executed on cluster nodes
+type fakeRemote struct {
+	// unavailable lists the addresses of the nodes without agents
+	unavailable map[string]bool
+	// failed lists the addresses of the nodes the commands fail on
+	failed   map[string]bool
+	commands []remoteCommand
+}
+
+type remoteCommand struct {
+	server storage.Server
+	args   []string
+}
+
+func (r *fakeRemote) Run(ctx context.Context, server storage.Server, args ...string) error {
+	r.commands = append(r.commands, remoteCommand{server: server, args: args})
+	if r.failed[server.AdvertiseIP] {
+		return trace.ConnectionProblem(nil, "failed to run on %v", server.AdvertiseIP)
+	}
+	return nil
+}
+
+func (r *fakeRemote) CanExecute(ctx context.Context, server storage.Server) error {
+	if r.unavailable[server.AdvertiseIP] {
+		return trace.ConnectionProblem(nil, "no agent on %v", server.AdvertiseIP)
+	}
+	return nil
+}
+
+func (r *fakeRemote) Close() error {
+	return nil
+}
+
+// sysctls returns the vm.max_map_count value applied on each node
+func (r *fakeRemote) sysctls(c *check.C) (result []string) {
+	for _, command := range r.commands {
+		profile, err := hostconfig.DecodeSpec(strings.TrimPrefix(command.args[2], "--spec="))
+		c.Assert(err, check.IsNil)
+		result = append(result, command.server.AdvertiseIP+"="+profile.Sysctls["vm.max_map_count"])
+	}
+	return result
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gravity
+
+import (
+	"time"
+
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/resources"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type ProxyResourceSuite struct{}
+
+var _ = check.Suite(&ProxyResourceSuite{})
+
+func (s *ProxyResourceSuite) TestDoesNotStoreProxyWithoutAgents(c *check.C) {
+	operator := &proxyOperator{}
+	remote := &fakeRemote{unavailable: map[string]bool{"10.255.0.2": true}}
+	r := newProxyResources(operator, remote)
+
+	err := r.Create(resources.CreateRequest{Resource: proxyResource(c, "http://proxy:3128")})
+	c.Assert(err, check.NotNil)
+	c.Assert(operator.proxy, check.IsNil)
+	c.Assert(operator.operation, check.IsNil)
+	c.Assert(remote.commands, check.HasLen, 0)
+}
+
+func (s *ProxyResourceSuite) TestUpdatesProxyWithOperation(c *check.C) {
+	operator := &proxyOperator{}
+	remote := &fakeRemote{}
+	r := newProxyResources(operator, remote)
+
+	err := r.Create(resources.CreateRequest{Resource: proxyResource(c, "http://proxy:3128")})
+	c.Assert(err, check.IsNil)
+	c.Assert(operator.proxy.GetHTTPProxy(), check.Equals, "http://proxy:3128")
+	c.Assert(operator.state, check.Equals, ops.OperationStateCompleted)
+	// every node is drained, configured, restarted, checked and uncordoned
+	c.Assert(remote.commands, check.HasLen, 10)
+}
+
+func (s *ProxyResourceSuite) TestRestoresProxyOnFailure(c *check.C) {
+	operator := &proxyOperator{}
+	remote := &fakeRemote{}
+	r := newProxyResources(operator, remote)
+	err := r.Create(resources.CreateRequest{Resource: proxyResource(c, "http://proxy:3128")})
+	c.Assert(err, check.IsNil)
+
+	remote.commands = nil
+	remote.failed = map[string]bool{"10.255.0.2": true}
+	err = r.Create(resources.CreateRequest{Resource: proxyResource(c, "http://new-proxy:3128")})
+	c.Assert(err, check.NotNil)
+	c.Assert(operator.proxy.GetHTTPProxy(), check.Equals, "http://proxy:3128")
+	c.Assert(operator.state, check.Equals, ops.OperationStateFailed)
+}
+
+func (s *ProxyResourceSuite) TestRemovesProxyWithOperation(c *check.C) {
+	operator := &proxyOperator{}
+	remote := &fakeRemote{}
+	r := newProxyResources(operator, remote)
+	err := r.Create(resources.CreateRequest{Resource: proxyResource(c, "http://proxy:3128")})
+	c.Assert(err, check.IsNil)
+
+	operator.state = ""
+	err = r.Remove(resources.RemoveRequest{Kind: storage.KindProxy, Name: storage.KindProxy})
+	c.Assert(err, check.IsNil)
+	c.Assert(operator.proxy, check.IsNil)
+	c.Assert(operator.state, check.Equals, ops.OperationStateCompleted)
+
+	err = r.Remove(resources.RemoveRequest{Kind: storage.KindProxy, Name: storage.KindProxy})
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+	err = r.Remove(resources.RemoveRequest{Kind: storage.KindProxy, Name: storage.KindProxy, Force: true})
+	c.Assert(err, check.IsNil)
+}
+
+func newProxyResources(operator ops.Operator, remote *fakeRemote) *Resources {
+	r := newClusterResources(operator, remote)
+	r.cluster.ClusterState.Servers[0].ClusterRole = string(schema.ServiceRoleMaster)
+	r.cluster.ClusterState.Servers[1].ClusterRole = string(schema.ServiceRoleNode)
+	return r
+}
+
+func proxyResource(c *check.C, httpProxy string) teleservices.UnknownResource {
+	return toUnknown(c, storage.NewProxy(storage.ProxySpecV2{HTTPProxy: httpProxy}))
+}
+
+// proxyOperator keeps the cluster proxy configuration and the proxy
+// update operation in memory
+type proxyOperator struct {
+	ops.Operator
+	proxy     storage.Proxy
+	operation *ops.SiteOperation
+	plan      storage.OperationPlan
+	changelog storage.PlanChangelog
+	state     string
+}
+
+func (o *proxyOperator) GetProxy(ops.SiteKey) (storage.Proxy, error) {
+	if o.proxy == nil {
+		return nil, trace.NotFound("no proxy configuration found")
+	}
+	return o.proxy, nil
+}
+
+func (o *proxyOperator) UpdateProxy(key ops.SiteKey, proxy storage.Proxy) error {
+	o.proxy = proxy
+	return nil
+}
+
+func (o *proxyOperator) DeleteProxy(ops.SiteKey) error {
+	if o.proxy == nil {
+		return trace.NotFound("no proxy configuration found")
+	}
+	o.proxy = nil
+	return nil
+}
+
+func (o *proxyOperator) GetSiteOperations(ops.SiteKey) (ops.SiteOperations, error) {
+	return nil, nil
+}
+
+func (o *proxyOperator) CreateUpdateProxyOperation(req ops.CreateUpdateProxyOperationRequest) (*ops.SiteOperationKey, error) {
+	o.operation = &ops.SiteOperation{
+		ID:         "1",
+		AccountID:  req.AccountID,
+		SiteDomain: req.ClusterName,
+		Type:       ops.OperationUpdateProxy,
+		State:      ops.OperationUpdateProxyInProgress,
+	}
+	o.changelog = nil
+	key := o.operation.Key()
+	return &key, nil
+}
+
+func (o *proxyOperator) GetSiteOperation(ops.SiteOperationKey) (*ops.SiteOperation, error) {
+	if o.operation == nil {
+		return nil, trace.NotFound("operation not found")
+	}
+	return o.operation, nil
+}
+
+func (o *proxyOperator) DeleteSiteOperation(ops.SiteOperationKey) error {
+	o.operation = nil
+	return nil
+}
+
+func (o *proxyOperator) CreateOperationPlan(key ops.SiteOperationKey, plan storage.OperationPlan) error {
+	o.plan = plan
+	return nil
+}
+
+func (o *proxyOperator) GetOperationPlan(ops.SiteOperationKey) (*storage.OperationPlan, error) {
+	return libfsm.ResolvePlan(o.plan, o.changelog), nil
+}
+
+func (o *proxyOperator) CreateOperationPlanChange(key ops.SiteOperationKey, change storage.PlanChange) error {
+	change.Created = time.Unix(int64(len(o.changelog)), 0)
+	o.changelog = append(o.changelog, change)
+	return nil
+}
+
+func (o *proxyOperator) CreateProgressEntry(ops.SiteOperationKey, ops.ProgressEntry) error {
+	return nil
+}
+
+func (o *proxyOperator) SetOperationState(key ops.SiteOperationKey, req ops.SetOperationStateRequest) error {
+	o.state = req.State
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gravity
+
+import (
+	"crypto/x509/pkix"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/resources"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/trustedca"
+
+	"github.com/gravitational/teleport/lib/tlsca"
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type TrustedCAResourceSuite struct {
+	// bundle is the PEM-encoded CA certificate
+	bundle string
+}
+
+var _ = check.Suite(&TrustedCAResourceSuite{})
+
+func (s *TrustedCAResourceSuite) SetUpSuite(c *check.C) {
+	_, cert, err := tlsca.GenerateSelfSignedCA(pkix.Name{CommonName: "proxy-ca"}, nil, time.Hour)
+	c.Assert(err, check.IsNil)
+	s.bundle = string(cert)
+}
+
+func (s *TrustedCAResourceSuite) TestRemovesCAFromFirstNodeOnFailure(c *check.C) {
+	operator := &trustedCAOperator{}
+	remote := &fakeRemote{failed: map[string]bool{"10.255.0.1": true}}
+	r := newClusterResources(operator, remote)
+
+	err := r.Create(resources.CreateRequest{Resource: toUnknown(c, storage.NewTrustedCA([]byte(s.bundle)))})
+	c.Assert(err, check.NotNil)
+	c.Assert(operator.ca, check.IsNil)
+	// installation stops at the failed node and the removal is attempted
+	// on the same node
+	c.Assert(remote.bundles(c), check.DeepEquals, []string{"10.255.0.1=" + s.bundle, "10.255.0.1="})
+}
+
+func (s *TrustedCAResourceSuite) TestRestoresCAOnFailedRemoval(c *check.C) {
+	operator := &trustedCAOperator{}
+	remote := &fakeRemote{}
+	r := newClusterResources(operator, remote)
+	err := r.Create(resources.CreateRequest{Resource: toUnknown(c, storage.NewTrustedCA([]byte(s.bundle)))})
+	c.Assert(err, check.IsNil)
+	c.Assert(remote.bundles(c), check.DeepEquals, []string{"10.255.0.1=" + s.bundle, "10.255.0.2=" + s.bundle})
+
+	remote.commands = nil
+	remote.failed = map[string]bool{"10.255.0.2": true}
+	err = r.Remove(resources.RemoveRequest{Kind: storage.KindTrustedCA, Name: storage.KindTrustedCA})
+	c.Assert(err, check.NotNil)
+	c.Assert(operator.ca, check.NotNil)
+	c.Assert(operator.ca.GetCertificates(), check.Equals, s.bundle)
+	c.Assert(remote.bundles(c), check.DeepEquals, []string{
+		"10.255.0.1=", "10.255.0.2=",
+		"10.255.0.1=" + s.bundle, "10.255.0.2=" + s.bundle,
+	})
+}
+
+func (s *TrustedCAResourceSuite) TestDoesNotRemoveCAWithoutAgents(c *check.C) {
+	operator := &trustedCAOperator{ca: storage.NewTrustedCA([]byte(s.bundle))}
+	remote := &fakeRemote{unavailable: map[string]bool{"10.255.0.1": true}}
+	r := newClusterResources(operator, remote)
+
+	err := r.Remove(resources.RemoveRequest{Kind: storage.KindTrustedCA, Name: storage.KindTrustedCA})
+	c.Assert(err, check.NotNil)
+	c.Assert(operator.ca, check.NotNil)
+	c.Assert(remote.commands, check.HasLen, 0)
+}
+
+// trustedCAOperator keeps the cluster trusted certificate authorities in memory
+type trustedCAOperator struct {
+	ops.Operator
+	ca storage.TrustedCA
+}
+
+func (o *trustedCAOperator) GetTrustedCA(ops.SiteKey) (storage.TrustedCA, error) {
+	if o.ca == nil {
+		return nil, trace.NotFound("no trusted certificate authorities found")
+	}
+	return o.ca, nil
+}
+
+func (o *trustedCAOperator) UpdateTrustedCA(key ops.SiteKey, ca storage.TrustedCA) error {
+	o.ca = ca
+	return nil
+}
+
+func (o *trustedCAOperator) DeleteTrustedCA(ops.SiteKey) error {
+	if o.ca == nil {
+		return trace.NotFound("no trusted certificate authorities found")
+	}
+	o.ca = nil
+	return nil
+}
+
+// bundles returns the CA bundle installed on each node
+func (r *fakeRemote) bundles(c *check.C) (result []string) {
+	for _, command := range r.commands {
+		bundle, err := trustedca.DecodeBundle(strings.TrimPrefix(command.args[2], "--bundle="))
+		c.Assert(err, check.IsNil)
+		result = append(result, command.server.AdvertiseIP+"="+string(bundle))
+	}
+	return result
+}
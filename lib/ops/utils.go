@@ -329,3 +329,18 @@ func MatchByType(opType string) OperationMatcher {
 
 // OperationMatcher is a function type that matches the given operation
 type OperationMatcher func(SiteOperation) bool
+
+// GetProxyEnv returns the proxy environment variables for the cluster.
+// The cluster node addresses, subnets and service domains are always
+// excluded from proxying
+func GetProxyEnv(operator Operator, cluster Site, proxy storage.Proxy) (map[string]string, error) {
+	subnets := storage.DefaultSubnets
+	op, err := GetCompletedInstallOperation(cluster.Key(), operator)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if op != nil && op.InstallExpand != nil && !op.InstallExpand.Subnets.IsEmpty() {
+		subnets = op.InstallExpand.Subnets
+	}
+	return proxy.GetEnv(storage.ClusterNoProxy(subnets, cluster.ClusterState.Servers)), nil
+}
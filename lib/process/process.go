@@ -63,6 +63,7 @@ import (
 	pb "github.com/gravitational/gravity/lib/rpc/proto"
 	rpcserver "github.com/gravitational/gravity/lib/rpc/server"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/status"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/users"
@@ -237,6 +238,7 @@ func New(ctx context.Context, cfg processconfig.Config, tcfg telecfg.FileConfig)
 		GetPeer:       peerPool.GetPeer,
 		ID:            processID,
 		AdvertiseAddr: fmt.Sprintf("https://%v", peerAddr.Addr),
+		MinReplicas:   cfg.Pack.MinReplicas,
 		// TODO: set WriteFactor to the number of controller instances
 	})
 	if err != nil {
@@ -615,6 +617,39 @@ func (p *Process) startSiteStatusChecker(ctx context.Context) error {
 	}
 }
 
+// startClusterStatusRecorder periodically records the cluster health snapshots
+// to make the history of node and probe state transitions available
+func (p *Process) startClusterStatusRecorder(ctx context.Context) error {
+	p.Info("Starting cluster status recorder.")
+	ticker := time.NewTicker(defaults.StatusHistoryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.recordClusterStatus(ctx); err != nil {
+				p.Warnf("Failed to record cluster status: %v.",
+					trace.DebugReport(err))
+			}
+		case <-ctx.Done():
+			p.Info("Stopping cluster status recorder.")
+			return nil
+		}
+	}
+}
+
+func (p *Process) recordClusterStatus(ctx context.Context) error {
+	cluster, err := p.operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	snapshot, err := status.Snapshot(ctx, p.operator, *cluster)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = p.backend.CreateStatusSnapshot(*snapshot, defaults.StatusHistoryRetention)
+	return trace.Wrap(err)
+}
+
 // startElection starts leader election process and watches the changes
 func (p *Process) startElection() error {
 	// elect gravity site leader - all other sites will remain
@@ -1239,6 +1274,7 @@ func (p *Process) initService(ctx context.Context) (err error) {
 
 	// site status checker executes status hook periodically
 	p.RegisterClusterService(p.startSiteStatusChecker)
+	p.RegisterClusterService(p.startClusterStatusRecorder)
 
 	// a few services that are running only when gravity is started in
 	// local site mode
@@ -36,11 +36,14 @@ import (
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/configure"
+	"github.com/gravitational/rigging"
 	telecfg "github.com/gravitational/teleport/lib/config"
 	teleservices "github.com/gravitational/teleport/lib/services"
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 // ReadConfig reads gravity OpsCenter or Site configuration directory
@@ -102,6 +105,22 @@ func ReadConfig(configDir string) (*Config, *telecfg.FileConfig, error) {
 	return nil, nil, trace.NotFound("no configuration found in directories %v", searchPaths)
 }
 
+// GetClusterConfig returns the cluster controller configuration
+// from the cluster ConfigMap
+func GetClusterConfig(client kubernetes.Interface) (*Config, error) {
+	configMap, err := client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).Get(
+		constants.GravityConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	var cfg Config
+	data := configMap.Data[defaults.GravityYAMLFile]
+	if err := configure.ParseYAML([]byte(data), &cfg, configure.EnableTemplating()); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &cfg, nil
+}
+
 // Config is a gravity specific file config
 type Config struct {
 	Hostname string `yaml:"hostname"`
@@ -308,6 +327,10 @@ type PackageServiceConfig struct {
 
 	// ReadDir is an optional directory with extra packages
 	ReadDir string `yaml:"read_dir"`
+
+	// MinReplicas is the minimum number of active peers that should
+	// store each package BLOB
+	MinReplicas int `yaml:"min_replicas"`
 }
 
 // PeerAddr returns peer address of the package service instance
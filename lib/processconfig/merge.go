@@ -64,6 +64,9 @@ func MergeConfig(into, from *Config) error {
 	if !from.Pack.PublicAdvertiseAddr.IsEmpty() {
 		into.Pack.PublicAdvertiseAddr = from.Pack.PublicAdvertiseAddr
 	}
+	if from.Pack.MinReplicas != 0 {
+		into.Pack.MinReplicas = from.Pack.MinReplicas
+	}
 	for i := range from.Users {
 		into.Users = append(into.Users, from.Users[i])
 	}
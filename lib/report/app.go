@@ -0,0 +1,203 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/lib/utils/kubectl"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// AppCollectors returns collectors for the report collectors
+// declared in the application manifest
+func AppCollectors(specs []schema.ReportCollector) (Collectors, error) {
+	var collectors Collectors
+	for _, spec := range specs {
+		if err := spec.Check(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		timeout, err := spec.GetTimeout()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		collectors = append(collectors, appCollector{
+			spec:    spec,
+			timeout: timeout,
+			maxSize: spec.GetMaxSize(),
+		})
+	}
+	return collectors, nil
+}
+
+// Collect runs the application-defined collector within its time limit
+// and writes at most maxSize bytes of its output.
+// Collect implements Collector
+func (r appCollector) Collect(reportWriter Writer, runner utils.CommandRunner) error {
+	name := fmt.Sprintf("app-%v", r.spec.Name)
+	if len(r.spec.Files) != 0 {
+		name = fmt.Sprintf("%v.tar.gz", name)
+	}
+	w, err := reportWriter(name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	limited := &limitWriter{w: w, remaining: r.maxSize}
+	switch {
+	case len(r.spec.Command) != 0:
+		err = utils.NewRunnerWithContext(ctx, nil).RunStream(limited, r.spec.Command...)
+	case len(r.spec.Kubectl) != 0:
+		err = utils.NewRunnerWithContext(ctx, nil).RunStream(limited,
+			utils.PlanetCommand(kubectl.Command(r.spec.Kubectl...))...)
+	case len(r.spec.Files) != 0:
+		// Compressed data cannot be truncated so the limit
+		// applies to the size of the files instead
+		err = collectFiles(ctx, w, r.spec.Files, r.maxSize)
+	case r.spec.HTTP != "":
+		err = collectHTTP(ctx, limited, r.spec.HTTP)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return trace.LimitExceeded("report collector %q timed out after %v", r.spec.Name, r.timeout)
+	}
+	if err != nil {
+		return trace.Wrap(err, "report collector %q failed", r.spec.Name)
+	}
+	if limited.truncated {
+		log.Warnf("Output of report collector %q truncated to %v bytes.", r.spec.Name, r.maxSize)
+	}
+	return nil
+}
+
+type appCollector struct {
+	spec    schema.ReportCollector
+	timeout time.Duration
+	maxSize int64
+}
+
+// collectFiles writes the files matching the specified glob patterns
+// into a compressed tarball. Files beyond the total size limit are skipped
+func collectFiles(ctx context.Context, w io.Writer, patterns []string, maxSize int64) error {
+	gzWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzWriter)
+	remaining := maxSize
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, path := range paths {
+			if ctx.Err() != nil {
+				return trace.Wrap(ctx.Err())
+			}
+			fi, err := os.Stat(path)
+			if err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			if fi.Size() > remaining {
+				log.Warnf("Skip %v: report collector size limit exceeded.", path)
+				continue
+			}
+			if err := addFile(tarWriter, path, fi); err != nil {
+				log.Warnf("Failed to collect %v: %v.", path, trace.ConvertSystemError(err))
+				continue
+			}
+			remaining -= fi.Size()
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(gzWriter.Close())
+}
+
+func addFile(w *tar.Writer, path string, fi os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	header, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	header.Name = path
+	if err := w.WriteHeader(header); err != nil {
+		return trace.Wrap(err)
+	}
+	// The file might have grown since it has been stat'ed
+	_, err = io.CopyN(w, f, fi.Size())
+	return trace.Wrap(err)
+}
+
+// collectHTTP writes the response of the HTTP endpoint at the specified URL
+func collectHTTP(ctx context.Context, w io.Writer, url string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return trace.BadParameter("unexpected response from %v: %v", url, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return trace.Wrap(err)
+}
+
+// limitWriter forwards at most the specified number of bytes
+// to the underlying writer and discards the rest
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+	truncated bool
+}
+
+// Write forwards data to the underlying writer until the limit is reached.
+// It implements io.Writer
+func (r *limitWriter) Write(data []byte) (n int, err error) {
+	if int64(len(data)) > r.remaining {
+		r.truncated = true
+		if _, err := r.w.Write(data[:r.remaining]); err != nil {
+			return 0, err
+		}
+		r.remaining = 0
+		return len(data), nil
+	}
+	n, err = r.w.Write(data)
+	r.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type AppCollectorSuite struct {
+	dir string
+}
+
+var _ = Suite(&AppCollectorSuite{})
+
+func (s *AppCollectorSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+func (s *AppCollectorSuite) TestTruncatesCommandOutput(c *C) {
+	s.collect(c, schema.ReportCollector{
+		Name:    "echo",
+		Command: []string{"echo", "0123456789"},
+		MaxSize: 4,
+	})
+	s.assertOutput(c, "app-echo", "0123")
+}
+
+func (s *AppCollectorSuite) TestCollectsHTTPEndpoint(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "up 1\n")
+	}))
+	defer server.Close()
+
+	s.collect(c, schema.ReportCollector{
+		Name: "metrics",
+		HTTP: server.URL + "/metrics",
+	})
+	s.assertOutput(c, "app-metrics", "up 1\n")
+}
+
+func (s *AppCollectorSuite) TestCollectsFiles(c *C) {
+	filesDir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(filesDir, "a.conf"), []byte("a"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(filesDir, "b.conf"), []byte("b"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(filesDir, "c.log"), []byte("c"), 0644), IsNil)
+
+	s.collect(c, schema.ReportCollector{
+		Name:  "config",
+		Files: []string{filepath.Join(filesDir, "*.conf")},
+	})
+
+	f, err := os.Open(filepath.Join(s.dir, "app-config.tar.gz"))
+	c.Assert(err, IsNil)
+	defer f.Close()
+	gzReader, err := gzip.NewReader(f)
+	c.Assert(err, IsNil)
+	var names []string
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, filepath.Base(header.Name))
+	}
+	c.Assert(names, DeepEquals, []string{"a.conf", "b.conf"})
+}
+
+func (s *AppCollectorSuite) TestEnforcesTimeout(c *C) {
+	collectors, err := AppCollectors([]schema.ReportCollector{{
+		Name:    "sleep",
+		Command: []string{"sleep", "10"},
+		Timeout: "100ms",
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(collectors, HasLen, 1)
+	err = collectors[0].Collect(NewFileWriter(s.dir), utils.NewRunner(nil))
+	c.Assert(trace.IsLimitExceeded(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *AppCollectorSuite) TestRejectsInvalidCollectors(c *C) {
+	_, err := AppCollectors([]schema.ReportCollector{{Name: "empty"}})
+	c.Assert(err, NotNil)
+}
+
+func (s *AppCollectorSuite) collect(c *C, spec schema.ReportCollector) {
+	collectors, err := AppCollectors([]schema.ReportCollector{spec})
+	c.Assert(err, IsNil)
+	c.Assert(collectors.Collect(NewFileWriter(s.dir), utils.NewRunner(nil)), IsNil)
+}
+
+func (s *AppCollectorSuite) assertOutput(c *C, name, expected string) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, expected)
+}
@@ -30,27 +30,47 @@ import (
 
 // KubernetesInfo returns a list of collectors to fetch kubernetes-related
 // diagnostics.
-func KubernetesInfo(runner utils.CommandRunner) Collectors {
+// If scope specifies namespaces, only resources and logs from these
+// namespaces are collected.
+func KubernetesInfo(runner utils.CommandRunner, scope Scope) Collectors {
 	runner = planetContextRunner{runner}
 	// general kubernetes info
 	commands := Collectors{
 		Cmd("k8s-nodes", utils.PlanetCommand(kubectl.Command("get", "nodes", "-o", "yaml"))...),
 		Cmd("k8s-nodes-describe", utils.PlanetCommand(kubectl.Command("describe", "nodes"))...),
-		Cmd("k8s-podlist", utils.PlanetCommand(kubectl.Command(
-			"get", "pods", "--all-namespaces", "--output", "wide"))...),
-		Cmd("k8s-pod-yaml", utils.PlanetCommand(kubectl.Command(
-			"get", "pods", "-o", "yaml", "--all-namespaces"))...),
-		Cmd("k8s-events", utils.PlanetCommand(kubectl.Command(
-			"get", "events", "--all-namespaces"))...),
+	}
+	if len(scope.Namespaces) == 0 {
+		commands = append(commands,
+			Cmd("k8s-podlist", utils.PlanetCommand(kubectl.Command(
+				"get", "pods", "--all-namespaces", "--output", "wide"))...),
+			Cmd("k8s-pod-yaml", utils.PlanetCommand(kubectl.Command(
+				"get", "pods", "-o", "yaml", "--all-namespaces"))...),
+			Cmd("k8s-events", utils.PlanetCommand(kubectl.Command(
+				"get", "events", "--all-namespaces"))...),
+		)
 	}
 
-	namespaces, err := kubectl.GetNamespaces(runner)
-	if err != nil || len(namespaces) == 0 {
-		namespaces = defaults.UsedNamespaces
+	namespaces := scope.Namespaces
+	if len(namespaces) == 0 {
+		var err error
+		namespaces, err = kubectl.GetNamespaces(runner)
+		if err != nil || len(namespaces) == 0 {
+			namespaces = defaults.UsedNamespaces
+		}
 	}
 	log.Debugf("kubernetes namespaces: %v", namespaces)
 
 	for _, namespace := range namespaces {
+		if len(scope.Namespaces) != 0 {
+			commands = append(commands,
+				Cmd(fmt.Sprintf("k8s-podlist-%v", namespace), utils.PlanetCommand(kubectl.Command(
+					"get", "pods", "--namespace", namespace, "--output", "wide"))...),
+				Cmd(fmt.Sprintf("k8s-pod-yaml-%v", namespace), utils.PlanetCommand(kubectl.Command(
+					"get", "pods", "-o", "yaml", "--namespace", namespace))...),
+				Cmd(fmt.Sprintf("k8s-events-%v", namespace), utils.PlanetCommand(kubectl.Command(
+					"get", "events", "--namespace", namespace))...),
+			)
+		}
 		for _, resourceType := range defaults.KubernetesReportResourceTypes {
 			name := fmt.Sprintf("k8s-%s-%s", namespace, resourceType)
 			commands = append(commands, Cmd(name,
@@ -72,10 +92,11 @@ func KubernetesInfo(runner utils.CommandRunner) Collectors {
 			}
 			for _, container := range containers {
 				name := fmt.Sprintf("k8s-logs-%v-%v-%v", namespace, pod, container)
-				commands = append(commands, Cmd(name, utils.PlanetCommand(kubectl.Command("logs", pod,
-					"--namespace", namespace,
-					fmt.Sprintf("-c=%v", container)))...),
-				)
+				args := []string{"logs", pod, "--namespace", namespace, fmt.Sprintf("-c=%v", container)}
+				if scope.Since != 0 {
+					args = append(args, fmt.Sprintf("--since=%v", scope.Since))
+				}
+				commands = append(commands, Cmd(name, utils.PlanetCommand(kubectl.Command(args...))...))
 			}
 		}
 	}
@@ -0,0 +1,456 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+// Redacted is the marker that replaces redacted data
+const Redacted = "<redacted>"
+
+// RedactionRule replaces sensitive data matched by a regular expression
+type RedactionRule struct {
+	// Name identifies the rule
+	Name string
+	// Context optionally restricts the rule to lines that match it
+	Context *regexp.Regexp
+	// Pattern matches the sensitive data
+	Pattern *regexp.Regexp
+	// Replacement is the replacement template for each match.
+	// See regexp.Regexp.Expand for the template syntax
+	Replacement string
+}
+
+// NewRedactionRule returns a rule that replaces all matches of the
+// specified regular expression with the redaction marker
+func NewRedactionRule(pattern string) (*RedactionRule, error) {
+	expr, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, trace.BadParameter("invalid redaction pattern %q: %v", pattern, err)
+	}
+	return &RedactionRule{
+		Name:        pattern,
+		Pattern:     expr,
+		Replacement: Redacted,
+	}, nil
+}
+
+// apply redacts the data matched by this rule in the specified line
+func (r RedactionRule) apply(line []byte) []byte {
+	if r.Context != nil && !r.Context.Match(line) {
+		return line
+	}
+	return r.Pattern.ReplaceAll(line, []byte(r.Replacement))
+}
+
+// DefaultRedactionRules returns the built-in redaction rules.
+// Multi-line private keys and the data of Kubernetes Secrets in YAML
+// documents are redacted by the Redactor independently of the rules
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{
+		{
+			Name:        "bearer-token",
+			Pattern:     regexp.MustCompile(`(?i)(\bbearer\s+)[a-z0-9\-._~+/]+=*`),
+			Replacement: "${1}" + Redacted,
+		},
+		{
+			Name:        "url-password",
+			Pattern:     regexp.MustCompile(`(?i)(\b[a-z][a-z0-9+.\-]*://[^:/@\s]+:)[^@/\s]+@`),
+			Replacement: "${1}" + Redacted + "@",
+		},
+		{
+			Name:        "private-key",
+			Pattern:     regexp.MustCompile(`(-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----).*?(-----END [A-Z0-9 ]*PRIVATE KEY-----)`),
+			Replacement: "${1}" + Redacted + "${2}",
+		},
+		{
+			// Secrets serialized as JSON on a single line, for example
+			// in the last-applied-configuration annotation
+			Name:        "secret-data",
+			Context:     regexp.MustCompile(`"kind":\s*"Secret"`),
+			Pattern:     regexp.MustCompile(`("(?:data|stringData)":\s*)\{[^{}]*\}`),
+			Replacement: `${1}"` + Redacted + `"`,
+		},
+	}
+}
+
+// NewRedactor returns a new redactor with the built-in rules
+// and the specified additional rules
+func NewRedactor(rules ...RedactionRule) *Redactor {
+	return &Redactor{rules: append(DefaultRedactionRules(), rules...)}
+}
+
+// Redactor removes sensitive information from diagnostic data
+type Redactor struct {
+	rules []RedactionRule
+}
+
+// Writer returns a Writer that redacts all streams created with w
+func (r *Redactor) Writer(w Writer) Writer {
+	return func(name string) (io.WriteCloser, error) {
+		out, err := w(name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return r.newStream(out), nil
+	}
+}
+
+// Redact copies data from src to dst redacting sensitive information.
+// Gzip-compressed data and tarballs are unpacked and redacted recursively,
+// other binary data is copied verbatim
+func (r *Redactor) Redact(dst io.Writer, src io.Reader) error {
+	reader := bufio.NewReaderSize(src, sniffLen)
+	head, err := reader.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return trace.Wrap(err)
+	}
+	switch {
+	case len(head) == 0:
+		return nil
+	case bytes.HasPrefix(head, gzipMagic):
+		return r.redactGzip(dst, reader)
+	case len(head) >= tarMagicOffset+len(tarMagic) &&
+		bytes.Equal(head[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		return r.redactTar(dst, reader)
+	case bytes.IndexByte(head, 0) >= 0:
+		_, err = io.Copy(dst, reader)
+		return trace.Wrap(err)
+	}
+	return r.redactText(dst, reader)
+}
+
+func (r *Redactor) redactGzip(dst io.Writer, src io.Reader) error {
+	gzReader, err := gzip.NewReader(src)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer gzReader.Close()
+	gzWriter := gzip.NewWriter(dst)
+	if err := r.Redact(gzWriter, gzReader); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(gzWriter.Close())
+}
+
+func (r *Redactor) redactTar(dst io.Writer, src io.Reader) error {
+	tarReader := tar.NewReader(src)
+	tarWriter := tar.NewWriter(dst)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+		if err := r.redactTarEntry(tarWriter, header, tarReader); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(tarWriter.Close())
+}
+
+// redactTarEntry writes the redacted contents of the file entry to w.
+// The contents are buffered in a temporary file since the size of the entry
+// is only known after redaction
+func (r *Redactor) redactTarEntry(w *tar.Writer, header *tar.Header, src io.Reader) error {
+	f, err := ioutil.TempFile("", "redact")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	if err := r.Redact(f, src); err != nil {
+		return trace.Wrap(err)
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	header.Size = size
+	if err := w.WriteHeader(header); err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = io.Copy(w, f)
+	return trace.Wrap(err)
+}
+
+func (r *Redactor) redactText(dst io.Writer, src *bufio.Reader) error {
+	filter := &lineFilter{rules: r.rules, w: dst, dataIndent: -1, kindIndent: -1}
+	for {
+		line, err := src.ReadBytes('\n')
+		if len(line) != 0 {
+			if errWrite := filter.line(line); errWrite != nil {
+				return trace.Wrap(errWrite)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(filter.flush(false))
+}
+
+func (r *Redactor) newStream(w io.WriteCloser) io.WriteCloser {
+	reader, writer := io.Pipe()
+	stream := &redactingWriter{PipeWriter: writer, w: w, errCh: make(chan error, 1)}
+	go func() {
+		err := r.Redact(w, reader)
+		if err == nil {
+			// Consume the trailing data, e.g. the tarball padding
+			_, err = io.Copy(ioutil.Discard, reader)
+		}
+		// Fail the pending and subsequent writes if redaction stopped early
+		reader.CloseWithError(err)
+		stream.errCh <- err
+	}()
+	return stream
+}
+
+// redactingWriter forwards data written to it to the redactor running
+// in the background
+type redactingWriter struct {
+	*io.PipeWriter
+	w     io.WriteCloser
+	errCh chan error
+}
+
+// Close waits for the redaction to complete and closes the underlying writer.
+// It implements io.Closer
+func (r *redactingWriter) Close() error {
+	r.PipeWriter.Close()
+	err := <-r.errCh
+	return trace.NewAggregate(err, r.w.Close())
+}
+
+// lineFilter redacts text line by line.
+//
+// Besides applying the rules to each line, it drops the body of multi-line
+// private keys and redacts values of the data and stringData blocks of
+// Kubernetes Secrets in YAML documents. As YAML serialized by kubectl
+// lists the data block before the kind of the object, the block is
+// buffered until the kind is known.
+type lineFilter struct {
+	rules []RedactionRule
+	w     io.Writer
+	// inKey is whether a multi-line private key is being skipped
+	inKey bool
+	// kind is the kind of the YAML object at kindIndent
+	kind       string
+	kindIndent int
+	// pending is the buffered data block starting at the key with dataIndent,
+	// or -1 if no block is buffered
+	pending    [][]byte
+	dataIndent int
+}
+
+func (r *lineFilter) line(line []byte) error {
+	if r.inKey {
+		idx := privateKeyEnd.FindIndex(line)
+		if idx == nil {
+			return nil
+		}
+		r.inKey = false
+		line = line[idx[0]:]
+	} else if privateKeyBegin.Match(line) && !privateKeyEnd.Match(line) {
+		idx := privateKeyBegin.FindIndex(line)
+		r.inKey = true
+		return r.write(append(append([]byte{}, line[:idx[1]]...), Redacted+"\n"...))
+	}
+
+	node := parseYAMLLine(line)
+	if node.blank {
+		if r.dataIndent >= 0 {
+			r.pending = append(r.pending, line)
+			return nil
+		}
+		return r.write(line)
+	}
+	if r.dataIndent >= 0 {
+		sibling := node.indent == r.dataIndent && !node.item && !node.document
+		switch {
+		case node.indent > r.dataIndent && !node.document:
+			// Either the contents of the block or of the keys that follow it
+			r.pending = append(r.pending, line)
+			return r.flushIfTooLong()
+		case sibling && node.key == "kind":
+			if err := r.flush(node.value == kindSecret); err != nil {
+				return trace.Wrap(err)
+			}
+		case sibling:
+			r.pending = append(r.pending, line)
+			return r.flushIfTooLong()
+		default:
+			if err := r.flush(false); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+	}
+	if node.document || (r.kindIndent >= 0 && (node.indent < r.kindIndent ||
+		(node.indent == r.kindIndent && node.item))) {
+		r.kind, r.kindIndent = "", -1
+	}
+	if node.key == "kind" {
+		r.kind, r.kindIndent = node.value, node.indent
+	}
+	if (node.key == "data" || node.key == "stringData") && node.value == "" {
+		r.dataIndent = node.indent
+		r.pending = append(r.pending, line)
+		return nil
+	}
+	return r.write(line)
+}
+
+// flush writes the buffered data block redacting its values if secret is set
+// or the block belongs to an object known to be a Secret
+func (r *lineFilter) flush(secret bool) error {
+	if r.dataIndent < 0 {
+		return nil
+	}
+	secret = secret || (r.kind == kindSecret && r.kindIndent == r.dataIndent)
+	pending, dataIndent := r.pending, r.dataIndent
+	r.pending, r.dataIndent = nil, -1
+	valueIndent := -1
+	for i, line := range pending {
+		node := parseYAMLLine(line)
+		if !secret || i == 0 || node.blank || node.indent <= dataIndent {
+			if err := r.write(line); err != nil {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+		if valueIndent < 0 {
+			valueIndent = node.indent
+		}
+		if node.indent != valueIndent || node.key == "" {
+			// Skip continuation lines of multi-line values
+			continue
+		}
+		redacted := strings.Repeat(" ", node.indent) + node.key + ": " + Redacted + "\n"
+		if err := r.write([]byte(redacted)); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// flushIfTooLong flushes the buffered data block as is if the kind of
+// the object has not been determined within a reasonable number of lines
+func (r *lineFilter) flushIfTooLong() error {
+	if len(r.pending) < maxPendingLines {
+		return nil
+	}
+	return r.flush(false)
+}
+
+func (r *lineFilter) write(line []byte) error {
+	for _, rule := range r.rules {
+		line = rule.apply(line)
+	}
+	_, err := r.w.Write(line)
+	return trace.Wrap(err)
+}
+
+// yamlLine describes a single line of a block-style YAML document
+type yamlLine struct {
+	// indent is the indentation of the mapping key
+	indent int
+	// item is whether the key starts a new sequence item
+	item bool
+	// key is the mapping key, if any
+	key string
+	// value is the inline value of the mapping key
+	value string
+	// blank is whether the line is empty or a comment
+	blank bool
+	// document is whether the line is a document separator
+	document bool
+}
+
+func parseYAMLLine(line []byte) yamlLine {
+	text := strings.TrimRight(string(line), "\r\n")
+	content := strings.TrimLeft(text, " ")
+	result := yamlLine{indent: len(text) - len(content)}
+	if content == "" || strings.HasPrefix(content, "#") {
+		result.blank = true
+		return result
+	}
+	if content == "---" {
+		result.document = true
+		return result
+	}
+	if strings.HasPrefix(content, "- ") {
+		rest := strings.TrimLeft(content[1:], " ")
+		result.indent += len(content) - len(rest)
+		result.item = true
+		content = rest
+	}
+	idx := strings.Index(content, ":")
+	if idx <= 0 || (idx < len(content)-1 && content[idx+1] != ' ') ||
+		strings.ContainsAny(content[:idx], " \"'{[") {
+		return result
+	}
+	result.key = content[:idx]
+	result.value = strings.TrimSpace(content[idx+1:])
+	return result
+}
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY-----`)
+
+	gzipMagic = []byte{0x1f, 0x8b}
+	tarMagic  = []byte("ustar")
+)
+
+const (
+	// sniffLen is the length of the prefix used to detect the type of data
+	sniffLen = 512
+	// tarMagicOffset is the offset of the magic value in the tar header
+	tarMagicOffset = 257
+	// maxPendingLines limits the number of lines buffered while
+	// determining the kind of the object with a data block
+	maxPendingLines = 10000
+	// kindSecret is the kind of Kubernetes Secret objects
+	kindSecret = "Secret"
+)
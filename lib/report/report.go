@@ -21,6 +21,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/utils"
@@ -89,6 +90,16 @@ type Collector interface {
 	Collect(Writer, utils.CommandRunner) error
 }
 
+// Scope limits the diagnostics collected in a report
+type Scope struct {
+	// Since limits logs to the time window of the specified duration.
+	// Logs are not limited if unspecified
+	Since time.Duration
+	// Namespaces limits Kubernetes diagnostics to the specified namespaces.
+	// All namespaces are collected if unspecified
+	Namespaces []string
+}
+
 // Collectors is a list of Collectors
 type Collectors []Collector
 
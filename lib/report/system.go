@@ -20,28 +20,47 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/utils"
 )
 
 // SystemInfo returns a list of collectors to fetch various bits of system information
-func SystemInfo() Collectors {
+func SystemInfo(scope Scope) Collectors {
 	var collectors Collectors
 	add := func(additional ...Collector) {
 		collectors = append(collectors, additional...)
 	}
 
-	add(basicSystemInfo()...)
-	add(planetServices()...)
-	add(syslogExportLogs())
-	add(systemFileLogs()...)
-	add(planetLogs()...)
-	add(bashHistoryCollector{})
+	add(HostInfo()...)
+	add(LogInfo(scope)...)
+	add(BashHistory())
 
 	return collectors
 }
 
+// HostInfo returns a list of collectors to fetch the host configuration
+// and the status of planet services
+func HostInfo() Collectors {
+	return append(basicSystemInfo(), planetServices()...)
+}
+
+// LogInfo returns a list of collectors to fetch system and planet logs.
+// Journal entries are limited to the time window of the specified scope
+func LogInfo(scope Scope) Collectors {
+	var collectors Collectors
+	collectors = append(collectors, syslogExportLogs(scope.Since))
+	collectors = append(collectors, systemFileLogs()...)
+	collectors = append(collectors, planetLogs(scope.Since)...)
+	return collectors
+}
+
+// BashHistory returns a collector to fetch shell histories of all users
+func BashHistory() Collector {
+	return bashHistoryCollector{}
+}
+
 func basicSystemInfo() Collectors {
 	return Collectors{
 		// networking
@@ -81,7 +100,7 @@ func planetServices() Collectors {
 
 // syslogExportLogs fetches logs for gravity binary invocations
 // (including installation logs)
-func syslogExportLogs() Collector {
+func syslogExportLogs(since time.Duration) Collector {
 	const script = `
 #!/bin/bash
 /bin/journalctl --no-pager --output=export %v | /bin/gzip -f`
@@ -93,8 +112,13 @@ func syslogExportLogs() Collector {
 		syslogID("gravity"),
 		syslogID(defaults.GravityBin),
 	}
+	var args []string
+	if since != 0 {
+		args = append(args, fmt.Sprintf("--since=%v", JournalSince(since)))
+	}
+	args = append(args, matches...)
 
-	return Script("gravity-system.log.gz", fmt.Sprintf(script, strings.Join(matches, " ")))
+	return Script("gravity-system.log.gz", fmt.Sprintf(script, strings.Join(args, " ")))
 }
 
 // systemFileLogs fetches gravity platform-related logs
@@ -109,7 +133,11 @@ cat %v 2> /dev/null || true`
 }
 
 // planetLogs fetches planet syslog messages as well as the fresh journal entries
-func planetLogs() Collectors {
+func planetLogs(since time.Duration) Collectors {
+	args := []string{"system", "export-runtime-journal"}
+	if since != 0 {
+		args = append(args, fmt.Sprintf("--since=%v", since))
+	}
 	return Collectors{
 		// Fetch planet syslog messages as a tarball
 		Script("planet-logs.tar.gz", tarball(defaults.InGravity("planet/log/messages*"))),
@@ -117,7 +145,13 @@ func planetLogs() Collectors {
 		// The log can be imported as a journal with systemd-journal-remote:
 		//
 		// $ cat ./node-1-planet-journal-export.log | /lib/systemd/systemd-journal-remote -o ./journal/system.journal -
-		Self("planet-journal-export.log.gz",
-			"system", "export-runtime-journal"),
+		Self("planet-journal-export.log.gz", args...),
 	}
 }
+
+// JournalSince formats the start of the time window of the specified
+// duration as a relative timestamp accepted by journalctl --since.
+// Relative timestamps do not depend on the time zone of the journal host
+func JournalSince(since time.Duration) string {
+	return fmt.Sprintf("-%vs", int64(since.Seconds()))
+}
@@ -53,6 +53,8 @@ type Client interface {
 	CheckPorts(context.Context, *validationpb.CheckPortsRequest) (*validationpb.CheckPortsResponse, error)
 	// CheckBandwidth executes a network bandwidth test
 	CheckBandwidth(context.Context, *validationpb.CheckBandwidthRequest) (*validationpb.CheckBandwidthResponse, error)
+	// CheckNetwork executes a network latency, packet loss and path MTU test
+	CheckNetwork(context.Context, *validationpb.CheckNetworkRequest) (*validationpb.CheckNetworkResponse, error)
 	// Shutdown requests remote agent to shut down
 	Shutdown(context.Context) error
 	// Close will close communication with remote agent
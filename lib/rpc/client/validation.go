@@ -40,3 +40,12 @@ func (c *client) CheckBandwidth(ctx context.Context, req *validationpb.CheckBand
 	}
 	return resp, nil
 }
+
+// CheckNetwork executes a network latency, packet loss and path MTU test
+func (c *client) CheckNetwork(ctx context.Context, req *validationpb.CheckNetworkRequest) (*validationpb.CheckNetworkResponse, error) {
+	resp, err := c.validation.CheckNetwork(ctx, req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp, nil
+}
@@ -264,6 +264,10 @@ func (r errorPeer) CheckBandwidth(context.Context, *validationpb.CheckBandwidthR
 	return nil, trace.Wrap(r.error)
 }
 
+func (r errorPeer) CheckNetwork(context.Context, *validationpb.CheckNetworkRequest) (*validationpb.CheckNetworkResponse, error) {
+	return nil, trace.Wrap(r.error)
+}
+
 func (r errorPeer) Shutdown(context.Context) error {
 	return trace.Wrap(r.error)
 }
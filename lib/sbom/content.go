@@ -0,0 +1,136 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/trace"
+	digest "github.com/opencontainers/go-digest"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// dirComponents returns Helm charts and Docker images found in the
+// unpacked application package directory
+func dirComponents(dir string) (components []Component, err error) {
+	charts, err := chartComponents(filepath.Join(dir, defaults.ResourcesDir))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	images, err := imageComponents(filepath.Join(dir, defaults.RegistryDir))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return append(charts, images...), nil
+}
+
+// chartComponents returns all Helm charts, including subcharts,
+// found in the specified resources directory
+func chartComponents(root string) (components []Component, err error) {
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return trace.ConvertSystemError(err)
+		}
+		if fi.IsDir() || fi.Name() != constants.HelmChartFile {
+			return nil
+		}
+		chart, err := chartutil.LoadChartfile(path)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		source, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		components = append(components, Component{
+			Type:    TypeApplication,
+			BOMRef:  fmt.Sprintf("chart:%v@%v", chart.Name, chart.Version),
+			Name:    chart.Name,
+			Version: chart.Version,
+			Properties: []Property{
+				{Name: PropertyKind, Value: KindChart},
+				{Name: PropertyPath, Value: source},
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return components, nil
+}
+
+// imageComponents returns all Docker images stored in the registry
+// with the specified root directory along with their layers
+func imageComponents(root string) (components []Component, err error) {
+	images, err := docker.ListRegistryImages(root)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, image := range images {
+		components = append(components, imageComponent(image))
+	}
+	return components, nil
+}
+
+// imageComponent returns the component describing the specified image
+func imageComponent(image docker.RegistryImage) Component {
+	component := Component{
+		Type:       TypeContainer,
+		BOMRef:     fmt.Sprintf("pkg:docker/%v@%v", image.Repository, image.Tag),
+		Name:       image.Repository,
+		Version:    image.Tag,
+		PackageURL: fmt.Sprintf("pkg:docker/%v@%v", image.Repository, image.Digest),
+		Hashes:     digestHashes(image.Digest),
+		Properties: []Property{{Name: PropertyKind, Value: KindImage}},
+	}
+	if config := image.Manifest.Config.Digest; config != "" {
+		component.Properties = append(component.Properties,
+			Property{Name: PropertyConfig, Value: config.String()})
+	}
+	for _, layer := range image.Manifest.Layers {
+		component.Components = append(component.Components, Component{
+			Type:   TypeFile,
+			BOMRef: fmt.Sprintf("%v:%v#%v", image.Repository, image.Tag, layer.Digest),
+			Name:   layer.Digest.String(),
+			Hashes: digestHashes(layer.Digest),
+			Properties: []Property{
+				{Name: PropertyKind, Value: KindLayer},
+				{Name: PropertySize, Value: strconv.FormatInt(layer.Size, 10)},
+			},
+		})
+	}
+	return component
+}
+
+// digestHashes returns the hashes of the specified content digest
+func digestHashes(dgst digest.Digest) []Hash {
+	if dgst.Algorithm() != digest.SHA256 {
+		return nil
+	}
+	return []Hash{{Algorithm: "SHA-256", Content: dgst.Hex()}}
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sbom generates software bills of materials for application
+// packages in CycloneDX JSON format.
+//
+// The bill of materials lists gravity packages and applications the
+// application depends on, Docker images vendored into the application
+// package along with their layers, Helm charts and the runtime components
+// (planet, Kubernetes and teleport) of cluster images.
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/gravitational/version"
+	"github.com/pborman/uuid"
+)
+
+// Config defines the bill of materials generation parameters
+type Config struct {
+	// Apps is the application service with the application and its dependencies
+	Apps app.Applications
+	// Packages is the package service with the application dependencies
+	Packages pack.PackageService
+	// Application is the application to generate the bill of materials for
+	Application app.Application
+	// Tool is the name of the tool generating the bill of materials
+	Tool string
+}
+
+// CheckAndSetDefaults validates the config and fills in defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Apps == nil {
+		return trace.BadParameter("missing Apps")
+	}
+	if c.Packages == nil {
+		return trace.BadParameter("missing Packages")
+	}
+	if c.Tool == "" {
+		c.Tool = constants.GravityBin
+	}
+	return nil
+}
+
+// Generate returns the bill of materials for the configured application
+func Generate(config Config) (*BOM, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	application := config.Application
+	bom := &BOM{
+		BOMFormat:    BOMFormat,
+		SpecVersion:  SpecVersion,
+		SerialNumber: fmt.Sprintf("urn:uuid:%v", uuid.New()),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: time.Now().UTC(),
+			Tools: []Tool{{
+				Vendor:  vendor,
+				Name:    config.Tool,
+				Version: version.Get().Version,
+			}},
+			Component: newAppComponent(application.Package),
+		},
+	}
+	dependencies, err := app.GetDependencies(&application, config.Apps)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, locator := range dependencies.Apps {
+		bom.Components = append(bom.Components, *newAppComponent(locator))
+	}
+	for _, locator := range dependencies.Packages {
+		component, err := packageComponent(config.Packages, locator)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		bom.Components = append(bom.Components, *component)
+	}
+	runtime, err := runtimeComponents(config.Apps, config.Packages, application, dependencies.Packages)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bom.Components = append(bom.Components, runtime...)
+	err = utils.WithTempDir(func(dir string) error {
+		err := pack.Unpack(config.Packages, application.Package, dir, nil)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		components, err := dirComponents(dir)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		bom.Components = append(bom.Components, components...)
+		return nil
+	}, "sbom")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return bom, nil
+}
+
+// Write writes the bill of materials to the provided writer in JSON format
+func (b BOM) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(b, "", "    ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = w.Write(data)
+	return trace.Wrap(err)
+}
+
+// runtimeComponents returns the runtime components of the specified application.
+// Applications without runtime have no runtime components
+func runtimeComponents(apps app.Applications, packages pack.PackageService, application app.Application, dependencies []loc.Locator) (components []Component, err error) {
+	runtime := application
+	if base := application.Manifest.Base(); base != nil {
+		baseApp, err := apps.GetApp(*base)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		runtime = *baseApp
+	}
+	runtimePackage, err := runtime.Manifest.DefaultRuntimePackage()
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	components = append(components, newRuntimeComponent(constants.PlanetPackage, runtimePackage.Version))
+	manifest, err := pack.GetPackageManifest(packages, *runtimePackage)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, label := range manifest.Labels {
+		if label.Name == constants.KubernetesVersionLabel {
+			components = append(components, newRuntimeComponent(kubernetesComponent, label.Value))
+		}
+	}
+	for _, locator := range dependencies {
+		if locator.Name == constants.TeleportPackage {
+			components = append(components, newRuntimeComponent(constants.TeleportPackage, locator.Version))
+		}
+	}
+	return components, nil
+}
+
+// packageComponent returns the component describing the specified gravity package
+func packageComponent(packages pack.PackageService, locator loc.Locator) (*Component, error) {
+	envelope, err := packages.ReadPackageEnvelope(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Component{
+		Type:       TypeFile,
+		BOMRef:     packageURL(locator),
+		Group:      locator.Repository,
+		Name:       locator.Name,
+		Version:    locator.Version,
+		PackageURL: packageURL(locator),
+		Properties: []Property{
+			{Name: PropertyKind, Value: KindPackage},
+			{Name: PropertyChecksum, Value: envelope.SHA512},
+			{Name: PropertySize, Value: strconv.FormatInt(envelope.SizeBytes, 10)},
+		},
+	}, nil
+}
+
+func newAppComponent(locator loc.Locator) *Component {
+	return &Component{
+		Type:       TypeApplication,
+		BOMRef:     packageURL(locator),
+		Group:      locator.Repository,
+		Name:       locator.Name,
+		Version:    locator.Version,
+		PackageURL: packageURL(locator),
+		Properties: []Property{{Name: PropertyKind, Value: KindApplication}},
+	}
+}
+
+func newRuntimeComponent(name, version string) Component {
+	return Component{
+		Type:       TypeFramework,
+		BOMRef:     fmt.Sprintf("runtime:%v@%v", name, version),
+		Name:       name,
+		Version:    version,
+		Properties: []Property{{Name: PropertyKind, Value: KindRuntime}},
+	}
+}
+
+// packageURL returns the package URL of the specified gravity package
+func packageURL(locator loc.Locator) string {
+	return fmt.Sprintf("pkg:generic/%v/%v@%v", locator.Repository, locator.Name, locator.Version)
+}
+
+// BOM is a CycloneDX bill of materials
+type BOM struct {
+	// BOMFormat is always CycloneDX
+	BOMFormat string `json:"bomFormat"`
+	// SpecVersion is the CycloneDX specification version
+	SpecVersion string `json:"specVersion"`
+	// SerialNumber uniquely identifies the bill of materials
+	SerialNumber string `json:"serialNumber"`
+	// Version is the version of the bill of materials
+	Version int `json:"version"`
+	// Metadata describes the bill of materials
+	Metadata Metadata `json:"metadata"`
+	// Components lists the application components
+	Components []Component `json:"components"`
+}
+
+// Metadata describes the bill of materials
+type Metadata struct {
+	// Timestamp is the bill of materials creation time
+	Timestamp time.Time `json:"timestamp"`
+	// Tools lists tools used to create the bill of materials
+	Tools []Tool `json:"tools"`
+	// Component is the application the bill of materials describes
+	Component *Component `json:"component,omitempty"`
+}
+
+// Tool describes the tool used to create the bill of materials
+type Tool struct {
+	// Vendor is the tool vendor
+	Vendor string `json:"vendor"`
+	// Name is the tool name
+	Name string `json:"name"`
+	// Version is the tool version
+	Version string `json:"version"`
+}
+
+// Component describes a single application component
+type Component struct {
+	// Type is the component type, e.g. container
+	Type string `json:"type"`
+	// BOMRef uniquely identifies the component within the bill of materials
+	BOMRef string `json:"bom-ref,omitempty"`
+	// Group is the component group, e.g. gravity package repository
+	Group string `json:"group,omitempty"`
+	// Name is the component name
+	Name string `json:"name"`
+	// Version is the component version
+	Version string `json:"version,omitempty"`
+	// PackageURL is the component package URL
+	PackageURL string `json:"purl,omitempty"`
+	// Hashes lists the component hashes
+	Hashes []Hash `json:"hashes,omitempty"`
+	// Properties lists additional component properties
+	Properties []Property `json:"properties,omitempty"`
+	// Components lists the nested components, e.g. image layers
+	Components []Component `json:"components,omitempty"`
+}
+
+// Property returns the value of the property with the specified name
+func (c Component) Property(name string) string {
+	for _, property := range c.Properties {
+		if property.Name == name {
+			return property.Value
+		}
+	}
+	return ""
+}
+
+// Hash is the component hash
+type Hash struct {
+	// Algorithm is the hash algorithm, e.g. SHA-256
+	Algorithm string `json:"alg"`
+	// Content is the hex-encoded hash value
+	Content string `json:"content"`
+}
+
+// Property is the named component property
+type Property struct {
+	// Name is the property name
+	Name string `json:"name"`
+	// Value is the property value
+	Value string `json:"value"`
+}
+
+const (
+	// BOMFormat is the format of the generated bill of materials
+	BOMFormat = "CycloneDX"
+	// SpecVersion is the CycloneDX specification version of the generated bill of materials
+	SpecVersion = "1.3"
+
+	// TypeApplication is the type of application and Helm chart components
+	TypeApplication = "application"
+	// TypeContainer is the type of Docker image components
+	TypeContainer = "container"
+	// TypeFile is the type of gravity package and image layer components
+	TypeFile = "file"
+	// TypeFramework is the type of runtime components
+	TypeFramework = "framework"
+
+	// PropertyKind is the property with the gravity-specific component kind
+	PropertyKind = "gravity:kind"
+	// PropertyChecksum is the property with the gravity package checksum
+	PropertyChecksum = "gravity:checksum"
+	// PropertySize is the property with the component size in bytes
+	PropertySize = "gravity:size"
+	// PropertyPath is the property with the path of the component within the application package
+	PropertyPath = "gravity:path"
+	// PropertyConfig is the property with the digest of the Docker image config
+	PropertyConfig = "gravity:config"
+
+	// KindApplication is the kind of gravity applications
+	KindApplication = "application"
+	// KindPackage is the kind of gravity packages
+	KindPackage = "package"
+	// KindRuntime is the kind of runtime components
+	KindRuntime = "runtime"
+	// KindImage is the kind of Docker images
+	KindImage = "image"
+	// KindLayer is the kind of Docker image layers
+	KindLayer = "layer"
+	// KindChart is the kind of Helm charts
+	KindChart = "chart"
+
+	// vendor is the vendor of the tools generating the bill of materials
+	vendor = "Gravitational"
+	// kubernetesComponent is the name of the Kubernetes runtime component
+	kubernetesComponent = "kubernetes"
+	// fileSuffix is the suffix of bill of materials files
+	fileSuffix = ".sbom.json"
+)
+
+// FileName returns the path of the bill of materials file for the installer
+// tarball with the specified path, e.g. app-1.0.0.sbom.json for app-1.0.0.tar
+func FileName(installerPath string) string {
+	return strings.TrimSuffix(installerPath, filepath.Ext(installerPath)) + fileSuffix
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	digest "github.com/opencontainers/go-digest"
+	"gopkg.in/check.v1"
+)
+
+func TestSBOM(t *testing.T) { check.TestingT(t) }
+
+type SBOMSuite struct{}
+
+var _ = check.Suite(&SBOMSuite{})
+
+func (s *SBOMSuite) TestFileName(c *check.C) {
+	c.Assert(FileName("app-1.0.0.tar"), check.Equals, "app-1.0.0.sbom.json")
+	c.Assert(FileName("/tmp/installer"), check.Equals, "/tmp/installer.sbom.json")
+}
+
+func (s *SBOMSuite) TestDirComponents(c *check.C) {
+	dir := c.MkDir()
+	writeFile(c, filepath.Join(dir, defaults.ResourcesDir, "charts", "web", "Chart.yaml"),
+		"name: web\nversion: 1.2.3\n")
+	writeFile(c, filepath.Join(dir, defaults.ResourcesDir, "charts", "web", "charts", "redis", "Chart.yaml"),
+		"name: redis\nversion: 0.1.0\n")
+
+	registry := filepath.Join(dir, defaults.RegistryDir, "docker", "registry", "v2")
+	config := digest.FromString("config")
+	layers := []digest.Digest{digest.FromString("layer1"), digest.FromString("layer2")}
+	manifest := fmt.Sprintf(`{
+  "schemaVersion": 2,
+  "config": {"mediaType": "application/vnd.docker.container.image.v1+json", "size": 10, "digest": %q},
+  "layers": [
+    {"mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip", "size": 100, "digest": %q},
+    {"mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip", "size": 200, "digest": %q}
+  ]
+}`, config, layers[0], layers[1])
+	manifestDigest := digest.FromString(manifest)
+	writeFile(c, filepath.Join(registry, "repositories", "example", "web",
+		"_manifests", "tags", "1.0", "current", "link"), manifestDigest.String())
+	writeFile(c, filepath.Join(registry, "blobs", "sha256", manifestDigest.Hex()[:2],
+		manifestDigest.Hex(), "data"), manifest)
+
+	components, err := dirComponents(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(components, check.HasLen, 3)
+
+	charts := map[string]Component{}
+	for _, component := range components[:2] {
+		c.Assert(component.Property(PropertyKind), check.Equals, KindChart)
+		charts[component.Name] = component
+	}
+	c.Assert(charts["web"].Version, check.Equals, "1.2.3")
+	c.Assert(charts["web"].Property(PropertyPath), check.Equals, filepath.Join("charts", "web"))
+	c.Assert(charts["redis"].Version, check.Equals, "0.1.0")
+
+	image := components[2]
+	c.Assert(image.Type, check.Equals, TypeContainer)
+	c.Assert(image.Name, check.Equals, "example/web")
+	c.Assert(image.Version, check.Equals, "1.0")
+	c.Assert(image.PackageURL, check.Equals, "pkg:docker/example/web@"+manifestDigest.String())
+	c.Assert(image.Hashes, check.DeepEquals, []Hash{{Algorithm: "SHA-256", Content: manifestDigest.Hex()}})
+	c.Assert(image.Property(PropertyConfig), check.Equals, config.String())
+	c.Assert(image.Components, check.HasLen, 2)
+	c.Assert(image.Components[0].Name, check.Equals, layers[0].String())
+	c.Assert(image.Components[0].Property(PropertySize), check.Equals, "100")
+	c.Assert(image.Components[1].Hashes, check.DeepEquals, []Hash{{Algorithm: "SHA-256", Content: layers[1].Hex()}})
+}
+
+func (s *SBOMSuite) TestEmptyDir(c *check.C) {
+	components, err := dirComponents(c.MkDir())
+	c.Assert(err, check.IsNil)
+	c.Assert(components, check.HasLen, 0)
+}
+
+func writeFile(c *check.C, path, data string) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask), check.IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(data), defaults.SharedReadMask), check.IsNil)
+}
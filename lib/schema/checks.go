@@ -37,6 +37,15 @@ import (
 // The specified directory is expected to be on the same filesystem
 // as the Docker graph directory (which might not exist at this point).
 func ValidateDocker(d Docker, dir string) (failed []*pb.Probe, err error) {
+	probes, err := CheckDocker(d, dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return probes.GetFailed(), nil
+}
+
+// CheckDocker validates Docker requirements and returns all executed probes
+func CheckDocker(d Docker, dir string) (probes health.Probes, err error) {
 	var checkers []health.Checker
 
 	checkers = append(checkers,
@@ -51,14 +60,18 @@ func ValidateDocker(d Docker, dir string) (failed []*pb.Probe, err error) {
 	}
 
 	all := monitoring.NewCompositeChecker("docker", checkers)
-	var probes health.Probes
-
 	all.Check(context.TODO(), &probes)
-	return probes.GetFailed(), nil
+	return probes, nil
 }
 
 // ValidateKubelet will check kubelet configuration
 func ValidateKubelet(profile NodeProfile, manifest Manifest) (failed []*pb.Probe) {
+	probes := CheckKubelet(profile, manifest)
+	return probes.GetFailed()
+}
+
+// CheckKubelet checks kubelet configuration and returns all executed probes
+func CheckKubelet(profile NodeProfile, manifest Manifest) (probes health.Probes) {
 	hairpinMode := manifest.HairpinMode(profile)
 	if hairpinMode != constants.HairpinModePromiscuousBridge {
 		// No validation required
@@ -76,14 +89,22 @@ func ValidateKubelet(profile NodeProfile, manifest Manifest) (failed []*pb.Probe
 		monitoring.NewCGroupChecker("cpu", "cpuacct", "cpuset", "memory"),
 	)
 	checker := monitoring.NewCompositeChecker("kubelet", checkers)
-
-	var probes health.Probes
 	checker.Check(context.TODO(), &probes)
-	return probes.GetFailed()
+	return probes
 }
 
 // ValidateRequirements will assess local node to match requirements
 func ValidateRequirements(reqs Requirements, stateDir string) (failed []*pb.Probe, err error) {
+	probes, err := CheckRequirements(reqs, stateDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return probes.GetFailed(), nil
+}
+
+// CheckRequirements assesses local node to match requirements
+// and returns all executed probes
+func CheckRequirements(reqs Requirements, stateDir string) (probes health.Probes, err error) {
 	var checkers []health.Checker
 	checkers = append(checkers, monitoring.NewHostChecker(
 		monitoring.HostConfig{
@@ -142,10 +163,8 @@ func ValidateRequirements(reqs Requirements, stateDir string) (failed []*pb.Prob
 	}
 
 	all := monitoring.NewCompositeChecker("common requirements", checkers)
-	var probes health.Probes
-
 	all.Check(context.TODO(), &probes)
-	return probes.GetFailed(), nil
+	return probes, nil
 }
 
 // shouldCheckVolume determines if this volume should be checked
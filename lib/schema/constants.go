@@ -77,6 +77,9 @@ const (
 	// KindRuntime defines a runtime application type
 	KindRuntime = "Runtime"
 
+	// kindJob is the Kubernetes job resource kind
+	kindJob = "Job"
+
 	// APIVersionV1 specifies the previous API version
 	APIVersionV1 = "v1"
 	// APIVersionLegacyV2 specifies legacy v2 version
@@ -0,0 +1,518 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	schemadefaults "github.com/gravitational/gravity/lib/schema/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	mastersemver "github.com/Masterminds/semver"
+	"github.com/coreos/go-semver/semver"
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"github.com/santhosh-tekuri/jsonschema"
+)
+
+// ManifestJSONSchema returns the JSON schema of the application manifest,
+// e.g. for use in editors that support YAML validation
+func ManifestJSONSchema() []byte {
+	return []byte(manifestSchema)
+}
+
+// Severity defines the severity of a lint diagnostic
+type Severity string
+
+const (
+	// SeverityError marks problems that prevent the manifest from being used
+	SeverityError Severity = "error"
+	// SeverityWarning marks suspicious but otherwise valid manifest attributes
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic describes a single problem found in the manifest
+type Diagnostic struct {
+	// Severity is the problem severity
+	Severity Severity `json:"severity"`
+	// Path is the JSON pointer to the offending manifest attribute
+	Path string `json:"path"`
+	// Line is the 1-based line of the offending attribute in the manifest file
+	Line int `json:"line"`
+	// Column is the 1-based column of the offending attribute in the manifest file
+	Column int `json:"column"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic as line:column: severity: message
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v:%v: %v: %v", d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Diagnostics is a list of lint diagnostics
+type Diagnostics []Diagnostic
+
+// HasErrors returns true if any of the diagnostics is an error
+func (r Diagnostics) HasErrors() bool {
+	for _, d := range r {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// LintConfig configures the manifest linter
+type LintConfig struct {
+	// Resolve optionally verifies that the dependency package exists.
+	// It is expected to return a NotFound error for missing packages
+	Resolve func(loc.Locator) error
+}
+
+// Lint validates the manifest given as YAML data against the manifest schema
+// and checks its cross-references. Unlike ParseManifestYAML, it does not stop
+// at the first problem and reports all of them with their locations.
+//
+// The returned error is only set for failures unrelated to the manifest contents
+func Lint(data []byte, config LintConfig) (Diagnostics, error) {
+	l := &linter{
+		LintConfig: config,
+		positions:  indexYAML(data),
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		l.addParseError(err)
+		return l.result(), nil
+	}
+	if err := l.lint(jsonData); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return l.result(), nil
+}
+
+type linter struct {
+	LintConfig
+	positions   positions
+	diagnostics Diagnostics
+}
+
+func (l *linter) lint(data []byte) error {
+	var header Header
+	if err := json.Unmarshal(data, &header); err != nil {
+		l.errorf("", "manifest is not an object: %v", err)
+		return nil
+	}
+	var manifest *Manifest
+	switch header.APIVersion {
+	case APIVersionV2, APIVersionV2Cluster, APIVersionV2App:
+		if err := schema.Validate(bytes.NewReader(data)); err != nil {
+			if err := l.addValidationError(err); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		l.lintDependencies(data)
+		type serializableManifest Manifest
+		var decoded serializableManifest
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			if !l.diagnostics.HasErrors() {
+				l.errorf("", "failed to decode manifest: %v", err)
+			}
+			return nil
+		}
+		if err := schemadefaults.Apply(&decoded, schema); err != nil {
+			return trace.Wrap(err, "failed to set manifest defaults")
+		}
+		manifest = (*Manifest)(&decoded)
+	case APIVersionV1:
+		l.warnf("/apiVersion", "API version %v is deprecated, use %v",
+			APIVersionV1, APIVersionV2)
+		l.lintDependencies(data)
+		var decoded Manifest
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			l.errorf("", "%v", trace.UserMessage(err))
+			return nil
+		}
+		manifest = &decoded
+	default:
+		l.errorf("/apiVersion", "unknown manifest API version: %q", header.APIVersion)
+		return nil
+	}
+	l.lintManifest(*manifest)
+	return nil
+}
+
+// lintManifest performs the semantic checks of CheckAndSetDefaults
+// along with the cross-reference checks
+func (l *linter) lintManifest(manifest Manifest) {
+	l.lintMetadata(manifest.Metadata)
+	switch manifest.Kind {
+	case KindBundle, KindCluster, KindApplication:
+	default:
+		return
+	}
+	if len(manifest.NodeProfiles) > 0 && len(manifest.FlavorNames()) == 0 {
+		l.errorf("/nodeProfiles", "at least one flavor is required when node profiles are defined")
+	}
+	l.lintProfiles(manifest)
+	if manifest.Installer != nil {
+		l.lintFlavors(manifest.Installer.Flavors, manifest.NodeProfiles)
+	}
+	if manifest.WebConfig != "" {
+		if err := checkWebConfig(manifest.WebConfig); err != nil {
+			l.errorf("/webConfig", "%v", trace.UserMessage(err))
+		}
+	}
+	l.lintHooks(manifest)
+	if manifest.Upgrade != nil && manifest.Upgrade.Rollback != nil {
+		if _, err := manifest.Upgrade.Rollback.GracePeriod(); err != nil {
+			l.errorf("/upgrade/rollback/healthCheckGracePeriod", "%v", trace.UserMessage(err))
+		}
+	}
+	if manifest.Report != nil {
+		for i, collector := range manifest.Report.Collectors {
+			path := fmt.Sprintf("/report/collectors/%v", i)
+			for _, err := range checkReport(Report{Collectors: []ReportCollector{collector}}, manifest.NodeProfiles) {
+				l.errorf(path, "%v", trace.UserMessage(err))
+			}
+		}
+	}
+	if manifest.SystemOptions != nil && manifest.SystemOptions.Runtime == nil {
+		l.errorf("/systemOptions", "no runtime application defined")
+	}
+}
+
+func (l *linter) lintMetadata(metadata Metadata) {
+	if err := utils.CheckName(metadata.Name); err != nil {
+		l.errorf("/metadata/name", "%v", trace.UserMessage(err))
+	}
+	if _, err := semver.NewVersion(metadata.ResourceVersion); err != nil {
+		l.errorf("/metadata/resourceVersion",
+			"app version must be in semver format, got %q", metadata.ResourceVersion)
+	}
+	if metadata.Repository != defaults.SystemAccountOrg {
+		l.errorf("/metadata/repository", "repository must be equal to %q", defaults.SystemAccountOrg)
+	}
+}
+
+func (l *linter) lintProfiles(manifest Manifest) {
+	names := make(map[string]int)
+	for i, profile := range manifest.NodeProfiles {
+		path := fmt.Sprintf("/nodeProfiles/%v", i)
+		if prev, ok := names[profile.Name]; ok {
+			l.errorf(path+"/name", "node profile %q is already defined at %v",
+				profile.Name, l.location(fmt.Sprintf("/nodeProfiles/%v/name", prev)))
+		} else {
+			names[profile.Name] = i
+		}
+		if err := checkProfile(profile); err != nil {
+			for _, err := range flatten(err) {
+				l.errorf(path, "node profile %q: %v", profile.Name, trace.UserMessage(err))
+			}
+		}
+		if err := checkDocker(manifest.Docker(profile)); err != nil {
+			dockerPath := "/systemOptions/docker/storageDriver"
+			if profile.SystemOptions != nil && profile.SystemOptions.Docker != nil &&
+				profile.SystemOptions.Docker.StorageDriver != "" {
+				dockerPath = path + dockerPath
+			}
+			l.errorf(dockerPath, "%v", trace.UserMessage(err))
+		}
+		l.lintPorts(path+"/requirements/network/ports", profile.Requirements.Network.Ports)
+		if err := profile.Requirements.Network.Check(); err != nil {
+			for _, err := range flatten(err) {
+				l.errorf(path+"/requirements/network", "node profile %q: %v",
+					profile.Name, trace.UserMessage(err))
+			}
+		}
+		for j := range profile.Requirements.Volumes {
+			volume := profile.Requirements.Volumes[j]
+			if err := volume.CheckAndSetDefaults(); err != nil {
+				l.errorf(fmt.Sprintf("%v/requirements/volumes/%v", path, j),
+					"volume %q: %v", volume.Path, trace.UserMessage(err))
+			}
+		}
+	}
+}
+
+// lintPorts makes sure the port ranges are valid and do not overlap
+func (l *linter) lintPorts(path string, ports []Port) {
+	type portRange struct {
+		from, to uint64
+		path     string
+	}
+	seen := make(map[string][]portRange)
+	for i, port := range ports {
+		for j, value := range port.Ranges {
+			rangePath := fmt.Sprintf("%v/%v/ranges/%v", path, i, j)
+			ranges, err := parsePortRanges(port.Protocol, []string{value})
+			if err != nil {
+				l.errorf(rangePath, "invalid port range %q", value)
+				continue
+			}
+			protocol := strings.ToLower(port.Protocol)
+			for _, r := range ranges {
+				if r.From > r.To {
+					l.errorf(rangePath, "invalid port range %q: start is greater than end", value)
+					continue
+				}
+				for _, other := range seen[protocol] {
+					if r.From <= other.to && other.from <= r.To {
+						l.errorf(rangePath, "%v port range %q overlaps with the range at %v",
+							port.Protocol, value, l.location(other.path))
+						break
+					}
+				}
+				seen[protocol] = append(seen[protocol], portRange{from: r.From, to: r.To, path: rangePath})
+			}
+		}
+	}
+}
+
+func (l *linter) lintFlavors(flavors Flavors, profiles NodeProfiles) {
+	names := make(map[string]bool)
+	for i, flavor := range flavors.Items {
+		path := fmt.Sprintf("/installer/flavors/items/%v", i)
+		if names[flavor.Name] {
+			l.errorf(path+"/name", "flavor %q is defined more than once", flavor.Name)
+		}
+		names[flavor.Name] = true
+		for j, node := range flavor.Nodes {
+			nodePath := fmt.Sprintf("%v/nodes/%v", path, j)
+			if _, err := profiles.ByName(node.Profile); err != nil {
+				l.errorf(nodePath+"/profile", "flavor %q refers to undefined profile %q",
+					flavor.Name, node.Profile)
+			}
+			if node.Count < 0 {
+				l.errorf(nodePath+"/count", "flavor %q has negative count for profile %q",
+					flavor.Name, node.Profile)
+			}
+		}
+	}
+	if flavors.Default != "" && !names[flavors.Default] {
+		l.errorf("/installer/flavors/default", "default flavor %q is not defined", flavors.Default)
+	}
+}
+
+func (l *linter) lintHooks(manifest Manifest) {
+	if manifest.Hooks == nil {
+		return
+	}
+	for _, hookType := range AllHooks() {
+		hook, err := HookFromString(hookType, manifest)
+		if err != nil || hook == nil || hook.Empty() {
+			continue
+		}
+		path := fmt.Sprintf("/hooks/%v/job", hookType)
+		if isExternalSource(hook.Job) {
+			l.warnf(path, "hook %q job is loaded from %v and is not checked", hookType, hook.Job)
+			continue
+		}
+		job, err := hook.GetJob()
+		if err != nil {
+			l.errorf(path, "hook %q job is not a valid Kubernetes Job: %v", hookType, trace.UserMessage(err))
+			continue
+		}
+		if job.Kind != "" && job.Kind != kindJob {
+			l.errorf(path, "hook %q job has kind %q, expected %q", hookType, job.Kind, kindJob)
+		}
+		if len(job.Spec.Template.Spec.Containers) == 0 {
+			l.errorf(path, "hook %q job does not define any containers", hookType)
+		}
+	}
+	hooks := manifest.Hooks
+	provisioning := map[HookType]*Hook{
+		HookClusterProvision:   hooks.ClusterProvision,
+		HookClusterDeprovision: hooks.ClusterDeprovision,
+		HookNodesProvision:     hooks.NodesProvision,
+		HookNodesDeprovision:   hooks.NodesDeprovision,
+	}
+	var defined bool
+	for _, hook := range provisioning {
+		defined = defined || hook != nil
+	}
+	if !defined {
+		return
+	}
+	for _, hookType := range []HookType{HookClusterProvision, HookClusterDeprovision,
+		HookNodesProvision, HookNodesDeprovision} {
+		if provisioning[hookType] == nil {
+			l.errorf("/hooks", "specify %v hook when using custom provisioning", hookType)
+		}
+	}
+}
+
+// lintDependencies makes sure dependency locators are valid and, if configured,
+// that they resolve to existing packages
+func (l *linter) lintDependencies(data []byte) {
+	var manifest struct {
+		Dependencies struct {
+			Packages []interface{} `json:"packages"`
+			Apps     []interface{} `json:"apps"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		// Reported by the schema validation
+		return
+	}
+	lint := func(kind string, deps []interface{}) {
+		seen := make(map[string]bool)
+		for i, dep := range deps {
+			path := fmt.Sprintf("/dependencies/%v/%v", kind, i)
+			if object, ok := dep.(map[string]interface{}); ok {
+				if constraint, ok := object["version"].(string); ok {
+					if _, err := mastersemver.NewConstraint(constraint); err != nil {
+						l.errorf(path+"/version", "invalid version constraint %q: %v", constraint, err)
+					}
+				}
+				continue
+			}
+			value, ok := dep.(string)
+			if !ok {
+				continue
+			}
+			locator, err := loc.ParseLocator(value)
+			if err != nil {
+				l.errorf(path, "invalid dependency locator %q: %v", value, trace.UserMessage(err))
+				continue
+			}
+			if seen[locator.String()] {
+				l.warnf(path, "dependency %v is listed more than once", locator)
+			}
+			seen[locator.String()] = true
+			if l.Resolve == nil {
+				continue
+			}
+			if err := l.Resolve(*locator); err != nil {
+				if trace.IsNotFound(err) {
+					l.errorf(path, "dependency %v not found", locator)
+				} else {
+					l.warnf(path, "failed to resolve dependency %v: %v", locator, trace.UserMessage(err))
+				}
+			}
+		}
+	}
+	lint("packages", manifest.Dependencies.Packages)
+	lint("apps", manifest.Dependencies.Apps)
+}
+
+// addValidationError adds diagnostics for all schema violations in err
+func (l *linter) addValidationError(err error) error {
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return trace.Wrap(err)
+	}
+	for _, cause := range leafErrors(validationErr) {
+		l.errorf(cause.InstancePtr, "%v", cause.Message)
+	}
+	return nil
+}
+
+// leafErrors returns the most specific causes of the validation error
+func leafErrors(err *jsonschema.ValidationError) (leaves []*jsonschema.ValidationError) {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	for _, cause := range err.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
+
+// addParseError adds the diagnostic for the YAML syntax error
+func (l *linter) addParseError(err error) {
+	message := strings.TrimPrefix(err.Error(), "error converting YAML to JSON: ")
+	d := Diagnostic{Severity: SeverityError, Line: 1, Column: 1, Message: message}
+	if match := reYAMLLine.FindStringSubmatch(message); len(match) == 2 {
+		d.Line, _ = strconv.Atoi(match[1])
+		d.Message = reYAMLLine.ReplaceAllString(message, "")
+	}
+	l.diagnostics = append(l.diagnostics, d)
+}
+
+func (l *linter) errorf(path, format string, args ...interface{}) {
+	l.add(SeverityError, path, fmt.Sprintf(format, args...))
+}
+
+func (l *linter) warnf(path, format string, args ...interface{}) {
+	l.add(SeverityWarning, path, fmt.Sprintf(format, args...))
+}
+
+func (l *linter) add(severity Severity, path, message string) {
+	path = strings.TrimPrefix(path, "#")
+	pos := l.positions.lookup(path)
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity: severity,
+		Path:     path,
+		Line:     pos.line,
+		Column:   pos.column,
+		Message:  message,
+	})
+}
+
+// location formats the location of the node specified with path
+func (l *linter) location(path string) string {
+	pos := l.positions.lookup(path)
+	return fmt.Sprintf("%v:%v", pos.line, pos.column)
+}
+
+// result returns the diagnostics ordered by their location
+func (l *linter) result() Diagnostics {
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i], l.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.diagnostics
+}
+
+// isExternalSource returns true if the value refers to a file or URL
+func isExternalSource(value string) bool {
+	for _, prefix := range []string{"file://", "http://", "https://"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// flatten returns the individual errors of the aggregate error
+func flatten(err error) []error {
+	if aggregate, ok := trace.Unwrap(err).(trace.Aggregate); ok {
+		var errors []error
+		for _, err := range aggregate.Errors() {
+			if err != nil {
+				errors = append(errors, flatten(err)...)
+			}
+		}
+		return errors
+	}
+	return []error{err}
+}
+
+// reYAMLLine matches the line reference in YAML syntax errors
+var reYAMLLine = regexp.MustCompile(`^yaml: line (\d+): `)
@@ -0,0 +1,169 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/loc"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type LintSuite struct{}
+
+var _ = Suite(&LintSuite{})
+
+func (s *LintSuite) TestAcceptsValidManifest(c *C) {
+	diagnostics, err := Lint([]byte(validLintManifest), LintConfig{})
+	c.Assert(err, IsNil)
+	c.Assert(diagnostics, HasLen, 0)
+}
+
+func (s *LintSuite) TestReportsAllProblemsWithLocations(c *C) {
+	diagnostics, err := Lint([]byte(invalidLintManifest), LintConfig{
+		Resolve: func(locator loc.Locator) error {
+			if locator.Name == "missing" {
+				return trace.NotFound("package not found")
+			}
+			return nil
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(diagnostics.HasErrors(), Equals, true)
+	c.Assert(summarize(diagnostics), DeepEquals, []string{
+		"4:3 /metadata/name",
+		"9:5 /dependencies/apps/1",
+		"18:15 /nodeProfiles/0/requirements/network/ports/0/ranges/1",
+		"24:13 /installer/flavors/items/0/nodes/0/profile",
+		"28:5 /hooks/install/job",
+	})
+}
+
+func (s *LintSuite) TestReportsSchemaViolations(c *C) {
+	diagnostics, err := Lint([]byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app
+  resourceVersion: 0.0.1
+nodeProfiles:
+  - name: node
+    requirements:
+      cpu:
+        min: many
+`), LintConfig{})
+	c.Assert(err, IsNil)
+	c.Assert(diagnostics, Not(HasLen), 0)
+	c.Assert(diagnostics[0].Path, Equals, "/nodeProfiles/0/requirements/cpu/min")
+	c.Assert(diagnostics[0].Line, Equals, 10)
+	c.Assert(diagnostics[0].Column, Equals, 9)
+}
+
+func (s *LintSuite) TestReportsSyntaxErrors(c *C) {
+	diagnostics, err := Lint([]byte("apiVersion: v2\nkind: [Bundle\n"), LintConfig{})
+	c.Assert(err, IsNil)
+	c.Assert(diagnostics, HasLen, 1)
+	c.Assert(diagnostics[0].Severity, Equals, SeverityError)
+	c.Assert(diagnostics[0].Line, Not(Equals), 0)
+}
+
+func (s *LintSuite) TestExportsSchema(c *C) {
+	var schema map[string]interface{}
+	c.Assert(json.Unmarshal(ManifestJSONSchema(), &schema), IsNil)
+	c.Assert(schema["definitions"], NotNil)
+}
+
+func summarize(diagnostics Diagnostics) (result []string) {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			result = append(result, fmt.Sprintf("%v:%v %v", d.Line, d.Column, d.Path))
+		}
+	}
+	return result
+}
+
+const validLintManifest = `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app
+  resourceVersion: 0.0.1
+nodeProfiles:
+  - name: node
+    requirements:
+      network:
+        ports:
+          - protocol: tcp
+            ranges: ["8080", "9000-9010"]
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: node
+            count: 1
+hooks:
+  install:
+    job: |
+      apiVersion: batch/v1
+      kind: Job
+      metadata:
+        name: install
+      spec:
+        template:
+          spec:
+            containers:
+              - name: install
+                image: install:1.0.0
+`
+
+const invalidLintManifest = `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app.name
+  resourceVersion: 0.0.1
+dependencies:
+  apps:
+    - gravitational.io/present:1.0.0
+    - gravitational.io/missing:1.0.0
+nodeProfiles:
+  - name: node
+    requirements:
+      network:
+        ports:
+          - protocol: tcp
+            ranges:
+              - "9000-9010"
+              - "9005"
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: master
+            count: 1
+hooks:
+  install:
+    job: |
+      apiVersion: batch/v1
+      kind: Job
+      spec:
+        template:
+          spec:
+            containers: []
+`
@@ -19,7 +19,9 @@ package schema
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -67,10 +69,17 @@ type Manifest struct {
 	License *License `json:"license,omitempty"`
 	// Hooks contains application-defined hooks
 	Hooks *Hooks `json:"hooks,omitempty"`
+	// Upgrade contains application upgrade settings
+	Upgrade *Upgrade `json:"upgrade,omitempty"`
+	// Report customizes the cluster diagnostics report
+	Report *Report `json:"report,omitempty"`
 	// SystemOptions contains various global settings
 	SystemOptions *SystemOptions `json:"systemOptions,omitempty"`
 	// Extensions allows to enable/disable various custom features
 	Extensions *Extensions `json:"extensions,omitempty"`
+	// ImageDigests pins container images vendored into the application
+	// to their content digests
+	ImageDigests []ImageDigest `json:"imageDigests,omitempty"`
 	// WebConfig allows to specify config.js used by UI to customize installer
 	WebConfig string `json:"webConfig,omitempty"`
 }
@@ -321,6 +330,24 @@ func (m Manifest) RuntimePackage(profile NodeProfile) (*loc.Locator, error) {
 	return m.DefaultRuntimePackage()
 }
 
+// RollbackPolicy returns the automatic upgrade rollback policy.
+// Returns nil if the manifest does not define one
+func (m Manifest) RollbackPolicy() *UpgradeRollback {
+	if m.Upgrade == nil {
+		return nil
+	}
+	return m.Upgrade.Rollback
+}
+
+// ReportCollectors returns the additional diagnostics collectors
+// declared by the application
+func (m Manifest) ReportCollectors() []ReportCollector {
+	if m.Report == nil {
+		return nil
+	}
+	return m.Report.Collectors
+}
+
 // DefaultRuntimePackage returns the default runtime package
 func (m Manifest) DefaultRuntimePackage() (*loc.Locator, error) {
 	if m.SystemOptions == nil || m.SystemOptions.Dependencies.Runtime == nil {
@@ -457,20 +484,71 @@ func (d Dependencies) GetApps() []loc.Locator {
 	return loc.Deduplicate(apps)
 }
 
+// HasConstraints returns true if any of the application dependencies
+// is specified with a version constraint
+func (d Dependencies) HasConstraints() bool {
+	for _, app := range d.Apps {
+		if app.Constraint != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // Dependency represents a package or app dependency
 type Dependency struct {
 	// Locator is dependency package locator
 	Locator loc.Locator
+	// Constraint is the optional semver range the version of the application
+	// dependency has to satisfy, e.g. "^1.2.0". The locator of a constrained
+	// dependency has no version until it is resolved during the build
+	Constraint string
+}
+
+// constrainedDependency is the serialized form of the dependency
+// specified with a version constraint
+type constrainedDependency struct {
+	// Name is the dependency name in the repository/name format
+	Name string `json:"name"`
+	// Version is the semver range of the dependency version
+	Version string `json:"version"`
 }
 
 // MarshalJSON marshals dependency into a JSON string
 func (d *Dependency) MarshalJSON() ([]byte, error) {
+	if d.Constraint != "" {
+		bytes, err := json.Marshal(constrainedDependency{
+			Name:    fmt.Sprintf("%v/%v", d.Locator.Repository, d.Locator.Name),
+			Version: d.Constraint,
+		})
+		return bytes, trace.Wrap(err)
+	}
 	bytes, err := json.Marshal(d.Locator.String())
 	return bytes, trace.Wrap(err)
 }
 
-// UnmarshalJSON unmarshals dependency from a JSON string
+// UnmarshalJSON unmarshals dependency from a JSON string or from
+// an object with the dependency name and version constraint
 func (d *Dependency) UnmarshalJSON(data []byte) error {
+	if len(data) != 0 && data[0] == '{' {
+		var dep constrainedDependency
+		if err := json.Unmarshal(data, &dep); err != nil {
+			return trace.Wrap(err)
+		}
+		parts := strings.Split(dep.Name, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return trace.BadParameter("dependency name should be repository/name, "+
+				"e.g. example.com/test, got %q", dep.Name)
+		}
+		if dep.Version == "" {
+			return trace.BadParameter("dependency %v is missing version constraint", dep.Name)
+		}
+		*d = Dependency{
+			Locator:    loc.Locator{Repository: parts[0], Name: parts[1]},
+			Constraint: dep.Version,
+		}
+		return nil
+	}
 	var locator string
 	if err := json.Unmarshal(data, &locator); err != nil {
 		return trace.Wrap(err)
@@ -746,6 +824,57 @@ type Network struct {
 	MinTransferRate utils.TransferRate `json:"minTransferRate,omitempty"`
 	// Ports specifies port ranges that should be available on the server
 	Ports []Port `json:"ports,omitempty"`
+	// MaxLatency is the maximum allowed average round-trip time
+	// between the nodes, e.g. "10ms"
+	MaxLatency string `json:"maxLatency,omitempty"`
+	// MaxJitter is the maximum allowed average variation of the round-trip
+	// time between the nodes, e.g. "5ms"
+	MaxJitter string `json:"maxJitter,omitempty"`
+	// MaxPacketLoss is the maximum allowed packet loss between the nodes, in percent
+	MaxPacketLoss float64 `json:"maxPacketLoss,omitempty"`
+	// MinMTU is the minimum required path MTU between the nodes
+	MinMTU int `json:"minMTU,omitempty"`
+}
+
+// Check makes sure the network requirements are valid
+func (r Network) Check() error {
+	var errors []error
+	if _, err := r.GetMaxLatency(); err != nil {
+		errors = append(errors, err)
+	}
+	if _, err := r.GetMaxJitter(); err != nil {
+		errors = append(errors, err)
+	}
+	if r.MaxPacketLoss < 0 || r.MaxPacketLoss > 100 {
+		errors = append(errors, trace.BadParameter(
+			"max packet loss should be within [0, 100], got %v", r.MaxPacketLoss))
+	}
+	if r.MinMTU < 0 {
+		errors = append(errors, trace.BadParameter(
+			"min MTU should not be negative, got %v", r.MinMTU))
+	}
+	return trace.NewAggregate(errors...)
+}
+
+// GetMaxLatency returns the maximum allowed round-trip time as a duration
+func (r Network) GetMaxLatency() (time.Duration, error) {
+	return parseNetworkDuration("max latency", r.MaxLatency)
+}
+
+// GetMaxJitter returns the maximum allowed round-trip time variation as a duration
+func (r Network) GetMaxJitter() (time.Duration, error) {
+	return parseNetworkDuration("max jitter", r.MaxJitter)
+}
+
+func parseNetworkDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, trace.BadParameter("invalid %v %q: %v", name, value, err)
+	}
+	return duration, nil
 }
 
 // Port describes port ranges
@@ -949,6 +1078,172 @@ func (r *SystemOptions) RuntimeArgs() []string {
 	return r.Args
 }
 
+// ImageDigest pins a vendored container image to its content digest
+type ImageDigest struct {
+	// Image is the image reference as found in the application resources
+	Image string `json:"image"`
+	// Digest is the repository digest of the image in its source registry
+	Digest string `json:"digest,omitempty"`
+	// ID is the digest of the image configuration
+	ID string `json:"id"`
+}
+
+// Upgrade defines application upgrade settings
+type Upgrade struct {
+	// Rollback defines the automatic rollback policy for failed upgrades
+	Rollback *UpgradeRollback `json:"rollback,omitempty"`
+}
+
+// UpgradeRollback defines the automatic rollback policy for failed upgrades
+type UpgradeRollback struct {
+	// OnFailure specifies whether completed upgrade phases are rolled back
+	// automatically if the upgrade fails
+	OnFailure bool `json:"onFailure,omitempty"`
+	// HealthCheckGracePeriod specifies the period of time the cluster has
+	// to become healthy after the upgrade, e.g. "5m".
+	// If the cluster does not become healthy, the upgrade is rolled back.
+	// Post-upgrade health checks are not run if unspecified
+	HealthCheckGracePeriod string `json:"healthCheckGracePeriod,omitempty"`
+}
+
+// GracePeriod returns the health check grace period as a duration
+func (r UpgradeRollback) GracePeriod() (time.Duration, error) {
+	if r.HealthCheckGracePeriod == "" {
+		return 0, nil
+	}
+	period, err := time.ParseDuration(r.HealthCheckGracePeriod)
+	if err != nil {
+		return 0, trace.BadParameter("invalid health check grace period %q: %v",
+			r.HealthCheckGracePeriod, err)
+	}
+	return period, nil
+}
+
+// Report customizes the cluster diagnostics report
+type Report struct {
+	// Collectors lists additional diagnostics collectors
+	Collectors []ReportCollector `json:"collectors,omitempty"`
+}
+
+// ReportCollector describes an additional diagnostics collector.
+// Exactly one of Command, Kubectl, Files or HTTP must be specified
+type ReportCollector struct {
+	// Name names the collector and its output in the report
+	Name string `json:"name"`
+	// NodeProfiles limits the host collectors to the nodes with the specified
+	// profiles. Host collectors run on all nodes if unspecified
+	NodeProfiles []string `json:"nodeProfiles,omitempty"`
+	// Command specifies the command to run on each node
+	Command []string `json:"command,omitempty"`
+	// Kubectl specifies the arguments of the kubectl query to run once
+	// per cluster, e.g. ["get", "pods", "--namespace", "app", "-o", "yaml"]
+	Kubectl []string `json:"kubectl,omitempty"`
+	// Files lists the paths or glob patterns of the files to collect
+	// from each node
+	Files []string `json:"files,omitempty"`
+	// HTTP specifies the URL of the HTTP endpoint, e.g. Prometheus metrics,
+	// to query on each node
+	HTTP string `json:"http,omitempty"`
+	// Timeout limits the run time of the collector, e.g. "30s".
+	// Defaults to defaults.ReportCollectorTimeout
+	Timeout string `json:"timeout,omitempty"`
+	// MaxSize limits the size of the collected data, e.g. "10MB".
+	// The output is truncated to this size.
+	// Defaults to defaults.ReportCollectorMaxSize
+	MaxSize utils.Capacity `json:"maxSize,omitempty"`
+}
+
+// Check validates this collector
+func (r ReportCollector) Check() error {
+	if err := utils.CheckName(r.Name); err != nil {
+		return trace.BadParameter("invalid report collector name %q: %v", r.Name, err)
+	}
+	var sources int
+	for _, specified := range []bool{len(r.Command) != 0, len(r.Kubectl) != 0,
+		len(r.Files) != 0, r.HTTP != ""} {
+		if specified {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return trace.BadParameter("report collector %q must specify exactly one of "+
+			"command, kubectl, files or http", r.Name)
+	}
+	if r.IsKubernetes() && len(r.NodeProfiles) != 0 {
+		return trace.BadParameter("report collector %q: kubectl queries run once per cluster "+
+			"and cannot be limited to node profiles", r.Name)
+	}
+	for _, pattern := range r.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return trace.BadParameter("report collector %q: invalid file pattern %q", r.Name, pattern)
+		}
+	}
+	if r.HTTP != "" {
+		u, err := url.Parse(r.HTTP)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return trace.BadParameter("report collector %q: invalid HTTP endpoint %q", r.Name, r.HTTP)
+		}
+	}
+	if _, err := r.GetTimeout(); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// IsKubernetes returns true if this collector queries Kubernetes
+func (r ReportCollector) IsKubernetes() bool {
+	return len(r.Kubectl) != 0
+}
+
+// MatchesProfile returns true if this collector runs on the nodes
+// with the specified profile
+func (r ReportCollector) MatchesProfile(profile string) bool {
+	return len(r.NodeProfiles) == 0 || utils.StringInSlice(r.NodeProfiles, profile)
+}
+
+// GetTimeout returns the collector timeout as a duration
+func (r ReportCollector) GetTimeout() (time.Duration, error) {
+	if r.Timeout == "" {
+		return defaults.ReportCollectorTimeout, nil
+	}
+	timeout, err := time.ParseDuration(r.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, trace.BadParameter("report collector %q: invalid timeout %q", r.Name, r.Timeout)
+	}
+	return timeout, nil
+}
+
+// GetMaxSize returns the maximum size of the collected data in bytes
+func (r ReportCollector) GetMaxSize() int64 {
+	if r.MaxSize == 0 {
+		return defaults.ReportCollectorMaxSize
+	}
+	return int64(r.MaxSize.Bytes())
+}
+
+// checkReport validates the report collectors
+func checkReport(report Report, profiles NodeProfiles) (errors []error) {
+	names := make(map[string]struct{})
+	for _, collector := range report.Collectors {
+		if err := collector.Check(); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if _, ok := names[collector.Name]; ok {
+			errors = append(errors, trace.BadParameter(
+				"report collector %q is already defined", collector.Name))
+		}
+		names[collector.Name] = struct{}{}
+		for _, profile := range collector.NodeProfiles {
+			if _, err := profiles.ByName(profile); err != nil {
+				errors = append(errors, trace.BadParameter(
+					"report collector %q refers to undefined node profile %q", collector.Name, profile))
+			}
+		}
+	}
+	return errors
+}
+
 // SystemOptions defines various global settings
 type SystemOptions struct {
 	// ExternalService specifies additional configuration for the runtime package
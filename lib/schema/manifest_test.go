@@ -17,7 +17,10 @@ limitations under the License.
 package schema
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/constants"
@@ -466,6 +469,29 @@ nodeProfiles:
 	c.Assert(err, NotNil)
 }
 
+func (s *ManifestSuite) TestInvalidNetworkRequirement(c *C) {
+	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: node
+            count: 1
+nodeProfiles:
+  - name: node
+    requirements:
+      network:
+        maxLatency: fast
+        maxPacketLoss: 120`)
+	_, err := ParseManifestYAML(bytes)
+	c.Assert(err, NotNil)
+}
+
 func (s *ManifestSuite) TestInvalidFileMode(c *C) {
 	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
 kind: Bundle
@@ -552,6 +578,144 @@ systemOptions:
 	c.Assert(err, NotNil)
 }
 
+func (s *ManifestSuite) TestParsesUpgradeRollbackPolicy(c *C) {
+	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+upgrade:
+  rollback:
+    onFailure: true
+    healthCheckGracePeriod: 5m`)
+	manifest, err := ParseManifestYAML(bytes)
+	c.Assert(err, IsNil)
+	policy := manifest.RollbackPolicy()
+	c.Assert(policy, DeepEquals, &UpgradeRollback{
+		OnFailure:              true,
+		HealthCheckGracePeriod: "5m",
+	})
+	gracePeriod, err := policy.GracePeriod()
+	c.Assert(err, IsNil)
+	c.Assert(gracePeriod, Equals, 5*time.Minute)
+}
+
+func (s *ManifestSuite) TestParsesConstrainedDependencies(c *C) {
+	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+dependencies:
+  apps:
+    - example.com/api:1.0.0
+    - name: example.com/db
+      version: ^1.2.0`)
+	manifest, err := ParseManifestYAML(bytes)
+	c.Assert(err, IsNil)
+	c.Assert(manifest.Dependencies.HasConstraints(), Equals, true)
+	c.Assert(manifest.Dependencies.Apps, DeepEquals, []Dependency{
+		{Locator: loc.MustParseLocator("example.com/api:1.0.0")},
+		{Locator: loc.Locator{Repository: "example.com", Name: "db"}, Constraint: "^1.2.0"},
+	})
+
+	data, err := json.Marshal(manifest.Dependencies)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals,
+		`{"apps":["example.com/api:1.0.0",{"name":"example.com/db","version":"^1.2.0"}]}`)
+}
+
+func (s *ManifestSuite) TestInvalidUpgradeGracePeriod(c *C) {
+	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+upgrade:
+  rollback:
+    healthCheckGracePeriod: soon`)
+	_, err := ParseManifestYAML(bytes)
+	c.Assert(err, NotNil)
+}
+
+func (s *ManifestSuite) TestParsesReportCollectors(c *C) {
+	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+nodeProfiles:
+  - name: db
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: db
+            count: 1
+report:
+  collectors:
+    - name: db-status
+      nodeProfiles: [db]
+      command: ["/usr/bin/db-status"]
+      timeout: 10s
+      maxSize: 1MB
+    - name: app-pods
+      kubectl: ["get", "pods", "--namespace", "app", "-o", "yaml"]
+    - name: metrics
+      http: http://localhost:9100/metrics`)
+	manifest, err := ParseManifestYAML(bytes)
+	c.Assert(err, IsNil)
+	collectors := manifest.ReportCollectors()
+	c.Assert(collectors, HasLen, 3)
+	timeout, err := collectors[0].GetTimeout()
+	c.Assert(err, IsNil)
+	c.Assert(timeout, Equals, 10*time.Second)
+	c.Assert(collectors[0].GetMaxSize(), Equals, int64(utils.MustParseCapacity("1MB").Bytes()))
+	c.Assert(collectors[0].MatchesProfile("db"), Equals, true)
+	c.Assert(collectors[0].MatchesProfile("node"), Equals, false)
+	c.Assert(collectors[1].IsKubernetes(), Equals, true)
+	c.Assert(collectors[2].GetMaxSize(), Equals, int64(defaults.ReportCollectorMaxSize))
+}
+
+func (s *ManifestSuite) TestInvalidReportCollectors(c *C) {
+	for _, collectors := range []string{
+		// no source
+		`- name: empty`,
+		// multiple sources
+		`- name: both
+  command: ["ls"]
+  http: http://localhost`,
+		// undefined profile
+		`- name: status
+  nodeProfiles: [missing]
+  command: ["ls"]`,
+		// duplicate name
+		`- name: status
+  command: ["ls"]
+- name: status
+  command: ["ps"]`,
+		// invalid timeout
+		`- name: status
+  command: ["ls"]
+  timeout: forever`,
+	} {
+		_, err := ParseManifestYAML([]byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+report:
+  collectors:
+` + indent(collectors, "    ")))
+		c.Assert(err, NotNil, Commentf(collectors))
+	}
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.Replace(s, "\n", "\n"+prefix, -1)
+}
+
 func (s *ManifestSuite) TestCanOverrideBooleans(c *C) {
 	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
 kind: Bundle
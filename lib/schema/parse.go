@@ -155,6 +155,9 @@ func CheckAndSetDefaults(manifest *Manifest) error {
 	}
 
 	for i, nodeProfile := range manifest.NodeProfiles {
+		if err := nodeProfile.Requirements.Network.Check(); err != nil {
+			errors = append(errors, trace.Wrap(err, "node profile %q", nodeProfile.Name))
+		}
 		for j := range nodeProfile.Requirements.Volumes {
 			if err := manifest.NodeProfiles[i].Requirements.Volumes[j].CheckAndSetDefaults(); err != nil {
 				errors = append(errors, err)
@@ -162,6 +165,16 @@ func CheckAndSetDefaults(manifest *Manifest) error {
 		}
 	}
 
+	if manifest.Upgrade != nil && manifest.Upgrade.Rollback != nil {
+		if _, err := manifest.Upgrade.Rollback.GracePeriod(); err != nil {
+			errors = append(errors, trace.Wrap(err))
+		}
+	}
+
+	if manifest.Report != nil {
+		errors = append(errors, checkReport(*manifest.Report, manifest.NodeProfiles)...)
+	}
+
 	if manifest.SystemOptions != nil {
 		if manifest.SystemOptions.Runtime == nil {
 			errors = append(errors, trace.NotFound("no runtime application defined"))
@@ -0,0 +1,184 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// position is a 1-based line/column location in a YAML document
+type position struct {
+	line   int
+	column int
+}
+
+// positions maps JSON pointers of the manifest document to the
+// locations of the corresponding nodes in the source YAML
+type positions map[string]position
+
+// lookup returns the location of the node specified with the JSON pointer
+// path, or of its closest ancestor that has a known location
+func (p positions) lookup(path string) position {
+	path = strings.TrimPrefix(path, "#")
+	for {
+		if pos, ok := p[path]; ok {
+			return pos
+		}
+		idx := strings.LastIndex(path, "/")
+		if idx <= 0 {
+			return position{line: 1, column: 1}
+		}
+		path = path[:idx]
+	}
+}
+
+// indexYAML builds the map of JSON pointers to node locations for the
+// block-style YAML document in data.
+//
+// The vendored YAML parser does not expose node positions so this is a
+// lightweight scanner that tracks mapping keys and sequence items by
+// indentation. Flow-style collections are indexed as a single node.
+func indexYAML(data []byte) positions {
+	result := positions{}
+	var stack []frame
+	// blockIndent is the indentation of the key owning the literal or
+	// folded block scalar being skipped, or -1
+	blockIndent := -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+		if blockIndent >= 0 {
+			if indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		if content == "---" || content == "..." {
+			continue
+		}
+		for strings.HasPrefix(content, "-") && (len(content) == 1 || content[1] == ' ') {
+			stack = popFrames(stack, indent, true)
+			parent := -1
+			if len(stack) > 0 {
+				parent = len(stack) - 1
+			}
+			index := 0
+			if parent >= 0 {
+				index = stack[parent].items
+				stack[parent].items++
+			}
+			path := pathOf(stack) + "/" + strconv.Itoa(index)
+			result[path] = position{line: lineNo, column: indent + 1}
+			stack = append(stack, frame{indent: indent, key: strconv.Itoa(index), item: true})
+			rest := strings.TrimLeft(content[1:], " ")
+			indent += len(content) - len(rest)
+			content = rest
+		}
+		key, value, ok := splitKey(content)
+		if !ok {
+			continue
+		}
+		stack = popFrames(stack, indent, false)
+		result[pathOf(stack)+"/"+escapePointer(key)] = position{line: lineNo, column: indent + 1}
+		stack = append(stack, frame{indent: indent, key: escapePointer(key)})
+		if isBlockScalar(value) {
+			blockIndent = indent
+		}
+	}
+	return result
+}
+
+// frame is a single level of the YAML node path being scanned
+type frame struct {
+	// indent is the indentation of the key or the sequence item marker
+	indent int
+	// key is the escaped mapping key or the sequence item index
+	key string
+	// item is whether the frame is a sequence item
+	item bool
+	// items counts sequence items nested under a mapping key
+	items int
+}
+
+// popFrames removes the frames that do not enclose a node at the given
+// indentation. Sequence items may share the indentation of the parent key
+func popFrames(stack []frame, indent int, item bool) []frame {
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.indent < indent || (item && top.indent == indent && !top.item) {
+			break
+		}
+		stack = stack[:len(stack)-1]
+	}
+	return stack
+}
+
+func pathOf(stack []frame) string {
+	var path []string
+	for _, f := range stack {
+		path = append(path, f.key)
+	}
+	if len(path) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(path, "/")
+}
+
+// splitKey splits the YAML mapping entry into the key and the value
+func splitKey(content string) (key, value string, ok bool) {
+	if strings.HasPrefix(content, `"`) || strings.HasPrefix(content, "'") {
+		quote := content[:1]
+		end := strings.Index(content[1:], quote)
+		if end < 0 {
+			return "", "", false
+		}
+		key = content[1 : end+1]
+		rest := content[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+	for i := 0; i < len(content); i++ {
+		if content[i] != ':' {
+			continue
+		}
+		if i == len(content)-1 || content[i+1] == ' ' {
+			return strings.TrimSpace(content[:i]), strings.TrimSpace(content[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// isBlockScalar returns true if the value starts a literal or folded block scalar
+func isBlockScalar(value string) bool {
+	return strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">")
+}
+
+// escapePointer escapes the key for use as a JSON pointer reference token
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
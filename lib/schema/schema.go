@@ -100,7 +100,20 @@ const manifestSchema = `
             },
             "apps": {
               "type": "array",
-              "items": {"type": "string"}
+              "items": {
+                "oneOf": [
+                  {"type": "string"},
+                  {
+                    "type": "object",
+                    "additionalProperties": false,
+                    "required": ["name", "version"],
+                    "properties": {
+                      "name": {"type": "string"},
+                      "version": {"type": "string"}
+                    }
+                  }
+                ]
+              }
             }
           }
         },
@@ -205,6 +218,10 @@ const manifestSchema = `
                     "additionalProperties": false,
                     "properties": {
                       "minTransferRate": {"type": "string"},
+                      "maxLatency": {"type": "string"},
+                      "maxJitter": {"type": "string"},
+                      "maxPacketLoss": {"type": "number"},
+                      "minMTU": {"type": "number"},
                       "ports": {
                         "type": "array",
                         "items": {
@@ -327,6 +344,57 @@ const manifestSchema = `
             "type": {"type": "string", "default": "certificate"}
           }
         },
+        "imageDigests": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "additionalProperties": false,
+            "required": ["image", "id"],
+            "properties": {
+              "image": {"type": "string"},
+              "digest": {"type": "string"},
+              "id": {"type": "string"}
+            }
+          }
+        },
+        "upgrade": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "rollback": {
+              "type": "object",
+              "additionalProperties": false,
+              "properties": {
+                "onFailure": {"type": "boolean"},
+                "healthCheckGracePeriod": {"type": "string"}
+              }
+            }
+          }
+        },
+        "report": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "collectors": {
+              "type": "array",
+              "items": {
+                "type": "object",
+                "additionalProperties": false,
+                "required": ["name"],
+                "properties": {
+                  "name": {"type": "string"},
+                  "nodeProfiles": {"type": "array", "items": {"type": "string"}},
+                  "command": {"type": "array", "items": {"type": "string"}},
+                  "kubectl": {"type": "array", "items": {"type": "string"}},
+                  "files": {"type": "array", "items": {"type": "string"}},
+                  "http": {"type": "string"},
+                  "timeout": {"type": "string"},
+                  "maxSize": {"type": "string"}
+                }
+              }
+            }
+          }
+        },
         "hooks": {
           "type": "object",
           "additionalProperties": false,
@@ -0,0 +1,183 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	pb "github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+)
+
+// Snapshot captures the current health of the specified cluster:
+// the status of its nodes and their health probes as reported by
+// the planet agents, and the operations active in the cluster
+func Snapshot(ctx context.Context, operator ops.Operator, cluster ops.Site) (*storage.StatusSnapshot, error) {
+	activeOperations, err := ops.GetActiveOperations(cluster.Key(), operator)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	status, err := planetAgentStatus(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to query cluster status from agent")
+	}
+	return newSnapshot(cluster, *status, activeOperations, time.Now().UTC()), nil
+}
+
+func newSnapshot(cluster ops.Site, systemStatus pb.SystemStatus, operations []ops.SiteOperation, created time.Time) *storage.StatusSnapshot {
+	snapshot := &storage.StatusSnapshot{
+		SiteDomain:   cluster.Domain,
+		Created:      created,
+		ClusterState: cluster.State,
+		SystemStatus: SystemStatus(systemStatus.Status).String(),
+	}
+	nodes := nodes(systemStatus)
+	for _, server := range cluster.ClusterState.Servers {
+		node, found := nodes[server.AdvertiseIP]
+		if !found {
+			snapshot.Nodes = append(snapshot.Nodes, storage.NodeStatusSnapshot{
+				Hostname:    server.Hostname,
+				AdvertiseIP: server.AdvertiseIP,
+				Status:      NodeOffline,
+			})
+			continue
+		}
+		snapshot.Nodes = append(snapshot.Nodes, storage.NodeStatusSnapshot{
+			Hostname:    server.Hostname,
+			AdvertiseIP: server.AdvertiseIP,
+			Status:      fromNodeStatus(*node).Status,
+			Probes:      probeSnapshots(node.Probes),
+		})
+	}
+	for _, op := range operations {
+		snapshot.Operations = append(snapshot.Operations, storage.OperationStatusSnapshot{
+			ID:    op.ID,
+			Type:  op.Type,
+			State: op.State,
+		})
+	}
+	return snapshot
+}
+
+// probeSnapshots returns the status of the specified probes keyed by checker.
+// Checkers that executed several probes are reported as failed
+// if any of their probes has failed
+func probeSnapshots(probes []*pb.Probe) (out []storage.ProbeStatusSnapshot) {
+	indexes := make(map[string]int)
+	for _, probe := range probes {
+		snapshot := storage.ProbeStatusSnapshot{
+			Name:   probe.Checker,
+			Status: strings.ToLower(probe.Status.String()),
+		}
+		if probe.Status != pb.Probe_Running {
+			snapshot.Detail = probeErrorDetail(*probe)
+		}
+		i, found := indexes[probe.Checker]
+		if !found {
+			indexes[probe.Checker] = len(out)
+			out = append(out, snapshot)
+			continue
+		}
+		if out[i].Status == probeRunning {
+			out[i] = snapshot
+		}
+	}
+	return out
+}
+
+// Transitions returns the state transitions of the cluster, its nodes,
+// health probes and operations recorded in the specified snapshots.
+// The snapshots are expected to be ordered by time.
+// The first snapshot establishes the initial state and does not produce transitions
+func Transitions(snapshots []storage.StatusSnapshot) (out []ops.StatusTransition) {
+	var clusterState string
+	nodeStates := make(map[string]string)
+	probeStates := make(map[probeKey]string)
+	operationStates := make(map[string]string)
+	for i, snapshot := range snapshots {
+		initial := i == 0
+		if !initial && snapshot.ClusterState != clusterState {
+			out = append(out, ops.StatusTransition{
+				Time: snapshot.Created,
+				From: clusterState,
+				To:   snapshot.ClusterState,
+			})
+		}
+		clusterState = snapshot.ClusterState
+		for _, node := range snapshot.Nodes {
+			prev, found := nodeStates[node.AdvertiseIP]
+			if !initial && (!found || prev != node.Status) {
+				out = append(out, ops.StatusTransition{
+					Time:     snapshot.Created,
+					Node:     node.AdvertiseIP,
+					Hostname: node.Hostname,
+					From:     prev,
+					To:       node.Status,
+				})
+			}
+			nodeStates[node.AdvertiseIP] = node.Status
+			for _, probe := range node.Probes {
+				key := probeKey{node: node.AdvertiseIP, probe: probe.Name}
+				prev, found := probeStates[key]
+				if !found {
+					// Probes are only reported for nodes the agent can reach,
+					// so assume unseen probes have been passing
+					prev = probeRunning
+				}
+				if !initial && prev != probe.Status {
+					out = append(out, ops.StatusTransition{
+						Time:     snapshot.Created,
+						Node:     node.AdvertiseIP,
+						Hostname: node.Hostname,
+						Probe:    probe.Name,
+						From:     prev,
+						To:       probe.Status,
+						Detail:   probe.Detail,
+					})
+				}
+				probeStates[key] = probe.Status
+			}
+		}
+		for _, op := range snapshot.Operations {
+			prev, found := operationStates[op.ID]
+			if !initial && (!found || prev != op.State) {
+				out = append(out, ops.StatusTransition{
+					Time:        snapshot.Created,
+					OperationID: op.ID,
+					From:        prev,
+					To:          op.State,
+					Detail:      op.Type,
+				})
+			}
+			operationStates[op.ID] = op.State
+		}
+	}
+	return out
+}
+
+type probeKey struct {
+	node  string
+	probe string
+}
+
+// probeRunning is the status of a passing probe
+const probeRunning = "running"
@@ -0,0 +1,114 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestStatus(t *testing.T) { TestingT(t) }
+
+type HistorySuite struct{}
+
+var _ = Suite(&HistorySuite{})
+
+func (s *HistorySuite) TestComputesTransitions(c *C) {
+	t0 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1, t2, t3 := t0.Add(time.Minute), t0.Add(2*time.Minute), t0.Add(3*time.Minute)
+	snapshots := []storage.StatusSnapshot{
+		newTestSnapshot(t0, ops.SiteStateActive,
+			node("192.168.1.1", NodeHealthy, probe("docker", "running")),
+			node("192.168.1.2", NodeHealthy)),
+		newTestSnapshot(t1, ops.SiteStateDegraded,
+			node("192.168.1.1", NodeDegraded, probe("docker", "failed")),
+			node("192.168.1.2", NodeOffline)),
+		newTestSnapshot(t2, ops.SiteStateDegraded,
+			node("192.168.1.1", NodeDegraded, probe("docker", "failed")),
+			node("192.168.1.2", NodeOffline)),
+		newTestSnapshot(t3, ops.SiteStateActive,
+			node("192.168.1.1", NodeHealthy, probe("docker", "running")),
+			// probes that have not been seen before are assumed to have been passing
+			node("192.168.1.2", NodeHealthy, probe("etcd", "running"))),
+	}
+	snapshots[2].Operations = []storage.OperationStatusSnapshot{
+		{ID: "op1", Type: ops.OperationUpdate, State: ops.OperationStateUpdateInProgress},
+	}
+
+	compare.DeepCompare(c, Transitions(snapshots), []ops.StatusTransition{
+		{Time: t1, From: ops.SiteStateActive, To: ops.SiteStateDegraded},
+		{Time: t1, Node: "192.168.1.1", Hostname: "node-192.168.1.1", From: NodeHealthy, To: NodeDegraded},
+		{Time: t1, Node: "192.168.1.1", Hostname: "node-192.168.1.1", Probe: "docker", From: "running", To: "failed", Detail: "docker failed"},
+		{Time: t1, Node: "192.168.1.2", Hostname: "node-192.168.1.2", From: NodeHealthy, To: NodeOffline},
+		{Time: t2, OperationID: "op1", To: ops.OperationStateUpdateInProgress, Detail: ops.OperationUpdate},
+		{Time: t3, From: ops.SiteStateDegraded, To: ops.SiteStateActive},
+		{Time: t3, Node: "192.168.1.1", Hostname: "node-192.168.1.1", From: NodeDegraded, To: NodeHealthy},
+		{Time: t3, Node: "192.168.1.1", Hostname: "node-192.168.1.1", Probe: "docker", From: "failed", To: "running"},
+		{Time: t3, Node: "192.168.1.2", Hostname: "node-192.168.1.2", From: NodeOffline, To: NodeHealthy},
+	})
+}
+
+func (s *HistorySuite) TestFiltersTransitions(c *C) {
+	t0 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	transition := ops.StatusTransition{
+		Time:     t0,
+		Node:     "192.168.1.1",
+		Hostname: "node-1",
+		Probe:    "docker",
+	}
+	c.Assert(ops.ClusterStatusHistoryRequest{Node: "node-1"}.Matches(transition), Equals, true)
+	c.Assert(ops.ClusterStatusHistoryRequest{Node: "192.168.1.1", Probe: "docker"}.Matches(transition), Equals, true)
+	c.Assert(ops.ClusterStatusHistoryRequest{Node: "node-2"}.Matches(transition), Equals, false)
+	c.Assert(ops.ClusterStatusHistoryRequest{Probe: "etcd"}.Matches(transition), Equals, false)
+	c.Assert(ops.ClusterStatusHistoryRequest{From: t0.Add(time.Second)}.Matches(transition), Equals, false)
+	c.Assert(ops.ClusterStatusHistoryRequest{To: t0.Add(-time.Second)}.Matches(transition), Equals, false)
+}
+
+func newTestSnapshot(created time.Time, state string, nodes ...storage.NodeStatusSnapshot) storage.StatusSnapshot {
+	return storage.StatusSnapshot{
+		SiteDomain:   "example.com",
+		Created:      created,
+		ClusterState: state,
+		Nodes:        nodes,
+	}
+}
+
+func node(addr, status string, probes ...storage.ProbeStatusSnapshot) storage.NodeStatusSnapshot {
+	return storage.NodeStatusSnapshot{
+		Hostname:    "node-" + addr,
+		AdvertiseIP: addr,
+		Status:      status,
+		Probes:      probes,
+	}
+}
+
+func probe(name, status string) storage.ProbeStatusSnapshot {
+	probe := storage.ProbeStatusSnapshot{
+		Name:   name,
+		Status: status,
+	}
+	if status != "running" {
+		probe.Detail = name + " failed"
+	}
+	return probe
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/hostconfig"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/roundtrip"
@@ -124,10 +125,32 @@ func FromCluster(ctx context.Context, operator ops.Operator, cluster ops.Site, o
 		return status, trace.Wrap(err, "failed to collect system status from agents")
 	}
 
+	status.HostConfigDrift, err = hostConfigDrift(operator, cluster)
+	if err != nil {
+		logrus.Warnf("Failed to check host configuration: %v.", trace.DebugReport(err))
+	}
+
 	status.State = cluster.State
 	return status, nil
 }
 
+// hostConfigDrift collects the differences between the cluster host configuration
+// and the actual state of the local node. The drift of the other nodes is
+// reported as unknown
+func hostConfigDrift(operator ops.Operator, cluster ops.Site) ([]hostconfig.NodeDrift, error) {
+	config, err := operator.GetHostConfig(cluster.Key())
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	return hostconfig.CollectDrift(hostconfig.DriftConfig{
+		Config:  config,
+		Servers: cluster.ClusterState.Servers,
+	}), nil
+}
+
 // FromPlanetAgent collects cluster status from the planet agent
 func FromPlanetAgent(ctx context.Context, servers []storage.Server) (*Agent, error) {
 	status, err := planetAgentStatus(ctx)
@@ -175,6 +198,9 @@ type Cluster struct {
 	ActiveOperations []*ClusterOperation `json:"active_operations,omitempty"`
 	// Endpoints contains cluster and application endpoints.
 	Endpoints Endpoints `json:"endpoints"`
+	// HostConfigDrift lists the nodes that differ from the cluster host
+	// configuration or could not be checked
+	HostConfigDrift []hostconfig.NodeDrift `json:"host_config_drift,omitempty"`
 	// Extension is a cluster status extension
 	Extension `json:",inline,omitempty"`
 }
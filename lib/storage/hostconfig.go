@@ -0,0 +1,362 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	teledefaults "github.com/gravitational/teleport/lib/defaults"
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// HostConfig describes the kernel parameters, kernel modules and
+// resource limits applied to cluster nodes
+type HostConfig interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults verifies that the object is valid
+	CheckAndSetDefaults() error
+	// GetProfiles returns the host configuration entries
+	GetProfiles() []HostConfigProfile
+	// ForProfile returns the host configuration for the nodes
+	// of the specified profile
+	ForProfile(profile string) HostConfigProfile
+}
+
+// NewHostConfig creates a new host configuration resource from the provided spec
+func NewHostConfig(spec HostConfigSpecV2) HostConfig {
+	return &HostConfigV2{
+		Kind:    KindHostConfig,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      KindHostConfig,
+			Namespace: teledefaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// HostConfigV2 defines the host configuration resource
+type HostConfigV2 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the host configuration
+	Spec HostConfigSpecV2 `json:"spec"`
+}
+
+// HostConfigSpecV2 defines the host configuration
+type HostConfigSpecV2 struct {
+	// Profiles lists host configuration per node profile
+	Profiles []HostConfigProfile `json:"profiles"`
+}
+
+// HostConfigProfile defines the host configuration for the nodes of a profile
+type HostConfigProfile struct {
+	// Profile is the name of the node profile the configuration applies to.
+	// Applies to all nodes if empty
+	Profile string `json:"profile,omitempty"`
+	// Sysctls maps kernel parameters to their values
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+	// KernelModules lists kernel modules to load
+	KernelModules []string `json:"kernelModules,omitempty"`
+	// Ulimits lists resource limits
+	Ulimits []Ulimit `json:"ulimits,omitempty"`
+}
+
+// Ulimit defines a resource limit of the planet services.
+// The limits are configured with the systemd Limit* directives, see systemd.exec(5)
+type Ulimit struct {
+	// Item is the limited resource, e.g. nofile
+	Item string `json:"item"`
+	// Soft is the soft limit. Defaults to the hard limit
+	Soft string `json:"soft,omitempty"`
+	// Hard is the hard limit. Defaults to the soft limit
+	Hard string `json:"hard,omitempty"`
+}
+
+// String returns the limit as a systemd directive, e.g. LimitNOFILE=1024:4096
+func (r Ulimit) String() string {
+	soft, hard := r.Soft, r.Hard
+	if soft == "" {
+		soft = hard
+	}
+	if hard == "" {
+		hard = soft
+	}
+	return fmt.Sprintf("%v=%v:%v", ulimitDirectives[r.Item],
+		systemdLimitValue(soft), systemdLimitValue(hard))
+}
+
+// Check makes sure the limit is valid
+func (r Ulimit) Check() error {
+	if _, ok := ulimitDirectives[r.Item]; !ok {
+		return trace.BadParameter("unsupported ulimit item %q", r.Item)
+	}
+	if r.Soft == "" && r.Hard == "" {
+		return trace.BadParameter("ulimit %v should specify soft or hard limit", r.Item)
+	}
+	for _, value := range []string{r.Soft, r.Hard} {
+		if value == "" || value == "unlimited" || value == "infinity" {
+			continue
+		}
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return trace.BadParameter("invalid value %q for ulimit %v", value, r.Item)
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true if this configuration has no settings
+func (r HostConfigProfile) IsEmpty() bool {
+	return len(r.Sysctls) == 0 && len(r.KernelModules) == 0 && len(r.Ulimits) == 0
+}
+
+// SortedSysctls returns the kernel parameter names in sorted order
+func (r HostConfigProfile) SortedSysctls() []string {
+	names := make([]string, 0, len(r.Sysctls))
+	for name := range r.Sysctls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check makes sure the configuration is valid
+func (r HostConfigProfile) Check() error {
+	for name, value := range r.Sysctls {
+		if !reSysctlName.MatchString(name) {
+			return trace.BadParameter("invalid kernel parameter name %q", name)
+		}
+		if strings.TrimSpace(value) == "" || strings.ContainsAny(value, "\n") {
+			return trace.BadParameter("invalid value %q for kernel parameter %v", value, name)
+		}
+	}
+	for _, module := range r.KernelModules {
+		if !reKernelModule.MatchString(module) {
+			return trace.BadParameter("invalid kernel module name %q", module)
+		}
+	}
+	for _, ulimit := range r.Ulimits {
+		if err := ulimit.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// GetProfiles returns the host configuration entries
+func (r *HostConfigV2) GetProfiles() []HostConfigProfile {
+	return r.Spec.Profiles
+}
+
+// ForProfile returns the host configuration for the nodes of the specified
+// profile. The profile-specific settings take precedence over the settings
+// for all nodes
+func (r *HostConfigV2) ForProfile(profile string) HostConfigProfile {
+	result := HostConfigProfile{Profile: profile}
+	for _, name := range []string{"", profile} {
+		for _, entry := range r.Spec.Profiles {
+			if entry.Profile != name {
+				continue
+			}
+			for key, value := range entry.Sysctls {
+				if result.Sysctls == nil {
+					result.Sysctls = make(map[string]string)
+				}
+				result.Sysctls[key] = value
+			}
+			for _, module := range entry.KernelModules {
+				if !utils.StringInSlice(result.KernelModules, module) {
+					result.KernelModules = append(result.KernelModules, module)
+				}
+			}
+			result.Ulimits = mergeUlimits(result.Ulimits, entry.Ulimits)
+		}
+		if profile == "" {
+			break
+		}
+	}
+	return result
+}
+
+// CheckAndSetDefaults checks validity of all parameters and sets defaults
+func (r *HostConfigV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		r.Metadata.Name = KindHostConfig
+	}
+	if len(r.Spec.Profiles) == 0 {
+		return trace.BadParameter("host configuration should specify at least one profile")
+	}
+	profiles := make(map[string]bool, len(r.Spec.Profiles))
+	for _, profile := range r.Spec.Profiles {
+		if profiles[profile.Profile] {
+			return trace.BadParameter("duplicate host configuration for profile %q", profile.Profile)
+		}
+		profiles[profile.Profile] = true
+		if err := profile.Check(); err != nil {
+			return trace.Wrap(err, "invalid host configuration for profile %q", profile.Profile)
+		}
+	}
+	return nil
+}
+
+// UnmarshalHostConfig unmarshals host configuration from JSON or YAML
+func UnmarshalHostConfig(data []byte) (HostConfig, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty configuration")
+	}
+
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var hdr teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &hdr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	switch hdr.Version {
+	case teleservices.V2:
+		var config HostConfigV2
+		err := teleutils.UnmarshalWithSchema(GetHostConfigSchema(), &config, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter(err.Error())
+		}
+		config.Metadata.CheckAndSetDefaults()
+		if err := config.CheckAndSetDefaults(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &config, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindHostConfig, hdr.Version)
+}
+
+// MarshalHostConfig marshals host configuration into JSON
+func MarshalHostConfig(config HostConfig, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(config)
+}
+
+// HostConfigSpecV2Schema is JSON schema for host configuration
+const HostConfigSpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["profiles"],
+  "properties": {
+    "profiles": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "profile": {"type": "string"},
+          "sysctls": {
+            "type": "object",
+            "patternProperties": {"^.*$": {"type": "string"}}
+          },
+          "kernelModules": {"type": "array", "items": {"type": "string"}},
+          "ulimits": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "additionalProperties": false,
+              "required": ["item"],
+              "properties": {
+                "item": {"type": "string"},
+                "soft": {"type": "string"},
+                "hard": {"type": "string"}
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// GetHostConfigSchema returns host configuration schema for version V2
+func GetHostConfigSchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		HostConfigSpecV2Schema, "")
+}
+
+// mergeUlimits returns the limits from base overridden with the limits
+// from override for the same item
+func mergeUlimits(base, override []Ulimit) []Ulimit {
+	result := append([]Ulimit(nil), base...)
+	for _, ulimit := range override {
+		var replaced bool
+		for i := range result {
+			if result[i].Item == ulimit.Item {
+				result[i] = ulimit
+				replaced = true
+			}
+		}
+		if !replaced {
+			result = append(result, ulimit)
+		}
+	}
+	return result
+}
+
+var (
+	reSysctlName   = regexp.MustCompile(`^[a-zA-Z0-9_\-]+(\.[a-zA-Z0-9_\-/]+)+$`)
+	reKernelModule = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+)
+
+// systemdLimitValue converts the limit value to the systemd format
+func systemdLimitValue(value string) string {
+	if value == "unlimited" {
+		return "infinity"
+	}
+	return value
+}
+
+// ulimitDirectives maps the resources that can be limited
+// to the systemd directives that limit them
+var ulimitDirectives = map[string]string{
+	"core":       "LimitCORE",
+	"data":       "LimitDATA",
+	"fsize":      "LimitFSIZE",
+	"memlock":    "LimitMEMLOCK",
+	"nofile":     "LimitNOFILE",
+	"rss":        "LimitRSS",
+	"stack":      "LimitSTACK",
+	"cpu":        "LimitCPU",
+	"nproc":      "LimitNPROC",
+	"as":         "LimitAS",
+	"locks":      "LimitLOCKS",
+	"sigpending": "LimitSIGPENDING",
+	"msgqueue":   "LimitMSGQUEUE",
+	"nice":       "LimitNICE",
+	"rtprio":     "LimitRTPRIO",
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"github.com/gravitational/gravity/lib/compare"
+
+	check "gopkg.in/check.v1"
+)
+
+type HostConfigSuite struct{}
+
+var _ = check.Suite(&HostConfigSuite{})
+
+func (s *HostConfigSuite) TestResourceParsing(c *check.C) {
+	spec := `kind: hostconfig
+version: v2
+metadata:
+  name: hostconfig
+spec:
+  profiles:
+  - sysctls:
+      vm.max_map_count: "262144"
+      net.core.somaxconn: "1024"
+    kernelModules: [ip_vs]
+    ulimits:
+    - item: nofile
+      soft: "65536"
+      hard: "65536"
+  - profile: db
+    sysctls:
+      vm.max_map_count: "524288"
+    kernelModules: [ip_vs, nf_conntrack]
+    ulimits:
+    - item: nofile
+      hard: "1048576"
+    - item: memlock
+      soft: unlimited
+`
+	config, err := UnmarshalHostConfig([]byte(spec))
+	c.Assert(err, check.IsNil)
+	c.Assert(config, compare.DeepEquals, NewHostConfig(HostConfigSpecV2{
+		Profiles: []HostConfigProfile{
+			{
+				Sysctls: map[string]string{
+					"vm.max_map_count":   "262144",
+					"net.core.somaxconn": "1024",
+				},
+				KernelModules: []string{"ip_vs"},
+				Ulimits: []Ulimit{
+					{Item: "nofile", Soft: "65536", Hard: "65536"},
+				},
+			},
+			{
+				Profile: "db",
+				Sysctls: map[string]string{
+					"vm.max_map_count": "524288",
+				},
+				KernelModules: []string{"ip_vs", "nf_conntrack"},
+				Ulimits: []Ulimit{
+					{Item: "nofile", Hard: "1048576"},
+					{Item: "memlock", Soft: "unlimited"},
+				},
+			},
+		},
+	}))
+
+	c.Assert(config.ForProfile("db"), compare.DeepEquals, HostConfigProfile{
+		Profile: "db",
+		Sysctls: map[string]string{
+			"vm.max_map_count":   "524288",
+			"net.core.somaxconn": "1024",
+		},
+		KernelModules: []string{"ip_vs", "nf_conntrack"},
+		Ulimits: []Ulimit{
+			{Item: "nofile", Hard: "1048576"},
+			{Item: "memlock", Soft: "unlimited"},
+		},
+	})
+	c.Assert(config.ForProfile("node"), compare.DeepEquals, HostConfigProfile{
+		Profile: "node",
+		Sysctls: map[string]string{
+			"vm.max_map_count":   "262144",
+			"net.core.somaxconn": "1024",
+		},
+		KernelModules: []string{"ip_vs"},
+		Ulimits: []Ulimit{
+			{Item: "nofile", Soft: "65536", Hard: "65536"},
+		},
+	})
+}
+
+func (s *HostConfigSuite) TestFormatsUlimitsAsSystemdDirectives(c *check.C) {
+	c.Assert(Ulimit{Item: "nofile", Soft: "65536", Hard: "1048576"}.String(),
+		check.Equals, "LimitNOFILE=65536:1048576")
+	c.Assert(Ulimit{Item: "nproc", Hard: "4096"}.String(),
+		check.Equals, "LimitNPROC=4096:4096")
+	c.Assert(Ulimit{Item: "memlock", Soft: "unlimited"}.String(),
+		check.Equals, "LimitMEMLOCK=infinity:infinity")
+}
+
+func (s *HostConfigSuite) TestValidatesResource(c *check.C) {
+	testCases := []struct {
+		spec    string
+		comment string
+	}{
+		{
+			spec: `kind: hostconfig
+version: v2
+spec:
+  profiles:
+  - sysctls:
+      "vm max_map_count": "1"`,
+			comment: "invalid kernel parameter name",
+		},
+		{
+			spec: `kind: hostconfig
+version: v2
+spec:
+  profiles:
+  - kernelModules: ["ip_vs; reboot"]`,
+			comment: "invalid kernel module name",
+		},
+		{
+			spec: `kind: hostconfig
+version: v2
+spec:
+  profiles:
+  - ulimits:
+    - item: files
+      soft: "1"`,
+			comment: "unsupported ulimit item",
+		},
+		{
+			spec: `kind: hostconfig
+version: v2
+spec:
+  profiles:
+  - ulimits:
+    - item: nofile
+      soft: many`,
+			comment: "invalid ulimit value",
+		},
+		{
+			spec: `kind: hostconfig
+version: v2
+spec:
+  profiles:
+  - profile: db
+  - profile: db`,
+			comment: "duplicate profile",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := UnmarshalHostConfig([]byte(tc.spec))
+		c.Assert(err, check.NotNil, check.Commentf(tc.comment))
+	}
+}
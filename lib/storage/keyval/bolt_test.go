@@ -240,3 +240,7 @@ func (s *BSuite) TestClusterLogin(c *C) {
 func (s *BSuite) TestIndexFile(c *C) {
 	s.suite.IndexFile(c)
 }
+
+func (s *BSuite) TestStatusHistoryCRUD(c *C) {
+	s.suite.StatusHistoryCRUD(c)
+}
@@ -264,3 +264,7 @@ func (s *ESuite) TestClusterLogin(c *C) {
 func (s *ESuite) TestIndexFile(c *C) {
 	s.suite.IndexFile(c)
 }
+
+func (s *ESuite) TestStatusHistoryCRUD(c *C) {
+	s.suite.StatusHistoryCRUD(c)
+}
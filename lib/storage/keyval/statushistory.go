@@ -0,0 +1,123 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+func (b *backend) CreateStatusSnapshot(s storage.StatusSnapshot, retention time.Duration) (*storage.StatusSnapshot, error) {
+	if err := s.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if _, err := b.GetSite(s.SiteDomain); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// Snapshot IDs are derived from the creation time so that
+	// the keys sort in chronological order
+	s.ID = statusSnapshotID(s.Created)
+	err := b.createVal(b.key(sitesP, s.SiteDomain, statusHistoryP, s.ID), s, retention)
+	if err != nil {
+		if trace.IsAlreadyExists(err) {
+			return nil, trace.Wrap(err, "status snapshot(%v) already exists", s.ID)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &s, nil
+}
+
+func (b *backend) GetStatusSnapshots(siteDomain string, from, to time.Time) ([]storage.StatusSnapshot, error) {
+	ids, err := b.getStatusSnapshotIDs(siteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// Narrow the sorted IDs down to the requested time range so that
+	// only the snapshots within the range are fetched
+	if !from.IsZero() {
+		ids = ids[sort.SearchStrings(ids, statusSnapshotID(from)):]
+	}
+	if !to.IsZero() {
+		last := statusSnapshotID(to)
+		ids = ids[:sort.Search(len(ids), func(i int) bool { return ids[i] > last })]
+	}
+	out := []storage.StatusSnapshot{}
+	for _, id := range ids {
+		s, err := b.getStatusSnapshot(siteDomain, id)
+		if err != nil {
+			// the snapshot might have expired in the meantime
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		out = append(out, *s)
+	}
+	return out, nil
+}
+
+func (b *backend) GetLastStatusSnapshot(siteDomain string, before time.Time) (*storage.StatusSnapshot, error) {
+	ids, err := b.getStatusSnapshotIDs(siteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// IDs sort in chronological order so walk them backwards starting
+	// with the latest snapshot preceding the specified time
+	for i := sort.SearchStrings(ids, statusSnapshotID(before)) - 1; i >= 0; i-- {
+		s, err := b.getStatusSnapshot(siteDomain, ids[i])
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		return s, nil
+	}
+	return nil, trace.NotFound("no status snapshots before %v", before)
+}
+
+func (b *backend) getStatusSnapshotIDs(siteDomain string) ([]string, error) {
+	if siteDomain == "" {
+		return nil, trace.BadParameter("missing site domain")
+	}
+	ids, err := b.getKeys(b.key(sitesP, siteDomain, statusHistoryP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (b *backend) getStatusSnapshot(siteDomain, id string) (*storage.StatusSnapshot, error) {
+	var s storage.StatusSnapshot
+	err := b.getVal(b.key(sitesP, siteDomain, statusHistoryP, id), &s)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	utils.UTC(&s.Created)
+	return &s, nil
+}
+
+func statusSnapshotID(created time.Time) string {
+	return fmt.Sprintf("%020d", created.UTC().UnixNano())
+}
@@ -103,6 +103,9 @@ type OperationPhaseData struct {
 	InstalledPackage *loc.Locator `json:"installed_package,omitempty" yaml:"installed_package,omitempty"`
 	// RuntimePackage references the update runtime package
 	RuntimePackage *loc.Locator `json:"runtime_package,omitempty" yaml:"runtime_package,omitempty"`
+	// ChangesetID optionally overrides the ID of the system package changeset
+	// created by the phase. If unspecified, the operation ID is used
+	ChangesetID string `json:"changeset_id,omitempty" yaml:"changeset_id,omitempty"`
 	// UpdatePlanet indicates whether the planet needs to be updated during bootstrap
 	UpdatePlanet bool `json:"update_planet" yaml:"update_planet"`
 	// ElectionChange describes changes to make to cluster elections
@@ -0,0 +1,234 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// Proxy describes the HTTP proxy configuration for outbound cluster traffic
+type Proxy interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults verifies that the object is valid
+	CheckAndSetDefaults() error
+	// GetHTTPProxy returns the proxy URL for HTTP requests
+	GetHTTPProxy() string
+	// GetHTTPSProxy returns the proxy URL for HTTPS requests
+	GetHTTPSProxy() string
+	// GetNoProxy returns the user-specified list of proxy exclusions
+	GetNoProxy() []string
+	// GetEnv returns the proxy environment variables. The specified
+	// exclusions are added to the user-specified ones
+	GetEnv(noProxy []string) map[string]string
+}
+
+// NewProxy creates a new proxy resource from the provided spec
+func NewProxy(spec ProxySpecV2) Proxy {
+	return &ProxyV2{
+		Kind:    KindProxy,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      KindProxy,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// ProxyV2 defines the proxy resource
+type ProxyV2 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the proxy configuration
+	Spec ProxySpecV2 `json:"spec"`
+}
+
+// ProxySpecV2 defines the proxy configuration
+type ProxySpecV2 struct {
+	// HTTPProxy is the proxy URL for HTTP requests
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the proxy URL for HTTPS requests
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy lists hosts, domains and CIDR ranges that should be
+	// accessed directly
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+// GetHTTPProxy returns the proxy URL for HTTP requests
+func (r *ProxyV2) GetHTTPProxy() string {
+	return r.Spec.HTTPProxy
+}
+
+// GetHTTPSProxy returns the proxy URL for HTTPS requests
+func (r *ProxyV2) GetHTTPSProxy() string {
+	return r.Spec.HTTPSProxy
+}
+
+// GetNoProxy returns the user-specified list of proxy exclusions
+func (r *ProxyV2) GetNoProxy() []string {
+	return r.Spec.NoProxy
+}
+
+// GetEnv returns the proxy environment variables. The specified
+// exclusions are added to the user-specified ones.
+// Both upper- and lowercase variants are returned as tools disagree
+// on which one to honor
+func (r *ProxyV2) GetEnv(noProxy []string) map[string]string {
+	env := make(map[string]string)
+	set := func(name, value string) {
+		if value == "" {
+			return
+		}
+		env[name] = value
+		env[strings.ToLower(name)] = value
+	}
+	set(constants.HTTPProxyEnvVar, r.Spec.HTTPProxy)
+	set(constants.HTTPSProxyEnvVar, r.Spec.HTTPSProxy)
+	var exclusions []string
+	for _, entry := range append(append([]string{}, r.Spec.NoProxy...), noProxy...) {
+		if !utils.StringInSlice(exclusions, entry) {
+			exclusions = append(exclusions, entry)
+		}
+	}
+	set(constants.NoProxyEnvVar, strings.Join(exclusions, ","))
+	return env
+}
+
+// CheckAndSetDefaults checks validity of all parameters and sets defaults
+func (r *ProxyV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		r.Metadata.Name = KindProxy
+	}
+	if r.Spec.HTTPProxy == "" && r.Spec.HTTPSProxy == "" {
+		return trace.BadParameter("either 'httpProxy' or 'httpsProxy' is required")
+	}
+	for _, proxy := range []string{r.Spec.HTTPProxy, r.Spec.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return trace.BadParameter("invalid proxy URL %q: %v", proxy, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return trace.BadParameter("proxy URL %q should use http or https scheme", proxy)
+		}
+		if u.Host == "" {
+			return trace.BadParameter("proxy URL %q is missing host", proxy)
+		}
+	}
+	for _, entry := range r.Spec.NoProxy {
+		if entry == "" || strings.ContainsAny(entry, ", \t") {
+			return trace.BadParameter("invalid 'noProxy' entry %q", entry)
+		}
+	}
+	return nil
+}
+
+// ClusterNoProxy returns the proxy exclusions that keep the cluster traffic
+// direct: the loopback, the node addresses, the pod and service subnets and
+// the cluster service domains
+func ClusterNoProxy(subnets Subnets, servers Servers) []string {
+	noProxy := []string{"localhost", "127.0.0.1"}
+	var addrs []string
+	for _, server := range servers {
+		addrs = append(addrs, server.AdvertiseIP)
+	}
+	sort.Strings(addrs)
+	noProxy = append(noProxy, addrs...)
+	for _, subnet := range []string{subnets.Overlay, subnets.Service} {
+		if subnet != "" {
+			noProxy = append(noProxy, subnet)
+		}
+	}
+	return append(noProxy,
+		"."+constants.LocalClusterCommonName,
+		".svc",
+		constants.APIServerDomainName,
+		constants.APIServerDomainNameGravity,
+		constants.RegistryDomainName,
+	)
+}
+
+// UnmarshalProxy unmarshals proxy resource from JSON or YAML
+func UnmarshalProxy(data []byte) (Proxy, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty input")
+	}
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header teleservices.ResourceHeader
+	if err := json.Unmarshal(jsonData, &header); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch header.Version {
+	case teleservices.V2:
+		var proxy ProxyV2
+		err := teleutils.UnmarshalWithSchema(GetProxySchema(), &proxy, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter(err.Error())
+		}
+		if err := proxy.CheckAndSetDefaults(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		proxy.Metadata.CheckAndSetDefaults()
+		return &proxy, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindProxy, header.Version)
+}
+
+// MarshalProxy marshals proxy resource into JSON
+func MarshalProxy(proxy Proxy, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(proxy)
+}
+
+// ProxySpecV2Schema is JSON schema for proxy resource
+const ProxySpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "httpProxy": {"type": "string"},
+    "httpsProxy": {"type": "string"},
+    "noProxy": {"type": "array", "items": {"type": "string"}}
+  }
+}`
+
+// GetProxySchema returns proxy schema for version V2
+func GetProxySchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		ProxySpecV2Schema, "")
+}
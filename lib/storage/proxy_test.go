@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"github.com/gravitational/gravity/lib/compare"
+
+	check "gopkg.in/check.v1"
+)
+
+type ProxySuite struct{}
+
+var _ = check.Suite(&ProxySuite{})
+
+func (s *ProxySuite) TestResourceParsing(c *check.C) {
+	spec := `kind: proxy
+version: v2
+spec:
+  httpProxy: http://proxy.example.com:3128
+  httpsProxy: http://proxy.example.com:3128
+  noProxy: [.example.com, 192.168.0.0/16]
+`
+	proxy, err := UnmarshalProxy([]byte(spec))
+	c.Assert(err, check.IsNil)
+	c.Assert(proxy, compare.DeepEquals, NewProxy(ProxySpecV2{
+		HTTPProxy:  "http://proxy.example.com:3128",
+		HTTPSProxy: "http://proxy.example.com:3128",
+		NoProxy:    []string{".example.com", "192.168.0.0/16"},
+	}))
+}
+
+func (s *ProxySuite) TestValidatesResource(c *check.C) {
+	specs := []string{
+		`kind: proxy
+version: v2
+spec:
+  noProxy: [.example.com]`,
+		`kind: proxy
+version: v2
+spec:
+  httpProxy: proxy.example.com:3128`,
+		`kind: proxy
+version: v2
+spec:
+  httpsProxy: socks5://proxy.example.com:1080`,
+		`kind: proxy
+version: v2
+spec:
+  httpProxy: http://proxy.example.com:3128
+  noProxy: ["a.com,b.com"]`,
+	}
+	for _, spec := range specs {
+		_, err := UnmarshalProxy([]byte(spec))
+		c.Assert(err, check.NotNil, check.Commentf(spec))
+	}
+}
+
+func (s *ProxySuite) TestGeneratesEnv(c *check.C) {
+	proxy := NewProxy(ProxySpecV2{
+		HTTPSProxy: "http://proxy.example.com:3128",
+		NoProxy:    []string{".example.com", "localhost"},
+	})
+	noProxy := ClusterNoProxy(
+		Subnets{Overlay: "10.244.0.0/16", Service: "10.100.0.0/16"},
+		Servers{{AdvertiseIP: "192.168.1.2"}, {AdvertiseIP: "192.168.1.1"}})
+	noProxyValue := ".example.com,localhost,127.0.0.1,192.168.1.1,192.168.1.2," +
+		"10.244.0.0/16,10.100.0.0/16,.cluster.local,.svc," +
+		"leader.telekube.local,leader.gravity.local,registry.local"
+	c.Assert(proxy.GetEnv(noProxy), check.DeepEquals, map[string]string{
+		"HTTPS_PROXY": "http://proxy.example.com:3128",
+		"https_proxy": "http://proxy.example.com:3128",
+		"NO_PROXY":    noProxyValue,
+		"no_proxy":    noProxyValue,
+	})
+}
@@ -56,6 +56,13 @@ const (
 	KindEndpoints = "endpoints"
 	// KindAuthGateway defines the auth gateway resource type
 	KindAuthGateway = "authgateway"
+	// KindHostConfig defines the cluster host configuration resource type
+	KindHostConfig = "hostconfig"
+	// KindTrustedCA defines the cluster trusted certificate authorities resource type
+	KindTrustedCA = "trustedca"
+
+	// KindProxy defines the cluster HTTP proxy configuration resource type
+	KindProxy = "proxy"
 )
 
 // SupportedGravityResources is a list of resources supported by
@@ -71,6 +78,9 @@ var SupportedGravityResources = []string{
 	KindAlertTarget,
 	KindTLSKeyPair,
 	KindAuthGateway,
+	KindHostConfig,
+	KindTrustedCA,
+	KindProxy,
 }
 
 // SupportedGravityResourcesToRemove is a list of resources supported by
@@ -84,6 +94,9 @@ var SupportedGravityResourcesToRemove = []string{
 	KindAlert,
 	KindAlertTarget,
 	KindTLSKeyPair,
+	KindHostConfig,
+	KindTrustedCA,
+	KindProxy,
 }
 
 // MetadataSchema is a copy of teleport/lib/services.MetadataSchema but with
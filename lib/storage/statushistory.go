@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// StatusHistory collection stores periodic snapshots of the cluster health
+type StatusHistory interface {
+	// CreateStatusSnapshot records the specified cluster status snapshot.
+	// The snapshot expires after the specified retention period
+	CreateStatusSnapshot(snapshot StatusSnapshot, retention time.Duration) (*StatusSnapshot, error)
+	// GetStatusSnapshots returns the snapshots of the specified cluster
+	// taken within the [from, to] time range ordered by time
+	GetStatusSnapshots(siteDomain string, from, to time.Time) ([]StatusSnapshot, error)
+	// GetLastStatusSnapshot returns the latest snapshot of the specified cluster
+	// taken before the given time
+	GetLastStatusSnapshot(siteDomain string, before time.Time) (*StatusSnapshot, error)
+}
+
+// StatusSnapshot is a point-in-time record of the cluster health
+type StatusSnapshot struct {
+	// ID uniquely identifies the snapshot
+	ID string `json:"id"`
+	// SiteDomain is the name of the cluster
+	SiteDomain string `json:"site_domain"`
+	// Created is the time the snapshot has been taken
+	Created time.Time `json:"created"`
+	// ClusterState is the state of the cluster
+	ClusterState string `json:"cluster_state"`
+	// SystemStatus is the health of the cluster as reported by the planet agents
+	SystemStatus string `json:"system_status"`
+	// Nodes lists the status of individual cluster nodes
+	Nodes []NodeStatusSnapshot `json:"nodes,omitempty"`
+	// Operations lists the operations active in the cluster
+	Operations []OperationStatusSnapshot `json:"operations,omitempty"`
+}
+
+// Check makes sure the snapshot is valid
+func (s StatusSnapshot) Check() error {
+	if s.SiteDomain == "" {
+		return trace.BadParameter("missing site domain")
+	}
+	if s.Created.IsZero() {
+		return trace.BadParameter("missing snapshot creation time")
+	}
+	return nil
+}
+
+// NodeStatusSnapshot records the status of a single cluster node
+type NodeStatusSnapshot struct {
+	// Hostname is the node's hostname
+	Hostname string `json:"hostname"`
+	// AdvertiseIP is the node's advertise IP address
+	AdvertiseIP string `json:"advertise_ip"`
+	// Status is the node's status (healthy, degraded or offline)
+	Status string `json:"status"`
+	// Probes lists the results of the health probes executed on the node
+	Probes []ProbeStatusSnapshot `json:"probes,omitempty"`
+}
+
+// ProbeStatusSnapshot records the result of a single health probe
+type ProbeStatusSnapshot struct {
+	// Name is the name of the checker that executed the probe
+	Name string `json:"name"`
+	// Status is the probe status
+	Status string `json:"status"`
+	// Detail describes the probe failure
+	Detail string `json:"detail,omitempty"`
+}
+
+// OperationStatusSnapshot records the state of a cluster operation
+type OperationStatusSnapshot struct {
+	// ID is the operation ID
+	ID string `json:"id"`
+	// Type is the operation type
+	Type string `json:"type"`
+	// State is the operation state
+	State string `json:"state"`
+}
@@ -1452,6 +1452,7 @@ type Backend interface {
 	LegacyRoles
 	SystemMetadata
 	Charts
+	StatusHistory
 }
 
 const (
@@ -1946,6 +1947,8 @@ type UpdateOperationState struct {
 	ServerUpdates []ServerUpdate `json:"server_updates,omitempty"`
 	// Manual specifies whether this update operation was created in manual mode
 	Manual bool `json:"manual"`
+	// RollbackPolicy specifies the optional automatic rollback policy
+	RollbackPolicy *RollbackPolicy `json:"rollback_policy,omitempty"`
 }
 
 // Package returns the update package locator
@@ -1957,6 +1960,28 @@ func (s UpdateOperationState) Package() (*loc.Locator, error) {
 	return locator, nil
 }
 
+// RollbackPolicy defines when an automatic update operation is rolled back
+type RollbackPolicy struct {
+	// OnFailure specifies whether all completed phases are rolled back
+	// if the operation fails
+	OnFailure bool `json:"on_failure"`
+	// HealthCheckGracePeriod specifies the period of time the cluster has to
+	// become healthy after the update before the update is rolled back.
+	// Post-update health checks are not run if unspecified
+	HealthCheckGracePeriod time.Duration `json:"health_check_grace_period,omitempty"`
+}
+
+// IsEmpty returns true if the policy does not require any automatic rollback
+func (r *RollbackPolicy) IsEmpty() bool {
+	return r == nil || (!r.OnFailure && r.HealthCheckGracePeriod == 0)
+}
+
+// String returns a textual representation of this policy
+func (r RollbackPolicy) String() string {
+	return fmt.Sprintf("RollbackPolicy(OnFailure=%v, HealthCheckGracePeriod=%v)",
+		r.OnFailure, r.HealthCheckGracePeriod)
+}
+
 // ServerUpdate represents server that is being updated
 type ServerUpdate struct {
 	// Server is a server being updated
@@ -1954,6 +1954,80 @@ func (s *StorageSuite) IndexFile(c *C) {
 	compare.DeepCompare(c, retrievedFile, updatedIndex2)
 }
 
+func (s *StorageSuite) StatusHistoryCRUD(c *C) {
+	a, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+	repo, err := s.Backend.CreateRepository(storage.NewRepository("example.com"))
+	c.Assert(err, IsNil)
+	app, err := s.Backend.CreatePackage(storage.Package{
+		Repository: repo.GetName(),
+		Name:       "app",
+		Version:    "0.0.1",
+		Manifest:   []byte("1"),
+		Type:       string(storage.AppUser),
+	})
+	c.Assert(err, IsNil)
+	site, err := s.Backend.CreateSite(storage.Site{
+		Created:   now,
+		AccountID: a.ID,
+		Domain:    "a.example.com",
+		App:       *app,
+	})
+	c.Assert(err, IsNil)
+
+	var snapshots []storage.StatusSnapshot
+	for i := 0; i < 3; i++ {
+		snapshot, err := s.Backend.CreateStatusSnapshot(storage.StatusSnapshot{
+			SiteDomain:   site.Domain,
+			Created:      now.Add(time.Duration(i) * time.Minute),
+			ClusterState: "active",
+			SystemStatus: "running",
+			Nodes: []storage.NodeStatusSnapshot{{
+				Hostname:    "node-1",
+				AdvertiseIP: "192.168.1.1",
+				Status:      "healthy",
+				Probes: []storage.ProbeStatusSnapshot{{
+					Name:   "docker",
+					Status: "running",
+				}},
+			}},
+		}, storage.Forever)
+		c.Assert(err, IsNil)
+		c.Assert(snapshot.ID, Not(Equals), "")
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	out, err := s.Backend.GetStatusSnapshots(site.Domain, time.Time{}, time.Time{})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, snapshots)
+
+	out, err = s.Backend.GetStatusSnapshots(site.Domain, now.Add(time.Minute), now.Add(2*time.Minute))
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, snapshots[1:])
+
+	out, err = s.Backend.GetStatusSnapshots(site.Domain, now.Add(time.Hour), time.Time{})
+	c.Assert(err, IsNil)
+	c.Assert(out, HasLen, 0)
+
+	last, err := s.Backend.GetLastStatusSnapshot(site.Domain, now.Add(time.Minute))
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, last, &snapshots[0])
+
+	last, err = s.Backend.GetLastStatusSnapshot(site.Domain, now.Add(time.Hour))
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, last, &snapshots[2])
+
+	_, err = s.Backend.GetLastStatusSnapshot(site.Domain, now)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+
+	// Snapshots for non existent cluster should fail
+	_, err = s.Backend.CreateStatusSnapshot(storage.StatusSnapshot{
+		SiteDomain: "nothere.com",
+		Created:    now,
+	}, storage.Forever)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+}
+
 func newIndex() *repo.IndexFile {
 	return &repo.IndexFile{
 		APIVersion: repo.APIVersionV1,
@@ -0,0 +1,169 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	cfsslerrors "github.com/cloudflare/cfssl/errors"
+	cfsslhelpers "github.com/cloudflare/cfssl/helpers"
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// TrustedCA describes the certificate authorities trusted by cluster nodes
+// in addition to the system ones, e.g. the CA of a TLS-intercepting proxy
+type TrustedCA interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults verifies that the object is valid
+	CheckAndSetDefaults() error
+	// GetCertificates returns the PEM-encoded CA certificate bundle
+	GetCertificates() string
+	// GetX509Certificates returns the parsed CA certificates
+	GetX509Certificates() ([]*x509.Certificate, error)
+}
+
+// NewTrustedCA creates a new trusted CA resource from the PEM-encoded bundle
+func NewTrustedCA(certificates []byte) TrustedCA {
+	return &TrustedCAV2{
+		Kind:    KindTrustedCA,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      KindTrustedCA,
+			Namespace: defaults.Namespace,
+		},
+		Spec: TrustedCASpecV2{
+			Certificates: string(certificates),
+		},
+	}
+}
+
+// TrustedCAV2 defines the trusted CA resource
+type TrustedCAV2 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the trusted certificate authorities
+	Spec TrustedCASpecV2 `json:"spec"`
+}
+
+// TrustedCASpecV2 defines the trusted certificate authorities
+type TrustedCASpecV2 struct {
+	// Certificates is the PEM-encoded CA certificate bundle
+	Certificates string `json:"certificates"`
+}
+
+// GetCertificates returns the PEM-encoded CA certificate bundle
+func (r *TrustedCAV2) GetCertificates() string {
+	return r.Spec.Certificates
+}
+
+// GetX509Certificates returns the parsed CA certificates
+func (r *TrustedCAV2) GetX509Certificates() ([]*x509.Certificate, error) {
+	certs, err := cfsslhelpers.ParseCertificatesPEM([]byte(r.Spec.Certificates))
+	if err != nil {
+		if cfsslerr, ok := err.(*cfsslerrors.Error); ok {
+			return nil, trace.BadParameter(cfsslerr.Message)
+		}
+		return nil, trace.Wrap(err, "failed to parse certificates, expected PEM formatted blocks")
+	}
+	return certs, nil
+}
+
+// CheckAndSetDefaults checks validity of all parameters and sets defaults
+func (r *TrustedCAV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		r.Metadata.Name = KindTrustedCA
+	}
+	if r.Spec.Certificates == "" {
+		return trace.BadParameter("missing parameter 'certificates'")
+	}
+	certs, err := r.GetX509Certificates()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(certs) == 0 {
+		return trace.BadParameter("no certificates found in the bundle")
+	}
+	for _, cert := range certs {
+		if !cert.IsCA {
+			return trace.BadParameter("certificate %q is not a certificate authority",
+				cert.Subject.CommonName)
+		}
+	}
+	return nil
+}
+
+// UnmarshalTrustedCA unmarshals trusted CA resource from JSON or YAML
+func UnmarshalTrustedCA(data []byte) (TrustedCA, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty input")
+	}
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header teleservices.ResourceHeader
+	if err := json.Unmarshal(jsonData, &header); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch header.Version {
+	case teleservices.V2:
+		var ca TrustedCAV2
+		err := teleutils.UnmarshalWithSchema(GetTrustedCASchema(), &ca, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter(err.Error())
+		}
+		ca.Metadata.CheckAndSetDefaults()
+		if err := ca.CheckAndSetDefaults(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &ca, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindTrustedCA, header.Version)
+}
+
+// MarshalTrustedCA marshals trusted CA resource into JSON
+func MarshalTrustedCA(ca TrustedCA, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(ca)
+}
+
+// TrustedCASpecV2Schema is JSON schema for trusted CA resource
+const TrustedCASpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["certificates"],
+  "properties": {
+    "certificates": {"type": "string"}
+  }
+}`
+
+// GetTrustedCASchema returns trusted CA schema for version V2
+func GetTrustedCASchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		TrustedCASpecV2Schema, "")
+}
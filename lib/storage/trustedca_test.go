@@ -0,0 +1,83 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"crypto/x509/pkix"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/teleport/lib/tlsca"
+	check "gopkg.in/check.v1"
+)
+
+type TrustedCASuite struct{}
+
+var _ = check.Suite(&TrustedCASuite{})
+
+func (s *TrustedCASuite) TestResourceParsing(c *check.C) {
+	_, certPEM, err := tlsca.GenerateSelfSignedCA(pkix.Name{CommonName: "proxy-ca"}, nil, time.Hour)
+	c.Assert(err, check.IsNil)
+	spec := fmt.Sprintf(`kind: trustedca
+version: v2
+spec:
+  certificates: |
+%v`, indent(string(certPEM), "    "))
+	ca, err := UnmarshalTrustedCA([]byte(spec))
+	c.Assert(err, check.IsNil)
+	c.Assert(ca.GetName(), check.Equals, KindTrustedCA)
+	certs, err := ca.GetX509Certificates()
+	c.Assert(err, check.IsNil)
+	c.Assert(certs, check.HasLen, 1)
+	c.Assert(certs[0].Subject.CommonName, check.Equals, "proxy-ca")
+
+	data, err := MarshalTrustedCA(ca)
+	c.Assert(err, check.IsNil)
+	parsed, err := UnmarshalTrustedCA(data)
+	c.Assert(err, check.IsNil)
+	c.Assert(parsed.GetCertificates(), check.Equals, ca.GetCertificates())
+}
+
+func (s *TrustedCASuite) TestValidatesResource(c *check.C) {
+	specs := []string{
+		`kind: trustedca
+version: v2
+spec:
+  certificates: ""`,
+		`kind: trustedca
+version: v2
+spec:
+  certificates: "not a certificate"`,
+		`kind: trustedca
+version: v1
+spec:
+  certificates: ""`,
+	}
+	for _, spec := range specs {
+		_, err := UnmarshalTrustedCA([]byte(spec))
+		c.Assert(err, check.NotNil, check.Commentf(spec))
+	}
+}
+
+func indent(s, prefix string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
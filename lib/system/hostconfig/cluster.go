@@ -0,0 +1,170 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systeminfo"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// ClusterConfig defines the configuration to apply host configuration
+// on cluster nodes
+type ClusterConfig struct {
+	// Config is the host configuration to apply
+	Config storage.HostConfig
+	// Servers lists the nodes to apply the configuration on
+	Servers []storage.Server
+	// Remote executes commands on the nodes
+	Remote fsm.RemoteRunner
+	// Progress reports the progress
+	utils.Progress
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (r *ClusterConfig) CheckAndSetDefaults() error {
+	if r.Config == nil {
+		return trace.BadParameter("missing host configuration")
+	}
+	if r.Remote == nil {
+		return trace.BadParameter("missing remote runner")
+	}
+	if r.Progress == nil {
+		r.Progress = utils.NewNopProgress()
+	}
+	return nil
+}
+
+// ApplyCluster applies the host configuration on the specified cluster nodes
+// in order. Each node receives the configuration for its profile.
+// A node that fails does not prevent the configuration from being applied
+// on the remaining nodes, all failures are returned as an aggregate error
+func ApplyCluster(ctx context.Context, config ClusterConfig) error {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	var errors []error
+	for _, server := range config.Servers {
+		err := applyServer(ctx, config, server)
+		if err != nil {
+			config.PrintWarn(err, "Failed to apply host configuration on %v", server.Hostname)
+			errors = append(errors, trace.Wrap(err, "failed to apply host configuration on %v",
+				server.AdvertiseIP))
+			continue
+		}
+		config.PrintInfo("Applied host configuration on %v (%v)", server.Hostname, server.AdvertiseIP)
+	}
+	return trace.NewAggregate(errors...)
+}
+
+// CommandArgs returns the gravity command line that applies the specified
+// host configuration on a node
+func CommandArgs(config storage.HostConfigProfile) ([]string, error) {
+	spec, err := encodeSpec(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return []string{"system", "apply-hostconfig", spec}, nil
+}
+
+// DriftConfig defines the configuration to collect the host configuration
+// drift of the cluster nodes
+type DriftConfig struct {
+	// Config is the cluster host configuration
+	Config storage.HostConfig
+	// Servers lists the cluster nodes
+	Servers []storage.Server
+}
+
+// NodeDrift describes the differences between the host configuration
+// and the actual state of a cluster node
+type NodeDrift struct {
+	// Hostname is the node hostname
+	Hostname string `json:"hostname"`
+	// AdvertiseIP is the node advertise address
+	AdvertiseIP string `json:"advertise_ip"`
+	// Drift lists the differences
+	Drift []string `json:"drift,omitempty"`
+	// Error describes why the drift could not be collected from the node
+	Error string `json:"error,omitempty"`
+}
+
+// CollectDrift collects the host configuration drift of the local node.
+// The other cluster nodes are not queried since the agents that could run
+// the check remotely are only available during operations, so their drift
+// is reported as unknown.
+// Returns the nodes that have drifted or could not be checked
+func CollectDrift(config DriftConfig) []NodeDrift {
+	var drifted []NodeDrift
+	for _, server := range config.Servers {
+		result := NodeDrift{Hostname: server.Hostname, AdvertiseIP: server.AdvertiseIP}
+		if systeminfo.HasInterface(server.AdvertiseIP) != nil {
+			result.Error = "run 'gravity status' on the node to check it"
+			drifted = append(drifted, result)
+			continue
+		}
+		drift, err := Drift(config.Config.ForProfile(server.Role))
+		if err != nil {
+			result.Error = trace.UserMessage(err)
+		}
+		result.Drift = drift
+		if len(result.Drift) != 0 || result.Error != "" {
+			drifted = append(drifted, result)
+		}
+	}
+	return drifted
+}
+
+func encodeSpec(config storage.HostConfigProfile) (string, error) {
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return fmt.Sprintf("--spec=%v", base64.StdEncoding.EncodeToString(bytes)), nil
+}
+
+// DecodeSpec decodes the host configuration encoded with CommandArgs
+func DecodeSpec(spec string) (*storage.HostConfigProfile, error) {
+	bytes, err := base64.StdEncoding.DecodeString(spec)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var config storage.HostConfigProfile
+	if err := json.Unmarshal(bytes, &config); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := config.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &config, nil
+}
+
+func applyServer(ctx context.Context, config ClusterConfig, server storage.Server) error {
+	args, err := CommandArgs(config.Config.ForProfile(server.Role))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(config.Remote.Run(ctx, server, args...))
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostconfig
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type ClusterSuite struct{}
+
+var _ = Suite(&ClusterSuite{})
+
+func (s *ClusterSuite) TestAppliesProfileConfigOnEachNode(c *C) {
+	remote := &fakeRunner{}
+	err := ApplyCluster(context.TODO(), ClusterConfig{
+		Config:  clusterConfig,
+		Servers: servers,
+		Remote:  remote,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(remote.servers(), DeepEquals, []string{"10.255.0.1", "10.255.0.2", "10.255.0.3"})
+	c.Assert(remote.applied(c), DeepEquals, []storage.HostConfigProfile{
+		clusterConfig.ForProfile("master"),
+		clusterConfig.ForProfile("db"),
+		clusterConfig.ForProfile("node"),
+	})
+	c.Assert(remote.applied(c)[1].Sysctls["vm.max_map_count"], Equals, "524288")
+}
+
+func (s *ClusterSuite) TestContinuesOnFailedNode(c *C) {
+	remote := &fakeRunner{failed: map[string]bool{"10.255.0.2": true}}
+	err := ApplyCluster(context.TODO(), ClusterConfig{
+		Config:  clusterConfig,
+		Servers: servers,
+		Remote:  remote,
+	})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*10.255.0.2.*")
+	c.Assert(remote.servers(), DeepEquals, []string{"10.255.0.1", "10.255.0.2", "10.255.0.3"})
+}
+
+func (s *ClusterSuite) TestRemovesConfigWithEmptyConfig(c *C) {
+	remote := &fakeRunner{}
+	err := ApplyCluster(context.TODO(), ClusterConfig{
+		Config:  storage.NewHostConfig(storage.HostConfigSpecV2{}),
+		Servers: servers,
+		Remote:  remote,
+	})
+	c.Assert(err, IsNil)
+	for _, profile := range remote.applied(c) {
+		c.Assert(profile.IsEmpty(), Equals, true)
+	}
+}
+
+func (s *ClusterSuite) TestReportsRemoteNodesAsUnknown(c *C) {
+	drift := CollectDrift(DriftConfig{
+		Config:  clusterConfig,
+		Servers: servers[:1],
+	})
+	c.Assert(drift, HasLen, 1)
+	c.Assert(drift[0].AdvertiseIP, Equals, "10.255.0.1")
+	c.Assert(drift[0].Drift, HasLen, 0)
+	c.Assert(drift[0].Error, Matches, ".*gravity status.*")
+}
+
+// fakeRunner records the host configuration commands executed on the nodes
+type fakeRunner struct {
+	// failed lists the addresses of the nodes the commands fail on
+	failed   map[string]bool
+	commands []command
+}
+
+type command struct {
+	server storage.Server
+	args   []string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, server storage.Server, args ...string) error {
+	r.commands = append(r.commands, command{server: server, args: args})
+	if r.failed[server.AdvertiseIP] {
+		return trace.ConnectionProblem(nil, "failed to run on %v", server.AdvertiseIP)
+	}
+	return nil
+}
+
+func (r *fakeRunner) CanExecute(context.Context, storage.Server) error {
+	return nil
+}
+
+func (r *fakeRunner) Close() error {
+	return nil
+}
+
+func (r *fakeRunner) servers() (addrs []string) {
+	for _, command := range r.commands {
+		addrs = append(addrs, command.server.AdvertiseIP)
+	}
+	return addrs
+}
+
+func (r *fakeRunner) applied(c *C) (profiles []storage.HostConfigProfile) {
+	for _, command := range r.commands {
+		c.Assert(command.args[:2], DeepEquals, []string{"system", "apply-hostconfig"})
+		profile, err := DecodeSpec(strings.TrimPrefix(command.args[2], "--spec="))
+		c.Assert(err, IsNil)
+		profiles = append(profiles, *profile)
+	}
+	return profiles
+}
+
+var servers = []storage.Server{
+	{Hostname: "master-1", AdvertiseIP: "10.255.0.1", Role: "master"},
+	{Hostname: "db-1", AdvertiseIP: "10.255.0.2", Role: "db"},
+	{Hostname: "node-1", AdvertiseIP: "10.255.0.3", Role: "node"},
+}
+
+var clusterConfig = storage.NewHostConfig(storage.HostConfigSpecV2{
+	Profiles: []storage.HostConfigProfile{
+		{
+			Sysctls:       map[string]string{"vm.max_map_count": "262144"},
+			KernelModules: []string{"ip_vs"},
+		},
+		{
+			Profile: "db",
+			Sysctls: map[string]string{"vm.max_map_count": "524288"},
+			Ulimits: []storage.Ulimit{{Item: "nofile", Hard: "1048576"}},
+		},
+	},
+})
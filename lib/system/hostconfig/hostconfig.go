@@ -0,0 +1,265 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hostconfig applies the cluster host configuration - kernel
+// parameters, kernel modules and resource limits of the planet services -
+// to cluster nodes and detects nodes that have drifted from it
+package hostconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systemservice"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// Apply applies the host configuration to the local node.
+// The kernel parameters and modules take effect immediately and persist
+// across reboots. The resource limits are configured with systemd drop-ins
+// for the planet services and take effect once planet restarts
+func Apply(ctx context.Context, config storage.HostConfigProfile, progress utils.Progress) error {
+	host, err := newHost()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return host.apply(ctx, config, progress)
+}
+
+// Drift returns the list of differences between the host configuration and
+// the actual state of the local node
+func Drift(config storage.HostConfigProfile) ([]string, error) {
+	host, err := newHost()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return host.drift(config)
+}
+
+func newHost() (*host, error) {
+	services, err := planetServices()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &host{
+		root:     "/",
+		services: services,
+		run: func(ctx context.Context, args ...string) ([]byte, error) {
+			return utils.RunCommand(ctx, nil, args...)
+		},
+	}, nil
+}
+
+// host applies host configuration to the node filesystem rooted at root
+type host struct {
+	// root is the root directory of the node filesystem
+	root string
+	// services lists the planet systemd units the resource limits apply to
+	services []string
+	// run executes the command specified with args
+	run func(ctx context.Context, args ...string) ([]byte, error)
+}
+
+func (r *host) apply(ctx context.Context, config storage.HostConfigProfile, progress utils.Progress) error {
+	for _, module := range config.KernelModules {
+		out, err := r.run(ctx, "modprobe", module)
+		if err != nil {
+			return trace.Wrap(err, "failed to load kernel module %v: %s", module, out)
+		}
+		progress.PrintInfo("Loaded kernel module %v", module)
+	}
+	if err := r.writeFile(defaults.HostConfigModulesPath, renderModules(config)); err != nil {
+		return trace.Wrap(err)
+	}
+
+	if err := r.writeFile(defaults.HostConfigSysctlPath, renderSysctls(config)); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, name := range config.SortedSysctls() {
+		value := config.Sysctls[name]
+		out, err := r.run(ctx, "sysctl", "-w", fmt.Sprintf("%v=%v", name, value))
+		if err != nil {
+			return trace.Wrap(err, "failed to set kernel parameter %v=%v: %s", name, value, out)
+		}
+		progress.PrintInfo("Set kernel parameter %v=%v", name, value)
+	}
+
+	return trace.Wrap(r.applyLimits(ctx, config, progress))
+}
+
+// applyLimits writes the resource limits drop-in for the planet services.
+// Planet is not restarted as it would disrupt the workloads on the node,
+// the limits take effect on its next restart
+func (r *host) applyLimits(ctx context.Context, config storage.HostConfigProfile, progress utils.Progress) error {
+	dropIn := renderLimits(config)
+	for _, service := range r.services {
+		if err := r.writeFile(dropInPath(service), dropIn); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if len(r.services) == 0 {
+		return nil
+	}
+	out, err := r.run(ctx, "systemctl", "daemon-reload")
+	if err != nil {
+		return trace.Wrap(err, "failed to reload systemd configuration: %s", out)
+	}
+	for _, ulimit := range config.Ulimits {
+		progress.PrintInfo("Configured resource limit %v for %v, it will take effect once planet restarts",
+			ulimit, strings.Join(r.services, ", "))
+	}
+	return nil
+}
+
+func (r *host) drift(config storage.HostConfigProfile) (drift []string, err error) {
+	for _, name := range config.SortedSysctls() {
+		expected := config.Sysctls[name]
+		path := r.path(defaults.ProcSysPath, strings.Replace(name, ".", "/", -1))
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				drift = append(drift, fmt.Sprintf("kernel parameter %v is not available", name))
+				continue
+			}
+			return nil, trace.ConvertSystemError(err)
+		}
+		actual := normalizeValue(string(data))
+		if actual != normalizeValue(expected) {
+			drift = append(drift, fmt.Sprintf("kernel parameter %v is %q, expected %q",
+				name, actual, expected))
+		}
+	}
+
+	for _, module := range config.KernelModules {
+		// /sys/module lists modules with dashes replaced by underscores
+		path := r.path(defaults.SysModulePath, strings.Replace(module, "-", "_", -1))
+		_, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				drift = append(drift, fmt.Sprintf("kernel module %v is not loaded", module))
+				continue
+			}
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
+
+	if len(config.Ulimits) == 0 {
+		return drift, nil
+	}
+	for _, service := range r.services {
+		data, err := ioutil.ReadFile(r.path(dropInPath(service)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, trace.ConvertSystemError(err)
+		}
+		lines := strings.Split(string(data), "\n")
+		for _, ulimit := range config.Ulimits {
+			if !utils.StringInSlice(lines, ulimit.String()) {
+				drift = append(drift, fmt.Sprintf("resource limit %v is not configured for %v",
+					ulimit, service))
+			}
+		}
+	}
+	return drift, nil
+}
+
+// writeFile replaces the contents of the file at the path relative
+// to the host root with data or removes the file if data is empty
+func (r *host) writeFile(path string, data []byte) error {
+	return trace.Wrap(utils.WriteOrRemovePath(r.path(path), data))
+}
+
+func (r *host) path(elems ...string) string {
+	return filepath.Join(append([]string{r.root}, elems...)...)
+}
+
+func renderModules(config storage.HostConfigProfile) []byte {
+	return render(config.KernelModules)
+}
+
+func renderSysctls(config storage.HostConfigProfile) []byte {
+	var lines []string
+	for _, name := range config.SortedSysctls() {
+		lines = append(lines, fmt.Sprintf("%v = %v", name, config.Sysctls[name]))
+	}
+	return render(lines)
+}
+
+func renderLimits(config storage.HostConfigProfile) []byte {
+	if len(config.Ulimits) == 0 {
+		return nil
+	}
+	lines := []string{"[Service]"}
+	for _, ulimit := range config.Ulimits {
+		lines = append(lines, ulimit.String())
+	}
+	return render(lines)
+}
+
+// planetServices returns the planet systemd units installed on host
+func planetServices() ([]string, error) {
+	services, err := systemservice.New()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	packageServices, err := services.ListPackageServices()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var units []string
+	for _, service := range packageServices {
+		if service.Package.Name == loc.Planet.Name {
+			units = append(units, systemservice.PackageServiceName(service.Package))
+		}
+	}
+	return units, nil
+}
+
+// dropInPath returns the path to the resource limits drop-in
+// for the specified systemd unit
+func dropInPath(service string) string {
+	return filepath.Join(defaults.InSystemUnitDir(service+".d"), defaults.HostConfigDropInFile)
+}
+
+func render(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// normalizeValue collapses the whitespace in the kernel parameter value
+// as multi-value parameters are reported separated with tabs
+func normalizeValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+const header = "# Generated by gravity from the cluster host configuration. Do not edit.\n"
@@ -0,0 +1,138 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestHostConfig(t *testing.T) { TestingT(t) }
+
+type HostConfigSuite struct {
+	host     *host
+	commands []string
+}
+
+var _ = Suite(&HostConfigSuite{})
+
+func (s *HostConfigSuite) SetUpTest(c *C) {
+	s.commands = nil
+	s.host = &host{
+		root:     c.MkDir(),
+		services: []string{planetService},
+		run: func(ctx context.Context, args ...string) ([]byte, error) {
+			s.commands = append(s.commands, strings.Join(args, " "))
+			return nil, nil
+		},
+	}
+}
+
+func (s *HostConfigSuite) TestAppliesConfig(c *C) {
+	err := s.host.apply(context.TODO(), config, utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	c.Assert(s.commands, DeepEquals, []string{
+		"modprobe ip_vs",
+		"modprobe nf-conntrack",
+		"sysctl -w net.ipv4.ip_local_port_range=1024 65000",
+		"sysctl -w vm.max_map_count=262144",
+		"systemctl daemon-reload",
+	})
+	s.assertFile(c, defaults.HostConfigModulesPath, header+"ip_vs\nnf-conntrack\n")
+	s.assertFile(c, defaults.HostConfigSysctlPath, header+
+		"net.ipv4.ip_local_port_range = 1024 65000\nvm.max_map_count = 262144\n")
+	s.assertFile(c, dropInPath(planetService), header+
+		"[Service]\nLimitNOFILE=65536:65536\n")
+
+	// empty configuration removes the persisted settings
+	err = s.host.apply(context.TODO(), storage.HostConfigProfile{}, utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	for _, path := range []string{defaults.HostConfigModulesPath, defaults.HostConfigSysctlPath, dropInPath(planetService)} {
+		_, err := os.Stat(s.host.path(path))
+		c.Assert(os.IsNotExist(err), Equals, true, Commentf(path))
+	}
+}
+
+func (s *HostConfigSuite) TestDetectsDrift(c *C) {
+	s.writeFile(c, filepath.Join(defaults.ProcSysPath, "vm/max_map_count"), "65530\n")
+	s.writeFile(c, filepath.Join(defaults.ProcSysPath, "net/ipv4/ip_local_port_range"), "1024\t65000\n")
+	s.writeFile(c, filepath.Join(defaults.SysModulePath, "nf_conntrack/refcnt"), "1\n")
+	s.writeFile(c, dropInPath(planetService), header+"[Service]\nLimitNOFILE=1024:4096\n")
+
+	drift, err := s.host.drift(config)
+	c.Assert(err, IsNil)
+	c.Assert(drift, DeepEquals, []string{
+		`kernel parameter vm.max_map_count is "65530", expected "262144"`,
+		"kernel module ip_vs is not loaded",
+		"resource limit LimitNOFILE=65536:65536 is not configured for " + planetService,
+	})
+
+	err = s.host.apply(context.TODO(), config, utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	s.writeFile(c, filepath.Join(defaults.ProcSysPath, "vm/max_map_count"), "262144\n")
+	s.writeFile(c, filepath.Join(defaults.SysModulePath, "ip_vs/refcnt"), "1\n")
+
+	drift, err = s.host.drift(config)
+	c.Assert(err, IsNil)
+	c.Assert(drift, HasLen, 0)
+}
+
+func (s *HostConfigSuite) TestEncodesCommandArgs(c *C) {
+	args, err := CommandArgs(config)
+	c.Assert(err, IsNil)
+	c.Assert(args, HasLen, 3)
+	c.Assert(args[:2], DeepEquals, []string{"system", "apply-hostconfig"})
+
+	decoded, err := DecodeSpec(strings.TrimPrefix(args[2], "--spec="))
+	c.Assert(err, IsNil)
+	c.Assert(*decoded, DeepEquals, config)
+}
+
+func (s *HostConfigSuite) writeFile(c *C, path, data string) {
+	path = s.host.path(path)
+	c.Assert(os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask), IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(data), defaults.SharedReadMask), IsNil)
+}
+
+func (s *HostConfigSuite) assertFile(c *C, path, expected string) {
+	data, err := ioutil.ReadFile(s.host.path(path))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, expected)
+}
+
+var config = storage.HostConfigProfile{
+	Sysctls: map[string]string{
+		"vm.max_map_count":             "262144",
+		"net.ipv4.ip_local_port_range": "1024 65000",
+	},
+	KernelModules: []string{"ip_vs", "nf-conntrack"},
+	Ulimits: []storage.Ulimit{
+		{Item: "nofile", Soft: "65536", Hard: "65536"},
+	},
+}
+
+const planetService = "gravity__gravitational.io__planet__7.0.0.service"
@@ -0,0 +1,311 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/cenkalti/backoff"
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+	kubeapi "k8s.io/client-go/kubernetes"
+)
+
+// Config describes the configuration of the proxy update operation
+type Config struct {
+	// Operation references the active proxy update operation
+	Operation *ops.SiteOperation
+	// Operator is the cluster operator service
+	Operator ops.Operator
+	// Client is the kubernetes client.
+	// Required to drain and uncordon nodes from this node
+	Client *kubeapi.Clientset
+	// Runner executes phases on remote nodes
+	Runner libfsm.RemoteRunner
+	// Spec specifies the function that resolves to an executor
+	Spec libfsm.FSMSpecFunc
+	// FieldLogger is the logger
+	log.FieldLogger
+}
+
+func (r *Config) checkAndSetDefaults() error {
+	if r.Operation == nil {
+		return trace.BadParameter("operation is required")
+	}
+	if r.Operator == nil {
+		return trace.BadParameter("operator service is required")
+	}
+	if r.Runner == nil {
+		return trace.BadParameter("remote runner is required")
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithFields(log.Fields{
+			trace.Component: "fsm:proxy",
+			"operation":     r.Operation.ID,
+		})
+	}
+	if r.Spec == nil {
+		r.Spec = configToExecutor(*r)
+	}
+	return nil
+}
+
+// New returns a new state machine for the proxy update operation
+func New(config Config) (*libfsm.FSM, error) {
+	if err := config.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	engine := &engine{
+		Config: config,
+	}
+	machine, err := libfsm.New(libfsm.Config{
+		Engine: engine,
+		Runner: config.Runner,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	machine.SetPreExec(engine.UpdateProgress)
+	return machine, nil
+}
+
+// Update executes the proxy update operation plan.
+// If any of the phases fails, the executed phases are rolled back
+// to restore the previous proxy environment on the nodes.
+// The operation is marked completed or failed based on the state of the plan
+func Update(ctx context.Context, config Config, progress utils.Progress) error {
+	machine, err := New(config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	planErr := machine.ExecutePlan(ctx, progress, false)
+	if planErr != nil {
+		progress.PrintWarn(planErr, "Failed to update proxy environment, rolling back")
+		_, err := machine.RollbackPlan(ctx, progress, false)
+		if err != nil {
+			config.Warnf("Failed to rollback plan: %v.", trace.DebugReport(err))
+			planErr = trace.Wrap(planErr, "failed to rollback the operation (%v), "+
+				"rollback the remaining phases with `gravity rollback --phase=<phase-id>`",
+				trace.UserMessage(err))
+		}
+	}
+
+	if err := machine.Complete(planErr); err != nil {
+		config.Warnf("Failed to complete operation: %v.", trace.DebugReport(err))
+	}
+	return trace.Wrap(planErr)
+}
+
+// CreateOperation creates a new proxy update operation along with its plan
+// that applies env on the cluster nodes and restores previous on rollback
+func CreateOperation(operator ops.Operator, cluster ops.Site, env, previous map[string]string) (operation *ops.SiteOperation, err error) {
+	key, err := operator.CreateUpdateProxyOperation(ops.CreateUpdateProxyOperationRequest{
+		AccountID:   cluster.AccountID,
+		ClusterName: cluster.Domain,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if errDelete := operator.DeleteSiteOperation(*key); errDelete != nil {
+			log.Warnf("Failed to clean up proxy update operation %v: %v.",
+				key, trace.DebugReport(errDelete))
+		}
+	}()
+
+	operation, err = operator.GetSiteOperation(*key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	plan, err := NewOperationPlan(*operation, cluster.ClusterState.Servers, env, previous)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	err = operator.CreateOperationPlan(*key, *plan)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return operation, nil
+}
+
+// UpdateProgress creates an appropriate progress entry in the operator
+func (r *engine) UpdateProgress(ctx context.Context, params libfsm.Params) error {
+	plan, err := r.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	phase, err := libfsm.FindPhase(plan, params.PhaseID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	key := r.Operation.Key()
+	entry := ops.ProgressEntry{
+		SiteDomain:  key.SiteDomain,
+		OperationID: key.OperationID,
+		Completion:  100 / utils.Max(len(plan.Phases), 1) * phase.Step,
+		Step:        phase.Step,
+		State:       ops.ProgressStateInProgress,
+		Message:     phase.Description,
+		Created:     time.Now().UTC(),
+	}
+	err = r.Operator.CreateProgressEntry(key, entry)
+	if err != nil {
+		r.Warnf("Failed to create progress entry %v: %v.", entry,
+			trace.DebugReport(err))
+	}
+	return nil
+}
+
+// Complete marks the operation as either completed or failed based
+// on the state of the operation plan
+func (r *engine) Complete(fsmErr error) error {
+	plan, err := r.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	if libfsm.IsCompleted(plan) {
+		err = ops.CompleteOperation(r.Operation.Key(), r.Operator)
+	} else {
+		var message string
+		if fsmErr != nil {
+			message = trace.Unwrap(fsmErr).Error()
+		}
+		err = ops.FailOperation(r.Operation.Key(), r.Operator, message)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	r.Debug("Marked operation complete.")
+	return nil
+}
+
+// ChangePhaseState creates an new changelog entry.
+// The cluster controller might be unavailable for a short time while
+// the services are being restarted on the node it runs on so the request
+// is retried on connection errors
+func (r *engine) ChangePhaseState(ctx context.Context, change libfsm.StateChange) error {
+	err := retryOperator(ctx, func() error {
+		return r.Operator.CreateOperationPlanChange(r.Operation.Key(),
+			storage.PlanChange{
+				ID:          uuid.New(),
+				ClusterName: r.Operation.SiteDomain,
+				OperationID: r.Operation.ID,
+				PhaseID:     change.Phase,
+				NewState:    change.State,
+				Error:       utils.ToRawTrace(change.Error),
+				Created:     time.Now().UTC(),
+			})
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	r.Debugf("Applied %v.", change)
+	return nil
+}
+
+// GetExecutor returns the appropriate phase executor based on the
+// provided parameters
+func (r *engine) GetExecutor(params libfsm.ExecutorParams, remote libfsm.Remote) (libfsm.PhaseExecutor, error) {
+	return r.Spec(params, remote)
+}
+
+// RunCommand executes the phase specified by params on the specified server
+// using the provided runner
+func (r *engine) RunCommand(ctx context.Context, runner libfsm.RemoteRunner, server storage.Server, params libfsm.Params) error {
+	args := []string{"system", "update-proxy", "--phase", params.PhaseID}
+	if params.Force {
+		args = append(args, "--force")
+	}
+	return runner.Run(ctx, server, args...)
+}
+
+// GetPlan returns the most up-to-date operation plan
+func (r *engine) GetPlan() (*storage.OperationPlan, error) {
+	var plan *storage.OperationPlan
+	err := retryOperator(context.TODO(), func() (err error) {
+		plan, err = r.Operator.GetOperationPlan(r.Operation.Key())
+		return trace.Wrap(err)
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
+// engine is the proxy update engine
+type engine struct {
+	// Config is the operation configuration
+	Config
+}
+
+// configToExecutor returns a function that maps configuration and a set of parameters
+// to a phase executor
+func configToExecutor(config Config) libfsm.FSMSpecFunc {
+	return func(params libfsm.ExecutorParams, remote libfsm.Remote) (libfsm.PhaseExecutor, error) {
+		logger := config.WithField("phase", params.Phase.ID)
+		switch path.Base(params.Phase.ID) {
+		case DrainPhase:
+			return newDrainExecutor(params, config.Client, logger)
+		case UncordonPhase:
+			return newUncordonExecutor(params, config.Client, logger)
+		case ApplyPhase:
+			return newApplyExecutor(params, remote, logger)
+		case RestartPhase:
+			return newRestartExecutor(params, remote, logger)
+		case HealthPhase:
+			return newHealthExecutor(params, remote, logger)
+		default:
+			return nil, trace.BadParameter("unknown phase %q", params.Phase.ID)
+		}
+	}
+}
+
+// retryOperator retries the specified operator request fn on connection
+// and transient cluster errors
+func retryOperator(ctx context.Context, fn func() error) error {
+	return utils.RetryWithInterval(ctx, utils.NewExponentialBackOff(defaults.TransientErrorTimeout), func() error {
+		err := fn()
+		if err == nil || trace.IsConnectionProblem(err) || utils.IsTransientClusterError(err) {
+			return trace.Wrap(err)
+		}
+		return &backoff.PermanentError{Err: err}
+	})
+}
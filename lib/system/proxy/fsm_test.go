@@ -0,0 +1,233 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type FSMSuite struct {
+	cluster  ops.Site
+	operator *fakeOperator
+	runner   *fakeRunner
+}
+
+var _ = Suite(&FSMSuite{})
+
+func (s *FSMSuite) SetUpTest(c *C) {
+	s.cluster = ops.Site{
+		AccountID: "0",
+		Domain:    "cluster",
+		ClusterState: storage.ClusterState{
+			Servers: []storage.Server{
+				// Addresses from TEST-NET-3 so that every phase is executed
+				// with the remote runner
+				{Hostname: "node-1", AdvertiseIP: "203.0.113.1", ClusterRole: string(schema.ServiceRoleMaster)},
+				{Hostname: "node-2", AdvertiseIP: "203.0.113.2", ClusterRole: string(schema.ServiceRoleNode)},
+			},
+		},
+	}
+	s.operator = &fakeOperator{}
+	s.runner = &fakeRunner{operator: s.operator}
+}
+
+func (s *FSMSuite) TestUpdatesNodesInOrder(c *C) {
+	env := map[string]string{"HTTP_PROXY": "http://proxy:3128"}
+	err := s.update(c, env, nil)
+	c.Assert(err, IsNil)
+	c.Assert(s.runner.commands, DeepEquals, []string{
+		"node-1: system update-proxy --phase /nodes/node-1/drain",
+		"node-1: system update-proxy --phase /nodes/node-1/apply",
+		"node-1: system update-proxy --phase /nodes/node-1/restart",
+		"node-1: system update-proxy --phase /nodes/node-1/health",
+		"node-1: system update-proxy --phase /nodes/node-1/uncordon",
+		"node-1: system update-proxy --phase /nodes/node-2/drain",
+		"node-2: system update-proxy --phase /nodes/node-2/apply",
+		"node-2: system update-proxy --phase /nodes/node-2/restart",
+		"node-2: system update-proxy --phase /nodes/node-2/health",
+		"node-1: system update-proxy --phase /nodes/node-2/uncordon",
+	})
+	c.Assert(s.operator.state, Equals, ops.OperationStateCompleted)
+}
+
+func (s *FSMSuite) TestRollsBackOnFailure(c *C) {
+	s.runner.fail = "/nodes/node-2/restart"
+	env := map[string]string{"HTTP_PROXY": "http://proxy:3128"}
+	err := s.update(c, env, nil)
+	c.Assert(err, NotNil)
+	c.Assert(s.runner.commands[len(s.runner.commands)-9:], DeepEquals, []string{
+		"node-2: system update-proxy --phase /nodes/node-2/restart",
+		"node-2: rollback --phase /nodes/node-2/restart --force=false",
+		"node-2: rollback --phase /nodes/node-2/apply --force=false",
+		"node-1: rollback --phase /nodes/node-2/drain --force=false",
+		"node-1: rollback --phase /nodes/node-1/uncordon --force=false",
+		"node-1: rollback --phase /nodes/node-1/health --force=false",
+		"node-1: rollback --phase /nodes/node-1/restart --force=false",
+		"node-1: rollback --phase /nodes/node-1/apply --force=false",
+		"node-1: rollback --phase /nodes/node-1/drain --force=false",
+	})
+	c.Assert(s.operator.state, Equals, ops.OperationStateFailed)
+	for _, phase := range libfsm.FlattenPlan(s.operator.resolvePlan()) {
+		if phase.HasSubphases() || phase.IsUnstarted() {
+			continue
+		}
+		c.Assert(phase.IsRolledBack(), Equals, true, Commentf("phase %v", phase.ID))
+	}
+}
+
+func (s *FSMSuite) TestRemovesEnv(c *C) {
+	previous := map[string]string{"HTTP_PROXY": "http://proxy:3128"}
+	err := s.update(c, nil, previous)
+	c.Assert(err, IsNil)
+	c.Assert(s.operator.state, Equals, ops.OperationStateCompleted)
+
+	phase, err := libfsm.FindPhase(&s.operator.plan, "/nodes/node-2/apply")
+	c.Assert(err, IsNil)
+	var data phaseData
+	c.Assert(json.Unmarshal([]byte(phase.Data.Data), &data), IsNil)
+	c.Assert(data, DeepEquals, phaseData{Previous: previous})
+}
+
+func (s *FSMSuite) TestCleansUpOperationWithoutMasters(c *C) {
+	s.cluster.ClusterState.Servers[0].ClusterRole = string(schema.ServiceRoleNode)
+	_, err := CreateOperation(s.operator, s.cluster, nil, nil)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+	c.Assert(s.operator.operation, IsNil)
+}
+
+func (s *FSMSuite) update(c *C, env, previous map[string]string) error {
+	operation, err := CreateOperation(s.operator, s.cluster, env, previous)
+	c.Assert(err, IsNil)
+	return Update(context.TODO(), Config{
+		Operation: operation,
+		Operator:  s.operator,
+		Runner:    s.runner,
+	}, utils.NewNopProgress())
+}
+
+// fakeOperator keeps a single proxy update operation and its plan in memory
+type fakeOperator struct {
+	ops.Operator
+	operation *ops.SiteOperation
+	plan      storage.OperationPlan
+	changelog storage.PlanChangelog
+	state     string
+}
+
+func (o *fakeOperator) CreateUpdateProxyOperation(req ops.CreateUpdateProxyOperationRequest) (*ops.SiteOperationKey, error) {
+	o.operation = &ops.SiteOperation{
+		ID:         "1",
+		AccountID:  req.AccountID,
+		SiteDomain: req.ClusterName,
+		Type:       ops.OperationUpdateProxy,
+		State:      ops.OperationUpdateProxyInProgress,
+	}
+	key := o.operation.Key()
+	return &key, nil
+}
+
+func (o *fakeOperator) GetSiteOperation(ops.SiteOperationKey) (*ops.SiteOperation, error) {
+	if o.operation == nil {
+		return nil, trace.NotFound("operation not found")
+	}
+	return o.operation, nil
+}
+
+func (o *fakeOperator) DeleteSiteOperation(ops.SiteOperationKey) error {
+	o.operation = nil
+	return nil
+}
+
+func (o *fakeOperator) CreateOperationPlan(key ops.SiteOperationKey, plan storage.OperationPlan) error {
+	o.plan = plan
+	return nil
+}
+
+func (o *fakeOperator) GetOperationPlan(ops.SiteOperationKey) (*storage.OperationPlan, error) {
+	return o.resolvePlan(), nil
+}
+
+func (o *fakeOperator) CreateOperationPlanChange(key ops.SiteOperationKey, change storage.PlanChange) error {
+	// Order the changes explicitly as they can be created within
+	// the same clock tick
+	change.Created = time.Unix(int64(len(o.changelog)), 0)
+	o.changelog = append(o.changelog, change)
+	return nil
+}
+
+func (o *fakeOperator) CreateProgressEntry(ops.SiteOperationKey, ops.ProgressEntry) error {
+	return nil
+}
+
+func (o *fakeOperator) SetOperationState(key ops.SiteOperationKey, req ops.SetOperationStateRequest) error {
+	o.state = req.State
+	return nil
+}
+
+func (o *fakeOperator) resolvePlan() *storage.OperationPlan {
+	return libfsm.ResolvePlan(o.plan, o.changelog)
+}
+
+// fakeRunner records the commands executed on nodes and updates the phase
+// state the way the remote node does
+type fakeRunner struct {
+	operator *fakeOperator
+	// fail specifies the phase that fails to execute
+	fail     string
+	commands []string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, server storage.Server, args ...string) error {
+	r.commands = append(r.commands, fmt.Sprintf("%v: %v", server.Hostname, strings.Join(args, " ")))
+	if args[0] != "system" {
+		return nil
+	}
+	phaseID := args[3]
+	state := storage.OperationPhaseStateCompleted
+	if phaseID == r.fail {
+		state = storage.OperationPhaseStateFailed
+	}
+	r.operator.CreateOperationPlanChange(r.operator.operation.Key(), storage.PlanChange{
+		PhaseID:  phaseID,
+		NewState: state,
+	})
+	if state == storage.OperationPhaseStateFailed {
+		return trace.BadParameter("failed to execute phase %q", phaseID)
+	}
+	return nil
+}
+
+func (r *fakeRunner) CanExecute(context.Context, storage.Server) error {
+	return nil
+}
+
+func (r *fakeRunner) Close() error {
+	return nil
+}
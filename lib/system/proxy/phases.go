@@ -0,0 +1,261 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/kubernetes"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	kubeapi "k8s.io/client-go/kubernetes"
+)
+
+// newDrainExecutor returns a new executor that drains the node
+func newDrainExecutor(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*drainExecutor, error) {
+	op, err := newKubernetesOperation(params, client, logger)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &drainExecutor{kubernetesOperation: *op}, nil
+}
+
+// drainExecutor drains the node before its services are restarted
+type drainExecutor struct {
+	kubernetesOperation
+}
+
+// Execute drains the node
+func (p *drainExecutor) Execute(ctx context.Context) error {
+	return trace.Wrap(p.drain(ctx))
+}
+
+// Rollback uncordons the node
+func (p *drainExecutor) Rollback(ctx context.Context) error {
+	return trace.Wrap(p.uncordon(ctx))
+}
+
+// newUncordonExecutor returns a new executor that uncordons the node
+func newUncordonExecutor(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*uncordonExecutor, error) {
+	op, err := newKubernetesOperation(params, client, logger)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &uncordonExecutor{kubernetesOperation: *op}, nil
+}
+
+// uncordonExecutor uncordons the node after it has become healthy
+type uncordonExecutor struct {
+	kubernetesOperation
+}
+
+// Execute uncordons the node
+func (p *uncordonExecutor) Execute(ctx context.Context) error {
+	return trace.Wrap(p.uncordon(ctx))
+}
+
+// Rollback drains the node again so the previous proxy environment
+// is restored on a drained node
+func (p *uncordonExecutor) Rollback(ctx context.Context) error {
+	return trace.Wrap(p.drain(ctx))
+}
+
+func newKubernetesOperation(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*kubernetesOperation, error) {
+	if params.Phase.Data == nil || params.Phase.Data.Server == nil {
+		return nil, trace.NotFound("no server specified for phase %q", params.Phase.ID)
+	}
+	if client == nil {
+		return nil, trace.BadParameter("phase %q must be run from a master node (requires kubernetes client)",
+			params.Phase.ID)
+	}
+	return &kubernetesOperation{
+		FieldLogger: logger,
+		client:      client,
+		server:      *params.Phase.Data.Server,
+		servers:     params.Plan.Servers,
+	}, nil
+}
+
+// kubernetesOperation changes the scheduling state of the node
+type kubernetesOperation struct {
+	log.FieldLogger
+	client  *kubeapi.Clientset
+	server  storage.Server
+	servers []storage.Server
+}
+
+// PreCheck makes sure the phase is being executed on a master node
+func (p *kubernetesOperation) PreCheck(context.Context) error {
+	return trace.Wrap(libfsm.CheckMasterServer(p.servers))
+}
+
+// PostCheck is a no-op
+func (p *kubernetesOperation) PostCheck(context.Context) error {
+	return nil
+}
+
+func (p *kubernetesOperation) drain(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, defaults.DrainTimeout)
+	defer cancel()
+	err := utils.RetryWithInterval(ctx, utils.NewExponentialBackOff(defaults.DrainErrorTimeout), func() error {
+		return trace.Wrap(kubernetes.Drain(ctx, p.client, p.server.KubeNodeID()))
+	})
+	return trace.Wrap(err)
+}
+
+func (p *kubernetesOperation) uncordon(ctx context.Context) error {
+	err := kubernetes.SetUnschedulable(ctx, p.client.CoreV1().Nodes(), p.server.KubeNodeID(), false)
+	return trace.Wrap(err)
+}
+
+// newApplyExecutor returns a new executor that configures
+// the proxy environment on the node
+func newApplyExecutor(params libfsm.ExecutorParams, remote libfsm.Remote, logger log.FieldLogger) (*applyExecutor, error) {
+	op, err := newNodeOperation(params, remote, logger)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var data phaseData
+	if err := json.Unmarshal([]byte(params.Phase.Data.Data), &data); err != nil {
+		return nil, trace.Wrap(err, "failed to decode proxy environment for phase %q", params.Phase.ID)
+	}
+	return &applyExecutor{
+		nodeOperation: *op,
+		data:          data,
+	}, nil
+}
+
+// applyExecutor configures the proxy environment on the node
+type applyExecutor struct {
+	nodeOperation
+	data phaseData
+}
+
+// Execute configures the proxy environment on the node.
+// The services are restarted in a separate phase
+func (p *applyExecutor) Execute(ctx context.Context) error {
+	return trace.Wrap(p.node.configure(ctx, p.data.Env, p.progress))
+}
+
+// Rollback restores the previous proxy environment on the node and restarts
+// the services to pick it up
+func (p *applyExecutor) Rollback(ctx context.Context) error {
+	if err := p.node.configure(ctx, p.data.Previous, p.progress); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(p.node.restart(ctx, p.progress))
+}
+
+// newRestartExecutor returns a new executor that restarts the services
+// on the node to pick up the proxy environment
+func newRestartExecutor(params libfsm.ExecutorParams, remote libfsm.Remote, logger log.FieldLogger) (*restartExecutor, error) {
+	op, err := newNodeOperation(params, remote, logger)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &restartExecutor{nodeOperation: *op}, nil
+}
+
+// restartExecutor restarts the services on the node
+type restartExecutor struct {
+	nodeOperation
+}
+
+// Execute restarts the services on the node
+func (p *restartExecutor) Execute(ctx context.Context) error {
+	return trace.Wrap(p.node.restart(ctx, p.progress))
+}
+
+// Rollback is a no-op: the services are restarted when the previous
+// proxy environment is restored
+func (p *restartExecutor) Rollback(context.Context) error {
+	return nil
+}
+
+// newHealthExecutor returns a new executor that waits for the node
+// to become healthy
+func newHealthExecutor(params libfsm.ExecutorParams, remote libfsm.Remote, logger log.FieldLogger) (*healthExecutor, error) {
+	op, err := newNodeOperation(params, remote, logger)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &healthExecutor{nodeOperation: *op}, nil
+}
+
+// healthExecutor waits for the node to become healthy
+type healthExecutor struct {
+	nodeOperation
+}
+
+// Execute waits for the node to become healthy
+func (p *healthExecutor) Execute(ctx context.Context) error {
+	err := utils.RetryWithInterval(ctx, utils.NewExponentialBackOff(defaults.NodeHealthWaitTimeout), func() error {
+		return trace.Wrap(p.node.checkHealth(ctx))
+	})
+	return trace.Wrap(err)
+}
+
+// Rollback is a no-op
+func (p *healthExecutor) Rollback(context.Context) error {
+	return nil
+}
+
+func newNodeOperation(params libfsm.ExecutorParams, remote libfsm.Remote, logger log.FieldLogger) (*nodeOperation, error) {
+	if params.Phase.Data == nil || params.Phase.Data.Server == nil {
+		return nil, trace.NotFound("no server specified for phase %q", params.Phase.ID)
+	}
+	node, err := newNode()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	progress := params.Progress
+	if progress == nil {
+		progress = utils.NewNopProgress()
+	}
+	return &nodeOperation{
+		FieldLogger: logger,
+		node:        node,
+		server:      *params.Phase.Data.Server,
+		remote:      remote,
+		progress:    progress,
+	}, nil
+}
+
+// nodeOperation configures the proxy environment on the local node
+type nodeOperation struct {
+	log.FieldLogger
+	node     *node
+	server   storage.Server
+	remote   libfsm.Remote
+	progress utils.Progress
+}
+
+// PreCheck makes sure the phase is being executed on the correct node
+func (p *nodeOperation) PreCheck(ctx context.Context) error {
+	return trace.Wrap(p.remote.CheckServer(ctx, p.server))
+}
+
+// PostCheck is a no-op
+func (p *nodeOperation) PostCheck(context.Context) error {
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// NewOperationPlan returns a new plan for the specified proxy update operation.
+// The nodes are updated one at a time: each node is drained, configured with
+// the proxy environment, has its services restarted and is uncordoned
+// once it is healthy again.
+// env is the proxy environment to apply and previous is the environment
+// to restore when the operation is rolled back
+func NewOperationPlan(operation ops.SiteOperation, servers []storage.Server, env, previous map[string]string) (*storage.OperationPlan, error) {
+	masters, _ := libfsm.SplitServers(servers)
+	if len(masters) == 0 {
+		return nil, trace.NotFound("no master servers found in cluster state")
+	}
+
+	data, err := json.Marshal(phaseData{Env: env, Previous: previous})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	builder := phaseBuilder{leadMaster: masters[0], data: string(data)}
+	nodes := *builder.nodes(servers)
+
+	plan := &storage.OperationPlan{
+		OperationID:   operation.ID,
+		OperationType: operation.Type,
+		AccountID:     operation.AccountID,
+		ClusterName:   operation.SiteDomain,
+		Phases:        phases{nodes}.asPhases(),
+		Servers:       servers,
+	}
+
+	return plan, nil
+}
+
+// phaseData is the proxy environment passed to the phases
+// that configure it on nodes
+type phaseData struct {
+	// Env is the proxy environment to apply
+	Env map[string]string `json:"env,omitempty"`
+	// Previous is the proxy environment to restore on rollback
+	Previous map[string]string `json:"previous,omitempty"`
+}
+
+func (r phaseBuilder) nodes(servers []storage.Server) *phase {
+	root := root(phase{
+		ID:          NodesPhase,
+		Description: "Update proxy environment on cluster nodes",
+	})
+
+	for _, server := range servers {
+		node := r.node(server, root, "Update proxy environment on node %q")
+		node.AddSequential(
+			r.drain(server, node),
+			r.apply(server, node),
+			r.restart(server, node),
+			r.health(server, node),
+			r.uncordon(server, node),
+		)
+		root.AddSequential(node)
+	}
+	return &root
+}
+
+func (r phaseBuilder) drain(server storage.Server, parent phase) phase {
+	return phase{
+		ID:          parent.ChildLiteral(DrainPhase),
+		Description: fmt.Sprintf("Drain node %q", server.Hostname),
+		Data: &storage.OperationPhaseData{
+			Server:     &server,
+			ExecServer: &r.leadMaster,
+		},
+	}
+}
+
+func (r phaseBuilder) apply(server storage.Server, parent phase) phase {
+	return phase{
+		ID:          parent.ChildLiteral(ApplyPhase),
+		Description: fmt.Sprintf("Configure proxy environment on node %q", server.Hostname),
+		Data: &storage.OperationPhaseData{
+			Server: &server,
+			Data:   r.data,
+		},
+	}
+}
+
+func (r phaseBuilder) restart(server storage.Server, parent phase) phase {
+	return phase{
+		ID:          parent.ChildLiteral(RestartPhase),
+		Description: fmt.Sprintf("Restart services on node %q", server.Hostname),
+		Data: &storage.OperationPhaseData{
+			Server: &server,
+		},
+	}
+}
+
+func (r phaseBuilder) health(server storage.Server, parent phase) phase {
+	return phase{
+		ID:          parent.ChildLiteral(HealthPhase),
+		Description: fmt.Sprintf("Wait for node %q to become healthy", server.Hostname),
+		Data: &storage.OperationPhaseData{
+			Server: &server,
+		},
+	}
+}
+
+func (r phaseBuilder) uncordon(server storage.Server, parent phase) phase {
+	return phase{
+		ID:          parent.ChildLiteral(UncordonPhase),
+		Description: fmt.Sprintf("Uncordon node %q", server.Hostname),
+		Data: &storage.OperationPhaseData{
+			Server:     &server,
+			ExecServer: &r.leadMaster,
+		},
+	}
+}
+
+func (r phaseBuilder) node(server storage.Server, parent phase, format string) phase {
+	return phase{
+		ID:          parent.ChildLiteral(server.Hostname),
+		Description: fmt.Sprintf(format, server.Hostname),
+	}
+}
+
+type phaseBuilder struct {
+	// leadMaster is the master server that executes kubernetes phases
+	leadMaster storage.Server
+	// data is the encoded proxy environment
+	data string
+}
+
+// AddSequential will append sub-phases which depend one upon another
+func (p *phase) AddSequential(sub ...phase) {
+	for i := range sub {
+		if len(p.Phases) > 0 {
+			sub[i].Require(phase(p.Phases[len(p.Phases)-1]))
+		}
+		p.Phases = append(p.Phases, storage.OperationPhase(sub[i]))
+	}
+}
+
+// Require adds the specified phases reqs as requirements for this phase
+func (p *phase) Require(reqs ...phase) *phase {
+	for _, req := range reqs {
+		p.Requires = append(p.Requires, req.ID)
+	}
+	return p
+}
+
+// ChildLiteral adds the specified sub phase ID as a child of this phase
+// and returns the resulting path
+func (p *phase) ChildLiteral(sub string) string {
+	if p == nil {
+		return path.Join("/", sub)
+	}
+	return path.Join(p.ID, sub)
+}
+
+// Root makes the specified phase root
+func root(sub phase) phase {
+	sub.ID = path.Join("/", sub.ID)
+	return sub
+}
+
+type phase storage.OperationPhase
+
+func (r phases) asPhases() (result []storage.OperationPhase) {
+	result = make([]storage.OperationPhase, 0, len(r))
+	for _, phase := range r {
+		result = append(result, storage.OperationPhase(phase))
+	}
+	return result
+}
+
+type phases []phase
+
+const (
+	// NodesPhase is the root phase that updates the proxy environment on nodes
+	NodesPhase = "nodes"
+	// DrainPhase drains the node
+	DrainPhase = "drain"
+	// ApplyPhase configures the proxy environment on the node
+	ApplyPhase = "apply"
+	// RestartPhase restarts the services on the node
+	RestartPhase = "restart"
+	// HealthPhase waits for the node to become healthy
+	HealthPhase = "health"
+	// UncordonPhase uncordons the node
+	UncordonPhase = "uncordon"
+)
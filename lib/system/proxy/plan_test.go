@@ -0,0 +1,135 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type PlanSuite struct{}
+
+var _ = Suite(&PlanSuite{})
+
+func (PlanSuite) TestUpdatesNodesOneAtATime(c *C) {
+	operation := ops.SiteOperation{
+		ID:         "1",
+		AccountID:  "0",
+		Type:       ops.OperationUpdateProxy,
+		SiteDomain: "cluster",
+	}
+	servers := []storage.Server{
+		{Hostname: "node-1", ClusterRole: string(schema.ServiceRoleMaster)},
+		{Hostname: "node-2", ClusterRole: string(schema.ServiceRoleNode)},
+	}
+	env := map[string]string{"HTTP_PROXY": "http://proxy:3128"}
+	previous := map[string]string{"HTTP_PROXY": "http://old:3128"}
+
+	plan, err := NewOperationPlan(operation, servers, env, previous)
+	c.Assert(err, IsNil)
+
+	data := `{"env":{"HTTP_PROXY":"http://proxy:3128"},"previous":{"HTTP_PROXY":"http://old:3128"}}`
+	c.Assert(plan, compare.DeepEquals, &storage.OperationPlan{
+		OperationID:   operation.ID,
+		OperationType: operation.Type,
+		AccountID:     operation.AccountID,
+		ClusterName:   operation.SiteDomain,
+		Servers:       servers,
+		Phases: []storage.OperationPhase{
+			{
+				ID:          "/nodes",
+				Description: "Update proxy environment on cluster nodes",
+				Phases: []storage.OperationPhase{
+					nodePhases(servers[0], servers[0], data),
+					func() storage.OperationPhase {
+						phase := nodePhases(servers[1], servers[0], data)
+						phase.Requires = []string{"/nodes/node-1"}
+						return phase
+					}(),
+				},
+			},
+		},
+	})
+}
+
+func (PlanSuite) TestRequiresMaster(c *C) {
+	operation := ops.SiteOperation{ID: "1", Type: ops.OperationUpdateProxy}
+	servers := []storage.Server{
+		{Hostname: "node-1", ClusterRole: string(schema.ServiceRoleNode)},
+	}
+
+	_, err := NewOperationPlan(operation, servers, nil, nil)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+}
+
+// nodePhases returns the phases that update the proxy environment on server
+func nodePhases(server, master storage.Server, data string) storage.OperationPhase {
+	id := "/nodes/" + server.Hostname
+	return storage.OperationPhase{
+		ID:          id,
+		Description: `Update proxy environment on node "` + server.Hostname + `"`,
+		Phases: []storage.OperationPhase{
+			{
+				ID:          id + "/drain",
+				Description: `Drain node "` + server.Hostname + `"`,
+				Data: &storage.OperationPhaseData{
+					Server:     &server,
+					ExecServer: &master,
+				},
+			},
+			{
+				ID:          id + "/apply",
+				Description: `Configure proxy environment on node "` + server.Hostname + `"`,
+				Requires:    []string{id + "/drain"},
+				Data: &storage.OperationPhaseData{
+					Server: &server,
+					Data:   data,
+				},
+			},
+			{
+				ID:          id + "/restart",
+				Description: `Restart services on node "` + server.Hostname + `"`,
+				Requires:    []string{id + "/apply"},
+				Data: &storage.OperationPhaseData{
+					Server: &server,
+				},
+			},
+			{
+				ID:          id + "/health",
+				Description: `Wait for node "` + server.Hostname + `" to become healthy`,
+				Requires:    []string{id + "/restart"},
+				Data: &storage.OperationPhaseData{
+					Server: &server,
+				},
+			},
+			{
+				ID:          id + "/uncordon",
+				Description: `Uncordon node "` + server.Hostname + `"`,
+				Requires:    []string{id + "/health"},
+				Data: &storage.OperationPhaseData{
+					Server:     &server,
+					ExecServer: &master,
+				},
+			},
+		},
+	}
+}
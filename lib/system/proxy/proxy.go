@@ -0,0 +1,295 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package proxy configures the cluster HTTP proxy environment for
+// the gravity services on the host and the services inside planet
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/state"
+	"github.com/gravitational/gravity/lib/systemservice"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// Apply configures the proxy environment on the local node and restarts
+// the services to pick up the change. Empty env removes the previously
+// configured proxy environment
+func Apply(ctx context.Context, env map[string]string, progress utils.Progress) error {
+	node, err := newNode()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := node.configure(ctx, env, progress); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(node.restart(ctx, progress))
+}
+
+func newNode() (*node, error) {
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	services, restartServices, err := hostServices()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &node{
+		root:            "/",
+		shareDir:        state.ShareDir(stateDir),
+		services:        services,
+		restartServices: restartServices,
+		run: func(ctx context.Context, args ...string) ([]byte, error) {
+			return utils.RunCommand(ctx, nil, args...)
+		},
+		runPlanet: func(ctx context.Context, args ...string) ([]byte, error) {
+			return utils.RunInPlanetCommand(ctx, nil, args...)
+		},
+		status: func(ctx context.Context) ([]byte, error) {
+			return utils.RunPlanetCommand(ctx, nil, "status", "--local")
+		},
+	}, nil
+}
+
+// node configures the proxy environment on a cluster node
+type node struct {
+	// root is the root directory of the node filesystem
+	root string
+	// shareDir is the host directory shared with planet
+	shareDir string
+	// services lists the gravity systemd units on host
+	services []string
+	// restartServices lists the gravity systemd units on host
+	// that are restarted to pick up the proxy environment
+	restartServices []string
+	// run executes the command specified with args on host
+	run func(ctx context.Context, args ...string) ([]byte, error)
+	// runPlanet executes the command specified with args inside planet
+	runPlanet func(ctx context.Context, args ...string) ([]byte, error)
+	// status queries the health of the local planet node
+	status func(ctx context.Context) ([]byte, error)
+}
+
+// configure writes the proxy environment for the gravity services on host
+// and the services inside planet without restarting them
+func (r *node) configure(ctx context.Context, env map[string]string, progress utils.Progress) error {
+	if err := r.updateHostServices(ctx, env, progress); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := r.updatePlanetEnvironment(ctx, env, progress); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := r.updatePlanetServices(ctx, env, progress); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// restart restarts docker and kubelet inside planet and the gravity
+// services on host to pick up the proxy environment
+func (r *node) restart(ctx context.Context, progress utils.Progress) error {
+	for _, service := range planetServices {
+		out, err := r.runPlanet(ctx, "systemctl", "restart", service)
+		if err != nil {
+			return trace.Wrap(err, "failed to restart %v: %s", service, out)
+		}
+	}
+	if len(r.restartServices) != 0 {
+		args := append([]string{"systemctl", "try-restart"}, r.restartServices...)
+		out, err := r.run(ctx, args...)
+		if err != nil {
+			return trace.Wrap(err, "failed to restart gravity services: %s", out)
+		}
+	}
+	progress.PrintInfo("Restarted services to pick up the proxy environment")
+	return nil
+}
+
+// checkHealth makes sure the local planet node is healthy
+func (r *node) checkHealth(ctx context.Context) error {
+	out, err := r.status(ctx)
+	if err != nil {
+		return trace.Wrap(err, "node is not healthy: %s", out)
+	}
+	return nil
+}
+
+// updateHostServices writes the proxy drop-in for the gravity services on host
+// and reloads the systemd configuration
+func (r *node) updateHostServices(ctx context.Context, env map[string]string, progress utils.Progress) error {
+	dropIn := formatDropIn(env)
+	for _, service := range r.services {
+		path := filepath.Join(r.root, dropInPath(service))
+		if err := utils.WriteOrRemovePath(path, dropIn); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	out, err := r.run(ctx, "systemctl", "daemon-reload")
+	if err != nil {
+		return trace.Wrap(err, "failed to reload systemd configuration: %s", out)
+	}
+	progress.PrintInfo("Updated proxy environment of gravity services")
+	return nil
+}
+
+// updatePlanetEnvironment replaces the proxy variables in the planet
+// container environment
+func (r *node) updatePlanetEnvironment(ctx context.Context, env map[string]string, progress utils.Progress) error {
+	out, err := r.runPlanet(ctx, "cat", defaults.ContainerEnvironmentFile)
+	if err != nil {
+		return trace.Wrap(err, "failed to read planet environment: %s", out)
+	}
+	containerEnv, err := utils.ParseEnv(bytes.NewReader(out))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, name := range envNames {
+		delete(containerEnv, name)
+		delete(containerEnv, strings.ToLower(name))
+	}
+	for name, value := range env {
+		containerEnv[name] = value
+	}
+	if err := utils.WriteOrRemovePath(filepath.Join(r.shareDir, defaults.ProxyEnvFile), formatEnv(containerEnv)); err != nil {
+		return trace.Wrap(err)
+	}
+	out, err = r.runPlanet(ctx, "cp", filepath.Join(defaults.PlanetShareDir, defaults.ProxyEnvFile),
+		defaults.ContainerEnvironmentFile)
+	if err != nil {
+		return trace.Wrap(err, "failed to update planet environment: %s", out)
+	}
+	return nil
+}
+
+// updatePlanetServices writes the proxy drop-in for docker and kubelet
+// inside planet and reloads the systemd configuration
+func (r *node) updatePlanetServices(ctx context.Context, env map[string]string, progress utils.Progress) error {
+	dropIn := formatDropIn(env)
+	if err := utils.WriteOrRemovePath(filepath.Join(r.shareDir, defaults.ProxyDropInFile), dropIn); err != nil {
+		return trace.Wrap(err)
+	}
+	var commands [][]string
+	for _, service := range planetServices {
+		path := dropInPath(service)
+		if len(dropIn) == 0 {
+			commands = append(commands, []string{"rm", "-f", path})
+			continue
+		}
+		commands = append(commands,
+			[]string{"mkdir", "-p", filepath.Dir(path)},
+			[]string{"cp", filepath.Join(defaults.PlanetShareDir, defaults.ProxyDropInFile), path})
+	}
+	commands = append(commands, []string{"systemctl", "daemon-reload"})
+	for _, args := range commands {
+		out, err := r.runPlanet(ctx, args...)
+		if err != nil {
+			return trace.Wrap(err, "failed to update planet services: %s", out)
+		}
+	}
+	progress.PrintInfo("Updated planet proxy environment")
+	return nil
+}
+
+// hostServices returns the gravity systemd units installed on host along with
+// the units that are restarted to pick up the proxy environment.
+// The agent is not restarted as it executes the command itself and picks up
+// the environment once it is redeployed, and the runtime container is not
+// restarted as docker and kubelet inside it are restarted individually
+func hostServices() (units, restartUnits []string, err error) {
+	services, err := systemservice.New()
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	packageServices, err := services.ListPackageServices()
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	units = []string{defaults.GravityRPCAgentServiceName}
+	for _, service := range packageServices {
+		unit := systemservice.PackageServiceName(service.Package)
+		units = append(units, unit)
+		if !isRuntimePackage(service.Package) {
+			restartUnits = append(restartUnits, unit)
+		}
+	}
+	return units, restartUnits, nil
+}
+
+func isRuntimePackage(locator loc.Locator) bool {
+	return locator.Name == constants.PlanetPackage || loc.IsLegacyRuntimePackage(locator)
+}
+
+// formatDropIn returns the systemd drop-in that sets the specified environment
+// or nothing if the environment is empty
+func formatDropIn(env map[string]string) []byte {
+	if len(env) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.WriteString("[Service]\n")
+	for _, name := range sortedKeys(env) {
+		fmt.Fprintf(&buf, "Environment=\"%v=%v\"\n", name, env[name])
+	}
+	return buf.Bytes()
+}
+
+// formatEnv formats the environment in the format of /etc/environment
+func formatEnv(env map[string]string) []byte {
+	var buf bytes.Buffer
+	for _, name := range sortedKeys(env) {
+		fmt.Fprintf(&buf, "%v=%v\n", name, env[name])
+	}
+	return buf.Bytes()
+}
+
+func sortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dropInPath returns the path to the proxy drop-in for the specified systemd unit
+func dropInPath(service string) string {
+	return filepath.Join(defaults.InSystemUnitDir(service+".d"), defaults.ProxyDropInFile)
+}
+
+// envNames lists the proxy environment variables
+var envNames = []string{
+	constants.HTTPProxyEnvVar,
+	constants.HTTPSProxyEnvVar,
+	constants.NoProxyEnvVar,
+}
+
+// planetServices lists the planet services that make outbound requests
+var planetServices = []string{"docker.service", "kube-kubelet.service"}
+
+const header = "# This file is generated by gravity from the cluster proxy configuration. Do not edit.\n"
@@ -0,0 +1,156 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestProxy(t *testing.T) { TestingT(t) }
+
+type ProxySuite struct {
+	node           *node
+	commands       []string
+	planetCommands []string
+	statusErr      error
+}
+
+var _ = Suite(&ProxySuite{})
+
+func (s *ProxySuite) SetUpTest(c *C) {
+	s.commands = nil
+	s.planetCommands = nil
+	s.statusErr = nil
+	root := c.MkDir()
+	s.node = &node{
+		root:            root,
+		shareDir:        filepath.Join(root, "share"),
+		services:        []string{"gravity-agent.service", "gravity__gravitational.io__teleport__3.0.0.service"},
+		restartServices: []string{"gravity__gravitational.io__teleport__3.0.0.service"},
+		run: func(ctx context.Context, args ...string) ([]byte, error) {
+			s.commands = append(s.commands, strings.Join(args, " "))
+			return nil, nil
+		},
+		runPlanet: func(ctx context.Context, args ...string) ([]byte, error) {
+			s.planetCommands = append(s.planetCommands, strings.Join(args, " "))
+			if args[0] == "cat" {
+				return []byte("KUBE_MASTER_IP=192.168.1.1\nhttp_proxy=http://old:3128\n"), nil
+			}
+			return nil, nil
+		},
+		status: func(ctx context.Context) ([]byte, error) {
+			if s.statusErr != nil {
+				return []byte("degraded"), s.statusErr
+			}
+			return nil, nil
+		},
+	}
+}
+
+func (s *ProxySuite) TestConfiguresEnvWithoutRestart(c *C) {
+	env := map[string]string{
+		"HTTP_PROXY": "http://proxy:3128",
+		"NO_PROXY":   "localhost,.cluster.local",
+	}
+	err := s.node.configure(context.TODO(), env, utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	c.Assert(s.commands, DeepEquals, []string{"systemctl daemon-reload"})
+	c.Assert(s.planetCommands, DeepEquals, []string{
+		"cat /etc/container-environment",
+		"cp /ext/share/container-environment /etc/container-environment",
+		"mkdir -p /etc/systemd/system/docker.service.d",
+		"cp /ext/share/gravity-proxy.conf /etc/systemd/system/docker.service.d/gravity-proxy.conf",
+		"mkdir -p /etc/systemd/system/kube-kubelet.service.d",
+		"cp /ext/share/gravity-proxy.conf /etc/systemd/system/kube-kubelet.service.d/gravity-proxy.conf",
+		"systemctl daemon-reload",
+	})
+	dropIn := header + `[Service]
+Environment="HTTP_PROXY=http://proxy:3128"
+Environment="NO_PROXY=localhost,.cluster.local"
+`
+	for _, service := range s.node.services {
+		s.assertFile(c, filepath.Join(s.node.root, dropInPath(service)), dropIn)
+	}
+	s.assertFile(c, filepath.Join(s.node.shareDir, "gravity-proxy.conf"), dropIn)
+	s.assertFile(c, filepath.Join(s.node.shareDir, "container-environment"),
+		"HTTP_PROXY=http://proxy:3128\nKUBE_MASTER_IP=192.168.1.1\nNO_PROXY=localhost,.cluster.local\n")
+}
+
+func (s *ProxySuite) TestRemovesEnv(c *C) {
+	err := s.node.configure(context.TODO(), map[string]string{"HTTP_PROXY": "http://proxy:3128"}, utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	s.planetCommands = nil
+
+	err = s.node.configure(context.TODO(), nil, utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	c.Assert(s.planetCommands, DeepEquals, []string{
+		"cat /etc/container-environment",
+		"cp /ext/share/container-environment /etc/container-environment",
+		"rm -f /etc/systemd/system/docker.service.d/gravity-proxy.conf",
+		"rm -f /etc/systemd/system/kube-kubelet.service.d/gravity-proxy.conf",
+		"systemctl daemon-reload",
+	})
+	for _, service := range s.node.services {
+		_, err = os.Stat(filepath.Join(s.node.root, dropInPath(service)))
+		c.Assert(os.IsNotExist(err), Equals, true)
+	}
+	s.assertFile(c, filepath.Join(s.node.shareDir, "container-environment"), "KUBE_MASTER_IP=192.168.1.1\n")
+}
+
+func (s *ProxySuite) TestRestartsPlanetAndHostServices(c *C) {
+	err := s.node.restart(context.TODO(), utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	c.Assert(s.planetCommands, DeepEquals, []string{
+		"systemctl restart docker.service",
+		"systemctl restart kube-kubelet.service",
+	})
+	c.Assert(s.commands, DeepEquals, []string{
+		"systemctl try-restart gravity__gravitational.io__teleport__3.0.0.service",
+	})
+}
+
+func (s *ProxySuite) TestSkipsHostRestartWithoutServices(c *C) {
+	s.node.restartServices = nil
+	err := s.node.restart(context.TODO(), utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	c.Assert(s.commands, HasLen, 0)
+}
+
+func (s *ProxySuite) TestChecksHealth(c *C) {
+	c.Assert(s.node.checkHealth(context.TODO()), IsNil)
+
+	s.statusErr = trace.BadParameter("exit status 1")
+	err := s.node.checkHealth(context.TODO())
+	c.Assert(err, NotNil)
+	c.Assert(err, ErrorMatches, "(?s).*node is not healthy: degraded.*")
+}
+
+func (s *ProxySuite) assertFile(c *C, path, expected string) {
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, expected)
+}
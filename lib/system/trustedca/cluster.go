@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trustedca
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// ClusterConfig defines the configuration to install trusted certificate
+// authorities on cluster nodes
+type ClusterConfig struct {
+	// CA is the trusted certificate authorities to install.
+	// If nil, the previously installed certificate authorities are removed
+	CA storage.TrustedCA
+	// Servers lists the nodes to install the certificate authorities on
+	Servers []storage.Server
+	// Remote executes commands on the nodes
+	Remote fsm.RemoteRunner
+	// Progress reports the progress
+	utils.Progress
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (r *ClusterConfig) CheckAndSetDefaults() error {
+	if r.Remote == nil {
+		return trace.BadParameter("missing remote runner")
+	}
+	if r.Progress == nil {
+		r.Progress = utils.NewNopProgress()
+	}
+	return nil
+}
+
+// ApplyCluster installs the trusted certificate authorities on the specified
+// cluster nodes one node at a time as docker is restarted on each node.
+// Stops at the first node that fails
+func ApplyCluster(ctx context.Context, config ClusterConfig) error {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	var bundle []byte
+	if config.CA != nil {
+		bundle = []byte(config.CA.GetCertificates())
+	}
+	args := CommandArgs(bundle)
+	for _, server := range config.Servers {
+		config.PrintInfo("Updating trusted certificate authorities on %v (%v)",
+			server.Hostname, server.AdvertiseIP)
+		err := config.Remote.Run(ctx, server, args...)
+		if err != nil {
+			return trace.Wrap(err, "failed to update trusted certificate authorities on %v",
+				server.AdvertiseIP)
+		}
+	}
+	return nil
+}
+
+// CommandArgs returns the gravity command line that installs the specified
+// CA bundle on a node
+func CommandArgs(bundle []byte) []string {
+	return []string{"system", "apply-trustedca",
+		fmt.Sprintf("--bundle=%v", base64.StdEncoding.EncodeToString(bundle))}
+}
+
+// DecodeBundle decodes the CA bundle encoded with CommandArgs
+func DecodeBundle(bundle string) ([]byte, error) {
+	bytes, err := base64.StdEncoding.DecodeString(bundle)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return bytes, nil
+}
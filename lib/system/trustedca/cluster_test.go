@@ -0,0 +1,108 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trustedca
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type ClusterSuite struct{}
+
+var _ = Suite(&ClusterSuite{})
+
+func (s *ClusterSuite) TestInstallsBundleOnNodesInOrder(c *C) {
+	remote := &fakeRunner{}
+	err := ApplyCluster(context.TODO(), ClusterConfig{
+		CA:      storage.NewTrustedCA([]byte(bundle)),
+		Servers: servers,
+		Remote:  remote,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(remote.servers, DeepEquals, []string{"10.255.0.1", "10.255.0.2", "10.255.0.3"})
+	c.Assert(remote.bundles, DeepEquals, []string{bundle, bundle, bundle})
+}
+
+func (s *ClusterSuite) TestStopsAtFailedNode(c *C) {
+	remote := &fakeRunner{
+		run: func(server storage.Server) error {
+			if server.AdvertiseIP == "10.255.0.2" {
+				return trace.ConnectionProblem(nil, "docker failed to restart")
+			}
+			return nil
+		},
+	}
+	err := ApplyCluster(context.TODO(), ClusterConfig{
+		CA:      storage.NewTrustedCA([]byte(bundle)),
+		Servers: servers,
+		Remote:  remote,
+	})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*10.255.0.2.*")
+	// the remaining nodes keep the docker daemon running
+	c.Assert(remote.servers, DeepEquals, []string{"10.255.0.1", "10.255.0.2"})
+}
+
+func (s *ClusterSuite) TestRemovesBundleWithoutCA(c *C) {
+	remote := &fakeRunner{}
+	err := ApplyCluster(context.TODO(), ClusterConfig{
+		Servers: servers,
+		Remote:  remote,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(remote.bundles, DeepEquals, []string{"", "", ""})
+}
+
+// fakeRunner records the bundles installed on the nodes
+type fakeRunner struct {
+	// run optionally returns the result of the command on the server
+	run     func(storage.Server) error
+	servers []string
+	bundles []string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, server storage.Server, args ...string) error {
+	bundle, err := DecodeBundle(strings.TrimPrefix(args[2], "--bundle="))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.servers = append(r.servers, server.AdvertiseIP)
+	r.bundles = append(r.bundles, string(bundle))
+	if r.run != nil {
+		return r.run(server)
+	}
+	return nil
+}
+
+func (r *fakeRunner) CanExecute(context.Context, storage.Server) error {
+	return nil
+}
+
+func (r *fakeRunner) Close() error {
+	return nil
+}
+
+var servers = []storage.Server{
+	{Hostname: "master-1", AdvertiseIP: "10.255.0.1", Role: "master"},
+	{Hostname: "master-2", AdvertiseIP: "10.255.0.2", Role: "master"},
+	{Hostname: "node-1", AdvertiseIP: "10.255.0.3", Role: "node"},
+}
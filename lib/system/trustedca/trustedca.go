@@ -0,0 +1,227 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package trustedca installs additional certificate authorities, e.g. the CA
+// of a TLS-intercepting proxy, into the trust stores of cluster nodes
+package trustedca
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/state"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// Apply installs the CA bundle into the trust stores of the host and the
+// planet container on the local node and restarts the services that
+// cache the trusted certificates. Empty bundle removes the previously installed
+// certificate authorities
+func Apply(ctx context.Context, bundle []byte, progress utils.Progress) error {
+	node, err := newNode()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return node.apply(ctx, bundle, progress)
+}
+
+// ApplyPlanetRootfs installs the certificate authorities persisted in the planet
+// share directory into the planet root filesystem specified with rootfs.
+// The root filesystem is replaced when the planet package is updated or rolled back,
+// so the certificate authorities are reinstalled before the new planet starts
+func ApplyPlanetRootfs(ctx context.Context, rootfs string) error {
+	node, err := newNode()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return node.applyPlanetRootfs(ctx, rootfs)
+}
+
+func newNode() (*node, error) {
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &node{
+		root:     "/",
+		shareDir: state.ShareDir(stateDir),
+		run: func(ctx context.Context, args ...string) ([]byte, error) {
+			return utils.RunCommand(ctx, nil, args...)
+		},
+		runPlanet: func(ctx context.Context, args ...string) ([]byte, error) {
+			return utils.RunInPlanetCommand(ctx, nil, args...)
+		},
+	}, nil
+}
+
+// node installs trusted certificate authorities on a cluster node
+type node struct {
+	// root is the root directory of the node filesystem
+	root string
+	// shareDir is the host directory shared with planet
+	shareDir string
+	// run executes the command specified with args on host
+	run func(ctx context.Context, args ...string) ([]byte, error)
+	// runPlanet executes the command specified with args inside planet
+	runPlanet func(ctx context.Context, args ...string) ([]byte, error)
+}
+
+func (r *node) apply(ctx context.Context, bundle []byte, progress utils.Progress) error {
+	if err := r.updateHostTrustStore(ctx, bundle, progress); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := r.updatePlanetTrustStore(ctx, bundle, progress); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := r.restartGravitySite(ctx, progress); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// applyPlanetRootfs installs the CA bundle from the share directory into
+// the trust store of the planet root filesystem at rootfs
+func (r *node) applyPlanetRootfs(ctx context.Context, rootfs string) error {
+	bundle, err := ioutil.ReadFile(filepath.Join(r.shareDir, defaults.TrustedCAFile))
+	if err != nil && !os.IsNotExist(err) {
+		return trace.ConvertSystemError(err)
+	}
+	path := filepath.Join(rootfs, defaults.PlanetTrustedCADir, defaults.TrustedCAName+".crt")
+	if len(bundle) == 0 {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			// nothing has been installed into this root filesystem
+			return nil
+		}
+	}
+	if err := utils.WriteOrRemovePath(path, bundle); err != nil {
+		return trace.Wrap(err)
+	}
+	out, err := r.run(ctx, "chroot", rootfs, "update-ca-certificates")
+	if err != nil {
+		return trace.Wrap(err, "failed to update planet trust store in %v: %s", rootfs, out)
+	}
+	return nil
+}
+
+// updateHostTrustStore installs the CA bundle into the trust store of the host
+func (r *node) updateHostTrustStore(ctx context.Context, bundle []byte, progress utils.Progress) error {
+	store := r.findHostTrustStore()
+	if store == nil {
+		progress.PrintWarn(nil, "Unsupported host trust store, install the trusted certificate authorities manually")
+		return nil
+	}
+	path := filepath.Join(r.root, store.dir, defaults.TrustedCAName+store.ext)
+	if err := utils.WriteOrRemovePath(path, bundle); err != nil {
+		return trace.Wrap(err)
+	}
+	out, err := r.run(ctx, store.update...)
+	if err != nil {
+		return trace.Wrap(err, "failed to update host trust store: %s", out)
+	}
+	progress.PrintInfo("Updated host trust store in %v", store.dir)
+	return nil
+}
+
+// updatePlanetTrustStore installs the CA bundle into the trust store of
+// the planet container using the shared directory and restarts docker
+// so that image pulls and containers started afterwards trust the bundle.
+// The copy in the shared directory outlives the planet root filesystem
+// and is used to reinstall the bundle when planet is updated
+func (r *node) updatePlanetTrustStore(ctx context.Context, bundle []byte, progress utils.Progress) error {
+	if err := utils.WriteOrRemovePath(filepath.Join(r.shareDir, defaults.TrustedCAFile), bundle); err != nil {
+		return trace.Wrap(err)
+	}
+	path := filepath.Join(defaults.PlanetTrustedCADir, defaults.TrustedCAName+".crt")
+	commands := [][]string{
+		{"rm", "-f", path},
+		{"update-ca-certificates"},
+		{"systemctl", "restart", "docker"},
+	}
+	if len(bundle) != 0 {
+		commands[0] = []string{"cp", filepath.Join(defaults.PlanetShareDir, defaults.TrustedCAFile), path}
+	}
+	for _, args := range commands {
+		out, err := r.runPlanet(ctx, args...)
+		if err != nil {
+			return trace.Wrap(err, "failed to update planet trust store: %s", out)
+		}
+	}
+	progress.PrintInfo("Updated planet trust store and restarted docker")
+	return nil
+}
+
+// restartGravitySite restarts the gravity-site containers running on the node.
+// gravity-site shares the trust store with planet but only loads the trusted
+// certificates on startup. The containers might survive the docker restart
+// if docker is configured to keep them running
+func (r *node) restartGravitySite(ctx context.Context, progress utils.Progress) error {
+	out, err := r.runPlanet(ctx, "docker", "ps", "--quiet", "--filter",
+		fmt.Sprintf("label=%v=%v", kubeContainerNameLabel, constants.GravityServiceName))
+	if err != nil {
+		return trace.Wrap(err, "failed to list %v containers: %s", constants.GravityServiceName, out)
+	}
+	containers := strings.Fields(string(out))
+	if len(containers) == 0 {
+		return nil
+	}
+	out, err = r.runPlanet(ctx, append([]string{"docker", "restart"}, containers...)...)
+	if err != nil {
+		return trace.Wrap(err, "failed to restart %v: %s", constants.GravityServiceName, out)
+	}
+	progress.PrintInfo("Restarted %v", constants.GravityServiceName)
+	return nil
+}
+
+func (r *node) findHostTrustStore() *trustStore {
+	for _, store := range hostTrustStores {
+		if _, err := os.Stat(filepath.Join(r.root, store.dir)); err == nil {
+			return &store
+		}
+	}
+	return nil
+}
+
+// trustStore describes the system trust store of a Linux distribution
+type trustStore struct {
+	// dir is the directory with local certificate authorities
+	dir string
+	// ext is the extension the certificate files are required to have
+	ext string
+	// update is the command that regenerates the trust store
+	update []string
+}
+
+// kubeContainerNameLabel is the docker label with the name of the container
+// in the kubernetes pod spec
+const kubeContainerNameLabel = "io.kubernetes.container.name"
+
+// hostTrustStores lists supported host trust stores
+var hostTrustStores = []trustStore{
+	// RHEL, CentOS, Fedora
+	{dir: "/etc/pki/ca-trust/source/anchors", ext: ".pem", update: []string{"update-ca-trust", "extract"}},
+	// SUSE
+	{dir: "/etc/pki/trust/anchors", ext: ".pem", update: []string{"update-ca-certificates"}},
+	// Debian, Ubuntu
+	{dir: "/usr/local/share/ca-certificates", ext: ".crt", update: []string{"update-ca-certificates"}},
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trustedca
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestTrustedCA(t *testing.T) { TestingT(t) }
+
+type TrustedCASuite struct {
+	node           *node
+	commands       []string
+	planetCommands []string
+	// containers lists the running gravity-site containers
+	containers string
+}
+
+var _ = Suite(&TrustedCASuite{})
+
+func (s *TrustedCASuite) SetUpTest(c *C) {
+	s.commands = nil
+	s.planetCommands = nil
+	s.containers = ""
+	root := c.MkDir()
+	s.node = &node{
+		root:     root,
+		shareDir: filepath.Join(root, "share"),
+		run: func(ctx context.Context, args ...string) ([]byte, error) {
+			s.commands = append(s.commands, strings.Join(args, " "))
+			return nil, nil
+		},
+		runPlanet: func(ctx context.Context, args ...string) ([]byte, error) {
+			s.planetCommands = append(s.planetCommands, strings.Join(args, " "))
+			if args[0] == "docker" && args[1] == "ps" {
+				return []byte(s.containers), nil
+			}
+			return nil, nil
+		},
+	}
+	c.Assert(os.MkdirAll(filepath.Join(root, "/etc/pki/ca-trust/source/anchors"), defaults.SharedDirMask), IsNil)
+}
+
+func (s *TrustedCASuite) TestInstallsBundle(c *C) {
+	err := s.node.apply(context.TODO(), []byte(bundle), utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	c.Assert(s.commands, DeepEquals, []string{"update-ca-trust extract"})
+	c.Assert(s.planetCommands, DeepEquals, []string{
+		"cp /ext/share/trusted-ca.pem /usr/local/share/ca-certificates/gravity-trusted-ca.crt",
+		"update-ca-certificates",
+		"systemctl restart docker",
+		listGravitySite,
+	})
+	s.assertFile(c, filepath.Join(s.node.root, "/etc/pki/ca-trust/source/anchors/gravity-trusted-ca.pem"), bundle)
+	s.assertFile(c, filepath.Join(s.node.shareDir, defaults.TrustedCAFile), bundle)
+}
+
+func (s *TrustedCASuite) TestRemovesBundle(c *C) {
+	err := s.node.apply(context.TODO(), []byte(bundle), utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	s.planetCommands = nil
+
+	err = s.node.apply(context.TODO(), nil, utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	c.Assert(s.planetCommands, DeepEquals, []string{
+		"rm -f /usr/local/share/ca-certificates/gravity-trusted-ca.crt",
+		"update-ca-certificates",
+		"systemctl restart docker",
+		listGravitySite,
+	})
+	for _, path := range []string{
+		filepath.Join(s.node.root, "/etc/pki/ca-trust/source/anchors/gravity-trusted-ca.pem"),
+		filepath.Join(s.node.shareDir, defaults.TrustedCAFile),
+	} {
+		_, err := os.Stat(path)
+		c.Assert(os.IsNotExist(err), Equals, true, Commentf(path))
+	}
+}
+
+func (s *TrustedCASuite) TestRestartsGravitySite(c *C) {
+	s.containers = "4f2a1c\n9b7e3d\n"
+	err := s.node.apply(context.TODO(), []byte(bundle), utils.NewNopProgress())
+	c.Assert(err, IsNil)
+	c.Assert(s.planetCommands[len(s.planetCommands)-2:], DeepEquals, []string{
+		listGravitySite,
+		"docker restart 4f2a1c 9b7e3d",
+	})
+}
+
+func (s *TrustedCASuite) TestReinstallsBundleInPlanetRootfs(c *C) {
+	rootfs := filepath.Join(s.node.root, "rootfs")
+	path := filepath.Join(rootfs, "/usr/local/share/ca-certificates/gravity-trusted-ca.crt")
+
+	// no bundle has been installed
+	err := s.node.applyPlanetRootfs(context.TODO(), rootfs)
+	c.Assert(err, IsNil)
+	c.Assert(s.commands, HasLen, 0)
+
+	err = utils.WriteOrRemovePath(filepath.Join(s.node.shareDir, defaults.TrustedCAFile), []byte(bundle))
+	c.Assert(err, IsNil)
+	err = s.node.applyPlanetRootfs(context.TODO(), rootfs)
+	c.Assert(err, IsNil)
+	c.Assert(s.commands, DeepEquals, []string{"chroot " + rootfs + " update-ca-certificates"})
+	s.assertFile(c, path, bundle)
+
+	// the bundle has been removed since the root filesystem was last used
+	s.commands = nil
+	err = utils.WriteOrRemovePath(filepath.Join(s.node.shareDir, defaults.TrustedCAFile), nil)
+	c.Assert(err, IsNil)
+	err = s.node.applyPlanetRootfs(context.TODO(), rootfs)
+	c.Assert(err, IsNil)
+	c.Assert(s.commands, DeepEquals, []string{"chroot " + rootfs + " update-ca-certificates"})
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), Equals, true)
+	c.Assert(s.planetCommands, HasLen, 0)
+}
+
+func (s *TrustedCASuite) TestEncodesBundle(c *C) {
+	args := CommandArgs([]byte(bundle))
+	c.Assert(args[:2], DeepEquals, []string{"system", "apply-trustedca"})
+	decoded, err := DecodeBundle(strings.TrimPrefix(args[2], "--bundle="))
+	c.Assert(err, IsNil)
+	c.Assert(string(decoded), Equals, bundle)
+}
+
+func (s *TrustedCASuite) assertFile(c *C, path, expected string) {
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, expected)
+}
+
+const listGravitySite = "docker ps --quiet --filter label=io.kubernetes.container.name=gravity-site"
+
+const bundle = `-----BEGIN CERTIFICATE-----
+MIIBfake
+-----END CERTIFICATE-----
+`
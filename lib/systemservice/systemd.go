@@ -124,6 +124,11 @@ func (u *systemdUnit) serviceName() string {
 		systemdServiceDelimiter) + systemdUnitFileSuffix
 }
 
+// PackageServiceName returns the name of the systemd unit for the specified package service
+func PackageServiceName(pkg loc.Locator) string {
+	return newSystemdUnit(pkg).serviceName()
+}
+
 func (u *systemdUnit) servicePath() string {
 	return filepath.Join(systemdUnitFileDir, u.serviceName())
 }
@@ -0,0 +1,322 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/helm"
+	"github.com/gravitational/gravity/lib/kubernetes"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/coreos/go-semver/semver"
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// APIDeprecationsRequest describes a request to scan for Kubernetes APIs
+// that are deprecated or removed in the Kubernetes version of the update
+type APIDeprecationsRequest struct {
+	// Client is the cluster Kubernetes client.
+	// Live cluster objects are not inspected if unspecified
+	Client kubeclient.Interface
+	// Apps is the application service with the update application
+	Apps app.Applications
+	// Packages is the package service with the update runtime packages
+	Packages pack.PackageService
+	// App is the update application
+	App app.Application
+}
+
+// CheckAPIDeprecations inventories live cluster objects and the resources
+// of the update application and returns the list of objects that use API versions
+// deprecated or removed in the Kubernetes version of the update runtime
+func CheckAPIDeprecations(req APIDeprecationsRequest) (kubernetes.APIFindings, error) {
+	version, err := getKubernetesVersion(req.Apps, req.Packages, req.App)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var releases []helm.ReleaseManifest
+	if req.Client != nil {
+		releases, err = helm.GetDeployedManifests(req.Client)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to list Helm releases")
+		}
+	}
+	objects, err := appObjects(req.Apps, req.App, releases)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if req.Client != nil {
+		liveObjects, err := kubernetes.ListDeprecationCandidates(req.Client)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to list cluster objects")
+		}
+		objects = append(objects, liveObjects...)
+		releaseObjects, err := helmReleaseObjects(releases)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to list Helm release objects")
+		}
+		objects = append(objects, releaseObjects...)
+	}
+	return kubernetes.FindDeprecatedAPIs(objects, *version), nil
+}
+
+// getKubernetesVersion returns the version of Kubernetes shipped with the runtime
+// of the specified application
+func getKubernetesVersion(apps app.Applications, packages pack.PackageService, application app.Application) (*semver.Version, error) {
+	runtime := application
+	if base := application.Manifest.Base(); base != nil {
+		baseApp, err := apps.GetApp(*base)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		runtime = *baseApp
+	}
+	runtimePackage, err := runtime.Manifest.DefaultRuntimePackage()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	label, err := getPackageLabel(constants.KubernetesVersionLabel, *runtimePackage, packages)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	version, err := semver.NewVersion(strings.TrimPrefix(label, "v"))
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid Kubernetes version %q of %v", label, runtimePackage)
+	}
+	return version, nil
+}
+
+// appObjects returns references to all Kubernetes objects defined
+// by the resources of the specified application, including Helm charts
+// rendered with the values of the deployed releases
+func appObjects(apps app.Applications, application app.Application, releases []helm.ReleaseManifest) (objects []kubernetes.ObjectRef, err error) {
+	reader, err := apps.GetAppResources(application.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+	err = utils.WithTempDir(func(dir string) error {
+		err := dockerarchive.Untar(reader, dir, archive.DefaultOptions())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		objects, err = objectsFromDir(filepath.Join(dir, defaults.ResourcesDir), releases)
+		return trace.Wrap(err)
+	}, "resources")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return objects, nil
+}
+
+// helmReleaseObjects returns references to the Kubernetes objects of the
+// specified deployed Helm releases.
+// Helm does not record the last applied configuration on the objects it creates
+// so the API versions are determined from the release manifests instead
+func helmReleaseObjects(manifests []helm.ReleaseManifest) (objects []kubernetes.ObjectRef, err error) {
+	for _, manifest := range manifests {
+		refs, err := releaseObjects(manifest)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		objects = append(objects, refs...)
+	}
+	return objects, nil
+}
+
+// releaseObjects returns references to the Kubernetes objects
+// of the specified Helm release manifest
+func releaseObjects(manifest helm.ReleaseManifest) (objects []kubernetes.ObjectRef, err error) {
+	source := fmt.Sprintf("release %v", manifest.Name)
+	err = resources.ForEachObject(strings.NewReader(manifest.Manifest), func(object runtime.Object) error {
+		ref := newObjectRef(object, source)
+		if ref.Kind == "" {
+			// skip empty documents
+			return nil
+		}
+		if ref.Namespace == "" {
+			ref.Namespace = manifest.Namespace
+		}
+		objects = append(objects, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse manifest of release %v", manifest.Name)
+	}
+	return objects, nil
+}
+
+// objectsFromDir returns references to the Kubernetes objects defined by
+// the resources in the specified directory.
+// Helm charts are rendered with the values of each deployed release of the chart
+// so that the objects enabled with release values are included, or with the
+// default values if the chart has not been deployed
+func objectsFromDir(root string, releases []helm.ReleaseManifest) (objects []kubernetes.ObjectRef, err error) {
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		source, err := filepath.Rel(root, path)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		collect := func(object runtime.Object) error {
+			objects = append(objects, newObjectRef(object, source))
+			return nil
+		}
+		if fi.IsDir() {
+			chartFile := filepath.Join(path, constants.HelmChartFile)
+			if _, err := os.Stat(chartFile); err != nil {
+				return nil
+			}
+			chart, err := chartutil.LoadChartfile(chartFile)
+			if err != nil {
+				return trace.Wrap(err, "failed to load chart %v", source)
+			}
+			for _, params := range renderParameters(path, chart.Name, releases) {
+				out, err := renderChart(params)
+				if err != nil {
+					return trace.Wrap(err, "failed to render chart %v", source)
+				}
+				err = resources.ForEachObject(bytes.NewReader(out), func(object runtime.Object) error {
+					ref := newObjectRef(object, source)
+					if ref.Kind == "" {
+						// skip empty documents
+						return nil
+					}
+					if ref.Namespace == "" {
+						ref.Namespace = params.Namespace
+					}
+					objects = append(objects, ref)
+					return nil
+				})
+				if err != nil {
+					return trace.Wrap(err)
+				}
+			}
+			// chart templates are not valid resources on their own
+			return filepath.SkipDir
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		if err := resources.ForEachObjectInFile(path, collect); err != nil {
+			log.Debugf("Skipping %v: %v.", source, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return objects, nil
+}
+
+// chartRender describes a single rendering of a chart
+type chartRender struct {
+	helm.RenderParameters
+	// values is the YAML-encoded values to render the chart with
+	values string
+}
+
+// renderParameters returns the parameters to render the chart at the specified
+// path with: once for every deployed release of the chart, or once with the
+// default values if there are none
+func renderParameters(path, chartName string, releases []helm.ReleaseManifest) (result []chartRender) {
+	for _, release := range releases {
+		if release.Chart != chartName {
+			continue
+		}
+		result = append(result, chartRender{
+			RenderParameters: helm.RenderParameters{
+				Path:      path,
+				Name:      release.Name,
+				Namespace: release.Namespace,
+			},
+			values: release.Values,
+		})
+	}
+	if len(result) == 0 {
+		result = append(result, chartRender{RenderParameters: helm.RenderParameters{Path: path}})
+	}
+	return result
+}
+
+// renderChart renders the chart with the specified parameters
+func renderChart(params chartRender) (out []byte, err error) {
+	if params.values == "" {
+		return helm.Render(params.RenderParameters)
+	}
+	err = utils.WithTempDir(func(dir string) error {
+		valuesFile := filepath.Join(dir, "values.yaml")
+		if err := ioutil.WriteFile(valuesFile, []byte(params.values), defaults.SharedReadMask); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		params.Values = []string{valuesFile}
+		out, err = helm.Render(params.RenderParameters)
+		return trace.Wrap(err)
+	}, "values")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return out, nil
+}
+
+func newObjectRef(object runtime.Object, source string) kubernetes.ObjectRef {
+	kind := object.GetObjectKind().GroupVersionKind()
+	ref := kubernetes.ObjectRef{
+		APIVersion: kind.GroupVersion().String(),
+		Kind:       kind.Kind,
+		Source:     source,
+	}
+	if unknown, ok := object.(*resources.Unknown); ok {
+		var resource struct {
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(unknown.Raw, &resource); err == nil {
+			ref.Name = resource.Metadata.Name
+			ref.Namespace = resource.Metadata.Namespace
+		}
+		return ref
+	}
+	if accessor, err := meta.Accessor(object); err == nil {
+		ref.Name = accessor.GetName()
+		ref.Namespace = accessor.GetNamespace()
+	}
+	return ref
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/helm"
+	"github.com/gravitational/gravity/lib/kubernetes"
+
+	"gopkg.in/check.v1"
+)
+
+type APIVersionsSuite struct{}
+
+var _ = check.Suite(&APIVersionsSuite{})
+
+func (s *APIVersionsSuite) TestCollectsResourceObjects(c *check.C) {
+	dir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "resources.yaml"), []byte(appResources), 0644)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a resource"), 0644)
+	c.Assert(err, check.IsNil)
+
+	objects, err := objectsFromDir(dir, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(objects, check.DeepEquals, []kubernetes.ObjectRef{
+		{
+			APIVersion: "extensions/v1beta1",
+			Kind:       "Deployment",
+			Namespace:  "kube-system",
+			Name:       "app",
+			Source:     "resources.yaml",
+		},
+		{
+			APIVersion: "apiextensions.k8s.io/v1beta1",
+			Kind:       "CustomResourceDefinition",
+			Name:       "crontabs.stable.example.com",
+			Source:     "resources.yaml",
+		},
+	})
+}
+
+func (s *APIVersionsSuite) TestRendersChartsWithReleaseValues(c *check.C) {
+	dir := c.MkDir()
+	chartDir := filepath.Join(dir, "web")
+	c.Assert(os.MkdirAll(filepath.Join(chartDir, "templates"), 0755), check.IsNil)
+	for path, data := range map[string]string{
+		"Chart.yaml":             "name: web\nversion: 2.0.0\n",
+		"values.yaml":            "ingress:\n  enabled: false\n",
+		"templates/ingress.yaml": chartIngress,
+	} {
+		err := ioutil.WriteFile(filepath.Join(chartDir, path), []byte(data), 0644)
+		c.Assert(err, check.IsNil)
+	}
+
+	objects, err := objectsFromDir(dir, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(objects, check.HasLen, 0)
+
+	objects, err = objectsFromDir(dir, []helm.ReleaseManifest{
+		{Name: "web", Namespace: "web", Chart: "web", Values: "ingress:\n  enabled: true\n"},
+		{Name: "other", Namespace: "default", Chart: "other", Values: "ingress:\n  enabled: true\n"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(objects, check.DeepEquals, []kubernetes.ObjectRef{
+		{
+			APIVersion: "extensions/v1beta1",
+			Kind:       "Ingress",
+			Namespace:  "web",
+			Name:       "web",
+			Source:     "web",
+		},
+	})
+}
+
+func (s *APIVersionsSuite) TestCollectsHelmReleaseObjects(c *check.C) {
+	objects, err := releaseObjects(helm.ReleaseManifest{
+		Name:      "web",
+		Namespace: "default",
+		Manifest:  releaseManifest,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(objects, check.DeepEquals, []kubernetes.ObjectRef{
+		{
+			APIVersion: "extensions/v1beta1",
+			Kind:       "Deployment",
+			Namespace:  "default",
+			Name:       "web",
+			Source:     "release web",
+		},
+		{
+			APIVersion: "extensions/v1beta1",
+			Kind:       "Ingress",
+			Namespace:  "web",
+			Name:       "web",
+			Source:     "release web",
+		},
+	})
+}
+
+const releaseManifest = `
+---
+# Source: web/templates/deployment.yaml
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.15
+---
+# Source: web/templates/ingress.yaml
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: web
+  namespace: web
+spec:
+  backend:
+    serviceName: web
+    servicePort: 80
+`
+
+const chartIngress = `{{- if .Values.ingress.enabled }}
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  backend:
+    serviceName: web
+    servicePort: 80
+{{- end }}
+`
+
+const appResources = `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: app
+  namespace: kube-system
+spec:
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      containers:
+      - name: app
+        image: app:1.0.0
+---
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+`
@@ -65,6 +65,16 @@ func AutomaticUpgrade(ctx context.Context, localEnv, updateEnv *localenv.LocalEn
 	progress := utils.NewProgress(ctx, "automatic upgrade", -1, false)
 	defer progress.Stop()
 
+	plan, err := fsm.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	operation, err := config.Operator.GetSiteOperation(clusterOperationKey(*plan))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	policy := getRollbackPolicy(*operation)
+
 	force := false
 	fsmErr := fsm.ExecutePlan(ctx, progress, force)
 	if fsmErr != nil {
@@ -72,6 +82,17 @@ func AutomaticUpgrade(ctx context.Context, localEnv, updateEnv *localenv.LocalEn
 		// fallthrough
 	}
 
+	if policy != nil {
+		rollback := fsmErr != nil && policy.OnFailure
+		if fsmErr == nil && policy.HealthCheckGracePeriod != 0 {
+			fsmErr = waitForHealthyCluster(ctx, config, *operation, *policy)
+			rollback = fsmErr != nil
+		}
+		if rollback {
+			fsmErr = rollbackPlan(ctx, fsm, progress, fsmErr)
+		}
+	}
+
 	err = fsm.Complete(fsmErr)
 	if err != nil {
 		return trace.Wrap(err)
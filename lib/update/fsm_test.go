@@ -24,6 +24,7 @@ import (
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/ops/opsservice"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
@@ -157,6 +158,129 @@ func (s *FSMSuite) TestFSMExecutePhaseWithSubphases(c *check.C) {
 	})
 }
 
+func (s *FSMSuite) TestRollbackPlanInReverseOrder(c *check.C) {
+	plan := storage.OperationPlan{
+		OperationID:   "operation-1",
+		OperationType: "test_operation",
+		ClusterName:   "example.com",
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1", Phases: []storage.OperationPhase{
+				{ID: "/phase1/sub1", State: storage.OperationPhaseStateCompleted},
+				{ID: "/phase1/sub2", State: storage.OperationPhaseStateCompleted},
+			}},
+			{ID: "/phase2", State: storage.OperationPhaseStateFailed},
+			{ID: "/phase3", State: storage.OperationPhaseStateUnstarted},
+			{ID: "/phase4", State: storage.OperationPhaseStateRolledBack},
+		},
+	}
+	s.engine.plan = &plan
+	var rolledBack []string
+	s.engine.Spec = getRecordingExecutor(&rolledBack, "")
+
+	phases, err := s.fsm.RollbackPlan(context.TODO(), utils.NewNopProgress(), false)
+	c.Assert(err, check.IsNil)
+	c.Assert(phases, check.DeepEquals, []string{"/phase2", "/phase1/sub2", "/phase1/sub1"})
+	c.Assert(rolledBack, check.DeepEquals, phases)
+
+	checkStates(c, s.resolvePlan(c, plan), map[string]string{
+		"/phase1/sub1": storage.OperationPhaseStateRolledBack,
+		"/phase1/sub2": storage.OperationPhaseStateRolledBack,
+		"/phase2":      storage.OperationPhaseStateRolledBack,
+		"/phase3":      storage.OperationPhaseStateUnstarted,
+	})
+}
+
+func (s *FSMSuite) TestRollbackPlanStopsOnFailure(c *check.C) {
+	plan := storage.OperationPlan{
+		OperationID:   "operation-1",
+		OperationType: "test_operation",
+		ClusterName:   "example.com",
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1", State: storage.OperationPhaseStateCompleted},
+			{ID: "/phase2", State: storage.OperationPhaseStateCompleted},
+			{ID: "/phase3", State: storage.OperationPhaseStateFailed},
+		},
+	}
+	s.engine.plan = &plan
+	var rolledBack []string
+	s.engine.Spec = getRecordingExecutor(&rolledBack, "/phase2")
+
+	phases, err := s.fsm.RollbackPlan(context.TODO(), utils.NewNopProgress(), false)
+	c.Assert(err, check.NotNil)
+	c.Assert(phases, check.DeepEquals, []string{"/phase3"})
+	c.Assert(rolledBack, check.DeepEquals, []string{"/phase3"})
+
+	checkStates(c, s.resolvePlan(c, plan), map[string]string{
+		"/phase1": storage.OperationPhaseStateCompleted,
+		"/phase2": storage.OperationPhaseStateFailed,
+		"/phase3": storage.OperationPhaseStateRolledBack,
+	})
+}
+
+func (s *FSMSuite) TestRollbackPlanOnRemoteServer(c *check.C) {
+	remote := storage.Server{AdvertiseIP: "192.0.2.1", Hostname: "node-2"}
+	plan := storage.OperationPlan{
+		OperationID:   "operation-1",
+		OperationType: "test_operation",
+		ClusterName:   "example.com",
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1", State: storage.OperationPhaseStateCompleted},
+			{
+				ID:    "/phase2",
+				State: storage.OperationPhaseStateCompleted,
+				Data:  &storage.OperationPhaseData{Server: &remote},
+			},
+		},
+	}
+	s.engine.plan = &plan
+	var rolledBack []string
+	s.engine.Spec = getRecordingExecutor(&rolledBack, "")
+	runner := &testRunner{}
+	s.fsm.Runner = runner
+
+	phases, err := s.fsm.RollbackPlan(context.TODO(), utils.NewNopProgress(), false)
+	c.Assert(err, check.IsNil)
+	c.Assert(phases, check.DeepEquals, []string{"/phase2", "/phase1"})
+	c.Assert(rolledBack, check.DeepEquals, []string{"/phase1"},
+		check.Commentf("Remote phase should not be rolled back locally."))
+	c.Assert(runner.commands, check.DeepEquals, [][]string{
+		{"node-2", "rollback", "--phase", "/phase2", "--force=false"},
+	})
+
+	checkStates(c, s.resolvePlan(c, plan), map[string]string{
+		"/phase1": storage.OperationPhaseStateRolledBack,
+		"/phase2": storage.OperationPhaseStateRolledBack,
+	})
+}
+
+func (s *FSMSuite) TestRollbackPlanFailsWithoutRemoteAgent(c *check.C) {
+	remote := storage.Server{AdvertiseIP: "192.0.2.1", Hostname: "node-2"}
+	plan := storage.OperationPlan{
+		OperationID:   "operation-1",
+		OperationType: "test_operation",
+		ClusterName:   "example.com",
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1", State: storage.OperationPhaseStateCompleted},
+			{
+				ID:    "/phase2",
+				State: storage.OperationPhaseStateCompleted,
+				Data:  &storage.OperationPhaseData{Server: &remote},
+			},
+		},
+	}
+	s.engine.plan = &plan
+	var rolledBack []string
+	s.engine.Spec = getRecordingExecutor(&rolledBack, "")
+	runner := &testRunner{err: trace.ConnectionProblem(nil, "agent is down")}
+	s.fsm.Runner = runner
+
+	phases, err := s.fsm.RollbackPlan(context.TODO(), utils.NewNopProgress(), false)
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+	c.Assert(phases, check.HasLen, 0)
+	c.Assert(rolledBack, check.HasLen, 0)
+	c.Assert(runner.commands, check.HasLen, 0)
+}
+
 func (s *FSMSuite) resolvePlan(c *check.C, plan storage.OperationPlan) *storage.OperationPlan {
 	changelog, err := s.engine.LocalBackend.GetOperationPlanChangelog(plan.ClusterName, plan.OperationID)
 	c.Assert(err, check.IsNil)
@@ -220,3 +344,60 @@ func (p *testPhase2) Execute(context.Context) error {
 func (p *testPhase2) Rollback(context.Context) error {
 	return nil
 }
+
+// getRecordingExecutor returns an executor factory that records the IDs of
+// the rolled back phases in rolledBack and fails the rollback of failPhase
+func getRecordingExecutor(rolledBack *[]string, failPhase string) fsm.FSMSpecFunc {
+	return func(p fsm.ExecutorParams, remote fsm.Remote) (fsm.PhaseExecutor, error) {
+		return &recordingPhase{
+			FieldLogger: logrus.NewEntry(logrus.New()),
+			id:          p.Phase.ID,
+			fail:        p.Phase.ID == failPhase,
+			rolledBack:  rolledBack,
+		}, nil
+	}
+}
+
+type recordingPhase struct {
+	logrus.FieldLogger
+	id         string
+	fail       bool
+	rolledBack *[]string
+}
+
+func (p *recordingPhase) PreCheck(context.Context) error {
+	return nil
+}
+func (p *recordingPhase) PostCheck(context.Context) error {
+	return nil
+}
+func (p *recordingPhase) Execute(context.Context) error {
+	return nil
+}
+func (p *recordingPhase) Rollback(context.Context) error {
+	if p.fail {
+		return trace.BadParameter("failed to rollback %v", p.id)
+	}
+	*p.rolledBack = append(*p.rolledBack, p.id)
+	return nil
+}
+
+// testRunner records remote commands
+type testRunner struct {
+	// err is returned by CanExecute
+	err      error
+	commands [][]string
+}
+
+func (r *testRunner) Run(ctx context.Context, server storage.Server, command ...string) error {
+	r.commands = append(r.commands, append([]string{server.Hostname}, command...))
+	return nil
+}
+
+func (r *testRunner) CanExecute(context.Context, storage.Server) error {
+	return r.err
+}
+
+func (r *testRunner) Close() error {
+	return nil
+}
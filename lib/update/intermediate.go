@@ -0,0 +1,464 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// findIntermediateRuntimes computes the chain of runtime applications the cluster
+// needs to be upgraded through on its way from the installed runtime to the update runtime.
+//
+// The chain is comprised of the latest available runtime version for each release
+// line (major.minor) strictly between the installed and the update runtime versions,
+// in ascending order. runtimes lists all runtime applications available for the update.
+// Returns an empty list if the update runtime can be upgraded to directly
+func findIntermediateRuntimes(runtimes []app.Application, installed, update loc.Locator) ([]app.Application, error) {
+	installedVersion, err := installed.SemVer()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	updateVersion, err := update.SemVer()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !skipsReleaseLine(*installedVersion, *updateVersion) {
+		return nil, nil
+	}
+	latest := make(map[releaseLine]intermediateRuntime)
+	for _, runtime := range runtimes {
+		if runtime.Package.Repository != update.Repository || runtime.Package.Name != update.Name {
+			continue
+		}
+		version, err := runtime.Package.SemVer()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if version.PreRelease != "" {
+			// Only consider stable releases as upgrade stops
+			continue
+		}
+		line := newReleaseLine(*version)
+		if !newReleaseLine(*installedVersion).less(line) || !line.less(newReleaseLine(*updateVersion)) {
+			continue
+		}
+		if existing, ok := latest[line]; ok && !existing.version.LessThan(*version) {
+			continue
+		}
+		latest[line] = intermediateRuntime{app: runtime, version: *version}
+	}
+	if missing := missingReleaseLines(*installedVersion, *updateVersion, latest); len(missing) != 0 {
+		return nil, trace.NotFound("no runtime found for release line(s) %v required to "+
+			"upgrade from %v to %v, make sure they are available in the cluster",
+			missing, installed.Version, update.Version)
+	}
+	result := make(intermediateRuntimes, 0, len(latest))
+	for _, runtime := range latest {
+		result = append(result, runtime)
+	}
+	sort.Sort(result)
+	return result.asApps(), nil
+}
+
+// PullIntermediateRuntimes makes sure that all intermediate runtimes the cluster
+// needs to be upgraded through on its way from the installed runtime to the update
+// runtime are available in the cluster.
+//
+// Runtimes missing from the cluster are looked up in the specified sources
+// in order and pulled into the cluster.
+// Returns an error if no runtime can be found for any of the required release lines
+func PullIntermediateRuntimes(req PullIntermediateRuntimesRequest) error {
+	if req.FieldLogger == nil {
+		req.FieldLogger = logrus.WithField(trace.Component, "update")
+	}
+	runtimes, err := listRuntimes(req.Apps, req.UpdateRuntime)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	existing := make(map[string]struct{}, len(runtimes))
+	for _, runtime := range runtimes {
+		existing[runtime.Package.String()] = struct{}{}
+	}
+	sources := make(map[string]RuntimeSource)
+	for _, source := range req.Sources {
+		sourceRuntimes, err := listRuntimes(source.Apps, req.UpdateRuntime)
+		if err != nil {
+			req.Warnf("Failed to list runtimes in %v: %v.", source.Description, trace.DebugReport(err))
+			continue
+		}
+		for _, runtime := range sourceRuntimes {
+			key := runtime.Package.String()
+			if _, ok := existing[key]; ok {
+				continue
+			}
+			if _, ok := sources[key]; ok {
+				continue
+			}
+			sources[key] = source
+			runtimes = append(runtimes, runtime)
+		}
+	}
+	intermediateRuntimes, err := findIntermediateRuntimes(runtimes, req.InstalledRuntime, req.UpdateRuntime)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, runtime := range intermediateRuntimes {
+		source, ok := sources[runtime.Package.String()]
+		if !ok {
+			continue
+		}
+		req.Infof("Pulling intermediate runtime %v from %v.", runtime.Package, source.Description)
+		_, err := service.PullApp(service.AppPullRequest{
+			FieldLogger: req.FieldLogger,
+			SrcPack:     source.Packages,
+			SrcApp:      source.Apps,
+			DstPack:     req.Packages,
+			DstApp:      req.Apps,
+			Package:     runtime.Package,
+		})
+		if err != nil && !trace.IsAlreadyExists(err) {
+			return trace.Wrap(err, "failed to pull intermediate runtime %v from %v",
+				runtime.Package, source.Description)
+		}
+	}
+	return nil
+}
+
+// PullIntermediateRuntimesRequest describes a request to make the intermediate
+// runtimes available in the cluster
+type PullIntermediateRuntimesRequest struct {
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	// Apps is the cluster application service
+	Apps app.Applications
+	// Packages is the cluster package service
+	Packages pack.PackageService
+	// Sources lists sources to look up the runtimes missing from the cluster in
+	Sources []RuntimeSource
+	// InstalledRuntime specifies the installed runtime application
+	InstalledRuntime loc.Locator
+	// UpdateRuntime specifies the update runtime application
+	UpdateRuntime loc.Locator
+}
+
+// RuntimeSource describes a source of runtime applications
+type RuntimeSource struct {
+	// Description is the human-readable description of the source
+	Description string
+	// Apps is the application service of the source
+	Apps app.Applications
+	// Packages is the package service of the source
+	Packages pack.PackageService
+}
+
+// listRuntimes returns runtime applications from the same repository
+// as the specified runtime
+func listRuntimes(apps app.Applications, runtime loc.Locator) ([]app.Application, error) {
+	runtimes, err := apps.ListApps(app.ListAppsRequest{
+		Repository: runtime.Repository,
+		Type:       storage.AppRuntime,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return runtimes, nil
+}
+
+// missingReleaseLines returns the release lines between the installed
+// and the update versions that have no runtime in latest.
+//
+// Release lines within a major version can only be enumerated up to a known
+// minor version, so for a major upgrade, only the release lines of the installed
+// major version up to the latest known one, and the release lines of the update
+// major version preceding the update version are verified
+func missingReleaseLines(installed, update semver.Version, latest map[releaseLine]intermediateRuntime) (missing []releaseLine) {
+	check := func(major, fromMinor, toMinor int64) {
+		for minor := fromMinor; minor < toMinor; minor++ {
+			line := releaseLine{major: major, minor: minor}
+			if _, ok := latest[line]; !ok {
+				missing = append(missing, line)
+			}
+		}
+	}
+	if installed.Major == update.Major {
+		check(installed.Major, installed.Minor+1, update.Minor)
+		return missing
+	}
+	lastMinor := installed.Minor
+	for line := range latest {
+		if line.major == installed.Major && line.minor > lastMinor {
+			lastMinor = line.minor
+		}
+	}
+	check(installed.Major, installed.Minor+1, lastMinor)
+	check(update.Major, 0, update.Minor)
+	return missing
+}
+
+// skipsReleaseLine returns true if the update version belongs to a release line
+// that does not immediately follow the release line of the installed version,
+// i.e. upgrading directly would skip at least one release line
+func skipsReleaseLine(installed, update semver.Version) bool {
+	if installed.Major == update.Major {
+		return update.Minor-installed.Minor > 1
+	}
+	return update.Major > installed.Major
+}
+
+// intermediate returns a new phase that updates system software on all nodes
+// to the specified intermediate runtime.
+//
+// The phase bootstraps the nodes with the runtime packages of the intermediate runtime,
+// performs the rolling update of masters and regular nodes and upgrades etcd if necessary
+func (r phaseBuilder) intermediate(update intermediateUpdate) *phase {
+	root := root(phase{
+		ID:          fmt.Sprintf("intermediate-%v", update.runtime.Package.Version),
+		Description: fmt.Sprintf("Update system software to runtime %v", update.runtime.Package.Version),
+	})
+
+	runtimePackages := make(map[string]loc.Locator, len(update.masters)+len(update.nodes))
+	for _, servers := range []runtimeServers{update.masters, update.nodes} {
+		for _, server := range servers {
+			runtimePackages[server.AdvertiseIP] = server.runtime
+		}
+	}
+	bootstrap := *r.bootstrap(update.servers, update.installedApp, update.updateApp)
+	for i := range bootstrap.Phases {
+		data := *bootstrap.Phases[i].Data
+		runtimePackage := runtimePackages[data.Server.AdvertiseIP]
+		data.RuntimePackage = &runtimePackage
+		bootstrap.Phases[i].Data = &data
+	}
+	bootstrap.Description = fmt.Sprintf("Bootstrap nodes for runtime %v", update.runtime.Package.Version)
+
+	masters, nodes := update.masters, update.nodes
+	leadMaster := masters[0]
+
+	mastersPhase := *r.masters(leadMaster, masters[1:], update.supportsTaints)
+	nodesPhase := *r.nodes(leadMaster.Server, nodes, update.supportsTaints)
+
+	phases := phases{bootstrap, mastersPhase}
+	if len(nodesPhase.Phases) > 0 {
+		phases = append(phases, nodesPhase)
+	}
+	if update.etcd != nil {
+		phases = append(phases, *r.etcdPlan(leadMaster.Server, masters[1:].asServers(),
+			nodes.asServers(), update.etcd.installed, update.etcd.update))
+	}
+
+	changesetID := intermediateChangesetID(update.operationID, update.runtime.Package.Version)
+	for _, sub := range phases {
+		sub := nest(root, sub)
+		setChangesetID(&sub, changesetID)
+		root.AddSequential(sub)
+	}
+	return &root
+}
+
+// intermediateRuntimeServers returns the specified servers with the runtime
+// (planet) packages of the given intermediate runtime application resolved
+// for their profiles
+func intermediateRuntimeServers(p newPlanParams, runtime app.Application, servers runtimeServers) (result runtimeServers, err error) {
+	for _, server := range servers {
+		runtimePackage, err := intermediateRuntimePackage(p, runtime, server.Role)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		result = append(result, runtimeServer{Server: server.Server, runtime: *runtimePackage})
+	}
+	return result, nil
+}
+
+// intermediateRuntimePackage returns the runtime (planet) package of the
+// intermediate runtime application for the specified node profile.
+//
+// The runtime package is taken from the profile of the intermediate runtime
+// if it defines one. Otherwise, if the profile of the update application uses
+// a custom runtime package, the same package at the version of the intermediate
+// runtime package is used, and the default runtime package of the intermediate
+// runtime is used for all other profiles
+func intermediateRuntimePackage(p newPlanParams, runtime app.Application, profileName string) (*loc.Locator, error) {
+	profile, err := runtime.Manifest.NodeProfiles.ByName(profileName)
+	if err == nil {
+		runtimePackage, err := runtime.Manifest.RuntimePackage(*profile)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to determine runtime package of %v for profile %q",
+				runtime.Package, profileName)
+		}
+		return runtimePackage, nil
+	}
+	defaultPackage, err := runtime.Manifest.DefaultRuntimePackage()
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to determine runtime package for %v",
+			runtime.Package)
+	}
+	updateProfile, err := p.updateApp.Manifest.NodeProfiles.ByName(profileName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if updateProfile.SystemOptions == nil || updateProfile.SystemOptions.Dependencies.Runtime == nil {
+		return defaultPackage, nil
+	}
+	custom := updateProfile.SystemOptions.Dependencies.Runtime.Locator
+	runtimePackage := loc.Locator{
+		Repository: custom.Repository,
+		Name:       custom.Name,
+		Version:    defaultPackage.Version,
+	}
+	if p.packageService == nil {
+		return nil, trace.NotFound("no package service to look up runtime package %v", runtimePackage)
+	}
+	_, err = p.packageService.ReadPackageEnvelope(runtimePackage)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("runtime package %v for profile %q is required to "+
+				"upgrade through runtime %v, make sure it is available in the cluster",
+				runtimePackage, profileName, runtime.Package.Version)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &runtimePackage, nil
+}
+
+// intermediateChangesetID returns the ID of the system package changeset
+// for the intermediate runtime update to the specified version
+func intermediateChangesetID(operationID string, version string) string {
+	return fmt.Sprintf("%v-%v", operationID, version)
+}
+
+// nest re-roots the specified phase tree under the given parent phase.
+// All absolute phase IDs and requirements of the tree are rewritten
+// to be relative to the parent
+func nest(parent phase, sub phase) phase {
+	sub.ID = nestedID(parent, sub.ID)
+	requires := make([]string, 0, len(sub.Requires))
+	for _, req := range sub.Requires {
+		requires = append(requires, nestedID(parent, req))
+	}
+	sub.Requires = requires
+	children := make([]storage.OperationPhase, 0, len(sub.Phases))
+	for _, child := range sub.Phases {
+		children = append(children, storage.OperationPhase(nest(parent, phase(child))))
+	}
+	sub.Phases = children
+	return sub
+}
+
+func nestedID(parent phase, id string) string {
+	if !path.IsAbs(id) {
+		return id
+	}
+	return path.Join(parent.ID, id)
+}
+
+// setChangesetID sets the specified changeset ID on all system update
+// phases in the given phase tree
+func setChangesetID(p *phase, changesetID string) {
+	if p.Executor == updateSystem && p.Data != nil {
+		data := *p.Data
+		data.ChangesetID = changesetID
+		p.Data = &data
+	}
+	for i := range p.Phases {
+		setChangesetID((*phase)(&p.Phases[i]), changesetID)
+	}
+}
+
+// intermediateUpdate describes the update to an intermediate runtime
+type intermediateUpdate struct {
+	// operationID is the ID of the update operation
+	operationID string
+	// runtime is the intermediate runtime application
+	runtime app.Application
+	// installedApp is the installed application package
+	installedApp loc.Locator
+	// updateApp is the update application package
+	updateApp loc.Locator
+	// servers lists all cluster servers
+	servers []storage.Server
+	// masters lists master servers with the lead master first
+	// and the runtime packages of the intermediate runtime
+	masters runtimeServers
+	// nodes lists regular nodes with the runtime packages
+	// of the intermediate runtime
+	nodes runtimeServers
+	// supportsTaints specifies whether the installed runtime supports taints
+	supportsTaints bool
+	// etcd optionally specifies the etcd upgrade
+	etcd *etcdVersions
+}
+
+// etcdVersions describes an etcd upgrade
+type etcdVersions struct {
+	// installed is the etcd version before the update
+	installed string
+	// update is the etcd version after the update
+	update string
+}
+
+func newReleaseLine(version semver.Version) releaseLine {
+	return releaseLine{major: version.Major, minor: version.Minor}
+}
+
+func (r releaseLine) less(other releaseLine) bool {
+	if r.major != other.major {
+		return r.major < other.major
+	}
+	return r.minor < other.minor
+}
+
+// String returns the release line formatted as major.minor
+func (r releaseLine) String() string {
+	return fmt.Sprintf("%v.%v", r.major, r.minor)
+}
+
+// releaseLine identifies a release line of a runtime
+type releaseLine struct {
+	major int64
+	minor int64
+}
+
+func (r intermediateRuntimes) Len() int           { return len(r) }
+func (r intermediateRuntimes) Less(i, j int) bool { return r[i].version.LessThan(r[j].version) }
+func (r intermediateRuntimes) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+func (r intermediateRuntimes) asApps() (result []app.Application) {
+	result = make([]app.Application, 0, len(r))
+	for _, runtime := range r {
+		result = append(result, runtime.app)
+	}
+	return result
+}
+
+type intermediateRuntimes []intermediateRuntime
+
+type intermediateRuntime struct {
+	app     app.Application
+	version semver.Version
+}
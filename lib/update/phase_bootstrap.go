@@ -79,6 +79,9 @@ type updatePhaseBootstrap struct {
 	runtimePackage loc.Locator
 	// installedRuntime specifies the installed runtime package
 	installedRuntime loc.Locator
+	// intermediate specifies whether this phase bootstraps an intermediate
+	// runtime update
+	intermediate bool
 }
 
 // NewUpdatePhaseBootstrap creates a new bootstrap phase executor
@@ -114,6 +117,12 @@ func NewUpdatePhaseBootstrap(c FSMConfig, plan storage.OperationPlan, phase stor
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	// bootstrap phases of intermediate runtime updates specify the runtime
+	// package explicitly
+	intermediate := phase.Data.RuntimePackage != nil
+	if intermediate {
+		runtimePackage = phase.Data.RuntimePackage
+	}
 	return &updatePhaseBootstrap{
 		Operator:         c.Operator,
 		Backend:          c.Backend,
@@ -131,6 +140,7 @@ func NewUpdatePhaseBootstrap(c FSMConfig, plan storage.OperationPlan, phase stor
 		remote:           remote,
 		runtimePackage:   *runtimePackage,
 		installedRuntime: *installedRuntime,
+		intermediate:     intermediate,
 	}, nil
 }
 
@@ -148,6 +158,11 @@ func (p *updatePhaseBootstrap) PostCheck(context.Context) error {
 // binary, creates new secrets/config packages in the local backend and
 // initializes local operation state
 func (p *updatePhaseBootstrap) Execute(ctx context.Context) error {
+	if p.intermediate {
+		// the node has already been bootstrapped for the operation,
+		// only pull the packages of the intermediate runtime
+		return trace.Wrap(p.pullRuntimeUpdates())
+	}
 	err := p.configureNode()
 	if err != nil {
 		return trace.Wrap(err)
@@ -170,15 +185,17 @@ func (p *updatePhaseBootstrap) Execute(ctx context.Context) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	err = p.pullSystemUpdates()
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	err = p.addUpdateRuntimePackageLabel()
+	return trace.Wrap(p.pullRuntimeUpdates())
+}
+
+// pullRuntimeUpdates pulls system package updates for the runtime package
+// and labels the runtime package accordingly
+func (p *updatePhaseBootstrap) pullRuntimeUpdates() error {
+	err := p.pullSystemUpdates()
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	return nil
+	return trace.Wrap(p.addUpdateRuntimePackageLabel())
 }
 
 // Rollback is no-op for this phase
@@ -228,13 +245,17 @@ func (p *updatePhaseBootstrap) updateDNSConfig() error {
 }
 
 func (p *updatePhaseBootstrap) pullSystemUpdates() error {
-	out, err := fsm.RunCommand(utils.PlanetCommandArgs(
-		filepath.Join(defaults.GravityUpdateDir, constants.GravityBin),
+	args := []string{filepath.Join(defaults.GravityUpdateDir, constants.GravityBin),
 		"--quiet", "--insecure", "system", "pull-updates",
 		"--uid", p.ServiceUser.UID,
 		"--gid", p.ServiceUser.GID,
 		"--runtime-package", p.runtimePackage.String(),
-		"--ops-url", defaults.GravityServiceURL))
+		"--ops-url", defaults.GravityServiceURL,
+	}
+	if p.intermediate {
+		args = append(args, "--exact-runtime")
+	}
+	out, err := fsm.RunCommand(utils.PlanetCommandArgs(args...))
 	if err != nil {
 		return trace.Wrap(err, "failed to pull system updates: %s", out)
 	}
@@ -17,15 +17,18 @@ limitations under the License.
 package update
 
 import (
+	"bytes"
 	"context"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
 )
 
 // updatePhaseChecks is the update phase which executes preflight checks on a set of nodes
@@ -43,6 +46,10 @@ type updatePhaseChecks struct {
 	// remote allows remote control of servers
 	remote         fsm.AgentRepository
 	existingDocker storage.DockerConfig
+	// client is the cluster Kubernetes client
+	client *kubernetes.Clientset
+	// packages is the cluster package service
+	packages pack.PackageService
 }
 
 // NewUpdatePhaseChecks creates a new preflight checks phase executor
@@ -70,6 +77,8 @@ func NewUpdatePhaseChecks(
 		installedPackage: *phase.Data.InstalledPackage,
 		existingDocker:   cluster.ClusterState.Docker,
 		remote:           remote,
+		client:           c.Client,
+		packages:         c.Packages,
 	}, nil
 }
 
@@ -103,7 +112,46 @@ func (p *updatePhaseChecks) Execute(ctx context.Context) error {
 	}
 
 	err = validate(ctx, p.remote, p.servers, installedApp.Manifest, app.Manifest, dockerConfig)
-	return trace.Wrap(err, "failed to validate requirements")
+	if err != nil {
+		return trace.Wrap(err, "failed to validate requirements")
+	}
+
+	return trace.Wrap(p.checkAPIDeprecations(*app))
+}
+
+// checkAPIDeprecations fails if the cluster or the update application
+// use Kubernetes APIs that are removed in the update runtime
+func (p *updatePhaseChecks) checkAPIDeprecations(app app.Application) error {
+	req := APIDeprecationsRequest{
+		Apps:     p.apps,
+		Packages: p.packages,
+		App:      app,
+	}
+	// only set the client if it is available to avoid
+	// a non-nil interface wrapping a nil pointer
+	if p.client != nil {
+		req.Client = p.client
+	}
+	findings, err := CheckAPIDeprecations(req)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			p.Warnf("Skip Kubernetes API deprecation check: %v.", err)
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	for _, finding := range findings {
+		if !finding.Removed {
+			p.Warn(finding.String())
+		}
+	}
+	if removed := findings.Removed(); len(removed) != 0 {
+		var buf bytes.Buffer
+		removed.Format(&buf)
+		return trace.BadParameter("the following objects use Kubernetes APIs "+
+			"that are not served after the update:\n%s", buf.String())
+	}
+	return nil
 }
 
 // Rollback is a no-op for this phase
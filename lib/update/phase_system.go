@@ -38,6 +38,8 @@ import (
 type updatePhaseSystem struct {
 	// OperationID is the id of the current update operation
 	OperationID string
+	// ChangesetID is the id of the system package changeset
+	ChangesetID string
 	// Server is the server currently being updated
 	Server storage.Server
 	// GravityPath is the path to the new gravity binary
@@ -47,6 +49,9 @@ type updatePhaseSystem struct {
 	remote fsm.Remote
 	// runtimePackage specifies the runtime package to update to
 	runtimePackage loc.Locator
+	// intermediate specifies whether this phase updates the node
+	// to an intermediate runtime
+	intermediate bool
 }
 
 // NewUpdatePhaseNode returns a new node update phase executor
@@ -61,8 +66,16 @@ func NewUpdatePhaseSystem(c FSMConfig, plan storage.OperationPlan, phase storage
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	// system phases of intermediate runtime updates use a dedicated changeset
+	changesetID := plan.OperationID
+	intermediate := phase.Data.ChangesetID != ""
+	if intermediate {
+		changesetID = phase.Data.ChangesetID
+	}
 	return &updatePhaseSystem{
 		OperationID:    plan.OperationID,
+		ChangesetID:    changesetID,
+		intermediate:   intermediate,
 		Server:         *phase.Data.Server,
 		GravityPath:    gravityPath,
 		FieldLogger:    logrus.NewEntry(logrus.New()),
@@ -83,12 +96,16 @@ func (p *updatePhaseSystem) PostCheck(context.Context) error {
 
 // Execute runs system update on the node
 func (p *updatePhaseSystem) Execute(context.Context) error {
-	out, err := fsm.RunCommand([]string{p.GravityPath,
+	args := []string{p.GravityPath,
 		"--insecure", "--debug", "system", "update",
-		"--changeset-id", p.OperationID,
+		"--changeset-id", p.ChangesetID,
 		"--runtime-package", p.runtimePackage.String(),
 		"--with-status",
-	})
+	}
+	if p.intermediate {
+		args = append(args, "--exact-runtime")
+	}
+	out, err := fsm.RunCommand(args)
 	if err != nil {
 		message := "failed to update system"
 		if errUninstall, ok := trace.Unwrap(err).(*utils.ErrorUninstallService); ok {
@@ -105,7 +122,7 @@ func (p *updatePhaseSystem) Execute(context.Context) error {
 // Rollback runs rolls back the system upgrade on the node
 func (p *updatePhaseSystem) Rollback(context.Context) error {
 	out, err := fsm.RunCommand([]string{p.GravityPath, "--insecure", "system", "rollback",
-		"--changeset-id", p.OperationID, "--with-status"})
+		"--changeset-id", p.ChangesetID, "--with-status"})
 	if err != nil {
 		return trace.Wrap(err, "failed to rollback system: %s", out)
 	}
@@ -202,6 +202,22 @@ func NewOperationPlan(env *localenv.ClusterEnvironment, op storage.SiteOperation
 		return nil, trace.Wrap(err)
 	}
 
+	runtimes, err := env.Apps.ListApps(app.ListAppsRequest{
+		Repository: updateRuntime.Package.Repository,
+		Type:       storage.AppRuntime,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	intermediateRuntimes, err := findIntermediateRuntimes(runtimes,
+		installedRuntime.Package, updateRuntime.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, runtime := range intermediateRuntimes {
+		log.Infof("Will upgrade through intermediate runtime %v.", runtime.Package)
+	}
+
 	links, err := env.Backend.GetOpsCenterLinks(op.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -217,19 +233,20 @@ func NewOperationPlan(env *localenv.ClusterEnvironment, op storage.SiteOperation
 	}
 
 	plan, err := newOperationPlan(newPlanParams{
-		operation:         op,
-		servers:           servers,
-		installedRuntime:  *installedRuntime,
-		installedApp:      *installedApp,
-		updateRuntime:     *updateRuntime,
-		updateApp:         *updateApp,
-		links:             links,
-		trustedClusters:   trustedClusters,
-		packageService:    env.ClusterPackages,
-		shouldUpdateEtcd:  shouldUpdateEtcd,
-		updateCoreDNS:     updateCoreDNS,
-		updateDNSAppEarly: updateDNSAppEarly,
-		roles:             roles,
+		operation:            op,
+		servers:              servers,
+		installedRuntime:     *installedRuntime,
+		installedApp:         *installedApp,
+		updateRuntime:        *updateRuntime,
+		updateApp:            *updateApp,
+		intermediateRuntimes: intermediateRuntimes,
+		links:                links,
+		trustedClusters:      trustedClusters,
+		packageService:       env.ClusterPackages,
+		shouldUpdateEtcd:     shouldUpdateEtcd,
+		updateCoreDNS:        updateCoreDNS,
+		updateDNSAppEarly:    updateDNSAppEarly,
+		roles:                roles,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -252,6 +269,9 @@ type newPlanParams struct {
 	updateRuntime app.Application
 	// updateApp is the update app
 	updateApp app.Application
+	// intermediateRuntimes lists runtimes the cluster is upgraded through
+	// before the update runtime, in ascending version order
+	intermediateRuntimes []app.Application
 	// links is a list of configured remote Ops Center links
 	links []storage.OpsCenterLink
 	// trustedClusters is a list of configured trusted clusters
@@ -368,8 +388,13 @@ func newOperationPlan(p newPlanParams) (*storage.OperationPlan, error) {
 		appPhase.RequireLiteral(runtimePhase.ChildLiteral(constants.BootstrapConfigPackage))
 	}
 
-	// check if etcd upgrade is required or not
-	updateEtcd, currentVersion, desiredVersion, err := p.shouldUpdateEtcd(p)
+	// check if etcd upgrade is required or not: with intermediate runtimes,
+	// the final etcd upgrade starts from the version of the last intermediate runtime
+	final := p
+	if n := len(p.intermediateRuntimes); n > 0 {
+		final.installedRuntime = p.intermediateRuntimes[n-1]
+	}
+	updateEtcd, currentVersion, desiredVersion, err := p.shouldUpdateEtcd(final)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -395,7 +420,25 @@ func newOperationPlan(p newPlanParams) (*storage.OperationPlan, error) {
 			}
 		}
 
-		phases = append(phases, bootstrapPhase, mastersPhase)
+		phases = append(phases, bootstrapPhase)
+
+		intermediatePhases, err := intermediateUpdates(p, masters, nodes, supportsTaints)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if len(intermediatePhases) > 0 {
+			// intermediate updates take over the requirements of the
+			// masters phase which then follows the last intermediate update
+			intermediatePhases[0].Requires = mastersPhase.Requires
+			for i := 1; i < len(intermediatePhases); i++ {
+				intermediatePhases[i].Require(intermediatePhases[i-1])
+			}
+			mastersPhase.Requires = nil
+			mastersPhase.Require(intermediatePhases[len(intermediatePhases)-1])
+			phases = append(phases, intermediatePhases...)
+		}
+
+		phases = append(phases, mastersPhase)
 		if len(nodesPhase.Phases) > 0 {
 			phases = append(phases, nodesPhase)
 		}
@@ -425,6 +468,48 @@ func newOperationPlan(p newPlanParams) (*storage.OperationPlan, error) {
 	return &plan, nil
 }
 
+// intermediateUpdates returns phases to update the cluster through the chain
+// of intermediate runtimes
+func intermediateUpdates(p newPlanParams, masters, nodes runtimeServers, supportsTaints bool) (result phases, err error) {
+	if len(p.intermediateRuntimes) != 0 && len(masters) == 0 {
+		return nil, trace.NotFound("no master servers found")
+	}
+	installedRuntime := p.installedRuntime
+	for _, runtime := range p.intermediateRuntimes {
+		runtimeMasters, err := intermediateRuntimeServers(p, runtime, masters)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		runtimeNodes, err := intermediateRuntimeServers(p, runtime, nodes)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		update := intermediateUpdate{
+			operationID:    p.operation.ID,
+			runtime:        runtime,
+			installedApp:   p.installedApp.Package,
+			updateApp:      p.updateApp.Package,
+			servers:        p.servers,
+			masters:        runtimeMasters,
+			nodes:          runtimeNodes,
+			supportsTaints: supportsTaints,
+		}
+		hop := p
+		hop.installedRuntime = installedRuntime
+		hop.updateRuntime = runtime
+		updateEtcd, installedVersion, updateVersion, err := p.shouldUpdateEtcd(hop)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if updateEtcd {
+			update.etcd = &etcdVersions{installed: installedVersion, update: updateVersion}
+		}
+		result = append(result, *phaseBuilder{}.intermediate(update))
+		installedRuntime = runtime
+	}
+	return result, nil
+}
+
 func shouldUpdateEtcd(p newPlanParams) (updateEtcd bool, installedEtcdVersion string, updateEtcdVersion string, err error) {
 	// TODO: should somehow maintain etcd version invariant across runtime packages
 	runtimePackage, err := p.installedRuntime.Manifest.DefaultRuntimePackage()
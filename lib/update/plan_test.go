@@ -17,6 +17,10 @@ limitations under the License.
 package update
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/constants"
@@ -27,6 +31,7 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
 	"gopkg.in/check.v1"
 )
 
@@ -160,6 +165,283 @@ func (s *PlanSuite) TestPlanWithoutRuntimeUpdate(c *check.C) {
 	compare.DeepCompare(c, *obtainedPlan, plan)
 }
 
+func (s *PlanSuite) TestPlanWithIntermediateRuntimeUpdate(c *check.C) {
+	// setup
+	runtimeLoc1 := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
+	appLoc1 := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	runtimeLoc2 := loc.MustParseLocator("gravitational.io/runtime:2.0.0")
+	appLoc2 := loc.MustParseLocator("gravitational.io/app:2.0.0")
+	intermediateLoc := loc.MustParseLocator("gravitational.io/runtime:1.1.0")
+
+	_, params := newTestPlan(c, params{
+		installedRuntime:         runtimeLoc1,
+		installedApp:             appLoc1,
+		updateRuntime:            runtimeLoc2,
+		updateApp:                appLoc2,
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+	})
+	params.intermediateRuntimes = []app.Application{
+		{
+			Package:  intermediateLoc,
+			Manifest: schema.MustParseManifestYAML([]byte(intermediateRuntimeManifest)),
+		},
+	}
+
+	// exercise
+	plan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+
+	// verify
+	var ids []string
+	for _, phase := range plan.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{
+		"/init", "/checks", "/pre-update", "/bootstrap", "/intermediate-1.1.0",
+		"/masters", "/nodes", "/etcd", "/migration", "/config", "/runtime", "/app", "/gc",
+	})
+
+	intermediate := plan.Phases[4]
+	c.Assert(intermediate.Requires, check.DeepEquals, []string{"/checks", "/bootstrap", "/pre-update"})
+	c.Assert(plan.Phases[5].Requires, check.DeepEquals, []string{"/intermediate-1.1.0"})
+
+	ids = nil
+	for _, phase := range intermediate.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{
+		"/intermediate-1.1.0/bootstrap",
+		"/intermediate-1.1.0/masters",
+		"/intermediate-1.1.0/nodes",
+		"/intermediate-1.1.0/etcd",
+	})
+
+	planet := loc.MustParseLocator("gravitational.io/planet:1.1.0")
+	bootstrap := intermediate.Phases[0].Phases[0]
+	c.Assert(bootstrap.ID, check.Equals, "/intermediate-1.1.0/bootstrap/node-1")
+	c.Assert(*bootstrap.Data.RuntimePackage, check.DeepEquals, planet)
+
+	nodes := intermediate.Phases[2]
+	c.Assert(nodes.Requires, check.DeepEquals, []string{"/intermediate-1.1.0/masters"})
+	system := nodes.Phases[0].Phases[1]
+	c.Assert(system.ID, check.Equals, "/intermediate-1.1.0/nodes/node-3/system-upgrade")
+	c.Assert(system.Executor, check.Equals, updateSystem)
+	c.Assert(*system.Data.RuntimePackage, check.DeepEquals, planet)
+	c.Assert(system.Data.ChangesetID, check.Equals, "123-1.1.0")
+}
+
+func (s *PlanSuite) TestIntermediateRuntimeResolvedPerProfile(c *check.C) {
+	// setup
+	_, params := newTestPlan(c, params{
+		installedRuntime:         loc.MustParseLocator("gravitational.io/runtime:1.0.0"),
+		installedApp:             loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		updateRuntime:            loc.MustParseLocator("gravitational.io/runtime:2.0.0"),
+		updateApp:                loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifestWithCustomRuntime,
+	})
+	params.servers[2].Role = "gpu"
+	params.intermediateRuntimes = []app.Application{
+		{
+			Package:  loc.MustParseLocator("gravitational.io/runtime:1.1.0"),
+			Manifest: schema.MustParseManifestYAML([]byte(intermediateRuntimeManifest)),
+		},
+	}
+	customPlanet := loc.MustParseLocator("gravitational.io/planet-gpu:1.1.0")
+	params.packageService = &runtimePackages{packages: []loc.Locator{customPlanet}}
+
+	// exercise
+	plan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+
+	// verify
+	intermediate := plan.Phases[4]
+	c.Assert(intermediate.ID, check.Equals, "/intermediate-1.1.0")
+	planet := loc.MustParseLocator("gravitational.io/planet:1.1.0")
+	bootstrap := intermediate.Phases[0]
+	c.Assert(*bootstrap.Phases[0].Data.RuntimePackage, check.DeepEquals, planet)
+	c.Assert(*bootstrap.Phases[2].Data.RuntimePackage, check.DeepEquals, customPlanet)
+	system := intermediate.Phases[2].Phases[0].Phases[1]
+	c.Assert(system.ID, check.Equals, "/intermediate-1.1.0/nodes/node-3/system-upgrade")
+	c.Assert(*system.Data.RuntimePackage, check.DeepEquals, customPlanet)
+
+	params.packageService = &runtimePackages{}
+	_, err = newOperationPlan(params)
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+	c.Assert(err, check.ErrorMatches, ".*planet-gpu:1.1.0.*")
+}
+
+func (s *PlanSuite) TestIntermediateRuntimeRequiresMasters(c *check.C) {
+	// setup
+	_, params := newTestPlan(c, params{
+		installedRuntime:         loc.MustParseLocator("gravitational.io/runtime:1.0.0"),
+		installedApp:             loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		updateRuntime:            loc.MustParseLocator("gravitational.io/runtime:2.0.0"),
+		updateApp:                loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+	})
+	params.intermediateRuntimes = []app.Application{
+		{
+			Package:  loc.MustParseLocator("gravitational.io/runtime:1.1.0"),
+			Manifest: schema.MustParseManifestYAML([]byte(intermediateRuntimeManifest)),
+		},
+	}
+
+	// exercise
+	_, err := intermediateUpdates(params, nil, runtimeServers{{Server: params.servers[2]}}, true)
+
+	// verify
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+}
+
+func (s *PlanSuite) TestFinalEtcdUpdateStartsFromLastIntermediateRuntime(c *check.C) {
+	// setup
+	_, params := newTestPlan(c, params{
+		installedRuntime:         loc.MustParseLocator("gravitational.io/runtime:1.0.0"),
+		installedApp:             loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		updateRuntime:            loc.MustParseLocator("gravitational.io/runtime:2.0.0"),
+		updateApp:                loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+	})
+	params.intermediateRuntimes = []app.Application{
+		{
+			Package:  loc.MustParseLocator("gravitational.io/runtime:1.1.0"),
+			Manifest: schema.MustParseManifestYAML([]byte(intermediateRuntimeManifest)),
+		},
+	}
+	etcdVersions := map[string]string{
+		"1.0.0": "3.3.0",
+		"1.1.0": "3.3.9",
+		"2.0.0": "3.3.9",
+	}
+	var hops []string
+	params.shouldUpdateEtcd = func(p newPlanParams) (bool, string, string, error) {
+		installed := etcdVersions[p.installedRuntime.Package.Version]
+		update := etcdVersions[p.updateRuntime.Package.Version]
+		hops = append(hops, fmt.Sprintf("%v->%v",
+			p.installedRuntime.Package.Version, p.updateRuntime.Package.Version))
+		return installed != update, installed, update, nil
+	}
+
+	// exercise
+	plan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+
+	// verify
+	var ids []string
+	for _, phase := range plan.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{
+		"/init", "/checks", "/pre-update", "/bootstrap", "/intermediate-1.1.0",
+		"/masters", "/nodes", "/migration", "/config", "/runtime", "/app", "/gc",
+	}, check.Commentf("Final etcd phase should not repeat the intermediate etcd upgrade."))
+	sort.Strings(hops)
+	c.Assert(hops, check.DeepEquals, []string{"1.0.0->1.1.0", "1.1.0->2.0.0"})
+}
+
+func (s *PlanSuite) TestFindsIntermediateRuntimes(c *check.C) {
+	var runtimes []app.Application
+	for _, version := range []string{
+		"5.0.0", "5.0.3", "5.1.0", "5.1.2", "5.2.0-alpha.1", "5.2.1", "5.3.0", "5.4.0", "6.0.0",
+	} {
+		runtimes = append(runtimes, app.Application{
+			Package: loc.MustParseLocator("gravitational.io/kubernetes:" + version),
+		})
+	}
+	runtimes = append(runtimes, app.Application{
+		Package: loc.MustParseLocator("gravitational.io/other:5.1.5"),
+	})
+	var testCases = []struct {
+		installed string
+		update    string
+		expected  []string
+		comment   string
+	}{
+		{
+			installed: "5.0.3",
+			update:    "5.1.2",
+			comment:   "next release line",
+		},
+		{
+			installed: "5.0.0",
+			update:    "5.0.3",
+			comment:   "same release line",
+		},
+		{
+			installed: "5.0.3",
+			update:    "5.3.0",
+			expected:  []string{"5.1.2", "5.2.1"},
+			comment:   "skips release lines",
+		},
+		{
+			installed: "5.1.0",
+			update:    "6.0.0",
+			expected:  []string{"5.2.1", "5.3.0", "5.4.0"},
+			comment:   "next major release",
+		},
+	}
+	for _, tc := range testCases {
+		comment := check.Commentf(tc.comment)
+		result, err := findIntermediateRuntimes(runtimes,
+			loc.MustParseLocator("gravitational.io/kubernetes:"+tc.installed),
+			loc.MustParseLocator("gravitational.io/kubernetes:"+tc.update))
+		c.Assert(err, check.IsNil, comment)
+		var versions []string
+		for _, runtime := range result {
+			versions = append(versions, runtime.Package.Version)
+		}
+		c.Assert(versions, check.DeepEquals, tc.expected, comment)
+	}
+}
+
+func (s *PlanSuite) TestFailsOnMissingReleaseLines(c *check.C) {
+	var runtimes []app.Application
+	for _, version := range []string{"5.0.0", "5.2.0", "5.3.0", "5.5.0", "6.0.0", "6.2.0"} {
+		runtimes = append(runtimes, app.Application{
+			Package: loc.MustParseLocator("gravitational.io/kubernetes:" + version),
+		})
+	}
+	var testCases = []struct {
+		installed string
+		update    string
+		missing   string
+		comment   string
+	}{
+		{
+			installed: "5.0.0",
+			update:    "5.3.0",
+			missing:   "[5.1]",
+			comment:   "missing release line within major version",
+		},
+		{
+			installed: "5.2.0",
+			update:    "6.2.0",
+			missing:   "[5.4 6.1]",
+			comment:   "missing release lines across major versions",
+		},
+	}
+	for _, tc := range testCases {
+		comment := check.Commentf(tc.comment)
+		_, err := findIntermediateRuntimes(runtimes,
+			loc.MustParseLocator("gravitational.io/kubernetes:"+tc.installed),
+			loc.MustParseLocator("gravitational.io/kubernetes:"+tc.update))
+		c.Assert(trace.IsNotFound(err), check.Equals, true, comment)
+		c.Assert(err, check.ErrorMatches, fmt.Sprintf(".*%v.*", regexp.QuoteMeta(tc.missing)), comment)
+	}
+}
+
 func newTestPlan(c *check.C, p params) (storage.OperationPlan, newPlanParams) {
 	servers := []storage.Server{
 		{
@@ -316,3 +598,56 @@ systemOptions:
   dependencies:
     runtimePackage: gravitational.io/planet:2.0.0
 `
+
+const updateAppManifestWithCustomRuntime = `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app
+  resourceVersion: 2.0.0
+dependencies:
+  apps:
+    - gravitational.io/app-dep-1:1.0.0
+    - gravitational.io/app-dep-2:2.0.0
+nodeProfiles:
+  - name: node
+  - name: gpu
+    systemOptions:
+      dependencies:
+        runtimePackage: gravitational.io/planet-gpu:2.0.0
+systemOptions:
+  dependencies:
+    runtimePackage: gravitational.io/planet:2.0.0
+`
+
+const intermediateRuntimeManifest = `apiVersion: bundle.gravitational.io/v2
+kind: Runtime
+metadata:
+  name: runtime
+  resourceVersion: 1.1.0
+dependencies:
+  packages:
+    - gravitational.io/gravity:1.1.0
+  apps:
+    - gravitational.io/runtime-dep-1:1.0.0
+    - gravitational.io/runtime-dep-2:1.1.0
+    - gravitational.io/rbac-app:1.1.0
+systemOptions:
+  dependencies:
+    runtimePackage: gravitational.io/planet:1.1.0
+`
+
+// runtimePackages is a package service that only knows about
+// the specified runtime packages
+type runtimePackages struct {
+	pack.PackageService
+	packages []loc.Locator
+}
+
+func (r *runtimePackages) ReadPackageEnvelope(locator loc.Locator) (*pack.PackageEnvelope, error) {
+	for _, runtimePackage := range r.packages {
+		if runtimePackage.IsEqualTo(locator) {
+			return &pack.PackageEnvelope{Locator: locator}, nil
+		}
+	}
+	return nil, trace.NotFound("package %v not found", locator)
+}
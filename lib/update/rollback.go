@@ -0,0 +1,157 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// getRollbackPolicy returns the automatic rollback policy of the specified
+// update operation.
+// Returns nil if the operation does not have a rollback policy
+func getRollbackPolicy(operation ops.SiteOperation) *storage.RollbackPolicy {
+	if operation.Update == nil || operation.Update.RollbackPolicy.IsEmpty() {
+		return nil
+	}
+	return operation.Update.RollbackPolicy
+}
+
+// waitForHealthyCluster waits for the cluster to become healthy after the update
+// for the duration of the grace period specified with the policy.
+// The cluster is considered healthy if the planet agents report the system as running
+// and the status hook of the update application succeeds
+func waitForHealthyCluster(ctx context.Context, config FSMConfig, operation ops.SiteOperation, policy storage.RollbackPolicy) error {
+	cluster, err := config.Operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	updatePackage, err := operation.Update.Package()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	updateApp, err := config.Apps.GetApp(*updatePackage)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(waitForHealthy(ctx, policy.HealthCheckGracePeriod,
+		checkPlanetStatus,
+		func(ctx context.Context) error {
+			return checkStatusHook(ctx, config.Apps, *updateApp, cluster.ServiceUser)
+		}))
+}
+
+// waitForHealthy waits for the specified health checks to succeed
+// for the duration of the grace period.
+// Checks are run in order and the first failed check fails the attempt
+func waitForHealthy(ctx context.Context, gracePeriod time.Duration, checks ...healthCheck) error {
+	log.Infof("Waiting up to %v for the cluster to become healthy.", gracePeriod)
+	err := utils.RetryFor(ctx, gracePeriod, func() error {
+		for _, check := range checks {
+			if err := check(ctx); err != nil {
+				log.Debugf("Cluster is not healthy yet: %v.", err)
+				return trace.Wrap(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return trace.BadParameter("cluster has not become healthy within %v after the upgrade: %v",
+			gracePeriod, trace.Unwrap(err))
+	}
+	return nil
+}
+
+// healthCheck checks an aspect of the cluster health
+type healthCheck func(context.Context) error
+
+// rollbackPlan rolls back all executed phases of the update plan after the update
+// has failed with the specified error.
+// Returns the error that describes the original failure and the rollback outcome
+func rollbackPlan(ctx context.Context, machine *fsm.FSM, progress utils.Progress, updateErr error) error {
+	log.Warnf("Rolling back the upgrade after failure: %v.", updateErr)
+	rolledBack, err := machine.RollbackPlan(ctx, progress, false)
+	report := formatRollbackReport(rolledBack)
+	log.Info(report)
+	if err != nil {
+		return trace.BadParameter("%v; automatic rollback failed: %v. %v",
+			trace.Unwrap(updateErr), trace.Unwrap(err), report)
+	}
+	return trace.BadParameter("%v; the upgrade has been automatically rolled back. %v",
+		trace.Unwrap(updateErr), report)
+}
+
+// formatRollbackReport returns a report listing the phases undone by the automatic rollback
+func formatRollbackReport(rolledBack []string) string {
+	if len(rolledBack) == 0 {
+		return "No phases have been rolled back."
+	}
+	return fmt.Sprintf("Rolled back phases: %v.", strings.Join(rolledBack, ", "))
+}
+
+// checkPlanetStatus checks the cluster health using planet agents
+func checkPlanetStatus(ctx context.Context) error {
+	planetStatus, err := status.FromPlanetAgent(ctx, nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(checkSystemStatus(*planetStatus))
+}
+
+// checkSystemStatus returns an error if the system status reported
+// by planet agents is not running
+func checkSystemStatus(planetStatus status.Agent) error {
+	if planetStatus.GetSystemStatus() != agentpb.SystemStatus_Running {
+		return trace.BadParameter("cluster is not healthy: %v", planetStatus.SystemStatus)
+	}
+	return nil
+}
+
+// checkStatusHook executes the status hook of the specified application
+func checkStatusHook(ctx context.Context, apps app.Applications, application app.Application, serviceUser storage.OSUser) error {
+	if !application.Manifest.HasHook(schema.HookStatus) {
+		return nil
+	}
+	ref, out, err := app.RunAppHook(ctx, apps, app.HookRunRequest{
+		Application: application.Package,
+		Hook:        schema.HookStatus,
+		ServiceUser: serviceUser,
+	})
+	if ref != nil {
+		if err := apps.DeleteAppHookJob(ctx, *ref); err != nil {
+			log.Warnf("Failed to delete status hook %v: %v.", ref, trace.DebugReport(err))
+		}
+	}
+	if err != nil {
+		return trace.Wrap(err, "status hook failed: %s", out)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type RollbackSuite struct{}
+
+var _ = check.Suite(&RollbackSuite{})
+
+func (s *RollbackSuite) TestHealthyWhenAllChecksSucceed(c *check.C) {
+	var checked []string
+	err := waitForHealthy(context.TODO(), 0,
+		recordingCheck(&checked, "planet", nil),
+		recordingCheck(&checked, "hook", nil))
+	c.Assert(err, check.IsNil)
+	c.Assert(checked, check.DeepEquals, []string{"planet", "hook"})
+}
+
+func (s *RollbackSuite) TestUnhealthyAfterGracePeriod(c *check.C) {
+	var checked []string
+	err := waitForHealthy(context.TODO(), 0,
+		recordingCheck(&checked, "planet", trace.BadParameter("cluster is degraded")),
+		recordingCheck(&checked, "hook", nil))
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+	c.Assert(err, check.ErrorMatches, "cluster has not become healthy within 0s after the upgrade: cluster is degraded")
+	c.Assert(checked, check.DeepEquals, []string{"planet"},
+		check.Commentf("Status hook should not run while planet is unhealthy."))
+}
+
+func (s *RollbackSuite) TestUnhealthyOnCanceledContext(c *check.C) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	var checked []string
+	err := waitForHealthy(ctx, time.Minute,
+		recordingCheck(&checked, "planet", trace.ConnectionProblem(nil, "agent is not available")))
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+	c.Assert(checked, check.DeepEquals, []string{"planet"})
+}
+
+func (s *RollbackSuite) TestChecksPlanetSystemStatus(c *check.C) {
+	var testCases = []struct {
+		status  agentpb.SystemStatus_Type
+		healthy bool
+	}{
+		{status: agentpb.SystemStatus_Running, healthy: true},
+		{status: agentpb.SystemStatus_Degraded},
+		{status: agentpb.SystemStatus_Unknown},
+	}
+	for _, tc := range testCases {
+		comment := check.Commentf("system status %v", tc.status)
+		err := checkSystemStatus(status.Agent{SystemStatus: status.SystemStatus(tc.status)})
+		if tc.healthy {
+			c.Assert(err, check.IsNil, comment)
+		} else {
+			c.Assert(trace.IsBadParameter(err), check.Equals, true, comment)
+		}
+	}
+}
+
+func (s *RollbackSuite) TestSkipsMissingStatusHook(c *check.C) {
+	apps := &hookApps{}
+	err := checkStatusHook(context.TODO(), apps, app.Application{
+		Package:  loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		Manifest: schema.Manifest{},
+	}, storage.OSUser{})
+	c.Assert(err, check.IsNil)
+	c.Assert(apps.started, check.Equals, 0)
+}
+
+func (s *RollbackSuite) TestStatusHookSucceeds(c *check.C) {
+	apps := &hookApps{}
+	err := checkStatusHook(context.TODO(), apps, applicationWithStatusHook(), storage.OSUser{})
+	c.Assert(err, check.IsNil)
+	c.Assert(apps.started, check.Equals, 1)
+	c.Assert(apps.deleted, check.Equals, 1, check.Commentf("Hook job should be cleaned up."))
+}
+
+func (s *RollbackSuite) TestStatusHookFails(c *check.C) {
+	apps := &hookApps{err: trace.BadParameter("job failed")}
+	err := checkStatusHook(context.TODO(), apps, applicationWithStatusHook(), storage.OSUser{})
+	c.Assert(err, check.NotNil)
+	c.Assert(trace.UserMessage(err), check.Matches, "job failed, status hook failed.*")
+	c.Assert(apps.deleted, check.Equals, 1, check.Commentf("Hook job should be cleaned up."))
+}
+
+func recordingCheck(checked *[]string, name string, err error) healthCheck {
+	return func(context.Context) error {
+		*checked = append(*checked, name)
+		return err
+	}
+}
+
+func applicationWithStatusHook() app.Application {
+	return app.Application{
+		Package: loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		Manifest: schema.Manifest{
+			Hooks: &schema.Hooks{
+				Status: &schema.Hook{Type: schema.HookStatus, Job: "job"},
+			},
+		},
+	}
+}
+
+// hookApps is an application service that runs hooks with
+// the preconfigured outcome
+type hookApps struct {
+	app.Applications
+	// err is the hook outcome
+	err error
+	// started counts started hooks
+	started int
+	// deleted counts deleted hook jobs
+	deleted int
+}
+
+func (a *hookApps) StartAppHook(ctx context.Context, req app.HookRunRequest) (*app.HookRef, error) {
+	a.started++
+	return &app.HookRef{Application: req.Application, Hook: req.Hook, Name: "status"}, nil
+}
+
+func (a *hookApps) WaitAppHook(context.Context, app.HookRef) error {
+	return a.err
+}
+
+func (a *hookApps) StreamAppHookLogs(context.Context, app.HookRef, io.Writer) error {
+	return nil
+}
+
+func (a *hookApps) DeleteAppHookJob(context.Context, app.HookRef) error {
+	a.deleted++
+	return nil
+}
@@ -40,23 +40,12 @@ import (
 
 func validate(ctx context.Context, remote fsm.AgentRepository, servers []storage.Server, old, new schema.Manifest,
 	docker storage.DockerConfig) error {
+	nodes, err := getServerInfos(ctx, remote, servers)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	profiles := make(map[string]string, len(servers))
-	nodes := make([]checks.Server, 0, len(servers))
 	for _, server := range servers {
-		connectCtx, cancel := context.WithTimeout(ctx, defaults.AgentConnectTimeout)
-		clt, err := remote.GetClient(connectCtx, server.AdvertiseIP)
-		cancel()
-		if err != nil {
-			return trace.Wrap(err, "failed to connect to agent.\n"+
-				"Make sure the node has an agent running by "+
-				"issuing `gravity agent deploy` from the upgrade node")
-		}
-
-		info, err := checks.GetServerInfo(ctx, clt)
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		nodes = append(nodes, checks.Server{server, *info})
 		profiles[server.AdvertiseIP] = server.Role
 	}
 
@@ -76,6 +65,44 @@ func validate(ctx context.Context, remote fsm.AgentRepository, servers []storage
 	return trace.Wrap(c.Run(ctx))
 }
 
+// CheckNetwork executes the full-mesh network test between the specified
+// cluster servers using the agents running on them and returns the measured network paths
+func CheckNetwork(ctx context.Context, remote fsm.AgentRepository, servers []storage.Server) ([]checks.NetworkPath, error) {
+	nodes, err := getServerInfos(ctx, remote, servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	paths, err := checks.RunNetworkTest(ctx, &remoteCommands{remote: remote}, nodes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return paths, nil
+}
+
+func getServerInfos(ctx context.Context, remote fsm.AgentRepository, servers []storage.Server) ([]checks.Server, error) {
+	nodes := make([]checks.Server, 0, len(servers))
+	for _, server := range servers {
+		connectCtx, cancel := context.WithTimeout(ctx, defaults.AgentConnectTimeout)
+		clt, err := remote.GetClient(connectCtx, server.AdvertiseIP)
+		cancel()
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to connect to agent.\n"+
+				"Make sure the node has an agent running by "+
+				"issuing `gravity agent deploy` from the upgrade node")
+		}
+
+		info, err := checks.GetServerInfo(ctx, clt)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		nodes = append(nodes, checks.Server{
+			Server:     server,
+			ServerInfo: *info,
+		})
+	}
+	return nodes, nil
+}
+
 // Exec executes an arbitrary command on the remote node specified with addr.
 // The output is written into out
 func (r *remoteCommands) Exec(ctx context.Context, addr string, command []string, out io.Writer) error {
@@ -104,6 +131,15 @@ func (r *remoteCommands) CheckBandwidth(ctx context.Context, req checks.PingPong
 	return resp, nil
 }
 
+// CheckNetwork validates the cluster network latency, packet loss and path MTU
+func (r *remoteCommands) CheckNetwork(ctx context.Context, req checks.PingPongGame) (checks.PingPongGameResults, error) {
+	resp, err := pingPong(ctx, r.remote, req, network)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp, nil
+}
+
 // Validate validates the node given with addr against the specified manifest.
 // Returns the list of failed test results.
 func (r *remoteCommands) Validate(ctx context.Context, addr string, manifest schema.Manifest, profileName string) ([]*agentpb.Probe, error) {
@@ -157,7 +193,7 @@ func pingPong(ctx context.Context, remote fsm.AgentRepository, game checks.PingP
 				return nil, trace.Wrap(result.err)
 			}
 			results[result.addr] = *result.resp
-		case <-time.After(2 * req.Duration):
+		case <-time.After(req.Timeout()):
 			return nil, trace.LimitExceeded("timeout waiting for servers")
 		}
 	}
@@ -182,6 +218,15 @@ func bandwidth(ctx context.Context, addr string, clt rpcclient.Client, req check
 	resultsCh <- pingpongResult{addr: addr, resp: checks.ResultFromBandwidthProto(resp, nil)}
 }
 
+func network(ctx context.Context, addr string, clt rpcclient.Client, req checks.PingPongRequest, resultsCh chan<- pingpongResult) {
+	resp, err := clt.CheckNetwork(ctx, req.NetworkProto())
+	if err != nil {
+		resultsCh <- pingpongResult{addr: addr, err: err}
+		return
+	}
+	resultsCh <- pingpongResult{addr: addr, resp: checks.ResultFromNetworkProto(resp, nil)}
+}
+
 type pingpongHandler func(ctx context.Context, addr string, clt rpcclient.Client,
 	req checks.PingPongRequest, resultsCh chan<- pingpongResult)
 
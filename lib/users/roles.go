@@ -313,6 +313,12 @@ func NewClusterAgentRole(name string, clusterName string) (teleservices.Role, er
 						teleservices.VerbUpdate,
 					},
 				},
+				{
+					// joining nodes apply the cluster host configuration
+					// and trusted certificate authorities
+					Resources: []string{storage.KindHostConfig, storage.KindTrustedCA, storage.KindProxy},
+					Verbs:     []string{teleservices.VerbRead},
+				},
 			},
 		},
 	})
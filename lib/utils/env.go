@@ -19,6 +19,7 @@ package utils
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -36,8 +37,14 @@ func ReadEnv(path string) (map[string]string, error) {
 		return nil, trace.Wrap(err)
 	}
 	defer file.Close()
+	return ParseEnv(file)
+}
+
+// ParseEnv parses environment variables in the format of /etc/environment
+// from the provided reader
+func ParseEnv(r io.Reader) (map[string]string, error) {
 	env := make(map[string]string)
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		kv := strings.SplitN(scanner.Text(), "=", 2)
 		if len(kv) != 2 {
@@ -92,6 +92,23 @@ func WritePath(path string, data []byte, perm os.FileMode) error {
 	return nil
 }
 
+// WriteOrRemovePath replaces the contents of the file at the given path
+// with data, creating the parent directories as needed, or removes
+// the file if data is empty
+func WriteOrRemovePath(path string, data []byte) error {
+	if len(data) == 0 {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return WritePath(path, data, defaults.SharedReadMask)
+}
+
 // ReadPath reads file at given path
 func ReadPath(path string) ([]byte, error) {
 	abs, err := NormalizePath(path)
@@ -18,6 +18,7 @@ package utils
 
 import (
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -61,3 +62,16 @@ qwe
 1234
 5678`))
 }
+
+func (s *FileutilsSuite) TestWritesOrRemovesPath(c *C) {
+	path := filepath.Join(c.MkDir(), "dir", "file")
+	c.Assert(WriteOrRemovePath(path, []byte("data")), IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "data")
+
+	c.Assert(WriteOrRemovePath(path, nil), IsNil)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), Equals, true)
+	c.Assert(WriteOrRemovePath(path, nil), IsNil)
+}
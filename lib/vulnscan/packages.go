@@ -0,0 +1,283 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vulnscan
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+)
+
+// Package is an OS package installed in the image
+type Package struct {
+	// Name is the package name
+	Name string `json:"name"`
+	// Version is the installed package version
+	Version string `json:"version"`
+	// Source is the name of the source package the package was built from
+	Source string `json:"source,omitempty"`
+}
+
+// OS identifies the distribution of the image
+type OS struct {
+	// ID is the distribution ID from os-release, e.g. debian or alpine
+	ID string `json:"id"`
+	// VersionID is the distribution version from os-release, e.g. 3.9.4
+	VersionID string `json:"versionID,omitempty"`
+}
+
+// imageFiles is the view of package database files of an image
+// assembled from its layers
+type imageFiles map[string][]byte
+
+// layer describes changes of the package database files made by an image layer
+type layer struct {
+	// files maps paths of the package database files to their contents
+	files map[string][]byte
+	// removed lists whiteout paths
+	removed []string
+	// opaque lists directories whose contents from lower layers are hidden
+	opaque []string
+}
+
+// readLayer reads the package database files from the layer tarball at the specified path
+func readLayer(path string) (*layer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	stream, err := archive.DecompressStream(f)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer stream.Close()
+	layer, err := parseLayer(tar.NewReader(stream))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return layer, nil
+}
+
+// parseLayer returns the package database changes from the layer tarball
+func parseLayer(reader *tar.Reader) (*layer, error) {
+	layer := &layer{files: map[string][]byte{}}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		dir, base := path.Split(name)
+		switch {
+		case base == opaqueWhiteout:
+			layer.opaque = append(layer.opaque, strings.TrimSuffix(dir, "/"))
+		case strings.HasPrefix(base, whiteoutPrefix):
+			layer.removed = append(layer.removed, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+		case header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA:
+			if !isPackageFile(name) {
+				continue
+			}
+			data, err := ioutil.ReadAll(reader)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			layer.files[name] = data
+		}
+	}
+	return layer, nil
+}
+
+// apply applies the layer on top of the files from the lower layers
+func (r imageFiles) apply(layer layer) {
+	for _, dir := range layer.opaque {
+		r.removeDir(dir)
+	}
+	for _, name := range layer.removed {
+		delete(r, name)
+		r.removeDir(name)
+	}
+	for name, data := range layer.files {
+		r[name] = data
+	}
+}
+
+func (r imageFiles) removeDir(dir string) {
+	for name := range r {
+		if strings.HasPrefix(name, dir+"/") {
+			delete(r, name)
+		}
+	}
+}
+
+// os returns the image distribution
+func (r imageFiles) os() *OS {
+	for _, name := range osReleaseFiles {
+		if data, ok := r[name]; ok {
+			return parseOSRelease(data)
+		}
+	}
+	return nil
+}
+
+// packages returns all installed OS packages
+func (r imageFiles) packages() (packages []Package) {
+	var names []string
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch {
+		case name == dpkgStatusFile, path.Dir(name) == dpkgStatusDir:
+			packages = append(packages, parseDpkgStatus(r[name])...)
+		case name == apkInstalledFile:
+			packages = append(packages, parseApkInstalled(r[name])...)
+		}
+	}
+	return packages
+}
+
+// parseDpkgStatus returns installed packages from the dpkg status database
+func parseDpkgStatus(data []byte) (packages []Package) {
+	for _, stanza := range parseStanzas(data) {
+		if !strings.HasSuffix(stanza["Status"], " installed") && stanza["Status"] != "" {
+			continue
+		}
+		if stanza["Package"] == "" {
+			continue
+		}
+		source := stanza["Source"]
+		// the source may include the source package version, e.g. openssl (1.1.1d-0)
+		if fields := strings.Fields(source); len(fields) != 0 {
+			source = fields[0]
+		}
+		packages = append(packages, Package{
+			Name:    stanza["Package"],
+			Version: stanza["Version"],
+			Source:  source,
+		})
+	}
+	return packages
+}
+
+// parseApkInstalled returns installed packages from the apk database
+func parseApkInstalled(data []byte) (packages []Package) {
+	for _, stanza := range parseStanzas(data) {
+		if stanza["P"] == "" {
+			continue
+		}
+		packages = append(packages, Package{
+			Name:    stanza["P"],
+			Version: stanza["V"],
+			Source:  stanza["o"],
+		})
+	}
+	return packages
+}
+
+// parseStanzas parses blank line-separated blocks of colon-separated
+// key/value fields. Continuation lines are ignored
+func parseStanzas(data []byte) (stanzas []map[string]string) {
+	stanza := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(stanza) != 0 {
+				stanzas = append(stanzas, stanza)
+				stanza = map[string]string{}
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		stanza[parts[0]] = strings.TrimSpace(parts[1])
+	}
+	if len(stanza) != 0 {
+		stanzas = append(stanzas, stanza)
+	}
+	return stanzas
+}
+
+// parseOSRelease parses the distribution from os-release data
+func parseOSRelease(data []byte) *OS {
+	var release OS
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.Trim(parts[1], `"'`)
+		switch parts[0] {
+		case "ID":
+			release.ID = value
+		case "VERSION_ID":
+			release.VersionID = value
+		}
+	}
+	return &release
+}
+
+func isPackageFile(name string) bool {
+	switch {
+	case name == dpkgStatusFile, name == apkInstalledFile:
+		return true
+	case path.Dir(name) == dpkgStatusDir:
+		return true
+	}
+	for _, release := range osReleaseFiles {
+		if name == release {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// dpkgStatusFile is the dpkg database of Debian-based images
+	dpkgStatusFile = "var/lib/dpkg/status"
+	// dpkgStatusDir is the directory with per-package dpkg databases of distroless images
+	dpkgStatusDir = "var/lib/dpkg/status.d"
+	// apkInstalledFile is the apk database of Alpine-based images
+	apkInstalledFile = "lib/apk/db/installed"
+	// whiteoutPrefix marks files removed in the layer
+	whiteoutPrefix = ".wh."
+	// opaqueWhiteout marks directories whose contents are replaced in the layer
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// osReleaseFiles lists locations of the os-release file in the order of preference
+var osReleaseFiles = []string{"etc/os-release", "usr/lib/os-release"}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vulnscan
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares two OS package versions using the dpkg
+// ordering rules ([epoch:]upstream[-revision]).
+// Returns a negative number if a < b, zero if a == b and a positive number otherwise.
+//
+// Alpine package versions (e.g. 1.1.1g-r0) sort correctly under the same rules
+func compareVersions(a, b string) int {
+	epochA, upstreamA, revisionA := splitVersion(a)
+	epochB, upstreamB, revisionB := splitVersion(b)
+	if epochA != epochB {
+		return epochA - epochB
+	}
+	if result := compareFragments(upstreamA, upstreamB); result != 0 {
+		return result
+	}
+	return compareFragments(revisionA, revisionB)
+}
+
+// splitVersion splits the version into epoch, upstream version and revision
+func splitVersion(version string) (epoch int, upstream, revision string) {
+	upstream = version
+	if index := strings.Index(upstream, ":"); index > 0 {
+		if value, err := strconv.Atoi(upstream[:index]); err == nil {
+			epoch = value
+			upstream = upstream[index+1:]
+		}
+	}
+	if index := strings.LastIndex(upstream, "-"); index >= 0 {
+		revision = upstream[index+1:]
+		upstream = upstream[:index]
+	}
+	return epoch, upstream, revision
+}
+
+// compareFragments compares upstream versions or revisions by alternating
+// between the non-digit and digit parts of both
+func compareFragments(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			orderA, orderB := charOrder(a), charOrder(b)
+			if orderA != orderB {
+				return orderA - orderB
+			}
+			a, b = advance(a), advance(b)
+		}
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		var firstDiff int
+		for a != "" && b != "" && isDigit(a[0]) && isDigit(b[0]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+		if a != "" && isDigit(a[0]) {
+			return 1
+		}
+		if b != "" && isDigit(b[0]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+// charOrder returns the sort weight of the first character of s:
+// tilde sorts before everything, even the end of the string,
+// letters sort before all other non-digit characters
+func charOrder(s string) int {
+	if s == "" {
+		return 0
+	}
+	c := s[0]
+	switch {
+	case isDigit(c):
+		return 0
+	case isLetter(c):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func advance(s string) string {
+	if s == "" {
+		return s
+	}
+	return s[1:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vulnscan implements offline vulnerability scanning of Docker
+// images stored in a registry directory.
+//
+// The scanner assembles the OS package databases (dpkg and apk) of each
+// image from its layers and matches the installed packages against
+// a vulnerability database read from a local file, so no network
+// access is required.
+package vulnscan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	digest "github.com/opencontainers/go-digest"
+	log "github.com/sirupsen/logrus"
+)
+
+// Severity is the vulnerability severity
+type Severity string
+
+// ParseSeverity parses the severity from the provided string
+func ParseSeverity(value string) (Severity, error) {
+	severity := Severity(strings.ToLower(value))
+	for _, known := range severities {
+		if severity == known {
+			return severity, nil
+		}
+	}
+	return "", trace.BadParameter("unknown severity %q, supported are %v", value, severities)
+}
+
+// AtLeast returns true if the severity is the same or higher than the specified one
+func (s Severity) AtLeast(other Severity) bool {
+	return s.rank() >= other.rank()
+}
+
+func (s Severity) rank() int {
+	for i, severity := range severities {
+		if s == severity {
+			return i
+		}
+	}
+	return 0
+}
+
+const (
+	// SeverityUnknown is the severity of vulnerabilities without one
+	SeverityUnknown Severity = "unknown"
+	// SeverityLow is the low severity
+	SeverityLow Severity = "low"
+	// SeverityMedium is the medium severity
+	SeverityMedium Severity = "medium"
+	// SeverityHigh is the high severity
+	SeverityHigh Severity = "high"
+	// SeverityCritical is the critical severity
+	SeverityCritical Severity = "critical"
+)
+
+// severities lists all severities in ascending order
+var severities = []Severity{SeverityUnknown, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Database is the vulnerability database
+type Database struct {
+	// Vulnerabilities lists known vulnerabilities
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Vulnerability describes a vulnerability of an OS package
+type Vulnerability struct {
+	// ID is the vulnerability ID, e.g. CVE-2019-1543
+	ID string `json:"id"`
+	// OS is the optional distribution ID the vulnerability applies to, e.g. debian
+	OS string `json:"os,omitempty"`
+	// OSVersion is the optional distribution version prefix, e.g. 3.9
+	OSVersion string `json:"osVersion,omitempty"`
+	// Package is the name of the vulnerable binary or source package
+	Package string `json:"package"`
+	// FixedVersion is the first package version with the fix.
+	// If empty, all versions are vulnerable
+	FixedVersion string `json:"fixedVersion,omitempty"`
+	// Severity is the vulnerability severity
+	Severity Severity `json:"severity"`
+	// Description is the optional vulnerability description
+	Description string `json:"description,omitempty"`
+}
+
+// ReadDatabase reads the vulnerability database from the JSON or YAML file at the specified path
+func ReadDatabase(path string) (*Database, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	database, err := ParseDatabase(data)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse vulnerability database %v", path)
+	}
+	return database, nil
+}
+
+// ParseDatabase parses the vulnerability database from the provided JSON or YAML data
+func ParseDatabase(data []byte) (*Database, error) {
+	var database Database
+	if err := yaml.Unmarshal(data, &database); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for i, vulnerability := range database.Vulnerabilities {
+		if vulnerability.ID == "" || vulnerability.Package == "" {
+			return nil, trace.BadParameter("vulnerability requires both id and package: %+v", vulnerability)
+		}
+		if vulnerability.Severity == "" {
+			database.Vulnerabilities[i].Severity = SeverityUnknown
+			continue
+		}
+		severity, err := ParseSeverity(string(vulnerability.Severity))
+		if err != nil {
+			return nil, trace.Wrap(err, "invalid severity of %v", vulnerability.ID)
+		}
+		database.Vulnerabilities[i].Severity = severity
+	}
+	return &database, nil
+}
+
+// match returns vulnerabilities of the package installed in the specified distribution
+func (d Database) match(release *OS, pkg Package) (matches []Vulnerability) {
+	for _, vulnerability := range d.Vulnerabilities {
+		if vulnerability.Package != pkg.Name && vulnerability.Package != pkg.Source {
+			continue
+		}
+		if vulnerability.OS != "" && (release == nil || vulnerability.OS != release.ID) {
+			continue
+		}
+		if vulnerability.OSVersion != "" && (release == nil || !hasVersionPrefix(release.VersionID, vulnerability.OSVersion)) {
+			continue
+		}
+		if vulnerability.FixedVersion != "" && compareVersions(pkg.Version, vulnerability.FixedVersion) >= 0 {
+			continue
+		}
+		matches = append(matches, vulnerability)
+	}
+	return matches
+}
+
+// Scan scans all images stored in the registry with the specified
+// root directory for vulnerable OS packages
+func Scan(registryDir string, database Database) (*Report, error) {
+	images, err := docker.ListRegistryImages(registryDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report := &Report{Summary: map[Severity]int{}}
+	layers := map[digest.Digest]*layer{}
+	for _, image := range images {
+		name := fmt.Sprintf("%v:%v", image.Repository, image.Tag)
+		files := imageFiles{}
+		for _, descriptor := range image.Manifest.Layers {
+			layer, ok := layers[descriptor.Digest]
+			if !ok {
+				layer, err = readLayer(docker.RegistryBlobPath(registryDir, descriptor.Digest))
+				if err != nil {
+					return nil, trace.Wrap(err, "failed to read layer %v of %v", descriptor.Digest, name)
+				}
+				layers[descriptor.Digest] = layer
+			}
+			files.apply(*layer)
+		}
+		release := files.os()
+		packages := files.packages()
+		log.Debugf("Image %v: os %v, %v packages.", name, release, len(packages))
+		result := ImageReport{Image: name, OS: release, Packages: len(packages)}
+		for _, pkg := range packages {
+			for _, vulnerability := range database.match(release, pkg) {
+				result.Findings = append(result.Findings, Finding{
+					Vulnerability:    vulnerability.ID,
+					Severity:         vulnerability.Severity,
+					Package:          pkg.Name,
+					InstalledVersion: pkg.Version,
+					FixedVersion:     vulnerability.FixedVersion,
+					Description:      vulnerability.Description,
+				})
+				report.Summary[vulnerability.Severity]++
+			}
+		}
+		sort.Slice(result.Findings, func(i, j int) bool {
+			if result.Findings[i].Severity != result.Findings[j].Severity {
+				return result.Findings[i].Severity.AtLeast(result.Findings[j].Severity)
+			}
+			return result.Findings[i].Vulnerability < result.Findings[j].Vulnerability
+		})
+		report.Images = append(report.Images, result)
+	}
+	return report, nil
+}
+
+// Report is the result of the vulnerability scan
+type Report struct {
+	// Images lists scanned images
+	Images []ImageReport `json:"images"`
+	// Summary is the number of findings for each severity
+	Summary map[Severity]int `json:"summary"`
+}
+
+// ImageReport is the result of the vulnerability scan of a single image
+type ImageReport struct {
+	// Image is the image reference
+	Image string `json:"image"`
+	// OS is the image distribution, if detected
+	OS *OS `json:"os,omitempty"`
+	// Packages is the number of scanned OS packages
+	Packages int `json:"packages"`
+	// Findings lists vulnerable packages
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Finding describes a vulnerable package found in an image
+type Finding struct {
+	// Vulnerability is the vulnerability ID
+	Vulnerability string `json:"vulnerability"`
+	// Severity is the vulnerability severity
+	Severity Severity `json:"severity"`
+	// Package is the vulnerable package name
+	Package string `json:"package"`
+	// InstalledVersion is the installed package version
+	InstalledVersion string `json:"installedVersion"`
+	// FixedVersion is the first package version with the fix
+	FixedVersion string `json:"fixedVersion,omitempty"`
+	// Description is the vulnerability description
+	Description string `json:"description,omitempty"`
+}
+
+// Check returns an error if the report has findings with the severity
+// at or above the specified threshold
+func (r Report) Check(threshold Severity) error {
+	var violations []string
+	for _, image := range r.Images {
+		for _, finding := range image.Findings {
+			if finding.Severity.AtLeast(threshold) {
+				violations = append(violations, fmt.Sprintf("%v: %v (%v) in %v %v",
+					image.Image, finding.Vulnerability, finding.Severity,
+					finding.Package, finding.InstalledVersion))
+			}
+		}
+	}
+	if len(violations) != 0 {
+		return trace.BadParameter("found %v vulnerabilities with severity %v or higher:\n%v",
+			len(violations), threshold, strings.Join(violations, "\n"))
+	}
+	return nil
+}
+
+// String returns the summary of the report
+func (r Report) String() string {
+	var counts []string
+	for i := len(severities) - 1; i >= 0; i-- {
+		if count := r.Summary[severities[i]]; count != 0 {
+			counts = append(counts, fmt.Sprintf("%v %v", count, severities[i]))
+		}
+	}
+	if len(counts) == 0 {
+		return fmt.Sprintf("%v images, no vulnerabilities found", len(r.Images))
+	}
+	return fmt.Sprintf("%v images, %v", len(r.Images), strings.Join(counts, ", "))
+}
+
+// Write writes the report to the provided writer in JSON format
+func (r Report) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = w.Write(data)
+	return trace.Wrap(err)
+}
+
+// ReportFileName returns the path of the vulnerability report file for the
+// installer tarball with the specified path, e.g. app-1.0.0.vulns.json for app-1.0.0.tar
+func ReportFileName(installerPath string) string {
+	return strings.TrimSuffix(installerPath, filepath.Ext(installerPath)) + reportSuffix
+}
+
+// hasVersionPrefix returns true if the version starts with the specified
+// prefix on the version component boundary, e.g. 3.9.4 starts with 3.9
+func hasVersionPrefix(version, prefix string) bool {
+	return version == prefix || strings.HasPrefix(version, prefix+".")
+}
+
+// reportSuffix is the suffix of vulnerability report files
+const reportSuffix = ".vulns.json"
@@ -0,0 +1,242 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vulnscan
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	digest "github.com/opencontainers/go-digest"
+	"gopkg.in/check.v1"
+)
+
+func TestVulnScan(t *testing.T) { check.TestingT(t) }
+
+type VulnScanSuite struct{}
+
+var _ = check.Suite(&VulnScanSuite{})
+
+func (s *VulnScanSuite) TestCompareVersions(c *check.C) {
+	testCases := []struct {
+		a, b   string
+		result int
+	}{
+		{a: "1.0", b: "1.0", result: 0},
+		{a: "1.0", b: "1.1", result: -1},
+		{a: "1.10", b: "1.9", result: 1},
+		{a: "1.0~rc1", b: "1.0", result: -1},
+		{a: "1:0.9", b: "1.0", result: 1},
+		{a: "1.1.1d-0+deb10u2", b: "1.1.1d-0+deb10u4", result: -1},
+		{a: "1.1.1g-r0", b: "1.1.1i-r0", result: -1},
+		{a: "2.28-10", b: "2.28-9", result: 1},
+		{a: "1.0a", b: "1.0+", result: -1},
+	}
+	for _, tc := range testCases {
+		result := compareVersions(tc.a, tc.b)
+		switch {
+		case result < 0:
+			result = -1
+		case result > 0:
+			result = 1
+		}
+		c.Assert(result, check.Equals, tc.result, check.Commentf("%v vs %v", tc.a, tc.b))
+	}
+}
+
+func (s *VulnScanSuite) TestParsesDatabase(c *check.C) {
+	database, err := ParseDatabase([]byte(`
+vulnerabilities:
+- id: CVE-2019-1543
+  package: openssl
+  fixedVersion: 1.1.1c-1
+  severity: HIGH
+- id: CVE-2019-0000
+  package: zlib
+`))
+	c.Assert(err, check.IsNil)
+	c.Assert(database.Vulnerabilities[0].Severity, check.Equals, SeverityHigh)
+	c.Assert(database.Vulnerabilities[1].Severity, check.Equals, SeverityUnknown)
+
+	_, err = ParseDatabase([]byte(`{"vulnerabilities": [{"id": "CVE-1", "package": "a", "severity": "severe"}]}`))
+	c.Assert(err, check.NotNil)
+}
+
+func (s *VulnScanSuite) TestScansImages(c *check.C) {
+	root := c.MkDir()
+	base := writeLayer(c, root, map[string]string{
+		"etc/os-release": "ID=debian\nVERSION_ID=\"10\"\n",
+		"var/lib/dpkg/status": `Package: libssl1.1
+Status: install ok installed
+Source: openssl
+Version: 1.1.1d-0+deb10u2
+Description: SSL shared libraries
+ continuation line
+
+Package: zlib1g
+Status: install ok installed
+Version: 1:1.2.11.dfsg-1
+
+Package: removed
+Status: deinstall ok config-files
+Version: 1.0
+`,
+	})
+	// upgrade openssl in the top layer
+	upgrade := writeLayer(c, root, map[string]string{
+		"var/lib/dpkg/status": `Package: libssl1.1
+Status: install ok installed
+Source: openssl (1.1.1d-0+deb10u4)
+Version: 1.1.1d-0+deb10u4
+
+Package: zlib1g
+Status: install ok installed
+Version: 1:1.2.11.dfsg-1
+`,
+	})
+	alpine := writeLayer(c, root, map[string]string{
+		"etc/os-release": "ID=alpine\nVERSION_ID=3.9.4\n",
+		"lib/apk/db/installed": `C:Q1
+P:musl
+V:1.1.20-r4
+o:musl
+
+P:libcrypto1.1
+V:1.1.1b-r1
+o:openssl
+`,
+	})
+	writeImage(c, root, "example/old", "1.0", base)
+	writeImage(c, root, "example/new", "1.0", base, upgrade)
+	writeImage(c, root, "example/alpine", "3.9", alpine)
+
+	database := Database{Vulnerabilities: []Vulnerability{
+		{ID: "CVE-1", OS: "debian", Package: "openssl", FixedVersion: "1.1.1d-0+deb10u3", Severity: SeverityCritical},
+		{ID: "CVE-2", Package: "zlib1g", Severity: SeverityLow},
+		{ID: "CVE-3", OS: "alpine", OSVersion: "3.9", Package: "openssl", FixedVersion: "1.1.1b-r2", Severity: SeverityHigh},
+		{ID: "CVE-4", OS: "alpine", OSVersion: "3.10", Package: "musl", Severity: SeverityHigh},
+		{ID: "CVE-5", Package: "removed", Severity: SeverityCritical},
+	}}
+	report, err := Scan(root, database)
+	c.Assert(err, check.IsNil)
+	c.Assert(report.Images, check.HasLen, 3)
+
+	images := map[string]ImageReport{}
+	for _, image := range report.Images {
+		images[image.Image] = image
+	}
+	c.Assert(images["example/old:1.0"].OS, check.DeepEquals, &OS{ID: "debian", VersionID: "10"})
+	c.Assert(images["example/old:1.0"].Packages, check.Equals, 2)
+	c.Assert(findingIDs(images["example/old:1.0"]), check.DeepEquals, []string{"CVE-1", "CVE-2"})
+	c.Assert(findingIDs(images["example/new:1.0"]), check.DeepEquals, []string{"CVE-2"})
+	c.Assert(findingIDs(images["example/alpine:3.9"]), check.DeepEquals, []string{"CVE-3"})
+	c.Assert(report.Summary, check.DeepEquals, map[Severity]int{
+		SeverityCritical: 1,
+		SeverityHigh:     1,
+		SeverityLow:      2,
+	})
+
+	c.Assert(report.Check(SeverityCritical), check.ErrorMatches, "(?s)found 1 vulnerabilities with severity critical or higher.*CVE-1.*")
+	c.Assert(report.Check(SeverityHigh), check.NotNil)
+	c.Assert(Report{}.Check(SeverityLow), check.IsNil)
+}
+
+func (s *VulnScanSuite) TestAppliesWhiteouts(c *check.C) {
+	files := imageFiles{}
+	files.apply(layer{files: map[string][]byte{
+		"var/lib/dpkg/status.d/base":   []byte("Package: base-files\nVersion: 10.3\n"),
+		"var/lib/dpkg/status.d/tzdata": []byte("Package: tzdata\nVersion: 2019c\n"),
+	}})
+	files.apply(layer{removed: []string{"var/lib/dpkg/status.d/tzdata"}})
+	c.Assert(files.packages(), check.DeepEquals, []Package{{Name: "base-files", Version: "10.3"}})
+
+	files.apply(layer{
+		opaque: []string{"var/lib/dpkg/status.d"},
+		files: map[string][]byte{
+			"var/lib/dpkg/status.d/libc6": []byte("Package: libc6\nVersion: 2.28-10\n"),
+		},
+	})
+	c.Assert(files.packages(), check.DeepEquals, []Package{{Name: "libc6", Version: "2.28-10"}})
+}
+
+func (s *VulnScanSuite) TestReportFileName(c *check.C) {
+	c.Assert(ReportFileName("app-1.0.0.tar"), check.Equals, "app-1.0.0.vulns.json")
+}
+
+func findingIDs(image ImageReport) (ids []string) {
+	for _, finding := range image.Findings {
+		ids = append(ids, finding.Vulnerability)
+	}
+	return ids
+}
+
+// writeLayer writes a gzipped layer tarball with the specified files
+// to the registry blobs and returns its digest
+func writeLayer(c *check.C, root string, files map[string]string) digest.Digest {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		c.Assert(tw.WriteHeader(&tar.Header{
+			Name:     "./" + name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}), check.IsNil)
+		_, err := tw.Write([]byte(data))
+		c.Assert(err, check.IsNil)
+	}
+	c.Assert(tw.Close(), check.IsNil)
+	c.Assert(gz.Close(), check.IsNil)
+	dgst := digest.FromBytes(buf.Bytes())
+	writeFile(c, docker.RegistryBlobPath(root, dgst), buf.Bytes())
+	return dgst
+}
+
+// writeImage writes the manifest of the image with the specified layers to the registry
+func writeImage(c *check.C, root, repository, tag string, layers ...digest.Digest) {
+	manifest := schema2.Manifest{Config: distribution.Descriptor{
+		MediaType: schema2.MediaTypeImageConfig,
+		Digest:    digest.FromString(repository),
+	}}
+	for _, layer := range layers {
+		manifest.Layers = append(manifest.Layers, distribution.Descriptor{
+			MediaType: schema2.MediaTypeLayer,
+			Digest:    layer,
+		})
+	}
+	data, err := json.Marshal(manifest)
+	c.Assert(err, check.IsNil)
+	dgst := digest.FromBytes(data)
+	writeFile(c, docker.RegistryBlobPath(root, dgst), data)
+	writeFile(c, filepath.Join(root, "docker", "registry", "v2", "repositories", repository,
+		"_manifests", "tags", tag, "current", "link"), []byte(dgst))
+}
+
+func writeFile(c *check.C, path string, data []byte) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(path, data, 0644), check.IsNil)
+}
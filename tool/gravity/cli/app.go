@@ -35,6 +35,7 @@ import (
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/sbom"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/systeminfo"
 	"github.com/gravitational/gravity/lib/utils"
@@ -90,6 +91,44 @@ func statusApp(env *localenv.LocalEnvironment, appPackage loc.Locator, portalURL
 	return nil
 }
 
+// appSBOM outputs the software bill of materials of the specified application
+// to the file at outPath or stdout
+func appSBOM(env *localenv.LocalEnvironment, appPackage loc.Locator, outPath, opsCenterURL string) error {
+	apps, err := env.AppService(opsCenterURL, localenv.AppConfig{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	packages, err := env.PackageService(opsCenterURL)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	application, err := apps.GetApp(appPackage)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	bom, err := sbom.Generate(sbom.Config{
+		Apps:        apps,
+		Packages:    packages,
+		Application: *application,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if outPath == "" {
+		return trace.Wrap(bom.Write(os.Stdout))
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if err := bom.Write(f); err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Software bill of materials of %v saved to %v\n", application.Package, outPath)
+	return nil
+}
+
 // listApps lists installed applications
 func listApps(env *localenv.LocalEnvironment, repository, appType string, showHidden bool, opsCenterURL string) error {
 	apps, err := env.AppService(opsCenterURL, localenv.AppConfig{})
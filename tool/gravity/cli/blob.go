@@ -0,0 +1,107 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	blobcluster "github.com/gravitational/gravity/lib/blob/cluster"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/processconfig"
+
+	"github.com/gravitational/trace"
+)
+
+// blobStatus displays replica health of objects in the cluster BLOB storage.
+// If minReplicas is not set, the value from the cluster configuration is used
+func blobStatus(env *localenv.LocalEnvironment, minReplicas int, format constants.Format) error {
+	clusterEnv, err := env.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if minReplicas == 0 {
+		minReplicas = clusterMinReplicas(clusterEnv)
+	}
+	status, err := blobcluster.GetStatus(blobcluster.StatusConfig{
+		Backend:     clusterEnv.Backend,
+		MinReplicas: minReplicas,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingText:
+		printBLOBStatus(*status)
+	case constants.EncodingJSON:
+		bytes, err := json.MarshalIndent(status, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+	default:
+		return trace.BadParameter("unsupported output format %q, supported are: %v, %v",
+			format, constants.EncodingText, constants.EncodingJSON)
+	}
+	if !status.IsHealthy() {
+		return trace.BadParameter("some objects are not sufficiently replicated")
+	}
+	return nil
+}
+
+func printBLOBStatus(status blobcluster.Status) {
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Peer\tAddress\tLast Heartbeat\tActive\n")
+	fmt.Fprintf(w, "----\t-------\t--------------\t------\n")
+	for _, p := range status.Peers {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", p.ID, p.AdvertiseAddr,
+			p.LastHeartbeat.Format(constants.HumanDateFormatSeconds), p.Active)
+	}
+	fmt.Fprintf(w, "\nObject\tReplicas\tPeers\tHealth\n")
+	fmt.Fprintf(w, "------\t--------\t-----\t------\n")
+	for _, o := range status.Objects {
+		fmt.Fprintf(w, "%v\t%v/%v\t%v\t%v\n", o.Hash, len(o.ActivePeers),
+			status.MinReplicas, strings.Join(o.Peers, ","), o.Health)
+	}
+	w.Flush()
+}
+
+// clusterMinReplicas returns the minimum number of BLOB replicas
+// configured for the cluster controller or the default value
+// if the configuration is not available
+func clusterMinReplicas(clusterEnv *localenv.ClusterEnvironment) int {
+	if clusterEnv.Client == nil {
+		log.Warn("Kubernetes client is not available, using default minimum replica count.")
+		return defaults.MinReplicas
+	}
+	config, err := processconfig.GetClusterConfig(clusterEnv.Client)
+	if err != nil {
+		log.Warnf("Failed to read cluster configuration, using default minimum replica count: %v.",
+			trace.DebugReport(err))
+		return defaults.MinReplicas
+	}
+	if config.Pack.MinReplicas < 1 {
+		return defaults.MinReplicas
+	}
+	return config.Pack.MinReplicas
+}
@@ -17,18 +17,26 @@ limitations under the License.
 package cli
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"time"
 
 	"github.com/gravitational/gravity/lib/checks"
+	"github.com/gravitational/gravity/lib/checks/survey"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/install"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/update"
+	"github.com/gravitational/gravity/lib/utils"
 
 	pb "github.com/gravitational/satellite/agent/proto/agentpb"
 	"github.com/gravitational/trace"
 )
 
-func checkManifest(env *localenv.LocalEnvironment, manifestPath, profileName string, autoFix bool) error {
+func checkManifest(env *localenv.LocalEnvironment, manifestPath, profileName string, autoFix, dryRun, confirm bool, format constants.Format) error {
 	data, err := ioutil.ReadFile(manifestPath)
 	if err != nil {
 		return trace.Wrap(err)
@@ -39,28 +47,193 @@ func checkManifest(env *localenv.LocalEnvironment, manifestPath, profileName str
 		return trace.Wrap(err)
 	}
 
+	if autoFix && !dryRun && !confirm {
+		// show the actions auto-fix is going to take before asking for confirmation
+		result, err := checks.ValidateLocal(checks.LocalChecksRequest{
+			Manifest: *manifest,
+			Role:     profileName,
+			AutoFix:  true,
+			DryRun:   true,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if len(result.Fixable) != 0 {
+			if err := enforceConfirmation("Apply the above changes to fix the failed checks?"); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+	}
+
 	result, err := checks.ValidateLocal(checks.LocalChecksRequest{
 		Manifest: *manifest,
 		Role:     profileName,
 		AutoFix:  autoFix,
+		DryRun:   dryRun,
 	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
+	if format != constants.EncodingText {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		report := checks.NewReport(hostname, *result)
+		if err := report.Write(os.Stdout, format); err != nil {
+			return trace.Wrap(err)
+		}
+		if failed := report.Failed(); len(failed) != 0 {
+			return trace.BadParameter("%v pre-flight check(s) failed", len(failed))
+		}
+		return nil
+	}
+
+	if len(result.Warnings) > 0 {
+		env.PrintStep("The following checks reported warnings, provide --autofix flag to let gravity to fix them:\n%v",
+			checks.FormatFailedChecks(result.Warnings))
+	}
+
 	var failedErr, fixableErr error
 	if len(result.Failed) > 0 {
 		failedErr = trace.BadParameter(fmt.Sprintf("The following checks failed:\n%v",
 			checks.FormatFailedChecks(result.Failed)))
 	}
 	if len(result.Fixable) > 0 {
-		fixableErr = trace.BadParameter(fmt.Sprintf("The following checks failed, provide --autofix flag to let gravity to autofix them:\n%v",
-			checks.FormatFailedChecks(result.Fixable)))
+		hint := "provide --autofix flag to let gravity to autofix them"
+		if autoFix && dryRun {
+			hint = "run without --dry-run to let gravity to autofix them"
+		}
+		fixableErr = trace.BadParameter(fmt.Sprintf("The following checks failed, %v:\n%v",
+			hint, checks.FormatFailedChecks(result.Fixable)))
 	}
 
 	return trace.NewAggregate(failedErr, fixableErr)
 }
 
+// confirmAutoFix asks the user to confirm auto-fixing of the failed pre-flight checks
+func confirmAutoFix() error {
+	return enforceConfirmation("Pre-flight checks auto-fix may disable swap and transparent hugepages, " +
+		"load kernel modules, change kernel parameters and open ports in the host firewall. Proceed?")
+}
+
+// checkUpgrade checks the cluster and the application from the installer
+// unpacked in the specified directory for Kubernetes APIs that are deprecated
+// or removed in the Kubernetes version of the installer
+func checkUpgrade(env *localenv.LocalEnvironment, installerDir string) error {
+	clusterEnv, err := env.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if clusterEnv.Client == nil {
+		return trace.BadParameter("this operation can only be executed on one of the master nodes")
+	}
+
+	installerEnv, err := localenv.New(installerDir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer installerEnv.Close()
+
+	installerApps, err := installerEnv.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	appPackage, err := install.GetAppPackage(installerApps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	app, err := installerApps.GetApp(*appPackage)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	env.PrintStep("Checking Kubernetes API versions used by the cluster and %v:%v",
+		appPackage.Name, appPackage.Version)
+	findings, err := update.CheckAPIDeprecations(update.APIDeprecationsRequest{
+		Client:   clusterEnv.Client,
+		Apps:     installerApps,
+		Packages: installerEnv.Packages,
+		App:      *app,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(findings) == 0 {
+		env.PrintStep("No deprecated Kubernetes APIs found")
+		return nil
+	}
+	findings.Format(os.Stdout)
+	if removed := findings.Removed(); len(removed) != 0 {
+		return trace.BadParameter("%v object(s) use Kubernetes APIs that are not served after the upgrade",
+			len(removed))
+	}
+	return nil
+}
+
+// checkNodes runs pre-flight checks on the nodes from the inventory
+// against the application from the installer tarball.
+// The nodes are checked remotely using temporary agents deployed over SSH
+func checkNodes(env *localenv.LocalEnvironment, config checkNodesConfig) error {
+	if config.installerPath == "" {
+		return trace.BadParameter("--installer is required to check nodes from the inventory")
+	}
+	inventory, err := survey.ReadInventory(config.inventoryPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	surveyConfig := survey.Config{
+		Inventory:     *inventory,
+		InstallerPath: config.installerPath,
+		Flavor:        config.flavor,
+		Emitter:       env,
+	}
+	if config.format != constants.EncodingText {
+		surveyConfig.Report = &checks.Report{Created: time.Now().UTC()}
+		// keep the progress off the standard output with the report
+		surveyConfig.Emitter = utils.NopEmitter()
+	}
+	// Cancel the survey on termination signals and wait for the agents
+	// deployed so far to be removed from the nodes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	surveyCtx, cancelSurvey := context.WithCancel(ctx)
+	done := make(chan struct{})
+	utils.WatchTerminationSignals(ctx, cancel, utils.StopperFunc(func(ctx context.Context) error {
+		cancelSurvey()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+	}), log)
+	err = survey.RunChecks(surveyCtx, surveyConfig)
+	close(done)
+	if surveyConfig.Report != nil {
+		// the report is written even if the checks have failed
+		reportErr := surveyConfig.Report.Write(os.Stdout, config.format)
+		return trace.NewAggregate(err, reportErr)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.PrintStep("All pre-flight checks passed on %v node(s)", len(inventory.Nodes))
+	return nil
+}
+
+type checkNodesConfig struct {
+	// inventoryPath is the path to the inventory of nodes
+	inventoryPath string
+	// installerPath is the path to the installer tarball
+	installerPath string
+	// flavor is the optional install flavor
+	flavor string
+	// format is the output format
+	format constants.Format
+}
+
 func printFailedChecks(failed []*pb.Probe) {
 	if len(failed) == 0 {
 		return
@@ -86,6 +86,10 @@ type Application struct {
 	UpgradeCmd UpgradeCmd
 	// StatusCmd displays cluster status
 	StatusCmd StatusCmd
+	// StatusClusterCmd displays the current cluster status
+	StatusClusterCmd StatusClusterCmd
+	// StatusHistoryCmd displays the cluster status history
+	StatusHistoryCmd StatusHistoryCmd
 	// StatusResetCmd resets the cluster to active state
 	StatusResetCmd StatusResetCmd
 	// BackupCmd launches app backup hook
@@ -126,6 +130,8 @@ type Application struct {
 	AppPackageUninstallCmd AppPackageUninstallCmd
 	// AppStatusCmd output app status
 	AppStatusCmd AppStatusCmd
+	// AppSBOMCmd generates application software bill of materials
+	AppSBOMCmd AppSBOMCmd
 	// AppPullCmd pulls app from specified cluster
 	AppPullCmd AppPullCmd
 	// AppPushCmd pushes app to specified cluster
@@ -266,6 +272,12 @@ type Application struct {
 	SystemEnablePromiscModeCmd SystemEnablePromiscModeCmd
 	// SystemDisablePromiscModeCmd removes promiscuous mode from interface
 	SystemDisablePromiscModeCmd SystemDisablePromiscModeCmd
+	// SystemApplyHostConfigCmd applies cluster host configuration to the node
+	SystemApplyHostConfigCmd SystemApplyHostConfigCmd
+	// SystemApplyTrustedCACmd installs trusted certificate authorities on the node
+	SystemApplyTrustedCACmd SystemApplyTrustedCACmd
+	// SystemUpdateProxyCmd executes phases of the proxy update operation
+	SystemUpdateProxyCmd SystemUpdateProxyCmd
 	// SystemExportRuntimeJournalCmd exports runtime journal to a file
 	SystemExportRuntimeJournalCmd SystemExportRuntimeJournalCmd
 	// SystemStreamRuntimeJournalCmd streams contents of the runtime journal to a file
@@ -276,6 +288,8 @@ type Application struct {
 	SystemGCPackageCmd SystemGCPackageCmd
 	// SystemGCRegistryCmd removes unused docker images
 	SystemGCRegistryCmd SystemGCRegistryCmd
+	// SystemBLOBStatusCmd displays replica health of cluster BLOB storage objects
+	SystemBLOBStatusCmd SystemBLOBStatusCmd
 	// GarbageCollectCmd prunes unused resources (package/journal files/docker images)
 	// in the cluster
 	GarbageCollectCmd GarbageCollectCmd
@@ -382,6 +396,12 @@ type InstallCmd struct {
 	DNSHosts *[]string
 	// DNSZones is a list of DNS zone overrides
 	DNSZones *[]string
+	// PreflightReport is the path to the pre-flight checks report file
+	PreflightReport *string
+	// AutoFix enables automatic fixing of the failed pre-flight checks
+	AutoFix *bool
+	// Confirm suppresses the auto-fix confirmation prompt
+	Confirm *bool
 }
 
 // JoinCmd joins to the installer or existing cluster
@@ -419,6 +439,10 @@ type JoinCmd struct {
 	Complete *bool
 	// OperationID is the ID of the operation created via UI
 	OperationID *string
+	// AutoFix enables automatic fixing of the failed pre-flight checks
+	AutoFix *bool
+	// Confirm suppresses the auto-fix confirmation prompt
+	Confirm *bool
 }
 
 // AutoJoinCmd uses cloud provider info to join existing cluster
@@ -525,6 +549,10 @@ type UpdateTriggerCmd struct {
 	App *string
 	// Manual starts operation in manual mode
 	Manual *bool
+	// RollbackOnFailure automatically rolls back the failed update
+	RollbackOnFailure *bool
+	// HealthCheckGracePeriod is the time the cluster has to become healthy after the update
+	HealthCheckGracePeriod *time.Duration
 }
 
 // UpdateUploadCmd uploads new app version to local cluster
@@ -552,6 +580,8 @@ type UpdateSystemCmd struct {
 	WithStatus *bool
 	// RuntimePackage specifies the runtime package to update to
 	RuntimePackage *loc.Locator
+	// ExactRuntime specifies whether to update to the exact runtime package version
+	ExactRuntime *bool
 }
 
 // UpgradeCmd launches app upgrade
@@ -573,6 +603,10 @@ type UpgradeCmd struct {
 	Resume *bool
 	// SkipVersionCheck suppresses version mismatch errors
 	SkipVersionCheck *bool
+	// RollbackOnFailure automatically rolls back the failed upgrade
+	RollbackOnFailure *bool
+	// HealthCheckGracePeriod is the time the cluster has to become healthy after the upgrade
+	HealthCheckGracePeriod *time.Duration
 }
 
 // StatusCmd displays cluster status
@@ -588,6 +622,25 @@ type StatusCmd struct {
 	Seconds *int
 	// Output is output format
 	Output *constants.Format
+	// Network runs the network test between the cluster nodes
+	Network *bool
+}
+
+// StatusClusterCmd displays the current cluster status.
+// It is the default subcommand of the status command
+type StatusClusterCmd struct {
+	*kingpin.CmdClause
+}
+
+// StatusHistoryCmd displays the cluster status history
+type StatusHistoryCmd struct {
+	*kingpin.CmdClause
+	// Since limits the history to the specified time window
+	Since *time.Duration
+	// Node limits the history to the specified node
+	Node *string
+	// Probe limits the history to the specified health probe
+	Probe *string
 }
 
 // StatusResetCmd resets cluster to active state
@@ -626,6 +679,21 @@ type CheckCmd struct {
 	Profile *string
 	// AutoFix enables automatic fixing of some failed checks
 	AutoFix *bool
+	// DryRun only prints the actions auto-fix would take
+	DryRun *bool
+	// Confirm suppresses the auto-fix confirmation prompt
+	Confirm *bool
+	// UpgradeTo is the path to the unpacked installer of the application
+	// to check the cluster upgrade against
+	UpgradeTo *string
+	// Output is the output format
+	Output *constants.Format
+	// Nodes is the path to the inventory of nodes to check remotely
+	Nodes *string
+	// Installer is the path to the installer tarball to check the nodes against
+	Installer *string
+	// Flavor is the install flavor to check the inventory against
+	Flavor *string
 }
 
 // AppCmd combines subcommands for app service
@@ -680,6 +748,18 @@ type AppUpgradeCmd struct {
 	RegistryCert *string
 	// RegistryKey is a registry client private key path.
 	RegistryKey *string
+	// Diff displays the changes the upgrade would make without upgrading.
+	Diff *bool
+	// Output is the diff output format.
+	Output *constants.Format
+	// Progressive enables health-gated upgrade with automatic rollback.
+	Progressive *bool
+	// HealthTimeout is the maximum time the upgraded release has to become healthy.
+	HealthTimeout *time.Duration
+	// BakeTime is the time the upgraded release has to stay healthy.
+	BakeTime *time.Duration
+	// Stages lists the progressive upgrade stages.
+	Stages *[]string
 }
 
 // AppRollbackCmd rolls back a release.
@@ -824,6 +904,17 @@ type AppStatusCmd struct {
 	OpsCenterURL *string
 }
 
+// AppSBOMCmd generates application software bill of materials
+type AppSBOMCmd struct {
+	*kingpin.CmdClause
+	// Locator is app locator
+	Locator *loc.Locator
+	// OutPath is the optional output file path
+	OutPath *string
+	// OpsCenterURL is app service URL
+	OpsCenterURL *string
+}
+
 // AppPullCmd pulls app from specified cluster
 type AppPullCmd struct {
 	*kingpin.CmdClause
@@ -1149,6 +1240,14 @@ type ReportCmd struct {
 	*kingpin.CmdClause
 	// FilePath is the report tarball path
 	FilePath *string
+	// Since limits the collected logs to the specified time window
+	Since *time.Duration
+	// Namespaces limits Kubernetes diagnostics to the specified namespaces
+	Namespaces *[]string
+	// Collectors limits the report to the specified collectors
+	Collectors *[]string
+	// RedactPatterns lists additional patterns of sensitive data to redact
+	RedactPatterns *[]string
 }
 
 // SiteCmd combines cluster related subcommands
@@ -1241,6 +1340,9 @@ type RPCAgentRunCmd struct {
 	*kingpin.CmdClause
 	// Args is additional arguments to the agent
 	Args *[]string
+	// SecretsDir is the directory with agent credentials.
+	// Defaults to the agent secrets directory under the state directory
+	SecretsDir *string
 }
 
 // SystemCmd combines system subcommands
@@ -1282,6 +1384,8 @@ type SystemPullUpdatesCmd struct {
 	OpsCenterURL *string
 	// RuntimePackage specifies the runtime package to update to
 	RuntimePackage *loc.Locator
+	// ExactRuntime specifies whether to update to the exact runtime package version
+	ExactRuntime *bool
 }
 
 // SystemUpdateCmd updates system packages
@@ -1295,6 +1399,8 @@ type SystemUpdateCmd struct {
 	WithStatus *bool
 	// RuntimePackage specifies the runtime package to update to
 	RuntimePackage *loc.Locator
+	// ExactRuntime specifies whether to update to the exact runtime package version
+	ExactRuntime *bool
 }
 
 // SystemReinstallCmd reinstalls specified system package
@@ -1393,6 +1499,18 @@ type SystemReportCmd struct {
 	Filter *[]string
 	// Compressed allows to gzip the tarball
 	Compressed *bool
+	// Since limits the collected logs to the specified time window
+	Since *time.Duration
+	// Namespaces limits Kubernetes diagnostics to the specified namespaces
+	Namespaces *[]string
+	// RedactPatterns lists additional patterns of sensitive data to redact
+	RedactPatterns *[]string
+	// EncodedRedactPatterns lists additional base64-encoded patterns
+	// of sensitive data to redact
+	EncodedRedactPatterns *[]string
+	// AppCollectors specifies the base64-encoded JSON list of
+	// application-defined report collectors to run
+	AppCollectors *string
 }
 
 // SystemStateDirCmd shows local state directory
@@ -1436,16 +1554,47 @@ type SystemDisablePromiscModeCmd struct {
 	Iface *string
 }
 
+// SystemApplyHostConfigCmd applies cluster host configuration to the node
+type SystemApplyHostConfigCmd struct {
+	*kingpin.CmdClause
+	// Spec is the base64-encoded host configuration
+	Spec *string
+}
+
+// SystemApplyTrustedCACmd installs trusted certificate authorities on the node
+type SystemApplyTrustedCACmd struct {
+	*kingpin.CmdClause
+	// Bundle is the base64-encoded CA bundle
+	Bundle *string
+}
+
+// SystemUpdateProxyCmd executes phases of the proxy update operation
+type SystemUpdateProxyCmd struct {
+	*kingpin.CmdClause
+	// Phase is the specific phase to run
+	Phase *string
+	// PhaseTimeout is the phase execution timeout
+	PhaseTimeout *time.Duration
+	// Resume is whether to resume a failed operation
+	Resume *bool
+	// Force forces phase execution
+	Force *bool
+}
+
 // SystemExportRuntimeJournalCmd exports runtime journal to a file
 type SystemExportRuntimeJournalCmd struct {
 	*kingpin.CmdClause
 	// OutputFile specifies the path of the resulting tarball
 	OutputFile *string
+	// Since limits the exported journal to the specified time window
+	Since *time.Duration
 }
 
 // SystemStreamRuntimeJournalCmd streams contents of the runtime journal
 type SystemStreamRuntimeJournalCmd struct {
 	*kingpin.CmdClause
+	// Since limits the streamed journal to the specified time window
+	Since *time.Duration
 }
 
 // SystemGCJournalCmd manages cleanup of journal files
@@ -1481,6 +1630,15 @@ type SystemGCRegistryCmd struct {
 	DryRun *bool
 }
 
+// SystemBLOBStatusCmd displays replica health of cluster BLOB storage objects
+type SystemBLOBStatusCmd struct {
+	*kingpin.CmdClause
+	// MinReplicas is the minimum number of active peers that should store each object
+	MinReplicas *int
+	// Output is the output format
+	Output *constants.Format
+}
+
 // GarbageCollectCmd prunes unused cluster resources
 type GarbageCollectCmd struct {
 	*kingpin.CmdClause
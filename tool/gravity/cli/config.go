@@ -107,6 +107,10 @@ type InstallConfig struct {
 	NodeTags []string
 	// NewProcess is used to launch gravity API server process
 	NewProcess process.NewGravityProcess
+	// PreflightReport is the path to the pre-flight checks report file
+	PreflightReport string
+	// AutoFix enables automatic fixing of the failed pre-flight checks
+	AutoFix bool
 }
 
 // NewInstallConfig creates install config from the passed CLI args and flags
@@ -144,11 +148,13 @@ func NewInstallConfig(g *Application) InstallConfig {
 			StorageDriver: g.InstallCmd.DockerStorageDriver.value,
 			Args:          *g.InstallCmd.DockerArgs,
 		},
-		DNSConfig:  g.InstallCmd.DNSConfig(),
-		Manual:     *g.InstallCmd.Manual,
-		ServiceUID: *g.InstallCmd.ServiceUID,
-		ServiceGID: *g.InstallCmd.ServiceGID,
-		NodeTags:   *g.InstallCmd.GCENodeTags,
+		DNSConfig:       g.InstallCmd.DNSConfig(),
+		Manual:          *g.InstallCmd.Manual,
+		ServiceUID:      *g.InstallCmd.ServiceUID,
+		ServiceGID:      *g.InstallCmd.ServiceGID,
+		NodeTags:        *g.InstallCmd.GCENodeTags,
+		PreflightReport: *g.InstallCmd.PreflightReport,
+		AutoFix:         *g.InstallCmd.AutoFix,
 	}
 }
 
@@ -304,40 +310,42 @@ func (i *InstallConfig) ToInstallerConfig(env *localenv.LocalEnvironment) (*inst
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	return &install.Config{
-		Context:       ctx,
-		Cancel:        cancel,
-		EventsC:       make(chan install.Event, 100),
-		AdvertiseAddr: advertiseAddr,
-		Resources:     resources,
-		AppPackage:    appPackage,
-		LocalPackages: env.Packages,
-		LocalApps:     env.Apps,
-		LocalBackend:  env.Backend,
-		Silent:        env.Silent,
-		SiteDomain:    i.SiteDomain,
-		StateDir:      i.ReadStateDir,
-		WriteStateDir: i.WriteStateDir,
-		UserLogFile:   i.UserLogFile,
-		SystemLogFile: i.SystemLogFile,
-		Token:         i.InstallToken,
-		CloudProvider: i.CloudProvider,
-		Flavor:        i.Flavor,
-		Role:          i.Role,
-		SystemDevice:  i.SystemDevice,
-		DockerDevice:  i.DockerDevice,
-		Mounts:        i.Mounts,
-		DNSOverrides:  *dnsOverrides,
-		DNSConfig:     i.DNSConfig,
-		Mode:          i.Mode,
-		PodCIDR:       i.PodCIDR,
-		ServiceCIDR:   i.ServiceCIDR,
-		VxlanPort:     i.VxlanPort,
-		Docker:        i.Docker,
-		Insecure:      i.Insecure,
-		Manual:        i.Manual,
-		ServiceUser:   i.ServiceUser,
-		GCENodeTags:   i.NodeTags,
-		NewProcess:    i.NewProcess,
+		Context:         ctx,
+		Cancel:          cancel,
+		EventsC:         make(chan install.Event, 100),
+		AdvertiseAddr:   advertiseAddr,
+		Resources:       resources,
+		AppPackage:      appPackage,
+		LocalPackages:   env.Packages,
+		LocalApps:       env.Apps,
+		LocalBackend:    env.Backend,
+		Silent:          env.Silent,
+		SiteDomain:      i.SiteDomain,
+		StateDir:        i.ReadStateDir,
+		WriteStateDir:   i.WriteStateDir,
+		UserLogFile:     i.UserLogFile,
+		SystemLogFile:   i.SystemLogFile,
+		Token:           i.InstallToken,
+		CloudProvider:   i.CloudProvider,
+		Flavor:          i.Flavor,
+		Role:            i.Role,
+		SystemDevice:    i.SystemDevice,
+		DockerDevice:    i.DockerDevice,
+		Mounts:          i.Mounts,
+		DNSOverrides:    *dnsOverrides,
+		DNSConfig:       i.DNSConfig,
+		Mode:            i.Mode,
+		PodCIDR:         i.PodCIDR,
+		ServiceCIDR:     i.ServiceCIDR,
+		VxlanPort:       i.VxlanPort,
+		Docker:          i.Docker,
+		Insecure:        i.Insecure,
+		Manual:          i.Manual,
+		ServiceUser:     i.ServiceUser,
+		GCENodeTags:     i.NodeTags,
+		NewProcess:      i.NewProcess,
+		PreflightReport: i.PreflightReport,
+		AutoFix:         i.AutoFix,
 	}, nil
 }
 
@@ -389,6 +397,8 @@ type JoinConfig struct {
 	Mounts map[string]string
 	// CloudProvider is the node cloud provider
 	CloudProvider string
+	// AutoFix enables automatic fixing of the failed pre-flight checks
+	AutoFix bool
 	// Manual turns on manual plan execution mode
 	Manual bool
 	// Phase is the plan phase to execute
@@ -414,6 +424,7 @@ func NewJoinConfig(g *Application) JoinConfig {
 		Manual:        *g.JoinCmd.Manual,
 		Phase:         *g.JoinCmd.Phase,
 		OperationID:   *g.JoinCmd.OperationID,
+		AutoFix:       *g.JoinCmd.AutoFix,
 	}
 }
 
@@ -505,6 +516,7 @@ func (j *JoinConfig) ToPeerConfig(env, joinEnv *localenv.LocalEnvironment) (*exp
 		JoinBackend:   joinEnv.Backend,
 		Manual:        j.Manual,
 		OperationID:   j.OperationID,
+		AutoFix:       j.AutoFix,
 	}, nil
 }
 
@@ -17,13 +17,17 @@ limitations under the License.
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	appservice "github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/catalog"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
@@ -67,6 +71,18 @@ type releaseUpgradeConfig struct {
 	valuesConfig
 	// registryConfig is registry configuration.
 	registryConfig
+	// Diff only displays the changes the upgrade would make to the release.
+	Diff bool
+	// Output is the diff output format.
+	Output constants.Format
+	// Progressive enables health-gated upgrade with automatic rollback.
+	Progressive bool
+	// HealthTimeout is the maximum time the upgraded release has to become healthy.
+	HealthTimeout time.Duration
+	// BakeTime is the time the upgraded release has to stay healthy.
+	BakeTime time.Duration
+	// Stages lists the progressive upgrade stages in the name:key=value[,key=value] format.
+	Stages []string
 }
 
 func (c *releaseUpgradeConfig) setDefaults(env *localenv.LocalEnvironment) error {
@@ -77,6 +93,21 @@ func (c *releaseUpgradeConfig) setDefaults(env *localenv.LocalEnvironment) error
 	return nil
 }
 
+// upgradeStages returns the progressive upgrade stages
+func (c *releaseUpgradeConfig) upgradeStages() (stages []helm.UpgradeStage, err error) {
+	if len(c.Stages) != 0 && !c.Progressive {
+		return nil, trace.BadParameter("upgrade stages can only be used with --progressive")
+	}
+	for _, spec := range c.Stages {
+		stage, err := helm.ParseUpgradeStage(spec)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		stages = append(stages, *stage)
+	}
+	return stages, nil
+}
+
 type releaseRollbackConfig struct {
 	// Release is a name of release to rollback.
 	Release string
@@ -215,9 +246,15 @@ func releaseUpgrade(env *localenv.LocalEnvironment, conf releaseUpgradeConfig) e
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	stages, err := conf.upgradeStages()
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	locator, err := makeLocator(env, conf.Image)
 	if err == nil { // not a tarball, but locator - should download
-		env.PrintStep("Downloading application image %v", conf.Image)
+		if conf.Output != constants.EncodingJSON {
+			env.PrintStep("Downloading application image %v", conf.Image)
+		}
 		result, err := catalog.Download(catalog.DownloadRequest{
 			Application: *locator,
 		})
@@ -242,13 +279,6 @@ func releaseUpgrade(env *localenv.LocalEnvironment, conf releaseUpgradeConfig) e
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	err = appSyncEnv(env, imageEnv, appSyncConfig{
-		Image:          conf.Image,
-		registryConfig: conf.registryConfig,
-	})
-	env.PrintStep("Upgrading release %v (%v) to version %v",
-		release.Name, release.Chart,
-		imageEnv.Manifest.Metadata.ResourceVersion)
 	tmp, err := ioutil.TempDir("", "")
 	if err != nil {
 		return trace.Wrap(err)
@@ -258,20 +288,100 @@ func releaseUpgrade(env *localenv.LocalEnvironment, conf releaseUpgradeConfig) e
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	release, err = helmClient.Upgrade(helm.UpgradeParameters{
+	params := helm.UpgradeParameters{
 		Release: release.Name,
 		Path:    filepath.Join(tmp, "resources"),
 		Values:  conf.Files,
 		Set:     conf.Values,
+	}
+	if conf.Diff {
+		diff, err := helmClient.Diff(params)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(printReleaseDiff(*diff, conf.Output))
+	}
+	err = appSyncEnv(env, imageEnv, appSyncConfig{
+		Image:          conf.Image,
+		registryConfig: conf.registryConfig,
 	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	env.PrintStep("Upgrading release %v (%v) to version %v",
+		release.Name, release.Chart,
+		imageEnv.Manifest.Metadata.ResourceVersion)
+	if conf.Progressive {
+		release, err = helmClient.ProgressiveUpgrade(context.TODO(), helm.ProgressiveUpgradeParameters{
+			UpgradeParameters: params,
+			Stages:            stages,
+			HealthGates:       releaseHealthGates(imageEnv),
+			Timeout:           conf.HealthTimeout,
+			BakeTime:          conf.BakeTime,
+			Progress: func(format string, args ...interface{}) {
+				env.PrintStep(format, args...)
+			},
+		})
+	} else {
+		release, err = helmClient.Upgrade(params)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	env.PrintStep("Upgraded release %v to version %v", release.Name,
 		imageEnv.Manifest.Metadata.ResourceVersion)
 	return nil
 }
 
+// releaseHealthGates returns the health gates for the release upgraded
+// to the application from the specified image
+func releaseHealthGates(imageEnv *localenv.ImageEnvironment) (gates []helm.HealthGate) {
+	if imageEnv.Manifest.HasHook(schema.HookStatus) {
+		gates = append(gates, func(ctx context.Context) error {
+			return trace.Wrap(runStatusHook(ctx, imageEnv))
+		})
+	}
+	return gates
+}
+
+// runStatusHook runs the status hook of the application from the specified image
+func runStatusHook(ctx context.Context, imageEnv *localenv.ImageEnvironment) error {
+	apps, err := imageEnv.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ref, out, err := appservice.RunAppHook(ctx, apps, appservice.HookRunRequest{
+		Application: imageEnv.Manifest.Locator(),
+		Hook:        schema.HookStatus,
+	})
+	if ref != nil {
+		if err := apps.DeleteAppHookJob(ctx, *ref); err != nil {
+			log.Warnf("Failed to delete status hook %v: %v.", ref, trace.DebugReport(err))
+		}
+	}
+	if err != nil {
+		return trace.Wrap(err, "status hook failed: %s", out)
+	}
+	return nil
+}
+
+func printReleaseDiff(diff helm.Diff, format constants.Format) error {
+	switch format {
+	case constants.EncodingText:
+		return trace.Wrap(diff.WriteText(os.Stdout))
+	case constants.EncodingJSON:
+		bytes, err := json.MarshalIndent(diff, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+		return nil
+	default:
+		return trace.BadParameter("unsupported output format %q, supported are: %v, %v",
+			format, constants.EncodingText, constants.EncodingJSON)
+	}
+}
+
 func releaseRollback(env *localenv.LocalEnvironment, conf releaseRollbackConfig) error {
 	helmClient, err := helm.NewClient(helm.ClientConfig{
 		DNSAddress: env.DNS.Addr(),
@@ -0,0 +1,39 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/system/hostconfig"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// applyHostConfig applies the encoded host configuration to the local node
+func applyHostConfig(env *localenv.LocalEnvironment, spec string) error {
+	config, err := hostconfig.DecodeSpec(spec)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx := context.TODO()
+	progress := utils.NewProgress(ctx, "host configuration", -1, bool(env.Silent))
+	defer progress.Stop()
+	return trace.Wrap(hostconfig.Apply(ctx, *config, progress))
+}
@@ -23,6 +23,7 @@ import (
 	_ "net/http/pprof"
 	"strings"
 
+	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/app/docker"
 	appservice "github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/constants"
@@ -34,6 +35,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack/encryptedpack"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update"
 	"github.com/gravitational/gravity/lib/users"
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/gravity/tool/common"
@@ -118,6 +120,21 @@ func uploadUpdate(env *localenv.LocalEnvironment, opsURL string) error {
 			"attempting again.")
 	}
 
+	clusterPackages, err := defaultEnv.ClusterPackages()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	// delta tarballs are restored before the package service is wrapped
+	// for decryption so the restored packages are stored unencrypted
+	restored, err := appservice.ReconstituteTarball(env.StateDir, env.Packages, clusterPackages)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if restored {
+		env.PrintStep("Restored delta upgrade contents from cluster packages")
+	}
+
 	var tarballPackages pack.PackageService = env.Packages
 	if cluster.License != nil {
 		parsed, err := license.ParseLicense(cluster.License.Raw)
@@ -131,11 +148,6 @@ func uploadUpdate(env *localenv.LocalEnvironment, opsURL string) error {
 		}
 	}
 
-	clusterPackages, err := defaultEnv.ClusterPackages()
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
 	clusterApps, err := defaultEnv.SiteApps()
 	if err != nil {
 		return trace.Wrap(err)
@@ -168,6 +180,12 @@ func uploadUpdate(env *localenv.LocalEnvironment, opsURL string) error {
 		env.PrintStep("Application already exists in local cluster")
 	}
 
+	err = pullTarballIntermediateRuntimes(env, tarballApps, tarballPackages,
+		clusterApps, clusterPackages, *cluster)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	var registries []string
 	err = utils.Retry(defaults.RetryInterval, defaults.RetryLessAttempts, func() error {
 		registries, err = getRegistries(context.TODO(), defaultEnv, cluster.ClusterState.Servers)
@@ -211,6 +229,47 @@ func uploadUpdate(env *localenv.LocalEnvironment, opsURL string) error {
 	return nil
 }
 
+// pullTarballIntermediateRuntimes imports the intermediate runtimes the cluster
+// needs to be upgraded through from the upgrade tarball.
+// Runtimes missing from both the cluster and the tarball are not considered
+// an error at this point since they can still be pulled from the Ops Center
+// when the upgrade is triggered
+func pullTarballIntermediateRuntimes(env *localenv.LocalEnvironment,
+	tarballApps app.Applications, tarballPackages pack.PackageService,
+	clusterApps app.Applications, clusterPackages pack.PackageService, cluster ops.Site) error {
+	appPackage, err := install.GetAppPackage(tarballApps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	application, err := tarballApps.GetApp(*appPackage)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	installedRuntime := cluster.App.Manifest.Base()
+	updateRuntime := application.Manifest.Base()
+	if installedRuntime == nil || updateRuntime == nil {
+		return nil
+	}
+	err = update.PullIntermediateRuntimes(update.PullIntermediateRuntimesRequest{
+		Apps:     clusterApps,
+		Packages: clusterPackages,
+		Sources: []update.RuntimeSource{{
+			Description: "upgrade tarball",
+			Apps:        tarballApps,
+			Packages:    tarballPackages,
+		}},
+		InstalledRuntime: *installedRuntime,
+		UpdateRuntime:    *updateRuntime,
+	})
+	if err != nil {
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		env.PrintStep("Warning: %v", err)
+	}
+	return nil
+}
+
 // getRegistries returns a list of registry addresses in the cluster
 func getRegistries(ctx context.Context, env *localenv.LocalEnvironment, servers []storage.Server) ([]string, error) {
 	// in planets before certain version registry was running only on active master
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/system/proxy"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"k8s.io/client-go/kubernetes"
+)
+
+// updateProxyPhase executes the specified phase of the active proxy update
+// operation. The root phase resumes the operation
+func updateProxyPhase(env *localenv.LocalEnvironment, phase string, phaseTimeout time.Duration, force bool) error {
+	config, err := newProxyUpdateConfig(env)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer config.Runner.Close()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), phaseTimeout)
+	defer cancel()
+	progress := utils.NewProgress(ctx, fmt.Sprintf("Executing phase %q", phase), -1, bool(env.Silent))
+	defer progress.Stop()
+
+	if phase == libfsm.RootPhase {
+		return trace.Wrap(proxy.Update(ctx, *config, progress))
+	}
+
+	machine, err := proxy.New(*config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(machine.ExecutePhase(ctx, libfsm.Params{
+		PhaseID:  phase,
+		Progress: progress,
+		Force:    force,
+	}))
+}
+
+// rollbackProxyPhase rolls back the specified phase of the active proxy
+// update operation
+func rollbackProxyPhase(env *localenv.LocalEnvironment, p rollbackParams) error {
+	config, err := newProxyUpdateConfig(env)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer config.Runner.Close()
+
+	machine, err := proxy.New(*config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), p.timeout)
+	defer cancel()
+	progress := utils.NewProgress(ctx, fmt.Sprintf("Rolling back phase %q", p.phaseID), -1, bool(env.Silent))
+	defer progress.Stop()
+
+	return trace.Wrap(machine.RollbackPhase(ctx, libfsm.Params{
+		PhaseID:  p.phaseID,
+		Progress: progress,
+		Force:    p.force,
+	}))
+}
+
+// hasProxyOperation returns true if the last cluster operation is
+// an active proxy update operation
+func hasProxyOperation(env *localenv.LocalEnvironment) bool {
+	operation, err := getProxyOperation(env)
+	if err != nil {
+		log.Debugf("No active proxy update operation: %v.", trace.DebugReport(err))
+		return false
+	}
+	return operation != nil
+}
+
+func getProxyOperation(env *localenv.LocalEnvironment) (*ops.SiteOperation, error) {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	operation, _, err := ops.GetLastOperation(cluster.Key(), operator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if operation.Type != ops.OperationUpdateProxy || operation.IsCompleted() {
+		return nil, trace.NotFound("no active proxy update operation")
+	}
+	return operation, nil
+}
+
+func newProxyUpdateConfig(env *localenv.LocalEnvironment) (*proxy.Config, error) {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	operation, err := getProxyOperation(env)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	runner, err := newAgentRepository()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &proxy.Config{
+		Operation: operation,
+		Operator:  operator,
+		Client:    newKubeClient(env),
+		Runner:    runner,
+	}, nil
+}
+
+// newKubeClient returns the cluster kubernetes client or nil
+// if the client is not available on this node
+func newKubeClient(env *localenv.LocalEnvironment) *kubernetes.Clientset {
+	client, _, err := httplib.GetClusterKubeClient(env.DNS.Addr())
+	if err != nil {
+		log.Warnf("Failed to create Kubernetes client: %v.", trace.DebugReport(err))
+		return nil
+	}
+	return client
+}
@@ -96,6 +96,9 @@ func RegisterCommands(app *kingpin.Application) *Application {
 		String()
 	g.InstallCmd.GCENodeTags = g.InstallCmd.Flag("gce-node-tag", "Override node tag on the instance in GCE required for load balanacing. Defaults to cluster name.").Strings()
 	g.InstallCmd.DNSHosts = g.InstallCmd.Flag("dns-host", "Specify an IP address that will be returned for the given domain within the cluster. Accepts <domain>/<ip> format. Can be specified multiple times.").Hidden().Strings()
+	g.InstallCmd.PreflightReport = g.InstallCmd.Flag("preflight-report", "Write the report of the pre-flight checks to the specified file, in JUnit XML format if the file has .xml extension or JSON format otherwise").String()
+	g.InstallCmd.AutoFix = g.InstallCmd.Flag("autofix", "Attempt to fix the failed pre-flight checks, e.g. disable swap or open the required ports in the host firewall").Bool()
+	g.InstallCmd.Confirm = g.InstallCmd.Flag("confirm", "Do not ask for confirmation before auto-fixing the failed pre-flight checks").Bool()
 	g.InstallCmd.DNSZones = g.InstallCmd.Flag("dns-zone", "Specify an upstream server for the given zone within the cluster. Accepts <zone>/<nameserver> format where <nameserver> can be either <ip> or <ip>:<port>. Can be specified multiple times.").Strings()
 
 	g.JoinCmd.CmdClause = g.Command("join", "Join existing cluster or on-going install operation")
@@ -115,6 +118,8 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.JoinCmd.Force = g.JoinCmd.Flag("force", "Force phase execution").Bool()
 	g.JoinCmd.Complete = g.JoinCmd.Flag("complete", "Complete join operation").Bool()
 	g.JoinCmd.OperationID = g.JoinCmd.Flag("operation-id", "ID of the operation that was created via UI").Hidden().String()
+	g.JoinCmd.AutoFix = g.JoinCmd.Flag("autofix", "Attempt to fix the failed pre-flight checks, e.g. disable swap or open the required ports in the host firewall").Bool()
+	g.JoinCmd.Confirm = g.JoinCmd.Flag("confirm", "Do not ask for confirmation before auto-fixing the failed pre-flight checks").Bool()
 
 	g.AutoJoinCmd.CmdClause = g.Command("autojoin", "Use cloud provider data to join a node to existing cluster")
 	g.AutoJoinCmd.ClusterName = g.AutoJoinCmd.Arg("cluster-name", "Cluster name used for discovery").Required().String()
@@ -153,6 +158,8 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.UpdateTriggerCmd.CmdClause = g.UpdateCmd.Command("trigger", "Trigger an update operation for given application").Hidden()
 	g.UpdateTriggerCmd.App = g.UpdateTriggerCmd.Arg("app", "Application version to update to, in the 'name:version' or 'name' (for latest version) format. If unspecified, currently installed application is updated").String()
 	g.UpdateTriggerCmd.Manual = g.UpdateTriggerCmd.Flag("manual", "Manual operation. Do not trigger automatic update").Short('m').Bool()
+	g.UpdateTriggerCmd.RollbackOnFailure = g.UpdateTriggerCmd.Flag("rollback-on-failure", "Automatically rollback the update if it fails").Bool()
+	g.UpdateTriggerCmd.HealthCheckGracePeriod = g.UpdateTriggerCmd.Flag("health-check-grace-period", "Rollback the update if the cluster does not become healthy within this period after the update").Duration()
 
 	// upgrade is aliased to "update trigger"
 	g.UpgradeCmd.CmdClause = g.Command("upgrade", "Trigger an update operation for given application").Hidden()
@@ -164,6 +171,8 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.UpgradeCmd.Complete = g.UpgradeCmd.Flag("complete", "Complete update operation").Bool()
 	g.UpgradeCmd.Resume = g.UpgradeCmd.Flag("resume", "Resume upgrade from the last failed step").Bool()
 	g.UpgradeCmd.SkipVersionCheck = g.UpgradeCmd.Flag("skip-version-check", "Bypass version compatibility check").Hidden().Bool()
+	g.UpgradeCmd.RollbackOnFailure = g.UpgradeCmd.Flag("rollback-on-failure", "Automatically rollback the upgrade if it fails").Bool()
+	g.UpgradeCmd.HealthCheckGracePeriod = g.UpgradeCmd.Flag("health-check-grace-period", "Rollback the upgrade if the cluster does not become healthy within this period after the upgrade").Duration()
 
 	g.UpdateUploadCmd.CmdClause = g.UpdateCmd.Command("upload", "Upload update package to locally running site").Hidden()
 	g.UpdateUploadCmd.OpsCenterURL = g.UpdateUploadCmd.Flag("ops-url", "Optional OpsCenter URL to upload new packages to (defaults to local gravity site)").Default(defaults.GravityServiceURL).String()
@@ -178,6 +187,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.UpdateSystemCmd.ServiceName = g.UpdateSystemCmd.Flag("service-name", "The name of the service to run update as a systemd unit").Hidden().String()
 	g.UpdateSystemCmd.WithStatus = g.UpdateSystemCmd.Flag("with-status", "Verify the system status at the end of the operation").Bool()
 	g.UpdateSystemCmd.RuntimePackage = Locator(g.UpdateSystemCmd.Flag("runtime-package", "The name of the runtime package to update to").Required())
+	g.UpdateSystemCmd.ExactRuntime = g.UpdateSystemCmd.Flag("exact-runtime", "Update to the exact version of the runtime package instead of the latest available").Hidden().Bool()
 
 	g.StatusCmd.CmdClause = g.Command("status", "Show the status of the cluster and the application running in it")
 	g.StatusCmd.Token = g.StatusCmd.Flag("token", "Show only the cluster token").Bool()
@@ -185,6 +195,14 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.StatusCmd.OperationID = g.StatusCmd.Flag("operation-id", "Check status of operation with given ID").Short('o').String()
 	g.StatusCmd.Seconds = g.StatusCmd.Flag("seconds", "Continuously display status every N seconds").Short('s').Int()
 	g.StatusCmd.Output = common.Format(g.StatusCmd.Flag("output", "output format: json or text").Default(string(constants.EncodingText)))
+	g.StatusCmd.Network = g.StatusCmd.Flag("network", "Measure latency, jitter, packet loss and path MTU between all cluster nodes. Requires agents deployed with 'gravity agent deploy'").Bool()
+
+	g.StatusClusterCmd.CmdClause = g.StatusCmd.Command("cluster", "Show the status of the cluster and the application running in it").Default().Hidden()
+
+	g.StatusHistoryCmd.CmdClause = g.StatusCmd.Command("history", "Show the recorded state transitions of the cluster, its nodes and health probes")
+	g.StatusHistoryCmd.Since = g.StatusHistoryCmd.Flag("since", "Only show the history within the specified duration").Default(defaults.StatusHistorySince.String()).Duration()
+	g.StatusHistoryCmd.Node = g.StatusHistoryCmd.Flag("node", "Only show the history of the node with the specified advertise IP or hostname").String()
+	g.StatusHistoryCmd.Probe = g.StatusHistoryCmd.Flag("probe", "Only show the history of the health probe with the specified name").String()
 
 	// reset cluster state, for debugging/emergencies
 	g.StatusResetCmd.CmdClause = g.Command("status-reset", "Reset the cluster state to 'active'").Hidden()
@@ -197,8 +215,15 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	g.CheckCmd.CmdClause = g.Command("check", "check host environment to match manifest")
 	g.CheckCmd.ManifestFile = g.CheckCmd.Arg("manifest", "application manifest in YAML format").Default(defaults.ManifestFileName).String()
-	g.CheckCmd.Profile = g.CheckCmd.Flag("profile", "profile to check").Short('p').Required().String()
+	g.CheckCmd.Profile = g.CheckCmd.Flag("profile", "profile to check").Short('p').String()
 	g.CheckCmd.AutoFix = g.CheckCmd.Flag("autofix", "attempt to fix some of the problems").Bool()
+	g.CheckCmd.DryRun = g.CheckCmd.Flag("dry-run", "with --autofix, only print the actions that would be taken to fix the problems").Bool()
+	g.CheckCmd.Confirm = g.CheckCmd.Flag("confirm", "with --autofix, do not ask for confirmation before fixing the problems").Bool()
+	g.CheckCmd.UpgradeTo = g.CheckCmd.Flag("upgrade-to", "path to the unpacked installer to check the cluster upgrade against").String()
+	g.CheckCmd.Output = common.Format(g.CheckCmd.Flag("output", fmt.Sprintf("output format: %v, %v or %v", constants.EncodingText, constants.EncodingJSON, constants.EncodingJUnit)).Short('o').Default(string(constants.EncodingText)))
+	g.CheckCmd.Nodes = g.CheckCmd.Flag("nodes", "path to the inventory of nodes to check remotely over SSH before install").String()
+	g.CheckCmd.Installer = g.CheckCmd.Flag("installer", "path to the installer tarball to check the nodes from the inventory against").String()
+	g.CheckCmd.Flavor = g.CheckCmd.Flag("flavor", "install flavor to check the inventory against").String()
 
 	// restore
 	g.RestoreCmd.CmdClause = g.Command("restore", "Restore state of the local application from a previously taken backup")
@@ -232,6 +257,12 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AppUpgradeCmd.RegistryCA = g.AppUpgradeCmd.Flag("registry-ca", "Docker registry CA certificate path.").String()
 	g.AppUpgradeCmd.RegistryCert = g.AppUpgradeCmd.Flag("registry-cert", "Docker registry client certificate path.").String()
 	g.AppUpgradeCmd.RegistryKey = g.AppUpgradeCmd.Flag("registry-key", "Docker registry client private key path.").String()
+	g.AppUpgradeCmd.Diff = g.AppUpgradeCmd.Flag("diff", "Display the changes the upgrade would make to the release objects without upgrading.").Bool()
+	g.AppUpgradeCmd.Output = common.Format(g.AppUpgradeCmd.Flag("output", "Output format for the diff, text or json.").Short('o').Default(string(constants.EncodingText)))
+	g.AppUpgradeCmd.Progressive = g.AppUpgradeCmd.Flag("progressive", "Wait for the release to pass health gates after the upgrade and roll it back automatically if it does not.").Bool()
+	g.AppUpgradeCmd.HealthTimeout = g.AppUpgradeCmd.Flag("health-timeout", "Maximum time the upgraded release has to become healthy in progressive mode.").Default(defaults.ReleaseHealthTimeout).Duration()
+	g.AppUpgradeCmd.BakeTime = g.AppUpgradeCmd.Flag("bake-time", "Time the upgraded release has to stay healthy in progressive mode.").Duration()
+	g.AppUpgradeCmd.Stages = g.AppUpgradeCmd.Flag("stage", "Progressive upgrade stage in the name:key=value[,key=value] format that has to pass health gates before the full upgrade. Can be repeated.").Strings()
 
 	g.AppRollbackCmd.CmdClause = g.AppCmd.Command("rollback", "Rollback a release.")
 	g.AppRollbackCmd.Release = g.AppRollbackCmd.Arg("release", "Release name to rollback.").Required().String()
@@ -304,6 +335,12 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AppStatusCmd.Locator = Locator(g.AppStatusCmd.Arg("pkg", "application package").Required())
 	g.AppStatusCmd.OpsCenterURL = g.AppStatusCmd.Flag("ops-url", "optional remote OpsCenter").String()
 
+	// generate software bill of materials of an application
+	g.AppSBOMCmd.CmdClause = g.AppCmd.Command("sbom", "generate CycloneDX software bill of materials of an application")
+	g.AppSBOMCmd.Locator = Locator(g.AppSBOMCmd.Arg("pkg", "application package").Required())
+	g.AppSBOMCmd.OutPath = g.AppSBOMCmd.Flag("out", "write the bill of materials to the specified file instead of stdout").Short('o').String()
+	g.AppSBOMCmd.OpsCenterURL = g.AppSBOMCmd.Flag("ops-url", "optional remote OpsCenter URL").String()
+
 	// pull an application from a remote OpsCenter
 	g.AppPullCmd.CmdClause = g.AppCmd.Command("pull", "pull an application package from remote OpsCenter").Hidden()
 	g.AppPullCmd.Package = Locator(g.AppPullCmd.Arg("pkg", "application package").Required())
@@ -485,6 +522,10 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	// get cluster diagnostics report
 	g.ReportCmd.CmdClause = g.Command("report", "Generate cluster diagnostics report")
 	g.ReportCmd.FilePath = g.ReportCmd.Flag("file", "target report file name").Default("report.tar.gz").String()
+	g.ReportCmd.Since = g.ReportCmd.Flag("since", "only collect logs newer than the specified duration, e.g. 24h. Collect all logs if unspecified").Duration()
+	g.ReportCmd.Namespaces = g.ReportCmd.Flag("namespace", "only collect kubernetes diagnostics from the specified namespace. Can be specified multiple times").Strings()
+	g.ReportCmd.Collectors = g.ReportCmd.Flag("collector", fmt.Sprintf("only collect the specified diagnostics, one of: %v. Can be specified multiple times. Collect everything if unspecified", strings.Join(constants.ReportFilters, ", "))).Strings()
+	g.ReportCmd.RedactPatterns = g.ReportCmd.Flag("redact", "regular expression matching additional sensitive data to redact from the report. Can be specified multiple times").Strings()
 
 	// operations on sites
 	g.SiteCmd.CmdClause = g.Command("site", "operations on gravity sites")
@@ -533,6 +574,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	g.RPCAgentRunCmd.CmdClause = g.RPCAgentCmd.Command("run", "run RPC agent").Hidden()
 	g.RPCAgentRunCmd.Args = g.RPCAgentRunCmd.Arg("arg", "additional arguments").Strings()
+	g.RPCAgentRunCmd.SecretsDir = g.RPCAgentRunCmd.Flag("secrets-dir", "directory with agent credentials").Hidden().String()
 
 	g.SystemCmd.CmdClause = g.Command("system", "operations on system components")
 
@@ -551,12 +593,14 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.SystemPullUpdatesCmd.CmdClause = g.SystemCmd.Command("pull-updates", "Pull new package updates from the system").Hidden()
 	g.SystemPullUpdatesCmd.OpsCenterURL = g.SystemPullUpdatesCmd.Flag("ops-url", "remote OpsCenter URL").String()
 	g.SystemPullUpdatesCmd.RuntimePackage = Locator(g.SystemPullUpdatesCmd.Flag("runtime-package", "The name of the runtime package to update to").Required())
+	g.SystemPullUpdatesCmd.ExactRuntime = g.SystemPullUpdatesCmd.Flag("exact-runtime", "Pull the exact version of the runtime package instead of the latest available").Hidden().Bool()
 
 	g.SystemUpdateCmd.CmdClause = g.SystemCmd.Command("update", "Update this system by installing newer version of system packages").Hidden()
 	g.SystemUpdateCmd.ChangesetID = g.SystemUpdateCmd.Flag("changeset-id", "Assign ID to this update operation (will be autogenerated if missing)").String()
 	g.SystemUpdateCmd.ServiceName = g.SystemUpdateCmd.Flag("service-name", "The name of the service to run update as a systemd unit").String()
 	g.SystemUpdateCmd.WithStatus = g.SystemUpdateCmd.Flag("with-status", "Verify the system status at the end of the operation").Bool()
 	g.SystemUpdateCmd.RuntimePackage = Locator(g.SystemUpdateCmd.Flag("runtime-package", "The name of the runtime package to update to").Required())
+	g.SystemUpdateCmd.ExactRuntime = g.SystemUpdateCmd.Flag("exact-runtime", "Update to the exact version of the runtime package instead of the latest available").Hidden().Bool()
 
 	g.SystemReinstallCmd.CmdClause = g.SystemCmd.Command("reinstall", "reinstall package on the system").Hidden()
 	g.SystemReinstallCmd.Package = Locator(g.SystemReinstallCmd.Arg("pkg", "the package to generate unit file for").Required())
@@ -605,8 +649,13 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.SystemServiceListCmd.CmdClause = g.SystemServiceCmd.Command("list", "list running services").Hidden()
 
 	g.SystemReportCmd.CmdClause = g.SystemCmd.Command("report", "collect system diagnostics and output as gzipped tarball to terminal").Hidden()
-	g.SystemReportCmd.Filter = g.SystemReportCmd.Flag("filter", "collect only specific diagnostics ('system', 'kubernetes'). Collect everything if unspecified").Strings()
+	g.SystemReportCmd.Filter = g.SystemReportCmd.Flag("filter", fmt.Sprintf("collect only specific diagnostics (%v). Collect everything if unspecified", strings.Join(append([]string{constants.ReportFilterKubernetes}, constants.NodeReportFilters...), ", "))).Strings()
 	g.SystemReportCmd.Compressed = g.SystemReportCmd.Flag("compressed", "whether to compress the tarball").Default("true").Bool()
+	g.SystemReportCmd.Since = g.SystemReportCmd.Flag("since", "only collect logs newer than the specified duration").Duration()
+	g.SystemReportCmd.Namespaces = g.SystemReportCmd.Flag("namespace", "only collect kubernetes diagnostics from the specified namespace").Strings()
+	g.SystemReportCmd.RedactPatterns = g.SystemReportCmd.Flag("redact", "regular expression matching additional sensitive data to redact").Strings()
+	g.SystemReportCmd.EncodedRedactPatterns = g.SystemReportCmd.Flag("redact-encoded", "base64-encoded regular expression matching additional sensitive data to redact").Hidden().Strings()
+	g.SystemReportCmd.AppCollectors = g.SystemReportCmd.Flag("app-collectors", "base64-encoded JSON list of application report collectors to run").Hidden().String()
 
 	g.SystemStateDirCmd.CmdClause = g.SystemCmd.Command("state-dir", "show where all gravity data is stored on the node").Hidden()
 
@@ -624,10 +673,26 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.SystemDisablePromiscModeCmd.CmdClause = g.SystemCmd.Command("disable-promisc-mode", "Remove promiscuous mode flag and deduplication rules").Hidden()
 	g.SystemDisablePromiscModeCmd.Iface = g.SystemDisablePromiscModeCmd.Arg("name", "Name of the interface (i.e. docker0)").Default(defaults.DockerBridge).String()
 
+	g.SystemApplyHostConfigCmd.CmdClause = g.SystemCmd.Command("apply-hostconfig", "Apply cluster host configuration to the node").Hidden()
+	g.SystemApplyHostConfigCmd.Spec = g.SystemApplyHostConfigCmd.Flag("spec", "base64-encoded JSON host configuration").Required().String()
+	g.SystemApplyTrustedCACmd.CmdClause = g.SystemCmd.Command("apply-trustedca", "Install trusted certificate authorities on the node").Hidden()
+	g.SystemApplyTrustedCACmd.Bundle = g.SystemApplyTrustedCACmd.Flag("bundle", "base64-encoded PEM CA bundle. Removes installed certificate authorities if empty").String()
+
+	g.SystemUpdateProxyCmd.CmdClause = g.SystemCmd.Command("update-proxy", "Execute phases of the proxy update operation").Hidden()
+	g.SystemUpdateProxyCmd.Phase = g.SystemUpdateProxyCmd.Flag("phase", "Specific phase to execute").String()
+	g.SystemUpdateProxyCmd.PhaseTimeout = g.SystemUpdateProxyCmd.Flag("timeout", "Phase execution timeout").
+		Default(defaults.PhaseTimeout).
+		Hidden().
+		Duration()
+	g.SystemUpdateProxyCmd.Resume = g.SystemUpdateProxyCmd.Flag("resume", "Resume aborted operation").Bool()
+	g.SystemUpdateProxyCmd.Force = g.SystemUpdateProxyCmd.Flag("force", "Force phase execution").Bool()
+
 	g.SystemExportRuntimeJournalCmd.CmdClause = g.SystemCmd.Command("export-runtime-journal", "Export runtime journal logs to a file").Hidden()
 	g.SystemExportRuntimeJournalCmd.OutputFile = g.SystemExportRuntimeJournalCmd.Flag("output", "Name of resulting tarball. Output to stdout if unspecified").String()
+	g.SystemExportRuntimeJournalCmd.Since = g.SystemExportRuntimeJournalCmd.Flag("since", "Only export entries newer than the specified duration").Duration()
 
 	g.SystemStreamRuntimeJournalCmd.CmdClause = g.SystemCmd.Command("stream-runtime-journal", "Stream runtime journal to stdout").Hidden()
+	g.SystemStreamRuntimeJournalCmd.Since = g.SystemStreamRuntimeJournalCmd.Flag("since", "Only stream entries newer than the specified duration").Duration()
 
 	// pruning cluster resources
 	g.GarbageCollectCmd.CmdClause = g.Command("gc", "Prune cluster resources")
@@ -662,6 +727,13 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.SystemGCRegistryCmd.Confirm = g.SystemGCRegistryCmd.Flag("confirm", "Confirm to remove unrelated docker").Bool()
 	g.SystemGCRegistryCmd.DryRun = g.SystemGCRegistryCmd.Flag("dry-run", "Only list docker images to remove w/o removing them").Bool()
 
+	// operations on cluster BLOB storage
+	systemBLOBCmd := g.SystemCmd.Command("blob", "Operations on cluster BLOB storage")
+
+	g.SystemBLOBStatusCmd.CmdClause = systemBLOBCmd.Command("status", "Display replica health of objects in cluster BLOB storage, must be run on a master node.")
+	g.SystemBLOBStatusCmd.MinReplicas = g.SystemBLOBStatusCmd.Flag("min-replicas", "Minimum number of active peers that should store each object, defaults to the value from the cluster configuration.").Int()
+	g.SystemBLOBStatusCmd.Output = common.Format(g.SystemBLOBStatusCmd.Flag("output", "Output format, text or json.").Short('o').Default(string(constants.EncodingText)))
+
 	// operations on planet (planet plugin)
 	g.PlanetCmd.CmdClause = g.Command("planet", "operations with planet").Hidden()
 
@@ -18,6 +18,8 @@ package cli
 
 import (
 	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"os"
@@ -30,6 +32,7 @@ import (
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/report"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/dustin/go-humanize"
@@ -39,27 +42,110 @@ import (
 	serializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
 )
 
+// reportScope limits the diagnostics collected in a report
+type reportScope struct {
+	// since limits the collected logs to the specified time window
+	since time.Duration
+	// namespaces limits Kubernetes diagnostics to the specified namespaces
+	namespaces []string
+	// filters limits the report to the specified collectors
+	filters []string
+	// redactPatterns lists additional patterns of sensitive data to redact
+	redactPatterns []string
+	// encodedRedactPatterns lists additional base64-encoded patterns
+	// of sensitive data to redact
+	encodedRedactPatterns []string
+	// appCollectors is the base64-encoded JSON list of application-defined
+	// report collectors
+	appCollectors string
+}
+
+// redactor returns the redactor for the patterns of this scope
+func (r reportScope) redactor() (*report.Redactor, error) {
+	patterns := r.redactPatterns
+	for _, encoded := range r.encodedRedactPatterns {
+		pattern, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, trace.BadParameter("invalid encoded redaction pattern %q: %v", encoded, err)
+		}
+		patterns = append(patterns, string(pattern))
+	}
+	var rules []report.RedactionRule
+	for _, pattern := range patterns {
+		rule, err := report.NewRedactionRule(pattern)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		rules = append(rules, *rule)
+	}
+	return report.NewRedactor(rules...), nil
+}
+
+// collectors returns the application-defined report collectors of this scope
+func (r reportScope) collectors() (report.Collectors, error) {
+	if r.appCollectors == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(r.appCollectors)
+	if err != nil {
+		return nil, trace.BadParameter("invalid encoded report collectors: %v", err)
+	}
+	var specs []schema.ReportCollector
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, trace.BadParameter("invalid report collectors: %v", err)
+	}
+	collectors, err := report.AppCollectors(specs)
+	return collectors, trace.Wrap(err)
+}
+
 // systemReport collects system diagnostics and outputs them as a (optionally compressed) tarball
 // to the stdout.
-// filters define the specific diagnostics to collect ('system', 'kubernetes'),
+// filters define the specific diagnostics to collect (e.g. 'system', 'kubernetes'),
 // if empty all diagnostics are collected.
-func systemReport(env *localenv.LocalEnvironment, filters []string, compressed bool) error {
+// All collected diagnostics are redacted to remove sensitive information.
+func systemReport(env *localenv.LocalEnvironment, filters []string, compressed bool, scope reportScope) error {
+	redactor, err := scope.redactor()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	appCollectors, err := scope.collectors()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	runner := utils.NewRunner(nil)
+	reportScope := report.Scope{
+		Since:      scope.since,
+		Namespaces: scope.namespaces,
+	}
 	var collectors report.Collectors
 	for _, filter := range teleutils.Deduplicate(filters) {
 		switch filter {
 		case constants.ReportFilterSystem:
-			collectors = append(collectors, report.SystemInfo()...)
+			collectors = append(collectors, report.SystemInfo(reportScope)...)
+			collectors = append(collectors, packageCollector{env})
+		case constants.ReportFilterHost:
+			collectors = append(collectors, report.HostInfo()...)
+		case constants.ReportFilterLogs:
+			collectors = append(collectors, report.LogInfo(reportScope)...)
+		case constants.ReportFilterBashHistory:
+			collectors = append(collectors, report.BashHistory())
+		case constants.ReportFilterPackages:
 			collectors = append(collectors, packageCollector{env})
 		case constants.ReportFilterKubernetes:
-			collectors = append(collectors, report.KubernetesInfo(runner)...)
+			collectors = append(collectors, report.KubernetesInfo(runner, reportScope)...)
+		case constants.ReportFilterApp:
+			// Application collectors are always run if specified
+		default:
+			return trace.BadParameter("unknown report filter %q", filter)
 		}
 	}
 	if len(filters) == 0 {
-		collectors = append(collectors, report.SystemInfo()...)
-		collectors = append(collectors, report.KubernetesInfo(runner)...)
+		collectors = append(collectors, report.SystemInfo(reportScope)...)
+		collectors = append(collectors, report.KubernetesInfo(runner, reportScope)...)
 		collectors = append(collectors, packageCollector{env})
 	}
+	collectors = append(collectors, appCollectors...)
 
 	dir, err := ioutil.TempDir("", "report")
 	if err != nil {
@@ -67,7 +153,7 @@ func systemReport(env *localenv.LocalEnvironment, filters []string, compressed b
 	}
 	defer os.RemoveAll(dir)
 
-	rw := report.NewFileWriter(dir)
+	rw := redactor.Writer(report.NewFileWriter(dir))
 	err = collectors.Collect(rw, runner)
 	if err != nil {
 		log.Errorf("failed to collect some diagnostics: %v", trace.DebugReport(err))
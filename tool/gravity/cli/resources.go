@@ -20,6 +20,7 @@ import (
 	"os"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops/resources"
 	"github.com/gravitational/gravity/lib/ops/resources/gravity"
@@ -34,10 +35,18 @@ func createResource(env *localenv.LocalEnvironment, filename string, upsert bool
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	remote, err := newAgentRepository()
+	if err != nil {
+		log.Warnf("Failed to create agent client: %v.", trace.DebugReport(err))
+	} else {
+		defer remote.Close()
+	}
 	gravityResources, err := gravity.New(gravity.Config{
 		Operator:    operator,
 		CurrentUser: env.CurrentUser(),
 		Silent:      env.Silent,
+		Remote:      remote,
+		Client:      newKubeClient(env),
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -60,10 +69,18 @@ func removeResource(env *localenv.LocalEnvironment, kind string, name string, fo
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	remote, err := newAgentRepository()
+	if err != nil {
+		log.Warnf("Failed to create agent client: %v.", trace.DebugReport(err))
+	} else {
+		defer remote.Close()
+	}
 	gravityResources, err := gravity.New(gravity.Config{
 		Operator:    operator,
 		CurrentUser: env.CurrentUser(),
 		Silent:      env.Silent,
+		Remote:      remote,
+		Client:      newKubeClient(env),
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -94,3 +111,13 @@ func getResources(env *localenv.LocalEnvironment, kind string, name string, with
 	}
 	return nil
 }
+
+// newAgentRepository returns the repository of RPC agent clients
+// to execute commands on cluster nodes
+func newAgentRepository() (fsm.AgentRepository, error) {
+	creds, err := fsm.GetClientCredentials()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return fsm.NewAgentRunner(creds), nil
+}
@@ -40,5 +40,8 @@ func rollbackOperationPhase(env, updateEnv, joinEnv *localenv.LocalEnvironment,
 	if joinEnv != nil && hasExpandOperation(joinEnv) {
 		return rollbackJoinPhase(env, joinEnv, p)
 	}
+	if hasProxyOperation(env) {
+		return rollbackProxyPhase(env, p)
+	}
 	return rollbackInstallPhase(env, p)
 }
@@ -60,8 +60,8 @@ func rpcAgentInstall(env *localenv.LocalEnvironment, args []string) error {
 }
 
 // rpcAgentRun runs a local agent executing the function specified with optional args
-func rpcAgentRun(localEnv, upgradeEnv *localenv.LocalEnvironment, args []string) error {
-	server, err := startAgent()
+func rpcAgentRun(localEnv, upgradeEnv *localenv.LocalEnvironment, secretsDir string, args []string) error {
+	server, err := startAgent(secretsDir)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -88,10 +88,13 @@ func rpcAgentRun(localEnv, upgradeEnv *localenv.LocalEnvironment, args []string)
 	return trace.Wrap(server.Serve())
 }
 
-func startAgent() (rpcserver.Server, error) {
-	secretsDir, err := fsm.AgentSecretsDir()
-	if err != nil {
-		return nil, trace.Wrap(err)
+func startAgent(secretsDir string) (rpcserver.Server, error) {
+	if secretsDir == "" {
+		var err error
+		secretsDir, err = fsm.AgentSecretsDir()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
 	}
 
 	serverCreds, clientCreds, err := rpc.Credentials(secretsDir)
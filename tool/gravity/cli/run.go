@@ -179,8 +179,14 @@ func InitAndCheck(g *Application, cmd string) error {
 		g.BackupCmd.FullCommand(),
 		g.RestoreCmd.FullCommand(),
 		g.GarbageCollectCmd.FullCommand(),
-		g.SystemGCRegistryCmd.FullCommand(),
-		g.CheckCmd.FullCommand():
+		g.SystemGCRegistryCmd.FullCommand():
+		if err := checkRunningAsRoot(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
+	// local checks must be run as root, remote checks only need SSH access
+	if cmd == g.CheckCmd.FullCommand() && *g.CheckCmd.Nodes == "" {
 		if err := checkRunningAsRoot(); err != nil {
 			return trace.Wrap(err)
 		}
@@ -193,7 +199,10 @@ func InitAndCheck(g *Application, cmd string) error {
 		g.UpgradeCmd.FullCommand(),
 		g.SystemGCRegistryCmd.FullCommand(),
 		g.PlanetEnterCmd.FullCommand(),
-		g.EnterCmd.FullCommand():
+		g.EnterCmd.FullCommand(),
+		g.SystemApplyHostConfigCmd.FullCommand(),
+		g.SystemApplyTrustedCACmd.FullCommand(),
+		g.SystemUpdateProxyCmd.FullCommand():
 		if utils.CheckInPlanet() {
 			return trace.BadParameter("this command must be run outside of planet container")
 		}
@@ -304,6 +313,11 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 				Timeout: *g.InstallCmd.PhaseTimeout,
 			})
 		}
+		if *g.InstallCmd.AutoFix && !*g.InstallCmd.Confirm {
+			if err := confirmAutoFix(); err != nil {
+				return trace.Wrap(err)
+			}
+		}
 		return startInstall(localEnv, NewInstallConfig(g))
 	case g.JoinCmd.FullCommand():
 		if *g.JoinCmd.Resume {
@@ -317,6 +331,11 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 				Complete: *g.JoinCmd.Complete,
 			})
 		}
+		if *g.JoinCmd.AutoFix && !*g.JoinCmd.Confirm {
+			if err := confirmAutoFix(); err != nil {
+				return trace.Wrap(err)
+			}
+		}
 		return Join(localEnv, joinEnv, NewJoinConfig(g))
 	case g.AutoJoinCmd.FullCommand():
 		return autojoin(localEnv, joinEnv, autojoinConfig{
@@ -334,7 +353,9 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return updateTrigger(localEnv,
 			upgradeEnv,
 			*g.UpdateTriggerCmd.App,
-			*g.UpdateTriggerCmd.Manual)
+			*g.UpdateTriggerCmd.Manual,
+			newRollbackPolicy(*g.UpdateTriggerCmd.RollbackOnFailure,
+				*g.UpdateTriggerCmd.HealthCheckGracePeriod))
 	case g.UpgradeCmd.FullCommand():
 		if *g.UpgradeCmd.Resume {
 			*g.UpgradeCmd.Phase = fsm.RootPhase
@@ -354,7 +375,9 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return updateTrigger(localEnv,
 			upgradeEnv,
 			*g.UpgradeCmd.App,
-			*g.UpgradeCmd.Manual)
+			*g.UpgradeCmd.Manual,
+			newRollbackPolicy(*g.UpgradeCmd.RollbackOnFailure,
+				*g.UpgradeCmd.HealthCheckGracePeriod))
 	case g.RollbackCmd.FullCommand():
 		return rollbackOperationPhase(localEnv,
 			upgradeEnv,
@@ -384,7 +407,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			force:     *g.RemoveCmd.Force,
 			confirmed: *g.RemoveCmd.Confirm,
 		})
-	case g.StatusCmd.FullCommand():
+	case g.StatusClusterCmd.FullCommand():
 		printOptions := printOptions{
 			token:       *g.StatusCmd.Token,
 			operationID: *g.StatusCmd.OperationID,
@@ -394,11 +417,21 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		if *g.StatusCmd.Tail {
 			return tailStatus(localEnv, *g.StatusCmd.OperationID)
 		}
+		if *g.StatusCmd.Network {
+			return statusNetwork(localEnv, *g.StatusCmd.Output)
+		}
 		if *g.StatusCmd.Seconds != 0 {
 			return statusPeriodic(localEnv, printOptions, *g.StatusCmd.Seconds)
 		} else {
 			return status(localEnv, printOptions)
 		}
+	case g.StatusHistoryCmd.FullCommand():
+		return statusHistory(localEnv, historyOptions{
+			since:  *g.StatusHistoryCmd.Since,
+			node:   *g.StatusHistoryCmd.Node,
+			probe:  *g.StatusHistoryCmd.Probe,
+			format: *g.StatusCmd.Output,
+		})
 	case g.UpdateUploadCmd.FullCommand():
 		return uploadUpdate(localEnv, *g.UpdateUploadCmd.OpsCenterURL)
 	case g.AppPackageCmd.FullCommand():
@@ -436,6 +469,12 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 				CertPath: *g.AppUpgradeCmd.RegistryCert,
 				KeyPath:  *g.AppUpgradeCmd.RegistryKey,
 			},
+			Diff:          *g.AppUpgradeCmd.Diff,
+			Output:        *g.AppUpgradeCmd.Output,
+			Progressive:   *g.AppUpgradeCmd.Progressive,
+			HealthTimeout: *g.AppUpgradeCmd.HealthTimeout,
+			BakeTime:      *g.AppUpgradeCmd.BakeTime,
+			Stages:        *g.AppUpgradeCmd.Stages,
 		})
 	case g.AppRollbackCmd.FullCommand():
 		return releaseRollback(localEnv, releaseRollbackConfig{
@@ -519,6 +558,11 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return statusApp(localEnv,
 			*g.AppStatusCmd.Locator,
 			*g.AppStatusCmd.OpsCenterURL)
+	case g.AppSBOMCmd.FullCommand():
+		return appSBOM(localEnv,
+			*g.AppSBOMCmd.Locator,
+			*g.AppSBOMCmd.OutPath,
+			*g.AppSBOMCmd.OpsCenterURL)
 	case g.AppPackageUninstallCmd.FullCommand():
 		return uninstallAppPackage(localEnv,
 			*g.AppPackageUninstallCmd.Locator)
@@ -640,7 +684,12 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.APIKeyDeleteCmd.Email,
 			*g.APIKeyDeleteCmd.Token)
 	case g.ReportCmd.FullCommand():
-		return getClusterReport(localEnv, *g.ReportCmd.FilePath)
+		return getClusterReport(localEnv, *g.ReportCmd.FilePath, reportScope{
+			since:          *g.ReportCmd.Since,
+			namespaces:     *g.ReportCmd.Namespaces,
+			filters:        *g.ReportCmd.Collectors,
+			redactPatterns: *g.ReportCmd.RedactPatterns,
+		})
 	// cluster commands
 	case g.SiteListCmd.FullCommand():
 		return listSites(localEnv, *g.SiteListCmd.OpsCenterURL)
@@ -677,19 +726,22 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 	case g.SystemPullUpdatesCmd.FullCommand():
 		return systemPullUpdates(localEnv,
 			*g.SystemPullUpdatesCmd.OpsCenterURL,
-			*g.SystemPullUpdatesCmd.RuntimePackage)
+			*g.SystemPullUpdatesCmd.RuntimePackage,
+			*g.SystemPullUpdatesCmd.ExactRuntime)
 	case g.SystemUpdateCmd.FullCommand():
 		return systemUpdate(localEnv,
 			*g.SystemUpdateCmd.ChangesetID,
 			*g.SystemUpdateCmd.ServiceName,
 			*g.SystemUpdateCmd.WithStatus,
-			*g.SystemUpdateCmd.RuntimePackage)
+			*g.SystemUpdateCmd.RuntimePackage,
+			*g.SystemUpdateCmd.ExactRuntime)
 	case g.UpdateSystemCmd.FullCommand():
 		return systemUpdate(localEnv,
 			*g.UpdateSystemCmd.ChangesetID,
 			*g.UpdateSystemCmd.ServiceName,
 			*g.UpdateSystemCmd.WithStatus,
-			*g.UpdateSystemCmd.RuntimePackage)
+			*g.UpdateSystemCmd.RuntimePackage,
+			*g.UpdateSystemCmd.ExactRuntime)
 	case g.SystemRollbackCmd.FullCommand():
 		return systemRollback(localEnv,
 			*g.SystemRollbackCmd.ChangesetID,
@@ -742,17 +794,40 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 	case g.SystemReportCmd.FullCommand():
 		return systemReport(localEnv,
 			*g.SystemReportCmd.Filter,
-			*g.SystemReportCmd.Compressed)
+			*g.SystemReportCmd.Compressed,
+			reportScope{
+				since:                 *g.SystemReportCmd.Since,
+				namespaces:            *g.SystemReportCmd.Namespaces,
+				redactPatterns:        *g.SystemReportCmd.RedactPatterns,
+				encodedRedactPatterns: *g.SystemReportCmd.EncodedRedactPatterns,
+				appCollectors:         *g.SystemReportCmd.AppCollectors,
+			})
 	case g.SystemStateDirCmd.FullCommand():
 		return printStateDir()
 	case g.SystemEnablePromiscModeCmd.FullCommand():
 		return enablePromiscMode(localEnv, *g.SystemEnablePromiscModeCmd.Iface)
 	case g.SystemDisablePromiscModeCmd.FullCommand():
 		return disablePromiscMode(localEnv, *g.SystemDisablePromiscModeCmd.Iface)
+	case g.SystemApplyHostConfigCmd.FullCommand():
+		return applyHostConfig(localEnv, *g.SystemApplyHostConfigCmd.Spec)
+	case g.SystemApplyTrustedCACmd.FullCommand():
+		return applyTrustedCA(localEnv, *g.SystemApplyTrustedCACmd.Bundle)
+	case g.SystemUpdateProxyCmd.FullCommand():
+		phase := *g.SystemUpdateProxyCmd.Phase
+		if *g.SystemUpdateProxyCmd.Resume {
+			phase = fsm.RootPhase
+		}
+		if phase == "" {
+			return trace.BadParameter("either --phase or --resume must be specified")
+		}
+		return updateProxyPhase(localEnv, phase, *g.SystemUpdateProxyCmd.PhaseTimeout,
+			*g.SystemUpdateProxyCmd.Force)
 	case g.SystemExportRuntimeJournalCmd.FullCommand():
-		return exportRuntimeJournal(localEnv, *g.SystemExportRuntimeJournalCmd.OutputFile)
+		return exportRuntimeJournal(localEnv,
+			*g.SystemExportRuntimeJournalCmd.OutputFile,
+			*g.SystemExportRuntimeJournalCmd.Since)
 	case g.SystemStreamRuntimeJournalCmd.FullCommand():
-		return streamRuntimeJournal(localEnv)
+		return streamRuntimeJournal(localEnv, *g.SystemStreamRuntimeJournalCmd.Since)
 	case g.GarbageCollectCmd.FullCommand():
 		phase := *g.GarbageCollectCmd.Phase
 		if *g.GarbageCollectCmd.Resume {
@@ -775,6 +850,10 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return removeUnusedImages(localEnv,
 			*g.SystemGCRegistryCmd.DryRun,
 			*g.SystemGCRegistryCmd.Confirm)
+	case g.SystemBLOBStatusCmd.FullCommand():
+		return blobStatus(localEnv,
+			*g.SystemBLOBStatusCmd.MinReplicas,
+			*g.SystemBLOBStatusCmd.Output)
 	case g.PlanetEnterCmd.FullCommand(), g.EnterCmd.FullCommand():
 		return planetEnter(localEnv, extraArgs)
 	case g.ExecCmd.FullCommand():
@@ -826,14 +905,32 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return rpcAgentInstall(localEnv, *g.RPCAgentInstallCmd.Args)
 	case g.RPCAgentRunCmd.FullCommand():
 		return rpcAgentRun(localEnv, upgradeEnv,
+			*g.RPCAgentRunCmd.SecretsDir,
 			*g.RPCAgentRunCmd.Args)
 	case g.RPCAgentShutdownCmd.FullCommand():
 		return rpcAgentShutdown(localEnv)
 	case g.CheckCmd.FullCommand():
+		if *g.CheckCmd.UpgradeTo != "" {
+			return checkUpgrade(localEnv, *g.CheckCmd.UpgradeTo)
+		}
+		if *g.CheckCmd.Nodes != "" {
+			return checkNodes(localEnv, checkNodesConfig{
+				inventoryPath: *g.CheckCmd.Nodes,
+				installerPath: *g.CheckCmd.Installer,
+				flavor:        *g.CheckCmd.Flavor,
+				format:        *g.CheckCmd.Output,
+			})
+		}
+		if *g.CheckCmd.Profile == "" {
+			return trace.BadParameter("required flag --profile not provided")
+		}
 		return checkManifest(localEnv,
 			*g.CheckCmd.ManifestFile,
 			*g.CheckCmd.Profile,
-			*g.CheckCmd.AutoFix)
+			*g.CheckCmd.AutoFix,
+			*g.CheckCmd.DryRun,
+			*g.CheckCmd.Confirm,
+			*g.CheckCmd.Output)
 	}
 	return trace.NotFound("unknown command %v", cmd)
 }
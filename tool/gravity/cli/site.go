@@ -122,7 +122,12 @@ func listSites(env *localenv.LocalEnvironment, opsCenterURL string) error {
 	return nil
 }
 
-func getClusterReport(env *localenv.LocalEnvironment, targetFile string) error {
+func getClusterReport(env *localenv.LocalEnvironment, targetFile string, scope reportScope) error {
+	// Validate the redaction patterns before creating the report file
+	if _, err := scope.redactor(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	f, err := os.Create(targetFile)
 	if err != nil {
 		return trace.Wrap(err)
@@ -139,9 +144,15 @@ func getClusterReport(env *localenv.LocalEnvironment, targetFile string) error {
 		return trace.Wrap(err)
 	}
 
-	report, err := operator.GetSiteReport(ops.SiteKey{
-		AccountID:  site.AccountID,
-		SiteDomain: site.Domain,
+	report, err := operator.GetSiteReport(ops.SiteReportRequest{
+		SiteKey: ops.SiteKey{
+			AccountID:  site.AccountID,
+			SiteDomain: site.Domain,
+		},
+		Since:          scope.since,
+		Namespaces:     scope.namespaces,
+		Filters:        scope.filters,
+		RedactPatterns: scope.redactPatterns,
 	})
 	if err != nil {
 		return trace.Wrap(err)
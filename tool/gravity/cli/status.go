@@ -28,10 +28,13 @@ import (
 	"github.com/gravitational/gravity/lib/checks"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	statusapi "github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/system/hostconfig"
+	"github.com/gravitational/gravity/lib/update"
 
 	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
@@ -146,6 +149,176 @@ func statusPeriodic(env *localenv.LocalEnvironment, printOptions printOptions, s
 	}
 }
 
+// historyOptions limits the displayed cluster status history
+type historyOptions struct {
+	// since limits the history to the specified time window
+	since time.Duration
+	// node limits the history to the specified node
+	node string
+	// probe limits the history to the specified health probe
+	probe string
+	// format is the output format
+	format constants.Format
+}
+
+// statusHistory displays the recorded state transitions of the cluster, its nodes and health probes
+func statusHistory(env *localenv.LocalEnvironment, options historyOptions) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req := ops.ClusterStatusHistoryRequest{
+		SiteKey: cluster.Key(),
+		Node:    options.node,
+		Probe:   options.probe,
+	}
+	if options.since != 0 {
+		req.From = time.Now().UTC().Add(-options.since)
+	}
+	transitions, err := operator.GetClusterStatusHistory(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch options.format {
+	case constants.EncodingJSON:
+		bytes, err := json.MarshalIndent(transitions, "", "  ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+	default:
+		if len(transitions) == 0 {
+			env.Println("No state transitions recorded.")
+			return nil
+		}
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Time\tTransition\tDetail\n")
+		fmt.Fprintf(w, "----\t----------\t------\n")
+		for _, transition := range transitions {
+			fmt.Fprintf(w, "%v\t%v\t%v\n",
+				transition.Time.Format(constants.HumanDateFormatSeconds),
+				transition,
+				transition.Detail)
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// statusNetwork runs the full-mesh network test between the cluster nodes
+// and displays the measured latency, jitter, packet loss and path MTU
+func statusNetwork(env *localenv.LocalEnvironment, format constants.Format) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	requirements, err := checks.RequirementsFromManifest(cluster.App.Manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	creds, err := fsm.GetClientCredentials()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	runner := fsm.NewAgentRunner(creds)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*defaults.NetworkTestDuration)
+	defer cancel()
+	if format == constants.EncodingText {
+		env.Println("Running network test between cluster nodes")
+	}
+	paths, err := update.CheckNetwork(ctx, runner, cluster.ClusterState.Servers)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	statuses := make([]networkPathStatus, 0, len(paths))
+	for _, path := range paths {
+		statuses = append(statuses, networkPathStatus{
+			From:       path.From.GetHostname(),
+			To:         path.To.GetHostname(),
+			Protocol:   path.Protocol,
+			Latency:    path.Latency.String(),
+			Jitter:     path.Jitter.String(),
+			PacketLoss: path.PacketLoss(),
+			MTU:        path.MTU,
+			OverlayMTU: path.OverlayMTU(),
+			Problems:   path.Problems(requirements[path.From.Server.Role].Network),
+		})
+	}
+	switch format {
+	case constants.EncodingJSON:
+		bytes, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+	default:
+		if len(statuses) == 0 {
+			env.Println("No network paths to test.")
+			return nil
+		}
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "From\tTo\tProtocol\tLatency\tJitter\tLoss\tMTU\tOverlay MTU\n")
+		fmt.Fprintf(w, "----\t--\t--------\t-------\t------\t----\t---\t-----------\n")
+		var problems []string
+		for _, status := range statuses {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%.1f%%\t%v\t%v\n",
+				status.From, status.To, status.Protocol,
+				status.Latency, status.Jitter, status.PacketLoss,
+				mtuOrNone(status.MTU), mtuOrNone(status.OverlayMTU))
+			problems = append(problems, status.Problems...)
+		}
+		w.Flush()
+		if len(problems) != 0 {
+			fmt.Printf("\nNetwork problems:\n")
+			for _, problem := range problems {
+				fmt.Printf("    %v %v\n", color.RedString("[×]"), problem)
+			}
+		}
+	}
+	return nil
+}
+
+// networkPathStatus describes the measured quality of a single network path
+type networkPathStatus struct {
+	// From is the hostname of the node the path has been probed from
+	From string `json:"from"`
+	// To is the hostname of the node the path has been probed to
+	To string `json:"to"`
+	// Protocol is the protocol used for probing
+	Protocol string `json:"protocol"`
+	// Latency is the average round-trip time
+	Latency string `json:"latency"`
+	// Jitter is the average round-trip time variation
+	Jitter string `json:"jitter"`
+	// PacketLoss is the ratio of lost probes as a percentage
+	PacketLoss float64 `json:"packet_loss"`
+	// MTU is the discovered path MTU
+	MTU int `json:"mtu,omitempty"`
+	// OverlayMTU is the maximum overlay network packet size for this path
+	OverlayMTU int `json:"overlay_mtu,omitempty"`
+	// Problems lists the network requirements this path does not satisfy
+	Problems []string `json:"problems,omitempty"`
+}
+
+func mtuOrNone(mtu int) string {
+	if mtu == 0 {
+		return "-"
+	}
+	return fmt.Sprint(mtu)
+}
+
 // statusOnce collects cluster status information
 func statusOnce(ctx context.Context, operator ops.Operator, operationID string) (*statusapi.Status, error) {
 	cluster, err := operator.GetLocalSite()
@@ -290,6 +463,9 @@ func printClusterStatus(cluster statusapi.Cluster, w io.Writer) {
 	if cluster.Extension != nil {
 		cluster.Extension.WriteTo(w)
 	}
+	if len(cluster.HostConfigDrift) != 0 {
+		printHostConfigDrift(cluster.HostConfigDrift, w)
+	}
 	if len(cluster.ActiveOperations) != 0 {
 		fmt.Fprintf(w, "Active operations:\n")
 		for _, op := range cluster.ActiveOperations {
@@ -303,6 +479,30 @@ func printClusterStatus(cluster statusapi.Cluster, w io.Writer) {
 	cluster.Endpoints.Cluster.WriteTo(w)
 }
 
+// printHostConfigDrift outputs the nodes that differ from the cluster
+// host configuration or could not be checked
+func printHostConfigDrift(nodes []hostconfig.NodeDrift, w io.Writer) {
+	state := color.YellowString("unknown")
+	for _, node := range nodes {
+		if len(node.Drift) != 0 {
+			state = color.YellowString("drifted")
+			break
+		}
+	}
+	fmt.Fprintf(w, "Host configuration:\t%v\n", state)
+	for _, node := range nodes {
+		if node.Error != "" {
+			fmt.Fprintf(w, "    * %v (%v): not checked: %v\n", node.Hostname, node.AdvertiseIP, node.Error)
+			continue
+		}
+		fmt.Fprintf(w, "    * %v (%v):\n", node.Hostname, node.AdvertiseIP)
+		for _, drift := range node.Drift {
+			fmt.Fprintf(w, "        - %v\n", drift)
+		}
+	}
+	fmt.Fprintf(w, "    Re-apply with 'gravity resource create' to reconcile\n")
+}
+
 func printOperation(operation *statusapi.ClusterOperation, w io.Writer) {
 	fmt.Fprintf(w, "    * %v (%v)\n", operation.Type, operation.ID)
 	fmt.Fprintf(w, "      started:\t%v (%v)\n",
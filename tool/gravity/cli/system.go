@@ -18,6 +18,7 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -39,6 +40,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack/localpack"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/trustedca"
 	"github.com/gravitational/gravity/lib/systemservice"
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/gravity/tool/common"
@@ -49,8 +51,10 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// systemPullUpdates pulls new packages from remote Ops Center
-func systemPullUpdates(env *localenv.LocalEnvironment, opsCenterURL string, runtimePackage loc.Locator) error {
+// systemPullUpdates pulls new packages from remote Ops Center.
+// If exactRuntime is true, the runtime package is pulled at the exact version
+// of runtimePackage instead of the latest available version
+func systemPullUpdates(env *localenv.LocalEnvironment, opsCenterURL string, runtimePackage loc.Locator, exactRuntime bool) error {
 	targetURL, err := env.SelectOpsCenter(opsCenterURL)
 	if err != nil {
 		return trace.Wrap(err)
@@ -61,7 +65,7 @@ func systemPullUpdates(env *localenv.LocalEnvironment, opsCenterURL string, runt
 		return trace.Wrap(err)
 	}
 
-	packages, err := findPackages(env.Packages, runtimePackage)
+	packages, err := findPackages(env.Packages, runtimePackage, exactRuntime)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -152,18 +156,24 @@ func systemHistory(env *localenv.LocalEnvironment) error {
 	return nil
 }
 
-// systemUpdate searches and applies package updates if any
+// systemUpdate searches and applies package updates if any.
+// If exactRuntime is true, the runtime package is updated to the exact version
+// of runtimePackage instead of the latest available version
 func systemUpdate(env *localenv.LocalEnvironment, changesetID string, serviceName string, withStatus bool,
-	runtimePackage loc.Locator) error {
+	runtimePackage loc.Locator, exactRuntime bool) error {
 	if serviceName != "" {
-		args := []string{"system", "update", "--changeset-id", changesetID, "--debug"}
+		args := []string{"system", "update", "--changeset-id", changesetID,
+			"--runtime-package", runtimePackage.String(), "--debug"}
 		if withStatus {
 			args = append(args, "--with-status")
 		}
+		if exactRuntime {
+			args = append(args, "--exact-runtime")
+		}
 		return trace.Wrap(installOneshotService(env.Silent, serviceName, args))
 	}
 
-	packages, err := findPackages(env.Packages, runtimePackage)
+	packages, err := findPackages(env.Packages, runtimePackage, exactRuntime)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -374,8 +384,10 @@ func applyUpdates(env *localenv.LocalEnvironment, updates []storage.PackageUpdat
 
 // findPackages returns a list of additional packages to pull during update.
 // These are packages that do not have a static name and need to be looked up
-// dynamically
-func findPackages(packages pack.PackageService, runtimePackageUpdate loc.Locator) (reqs []packageRequest, err error) {
+// dynamically.
+// If exactRuntime is true, the runtime package is updated to the exact version
+// of runtimePackageUpdate
+func findPackages(packages pack.PackageService, runtimePackageUpdate loc.Locator, exactRuntime bool) (reqs []packageRequest, err error) {
 	secrets, err := findSecretsPackage(packages)
 	if err != nil {
 		return nil, trace.Wrap(err, "failed to find secrets package")
@@ -404,6 +416,9 @@ func findPackages(packages pack.PackageService, runtimePackageUpdate loc.Locator
 			filter:       *planetPackage,
 			updateFilter: &runtimePackageUpdate,
 			labels:       pack.RuntimePackageLabels,
+			// runtime package is updated to the exact version requested
+			// when the cluster is upgraded through an intermediate runtime
+			pinned: exactRuntime,
 		},
 		packageRequest{
 			filter: *planetConfig,
@@ -512,6 +527,11 @@ func updatePlanetPackage(env *localenv.LocalEnvironment, installedPackage, newPa
 		log.Warningf("kubectl will not work on host: %v", trace.DebugReport(err))
 	}
 
+	err = trustedca.ApplyPlanetRootfs(context.TODO(), filepath.Join(planetPath, constants.PlanetRootfs))
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to install trusted certificate authorities inside planet")
+	}
+
 	labelUpdates, err = reinstallSystemService(env, installedPackage, newPackage, &configPackage)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -1168,21 +1188,21 @@ func findLegacyRuntimeConfigPackage(packages pack.PackageService) (configPackage
 // findPackageUpdate searches for updates for the installed package specified with req
 func findPackageUpdate(localPackages, remotePackages pack.PackageService, req packageRequest) (*storage.PackageUpdate, error) {
 	if req.withoutInstalledLabel {
-		return findPackageUpdateHelper(remotePackages, req.filter, req.updateFilter)
+		return findPackageUpdateHelper(remotePackages, req.filter, req.updateFilter, req.pinned)
 	}
 
 	installedPackage, err := pack.FindInstalledPackage(localPackages, req.filter)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return findPackageUpdateHelper(remotePackages, *installedPackage, req.updateFilter)
+	return findPackageUpdateHelper(remotePackages, *installedPackage, req.updateFilter, req.pinned)
 }
 
-func findPackageUpdateHelper(packages pack.PackageService, filter loc.Locator, updateFilter *loc.Locator) (*storage.PackageUpdate, error) {
+func findPackageUpdateHelper(packages pack.PackageService, filter loc.Locator, updateFilter *loc.Locator, pinned bool) (*storage.PackageUpdate, error) {
 	if updateFilter == nil {
 		updateFilter = &filter
 	}
-	latestPackage, err := pack.FindLatestPackage(packages, *updateFilter)
+	latestPackage, err := findUpdateCandidate(packages, *updateFilter, pinned)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -1200,6 +1220,20 @@ func findPackageUpdateHelper(packages pack.PackageService, filter loc.Locator, u
 	return nil, trace.NotFound("%v is already at the latest version", filter)
 }
 
+// findUpdateCandidate returns the package to update to given the specified filter.
+// If pinned is true, the exact package version from filter is returned, otherwise
+// the latest version of the package is looked up
+func findUpdateCandidate(packages pack.PackageService, filter loc.Locator, pinned bool) (*loc.Locator, error) {
+	if !pinned {
+		return pack.FindLatestPackage(packages, filter)
+	}
+	envelope, err := packages.ReadPackageEnvelope(filter)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &envelope.Locator, nil
+}
+
 func findLatestPlanetConfigPackage(localPackages pack.PackageService, planetPackage loc.Locator) (*loc.Locator, error) {
 	configPackage, err := pack.FindConfigPackage(localPackages, planetPackage)
 	log.Debugf("Runtime configuration package: %v (%v).", configPackage, err)
@@ -1350,6 +1384,9 @@ type packageRequest struct {
 	// withoutInstalledLabel specifies if the search does not require the
 	// source package to be labeled with installed label
 	withoutInstalledLabel bool
+	// pinned specifies whether the update should use the exact version
+	// from updateFilter instead of the latest available version
+	pinned bool
 }
 
 var (
@@ -19,6 +19,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appservice "github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/constants"
@@ -29,10 +30,26 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update"
 
 	"github.com/gravitational/trace"
 )
 
+// newRollbackPolicy returns the automatic rollback policy specified on command line.
+// Returns nil if no policy has been specified in which case the policy
+// from the application manifest is used
+func newRollbackPolicy(onFailure bool, healthCheckGracePeriod time.Duration) *storage.RollbackPolicy {
+	policy := &storage.RollbackPolicy{
+		OnFailure:              onFailure,
+		HealthCheckGracePeriod: healthCheckGracePeriod,
+	}
+	if policy.IsEmpty() {
+		return nil
+	}
+	return policy
+}
+
 func updateCheck(env *localenv.LocalEnvironment, appPackage string) error {
 	operator, err := env.SiteOperator()
 	if err != nil {
@@ -53,6 +70,7 @@ func updateTrigger(
 	upgradeEnv *localenv.LocalEnvironment,
 	appPackage string,
 	manual bool,
+	rollbackPolicy *storage.RollbackPolicy,
 ) error {
 	clusterEnv, err := localEnv.NewClusterEnvironment()
 	if err != nil {
@@ -89,10 +107,16 @@ func updateTrigger(
 		return trace.Wrap(err)
 	}
 
+	err = pullIntermediateRuntimes(localEnv, clusterEnv, *cluster, app.Manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	opKey, err := operator.CreateSiteAppUpdateOperation(ops.CreateSiteAppUpdateOperationRequest{
-		AccountID:  cluster.AccountID,
-		SiteDomain: cluster.Domain,
-		App:        app.Package.String(),
+		AccountID:      cluster.AccountID,
+		SiteDomain:     cluster.Domain,
+		App:            app.Package.String(),
+		RollbackPolicy: rollbackPolicy,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -176,6 +200,53 @@ will be returned to the "active" state.`)
 	return nil
 }
 
+// pullIntermediateRuntimes makes sure that the runtimes the cluster needs to be
+// upgraded through on its way to the update application are available in the cluster.
+// Missing runtimes are pulled from the Ops Center the cluster is connected to, if any
+func pullIntermediateRuntimes(env *localenv.LocalEnvironment, clusterEnv *localenv.ClusterEnvironment, cluster ops.Site, manifest schema.Manifest) error {
+	installedRuntime := cluster.App.Manifest.Base()
+	updateRuntime := manifest.Base()
+	if installedRuntime == nil || updateRuntime == nil {
+		return nil
+	}
+	var sources []update.RuntimeSource
+	opsURL, err := env.SelectOpsCenter("")
+	if err != nil {
+		log.Debugf("No Ops Center to pull intermediate runtimes from: %v.", err)
+	}
+	if err == nil && opsURL != defaults.GravityServiceURL {
+		source, err := newOpsCenterRuntimeSource(env, opsURL)
+		if err != nil {
+			log.Warnf("Failed to connect to Ops Center %v: %v.", opsURL, trace.DebugReport(err))
+		} else {
+			sources = append(sources, *source)
+		}
+	}
+	return trace.Wrap(update.PullIntermediateRuntimes(update.PullIntermediateRuntimesRequest{
+		Apps:             clusterEnv.Apps,
+		Packages:         clusterEnv.ClusterPackages,
+		Sources:          sources,
+		InstalledRuntime: *installedRuntime,
+		UpdateRuntime:    *updateRuntime,
+	}))
+}
+
+func newOpsCenterRuntimeSource(env *localenv.LocalEnvironment, opsURL string) (*update.RuntimeSource, error) {
+	apps, err := env.AppService(opsURL, localenv.AppConfig{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	packages, err := env.PackageService(opsURL)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &update.RuntimeSource{
+		Description: fmt.Sprintf("Ops Center %v", opsURL),
+		Apps:        apps,
+		Packages:    packages,
+	}, nil
+}
+
 func checkCanUpdate(cluster ops.Site, operator ops.Operator, manifest schema.Manifest) error {
 	existingGravityPackage, err := cluster.App.Manifest.Dependencies.ByName(constants.GravityPackage)
 	if err != nil {
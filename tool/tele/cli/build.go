@@ -18,10 +18,12 @@ package cli
 
 import (
 	"context"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/builder"
 	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/lib/vulnscan"
 
 	"github.com/gravitational/trace"
 )
@@ -44,6 +46,18 @@ type BuildParameters struct {
 	Silent bool
 	// Insecure turns on insecure verify mode
 	Insecure bool
+	// VulnDatabase is the optional vulnerability database to scan vendored images against
+	VulnDatabase *vulnscan.Database
+	// VulnThreshold is the minimum severity of vulnerabilities that fails the build
+	VulnThreshold vulnscan.Severity
+	// DeltaFrom is the optional path to the installer to build a delta upgrade against
+	DeltaFrom string
+	// CacheDir is the optional directory of the persistent build cache
+	CacheDir string
+	// CacheMaxAge is the time after which unused build cache entries are pruned
+	CacheMaxAge time.Duration
+	// Overlays lists the overlays to apply to the manifest and resource files
+	Overlays []string
 }
 
 // build builds an installer tarball according to the provided parameters
@@ -58,6 +72,12 @@ func build(ctx context.Context, params BuildParameters, req service.VendorReques
 		Repository:       params.Repository,
 		SkipVersionCheck: params.SkipVersionCheck,
 		VendorReq:        req,
+		VulnDatabase:     params.VulnDatabase,
+		VulnThreshold:    params.VulnThreshold,
+		DeltaFrom:        params.DeltaFrom,
+		CacheDir:         params.CacheDir,
+		CacheMaxAge:      params.CacheMaxAge,
+		Overlays:         params.Overlays,
 		Progress:         utils.NewProgress(ctx, "Build", 6, params.Silent),
 	})
 	if err != nil {
@@ -17,6 +17,8 @@ limitations under the License.
 package cli
 
 import (
+	"time"
+
 	"gopkg.in/alecthomas/kingpin.v2"
 
 	"github.com/gravitational/gravity/lib/constants"
@@ -41,6 +43,10 @@ type Application struct {
 	ListCmd ListCmd
 	// PullCmd downloads app installer from Ops Center
 	PullCmd PullCmd
+	// DiffCmd compares two installers or applications
+	DiffCmd DiffCmd
+	// LintCmd validates an application manifest
+	LintCmd LintCmd
 }
 
 // VersionCmd outputs the binary version
@@ -73,6 +79,20 @@ type BuildCmd struct {
 	SetImages *loc.DockerImages
 	// SetDeps rewrites app dependencies to specified versions
 	SetDeps *loc.Locators
+	// ImagePolicy is the path to the image policy file
+	ImagePolicy *string
+	// VulnDB is the path to the vulnerability database file
+	VulnDB *string
+	// VulnSeverity is the minimum severity of vulnerabilities that fails the build
+	VulnSeverity *string
+	// DeltaFrom is the path to the installer of the previous version to build a delta upgrade against
+	DeltaFrom *string
+	// CacheDir is the directory of the persistent build cache
+	CacheDir *string
+	// CacheMaxAge is the time after which unused build cache entries are pruned
+	CacheMaxAge *time.Duration
+	// Overlays lists the overlays to apply to the manifest and resource files
+	Overlays *[]string
 	// SkipVersionCheck suppresses version mismatch check
 	SkipVersionCheck *bool
 	// Parallel defines the number of tasks to execute concurrently
@@ -103,3 +123,25 @@ type PullCmd struct {
 	// Quiet allows to suppress console output
 	Quiet *bool
 }
+
+// DiffCmd compares two installers or applications
+type DiffCmd struct {
+	*kingpin.CmdClause
+	// Old is the old installer tarball or application locator
+	Old *string
+	// New is the new installer tarball or application locator
+	New *string
+	// Format is the output format
+	Format *constants.Format
+}
+
+// LintCmd validates an application manifest
+type LintCmd struct {
+	*kingpin.CmdClause
+	// ManifestPath is the path to the manifest file
+	ManifestPath *string
+	// Format is the output format
+	Format *constants.Format
+	// Schema prints the manifest JSON schema instead
+	Schema *bool
+}
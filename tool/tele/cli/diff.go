@@ -0,0 +1,113 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"os"
+
+	"github.com/gravitational/gravity/lib/app/diff"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/install"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// diffApps compares two installer tarballs or application packages
+// from the local state directory and prints the differences
+func diffApps(env localenv.LocalEnvironment, old, new string, format constants.Format) error {
+	oldSource, cleanup, err := diffSource(env, old)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer cleanup()
+	newSource, cleanup, err := diffSource(env, new)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer cleanup()
+	result, err := diff.Compare(*oldSource, *newSource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON:
+		return trace.Wrap(result.WriteJSON(os.Stdout))
+	case constants.EncodingText:
+		return trace.Wrap(result.WriteText(os.Stdout))
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
+
+// diffSource returns the application to compare specified either with
+// the path to an installer tarball or an application package locator
+func diffSource(env localenv.LocalEnvironment, source string) (*diff.Source, func(), error) {
+	fi, err := utils.StatFile(source)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, nil, trace.Wrap(err)
+	}
+	if fi == nil {
+		locator, err := loc.ParseLocator(source)
+		if err != nil {
+			return nil, nil, trace.BadParameter("%q is neither an installer tarball "+
+				"nor an application package locator", source)
+		}
+		apps, err := env.AppServiceLocal(localenv.AppConfig{})
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		return &diff.Source{
+			Apps:     apps,
+			Packages: env.Packages,
+			Package:  *locator,
+		}, func() {}, nil
+	}
+	dir, err := archive.Unpack(source)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	installerEnv, err := localenv.NewLocalEnvironment(localenv.LocalEnvironmentArgs{
+		StateDir: dir,
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, trace.Wrap(err)
+	}
+	cleanup := func() {
+		installerEnv.Close()
+		os.RemoveAll(dir)
+	}
+	apps, err := installerEnv.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		cleanup()
+		return nil, nil, trace.Wrap(err)
+	}
+	application, err := install.GetApp(apps)
+	if err != nil {
+		cleanup()
+		return nil, nil, trace.Wrap(err)
+	}
+	return &diff.Source{
+		Apps:     apps,
+		Packages: installerEnv.Packages,
+		Package:  application.Package,
+	}, cleanup, nil
+}
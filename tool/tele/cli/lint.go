@@ -0,0 +1,85 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+)
+
+// lint validates the manifest at the specified path and prints all found problems.
+// If packages is set, the manifest dependencies are resolved against it
+func lint(manifestPath string, packages pack.PackageService, format constants.Format) error {
+	data, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	var config schema.LintConfig
+	if packages != nil {
+		config.Resolve = func(locator loc.Locator) error {
+			_, err := packages.ReadPackageEnvelope(locator)
+			return trace.Wrap(err)
+		}
+	}
+	diagnostics, err := schema.Lint(data, config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON:
+		if diagnostics == nil {
+			diagnostics = schema.Diagnostics{}
+		}
+		bytes, err := json.MarshalIndent(diagnostics, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+	case constants.EncodingText:
+		var errors, warnings int
+		for _, d := range diagnostics {
+			fmt.Printf("%v:%v\n", manifestPath, d)
+			if d.Severity == schema.SeverityError {
+				errors++
+			} else {
+				warnings++
+			}
+		}
+		fmt.Printf("%v: %v error(s), %v warning(s)\n", manifestPath, errors, warnings)
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+	if diagnostics.HasErrors() {
+		return trace.BadParameter("manifest %v is invalid", manifestPath)
+	}
+	return nil
+}
+
+// printManifestSchema outputs the manifest JSON schema
+func printManifestSchema() error {
+	_, err := os.Stdout.Write(schema.ManifestJSONSchema())
+	return trace.Wrap(err)
+}
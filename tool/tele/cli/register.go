@@ -51,6 +51,13 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.BuildCmd.VendorIgnorePatterns = tele.BuildCmd.Flag("ignore", "Ignore files matching this regular expression when searching for container references").Hidden().Strings()
 	tele.BuildCmd.SetImages = loc.ImagesSlice(tele.BuildCmd.Flag("set-image", "Rewrite docker image versions in the application resource files during vendoring, e.g. 'postgres:9.3.4' will rewrite all images with name 'postgres' to 'postgres:9.3.4'").Hidden())
 	tele.BuildCmd.SetDeps = loc.LocatorSlice(tele.BuildCmd.Flag("set-dep", "Rewrite dependencies section in the application manifest file during vendoring, e.g. 'gravitational.io/site-app:0.0.39' will overwrite dependency to 'gravitational.io/site-app:0.0.39'").Hidden())
+	tele.BuildCmd.ImagePolicy = tele.BuildCmd.Flag("image-policy", "Path to the YAML file with the policy that vendored container images have to satisfy").String()
+	tele.BuildCmd.VulnDB = tele.BuildCmd.Flag("vuln-db", "Path to the vulnerability database file to scan vendored container images against").String()
+	tele.BuildCmd.VulnSeverity = tele.BuildCmd.Flag("vuln-severity", "Minimum severity of found vulnerabilities that fails the build, one of: unknown, low, medium, high, critical").Default(defaults.VulnSeverityThreshold).String()
+	tele.BuildCmd.DeltaFrom = tele.BuildCmd.Flag("delta-from", "Path to the installer of the previous version to produce an upgrade tarball with only the packages and image layers not present in it").String()
+	tele.BuildCmd.CacheDir = tele.BuildCmd.Flag("cache-dir", "Optional directory of the persistent cache of vendored images and application packages reused between builds").String()
+	tele.BuildCmd.CacheMaxAge = tele.BuildCmd.Flag("cache-max-age", "Prune build cache entries that have not been used for longer than this duration").Default(defaults.BuildCacheMaxAge.String()).Duration()
+	tele.BuildCmd.Overlays = tele.BuildCmd.Flag("overlay", fmt.Sprintf("Name of the overlay in the %q directory next to the manifest, or path to the overlay file, to build a variant of the application with. Can be repeated, overlays are applied in order", defaults.OverlaysDir)).Strings()
 	tele.BuildCmd.SkipVersionCheck = tele.BuildCmd.Flag("skip-version-check", "Skip version compatibility check").Hidden().Bool()
 	tele.BuildCmd.Parallel = tele.BuildCmd.Flag("parallel", "Specifies the number of concurrent tasks. If < 0, the number of tasks is not restricted, if unspecified, then tasks are capped at the number of logical CPU cores").Int()
 	tele.BuildCmd.Quiet = tele.BuildCmd.Flag("quiet", "Suppress any extra output to stdout").Short('q').Bool()
@@ -66,5 +73,15 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.PullCmd.Force = tele.PullCmd.Flag("force", "Overwrite existing tarball").Short('f').Bool()
 	tele.PullCmd.Quiet = tele.PullCmd.Flag("quiet", "Suppress any extra output to stdout").Short('q').Bool()
 
+	tele.DiffCmd.CmdClause = app.Command("diff", "Compare two application installers or application packages")
+	tele.DiffCmd.Old = tele.DiffCmd.Arg("old", "Old installer tarball or application package locator").Required().String()
+	tele.DiffCmd.New = tele.DiffCmd.Arg("new", "New installer tarball or application package locator").Required().String()
+	tele.DiffCmd.Format = common.Format(tele.DiffCmd.Flag("format", fmt.Sprintf("Output format, one of: %v", constants.OutputFormats)).Default(string(constants.EncodingText)))
+
+	tele.LintCmd.CmdClause = app.Command("lint", "Validate an application manifest and report all problems found in it")
+	tele.LintCmd.ManifestPath = tele.LintCmd.Arg("manifest-path", "Path to the application manifest file").Default(defaults.ManifestFileName).String()
+	tele.LintCmd.Format = common.Format(tele.LintCmd.Flag("format", fmt.Sprintf("Output format, one of: %v", constants.OutputFormats)).Default(string(constants.EncodingText)))
+	tele.LintCmd.Schema = tele.LintCmd.Flag("schema", "Print the manifest JSON schema for editor integration and exit").Bool()
+
 	return tele
 }
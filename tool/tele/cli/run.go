@@ -23,6 +23,8 @@ import (
 
 	"github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/vulnscan"
 
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
@@ -50,6 +52,24 @@ func Run(tele Application) error {
 	case tele.VersionCmd.FullCommand():
 		return printVersion(*tele.VersionCmd.Output)
 	case tele.BuildCmd.FullCommand():
+		var imagePolicy *service.ImagePolicy
+		if *tele.BuildCmd.ImagePolicy != "" {
+			imagePolicy, err = service.ReadImagePolicy(*tele.BuildCmd.ImagePolicy)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		var vulnDatabase *vulnscan.Database
+		if *tele.BuildCmd.VulnDB != "" {
+			vulnDatabase, err = vulnscan.ReadDatabase(*tele.BuildCmd.VulnDB)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		vulnThreshold, err := vulnscan.ParseSeverity(*tele.BuildCmd.VulnSeverity)
+		if err != nil {
+			return trace.Wrap(err)
+		}
 		return build(context.Background(), BuildParameters{
 			StateDir:         *tele.StateDir,
 			ManifestPath:     *tele.BuildCmd.ManifestPath,
@@ -58,6 +78,12 @@ func Run(tele Application) error {
 			Repository:       *tele.BuildCmd.Repository,
 			SkipVersionCheck: *tele.BuildCmd.SkipVersionCheck,
 			Silent:           *tele.BuildCmd.Quiet,
+			VulnDatabase:     vulnDatabase,
+			VulnThreshold:    vulnThreshold,
+			DeltaFrom:        *tele.BuildCmd.DeltaFrom,
+			CacheDir:         *tele.BuildCmd.CacheDir,
+			CacheMaxAge:      *tele.BuildCmd.CacheMaxAge,
+			Overlays:         *tele.BuildCmd.Overlays,
 			Insecure:         *tele.Insecure,
 		}, service.VendorRequest{
 			PackageName:            *tele.BuildCmd.Name,
@@ -66,9 +92,14 @@ func Run(tele Application) error {
 			IgnoreResourcePatterns: *tele.BuildCmd.VendorIgnorePatterns,
 			SetImages:              *tele.BuildCmd.SetImages,
 			SetDeps:                *tele.BuildCmd.SetDeps,
+			ImagePolicy:            imagePolicy,
 			Parallel:               *tele.BuildCmd.Parallel,
 			VendorRuntime:          true,
 		})
+	case tele.LintCmd.FullCommand():
+		if *tele.LintCmd.Schema {
+			return printManifestSchema()
+		}
 	}
 
 	keystoreDir := *tele.StateDir
@@ -102,6 +133,18 @@ func Run(tele Application) error {
 		return list(*env,
 			*tele.ListCmd.All,
 			*tele.ListCmd.Format)
+	case tele.DiffCmd.FullCommand():
+		return diffApps(*env,
+			*tele.DiffCmd.Old,
+			*tele.DiffCmd.New,
+			*tele.DiffCmd.Format)
+	case tele.LintCmd.FullCommand():
+		var packages pack.PackageService
+		if keystoreDir != "" {
+			// only resolve dependencies against an explicitly specified state directory
+			packages = env.Packages
+		}
+		return lint(*tele.LintCmd.ManifestPath, packages, *tele.LintCmd.Format)
 	}
 
 	return trace.NotFound("unknown command %v", cmd)